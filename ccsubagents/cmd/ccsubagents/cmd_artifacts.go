@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonclient"
@@ -20,25 +21,25 @@ import (
 
 var artifactRefPattern = regexp.MustCompile(`^\d{8}T\d{6}(?:\.\d{3})?Z-[0-9a-f]{16}$`)
 
+// artifactWriteOutput is the JSON result of artifacts get --out and put.
+type artifactWriteOutput struct {
+	Artifact daemonclient.ArtifactVersion `json:"artifact"`
+	Path     string                       `json:"path,omitempty"`
+}
+
 type artifactsContext struct {
 	home      string
 	stateDir  string
 	getClient func() (*daemonclient.Client, error)
 }
 
-func runArtifacts(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+func runArtifacts(args []string, stdin io.Reader, out cliOutput) int {
 	if len(args) == 0 {
-		if err := writeln(stderr, "Usage: ccsubagents artifacts <ls|get|put|openwebui>"); err != nil {
-			return 1
-		}
-		return 2
+		return out.fail(newUsageError("Usage: ccsubagents artifacts <ls|get|put|verify|migrate-legacy|rotate-key|backup|restore|identities|share|shares|aliases|openwebui>"), 2)
 	}
 	home, err := os.UserHomeDir()
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	stateDir := paths.ResolveDaemonStateDir(home, os.Getenv)
 
@@ -59,18 +60,33 @@ func runArtifacts(args []string, stdin io.Reader, stdout, stderr io.Writer) int
 	sub := strings.TrimSpace(args[0])
 	switch sub {
 	case "openwebui":
-		return runArtifactsOpenWebUI(ctx, args[1:], stdout, stderr)
+		return runArtifactsOpenWebUI(ctx, args[1:], out)
 	case "ls":
-		return runArtifactsLS(ctx, args[1:], stdout, stderr)
+		return runArtifactsLS(ctx, args[1:], out)
 	case "get":
-		return runArtifactsGet(ctx, args[1:], stdin, stdout, stderr)
+		return runArtifactsGet(ctx, args[1:], stdin, out)
 	case "put":
-		return runArtifactsPut(ctx, args[1:], stdin, stdout, stderr)
+		return runArtifactsPut(ctx, args[1:], stdin, out)
+	case "verify":
+		return runArtifactsVerify(ctx, args[1:], out)
+	case "migrate-legacy":
+		return runArtifactsMigrateLegacy(ctx, args[1:], out)
+	case "rotate-key":
+		return runArtifactsRotateKey(ctx, args[1:], out)
+	case "backup":
+		return runArtifactsBackup(ctx, args[1:], out)
+	case "restore":
+		return runArtifactsRestore(ctx, args[1:], out)
+	case "identities":
+		return runArtifactsIdentities(ctx, args[1:], out)
+	case "share":
+		return runArtifactsShare(ctx, args[1:], out)
+	case "shares":
+		return runArtifactsShares(ctx, args[1:], out)
+	case "aliases":
+		return runArtifactsAliases(ctx, args[1:], out)
 	default:
-		if err := writef(stderr, "unknown artifacts subcommand %q\n", sub); err != nil {
-			return 1
-		}
-		return 2
+		return out.fail(newUsageError("unknown artifacts subcommand %q", sub), 2)
 	}
 }
 
@@ -88,21 +104,15 @@ func workspaceSelector(flagValue string) daemonclient.WorkspaceSelector {
 	return daemonclient.WorkspaceSelector{WorkspaceID: normalizeWorkspaceID(flagValue)}
 }
 
-func runArtifactsOpenWebUI(ctx artifactsContext, args []string, stdout, stderr io.Writer) int {
+func runArtifactsOpenWebUI(ctx artifactsContext, args []string, out cliOutput) int {
 	_ = args
 	cwd, err := os.Getwd()
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	settings, err := config.LoadMergedInstallSettings(ctx.home, cwd)
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 
 	addr := "127.0.0.1:19130"
@@ -112,43 +122,32 @@ func runArtifactsOpenWebUI(ctx artifactsContext, args []string, stdout, stderr i
 		addr = fmt.Sprintf("127.0.0.1:%d", settings.WebUIPort)
 	}
 
-	if settings.NoAuth {
-		if err := writef(stdout, "http://%s/\n", addr); err != nil {
-			return 1
+	webURL := fmt.Sprintf("http://%s/", addr)
+	if !settings.NoAuth {
+		if token := daemonclient.ResolveDaemonToken(ctx.stateDir, os.Getenv); token != "" {
+			webURL += "?token=" + url.QueryEscape(token)
 		}
-		return 0
 	}
-
-	token := daemonclient.ResolveDaemonToken(ctx.stateDir, os.Getenv)
-	if token == "" {
-		if err := writef(stdout, "http://%s/\n", addr); err != nil {
-			return 1
-		}
-		return 0
+	if out.json() {
+		return out.result(map[string]string{"url": webURL})
 	}
-	if err := writef(stdout, "http://%s/?token=%s\n", addr, url.QueryEscape(token)); err != nil {
+	if err := writeln(out.stdout, webURL); err != nil {
 		return 1
 	}
 	return 0
 }
 
-func runArtifactsLS(ctx artifactsContext, args []string, stdout, stderr io.Writer) int {
+func runArtifactsLS(ctx artifactsContext, args []string, out cliOutput) int {
 	fs := newQuietFlagSet("artifacts ls")
 	prefix := fs.String("prefix", "", "name prefix")
 	limit := fs.Int("limit", 100, "max results")
 	workspaceID := addWorkspaceFlag(fs)
 	if err := fs.Parse(args); err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 2
+		return out.fail(usageError{err: err}, 2)
 	}
 	client, err := ctx.getClient()
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	res, err := client.List(context.Background(), daemonclient.ListRequest{
 		Workspace: workspaceSelector(*workspaceID),
@@ -156,42 +155,37 @@ func runArtifactsLS(ctx artifactsContext, args []string, stdout, stderr io.Write
 		Limit:     *limit,
 	})
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
+		return out.fail(err, 1)
+	}
+	if out.json() {
+		if res.Items == nil {
+			res.Items = []daemonclient.ArtifactVersion{}
 		}
-		return 1
+		return out.result(res)
 	}
 	for _, item := range res.Items {
-		if err := writef(stdout, "%s\t%s\n", item.Name, item.Ref); err != nil {
+		if err := writef(out.stdout, "%s\t%s\n", item.Name, item.Ref); err != nil {
 			return 1
 		}
 	}
 	return 0
 }
 
-func runArtifactsGet(ctx artifactsContext, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+func runArtifactsGet(ctx artifactsContext, args []string, stdin io.Reader, out cliOutput) int {
 	_ = stdin
 	fs := newQuietFlagSet("artifacts get")
 	outPath := fs.String("out", "-", "output path or - for stdout")
+	from := fs.String("from", "", "workspace id or alias that shared the artifact")
 	workspaceID := addWorkspaceFlag(fs)
 	if err := fs.Parse(args); err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 2
+		return out.fail(usageError{err: err}, 2)
 	}
 	if fs.NArg() != 1 {
-		if err := writeln(stderr, "Usage: ccsubagents artifacts get <name|ref> [--out PATH|-]"); err != nil {
-			return 1
-		}
-		return 2
+		return out.fail(newUsageError("Usage: ccsubagents artifacts get <name|ref> [--out PATH|-] [--from=ID|ALIAS]"), 2)
 	}
 	client, err := ctx.getClient()
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	id := strings.TrimSpace(fs.Arg(0))
 	sel := daemonclient.Selector{Name: id}
@@ -201,84 +195,71 @@ func runArtifactsGet(ctx artifactsContext, args []string, stdin io.Reader, stdou
 	res, err := client.Get(context.Background(), daemonclient.GetRequest{
 		Workspace: workspaceSelector(*workspaceID),
 		Selector:  sel,
+		Source:    strings.TrimSpace(*from),
 	})
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	payload, err := base64.StdEncoding.DecodeString(res.DataBase64)
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	if *outPath == "-" {
-		if err := writeAll(stdout, payload); err != nil {
+		if out.json() {
+			return out.result(res)
+		}
+		if err := writeAll(out.stdout, payload); err != nil {
 			return 1
 		}
 		return 0
 	}
 	if err := os.MkdirAll(filepath.Dir(*outPath), 0o755); err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	if err := os.WriteFile(*outPath, payload, 0o600); err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
-	if err := writef(stdout, "wrote %s\n", *outPath); err != nil {
+	if out.json() {
+		return out.result(artifactWriteOutput{Artifact: res.Artifact, Path: *outPath})
+	}
+	if err := writef(out.stdout, "wrote %s\n", *outPath); err != nil {
 		return 1
 	}
 	return 0
 }
 
-func runArtifactsPut(ctx artifactsContext, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+func runArtifactsPut(ctx artifactsContext, args []string, stdin io.Reader, out cliOutput) int {
 	fs := newQuietFlagSet("artifacts put")
 	mimeType := fs.String("mime-type", "", "content MIME type")
 	filename := fs.String("filename", "", "optional filename metadata")
 	workspaceID := addWorkspaceFlag(fs)
 	expectedPrevRef := fs.String("expected-prev-ref", "", "optimistic concurrency ref")
+	ttl := fs.String("ttl", "", "delete the name after this duration, e.g. 24h")
 	if err := fs.Parse(args); err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 2
+		return out.fail(usageError{err: err}, 2)
 	}
 	if fs.NArg() != 2 {
-		if err := writeln(stderr, "Usage: ccsubagents artifacts put <name> <path|-> [flags]"); err != nil {
-			return 1
-		}
-		return 2
+		return out.fail(newUsageError("Usage: ccsubagents artifacts put <name> <path|-> [flags]"), 2)
 	}
 	name := strings.TrimSpace(fs.Arg(0))
 	path := strings.TrimSpace(fs.Arg(1))
 	if name == "" {
-		if err := writeln(stderr, "artifact name is required"); err != nil {
-			return 1
+		return out.fail(newUsageError("artifact name is required"), 2)
+	}
+	ttlValue := strings.TrimSpace(*ttl)
+	if ttlValue != "" {
+		if d, err := time.ParseDuration(ttlValue); err != nil || d <= 0 {
+			return out.fail(newUsageError("--ttl must be a positive duration such as 24h"), 2)
 		}
-		return 2
 	}
 	client, err := ctx.getClient()
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 
 	data, err := readPutData(stdin, path)
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	typeHint := strings.TrimSpace(*mimeType)
 	if typeHint == "" {
@@ -290,40 +271,518 @@ func runArtifactsPut(ctx artifactsContext, args []string, stdin io.Reader, stdou
 	}
 
 	workspace := workspaceSelector(*workspaceID)
+	var saved daemonclient.ArtifactVersion
 	if strings.HasPrefix(typeHint, "text/") {
-		saved, err := client.SaveText(context.Background(), daemonclient.SaveTextRequest{
+		saved, err = client.SaveText(context.Background(), daemonclient.SaveTextRequest{
 			Workspace:       workspace,
 			Name:            name,
 			Text:            string(data),
 			MimeType:        typeHint,
 			ExpectedPrevRef: strings.TrimSpace(*expectedPrevRef),
+			TTL:             ttlValue,
+		})
+	} else {
+		saved, err = client.SaveBlob(context.Background(), daemonclient.SaveBlobRequest{
+			Workspace:       workspace,
+			Name:            name,
+			DataBase64:      base64.StdEncoding.EncodeToString(data),
+			MimeType:        typeHint,
+			Filename:        strings.TrimSpace(*filename),
+			ExpectedPrevRef: strings.TrimSpace(*expectedPrevRef),
+			TTL:             ttlValue,
 		})
+	}
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	if out.json() {
+		return out.result(artifactWriteOutput{Artifact: saved})
+	}
+	if err := writef(out.stdout, "%s\n", saved.Ref); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func runArtifactsVerify(ctx artifactsContext, args []string, out cliOutput) int {
+	fs := newQuietFlagSet("artifacts verify")
+	repair := fs.Bool("repair", false, "quarantine corrupt data and fix dangling links")
+	workspaceID := fs.String("workspace-id", "", "workspace id (default: all workspaces)")
+	if err := fs.Parse(args); err != nil {
+		return out.fail(usageError{err: err}, 2)
+	}
+	if fs.NArg() != 0 {
+		return out.fail(newUsageError("Usage: ccsubagents artifacts verify [--repair] [--workspace-id=ID]"), 2)
+	}
+	client, err := ctx.getClient()
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	req := daemonclient.VerifyRequest{Repair: *repair}
+	if strings.TrimSpace(*workspaceID) != "" {
+		req.Workspace = workspaceSelector(*workspaceID)
+	}
+	res, err := client.Verify(context.Background(), req)
+	if err != nil {
+		return out.fail(err, 1)
+	}
+
+	unresolved := 0
+	for _, ws := range res.Workspaces {
+		for _, issue := range ws.Issues {
+			if issue.Action == "" {
+				unresolved++
+			}
+		}
+	}
+	if out.json() {
+		if res.Workspaces == nil {
+			res.Workspaces = []daemonclient.WorkspaceIntegrity{}
+		}
+		if code := out.result(res); code != 0 {
+			return code
+		}
+	} else if err := writeVerifyReport(out.stdout, res); err != nil {
+		return 1
+	}
+	if unresolved > 0 {
+		return 1
+	}
+	return 0
+}
+
+func writeVerifyReport(w io.Writer, res daemonclient.VerifyResponse) error {
+	for _, ws := range res.Workspaces {
+		status := "ok"
+		if len(ws.Issues) > 0 {
+			status = fmt.Sprintf("%d issue(s)", len(ws.Issues))
+		}
+		if err := writef(w, "%s\t%d versions\t%d blobs\t%s\n", ws.WorkspaceID, ws.Versions, ws.Blobs, status); err != nil {
+			return err
+		}
+		for _, issue := range ws.Issues {
+			subject := issue.Ref
+			if subject == "" {
+				subject = issue.Digest
+			}
+			line := fmt.Sprintf("  %s\t%s\t%s", issue.Kind, subject, issue.Detail)
+			if issue.Action != "" {
+				line += " (" + issue.Action + ")"
+			}
+			if err := writeln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func runArtifactsMigrateLegacy(ctx artifactsContext, args []string, out cliOutput) int {
+	fs := newQuietFlagSet("artifacts migrate-legacy")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without writing")
+	workspaceID := addWorkspaceFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return out.fail(usageError{err: err}, 2)
+	}
+	if fs.NArg() != 1 || strings.TrimSpace(fs.Arg(0)) == "" {
+		return out.fail(newUsageError("Usage: ccsubagents artifacts migrate-legacy <legacy-root> [--dry-run] [--workspace-id=ID]"), 2)
+	}
+	legacyRoot, err := filepath.Abs(strings.TrimSpace(fs.Arg(0)))
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	client, err := ctx.getClient()
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	res, err := client.MigrateLegacy(context.Background(), daemonclient.MigrateLegacyRequest{
+		Workspace:  workspaceSelector(*workspaceID),
+		LegacyRoot: legacyRoot,
+		DryRun:     *dryRun,
+	})
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	if out.json() {
+		if res.Skipped == nil {
+			res.Skipped = []daemonclient.MigrateIssue{}
+		}
+		if code := out.result(res); code != 0 {
+			return code
+		}
+	} else if err := writeMigrateReport(out.stdout, res); err != nil {
+		return 1
+	}
+	if len(res.Skipped) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func writeMigrateReport(w io.Writer, res daemonclient.MigrateLegacyResponse) error {
+	verb := "imported"
+	if res.DryRun {
+		verb = "would import"
+	}
+	if err := writef(w, "%s %d of %d versions across %d names into %s (%d already present)\n", verb, res.Imported, res.Versions, res.Names, res.WorkspaceID, res.Existing); err != nil {
+		return err
+	}
+	for _, issue := range res.Skipped {
+		if err := writef(w, "  skipped %s %s: %s\n", issue.Name, issue.Ref, issue.Detail); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runArtifactsRotateKey(ctx artifactsContext, args []string, out cliOutput) int {
+	if len(args) != 0 {
+		return out.fail(newUsageError("Usage: ccsubagents artifacts rotate-key"), 2)
+	}
+	client, err := ctx.getClient()
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	res, err := client.RotateKey(context.Background())
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	if out.json() {
+		if res.Rewrapped == nil {
+			res.Rewrapped = []string{}
+		}
+		return out.result(res)
+	}
+	if err := writef(out.stdout, "rotated master key to %s; rewrapped %d workspace keys\n", res.MasterKeyID, len(res.Rewrapped)); err != nil {
+		return 1
+	}
+	for _, id := range res.Rewrapped {
+		if err := writef(out.stdout, "  %s\n", id); err != nil {
+			return 1
+		}
+	}
+	return 0
+}
+
+func runArtifactsBackup(ctx artifactsContext, args []string, out cliOutput) int {
+	fs := newQuietFlagSet("artifacts backup")
+	dir := fs.String("dir", "", "snapshot directory (default: the daemon's snapshot directory)")
+	list := fs.Bool("list", false, "list existing snapshots instead of taking one")
+	if err := fs.Parse(args); err != nil {
+		return out.fail(usageError{err: err}, 2)
+	}
+	if fs.NArg() != 0 || (*list && strings.TrimSpace(*dir) != "") {
+		return out.fail(newUsageError("Usage: ccsubagents artifacts backup [--dir=DIR] | --list"), 2)
+	}
+	client, err := ctx.getClient()
+	if err != nil {
+		return out.fail(err, 1)
+	}
+
+	if *list {
+		res, err := client.ListSnapshots(context.Background())
 		if err != nil {
-			if writeErr := writeln(stderr, err); writeErr != nil {
+			return out.fail(err, 1)
+		}
+		if out.json() {
+			if res.Snapshots == nil {
+				res.Snapshots = []daemonclient.SnapshotManifest{}
+			}
+			return out.result(res)
+		}
+		for _, snapshot := range res.Snapshots {
+			kind := "manual"
+			if snapshot.Scheduled {
+				kind = "scheduled"
+			}
+			if err := writef(out.stdout, "%s\t%s\t%s\t%d workspaces\n", snapshot.ID, snapshot.CreatedAt.Format(time.RFC3339), kind, len(snapshot.Workspaces)); err != nil {
 				return 1
 			}
-			return 1
 		}
-		if err := writef(stdout, "%s\n", saved.Ref); err != nil {
+		return 0
+	}
+
+	req := daemonclient.BackupRequest{}
+	if trimmed := strings.TrimSpace(*dir); trimmed != "" {
+		abs, err := filepath.Abs(trimmed)
+		if err != nil {
+			return out.fail(err, 1)
+		}
+		req.Dir = abs
+	}
+	res, err := client.Backup(context.Background(), req)
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	missing := 0
+	for _, ws := range res.Workspaces {
+		missing += ws.Missing
+	}
+	if out.json() {
+		if res.Workspaces == nil {
+			res.Workspaces = []daemonclient.SnapshotWorkspace{}
+		}
+		if code := out.result(res); code != 0 {
+			return code
+		}
+	} else if err := writeBackupReport(out.stdout, res); err != nil {
+		return 1
+	}
+	if missing > 0 {
+		return 1
+	}
+	return 0
+}
+
+func writeBackupReport(w io.Writer, res daemonclient.BackupResponse) error {
+	if err := writef(w, "snapshot %s written to %s\n", res.ID, res.Path); err != nil {
+		return err
+	}
+	for _, ws := range res.Workspaces {
+		line := fmt.Sprintf("  %s\t%d versions\t%d blobs\t%d bytes", ws.WorkspaceID, ws.Versions, ws.Blobs, ws.Bytes)
+		if ws.Missing > 0 {
+			line += fmt.Sprintf("\t%d missing blobs (run artifacts verify)", ws.Missing)
+		}
+		if err := writeln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runArtifactsRestore(ctx artifactsContext, args []string, out cliOutput) int {
+	fs := newQuietFlagSet("artifacts restore")
+	workspaceID := fs.String("workspace-id", "", "workspace id (default: every workspace in the snapshot)")
+	if err := fs.Parse(args); err != nil {
+		return out.fail(usageError{err: err}, 2)
+	}
+	if fs.NArg() != 1 || strings.TrimSpace(fs.Arg(0)) == "" {
+		return out.fail(newUsageError("Usage: ccsubagents artifacts restore <snapshot-id|path> [--workspace-id=ID]"), 2)
+	}
+	snapshot := strings.TrimSpace(fs.Arg(0))
+	if strings.ContainsAny(snapshot, `/\`) || strings.HasPrefix(snapshot, ".") {
+		abs, err := filepath.Abs(snapshot)
+		if err != nil {
+			return out.fail(err, 1)
+		}
+		snapshot = abs
+	}
+	client, err := ctx.getClient()
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	req := daemonclient.RestoreRequest{Snapshot: snapshot}
+	if strings.TrimSpace(*workspaceID) != "" {
+		req.Workspace = workspaceSelector(*workspaceID)
+	}
+	res, err := client.Restore(context.Background(), req)
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	if out.json() {
+		if res.Workspaces == nil {
+			res.Workspaces = []string{}
+		}
+		return out.result(res)
+	}
+	summary := fmt.Sprintf("restored %d workspaces from %s", len(res.Workspaces), res.SnapshotID)
+	if res.Registry {
+		summary += " (including the workspace registry)"
+	}
+	if err := writeln(out.stdout, summary); err != nil {
+		return 1
+	}
+	for _, id := range res.Workspaces {
+		if err := writef(out.stdout, "  %s\n", id); err != nil {
 			return 1
 		}
+	}
+	return 0
+}
+
+func runArtifactsIdentities(ctx artifactsContext, args []string, out cliOutput) int {
+	const usage = "Usage: ccsubagents artifacts identities [map <key> --workspace-id=ID]"
+	if len(args) == 0 {
+		client, err := ctx.getClient()
+		if err != nil {
+			return out.fail(err, 1)
+		}
+		res, err := client.ListIdentities(context.Background())
+		if err != nil {
+			return out.fail(err, 1)
+		}
+		if out.json() {
+			if res.Identities == nil {
+				res.Identities = []daemonclient.WorkspaceIdentity{}
+			}
+			return out.result(res)
+		}
+		for _, identity := range res.Identities {
+			if err := writef(out.stdout, "%s\t%s\n", identity.Key, identity.WorkspaceID); err != nil {
+				return 1
+			}
+		}
 		return 0
 	}
-	saved, err := client.SaveBlob(context.Background(), daemonclient.SaveBlobRequest{
-		Workspace:       workspace,
-		Name:            name,
-		DataBase64:      base64.StdEncoding.EncodeToString(data),
-		MimeType:        typeHint,
-		Filename:        strings.TrimSpace(*filename),
-		ExpectedPrevRef: strings.TrimSpace(*expectedPrevRef),
+	if strings.TrimSpace(args[0]) != "map" {
+		return out.fail(newUsageError(usage), 2)
+	}
+
+	fs := newQuietFlagSet("artifacts identities map")
+	workspaceID := fs.String("workspace-id", "", "workspace id to map the key to")
+	if err := fs.Parse(args[1:]); err != nil {
+		return out.fail(usageError{err: err}, 2)
+	}
+	if fs.NArg() != 1 || strings.TrimSpace(fs.Arg(0)) == "" || strings.TrimSpace(*workspaceID) == "" {
+		return out.fail(newUsageError(usage), 2)
+	}
+	client, err := ctx.getClient()
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	res, err := client.MapIdentity(context.Background(), daemonclient.MapIdentityRequest{
+		Key:       strings.TrimSpace(fs.Arg(0)),
+		Workspace: workspaceSelector(*workspaceID),
+	})
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	if out.json() {
+		return out.result(res)
+	}
+	if err := writef(out.stdout, "%s\t%s\n", res.Key, res.WorkspaceID); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func runArtifactsShare(ctx artifactsContext, args []string, out cliOutput) int {
+	fs := newQuietFlagSet("artifacts share")
+	with := fs.String("with", "*", "workspace id or alias to share with, or * for every workspace")
+	revoke := fs.Bool("revoke", false, "withdraw the share")
+	workspaceID := addWorkspaceFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return out.fail(usageError{err: err}, 2)
+	}
+	if fs.NArg() != 1 || strings.TrimSpace(fs.Arg(0)) == "" {
+		return out.fail(newUsageError("Usage: ccsubagents artifacts share <name> [--with=*|ID|ALIAS] [--revoke] [--workspace-id=ID]"), 2)
+	}
+	client, err := ctx.getClient()
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	res, err := client.Share(context.Background(), daemonclient.ShareRequest{
+		Workspace: workspaceSelector(*workspaceID),
+		Name:      strings.TrimSpace(fs.Arg(0)),
+		With:      strings.TrimSpace(*with),
+		Revoke:    *revoke,
 	})
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
+		return out.fail(err, 1)
+	}
+	if out.json() {
+		return out.result(res)
+	}
+	verb := "shared"
+	if res.Revoked {
+		verb = "revoked"
+	}
+	if err := writef(out.stdout, "%s %s with %s\n", verb, res.URI, res.Share.TargetWorkspaceID); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func runArtifactsShares(ctx artifactsContext, args []string, out cliOutput) int {
+	fs := newQuietFlagSet("artifacts shares")
+	workspaceID := addWorkspaceFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return out.fail(usageError{err: err}, 2)
+	}
+	if fs.NArg() != 0 {
+		return out.fail(newUsageError("Usage: ccsubagents artifacts shares [--workspace-id=ID]"), 2)
+	}
+	client, err := ctx.getClient()
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	res, err := client.ListShares(context.Background(), daemonclient.ListSharesRequest{Workspace: workspaceSelector(*workspaceID)})
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	if out.json() {
+		if res.Shares == nil {
+			res.Shares = []daemonclient.WorkspaceShare{}
+		}
+		return out.result(res)
+	}
+	for _, share := range res.Shares {
+		if err := writef(out.stdout, "%s\t%s\t%s\n", share.SourceWorkspaceID, share.Name, share.TargetWorkspaceID); err != nil {
 			return 1
 		}
-		return 1
 	}
-	if err := writef(stdout, "%s\n", saved.Ref); err != nil {
+	return 0
+}
+
+func runArtifactsAliases(ctx artifactsContext, args []string, out cliOutput) int {
+	const usage = "Usage: ccsubagents artifacts aliases [set <alias> --workspace-id=ID | rm <alias>]"
+	if len(args) == 0 {
+		client, err := ctx.getClient()
+		if err != nil {
+			return out.fail(err, 1)
+		}
+		res, err := client.ListAliases(context.Background())
+		if err != nil {
+			return out.fail(err, 1)
+		}
+		if out.json() {
+			if res.Aliases == nil {
+				res.Aliases = []daemonclient.WorkspaceAlias{}
+			}
+			return out.result(res)
+		}
+		for _, alias := range res.Aliases {
+			if err := writef(out.stdout, "%s\t%s\n", alias.Alias, alias.WorkspaceID); err != nil {
+				return 1
+			}
+		}
+		return 0
+	}
+	action := strings.TrimSpace(args[0])
+	if action != "set" && action != "rm" {
+		return out.fail(newUsageError(usage), 2)
+	}
+
+	fs := newQuietFlagSet("artifacts aliases " + action)
+	workspaceID := fs.String("workspace-id", "", "workspace id the alias names")
+	if err := fs.Parse(args[1:]); err != nil {
+		return out.fail(usageError{err: err}, 2)
+	}
+	hasWorkspace := strings.TrimSpace(*workspaceID) != ""
+	if fs.NArg() != 1 || strings.TrimSpace(fs.Arg(0)) == "" || hasWorkspace != (action == "set") {
+		return out.fail(newUsageError(usage), 2)
+	}
+	req := daemonclient.SetAliasRequest{Alias: strings.TrimSpace(fs.Arg(0)), Remove: action == "rm"}
+	if hasWorkspace {
+		req.Workspace = workspaceSelector(*workspaceID)
+	}
+	client, err := ctx.getClient()
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	res, err := client.SetAlias(context.Background(), req)
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	if out.json() {
+		return out.result(res)
+	}
+	if req.Remove {
+		err = writef(out.stdout, "removed %s\n", res.Alias)
+	} else {
+		err = writef(out.stdout, "%s\t%s\n", res.Alias, res.WorkspaceID)
+	}
+	if err != nil {
 		return 1
 	}
 	return 0
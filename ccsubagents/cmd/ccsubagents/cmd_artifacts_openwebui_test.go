@@ -73,7 +73,7 @@ func TestRunArtifactsOpenWebUI_NoAuthIgnoresToken(t *testing.T) {
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	code := runArtifacts([]string{"openwebui"}, nil, &stdout, &stderr)
+	code := runArtifacts([]string{"openwebui"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
 	if code != 0 {
 		t.Fatalf("runArtifacts exit code = %d, stderr=%q", code, stderr.String())
 	}
@@ -99,7 +99,7 @@ func TestRunArtifactsOpenWebUI_WithTokenWhenAuthEnabled(t *testing.T) {
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	code := runArtifacts([]string{"openwebui"}, nil, &stdout, &stderr)
+	code := runArtifacts([]string{"openwebui"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
 	if code != 0 {
 		t.Fatalf("runArtifacts exit code = %d, stderr=%q", code, stderr.String())
 	}
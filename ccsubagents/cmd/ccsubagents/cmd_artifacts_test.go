@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonclient"
 )
 
 func TestLooksLikeRef_StrictPattern(t *testing.T) {
@@ -31,11 +33,11 @@ func TestRunArtifacts_NoArgs_UsageExit2(t *testing.T) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 
-	code := runArtifacts(nil, nil, &stdout, &stderr)
+	code := runArtifacts(nil, nil, cliOutput{stdout: &stdout, stderr: &stderr})
 	if code != 2 {
 		t.Fatalf("runArtifacts exit=%d, want=2", code)
 	}
-	if got := stderr.String(); got != "Usage: ccsubagents artifacts <ls|get|put|openwebui>\n" {
+	if got := stderr.String(); got != "Usage: ccsubagents artifacts <ls|get|put|verify|migrate-legacy|rotate-key|backup|restore|identities|share|shares|aliases|openwebui>\n" {
 		t.Fatalf("stderr mismatch: got=%q", got)
 	}
 	if stdout.Len() != 0 {
@@ -47,7 +49,7 @@ func TestRunArtifacts_UnknownSubcommand_Exit2(t *testing.T) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 
-	code := runArtifacts([]string{"wat"}, nil, &stdout, &stderr)
+	code := runArtifacts([]string{"wat"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
 	if code != 2 {
 		t.Fatalf("runArtifacts exit=%d, want=2", code)
 	}
@@ -63,11 +65,11 @@ func TestRunArtifactsGet_WrongArgCount_ShowsUsageExit2(t *testing.T) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 
-	code := runArtifacts([]string{"get"}, nil, &stdout, &stderr)
+	code := runArtifacts([]string{"get"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
 	if code != 2 {
 		t.Fatalf("runArtifacts exit=%d, want=2", code)
 	}
-	if got := stderr.String(); got != "Usage: ccsubagents artifacts get <name|ref> [--out PATH|-]\n" {
+	if got := stderr.String(); got != "Usage: ccsubagents artifacts get <name|ref> [--out PATH|-] [--from=ID|ALIAS]\n" {
 		t.Fatalf("stderr mismatch: got=%q", got)
 	}
 	if stdout.Len() != 0 {
@@ -79,7 +81,7 @@ func TestRunArtifactsPut_WrongArgCount_ShowsUsageExit2(t *testing.T) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 
-	code := runArtifacts([]string{"put", "only-name"}, nil, &stdout, &stderr)
+	code := runArtifacts([]string{"put", "only-name"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
 	if code != 2 {
 		t.Fatalf("runArtifacts exit=%d, want=2", code)
 	}
@@ -95,7 +97,7 @@ func TestRunArtifactsPut_EmptyName_IsUsageErrorExit2(t *testing.T) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 
-	code := runArtifacts([]string{"put", "", "-"}, bytes.NewBufferString("payload"), &stdout, &stderr)
+	code := runArtifacts([]string{"put", "", "-"}, bytes.NewBufferString("payload"), cliOutput{stdout: &stdout, stderr: &stderr})
 	if code != 2 {
 		t.Fatalf("runArtifacts exit=%d, want=2", code)
 	}
@@ -106,3 +108,165 @@ func TestRunArtifactsPut_EmptyName_IsUsageErrorExit2(t *testing.T) {
 		t.Fatalf("expected empty stdout, got %q", stdout.String())
 	}
 }
+
+func TestRunArtifactsPut_InvalidTTL_IsUsageErrorExit2(t *testing.T) {
+	for _, ttl := range []string{"tomorrow", "0s", "-1h"} {
+		var stdout bytes.Buffer
+		var stderr bytes.Buffer
+
+		code := runArtifacts([]string{"put", "--ttl=" + ttl, "scratch/x", "-"}, bytes.NewBufferString("payload"), cliOutput{stdout: &stdout, stderr: &stderr})
+		if code != 2 {
+			t.Fatalf("runArtifacts(--ttl=%s) exit=%d, want=2", ttl, code)
+		}
+		if got := stderr.String(); got != "--ttl must be a positive duration such as 24h\n" {
+			t.Fatalf("stderr mismatch: got=%q", got)
+		}
+	}
+}
+
+func TestRunArtifactsVerify_ExtraArgs_ShowsUsageExit2(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	code := runArtifacts([]string{"verify", "plan/x"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
+	if code != 2 {
+		t.Fatalf("runArtifacts exit=%d, want=2", code)
+	}
+	if got := stderr.String(); got != "Usage: ccsubagents artifacts verify [--repair] [--workspace-id=ID]\n" {
+		t.Fatalf("stderr mismatch: got=%q", got)
+	}
+}
+
+func TestWriteVerifyReport_ListsIssuesPerWorkspace(t *testing.T) {
+	var out bytes.Buffer
+	err := writeVerifyReport(&out, daemonclient.VerifyResponse{Workspaces: []daemonclient.WorkspaceIntegrity{
+		{WorkspaceID: "global", Versions: 3, Blobs: 3},
+		{WorkspaceID: "abc", Versions: 2, Blobs: 1, Issues: []daemonclient.IntegrityIssue{
+			{Kind: "missing_blob", Ref: "20260227T120000Z-aaaaaaaaaaaaaaaa", Detail: "blob is missing", Action: "quarantined"},
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("write report: %v", err)
+	}
+	want := "global\t3 versions\t3 blobs\tok\n" +
+		"abc\t2 versions\t1 blobs\t1 issue(s)\n" +
+		"  missing_blob\t20260227T120000Z-aaaaaaaaaaaaaaaa\tblob is missing (quarantined)\n"
+	if got := out.String(); got != want {
+		t.Fatalf("report mismatch:\ngot=%q\nwant=%q", got, want)
+	}
+}
+
+func TestRunArtifactsMigrateLegacy_MissingRoot_ShowsUsageExit2(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	code := runArtifacts([]string{"migrate-legacy", "--dry-run"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
+	if code != 2 {
+		t.Fatalf("runArtifacts exit=%d, want=2", code)
+	}
+	if got := stderr.String(); got != "Usage: ccsubagents artifacts migrate-legacy <legacy-root> [--dry-run] [--workspace-id=ID]\n" {
+		t.Fatalf("stderr mismatch: got=%q", got)
+	}
+}
+
+func TestWriteMigrateReport_DryRunWording(t *testing.T) {
+	var out bytes.Buffer
+	err := writeMigrateReport(&out, daemonclient.MigrateLegacyResponse{
+		WorkspaceID: "global", Names: 2, Versions: 5, Imported: 3, Existing: 1, DryRun: true,
+		Skipped: []daemonclient.MigrateIssue{{Name: "plan/a", Ref: "r1", Detail: "payload is missing"}},
+	})
+	if err != nil {
+		t.Fatalf("write report: %v", err)
+	}
+	want := "would import 3 of 5 versions across 2 names into global (1 already present)\n" +
+		"  skipped plan/a r1: payload is missing\n"
+	if got := out.String(); got != want {
+		t.Fatalf("report mismatch:\ngot=%q\nwant=%q", got, want)
+	}
+}
+
+func TestRunArtifactsRotateKey_ExtraArgs_ShowsUsageExit2(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	code := runArtifacts([]string{"rotate-key", "--workspace-id=global"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
+	if code != 2 {
+		t.Fatalf("runArtifacts exit=%d, want=2", code)
+	}
+	if got := stderr.String(); got != "Usage: ccsubagents artifacts rotate-key\n" {
+		t.Fatalf("stderr mismatch: got=%q", got)
+	}
+}
+
+func TestRunArtifactsBackup_ListWithDir_ShowsUsageExit2(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	code := runArtifacts([]string{"backup", "--list", "--dir=/tmp/snapshots"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
+	if code != 2 {
+		t.Fatalf("runArtifacts exit=%d, want=2", code)
+	}
+	if got := stderr.String(); got != "Usage: ccsubagents artifacts backup [--dir=DIR] | --list\n" {
+		t.Fatalf("stderr mismatch: got=%q", got)
+	}
+}
+
+func TestRunArtifactsRestore_MissingSnapshot_ShowsUsageExit2(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	code := runArtifacts([]string{"restore", "--workspace-id=global"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
+	if code != 2 {
+		t.Fatalf("runArtifacts exit=%d, want=2", code)
+	}
+	if got := stderr.String(); got != "Usage: ccsubagents artifacts restore <snapshot-id|path> [--workspace-id=ID]\n" {
+		t.Fatalf("stderr mismatch: got=%q", got)
+	}
+}
+
+func TestWriteBackupReport_FlagsMissingBlobs(t *testing.T) {
+	var out bytes.Buffer
+	err := writeBackupReport(&out, daemonclient.BackupResponse{
+		Path: "/snapshots/20261018T120000Z",
+		SnapshotManifest: daemonclient.SnapshotManifest{
+			ID: "20261018T120000Z",
+			Workspaces: []daemonclient.SnapshotWorkspace{
+				{WorkspaceID: "global", Versions: 3, Blobs: 2, Bytes: 42, Missing: 1},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("write report: %v", err)
+	}
+	want := "snapshot 20261018T120000Z written to /snapshots/20261018T120000Z\n" +
+		"  global\t3 versions\t2 blobs\t42 bytes\t1 missing blobs (run artifacts verify)\n"
+	if got := out.String(); got != want {
+		t.Fatalf("report mismatch:\ngot=%q\nwant=%q", got, want)
+	}
+}
+
+func TestRunArtifactsIdentities_MapWithoutWorkspace_ShowsUsageExit2(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	code := runArtifacts([]string{"identities", "map", "git:github.com/owner/repo#."}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
+	if code != 2 {
+		t.Fatalf("runArtifacts exit=%d, want=2", code)
+	}
+	if got := stderr.String(); got != "Usage: ccsubagents artifacts identities [map <key> --workspace-id=ID]\n" {
+		t.Fatalf("stderr mismatch: got=%q", got)
+	}
+}
+
+func TestRunArtifactsAliases_SetWithoutWorkspace_ShowsUsageExit2(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	code := runArtifacts([]string{"aliases", "set", "team"}, nil, cliOutput{stdout: &stdout, stderr: &stderr})
+	if code != 2 {
+		t.Fatalf("runArtifacts exit=%d, want=2", code)
+	}
+	if got := stderr.String(); got != "Usage: ccsubagents artifacts aliases [set <alias> --workspace-id=ID | rm <alias>]\n" {
+		t.Fatalf("stderr mismatch: got=%q", got)
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,110 +13,165 @@ import (
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/paths"
 )
 
-func runDaemon(args []string, stdout, stderr io.Writer) int {
+type daemonStatusOutput struct {
+	Status        string `json:"status"`
+	PID           int    `json:"pid,omitempty"`
+	Socket        string `json:"socket,omitempty"`
+	Version       string `json:"version,omitempty"`
+	UptimeSeconds int64  `json:"uptimeSeconds,omitempty"`
+	StateDir      string `json:"stateDir"`
+	Error         string `json:"error,omitempty"`
+
+	Details *daemonclient.StatusResponse `json:"details,omitempty"`
+}
+
+func runDaemon(args []string, out cliOutput) int {
 	if len(args) == 0 {
-		if err := writeln(stderr, "Usage: ccsubagents daemon <status|start|stop>"); err != nil {
-			return 1
-		}
-		return 2
+		return out.fail(newUsageError("Usage: ccsubagents daemon <status|start|stop>"), 2)
 	}
 	home, err := os.UserHomeDir()
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	stateDir := paths.ResolveDaemonStateDir(home, os.Getenv)
 
 	sub := strings.TrimSpace(args[0])
 	switch sub {
 	case "status":
-		client, err := daemonclient.NewDefaultClient(stateDir, os.Getenv)
-		if err != nil {
-			if writeErr := writeln(stderr, err); writeErr != nil {
-				return 1
-			}
-			return 1
-		}
-		if err := client.Health(context.Background()); err != nil {
-			if daemonctl.IsDaemonStoppedOrUnavailable(err) {
-				if writeErr := writef(stdout, "daemon status: stopped\nstate dir: %s\n", stateDir); writeErr != nil {
-					return 1
-				}
-				return 0
-			}
-			if writeErr := writef(stdout, "daemon status: unavailable (%v)\n", err); writeErr != nil {
-				return 1
-			}
-			return 1
-		}
-		if err := writef(stdout, "daemon status: ok\nstate dir: %s\n", stateDir); err != nil {
-			return 1
-		}
-		return 0
+		return runDaemonStatus(stateDir, out)
 	case "start":
 		cwd, err := os.Getwd()
 		if err != nil {
-			if writeErr := writeln(stderr, err); writeErr != nil {
-				return 1
-			}
-			return 1
+			return out.fail(err, 1)
 		}
 		settings, err := config.LoadMergedInstallSettings(home, cwd)
 		if err != nil {
-			if writeErr := writeln(stderr, err); writeErr != nil {
-				return 1
-			}
-			return 1
+			return out.fail(err, 1)
 		}
 		storeRoot := resolveStoreRoot(home)
-		if err := daemonctl.StartAndWait(context.Background(), stateDir, storeRoot, settings.NoAuth, stderr); err != nil {
-			if writeErr := writeln(stderr, err); writeErr != nil {
-				return 1
-			}
-			return 1
+		if err := daemonctl.StartAndWait(context.Background(), stateDir, storeRoot, settings.NoAuth, out.stderr); err != nil {
+			return out.fail(err, 1)
+		}
+		if out.json() {
+			return out.result(daemonStatusOutput{Status: "started", StateDir: stateDir})
 		}
-		if err := writeln(stdout, "daemon started"); err != nil {
+		if err := writeln(out.stdout, "daemon started"); err != nil {
 			return 1
 		}
 		return 0
 	case "stop":
 		client, err := daemonclient.NewDefaultClient(stateDir, os.Getenv)
 		if err != nil {
-			if writeErr := writeln(stderr, err); writeErr != nil {
-				return 1
-			}
-			return 1
+			return out.fail(err, 1)
 		}
 		if _, err := client.Shutdown(context.Background()); err != nil && !daemonctl.IsDaemonStoppedOrUnavailable(err) {
-			if writeErr := writeln(stderr, err); writeErr != nil {
-				return 1
-			}
-			return 1
+			return out.fail(err, 1)
 		}
 		if err := daemonctl.WaitForStop(context.Background(), stateDir, 4*time.Second); err != nil {
-			if writeErr := writeln(stderr, err); writeErr != nil {
-				return 1
-			}
-			return 1
+			return out.fail(err, 1)
 		}
 		if err := daemonctl.StopRegisteredProcesses(context.Background(), stateDir, []string{"web", "mcp"}); err != nil {
-			if writeErr := writeln(stderr, err); writeErr != nil {
-				return 1
-			}
-			return 1
+			return out.fail(err, 1)
 		}
-		if err := writeln(stdout, "daemon stopped"); err != nil {
+		if out.json() {
+			return out.result(daemonStatusOutput{Status: "stopped", StateDir: stateDir})
+		}
+		if err := writeln(out.stdout, "daemon stopped"); err != nil {
 			return 1
 		}
 		return 0
 	default:
-		if err := writef(stderr, "unknown daemon subcommand %q\n", sub); err != nil {
+		return out.fail(newUsageError("unknown daemon subcommand %q", sub), 2)
+	}
+}
+
+func runDaemonStatus(stateDir string, out cliOutput) int {
+	client, err := daemonclient.NewDefaultClient(stateDir, os.Getenv)
+	if err != nil {
+		return out.fail(err, 1)
+	}
+	status := daemonStatusOutput{Socket: client.Endpoint(), StateDir: stateDir}
+	exitCode := 0
+	err = client.Health(context.Background())
+	switch {
+	case err == nil:
+		status.Status = "ok"
+		if details, detailsErr := client.Status(context.Background()); detailsErr == nil {
+			status.PID = details.PID
+			status.Version = details.Version
+			status.UptimeSeconds = details.UptimeSeconds
+			status.Details = &details
+		}
+	case daemonctl.IsDaemonStoppedOrUnavailable(err):
+		status.Status = "stopped"
+	default:
+		status.Status = "unavailable"
+		status.Error = err.Error()
+		exitCode = 1
+	}
+
+	if out.json() {
+		if code := out.result(status); code != 0 {
+			return code
+		}
+		return exitCode
+	}
+	if status.Status == "unavailable" {
+		if writeErr := writef(out.stdout, "daemon status: unavailable (%v)\n", err); writeErr != nil {
 			return 1
 		}
-		return 2
+		return exitCode
+	}
+	if err := writef(out.stdout, "daemon status: %s\nstate dir: %s\n", status.Status, stateDir); err != nil {
+		return 1
+	}
+	if status.Status != "ok" {
+		return exitCode
+	}
+	if status.Socket != "" {
+		if err := writef(out.stdout, "socket: %s\n", status.Socket); err != nil {
+			return 1
+		}
+	}
+	if status.PID != 0 {
+		if err := writef(out.stdout, "pid: %d\nuptime: %s\n", status.PID, time.Duration(status.UptimeSeconds)*time.Second); err != nil {
+			return 1
+		}
+	}
+	if status.Version != "" {
+		if err := writef(out.stdout, "version: %s\n", status.Version); err != nil {
+			return 1
+		}
+	}
+	if status.Details != nil {
+		if err := writeDaemonStatusDetails(out, *status.Details); err != nil {
+			return 1
+		}
+	}
+	return exitCode
+}
+
+func writeDaemonStatusDetails(out cliOutput, details daemonclient.StatusResponse) error {
+	var requests, requestErrors uint64
+	for _, route := range details.Routes {
+		requests += route.Requests
+		requestErrors += route.Errors
+	}
+	var blobBytes, blobFiles int64
+	for _, ws := range details.Workspaces {
+		blobBytes += ws.BlobBytes
+		blobFiles += ws.BlobFiles
+	}
+	if err := writef(out.stdout, "open workspaces: %d of %d\n", len(details.OpenWorkspaces), len(details.Workspaces)); err != nil {
+		return err
+	}
+	if err := writef(out.stdout, "blob store: %s in %d files\n", daemonclient.FormatByteSize(blobBytes), blobFiles); err != nil {
+		return err
+	}
+	if err := writef(out.stdout, "requests: %d (%d errors)\n", requests, requestErrors); err != nil {
+		return err
 	}
+	return writef(out.stdout, "sqlite busy retries: %d\nauth failures: %d\n", details.SQLiteBusyRetries, details.AuthFailures)
 }
 
 func resolveStoreRoot(home string) string {
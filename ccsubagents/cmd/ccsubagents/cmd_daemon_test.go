@@ -2,12 +2,17 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/bootstrap"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonclient"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonctl"
 )
@@ -90,7 +95,7 @@ func TestRunDaemonStop_MissingSocket_IsIdempotent(t *testing.T) {
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	code := runDaemon([]string{"stop"}, &stdout, &stderr)
+	code := runDaemon([]string{"stop"}, cliOutput{stdout: &stdout, stderr: &stderr})
 	if code != 0 {
 		t.Fatalf("runDaemon stop exit=%d stderr=%q", code, stderr.String())
 	}
@@ -118,7 +123,7 @@ func TestRunDaemonStop_MissingSocket_StillRunsRegisteredProcessCleanup(t *testin
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	code := runDaemon([]string{"stop"}, &stdout, &stderr)
+	code := runDaemon([]string{"stop"}, cliOutput{stdout: &stdout, stderr: &stderr})
 	if code != 1 {
 		t.Fatalf("runDaemon stop exit=%d stderr=%q", code, stderr.String())
 	}
@@ -132,3 +137,90 @@ func TestRunDaemonStop_MissingSocket_StillRunsRegisteredProcessCleanup(t *testin
 		t.Fatalf("expected empty stdout on failure, got %q", stdout.String())
 	}
 }
+
+func TestRunDaemonStatus_MissingSocket_JSONReportsStopped(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("LOCAL_ARTIFACT_STATE_DIR", stateDir)
+	setMissingDaemonSocket(t)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := runDaemon([]string{"status"}, cliOutput{stdout: &stdout, stderr: &stderr, format: bootstrap.OutputJSON})
+	if code != 0 {
+		t.Fatalf("runDaemon status exit=%d stderr=%q", code, stderr.String())
+	}
+	var got daemonStatusOutput
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("expected JSON status, got %q: %v", stdout.String(), err)
+	}
+	if got.Status != "stopped" || got.StateDir != stateDir || got.Socket == "" {
+		t.Fatalf("unexpected status: %+v", got)
+	}
+}
+
+func TestRunDaemonStatus_PrintsDetailsSummary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket daemon only")
+	}
+	stateDir := t.TempDir()
+	t.Setenv("LOCAL_ARTIFACT_STATE_DIR", stateDir)
+	setMissingDaemonSocket(t)
+	socket := os.Getenv("LOCAL_ARTIFACT_DAEMON_SOCKET")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	writeData := func(w http.ResponseWriter, data any) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"ok": true, "data": data}); err != nil {
+			t.Errorf("encode: %v", err)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/daemon/v1/health", func(w http.ResponseWriter, _ *http.Request) {
+		writeData(w, map[string]any{"status": "ok"})
+	})
+	mux.HandleFunc("/daemon/v1/status", func(w http.ResponseWriter, _ *http.Request) {
+		writeData(w, daemonclient.StatusResponse{
+			Status:         "ok",
+			PID:            42,
+			Version:        "v9",
+			UptimeSeconds:  5,
+			OpenWorkspaces: []string{"global"},
+			Workspaces: []daemonclient.WorkspaceStatus{
+				{WorkspaceID: "global", Open: true, BlobFiles: 3, BlobBytes: 3 << 20},
+				{WorkspaceID: strings.Repeat("a", 64), BlobFiles: 1, BlobBytes: 512},
+			},
+			Routes: []daemonclient.RouteMetrics{
+				{Route: "/daemon/v1/artifacts/get", Requests: 7, Errors: 1},
+				{Route: "/daemon/v1/health", Requests: 3},
+			},
+			SQLiteBusyRetries: 2,
+			AuthFailures:      4,
+		})
+	})
+	srv := &http.Server{Handler: mux}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := runDaemon([]string{"status"}, cliOutput{stdout: &stdout, stderr: &stderr})
+	if code != 0 {
+		t.Fatalf("runDaemon status exit=%d stderr=%q", code, stderr.String())
+	}
+	for _, want := range []string{
+		"daemon status: ok\n",
+		"pid: 42\n",
+		"version: v9\n",
+		"open workspaces: 1 of 2\n",
+		"blob store: 3.0 MiB in 4 files\n",
+		"requests: 10 (1 errors)\n",
+		"sqlite busy retries: 2\n",
+		"auth failures: 4\n",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("status output missing %q:\n%s", want, stdout.String())
+		}
+	}
+}
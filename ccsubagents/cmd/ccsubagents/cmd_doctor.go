@@ -6,58 +6,72 @@ import (
 	"io"
 	"os"
 
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/doctor"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/installer"
 )
 
-func runDoctor(args []string, stdout, stderr io.Writer) int {
+func runDoctor(args []string, out cliOutput) int {
 	fs := flag.NewFlagSet("ccsubagents doctor", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
+	fix := fs.Bool("fix", false, "repair failing checks and re-run them")
 	help := fs.Bool("help", false, "show help")
 	fs.BoolVar(help, "h", false, "show help")
 	if err := fs.Parse(args); err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 2
+		return out.fail(usageError{err: err}, 2)
 	}
 	if *help {
-		if err := writeln(stdout, "Usage: ccsubagents doctor"); err != nil {
+		if err := writeln(out.stdout, "Usage: ccsubagents doctor [--fix]"); err != nil {
 			return 1
 		}
 		return 0
 	}
 	if fs.NArg() > 0 {
-		if err := writef(stderr, "unexpected arguments: %v\n", fs.Args()); err != nil {
-			return 1
-		}
-		return 2
+		return out.fail(newUsageError("unexpected arguments: %v", fs.Args()), 2)
 	}
 
 	home, err := os.UserHomeDir()
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	cwd, err := os.Getwd()
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
+		return out.fail(err, 1)
+	}
+
+	opts := doctor.Options{
+		Home:   home,
+		CWD:    cwd,
+		Out:    out.stdout,
+		Config: installer.NewRunner(),
+		Fix:    *fix,
+		Stderr: out.stderr,
+	}
+	if *fix {
+		settings, err := config.LoadMergedInstallSettings(home, cwd)
+		if err != nil {
+			return out.fail(err, 1)
+		}
+		opts.StoreRoot = resolveStoreRoot(home)
+		opts.DisableAuth = settings.NoAuth
+	}
+	if out.json() {
+		report := doctor.Collect(context.Background(), opts)
+		if *fix && report.Issues > 0 {
+			report = doctor.Repair(context.Background(), opts, report)
+		}
+		if code := out.result(report); code != 0 {
+			return code
+		}
+		if report.Issues > 0 {
 			return 1
 		}
-		return 1
+		return 0
 	}
 
-	issues, err := doctor.Run(context.Background(), doctor.Options{
-		Home: home,
-		CWD:  cwd,
-		Out:  stdout,
-	})
+	issues, err := doctor.Run(context.Background(), opts)
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	if issues > 0 {
 		return 1
@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"flag"
-	"fmt"
 	"io"
 	"os"
 	"strings"
@@ -21,11 +20,28 @@ type lifecycleArgs struct {
 	pinned                bool
 	skipAttestationsCheck bool
 	verbose               bool
+	nonInteractive        bool
+	answers               *bootstrap.Answers
+	dryRun                bool
 	showUsage             bool
 }
 
 func run(args []string, stdout, stderr io.Writer) int {
+	out := cliOutput{stdout: stdout, stderr: stderr, format: bootstrap.OutputText}
+	args, outputRaw, err := extractOutputFlag(args)
+	if err != nil {
+		return out.failWithUsage(err, 2)
+	}
+	format, err := bootstrap.ParseOutputFormat(outputRaw)
+	if err != nil {
+		return out.failWithUsage(err, 2)
+	}
+	out.format = format
+
 	if len(args) == 0 {
+		if out.json() {
+			return out.fail(newUsageError("missing command"), 2)
+		}
 		if err := printUsage(stderr); err != nil {
 			return 1
 		}
@@ -42,37 +58,25 @@ func run(args []string, stdout, stderr io.Writer) int {
 
 	switch command {
 	case "install", "update", "uninstall":
-		return runLifecycle(command, args[1:], stdout, stderr)
+		return runLifecycle(command, args[1:], out)
 	case "doctor":
-		return runDoctor(args[1:], stdout, stderr)
+		return runDoctor(args[1:], out)
 	case "daemon":
-		return runDaemon(args[1:], stdout, stderr)
+		return runDaemon(args[1:], out)
 	case "artifacts":
-		return runArtifacts(args[1:], os.Stdin, stdout, stderr)
+		return runArtifacts(args[1:], os.Stdin, out)
 	default:
-		if err := writef(stderr, "unknown command %q\n", command); err != nil {
-			return 1
-		}
-		if err := printUsage(stderr); err != nil {
-			return 1
-		}
-		return 1
+		return out.failWithUsage(newUsageError("unknown command %q", command), 1)
 	}
 }
 
-func runLifecycle(command string, args []string, stdout, stderr io.Writer) int {
+func runLifecycle(command string, args []string, out cliOutput) int {
 	parsed, err := parseLifecycleArgs(command, args)
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		if writeErr := printUsage(stderr); writeErr != nil {
-			return 1
-		}
-		return 2
+		return out.failWithUsage(err, 2)
 	}
 	if parsed.showUsage {
-		if err := printUsage(stderr); err != nil {
+		if err := printUsage(out.stderr); err != nil {
 			return 1
 		}
 		return 2
@@ -80,41 +84,36 @@ func runLifecycle(command string, args []string, stdout, stderr io.Writer) int {
 
 	parsedCommand, err := bootstrap.ParseCommand(command)
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		if writeErr := printUsage(stderr); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.failWithUsage(err, 1)
 	}
 	scope, err := bootstrap.ResolveScope(parsedCommand, parsed.scopeRaw)
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		if writeErr := printUsage(stderr); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.failWithUsage(err, 1)
 	}
 
+	options := bootstrap.ExecuteOptions{
+		InstallVersion:        parsed.versionRaw,
+		Pinned:                parsed.pinned,
+		SkipAttestationsCheck: parsed.skipAttestationsCheck,
+		Verbose:               parsed.verbose,
+		StatusWriter:          out.stdout,
+		NonInteractive:        parsed.nonInteractive,
+		Answers:               parsed.answers,
+		DryRun:                parsed.dryRun,
+		Output:                out.format,
+	}
+	if out.json() {
+		// Keep stdout a clean JSON stream; prompts move to stderr.
+		options.PromptInput = os.Stdin
+		options.PromptOutput = out.stderr
+	}
 	err = bootstrap.Execute(context.Background(), bootstrap.ExecuteRequest{
 		Command: parsedCommand,
 		Scope:   scope,
-		Options: bootstrap.ExecuteOptions{
-			InstallVersion:        parsed.versionRaw,
-			Pinned:                parsed.pinned,
-			SkipAttestationsCheck: parsed.skipAttestationsCheck,
-			Verbose:               parsed.verbose,
-			StatusWriter:          stdout,
-		},
+		Options: options,
 	})
 	if err != nil {
-		if writeErr := writeln(stderr, err); writeErr != nil {
-			return 1
-		}
-		return 1
+		return out.fail(err, 1)
 	}
 	return 0
 }
@@ -130,23 +129,36 @@ func parseLifecycleArgs(command string, args []string) (lifecycleArgs, error) {
 	scope := fs.String("scope", "", "scope for install lifecycle (local or global)")
 	version := fs.String("version", "", "release version to install (for example v1.2.3)")
 	pinned := fs.Bool("pinned", false, "pin the specified --version in settings.json")
+	yes := fs.Bool("yes", false, "run non-interactively and confirm destructive prompts")
+	targets := fs.String("targets", "", "global install targets (comma-separated)")
+	localMode := fs.String("local-mode", "", "local install mode (personal or team)")
+	answersPath := fs.String("answers", "", "JSON file with answers for install prompts")
+	dryRun := fs.Bool("dry-run", false, "report planned changes without applying them")
 
 	if err := fs.Parse(args); err != nil {
-		return lifecycleArgs{}, err
+		return lifecycleArgs{}, usageError{err: err}
 	}
 	if *help {
 		return lifecycleArgs{showUsage: true}, nil
 	}
 	if fs.NArg() > 0 {
-		return lifecycleArgs{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+		return lifecycleArgs{}, newUsageError("unexpected arguments: %s", strings.Join(fs.Args(), " "))
 	}
 
 	if command != "install" && (strings.TrimSpace(*version) != "" || *pinned) {
-		return lifecycleArgs{}, fmt.Errorf("--version and --pinned can only be used with install")
+		return lifecycleArgs{}, newUsageError("--version and --pinned can only be used with install")
 	}
 	if *pinned && bootstrap.NormalizeInstallVersionTag(*version) == "" {
 		return lifecycleArgs{}, bootstrap.ErrPinnedRequiresVersion
 	}
+	if command != "install" && (strings.TrimSpace(*targets) != "" || strings.TrimSpace(*localMode) != "") {
+		return lifecycleArgs{}, newUsageError("--targets and --local-mode can only be used with install")
+	}
+
+	answers, err := resolveLifecycleAnswers(*answersPath, *targets, *localMode, *yes)
+	if err != nil {
+		return lifecycleArgs{}, err
+	}
 
 	return lifecycleArgs{
 		scopeRaw:              *scope,
@@ -154,9 +166,44 @@ func parseLifecycleArgs(command string, args []string) (lifecycleArgs, error) {
 		pinned:                *pinned,
 		skipAttestationsCheck: *skipAttestationsCheck,
 		verbose:               *verbose,
+		nonInteractive:        *yes || strings.TrimSpace(*answersPath) != "",
+		answers:               answers,
+		dryRun:                *dryRun,
 	}, nil
 }
 
+func resolveLifecycleAnswers(answersPath, targetsRaw, localMode string, yes bool) (*bootstrap.Answers, error) {
+	answers := bootstrap.Answers{}
+	if path := strings.TrimSpace(answersPath); path != "" {
+		loaded, err := bootstrap.LoadAnswersFile(path)
+		if err != nil {
+			return nil, err
+		}
+		answers = loaded
+	}
+	if strings.TrimSpace(targetsRaw) != "" {
+		answers.Targets = nil
+		for _, target := range strings.Split(targetsRaw, ",") {
+			if trimmed := strings.TrimSpace(target); trimmed != "" {
+				answers.Targets = append(answers.Targets, trimmed)
+			}
+		}
+	}
+	if strings.TrimSpace(localMode) != "" {
+		answers.LocalMode = strings.TrimSpace(localMode)
+	}
+	if yes {
+		answers.ConfirmUninstall = true
+	}
+	if err := answers.Validate(); err != nil {
+		return nil, err
+	}
+	if len(answers.Targets) == 0 && answers.LocalMode == "" && answers.Location == "" && !answers.ConfirmUninstall {
+		return nil, nil
+	}
+	return &answers, nil
+}
+
 func printUsage(w io.Writer) error {
 	const usage = `Usage: ccsubagents <command> [options]
 
@@ -165,27 +212,55 @@ Commands:
   update       Update an existing installation to the latest release
   uninstall    Remove installed files and revert configuration changes
   doctor       Run diagnostics for paths, daemon, binaries, and transaction state
+               (--fix repairs what it can and re-runs the checks)
   daemon       Manage daemon lifecycle (status, start, stop)
-  artifacts    Manage daemon artifacts (ls, get, put, openwebui)
+  artifacts    Manage daemon artifacts (ls, get, put, verify,
+               migrate-legacy, rotate-key, backup, restore,
+               identities, share, shares, aliases, openwebui)
+
+Global options:
+  --output=text|json           Output format (default: text). JSON mode writes results to
+                               stdout and errors as {"error":{"code","message"}} to stderr
 
 Lifecycle options (install/update/uninstall):
   --scope=local|global         Installation scope (default: install->local, update/uninstall->global)
   --version=<tag>              Install a specific release tag (install only)
   --pinned                     Save --version as pinned-version in settings.json (install only)
   --skip-attestations-check    Skip release attestation verification
+  --yes                        Run non-interactively; missing answers fail instead of prompting
+  --targets=<list>             Global install targets (install only): stable, insiders,
+                               desktop-stable, desktop-insiders, cursor, claude-desktop,
+                               zed, windsurf, custom:<path>
+  --local-mode=personal|team   Local install mode for git repositories (install only)
+  --answers=<file.json>        Read prompt answers from a JSON file (implies non-interactive)
+  --dry-run                    Report planned file, JSON and ignore-rule changes without applying them
   --verbose                    Show detailed output
   --help, -h                   Show this usage text
 
 Examples:
   ccsubagents install
   ccsubagents update --scope=global
+  ccsubagents install --scope=global --yes --targets=stable,custom:~/dev
+  ccsubagents install --scope=local --dry-run --output=json
   ccsubagents doctor
+  ccsubagents doctor --output=json
+  ccsubagents doctor --fix
   ccsubagents daemon status
   ccsubagents daemon start
   ccsubagents daemon stop
   ccsubagents artifacts ls --workspace-id=global
   ccsubagents artifacts get plan/demo --out=./demo.txt
   ccsubagents artifacts put plan/demo ./demo.txt --mime-type=text/plain
+  ccsubagents artifacts put --ttl=24h scratch/dump ./out.txt
+  ccsubagents artifacts verify --repair
+  ccsubagents artifacts migrate-legacy ~/old-artifacts --dry-run
+  ccsubagents artifacts rotate-key
+  ccsubagents artifacts backup
+  ccsubagents artifacts restore 20261018T120000Z --workspace-id=global
+  ccsubagents artifacts identities
+  ccsubagents artifacts share style/guide --with=*
+  ccsubagents artifacts get style/guide --from=global --workspace-id=<id>
+  ccsubagents artifacts aliases set team --workspace-id=<id>
   ccsubagents artifacts openwebui
 `
 
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"strings"
 	"testing"
 )
@@ -17,6 +18,12 @@ func TestParseLifecycleArgs(t *testing.T) {
 		{name: "update rejects version", command: "update", args: []string{"--version", "v1.2.3"}, wantErr: "can only be used with install"},
 		{name: "pinned requires version", command: "install", args: []string{"--pinned"}, wantErr: "--pinned requires --version"},
 		{name: "unexpected positional", command: "install", args: []string{"extra"}, wantErr: "unexpected arguments"},
+		{name: "install non-interactive targets", command: "install", args: []string{"--yes", "--targets=stable,custom:~/dev", "--local-mode=team"}},
+		{name: "update rejects targets", command: "update", args: []string{"--targets=stable"}, wantErr: "can only be used with install"},
+		{name: "unknown target", command: "install", args: []string{"--targets=emacs"}, wantErr: "unknown install target"},
+		{name: "unknown local mode", command: "install", args: []string{"--local-mode=shared"}, wantErr: "unknown local mode"},
+		{name: "dry run", command: "uninstall", args: []string{"--dry-run"}},
+		{name: "missing answers file", command: "install", args: []string{"--answers=/nonexistent/answers.json"}, wantErr: "read answers file"},
 	}
 
 	for _, tc := range tests {
@@ -79,3 +86,80 @@ func TestRun_UsageAndCommandErrors(t *testing.T) {
 		}
 	})
 }
+
+func TestExtractOutputFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantArgs []string
+		want     string
+		wantErr  bool
+	}{
+		{name: "absent", args: []string{"doctor"}, wantArgs: []string{"doctor"}},
+		{name: "leading inline", args: []string{"--output=json", "daemon", "status"}, wantArgs: []string{"daemon", "status"}, want: "json"},
+		{name: "trailing separate value", args: []string{"artifacts", "ls", "--output", "json"}, wantArgs: []string{"artifacts", "ls"}, want: "json"},
+		{name: "after terminator is kept", args: []string{"artifacts", "put", "--", "--output=json"}, wantArgs: []string{"artifacts", "put", "--", "--output=json"}},
+		{name: "missing value", args: []string{"doctor", "--output"}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args, value, err := extractOutputFlag(tc.args)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(args, " ") != strings.Join(tc.wantArgs, " ") || value != tc.want {
+				t.Fatalf("mismatch: args=%q value=%q", args, value)
+			}
+		})
+	}
+}
+
+func TestRun_JSONOutputReportsErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantExit int
+		wantCode string
+	}{
+		{name: "unknown command", args: []string{"--output=json", "upgrade"}, wantExit: 1, wantCode: errCodeUsage},
+		{name: "pinned without version", args: []string{"install", "--pinned", "--output", "json"}, wantExit: 2, wantCode: errCodePinnedRequiresVersion},
+		{name: "unknown daemon subcommand", args: []string{"daemon", "restart", "--output=json"}, wantExit: 2, wantCode: errCodeUsage},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			exit := run(tc.args, &stdout, &stderr)
+			if exit != tc.wantExit {
+				t.Fatalf("exit mismatch: got=%d want=%d stderr=%q", exit, tc.wantExit, stderr.String())
+			}
+			var got errorOutput
+			if err := json.Unmarshal(stderr.Bytes(), &got); err != nil {
+				t.Fatalf("expected JSON error on stderr, got %q: %v", stderr.String(), err)
+			}
+			if got.Error.Code != tc.wantCode || got.Error.Message == "" {
+				t.Fatalf("unexpected error object: %+v", got)
+			}
+			if stdout.Len() != 0 {
+				t.Fatalf("expected empty stdout, got %q", stdout.String())
+			}
+		})
+	}
+
+	t.Run("unknown output format is a text usage error", func(t *testing.T) {
+		var out bytes.Buffer
+		if exit := run([]string{"doctor", "--output=yaml"}, &out, &out); exit != 2 {
+			t.Fatalf("exit mismatch: got=%d want=2", exit)
+		}
+		if !strings.Contains(out.String(), "unknown output format") {
+			t.Fatalf("expected unknown output format error, got %q", out.String())
+		}
+	})
+}
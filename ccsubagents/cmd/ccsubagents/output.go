@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/bootstrap"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonclient"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonctl"
+)
+
+// Stable error codes reported in --output=json mode.
+const (
+	errCodeUsage                 = "usage"
+	errCodeMissingAnswer         = "missing_answer"
+	errCodePinnedRequiresVersion = "pinned_requires_version"
+	errCodeDaemonUnavailable     = "daemon_unavailable"
+	errCodeInvalidInput          = "invalid_input"
+	errCodeNotFound              = "not_found"
+	errCodeConflict              = "conflict"
+	errCodeUnauthorized          = "unauthorized"
+	errCodeFailed                = "failed"
+)
+
+// cliOutput carries the output streams and the format selected with the
+// global --output flag.
+type cliOutput struct {
+	stdout io.Writer
+	stderr io.Writer
+	format bootstrap.OutputFormat
+}
+
+type errorOutput struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+
+func (e usageError) Unwrap() error { return e.err }
+
+func newUsageError(format string, args ...any) error {
+	return usageError{err: fmt.Errorf(format, args...)}
+}
+
+func (o cliOutput) json() bool {
+	return o.format == bootstrap.OutputJSON
+}
+
+// result writes v to stdout as a single JSON document.
+func (o cliOutput) result(v any) int {
+	if err := json.NewEncoder(o.stdout).Encode(v); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// fail reports err on stderr, as a JSON error object in JSON mode, and
+// returns exitCode.
+func (o cliOutput) fail(err error, exitCode int) int {
+	if o.json() {
+		body := errorBody{Code: errorCode(err), Message: err.Error()}
+		if writeErr := json.NewEncoder(o.stderr).Encode(errorOutput{Error: body}); writeErr != nil {
+			return 1
+		}
+		return exitCode
+	}
+	if writeErr := writeln(o.stderr, err); writeErr != nil {
+		return 1
+	}
+	return exitCode
+}
+
+// failWithUsage is fail for argument errors; text mode follows the error with
+// the full usage text.
+func (o cliOutput) failWithUsage(err error, exitCode int) int {
+	var usage usageError
+	if !errors.As(err, &usage) {
+		err = usageError{err: err}
+	}
+	code := o.fail(err, exitCode)
+	if o.json() || code != exitCode {
+		return code
+	}
+	if writeErr := printUsage(o.stderr); writeErr != nil {
+		return 1
+	}
+	return exitCode
+}
+
+func errorCode(err error) string {
+	if errors.Is(err, bootstrap.ErrPinnedRequiresVersion) {
+		return errCodePinnedRequiresVersion
+	}
+	if errors.Is(err, bootstrap.ErrMissingAnswer) {
+		return errCodeMissingAnswer
+	}
+	var usage usageError
+	if errors.As(err, &usage) {
+		return errCodeUsage
+	}
+	var remote *daemonclient.RemoteError
+	if errors.As(err, &remote) {
+		if daemonctl.IsDaemonStoppedOrUnavailable(err) {
+			return errCodeDaemonUnavailable
+		}
+		switch remote.Code {
+		case daemonclient.CodeServiceUnavailable:
+			return errCodeDaemonUnavailable
+		case daemonclient.CodeInvalidInput:
+			return errCodeInvalidInput
+		case daemonclient.CodeNotFound:
+			return errCodeNotFound
+		case daemonclient.CodeConflict:
+			return errCodeConflict
+		case daemonclient.CodeUnauthorized:
+			return errCodeUnauthorized
+		}
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return errCodeNotFound
+	}
+	return errCodeFailed
+}
+
+// extractOutputFlag removes the global --output flag from anywhere before a
+// "--" terminator and returns the remaining arguments and its value.
+func extractOutputFlag(args []string) ([]string, string, error) {
+	rest := make([]string, 0, len(args))
+	value := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, inline, hasInline := strings.Cut(arg, "=")
+		if name != "--output" && name != "-output" {
+			rest = append(rest, arg)
+			continue
+		}
+		if hasInline {
+			value = inline
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, "", newUsageError("flag needs an argument: -output")
+		}
+		i++
+		value = args[i]
+	}
+	return rest, value, nil
+}
@@ -0,0 +1,9 @@
+package bootstrap
+
+import "github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/installer"
+
+type Answers = installer.Answers
+
+func LoadAnswersFile(path string) (Answers, error) {
+	return installer.LoadAnswersFile(path)
+}
@@ -11,6 +11,8 @@ type Command = installer.Command
 
 type Scope = installer.Scope
 
+type OutputFormat = installer.OutputFormat
+
 const (
 	CommandInstall   Command = installer.CommandInstall
 	CommandUpdate    Command = installer.CommandUpdate
@@ -18,6 +20,9 @@ const (
 
 	ScopeLocal  Scope = installer.ScopeLocal
 	ScopeGlobal Scope = installer.ScopeGlobal
+
+	OutputText OutputFormat = installer.OutputText
+	OutputJSON OutputFormat = installer.OutputJSON
 )
 
 const (
@@ -68,3 +73,7 @@ func ResolveScope(command Command, raw string) (Scope, error) {
 	}
 	return ParseScope(trimmed)
 }
+
+func ParseOutputFormat(raw string) (OutputFormat, error) {
+	return installer.ParseOutputFormat(raw)
+}
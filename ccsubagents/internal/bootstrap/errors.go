@@ -2,4 +2,7 @@ package bootstrap
 
 import "github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/installer"
 
-var ErrPinnedRequiresVersion = installer.ErrPinnedRequiresVersion
+var (
+	ErrPinnedRequiresVersion = installer.ErrPinnedRequiresVersion
+	ErrMissingAnswer         = installer.ErrMissingAnswer
+)
@@ -14,6 +14,10 @@ type executeManager interface {
 	SetInstallPromptIO(io.Reader, io.Writer)
 	SetInstallVersion(string)
 	SetPinned(bool)
+	SetAnswers(Answers)
+	SetNonInteractive(bool)
+	SetDryRun(bool)
+	SetOutputFormat(OutputFormat)
 	Run(context.Context, Command, Scope) error
 }
 
@@ -35,6 +39,10 @@ type ExecuteOptions struct {
 	StatusWriter          io.Writer
 	PromptInput           io.Reader
 	PromptOutput          io.Writer
+	NonInteractive        bool
+	Answers               *Answers
+	DryRun                bool
+	Output                OutputFormat
 }
 
 func Execute(ctx context.Context, request ExecuteRequest) error {
@@ -43,6 +51,12 @@ func Execute(ctx context.Context, request ExecuteRequest) error {
 	manager.SetPinned(request.Options.Pinned)
 	manager.SetSkipAttestationsCheck(request.Options.SkipAttestationsCheck)
 	manager.SetVerbose(request.Options.Verbose)
+	manager.SetNonInteractive(request.Options.NonInteractive)
+	manager.SetDryRun(request.Options.DryRun)
+	manager.SetOutputFormat(request.Options.Output)
+	if request.Options.Answers != nil {
+		manager.SetAnswers(*request.Options.Answers)
+	}
 
 	if request.Options.StatusWriter != nil {
 		manager.SetStatusWriter(request.Options.StatusWriter)
@@ -10,6 +10,7 @@ import (
 
 type executeManagerRecorder struct {
 	got                ExecuteOptions
+	answers            Answers
 	statusWriterCalled bool
 	promptIOCalled     bool
 	runCalled          bool
@@ -25,6 +26,13 @@ func (m *executeManagerRecorder) SetSkipAttestationsCheck(skip bool) {
 func (m *executeManagerRecorder) SetVerbose(verbose bool)          { m.got.Verbose = verbose }
 func (m *executeManagerRecorder) SetInstallVersion(version string) { m.got.InstallVersion = version }
 func (m *executeManagerRecorder) SetPinned(pinned bool)            { m.got.Pinned = pinned }
+func (m *executeManagerRecorder) SetNonInteractive(enabled bool)   { m.got.NonInteractive = enabled }
+func (m *executeManagerRecorder) SetDryRun(dryRun bool)            { m.got.DryRun = dryRun }
+func (m *executeManagerRecorder) SetOutputFormat(f OutputFormat)   { m.got.Output = f }
+func (m *executeManagerRecorder) SetAnswers(answers Answers) {
+	m.answers = answers
+	m.got.Answers = &m.answers
+}
 func (m *executeManagerRecorder) SetStatusWriter(writer io.Writer) {
 	m.statusWriterCalled, m.got.StatusWriter = true, writer
 }
@@ -67,6 +75,8 @@ func TestExecute(t *testing.T) {
 					StatusWriter:          statusOut,
 					PromptInput:           promptIn,
 					PromptOutput:          promptOut,
+					DryRun:                true,
+					Output:                OutputJSON,
 				},
 			},
 			runErr:           runErr,
@@ -103,3 +113,24 @@ func TestExecute(t *testing.T) {
 		}
 	}
 }
+
+func TestExecute_ForwardsNonInteractiveAnswers(t *testing.T) {
+	stub := &executeManagerRecorder{}
+	useExecuteManager(t, stub)
+
+	answers := &Answers{Targets: []string{"stable", "custom:~/dev"}, LocalMode: "team", ConfirmUninstall: true}
+	err := Execute(context.Background(), ExecuteRequest{
+		Command: CommandInstall,
+		Scope:   ScopeGlobal,
+		Options: ExecuteOptions{NonInteractive: true, Answers: answers},
+	})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !stub.got.NonInteractive {
+		t.Fatalf("expected non-interactive mode forwarded")
+	}
+	if stub.got.Answers == nil || stub.answers.LocalMode != "team" || !stub.answers.ConfirmUninstall || strings.Join(stub.answers.Targets, ",") != "stable,custom:~/dev" {
+		t.Fatalf("unexpected forwarded answers: %#v", stub.answers)
+	}
+}
@@ -17,25 +17,61 @@ const (
 )
 
 func ApplySettingsEdit(settingsPath, agentsDir string, previous *state.TrackedState, filePerm os.FileMode) (state.SettingsEdit, error) {
-	var previousEdit state.SettingsEdit
-	hasPreviousEdit := false
-	if previous != nil {
-		if matched, ok := previous.JSONEdits.SettingsEditForFile(settingsPath); ok {
-			previousEdit = matched
-			hasPreviousEdit = true
-		}
-	}
+	previousEdit, hasPreviousEdit := previousSettingsEdit(previous, settingsPath)
 
-	root, err := readJSONFile(settingsPath)
+	doc, err := readJSONCDocumentOrEmpty(settingsPath)
+	if err != nil {
+		return state.SettingsEdit{}, fmt.Errorf("read settings.json: %w", err)
+	}
+	root, err := doc.decode()
 	if err != nil {
 		return state.SettingsEdit{}, fmt.Errorf("read settings.json: %w", err)
 	}
 
+	_, hadLocations := root[SettingsAgentPathKey]
+	added, err := editSettingsRoot(root, agentsDir, previousEdit, hasPreviousEdit)
+	if err != nil {
+		return state.SettingsEdit{}, err
+	}
+	if hasPreviousEdit {
+		previousPath := strings.TrimSpace(previousEdit.AgentPath)
+		if previousPath != "" && previousPath != agentsDir {
+			if _, err := doc.remove([]string{SettingsAgentPathKey, previousPath}); err != nil {
+				return state.SettingsEdit{}, fmt.Errorf("edit settings.json: %w", err)
+			}
+		}
+	}
+	if added {
+		if err := doc.set([]string{SettingsAgentPathKey, agentsDir}, true); err != nil {
+			return state.SettingsEdit{}, fmt.Errorf("edit settings.json: %w", err)
+		}
+	}
+
+	if err := doc.write(settingsPath, filePerm); err != nil {
+		return state.SettingsEdit{}, fmt.Errorf("write settings.json: %w", err)
+	}
+
+	edit := state.SettingsEdit{File: settingsPath, AgentPath: agentsDir, Added: added, CreatedLocations: added && !hadLocations}
+	if hasPreviousEdit && previousEdit.Added {
+		edit.Added = true
+		edit.CreatedLocations = previousEdit.CreatedLocations
+	}
+	return edit, nil
+}
+
+func previousSettingsEdit(previous *state.TrackedState, settingsPath string) (state.SettingsEdit, bool) {
+	if previous == nil {
+		return state.SettingsEdit{}, false
+	}
+	return previous.JSONEdits.SettingsEditForFile(settingsPath)
+}
+
+func editSettingsRoot(root map[string]any, agentsDir string, previousEdit state.SettingsEdit, hasPreviousEdit bool) (bool, error) {
 	added := false
 	if current, exists := root[SettingsAgentPathKey]; exists {
 		locations, ok := current.(map[string]any)
 		if !ok {
-			return state.SettingsEdit{}, fmt.Errorf("settings %s must be an object when present", SettingsAgentPathKey)
+			return false, fmt.Errorf("settings %s must be an object when present", SettingsAgentPathKey)
 		}
 		if hasPreviousEdit {
 			previousPath := strings.TrimSpace(previousEdit.AgentPath)
@@ -54,77 +90,137 @@ func ApplySettingsEdit(settingsPath, agentsDir string, previous *state.TrackedSt
 
 	if chatRaw, chatExists := root["chat"]; chatExists {
 		if _, ok := chatRaw.(map[string]any); !ok {
-			return state.SettingsEdit{}, errors.New("settings key chat must be an object when present")
+			return false, errors.New("settings key chat must be an object when present")
 		}
 	}
-
-	if err := writeJSONFile(settingsPath, root, filePerm); err != nil {
-		return state.SettingsEdit{}, fmt.Errorf("write settings.json: %w", err)
-	}
-
-	wasAdded := added
-	if hasPreviousEdit && previousEdit.Added {
-		wasAdded = true
-	}
-	return state.SettingsEdit{File: settingsPath, AgentPath: agentsDir, Added: wasAdded}, nil
+	return added, nil
 }
 
 func RevertSettingsEdit(edit state.SettingsEdit, filePerm os.FileMode) error {
 	if !edit.Added {
 		return nil
 	}
-	root, err := readJSONFile(edit.File)
+	doc, err := readJSONCDocument(edit.File)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
 		return fmt.Errorf("read settings.json for uninstall: %w", err)
 	}
+	root, err := doc.decode()
+	if err != nil {
+		return fmt.Errorf("read settings.json for uninstall: %w", err)
+	}
+
+	changed, err := revertSettingsRoot(root, edit)
+	if err != nil || !changed {
+		return err
+	}
+	if _, err := doc.remove([]string{SettingsAgentPathKey, edit.AgentPath}); err != nil {
+		return fmt.Errorf("edit settings.json during uninstall: %w", err)
+	}
+	if edit.CreatedLocations && doc.emptyObject([]string{SettingsAgentPathKey}) {
+		if _, err := doc.remove([]string{SettingsAgentPathKey}); err != nil {
+			return fmt.Errorf("edit settings.json during uninstall: %w", err)
+		}
+	}
+
+	if err := doc.write(edit.File, filePerm); err != nil {
+		return fmt.Errorf("write settings.json during uninstall: %w", err)
+	}
+	return nil
+}
 
+func revertSettingsRoot(root map[string]any, edit state.SettingsEdit) (bool, error) {
 	locationsRaw, ok := root[SettingsAgentPathKey]
 	if !ok {
-		return nil
+		return false, nil
 	}
 	locations, ok := locationsRaw.(map[string]any)
 	if !ok {
-		return fmt.Errorf("settings %s must be an object when present", SettingsAgentPathKey)
+		return false, fmt.Errorf("settings %s must be an object when present", SettingsAgentPathKey)
 	}
 	if _, exists := locations[edit.AgentPath]; !exists {
-		return nil
+		return false, nil
 	}
 	delete(locations, edit.AgentPath)
-
-	if err := writeJSONFile(edit.File, root, filePerm); err != nil {
-		return fmt.Errorf("write settings.json during uninstall: %w", err)
+	if edit.CreatedLocations && len(locations) == 0 {
+		delete(root, SettingsAgentPathKey)
 	}
-	return nil
+	return true, nil
 }
 
 func ApplyMCPEdit(path, commandPath string, previous *state.TrackedState, filePerm os.FileMode) (state.MCPEdit, error) {
-	root, err := readJSONFile(path)
+	return ApplyMCPEditWithFormat(path, VSCodeMCPFormat, commandPath, previous, filePerm)
+}
+
+func ApplyMCPEditWithFormat(path string, format MCPFormat, commandPath string, previous *state.TrackedState, filePerm os.FileMode) (state.MCPEdit, error) {
+	entry, err := format.entry(commandPath)
+	if err != nil {
+		return state.MCPEdit{}, err
+	}
+	return applyMCPEntry(path, format, entry, previous, filePerm)
+}
+
+// ApplyMCPURLEdit registers the Streamable HTTP endpoint at url instead of a
+// command, authenticating with auth.
+func ApplyMCPURLEdit(path string, format MCPFormat, url string, auth MCPHTTPAuth, previous *state.TrackedState, filePerm os.FileMode) (state.MCPEdit, error) {
+	entry, err := format.urlEntry(url, auth)
+	if err != nil {
+		return state.MCPEdit{}, err
+	}
+	return applyMCPEntry(path, format, entry, previous, filePerm)
+}
+
+func applyMCPEntry(path string, format MCPFormat, entry map[string]any, previous *state.TrackedState, filePerm os.FileMode) (state.MCPEdit, error) {
+	doc, err := readJSONCDocumentOrEmpty(path)
+	if err != nil {
+		return state.MCPEdit{}, fmt.Errorf("read mcp.json: %w", err)
+	}
+	root, err := doc.decode()
 	if err != nil {
 		return state.MCPEdit{}, fmt.Errorf("read mcp.json: %w", err)
 	}
 
-	servers, err := ensureObject(root, "servers")
+	keys := []string{format.serversKey(), MCPServerKey}
+	existingText, _ := doc.rawValue(keys)
+	edit, err := editMCPRoot(root, path, format, entry, previous, existingText)
 	if err != nil {
-		return state.MCPEdit{}, fmt.Errorf("mcp key servers: %w", err)
+		return state.MCPEdit{}, err
+	}
+	if err := doc.set(keys, entry); err != nil {
+		return state.MCPEdit{}, fmt.Errorf("edit mcp.json: %w", err)
 	}
 
-	edit := state.MCPEdit{File: path, Key: MCPServerKey, Touched: true}
-	if prev := previous; prev != nil {
-		if matched, ok := prev.JSONEdits.MCPEditForFile(path); ok && matched.Touched {
-			edit.HadPrevious = matched.HadPrevious
-			if len(matched.Previous) > 0 {
-				edit.Previous = slices.Clone(matched.Previous)
-			}
-		} else if existing, ok := servers[MCPServerKey]; ok {
-			encoded, err := json.Marshal(existing)
-			if err != nil {
-				return state.MCPEdit{}, fmt.Errorf("marshal existing mcp server config: %w", err)
-			}
-			edit.HadPrevious = true
-			edit.Previous = encoded
+	if err := doc.write(path, filePerm); err != nil {
+		return state.MCPEdit{}, fmt.Errorf("write mcp.json: %w", err)
+	}
+
+	return edit, nil
+}
+
+// editMCPRoot registers the server in root and describes the edit.
+// existingText is the current entry as written in the file, if any.
+func editMCPRoot(root map[string]any, path string, format MCPFormat, entry map[string]any, previous *state.TrackedState, existingText string) (state.MCPEdit, error) {
+	serversKey := format.serversKey()
+	_, hadServers := root[serversKey]
+	servers, err := ensureObject(root, serversKey)
+	if err != nil {
+		return state.MCPEdit{}, fmt.Errorf("mcp key %s: %w", serversKey, err)
+	}
+
+	edit := state.MCPEdit{File: path, ServersKey: serversKey, Key: MCPServerKey, Touched: true, CreatedServers: !hadServers}
+	matched, hasMatched := state.MCPEdit{}, false
+	if previous != nil {
+		matched, hasMatched = previous.JSONEdits.MCPEditForFile(path)
+		hasMatched = hasMatched && matched.Touched
+	}
+	if hasMatched {
+		edit.HadPrevious = matched.HadPrevious
+		edit.PreviousText = matched.PreviousText
+		edit.CreatedServers = matched.CreatedServers
+		if len(matched.Previous) > 0 {
+			edit.Previous = slices.Clone(matched.Previous)
 		}
 	} else if existing, ok := servers[MCPServerKey]; ok {
 		encoded, err := json.Marshal(existing)
@@ -133,16 +229,10 @@ func ApplyMCPEdit(path, commandPath string, previous *state.TrackedState, filePe
 		}
 		edit.HadPrevious = true
 		edit.Previous = encoded
+		edit.PreviousText = existingText
 	}
 
-	servers[MCPServerKey] = map[string]any{
-		"command": commandPath,
-	}
-
-	if err := writeJSONFile(path, root, filePerm); err != nil {
-		return state.MCPEdit{}, fmt.Errorf("write mcp.json: %w", err)
-	}
-
+	servers[MCPServerKey] = entry
 	return edit, nil
 }
 
@@ -150,17 +240,54 @@ func RevertMCPEdit(edit state.MCPEdit, filePerm os.FileMode) error {
 	if !edit.Touched {
 		return nil
 	}
-	root, err := readJSONFile(edit.File)
+	doc, err := readJSONCDocument(edit.File)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
 		return fmt.Errorf("read mcp.json for uninstall: %w", err)
 	}
+	root, err := doc.decode()
+	if err != nil {
+		return fmt.Errorf("read mcp.json for uninstall: %w", err)
+	}
 
-	servers, err := ensureObject(root, "servers")
+	if err := revertMCPRoot(root, edit); err != nil {
+		return err
+	}
+	serversKey := mcpServersKeyForEdit(edit.ServersKey)
+	keys := []string{serversKey, edit.Key}
+	_, present := doc.lookup(keys)
+	switch {
+	case edit.HadPrevious && edit.PreviousText != "" && present:
+		err = doc.setRaw(keys, edit.PreviousText)
+	case edit.HadPrevious:
+		var restored any
+		if err := json.Unmarshal(edit.Previous, &restored); err != nil {
+			return fmt.Errorf("decode tracked previous mcp value: %w", err)
+		}
+		err = doc.set(keys, restored)
+	default:
+		_, err = doc.remove(keys)
+		if err == nil && edit.CreatedServers && doc.emptyObject([]string{serversKey}) {
+			_, err = doc.remove([]string{serversKey})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("edit mcp.json during uninstall: %w", err)
+	}
+
+	if err := doc.write(edit.File, filePerm); err != nil {
+		return fmt.Errorf("write mcp.json during uninstall: %w", err)
+	}
+	return nil
+}
+
+func revertMCPRoot(root map[string]any, edit state.MCPEdit) error {
+	serversKey := mcpServersKeyForEdit(edit.ServersKey)
+	servers, err := ensureObject(root, serversKey)
 	if err != nil {
-		return fmt.Errorf("mcp key servers: %w", err)
+		return fmt.Errorf("mcp key %s: %w", serversKey, err)
 	}
 
 	if edit.HadPrevious {
@@ -174,10 +301,33 @@ func RevertMCPEdit(edit state.MCPEdit, filePerm os.FileMode) error {
 		servers[edit.Key] = restored
 	} else {
 		delete(servers, edit.Key)
+		if edit.CreatedServers && len(servers) == 0 {
+			delete(root, serversKey)
+		}
 	}
+	return nil
+}
 
-	if err := writeJSONFile(edit.File, root, filePerm); err != nil {
-		return fmt.Errorf("write mcp.json during uninstall: %w", err)
+// SettingsEditPresent reports whether the agents path recorded by edit is still
+// listed in its settings file.
+func SettingsEditPresent(edit state.SettingsEdit) (bool, error) {
+	return jsonKeyPresent(edit.File, []string{SettingsAgentPathKey, edit.AgentPath})
+}
+
+// MCPEditPresent reports whether the server entry recorded by edit is still
+// registered in its MCP config file.
+func MCPEditPresent(edit state.MCPEdit) (bool, error) {
+	return jsonKeyPresent(edit.File, []string{mcpServersKeyForEdit(edit.ServersKey), edit.Key})
+}
+
+func jsonKeyPresent(path string, keys []string) (bool, error) {
+	root, err := readJSONFile(path)
+	if err != nil {
+		return false, fmt.Errorf("read %s: %w", path, err)
 	}
-	return nil
+	value, err := marshalJSONValueAt(root, keys)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
 }
@@ -33,15 +33,6 @@ func readJSONFile(path string) (map[string]any, error) {
 	return root, nil
 }
 
-func writeJSONFile(path string, v map[string]any, perm os.FileMode) error {
-	data, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		return err
-	}
-	data = append(data, '\n')
-	return os.WriteFile(path, data, perm)
-}
-
 func ensureObject(root map[string]any, key string) (map[string]any, error) {
 	v, exists := root[key]
 	if !exists {
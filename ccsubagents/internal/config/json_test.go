@@ -1,9 +1,9 @@
 package config
 
 import (
-	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -58,12 +58,8 @@ func TestApplySettingsEdit_AcceptsVSCodeSettingsWithTrailingComma(t *testing.T)
 		t.Fatalf("apply settings edit: %v", err)
 	}
 
-	b, err := os.ReadFile(path)
+	root, err := readJSONFile(path)
 	if err != nil {
-		t.Fatalf("read updated settings: %v", err)
-	}
-	var root map[string]any
-	if err := json.Unmarshal(b, &root); err != nil {
 		t.Fatalf("decode updated settings: %v", err)
 	}
 	locations, ok := root[SettingsAgentPathKey].(map[string]any)
@@ -74,3 +70,59 @@ func TestApplySettingsEdit_AcceptsVSCodeSettingsWithTrailingComma(t *testing.T)
 		t.Fatalf("expected managed agent path to be added, got %#v", locations)
 	}
 }
+
+func TestApplyMCPURLEdit_RegistersHTTPURLEntries(t *testing.T) {
+	const url = "http://127.0.0.1:19132/mcp"
+	headers := map[string]any{"Authorization": "Bearer secret"}
+	cases := []struct {
+		name   string
+		format MCPFormat
+		want   map[string]any
+	}{
+		{name: "vscode", format: VSCodeMCPFormat, want: map[string]any{"type": "http", "url": url, "headers": headers}},
+		{name: "mcpServers", format: MCPServersFormat, want: map[string]any{"url": url, "headers": headers}},
+		{name: "zed", format: ZedMCPFormat, want: map[string]any{"url": url, "headers": headers}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mcp.json")
+			if _, err := ApplyMCPURLEdit(path, tc.format, url, MCPHTTPAuth{Token: "secret"}, nil, 0o644); err != nil {
+				t.Fatalf("apply mcp edit: %v", err)
+			}
+			root, err := readJSONFile(path)
+			if err != nil {
+				t.Fatalf("read mcp: %v", err)
+			}
+			servers, _ := root[tc.format.serversKey()].(map[string]any)
+			if got := servers[MCPServerKey]; !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %#v, got %#v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyMCPURLEdit_RequiresTokenUnlessNoAuth(t *testing.T) {
+	const url = "http://127.0.0.1:19132/mcp"
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	if _, err := ApplyMCPURLEdit(path, VSCodeMCPFormat, url, MCPHTTPAuth{}, nil, 0o644); err == nil {
+		t.Fatal("expected a URL entry without a token to be refused")
+	}
+	if _, err := ApplyMCPEditWithFormat(path, VSCodeMCPFormat, url, nil, 0o644); err == nil {
+		t.Fatal("expected a URL target passed as a command path to be refused")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("refused edits must not write %s, stat err: %v", path, err)
+	}
+
+	if _, err := ApplyMCPURLEdit(path, VSCodeMCPFormat, url, MCPHTTPAuth{NoAuth: true}, nil, 0o644); err != nil {
+		t.Fatalf("apply no-auth url edit: %v", err)
+	}
+	root, err := readJSONFile(path)
+	if err != nil {
+		t.Fatalf("read mcp: %v", err)
+	}
+	servers, _ := root["servers"].(map[string]any)
+	if got, want := servers[MCPServerKey], map[string]any{"type": "http", "url": url}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %#v, got %#v", want, got)
+	}
+}
@@ -0,0 +1,542 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// jsoncValue is a parsed JSONC value that remembers where it sits in the
+// source, so edits can splice text and leave everything else untouched.
+type jsoncValue struct {
+	start   int
+	end     int
+	object  bool
+	members []jsoncMember
+}
+
+type jsoncMember struct {
+	key      string
+	keyStart int
+	value    *jsoncValue
+	// comma is the offset of the comma after the value, or -1.
+	comma int
+}
+
+func (v *jsoncValue) member(key string) (jsoncMember, bool) {
+	for _, m := range v.members {
+		if m.key == key {
+			return m, true
+		}
+	}
+	return jsoncMember{}, false
+}
+
+// jsoncDocument edits a JSONC file in place: inserted members copy the
+// indentation around them, and removing a member that was inserted here gives
+// back the original text byte for byte.
+type jsoncDocument struct {
+	src        []byte
+	root       *jsoncValue
+	newline    string
+	indentUnit string
+}
+
+func readJSONCDocument(path string) (*jsoncDocument, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseJSONCDocument(b)
+}
+
+// readJSONCDocumentOrEmpty is readJSONCDocument for files an edit may create.
+func readJSONCDocumentOrEmpty(path string) (*jsoncDocument, error) {
+	doc, err := readJSONCDocument(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return parseJSONCDocument(nil)
+	}
+	return doc, err
+}
+
+func parseJSONCDocument(src []byte) (*jsoncDocument, error) {
+	if len(bytes.TrimSpace(bytes.TrimPrefix(src, utf8BOM))) == 0 {
+		src = []byte("{}\n")
+	}
+	doc := &jsoncDocument{newline: "\n"}
+	if bytes.Contains(src, []byte("\r\n")) {
+		doc.newline = "\r\n"
+	}
+	if err := doc.reset(src); err != nil {
+		return nil, err
+	}
+	doc.indentUnit = "  "
+	if len(doc.root.members) > 0 {
+		first := doc.root.members[0].keyStart
+		if indent := doc.lineIndent(first); indent != "" && doc.startsLine(first) {
+			doc.indentUnit = indent
+		}
+	}
+	return doc, nil
+}
+
+func (d *jsoncDocument) reset(src []byte) error {
+	root, err := parseJSONC(src)
+	if err != nil {
+		return err
+	}
+	if !root.object {
+		return errors.New("top-level JSON value must be an object")
+	}
+	d.src = src
+	d.root = root
+	return nil
+}
+
+func (d *jsoncDocument) write(path string, perm os.FileMode) error {
+	return os.WriteFile(path, d.src, perm)
+}
+
+// decode returns the document as a plain map, comments dropped.
+func (d *jsoncDocument) decode() (map[string]any, error) {
+	normalized, err := normalizeJSONC(d.src)
+	if err != nil {
+		return nil, err
+	}
+	root := map[string]any{}
+	if err := json.Unmarshal(normalized, &root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// lookup returns the value at keys, or false when any key along the way is
+// missing or not an object.
+func (d *jsoncDocument) lookup(keys []string) (*jsoncValue, bool) {
+	current := d.root
+	for _, key := range keys {
+		if !current.object {
+			return nil, false
+		}
+		m, ok := current.member(key)
+		if !ok {
+			return nil, false
+		}
+		current = m.value
+	}
+	return current, true
+}
+
+func (d *jsoncDocument) emptyObject(keys []string) bool {
+	v, ok := d.lookup(keys)
+	return ok && v.object && len(v.members) == 0
+}
+
+// rawValue returns the source text of the value at keys.
+func (d *jsoncDocument) rawValue(keys []string) (string, bool) {
+	v, ok := d.lookup(keys)
+	if !ok {
+		return "", false
+	}
+	return string(d.src[v.start:v.end]), true
+}
+
+// set writes value at keys, inserting any missing objects along the way.
+func (d *jsoncDocument) set(keys []string, value any) error {
+	obj := d.root
+	for i, key := range keys {
+		m, ok := obj.member(key)
+		if !ok {
+			nested := value
+			for j := len(keys) - 1; j > i; j-- {
+				nested = map[string]any{keys[j]: nested}
+			}
+			return d.insertMember(obj, key, nested)
+		}
+		if i == len(keys)-1 {
+			text, err := d.marshalValue(value, d.lineIndent(m.keyStart))
+			if err != nil {
+				return err
+			}
+			return d.splice(m.value.start, m.value.end, text)
+		}
+		if !m.value.object {
+			return fmt.Errorf("%s must be an object when present", key)
+		}
+		obj = m.value
+	}
+	return nil
+}
+
+// setRaw replaces the value at keys with text taken verbatim from an earlier
+// version of the file.
+func (d *jsoncDocument) setRaw(keys []string, text string) error {
+	v, ok := d.lookup(keys)
+	if !ok {
+		return fmt.Errorf("%s is missing", jsonPointer(keys))
+	}
+	if _, err := parseJSONC([]byte(text)); err != nil {
+		return fmt.Errorf("decode previous value for %s: %w", jsonPointer(keys), err)
+	}
+	return d.splice(v.start, v.end, []byte(text))
+}
+
+// remove deletes the member at keys along with the separator and comments on
+// its line, and reports whether it was present.
+func (d *jsoncDocument) remove(keys []string) (bool, error) {
+	if len(keys) == 0 {
+		return false, errors.New("cannot remove the top-level value")
+	}
+	parent, ok := d.lookup(keys[:len(keys)-1])
+	if !ok || !parent.object {
+		return false, nil
+	}
+	index := -1
+	for i, m := range parent.members {
+		if m.key == keys[len(keys)-1] {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return false, nil
+	}
+
+	m := parent.members[index]
+	start := d.whitespaceBefore(m.keyStart)
+	if m.comma >= 0 {
+		return true, d.splice(start, d.lineRest(m.comma+1), nil)
+	}
+	end := d.lineRest(m.value.end)
+	if index > 0 {
+		prevComma := parent.members[index-1].comma
+		src := make([]byte, 0, len(d.src))
+		src = append(src, d.src[:prevComma]...)
+		src = append(src, d.src[prevComma+1:start]...)
+		src = append(src, d.src[end:]...)
+		return true, d.reset(src)
+	}
+	// Removing the only member: fold `{\n<indent>}` back to the `{}` it was
+	// expanded from.
+	closeBrace := parent.end - 1
+	if string(d.src[parent.start+1:start])+string(d.src[end:closeBrace]) == d.newline+d.lineIndent(parent.start) {
+		return true, d.splice(parent.start+1, closeBrace, nil)
+	}
+	return true, d.splice(start, end, nil)
+}
+
+func (d *jsoncDocument) insertMember(obj *jsoncValue, key string, value any) error {
+	parentIndent := d.lineIndent(obj.start)
+	if len(obj.members) == 0 {
+		childIndent := parentIndent + d.indentUnit
+		member, err := d.marshalMember(key, value, childIndent)
+		if err != nil {
+			return err
+		}
+		text := append([]byte(d.newline+childIndent), member...)
+		inner := d.src[obj.start+1 : obj.end-1]
+		if bytes.ContainsAny(inner, "\r\n") {
+			return d.splice(obj.start+1, obj.start+1, text)
+		}
+		text = append(text, d.newline+parentIndent...)
+		if len(bytes.TrimSpace(inner)) == 0 {
+			return d.splice(obj.start+1, obj.end-1, text)
+		}
+		return d.splice(obj.start+1, obj.start+1, text)
+	}
+
+	last := obj.members[len(obj.members)-1]
+	sep := " "
+	childIndent := parentIndent + d.indentUnit
+	if d.startsLine(last.keyStart) {
+		childIndent = d.lineIndent(last.keyStart)
+		sep = d.newline + childIndent
+	}
+	member, err := d.marshalMember(key, value, childIndent)
+	if err != nil {
+		return err
+	}
+	if last.comma >= 0 {
+		at := d.lineRest(last.comma + 1)
+		text := append(append([]byte(sep), member...), ',')
+		return d.splice(at, at, text)
+	}
+	at := d.lineRest(last.value.end)
+	src := make([]byte, 0, len(d.src)+len(sep)+len(member)+1)
+	src = append(src, d.src[:last.value.end]...)
+	src = append(src, ',')
+	src = append(src, d.src[last.value.end:at]...)
+	src = append(src, sep...)
+	src = append(src, member...)
+	src = append(src, d.src[at:]...)
+	return d.reset(src)
+}
+
+func (d *jsoncDocument) marshalMember(key string, value any, indent string) ([]byte, error) {
+	encodedKey, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	encodedValue, err := d.marshalValue(value, indent)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(encodedKey, ": "...), encodedValue...), nil
+}
+
+func (d *jsoncDocument) marshalValue(value any, indent string) ([]byte, error) {
+	encoded, err := json.MarshalIndent(value, indent, d.indentUnit)
+	if err != nil {
+		return nil, err
+	}
+	if d.newline != "\n" {
+		encoded = bytes.ReplaceAll(encoded, []byte("\n"), []byte(d.newline))
+	}
+	return encoded, nil
+}
+
+func (d *jsoncDocument) splice(start, end int, text []byte) error {
+	src := make([]byte, 0, len(d.src)-(end-start)+len(text))
+	src = append(src, d.src[:start]...)
+	src = append(src, text...)
+	src = append(src, d.src[end:]...)
+	return d.reset(src)
+}
+
+func (d *jsoncDocument) lineStart(pos int) int {
+	return bytes.LastIndexByte(d.src[:pos], '\n') + 1
+}
+
+func (d *jsoncDocument) lineIndent(pos int) string {
+	start := d.lineStart(pos)
+	end := start
+	for end < len(d.src) && (d.src[end] == ' ' || d.src[end] == '\t') {
+		end++
+	}
+	return string(d.src[start:end])
+}
+
+// startsLine reports whether only indentation precedes pos on its line.
+func (d *jsoncDocument) startsLine(pos int) bool {
+	return len(bytes.Trim(d.src[d.lineStart(pos):pos], " \t\ufeff")) == 0
+}
+
+func (d *jsoncDocument) whitespaceBefore(pos int) int {
+	for pos > 0 && isJSONSpace(d.src[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// lineRest returns the end of any comments that follow pos on the same line,
+// or pos itself when there are none.
+func (d *jsoncDocument) lineRest(pos int) int {
+	end := pos
+	for i := pos; i < len(d.src); {
+		switch {
+		case d.src[i] == ' ' || d.src[i] == '\t':
+			i++
+		case bytes.HasPrefix(d.src[i:], []byte("//")):
+			for i < len(d.src) && d.src[i] != '\n' && d.src[i] != '\r' {
+				i++
+			}
+			return i
+		case bytes.HasPrefix(d.src[i:], []byte("/*")):
+			closing := bytes.Index(d.src[i+2:], []byte("*/"))
+			if closing < 0 || bytes.ContainsAny(d.src[i:i+2+closing], "\r\n") {
+				return end
+			}
+			i += closing + 4
+			end = i
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+func isJSONSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+func parseJSONC(src []byte) (*jsoncValue, error) {
+	p := &jsoncParser{src: src}
+	if bytes.HasPrefix(src, utf8BOM) {
+		p.pos = len(utf8BOM)
+	}
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.pos != len(src) {
+		return nil, p.errorf("unexpected content after top-level value")
+	}
+	return v, nil
+}
+
+type jsoncParser struct {
+	src []byte
+	pos int
+}
+
+func (p *jsoncParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid JSONC at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// skip advances past whitespace and comments.
+func (p *jsoncParser) skip() error {
+	for p.pos < len(p.src) {
+		switch {
+		case isJSONSpace(p.src[p.pos]):
+			p.pos++
+		case bytes.HasPrefix(p.src[p.pos:], []byte("//")):
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+				p.pos++
+			}
+		case bytes.HasPrefix(p.src[p.pos:], []byte("/*")):
+			closing := bytes.Index(p.src[p.pos+2:], []byte("*/"))
+			if closing < 0 {
+				return errors.New("unterminated JSON block comment")
+			}
+			p.pos += closing + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *jsoncParser) value() (*jsoncValue, error) {
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of input")
+	}
+	switch p.src[p.pos] {
+	case '{':
+		return p.object()
+	case '[':
+		return p.array()
+	case '"':
+		start := p.pos
+		if err := p.string(); err != nil {
+			return nil, err
+		}
+		return &jsoncValue{start: start, end: p.pos}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) && !isJSONSpace(p.src[p.pos]) && strings.IndexByte(",:]}/", p.src[p.pos]) < 0 {
+		p.pos++
+	}
+	if start == p.pos || !json.Valid(p.src[start:p.pos]) {
+		p.pos = start
+		return nil, p.errorf("invalid value")
+	}
+	return &jsoncValue{start: start, end: p.pos}, nil
+}
+
+func (p *jsoncParser) string() error {
+	p.pos++
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '"':
+			p.pos++
+			return nil
+		default:
+			p.pos++
+		}
+	}
+	return errors.New("unterminated JSON string")
+}
+
+func (p *jsoncParser) object() (*jsoncValue, error) {
+	v := &jsoncValue{start: p.pos, object: true}
+	p.pos++
+	for {
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == '}' {
+			p.pos++
+			v.end = p.pos
+			return v, nil
+		}
+		if p.pos >= len(p.src) || p.src[p.pos] != '"' {
+			return nil, p.errorf("expected object key")
+		}
+		m := jsoncMember{keyStart: p.pos, comma: -1}
+		if err := p.string(); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(p.src[m.keyStart:p.pos], &m.key); err != nil {
+			return nil, p.errorf("invalid object key: %v", err)
+		}
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.src) || p.src[p.pos] != ':' {
+			return nil, p.errorf("expected ':' after object key")
+		}
+		p.pos++
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		m.value = value
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			m.comma = p.pos
+			p.pos++
+			v.members = append(v.members, m)
+			continue
+		}
+		v.members = append(v.members, m)
+		if p.pos >= len(p.src) || p.src[p.pos] != '}' {
+			return nil, p.errorf("expected ',' or '}' in object")
+		}
+	}
+}
+
+func (p *jsoncParser) array() (*jsoncValue, error) {
+	v := &jsoncValue{start: p.pos}
+	p.pos++
+	for {
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == ']' {
+			p.pos++
+			v.end = p.pos
+			return v, nil
+		}
+		if _, err := p.value(); err != nil {
+			return nil, err
+		}
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testAgentsDir = "~/.local/share/ccsubagents/agents"
+
+func TestSettingsEdit_PreservesCommentsAndRevertsExactly(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		want     string
+	}{
+		{
+			name: "comments and trailing comma",
+			original: `{
+    // editor
+    "editor.fontSize": 14, // keep me
+    /* block */
+    "files.autoSave": "afterDelay",
+}
+`,
+			want: `{
+    // editor
+    "editor.fontSize": 14, // keep me
+    /* block */
+    "files.autoSave": "afterDelay",
+    "chat.agentFilesLocations": {
+        "~/.local/share/ccsubagents/agents": true
+    },
+}
+`,
+		},
+		{
+			name:     "line comment after last member",
+			original: "{\n\t\"a\": 1 // note\n}\n",
+			want:     "{\n\t\"a\": 1, // note\n\t\"chat.agentFilesLocations\": {\n\t\t\"~/.local/share/ccsubagents/agents\": true\n\t}\n}\n",
+		},
+		{
+			name:     "existing locations",
+			original: "{\r\n  \"chat.agentFilesLocations\": {\r\n    \".github/agents\": true\r\n  }\r\n}\r\n",
+			want:     "{\r\n  \"chat.agentFilesLocations\": {\r\n    \".github/agents\": true,\r\n    \"~/.local/share/ccsubagents/agents\": true\r\n  }\r\n}\r\n",
+		},
+		{
+			name:     "empty object",
+			original: "{}\n",
+			want:     "{\n  \"chat.agentFilesLocations\": {\n    \"~/.local/share/ccsubagents/agents\": true\n  }\n}\n",
+		},
+		{
+			name:     "empty locations",
+			original: "{\n  \"chat.agentFilesLocations\": {}\n}\n",
+			want:     "{\n  \"chat.agentFilesLocations\": {\n    \"~/.local/share/ccsubagents/agents\": true\n  }\n}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "settings.json")
+			if err := os.WriteFile(path, []byte(tc.original), 0o644); err != nil {
+				t.Fatalf("write fixture: %v", err)
+			}
+
+			edit, err := ApplySettingsEdit(path, testAgentsDir, nil, 0o644)
+			if err != nil {
+				t.Fatalf("apply settings edit: %v", err)
+			}
+			assertFileText(t, path, tc.want)
+
+			if err := RevertSettingsEdit(edit, 0o644); err != nil {
+				t.Fatalf("revert settings edit: %v", err)
+			}
+			assertFileText(t, path, tc.original)
+		})
+	}
+}
+
+func TestMCPEdit_RestoresReplacedEntryText(t *testing.T) {
+	original := `{
+  "servers": {
+    // pinned by hand
+    "artifact-mcp": { "command": "/opt/old", "args": ["--verbose"] }, // old build
+    "other": {"command": "x"}
+  }
+}
+`
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	edit, err := ApplyMCPEdit(path, "/usr/local/bin/local-artifact-mcp", nil, 0o644)
+	if err != nil {
+		t.Fatalf("apply mcp edit: %v", err)
+	}
+	if !edit.HadPrevious || edit.PreviousText != `{ "command": "/opt/old", "args": ["--verbose"] }` {
+		t.Fatalf("expected previous entry text to be tracked, got %#v", edit)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read mcp: %v", err)
+	}
+	for _, want := range []string{"// pinned by hand", "// old build", `"command": "/usr/local/bin/local-artifact-mcp"`, `"other": {"command": "x"}`} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("expected %q in edited mcp.json, got:\n%s", want, b)
+		}
+	}
+
+	if err := RevertMCPEdit(edit, 0o644); err != nil {
+		t.Fatalf("revert mcp edit: %v", err)
+	}
+	assertFileText(t, path, original)
+}
+
+func TestMCPEdit_RemovesServersObjectItCreated(t *testing.T) {
+	original := "{\n  // zed settings\n  \"theme\": \"One Dark\"\n}\n"
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	edit, err := ApplyMCPEditWithFormat(path, ZedMCPFormat, "/bin/mcp", nil, 0o644)
+	if err != nil {
+		t.Fatalf("apply mcp edit: %v", err)
+	}
+	if !edit.CreatedServers {
+		t.Fatalf("expected edit to record the created servers object, got %#v", edit)
+	}
+	if err := RevertMCPEdit(edit, 0o644); err != nil {
+		t.Fatalf("revert mcp edit: %v", err)
+	}
+	assertFileText(t, path, original)
+}
+
+func TestParseJSONC_RejectsMalformedInput(t *testing.T) {
+	for _, input := range []string{`{"a": }`, `{"a" 1}`, `{"a": 1} x`, `[1, 2]`, `{"a": "x`, `{/* x`} {
+		if _, err := parseJSONCDocument([]byte(input)); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func assertFileText(t *testing.T, path, want string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if string(b) != want {
+		t.Fatalf("unexpected file text\nwant:\n%q\ngot:\n%q", want, b)
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const defaultMCPServersKey = "servers"
+
+// MCPFormat describes where a client keeps its MCP server entries and how a
+// single server entry is shaped. Entry builds a stdio entry; URLEntry builds
+// one for a Streamable HTTP endpoint, written by ApplyMCPURLEdit.
+type MCPFormat struct {
+	ServersKey string
+	Entry      func(commandPath string) map[string]any
+	URLEntry   func(url string) map[string]any
+}
+
+var (
+	VSCodeMCPFormat = MCPFormat{
+		ServersKey: defaultMCPServersKey,
+		Entry: func(commandPath string) map[string]any {
+			return map[string]any{"command": commandPath}
+		},
+		URLEntry: func(url string) map[string]any {
+			return map[string]any{"type": "http", "url": url}
+		},
+	}
+	// MCPServersFormat is the `mcpServers` layout shared by Cursor, Claude
+	// Desktop and Windsurf.
+	MCPServersFormat = MCPFormat{
+		ServersKey: "mcpServers",
+		Entry: func(commandPath string) map[string]any {
+			return map[string]any{"command": commandPath, "args": []any{}}
+		},
+		URLEntry: urlOnlyEntry,
+	}
+	ZedMCPFormat = MCPFormat{
+		ServersKey: "context_servers",
+		Entry: func(commandPath string) map[string]any {
+			return map[string]any{"source": "custom", "command": commandPath, "args": []any{}}
+		},
+		URLEntry: urlOnlyEntry,
+	}
+)
+
+func (f MCPFormat) serversKey() string {
+	if key := strings.TrimSpace(f.ServersKey); key != "" {
+		return key
+	}
+	return defaultMCPServersKey
+}
+
+func urlOnlyEntry(url string) map[string]any {
+	return map[string]any{"url": url}
+}
+
+// IsMCPURL reports whether target names a Streamable HTTP endpoint rather than
+// a command path.
+func IsMCPURL(target string) bool {
+	target = strings.TrimSpace(target)
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
+
+// MCPHTTPAuth is how a URL entry authenticates to `local-artifact-mcp --http`,
+// which requires the daemon token unless no-auth is set.
+type MCPHTTPAuth struct {
+	Token  string
+	NoAuth bool
+}
+
+var errMCPURLTarget = errors.New("mcp target is an HTTP endpoint; use ApplyMCPURLEdit so the entry carries the daemon token")
+
+func (f MCPFormat) entry(commandPath string) (map[string]any, error) {
+	if IsMCPURL(commandPath) {
+		return nil, errMCPURLTarget
+	}
+	if f.Entry == nil {
+		return VSCodeMCPFormat.Entry(commandPath), nil
+	}
+	return f.Entry(commandPath), nil
+}
+
+// urlEntry builds the entry for a Streamable HTTP endpoint. The token goes in
+// an Authorization header; without one the entry is refused unless auth is
+// off, since the server would answer every request with 401.
+func (f MCPFormat) urlEntry(url string, auth MCPHTTPAuth) (map[string]any, error) {
+	url = strings.TrimSpace(url)
+	if !IsMCPURL(url) {
+		return nil, fmt.Errorf("mcp url %q must start with http:// or https://", url)
+	}
+	token := strings.TrimSpace(auth.Token)
+	if token == "" && !auth.NoAuth {
+		return nil, errors.New("mcp http endpoint requires the daemon token unless no-auth is set")
+	}
+	build := f.URLEntry
+	if build == nil {
+		build = urlOnlyEntry
+	}
+	entry := build(url)
+	if !auth.NoAuth {
+		entry["headers"] = map[string]any{"Authorization": "Bearer " + token}
+	}
+	return entry, nil
+}
+
+func mcpServersKeyForEdit(serversKey string) string {
+	return MCPFormat{ServersKey: serversKey}.serversKey()
+}
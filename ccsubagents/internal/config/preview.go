@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
+)
+
+// JSONChange is the effect an edit would have on one key of a JSON config
+// file. Before and After are nil when the key is absent on that side.
+type JSONChange struct {
+	File    string
+	Pointer string
+	Before  json.RawMessage
+	After   json.RawMessage
+}
+
+func (c JSONChange) Changed() bool {
+	return !bytes.Equal(c.Before, c.After)
+}
+
+func PreviewSettingsEdit(settingsPath, agentsDir string, previous *state.TrackedState) (JSONChange, error) {
+	previousEdit, hasPreviousEdit := previousSettingsEdit(previous, settingsPath)
+	return previewJSONEdit(settingsPath, []string{SettingsAgentPathKey}, func(root map[string]any) error {
+		_, err := editSettingsRoot(root, agentsDir, previousEdit, hasPreviousEdit)
+		return err
+	})
+}
+
+func PreviewSettingsRevert(edit state.SettingsEdit) (JSONChange, error) {
+	if !edit.Added {
+		return JSONChange{File: edit.File}, nil
+	}
+	return previewJSONEdit(edit.File, []string{SettingsAgentPathKey}, func(root map[string]any) error {
+		_, err := revertSettingsRoot(root, edit)
+		return err
+	})
+}
+
+func PreviewMCPEdit(path string, format MCPFormat, commandPath string, previous *state.TrackedState) (JSONChange, error) {
+	entry, err := format.entry(commandPath)
+	if err != nil {
+		return JSONChange{}, err
+	}
+	return previewJSONEdit(path, []string{format.serversKey(), MCPServerKey}, func(root map[string]any) error {
+		_, err := editMCPRoot(root, path, format, entry, previous, "")
+		return err
+	})
+}
+
+func PreviewMCPRevert(edit state.MCPEdit) (JSONChange, error) {
+	if !edit.Touched {
+		return JSONChange{File: edit.File}, nil
+	}
+	return previewJSONEdit(edit.File, []string{mcpServersKeyForEdit(edit.ServersKey), edit.Key}, func(root map[string]any) error {
+		return revertMCPRoot(root, edit)
+	})
+}
+
+func PreviewPinnedVersion(path, versionTag string) (JSONChange, error) {
+	normalized := NormalizeVersionTag(versionTag)
+	if normalized == "" {
+		return JSONChange{}, errors.New("pinned version cannot be empty")
+	}
+	return previewJSONEdit(path, []string{"pinned-version"}, func(root map[string]any) error {
+		root["pinned-version"] = normalized
+		return nil
+	})
+}
+
+// previewJSONEdit runs mutate against an in-memory copy of path and reports
+// the value at keys before and after, without writing anything.
+func previewJSONEdit(path string, keys []string, mutate func(map[string]any) error) (JSONChange, error) {
+	change := JSONChange{File: path, Pointer: jsonPointer(keys)}
+	root, err := readJSONFile(path)
+	if err != nil {
+		return JSONChange{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	if change.Before, err = marshalJSONValueAt(root, keys); err != nil {
+		return JSONChange{}, err
+	}
+	if err := mutate(root); err != nil {
+		return JSONChange{}, err
+	}
+	if change.After, err = marshalJSONValueAt(root, keys); err != nil {
+		return JSONChange{}, err
+	}
+	return change, nil
+}
+
+func marshalJSONValueAt(root map[string]any, keys []string) (json.RawMessage, error) {
+	var current any = root
+	for _, key := range keys {
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		if current, ok = obj[key]; !ok {
+			return nil, nil
+		}
+	}
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s: %w", jsonPointer(keys), err)
+	}
+	return encoded, nil
+}
+
+func jsonPointer(keys []string) string {
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteByte('/')
+		b.WriteString(escaper.Replace(key))
+	}
+	return b.String()
+}
+
+// PreviewLocalIgnoreRules reports the ignore file ApplyLocalIgnoreRules would
+// edit and the lines it would append.
+func PreviewLocalIgnoreRules(installRoot, repoRoot string, mode state.LocalInstallMode) (string, []string, error) {
+	path, lines, err := ResolveLocalIgnoreTarget(installRoot, repoRoot, mode)
+	if err != nil {
+		return "", nil, err
+	}
+	if strings.TrimSpace(path) == "" || len(lines) == 0 {
+		return "", nil, nil
+	}
+	existing, err := readIgnoreLines(path)
+	if err != nil {
+		return "", nil, err
+	}
+	missing := []string{}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if _, ok := existing[trimmed]; ok {
+			continue
+		}
+		existing[trimmed] = struct{}{}
+		missing = append(missing, trimmed)
+	}
+	return path, missing, nil
+}
+
+// PreviewIgnoreRevert reports, per file, the tracked lines RevertIgnoreEdits
+// would remove.
+func PreviewIgnoreRevert(edits []state.IgnoreEdit) ([]state.IgnoreEdit, error) {
+	out := []state.IgnoreEdit{}
+	for _, edit := range edits {
+		if strings.TrimSpace(edit.File) == "" || len(edit.AddedLines) == 0 {
+			continue
+		}
+		existing, err := readIgnoreLines(edit.File)
+		if err != nil {
+			return nil, err
+		}
+		present := []string{}
+		for _, line := range edit.AddedLines {
+			if _, ok := existing[strings.TrimSpace(line)]; ok {
+				present = append(present, strings.TrimSpace(line))
+			}
+		}
+		if len(present) > 0 {
+			out = append(out, state.IgnoreEdit{File: edit.File, AddedLines: present})
+		}
+	}
+	return out, nil
+}
+
+func readIgnoreLines(path string) (map[string]struct{}, error) {
+	set := map[string]struct{}{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return set, nil
+		}
+		return nil, fmt.Errorf("read ignore file %s: %w", path, err)
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			set[trimmed] = struct{}{}
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
+)
+
+func TestPreviewMCPEdit_ReportsDiffWithoutWriting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	original := "{\"servers\":{\"artifact-mcp\":{\"command\":\"old\"}}}\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	change, err := PreviewMCPEdit(path, VSCodeMCPFormat, "~/.local/bin/local-artifact-mcp", nil)
+	if err != nil {
+		t.Fatalf("preview mcp edit: %v", err)
+	}
+	if change.Pointer != "/servers/artifact-mcp" {
+		t.Fatalf("unexpected pointer %q", change.Pointer)
+	}
+	if string(change.Before) != `{"command":"old"}` || string(change.After) != `{"command":"~/.local/bin/local-artifact-mcp"}` {
+		t.Fatalf("unexpected diff before=%s after=%s", change.Before, change.After)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read mcp.json: %v", err)
+	}
+	if string(b) != original {
+		t.Fatalf("preview must not write the file, got %q", b)
+	}
+
+	revert, err := PreviewMCPRevert(state.MCPEdit{File: path, Key: MCPServerKey, Touched: true})
+	if err != nil {
+		t.Fatalf("preview mcp revert: %v", err)
+	}
+	if !revert.Changed() || revert.After != nil {
+		t.Fatalf("expected revert to remove the key, got %#v", revert)
+	}
+}
+
+func TestPreviewLocalIgnoreRules_ReportsOnlyMissingLines(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, ".gitignore"), []byte(".ccsubagents\n"), 0o644); err != nil {
+		t.Fatalf("write .gitignore: %v", err)
+	}
+
+	path, lines, err := PreviewLocalIgnoreRules(repo, repo, state.LocalInstallModeTeam)
+	if err != nil {
+		t.Fatalf("preview ignore rules: %v", err)
+	}
+	if path != filepath.Join(repo, ".gitignore") {
+		t.Fatalf("unexpected ignore path %q", path)
+	}
+	want, _, _ := ResolveLocalIgnoreTarget(repo, repo, state.LocalInstallModeTeam)
+	if want != path {
+		t.Fatalf("preview path %q differs from resolved target %q", path, want)
+	}
+	for _, line := range lines {
+		if line == ".ccsubagents" {
+			t.Fatalf("expected existing line to be skipped, got %v", lines)
+		}
+	}
+}
@@ -196,7 +196,7 @@ func WritePinnedVersion(path, versionTag string, dirPerm, filePerm os.FileMode)
 		return errors.New("pinned version cannot be empty")
 	}
 
-	root, err := readJSONFile(path)
+	doc, err := readJSONCDocumentOrEmpty(path)
 	if err != nil {
 		return fmt.Errorf("read settings file %s: %w", path, err)
 	}
@@ -205,8 +205,10 @@ func WritePinnedVersion(path, versionTag string, dirPerm, filePerm os.FileMode)
 		return fmt.Errorf("create settings directory for %s: %w", path, err)
 	}
 
-	root["pinned-version"] = normalized
-	if err := writeJSONFile(path, root, filePerm); err != nil {
+	if err := doc.set([]string{"pinned-version"}, normalized); err != nil {
+		return fmt.Errorf("edit settings file %s: %w", path, err)
+	}
+	if err := doc.write(path, filePerm); err != nil {
 		return fmt.Errorf("write settings file %s: %w", path, err)
 	}
 
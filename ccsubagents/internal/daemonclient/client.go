@@ -23,10 +23,11 @@ const (
 )
 
 type Client struct {
-	baseURL string
-	token   string
-	http    *http.Client
-	failErr error
+	baseURL  string
+	endpoint string
+	token    string
+	http     *http.Client
+	failErr  error
 }
 
 func NewUnavailableClient(cause error) *Client {
@@ -37,9 +38,11 @@ func NewUnavailableClient(cause error) *Client {
 }
 
 func NewHTTPClient(baseURL, token string) *Client {
+	trimmed := strings.TrimRight(strings.TrimSpace(baseURL), "/")
 	return &Client{
-		baseURL: strings.TrimRight(strings.TrimSpace(baseURL), "/"),
-		token:   strings.TrimSpace(token),
+		baseURL:  trimmed,
+		endpoint: trimmed,
+		token:    strings.TrimSpace(token),
 		http: &http.Client{
 			Timeout: 30 * time.Second,
 		},
@@ -54,8 +57,9 @@ func NewUnixSocketClient(socketPath, token string) *Client {
 		},
 	}
 	return &Client{
-		baseURL: "http://daemon.local",
-		token:   strings.TrimSpace(token),
+		baseURL:  "http://daemon.local",
+		endpoint: socketPath,
+		token:    strings.TrimSpace(token),
 		http: &http.Client{
 			Timeout:   30 * time.Second,
 			Transport: transport,
@@ -98,11 +102,145 @@ func ResolveDaemonToken(stateDir string, getenv func(string) string) string {
 	return strings.TrimSpace(string(b))
 }
 
+// Endpoint returns the unix socket path or base URL the client dials.
+func (c *Client) Endpoint() string {
+	if c == nil {
+		return ""
+	}
+	return c.endpoint
+}
+
 func (c *Client) Health(ctx context.Context) error {
 	var out HealthResponse
 	return c.do(ctx, http.MethodGet, "/daemon/v1/health", nil, &out)
 }
 
+// Status fetches the detailed daemon status. Daemons older than the status
+// endpoint answer with a not-found error.
+func (c *Client) Status(ctx context.Context) (StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do(ctx, http.MethodGet, "/daemon/v1/status", nil, &out); err != nil {
+		return StatusResponse{}, err
+	}
+	return out, nil
+}
+
+// Verify checks artifact store integrity. An empty workspace selector covers
+// every known workspace; with Repair the daemon quarantines what it finds.
+func (c *Client) Verify(ctx context.Context, req VerifyRequest) (VerifyResponse, error) {
+	var out VerifyResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/verify", req, &out); err != nil {
+		return VerifyResponse{}, err
+	}
+	return out, nil
+}
+
+// MigrateLegacy imports a filestore directory written by early builds into a
+// workspace. The daemon reads LegacyRoot itself, so it must be absolute.
+func (c *Client) MigrateLegacy(ctx context.Context, req MigrateLegacyRequest) (MigrateLegacyResponse, error) {
+	var out MigrateLegacyResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/migrate_legacy", req, &out); err != nil {
+		return MigrateLegacyResponse{}, err
+	}
+	return out, nil
+}
+
+// RotateKey replaces the daemon's master key and rewraps every workspace
+// data key under it.
+func (c *Client) RotateKey(ctx context.Context) (RotateKeyResponse, error) {
+	var out RotateKeyResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/rotate_key", map[string]any{}, &out); err != nil {
+		return RotateKeyResponse{}, err
+	}
+	return out, nil
+}
+
+// Backup takes a snapshot of every workspace. Dir, when set, must be an
+// absolute path on the daemon host.
+func (c *Client) Backup(ctx context.Context, req BackupRequest) (BackupResponse, error) {
+	var out BackupResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/backup", req, &out); err != nil {
+		return BackupResponse{}, err
+	}
+	return out, nil
+}
+
+// ListSnapshots lists the snapshots in the daemon's snapshot directory,
+// newest first.
+func (c *Client) ListSnapshots(ctx context.Context) (ListSnapshotsResponse, error) {
+	var out ListSnapshotsResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/snapshots", map[string]any{}, &out); err != nil {
+		return ListSnapshotsResponse{}, err
+	}
+	return out, nil
+}
+
+// Restore restores workspaces from a snapshot ID or absolute snapshot path.
+// An empty workspace selector restores everything in the snapshot.
+func (c *Client) Restore(ctx context.Context, req RestoreRequest) (RestoreResponse, error) {
+	var out RestoreResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/restore", req, &out); err != nil {
+		return RestoreResponse{}, err
+	}
+	return out, nil
+}
+
+// ListIdentities lists the git identity keys the daemon has mapped to
+// workspaces.
+func (c *Client) ListIdentities(ctx context.Context) (ListIdentitiesResponse, error) {
+	var out ListIdentitiesResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/identities", map[string]any{}, &out); err != nil {
+		return ListIdentitiesResponse{}, err
+	}
+	return out, nil
+}
+
+// MapIdentity points a git identity key at an existing workspace.
+func (c *Client) MapIdentity(ctx context.Context, req MapIdentityRequest) (WorkspaceIdentity, error) {
+	var out WorkspaceIdentity
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/map_identity", req, &out); err != nil {
+		return WorkspaceIdentity{}, err
+	}
+	return out, nil
+}
+
+// Share grants, or with req.Revoke withdraws, other workspaces read access to
+// an artifact name.
+func (c *Client) Share(ctx context.Context, req ShareRequest) (ShareResponse, error) {
+	var out ShareResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/share", req, &out); err != nil {
+		return ShareResponse{}, err
+	}
+	return out, nil
+}
+
+// ListShares lists the shares a workspace has granted and can read.
+func (c *Client) ListShares(ctx context.Context, req ListSharesRequest) (ListSharesResponse, error) {
+	var out ListSharesResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/shares", req, &out); err != nil {
+		return ListSharesResponse{}, err
+	}
+	return out, nil
+}
+
+// ListAliases lists the workspace aliases usable in artifact://ws/ URIs.
+func (c *Client) ListAliases(ctx context.Context) (ListAliasesResponse, error) {
+	var out ListAliasesResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/aliases", map[string]any{}, &out); err != nil {
+		return ListAliasesResponse{}, err
+	}
+	return out, nil
+}
+
+// SetAlias points an alias at a workspace, or removes it with req.Remove.
+func (c *Client) SetAlias(ctx context.Context, req SetAliasRequest) (WorkspaceAlias, error) {
+	var out WorkspaceAlias
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/alias", req, &out); err != nil {
+		return WorkspaceAlias{}, err
+	}
+	return out, nil
+}
+
 func (c *Client) Shutdown(ctx context.Context) (ShutdownResponse, error) {
 	var out ShutdownResponse
 	if err := c.do(ctx, http.MethodPost, "/daemon/v1/control/shutdown", map[string]any{}, &out); err != nil {
@@ -131,6 +269,26 @@ func (c *Client) SaveBlob(ctx context.Context, req SaveBlobRequest) (ArtifactVer
 	return out.Artifact, nil
 }
 
+func (c *Client) SaveJSON(ctx context.Context, req SaveJSONRequest) (ArtifactVersion, error) {
+	var out struct {
+		Artifact ArtifactVersion `json:"artifact"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/save_json", req, &out); err != nil {
+		return ArtifactVersion{}, err
+	}
+	return out.Artifact, nil
+}
+
+func (c *Client) PatchJSON(ctx context.Context, req PatchJSONRequest) (ArtifactVersion, error) {
+	var out struct {
+		Artifact ArtifactVersion `json:"artifact"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/patch_json", req, &out); err != nil {
+		return ArtifactVersion{}, err
+	}
+	return out.Artifact, nil
+}
+
 func (c *Client) Resolve(ctx context.Context, req ResolveRequest) (ResolveResponse, error) {
 	var out ResolveResponse
 	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/resolve", req, &out); err != nil {
@@ -163,6 +321,15 @@ func (c *Client) Delete(ctx context.Context, req DeleteRequest) (DeleteResponse,
 	return out, nil
 }
 
+// Batch applies every op or none of them.
+func (c *Client) Batch(ctx context.Context, req BatchRequest) (BatchResponse, error) {
+	var out BatchResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/batch", req, &out); err != nil {
+		return BatchResponse{}, err
+	}
+	return out, nil
+}
+
 func (c *Client) do(ctx context.Context, method, path string, reqBody any, out any) error {
 	if c == nil || c.failErr != nil {
 		cause := errors.New("daemon client unavailable")
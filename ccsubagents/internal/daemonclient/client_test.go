@@ -18,11 +18,23 @@ func TestClient_SaveGetListAndShutdown(t *testing.T) {
 		writeEnvelope(t, w, map[string]any{
 			"artifact":   map[string]any{"ref": "r1", "name": "note/t1", "kind": "text", "mimeType": "text/plain", "sizeBytes": 5},
 			"dataBase64": base64.StdEncoding.EncodeToString([]byte("hello")),
+			"totalBytes": 5,
+			"totalLines": 1,
 		})
 	})
 	h.HandleFunc("/daemon/v1/artifacts/list", func(w http.ResponseWriter, r *http.Request) {
 		writeEnvelope(t, w, map[string]any{"items": []map[string]any{{"ref": "r1", "name": "note/t1", "kind": "text", "mimeType": "text/plain", "sizeBytes": 5}}})
 	})
+	h.HandleFunc("/daemon/v1/artifacts/batch", func(w http.ResponseWriter, r *http.Request) {
+		var req BatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Ops) != 2 || req.Ops[1].Op != "delete" {
+			t.Fatalf("unexpected batch request: %+v (%v)", req, err)
+		}
+		writeEnvelope(t, w, map[string]any{"artifacts": []map[string]any{
+			{"ref": "r2", "name": "note/t2", "kind": "text", "mimeType": "text/plain", "sizeBytes": 2},
+			{"ref": "r3", "name": "note/t1", "kind": "text", "mimeType": "text/plain", "tombstone": true, "prevRef": "r1"},
+		}})
+	})
 	h.HandleFunc("/daemon/v1/control/shutdown", func(w http.ResponseWriter, r *http.Request) {
 		writeEnvelope(t, w, map[string]any{"status": "shutting-down"})
 	})
@@ -49,8 +61,8 @@ func TestClient_SaveGetListAndShutdown(t *testing.T) {
 	if err != nil {
 		t.Fatalf("decode payload: %v", err)
 	}
-	if string(payload) != "hello" {
-		t.Fatalf("payload mismatch: %q", string(payload))
+	if string(payload) != "hello" || got.TotalBytes != 5 || got.TotalLines != 1 || got.Truncated {
+		t.Fatalf("payload mismatch: %q (%+v)", string(payload), got)
 	}
 
 	list, err := c.List(ctx, ListRequest{Workspace: WorkspaceSelector{WorkspaceID: "global"}})
@@ -61,6 +73,17 @@ func TestClient_SaveGetListAndShutdown(t *testing.T) {
 		t.Fatalf("unexpected list output: %+v", list.Items)
 	}
 
+	batch, err := c.Batch(ctx, BatchRequest{Workspace: WorkspaceSelector{WorkspaceID: "global"}, Ops: []BatchOp{
+		{Op: "put", Name: "note/t2", Text: "hi"},
+		{Op: "delete", Name: "note/t1", ExpectedPrevRef: "r1"},
+	}})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if len(batch.Artifacts) != 2 || !batch.Artifacts[1].Tombstone {
+		t.Fatalf("unexpected batch output: %+v", batch.Artifacts)
+	}
+
 	shutdown, err := c.Shutdown(ctx)
 	if err != nil {
 		t.Fatalf("shutdown: %v", err)
@@ -70,6 +93,33 @@ func TestClient_SaveGetListAndShutdown(t *testing.T) {
 	}
 }
 
+func TestClient_PatchJSONSendsFormatAndPatch(t *testing.T) {
+	h := http.NewServeMux()
+	h.HandleFunc("/daemon/v1/artifacts/patch_json", func(w http.ResponseWriter, r *http.Request) {
+		var req PatchJSONRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Format != PatchFormatMergePatch || string(req.Patch) != `{"verdict":"approved"}` {
+			t.Fatalf("unexpected patch request: %+v (%v)", req, err)
+		}
+		writeEnvelope(t, w, map[string]any{"artifact": map[string]any{"ref": "r2", "name": "review/r1", "kind": "text", "mimeType": "application/json", "prevRef": "r1"}})
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	c := NewHTTPClient(srv.URL, "")
+	a, err := c.PatchJSON(context.Background(), PatchJSONRequest{
+		Workspace: WorkspaceSelector{WorkspaceID: "global"},
+		Name:      "review/r1",
+		Patch:     json.RawMessage(`{"verdict":"approved"}`),
+		Format:    PatchFormatMergePatch,
+	})
+	if err != nil {
+		t.Fatalf("patch json: %v", err)
+	}
+	if a.Ref != "r2" || a.PrevRef != "r1" {
+		t.Fatalf("unexpected patch output: %+v", a)
+	}
+}
+
 func TestClient_MapsRemoteError(t *testing.T) {
 	h := http.NewServeMux()
 	h.HandleFunc("/daemon/v1/artifacts/list", func(w http.ResponseWriter, r *http.Request) {
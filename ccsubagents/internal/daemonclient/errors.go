@@ -6,8 +6,10 @@ const (
 	CodeConflict           = "CONFLICT"
 	CodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"
 	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
 	CodeInternal           = "INTERNAL"
 	CodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	CodeQuotaExceeded      = "QUOTA_EXCEEDED"
 )
 
 type EnvelopeError struct {
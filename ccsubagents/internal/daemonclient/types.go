@@ -1,6 +1,10 @@
 package daemonclient
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 type WorkspaceSelector struct {
 	WorkspaceID string   `json:"workspaceID,omitempty"`
@@ -8,16 +12,17 @@ type WorkspaceSelector struct {
 }
 
 type ArtifactVersion struct {
-	Ref       string    `json:"ref"`
-	Name      string    `json:"name,omitempty"`
-	Kind      string    `json:"kind"`
-	MimeType  string    `json:"mimeType"`
-	Filename  string    `json:"filename,omitempty"`
-	SizeBytes int64     `json:"sizeBytes"`
-	SHA256    string    `json:"sha256,omitempty"`
-	CreatedAt time.Time `json:"createdAt"`
-	PrevRef   string    `json:"prevRef,omitempty"`
-	Tombstone bool      `json:"tombstone,omitempty"`
+	Ref       string     `json:"ref"`
+	Name      string     `json:"name,omitempty"`
+	Kind      string     `json:"kind"`
+	MimeType  string     `json:"mimeType"`
+	Filename  string     `json:"filename,omitempty"`
+	SizeBytes int64      `json:"sizeBytes"`
+	SHA256    string     `json:"sha256,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	PrevRef   string     `json:"prevRef,omitempty"`
+	Tombstone bool       `json:"tombstone,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 type SaveTextRequest struct {
@@ -26,6 +31,8 @@ type SaveTextRequest struct {
 	Text            string            `json:"text"`
 	MimeType        string            `json:"mimeType,omitempty"`
 	ExpectedPrevRef string            `json:"expectedPrevRef,omitempty"`
+	TTL             string            `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time        `json:"expiresAt,omitempty"`
 }
 
 type SaveBlobRequest struct {
@@ -35,6 +42,32 @@ type SaveBlobRequest struct {
 	MimeType        string            `json:"mimeType"`
 	Filename        string            `json:"filename,omitempty"`
 	ExpectedPrevRef string            `json:"expectedPrevRef,omitempty"`
+	TTL             string            `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time        `json:"expiresAt,omitempty"`
+}
+
+type SaveJSONRequest struct {
+	Workspace       WorkspaceSelector `json:"workspace"`
+	Name            string            `json:"name"`
+	JSON            json.RawMessage   `json:"json"`
+	ExpectedPrevRef string            `json:"expectedPrevRef,omitempty"`
+	TTL             string            `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time        `json:"expiresAt,omitempty"`
+}
+
+const (
+	PatchFormatJSONPatch  = "json-patch"
+	PatchFormatMergePatch = "merge-patch"
+)
+
+type PatchJSONRequest struct {
+	Workspace       WorkspaceSelector `json:"workspace"`
+	Name            string            `json:"name"`
+	Patch           json.RawMessage   `json:"patch"`
+	Format          string            `json:"format,omitempty"`
+	ExpectedPrevRef string            `json:"expectedPrevRef,omitempty"`
+	TTL             string            `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time        `json:"expiresAt,omitempty"`
 }
 
 type ResolveRequest struct {
@@ -55,11 +88,25 @@ type Selector struct {
 type GetRequest struct {
 	Workspace WorkspaceSelector `json:"workspace"`
 	Selector  Selector          `json:"selector"`
+	Source    string            `json:"source,omitempty"`
+	Pointer   string            `json:"pointer,omitempty"`
+	Offset    int64             `json:"offset,omitempty"`
+	Length    int64             `json:"length,omitempty"`
+	StartLine int               `json:"startLine,omitempty"`
+	EndLine   int               `json:"endLine,omitempty"`
+	Head      int               `json:"head,omitempty"`
+	Tail      int               `json:"tail,omitempty"`
+	MaxBytes  int64             `json:"maxBytes,omitempty"`
 }
 
 type GetResponse struct {
 	Artifact   ArtifactVersion `json:"artifact"`
 	DataBase64 string          `json:"dataBase64"`
+	Pointer    string          `json:"pointer,omitempty"`
+	Offset     int64           `json:"offset,omitempty"`
+	TotalBytes int64           `json:"totalBytes"`
+	TotalLines int             `json:"totalLines"`
+	Truncated  bool            `json:"truncated,omitempty"`
 }
 
 type ListRequest struct {
@@ -82,10 +129,259 @@ type DeleteResponse struct {
 	Artifact ArtifactVersion `json:"artifact"`
 }
 
+// BatchOp is one write of a batch: Op "put" saves Text or DataBase64, Op
+// "delete" tombstones Name or Ref.
+type BatchOp struct {
+	Op              string     `json:"op"`
+	Name            string     `json:"name,omitempty"`
+	Ref             string     `json:"ref,omitempty"`
+	Text            string     `json:"text,omitempty"`
+	DataBase64      string     `json:"dataBase64,omitempty"`
+	MimeType        string     `json:"mimeType,omitempty"`
+	Filename        string     `json:"filename,omitempty"`
+	ExpectedPrevRef string     `json:"expectedPrevRef,omitempty"`
+	TTL             string     `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time `json:"expiresAt,omitempty"`
+}
+
+type BatchRequest struct {
+	Workspace WorkspaceSelector `json:"workspace"`
+	Ops       []BatchOp         `json:"ops"`
+}
+
+type BatchResponse struct {
+	Artifacts []ArtifactVersion `json:"artifacts"`
+}
+
 type HealthResponse struct {
 	Status string `json:"status"`
 }
 
+type StatusResponse struct {
+	Status             string            `json:"status"`
+	PID                int               `json:"pid"`
+	Version            string            `json:"version,omitempty"`
+	StartedAt          time.Time         `json:"startedAt"`
+	UptimeSeconds      int64             `json:"uptimeSeconds"`
+	OpenWorkspaces     []string          `json:"openWorkspaces"`
+	MaxOpenWorkspaces  int               `json:"maxOpenWorkspaces"`
+	WorkspaceEvictions uint64            `json:"workspaceEvictions"`
+	Quota              Quota             `json:"quota"`
+	EncryptAtRest      bool              `json:"encryptAtRest"`
+	MasterKeyID        string            `json:"masterKeyID,omitempty"`
+	Workspaces         []WorkspaceStatus `json:"workspaces"`
+	Routes             []RouteMetrics    `json:"routes"`
+	SQLiteBusyRetries  uint64            `json:"sqliteBusyRetries"`
+	AuthFailures       uint64            `json:"authFailures"`
+}
+
+// Quota mirrors the daemon's per-workspace storage limits. Zero fields are
+// unlimited.
+type Quota struct {
+	MaxTotalBytes      int64 `json:"maxTotalBytes,omitempty"`
+	MaxArtifactBytes   int64 `json:"maxArtifactBytes,omitempty"`
+	MaxVersionsPerName int   `json:"maxVersionsPerName,omitempty"`
+}
+
+// WorkspaceStatus mirrors the daemon's per-workspace report. BlobBytes is the
+// on-disk blob store; Usage is what quotas are enforced against.
+type WorkspaceStatus struct {
+	WorkspaceID string `json:"workspaceID"`
+	Open        bool   `json:"open"`
+	BlobFiles   int64  `json:"blobFiles"`
+	BlobBytes   int64  `json:"blobBytes"`
+	Usage       Usage  `json:"usage"`
+}
+
+// Usage mirrors the daemon's logical workspace usage.
+type Usage struct {
+	Artifacts int64 `json:"artifacts"`
+	Versions  int64 `json:"versions"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// FormatByteSize renders n with a binary unit, e.g. "3.0 MiB".
+func FormatByteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+type RouteMetrics struct {
+	Route     string  `json:"route"`
+	Requests  uint64  `json:"requests"`
+	Errors    uint64  `json:"errors"`
+	AvgMillis float64 `json:"avgMillis"`
+	MaxMillis float64 `json:"maxMillis"`
+}
+
+type VerifyRequest struct {
+	Workspace WorkspaceSelector `json:"workspace"`
+	Repair    bool              `json:"repair,omitempty"`
+}
+
+type VerifyResponse struct {
+	Workspaces []WorkspaceIntegrity `json:"workspaces"`
+}
+
+// WorkspaceIntegrity is the integrity report of one workspace store.
+type WorkspaceIntegrity struct {
+	WorkspaceID string           `json:"workspaceID"`
+	Versions    int              `json:"versions"`
+	Blobs       int              `json:"blobs"`
+	Quarantined int              `json:"quarantined"`
+	Issues      []IntegrityIssue `json:"issues"`
+	Repaired    bool             `json:"repaired"`
+}
+
+type IntegrityIssue struct {
+	Kind   string `json:"kind"`
+	Ref    string `json:"ref,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Digest string `json:"digest,omitempty"`
+	Detail string `json:"detail"`
+	Action string `json:"action,omitempty"`
+}
+
+type MigrateLegacyRequest struct {
+	Workspace  WorkspaceSelector `json:"workspace"`
+	LegacyRoot string            `json:"legacyRoot"`
+	DryRun     bool              `json:"dryRun,omitempty"`
+}
+
+type MigrateLegacyResponse struct {
+	WorkspaceID string         `json:"workspaceID"`
+	Names       int            `json:"names"`
+	Versions    int            `json:"versions"`
+	Imported    int            `json:"imported"`
+	Existing    int            `json:"existing"`
+	Skipped     []MigrateIssue `json:"skipped"`
+	DryRun      bool           `json:"dryRun"`
+}
+
+type RotateKeyResponse struct {
+	MasterKeyID string   `json:"masterKeyID"`
+	Rewrapped   []string `json:"rewrapped"`
+}
+
+type BackupRequest struct {
+	Dir string `json:"dir,omitempty"`
+}
+
+type BackupResponse struct {
+	Path string `json:"path"`
+	SnapshotManifest
+}
+
+// SnapshotManifest is the manifest.json written into every snapshot.
+type SnapshotManifest struct {
+	Version    int                 `json:"version"`
+	ID         string              `json:"id"`
+	CreatedAt  time.Time           `json:"createdAt"`
+	Scheduled  bool                `json:"scheduled,omitempty"`
+	Registry   bool                `json:"registry"`
+	Workspaces []SnapshotWorkspace `json:"workspaces"`
+}
+
+type SnapshotWorkspace struct {
+	WorkspaceID string `json:"workspaceID"`
+	Encrypted   bool   `json:"encrypted,omitempty"`
+	Versions    int    `json:"versions"`
+	Blobs       int    `json:"blobs"`
+	Bytes       int64  `json:"bytes"`
+	Missing     int    `json:"missing,omitempty"`
+}
+
+type ListSnapshotsResponse struct {
+	Dir       string             `json:"dir"`
+	Snapshots []SnapshotManifest `json:"snapshots"`
+}
+
+type RestoreRequest struct {
+	Snapshot  string            `json:"snapshot"`
+	Workspace WorkspaceSelector `json:"workspace"`
+}
+
+type RestoreResponse struct {
+	SnapshotID string   `json:"snapshotID"`
+	Registry   bool     `json:"registry"`
+	Workspaces []string `json:"workspaces"`
+}
+
+// WorkspaceIdentity maps a git identity key to the workspace it resolves to.
+type WorkspaceIdentity struct {
+	Key         string    `json:"key"`
+	WorkspaceID string    `json:"workspaceID"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ListIdentitiesResponse struct {
+	Identities []WorkspaceIdentity `json:"identities"`
+}
+
+type MapIdentityRequest struct {
+	Key       string            `json:"key"`
+	Workspace WorkspaceSelector `json:"workspace"`
+}
+
+type ShareRequest struct {
+	Workspace WorkspaceSelector `json:"workspace"`
+	Name      string            `json:"name"`
+	With      string            `json:"with,omitempty"`
+	Revoke    bool              `json:"revoke,omitempty"`
+}
+
+type WorkspaceShare struct {
+	SourceWorkspaceID string    `json:"sourceWorkspaceID"`
+	Name              string    `json:"name"`
+	TargetWorkspaceID string    `json:"targetWorkspaceID"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+type ShareResponse struct {
+	Share   WorkspaceShare `json:"share"`
+	URI     string         `json:"uri"`
+	Revoked bool           `json:"revoked,omitempty"`
+}
+
+type ListSharesRequest struct {
+	Workspace WorkspaceSelector `json:"workspace"`
+}
+
+type ListSharesResponse struct {
+	WorkspaceID string           `json:"workspaceID"`
+	Shares      []WorkspaceShare `json:"shares"`
+}
+
+type WorkspaceAlias struct {
+	Alias       string    `json:"alias"`
+	WorkspaceID string    `json:"workspaceID,omitempty"`
+	CreatedAt   time.Time `json:"createdAt,omitempty"`
+}
+
+type SetAliasRequest struct {
+	Alias     string            `json:"alias"`
+	Workspace WorkspaceSelector `json:"workspace"`
+	Remove    bool              `json:"remove,omitempty"`
+}
+
+type ListAliasesResponse struct {
+	Aliases []WorkspaceAlias `json:"aliases"`
+}
+
+type MigrateIssue struct {
+	Name   string `json:"name"`
+	Ref    string `json:"ref,omitempty"`
+	Detail string `json:"detail"`
+}
+
 type ShutdownResponse struct {
 	Status string `json:"status"`
 }
@@ -0,0 +1,70 @@
+package daemonctl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RepairToken makes sure the daemon token file exists with owner-only
+// permissions. With auth disabled the file is kept empty, as StartAndWait
+// leaves it.
+func RepairToken(stateDir string, disableAuth bool) error {
+	if disableAuth {
+		return clearToken(stateDir)
+	}
+	if _, err := ensureToken(stateDir, false); err != nil {
+		return err
+	}
+	return os.Chmod(filepath.Join(stateDir, "daemon", "daemon.token"), 0o600)
+}
+
+// StalePIDFiles lists registered pid files whose process has exited or whose
+// pid now belongs to a different process.
+func StalePIDFiles(stateDir string, roles []string) ([]string, error) {
+	var stale []string
+	var errs []error
+	for _, role := range roles {
+		safeRole, ok := sanitizeRegistryRole(role)
+		if !ok {
+			errs = append(errs, fmt.Errorf("invalid role %q", role))
+			continue
+		}
+		listing, err := listRolePIDs(stateDir, safeRole)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("list registered %s processes: %w", safeRole, err))
+			continue
+		}
+		for _, item := range listing.registered {
+			if !processExistsFn(item.pid) {
+				stale = append(stale, item.pidFilePath)
+				continue
+			}
+			matches, matchErr := processIdentityMatchesFn(item.pid, item.startID)
+			if matchErr == nil && !matches {
+				stale = append(stale, item.pidFilePath)
+			}
+		}
+	}
+	return stale, errors.Join(errs...)
+}
+
+// RemoveStalePIDFiles deletes the pid files StalePIDFiles reports and returns
+// the removed paths.
+func RemoveStalePIDFiles(stateDir string, roles []string) ([]string, error) {
+	stale, err := StalePIDFiles(stateDir, roles)
+	if err != nil {
+		return nil, err
+	}
+	removed := make([]string, 0, len(stale))
+	var errs []error
+	for _, path := range stale {
+		if err := removePIDFile(path); err != nil {
+			errs = append(errs, fmt.Errorf("remove stale pid file %s: %w", path, err))
+			continue
+		}
+		removed = append(removed, path)
+	}
+	return removed, errors.Join(errs...)
+}
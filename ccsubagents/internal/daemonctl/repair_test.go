@@ -0,0 +1,67 @@
+package daemonctl
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRemoveStalePIDFiles_KeepsLiveProcesses(t *testing.T) {
+	resetStopProcessHooks(t)
+	stateDir := t.TempDir()
+	dead := seedRegisteredPIDFile(t, stateDir, "web", 4242, "start-4242")
+	reused := seedRegisteredPIDFile(t, stateDir, "mcp", 5151, "start-5151")
+	live := seedRegisteredPIDFile(t, stateDir, "mcp", 6161, "start-6161")
+
+	processExistsFn = func(pid int) bool { return pid != 4242 }
+	processIdentityMatchesFn = func(pid int, startID string) (bool, error) { return pid == 6161, nil }
+
+	removed, err := RemoveStalePIDFiles(stateDir, []string{"web", "mcp"})
+	if err != nil {
+		t.Fatalf("RemoveStalePIDFiles returned error: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Fatalf("expected two removed pid files, got %v", removed)
+	}
+	for _, path := range []string{dead, reused} {
+		if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+			t.Fatalf("expected %s to be removed, stat err=%v", path, statErr)
+		}
+	}
+	if _, statErr := os.Stat(live); statErr != nil {
+		t.Fatalf("expected live pid file to remain: %v", statErr)
+	}
+}
+
+func TestRepairToken_CreatesOwnerOnlyToken(t *testing.T) {
+	stateDir := t.TempDir()
+	tokenPath := filepath.Join(stateDir, "daemon", "daemon.token")
+	if err := os.MkdirAll(filepath.Dir(tokenPath), 0o755); err != nil {
+		t.Fatalf("mkdir daemon dir: %v", err)
+	}
+	if err := os.WriteFile(tokenPath, []byte("existing"), 0o644); err != nil {
+		t.Fatalf("seed token: %v", err)
+	}
+
+	if err := RepairToken(stateDir, false); err != nil {
+		t.Fatalf("RepairToken returned error: %v", err)
+	}
+	b, err := os.ReadFile(tokenPath)
+	if err != nil {
+		t.Fatalf("read token: %v", err)
+	}
+	if string(b) != "existing" {
+		t.Fatalf("expected existing token to be kept, got %q", b)
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(tokenPath)
+	if err != nil {
+		t.Fatalf("stat token: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected token mode 0600, got %#o", info.Mode().Perm())
+	}
+}
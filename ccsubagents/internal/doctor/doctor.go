@@ -2,13 +2,17 @@ package doctor
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonclient"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonctl"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/installer"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/paths"
 )
 
@@ -18,13 +22,66 @@ type Options struct {
 	Out      io.Writer
 	Getenv   func(string) string
 	LookPath func(string) (string, error)
+
+	// Config, when set, adds checks for tracked config edits that have gone
+	// missing from their files.
+	Config ConfigRepairer
+
+	// Fix makes Run repair what it can and re-run the checks. StoreRoot,
+	// DisableAuth and Stderr are used when the daemon has to be restarted.
+	Fix         bool
+	StoreRoot   string
+	DisableAuth bool
+	Stderr      io.Writer
 }
 
-func Run(ctx context.Context, opts Options) (issues int, err error) {
-	out := opts.Out
-	if out == nil {
-		out = os.Stdout
-	}
+// ConfigRepairer finds and re-applies tracked settings and MCP edits.
+type ConfigRepairer interface {
+	FindConfigDrift() ([]installer.ConfigDrift, error)
+	RepairConfigDrift() ([]installer.ConfigDrift, error)
+}
+
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+type Severity string
+
+const (
+	SeverityInfo  Severity = "info"
+	SeverityError Severity = "error"
+)
+
+// Check is the outcome of a single diagnostic. Failing checks carry a
+// remediation hint describing how to resolve them.
+type Check struct {
+	Name        string   `json:"name"`
+	Status      Status   `json:"status"`
+	Severity    Severity `json:"severity"`
+	Value       string   `json:"value,omitempty"`
+	Message     string   `json:"message,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
+}
+
+type Report struct {
+	Checks []Check `json:"checks"`
+	Issues int     `json:"issues"`
+	Fixes  []Fix   `json:"fixes,omitempty"`
+}
+
+func okCheck(name, value, message string) Check {
+	return Check{Name: name, Status: StatusOK, Severity: SeverityInfo, Value: value, Message: message}
+}
+
+func failCheck(name, value, message, remediation string) Check {
+	return Check{Name: name, Status: StatusFail, Severity: SeverityError, Value: value, Message: message, Remediation: remediation}
+}
+
+// Collect runs every diagnostic without writing anything.
+func Collect(ctx context.Context, opts Options) Report {
 	getenv := opts.Getenv
 	if getenv == nil {
 		getenv = os.Getenv
@@ -36,62 +93,58 @@ func Run(ctx context.Context, opts Options) (issues int, err error) {
 
 	resolved := paths.Resolve(opts.Home, opts.CWD, getenv)
 	daemonStateDir := paths.ResolveDaemonStateDir(opts.Home, getenv)
-	if err := writef(out, "paths.config=%s (%s)\n", resolved.ConfigDir.Value, resolved.ConfigDir.Source); err != nil {
-		return issues, err
-	}
-	if err := writef(out, "paths.state=%s (%s)\n", resolved.StateDir.Value, resolved.StateDir.Source); err != nil {
-		return issues, err
-	}
-	if err := writef(out, "paths.log=%s (%s)\n", resolved.LogDir.Value, resolved.LogDir.Source); err != nil {
-		return issues, err
-	}
-	if err := writef(out, "paths.blob=%s (%s)\n", resolved.BlobDir.Value, resolved.BlobDir.Source); err != nil {
-		return issues, err
-	}
-	if err := writef(out, "daemon.state=%s\n", daemonStateDir); err != nil {
-		return issues, err
+	checks := []Check{
+		okCheck("paths.config", resolved.ConfigDir.Value, string(resolved.ConfigDir.Source)),
+		okCheck("paths.state", resolved.StateDir.Value, string(resolved.StateDir.Source)),
+		okCheck("paths.log", resolved.LogDir.Value, string(resolved.LogDir.Source)),
+		okCheck("paths.blob", resolved.BlobDir.Value, string(resolved.BlobDir.Source)),
+		okCheck("daemon.state", daemonStateDir, ""),
 	}
 
 	for _, bin := range []string{"local-artifact-mcp", "local-artifact-web", "ccsubagentsd"} {
+		name := "binary." + bin
 		path, findErr := lookPath(bin)
 		if findErr != nil {
-			issues++
-			if err := writef(out, "missing binary: %s\n", bin); err != nil {
-				return issues, err
-			}
+			checks = append(checks, failCheck(name, "missing", findErr.Error(), "run `ccsubagents install`, or add the install directory to PATH"))
 			continue
 		}
-		if err := writef(out, "binary.%s=%s\n", bin, path); err != nil {
-			return issues, err
-		}
+		checks = append(checks, okCheck(name, path, ""))
 	}
 
 	tokenPath := filepath.Join(daemonStateDir, "daemon", "daemon.token")
 	if info, statErr := os.Stat(tokenPath); statErr != nil {
-		issues++
-		if err := writef(out, "daemon.token=missing (%v)\n", statErr); err != nil {
-			return issues, err
-		}
+		checks = append(checks, failCheck("daemon.token", "missing", statErr.Error(), "run `ccsubagents daemon start` to create the daemon token"))
+	} else if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		checks = append(checks, failCheck("daemon.token", tokenPath, fmt.Sprintf("mode=%#o, readable by other users", info.Mode().Perm()), "run `ccsubagents doctor --fix` to restrict it to the owner"))
 	} else {
-		if err := writef(out, "daemon.token=%s mode=%#o\n", tokenPath, info.Mode().Perm()); err != nil {
-			return issues, err
+		checks = append(checks, okCheck("daemon.token", tokenPath, fmt.Sprintf("mode=%#o", info.Mode().Perm())))
+	}
+
+	// The master key only exists once encrypt-at-rest has been enabled. The
+	// daemon refuses to start while it is readable by other users.
+	masterKeyPath := filepath.Join(daemonStateDir, "daemon", "master.key")
+	if info, statErr := os.Stat(masterKeyPath); statErr == nil {
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+			checks = append(checks, failCheck("daemon.master-key", masterKeyPath, fmt.Sprintf("mode=%#o, readable by other users", info.Mode().Perm()), "run `ccsubagents doctor --fix` to restrict it to the owner"))
+		} else {
+			checks = append(checks, okCheck("daemon.master-key", masterKeyPath, fmt.Sprintf("mode=%#o", info.Mode().Perm())))
 		}
 	}
 
 	client, clientErr := daemonclient.NewDefaultClient(daemonStateDir, getenv)
 	if clientErr != nil {
-		issues++
-		if err := writef(out, "daemon.client=unavailable (%v)\n", clientErr); err != nil {
-			return issues, err
-		}
+		checks = append(checks, failCheck("daemon.client", "unavailable", clientErr.Error(), "check LOCAL_ARTIFACT_DAEMON_SOCKET and LOCAL_ARTIFACT_DAEMON_ADDR"))
 	} else if err := client.Health(ctx); err != nil {
-		issues++
-		if writeErr := writef(out, "daemon.health=unavailable (%v)\n", err); writeErr != nil {
-			return issues, writeErr
-		}
+		checks = append(checks, failCheck("daemon.health", "unavailable", err.Error(), "run `ccsubagents daemon start`"))
 	} else {
-		if err := writeln(out, "daemon.health=ok"); err != nil {
-			return issues, err
+		checks = append(checks, okCheck("daemon.health", "ok", ""))
+		// Older daemons have no status endpoint; skip usage checks for them.
+		if status, statusErr := client.Status(ctx); statusErr == nil {
+			checks = append(checks, storageChecks(status)...)
+			checks = append(checks, encryptionCheck(status))
+			if verify, verifyErr := client.Verify(ctx, daemonclient.VerifyRequest{}); verifyErr == nil {
+				checks = append(checks, integrityChecks(verify)...)
+			}
 		}
 	}
 
@@ -99,13 +152,164 @@ func Run(ctx context.Context, opts Options) (issues int, err error) {
 	if readErr == nil {
 		for _, entry := range entries {
 			if strings.HasSuffix(entry.Name(), "-active.json") {
-				issues++
-				if err := writef(out, "transaction.active=%s\n", filepath.Join(resolved.StateDir.Value, "tx", entry.Name())); err != nil {
-					return issues, err
-				}
+				journal := filepath.Join(resolved.StateDir.Value, "tx", entry.Name())
+				checks = append(checks, failCheck("transaction.active", journal, "interrupted transaction", "re-run the interrupted ccsubagents command, or run `ccsubagents doctor --fix`, to roll it back"))
+			}
+		}
+	}
+
+	stale, staleErr := daemonctl.StalePIDFiles(daemonStateDir, processRoles)
+	if staleErr != nil {
+		checks = append(checks, failCheck("process.registry", "unreadable", staleErr.Error(), "check permissions of "+filepath.Join(daemonStateDir, "daemon", "processes")))
+	}
+	for _, path := range stale {
+		checks = append(checks, failCheck("process.stale", path, "registered process is no longer running", "run `ccsubagents doctor --fix` to remove the pid file"))
+	}
+
+	if opts.Config != nil {
+		drift, driftErr := opts.Config.FindConfigDrift()
+		if driftErr != nil {
+			checks = append(checks, failCheck("config.tracked", "unreadable", driftErr.Error(), "re-run `ccsubagents install` to rewrite the tracked state"))
+		}
+		for _, item := range drift {
+			checks = append(checks, failCheck("config."+item.Kind, item.File, "tracked edit "+item.Pointer+" is missing", "run `ccsubagents doctor --fix` to re-apply it"))
+		}
+	}
+
+	report := Report{Checks: checks}
+	for _, check := range checks {
+		if check.Status != StatusOK {
+			report.Issues++
+		}
+	}
+	return report
+}
+
+func encryptionCheck(status daemonclient.StatusResponse) Check {
+	if !status.EncryptAtRest {
+		return okCheck("storage.encryption", "off", "")
+	}
+	return okCheck("storage.encryption", "on", "master key "+status.MasterKeyID)
+}
+
+// quotaWarnRatio is the share of the storage quota at which doctor starts
+// reporting a workspace as an issue.
+const quotaWarnRatio = 0.9
+
+func storageChecks(status daemonclient.StatusResponse) []Check {
+	quota := status.Quota
+	limits := []string{}
+	if quota.MaxTotalBytes > 0 {
+		limits = append(limits, daemonclient.FormatByteSize(quota.MaxTotalBytes)+" per workspace")
+	}
+	if quota.MaxArtifactBytes > 0 {
+		limits = append(limits, daemonclient.FormatByteSize(quota.MaxArtifactBytes)+" per artifact")
+	}
+	if quota.MaxVersionsPerName > 0 {
+		limits = append(limits, fmt.Sprintf("%d versions per name", quota.MaxVersionsPerName))
+	}
+	quotaValue := "unlimited"
+	if len(limits) > 0 {
+		quotaValue = strings.Join(limits, ", ")
+	}
+
+	var total int64
+	for _, ws := range status.Workspaces {
+		total += ws.Usage.Bytes
+	}
+	checks := []Check{
+		okCheck("storage.quota", quotaValue, ""),
+		okCheck("storage.usage", daemonclient.FormatByteSize(total), fmt.Sprintf("%d workspaces", len(status.Workspaces))),
+	}
+	if quota.MaxTotalBytes <= 0 {
+		return checks
+	}
+	for _, ws := range status.Workspaces {
+		name := "storage.workspace." + ws.WorkspaceID
+		value := fmt.Sprintf("%s of %s", daemonclient.FormatByteSize(ws.Usage.Bytes), daemonclient.FormatByteSize(quota.MaxTotalBytes))
+		used := float64(ws.Usage.Bytes) / float64(quota.MaxTotalBytes)
+		message := fmt.Sprintf("%.0f%% used", used*100)
+		if used >= quotaWarnRatio {
+			checks = append(checks, failCheck(name, value, message, "delete artifacts you no longer need, or raise quota.max-total-bytes in settings.json and restart the daemon"))
+			continue
+		}
+		checks = append(checks, okCheck(name, value, message))
+	}
+	return checks
+}
+
+// integrityChecks reports one store.integrity check per workspace, with the
+// workspace ID as the value so --fix can repair just the failing ones.
+func integrityChecks(res daemonclient.VerifyResponse) []Check {
+	checks := make([]Check, 0, len(res.Workspaces))
+	for _, ws := range res.Workspaces {
+		if len(ws.Issues) == 0 {
+			checks = append(checks, okCheck("store.integrity", ws.WorkspaceID, fmt.Sprintf("%d versions, %d blobs", ws.Versions, ws.Blobs)))
+			continue
+		}
+		counts := map[string]int{}
+		kinds := []string{}
+		for _, issue := range ws.Issues {
+			if counts[issue.Kind] == 0 {
+				kinds = append(kinds, issue.Kind)
 			}
+			counts[issue.Kind]++
+		}
+		parts := make([]string, 0, len(kinds))
+		for _, kind := range kinds {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[kind], kind))
 		}
+		checks = append(checks, failCheck("store.integrity", ws.WorkspaceID, strings.Join(parts, ", "), "run `ccsubagents doctor --fix` or `ccsubagents artifacts verify --repair` to quarantine damaged data"))
+	}
+	return checks
+}
+
+// Run collects the diagnostics and writes them as key=value lines. With
+// opts.Fix it then repairs the failing checks, reports each fix and writes the
+// re-run checks.
+func Run(ctx context.Context, opts Options) (issues int, err error) {
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	report := Collect(ctx, opts)
+	if err := WriteText(out, report); err != nil {
+		return report.Issues, err
+	}
+	if !opts.Fix || report.Issues == 0 {
+		return report.Issues, nil
 	}
 
-	return issues, nil
+	report = Repair(ctx, opts, report)
+	if err := writeln(out); err != nil {
+		return report.Issues, err
+	}
+	if err := WriteFixes(out, report.Fixes); err != nil {
+		return report.Issues, err
+	}
+	if err := writeln(out, "\nre-check:"); err != nil {
+		return report.Issues, err
+	}
+	if err := WriteText(out, report); err != nil {
+		return report.Issues, err
+	}
+	return report.Issues, nil
+}
+
+func WriteText(out io.Writer, report Report) error {
+	for _, check := range report.Checks {
+		line := check.Name + "=" + check.Value
+		if check.Message != "" {
+			line += " (" + check.Message + ")"
+		}
+		if err := writeln(out, line); err != nil {
+			return err
+		}
+		if check.Remediation != "" {
+			if err := writef(out, "  hint: %s\n", check.Remediation); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
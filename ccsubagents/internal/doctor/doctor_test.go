@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonclient"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/paths"
 )
 
@@ -122,3 +123,92 @@ func TestRun_DaemonStateDirEnvOverrideAffectsDaemonDiagnostics(t *testing.T) {
 	}
 	assertMissingDaemonTokenPath(t, got, wantTokenPath)
 }
+
+func TestCollect_FailingChecksCarrySeverityAndRemediation(t *testing.T) {
+	home := t.TempDir()
+	cwd := t.TempDir()
+
+	report := Collect(context.Background(), Options{
+		Home:   home,
+		CWD:    cwd,
+		Getenv: func(string) string { return "" },
+		LookPath: func(string) (string, error) {
+			return "", os.ErrNotExist
+		},
+	})
+	if report.Issues == 0 {
+		t.Fatalf("expected issues, got %+v", report)
+	}
+	failing := 0
+	for _, check := range report.Checks {
+		if check.Status == StatusOK {
+			if check.Severity != SeverityInfo {
+				t.Fatalf("passing check %q should be informational, got %q", check.Name, check.Severity)
+			}
+			continue
+		}
+		failing++
+		if check.Severity != SeverityError || strings.TrimSpace(check.Remediation) == "" {
+			t.Fatalf("failing check %q should carry error severity and a remediation hint, got %+v", check.Name, check)
+		}
+	}
+	if failing != report.Issues {
+		t.Fatalf("issue count mismatch: failing=%d issues=%d", failing, report.Issues)
+	}
+}
+
+func TestStorageChecks_FlagsWorkspacesNearQuota(t *testing.T) {
+	checks := storageChecks(daemonclient.StatusResponse{
+		Quota: daemonclient.Quota{MaxTotalBytes: 100 << 20, MaxVersionsPerName: 20},
+		Workspaces: []daemonclient.WorkspaceStatus{
+			{WorkspaceID: "global", BlobBytes: 2 << 20, Usage: daemonclient.Usage{Bytes: 10 << 20}},
+			// Compressed blobs can sit well under the quota while logical usage does not.
+			{WorkspaceID: "full", BlobBytes: 40 << 20, Usage: daemonclient.Usage{Bytes: 95 << 20}},
+		},
+	})
+
+	byName := map[string]Check{}
+	for _, check := range checks {
+		byName[check.Name] = check
+	}
+	if got := byName["storage.quota"].Value; got != "100.0 MiB per workspace, 20 versions per name" {
+		t.Fatalf("storage.quota value=%q", got)
+	}
+	if got := byName["storage.usage"].Value; got != "105.0 MiB" {
+		t.Fatalf("storage.usage value=%q", got)
+	}
+	if check := byName["storage.workspace.global"]; check.Status != StatusOK || check.Message != "10% used" {
+		t.Fatalf("unexpected global check: %+v", check)
+	}
+	full := byName["storage.workspace.full"]
+	if full.Status != StatusFail || full.Remediation == "" || full.Value != "95.0 MiB of 100.0 MiB" {
+		t.Fatalf("expected failing check with remediation, got %+v", full)
+	}
+}
+
+func TestStorageChecks_UnlimitedReportsTotalsOnly(t *testing.T) {
+	checks := storageChecks(daemonclient.StatusResponse{
+		Workspaces: []daemonclient.WorkspaceStatus{{WorkspaceID: "global", BlobBytes: 1024, Usage: daemonclient.Usage{Bytes: 2048}}},
+	})
+	if len(checks) != 2 || checks[0].Value != "unlimited" || checks[1].Value != "2.0 KiB" {
+		t.Fatalf("unexpected checks: %+v", checks)
+	}
+}
+
+func TestIntegrityChecks_FailsWorkspacesWithIssues(t *testing.T) {
+	checks := integrityChecks(daemonclient.VerifyResponse{Workspaces: []daemonclient.WorkspaceIntegrity{
+		{WorkspaceID: "global", Versions: 4, Blobs: 4},
+		{WorkspaceID: "abc", Issues: []daemonclient.IntegrityIssue{
+			{Kind: "corrupt_blob"}, {Kind: "orphan_blob"}, {Kind: "corrupt_blob"},
+		}},
+	}})
+	if len(checks) != 2 {
+		t.Fatalf("checks=%+v", checks)
+	}
+	if checks[0].Status != StatusOK || checks[0].Message != "4 versions, 4 blobs" {
+		t.Fatalf("unexpected global check: %+v", checks[0])
+	}
+	if checks[1].Status != StatusFail || checks[1].Value != "abc" || checks[1].Message != "2 corrupt_blob, 1 orphan_blob" || checks[1].Remediation == "" {
+		t.Fatalf("unexpected failing check: %+v", checks[1])
+	}
+}
@@ -0,0 +1,166 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonclient"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/daemonctl"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/integration/txn"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/paths"
+)
+
+var processRoles = []string{"web", "mcp"}
+
+var (
+	recoverJournalFn = txn.RecoverIdle
+	startDaemonFn    = daemonctl.StartAndWait
+)
+
+// Fix is one repair attempted by Repair. Error is set when it failed.
+type Fix struct {
+	Check  string `json:"check"`
+	Target string `json:"target,omitempty"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Repair fixes what the failing checks in report point at, then re-runs the
+// checks. The returned report carries the fixes that were attempted.
+// Missing binaries are left to `ccsubagents install`.
+func Repair(ctx context.Context, opts Options, report Report) Report {
+	getenv := opts.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	resolved := paths.Resolve(opts.Home, opts.CWD, getenv)
+	daemonStateDir := paths.ResolveDaemonStateDir(opts.Home, getenv)
+
+	failing := map[string][]Check{}
+	for _, check := range report.Checks {
+		if check.Status != StatusOK {
+			failing[check.Name] = append(failing[check.Name], check)
+		}
+	}
+
+	var fixes []Fix
+	for _, check := range failing["transaction.active"] {
+		scopeID := strings.TrimSuffix(filepath.Base(check.Value), "-active.json")
+		fixes = append(fixes, newFix(check.Name, check.Value, "rolled back interrupted transaction", recoverJournalFn(resolved.StateDir.Value, scopeID)))
+	}
+
+	if len(failing["process.stale"]) > 0 {
+		removed, err := daemonctl.RemoveStalePIDFiles(daemonStateDir, processRoles)
+		for _, path := range removed {
+			fixes = append(fixes, newFix("process.stale", path, "removed stale pid file", nil))
+		}
+		if err != nil {
+			fixes = append(fixes, newFix("process.stale", "", "remove stale pid files", err))
+		}
+	}
+
+	if len(failing["daemon.token"]) > 0 {
+		tokenPath := filepath.Join(daemonStateDir, "daemon", "daemon.token")
+		fixes = append(fixes, newFix("daemon.token", tokenPath, "wrote daemon token with owner-only permissions", daemonctl.RepairToken(daemonStateDir, opts.DisableAuth)))
+	}
+
+	for _, check := range failing["daemon.master-key"] {
+		fixes = append(fixes, newFix(check.Name, check.Value, "restricted master key to the owner", os.Chmod(check.Value, 0o600)))
+	}
+
+	if len(failing["daemon.health"]) > 0 {
+		if fix, ok := restartDaemon(ctx, opts, daemonStateDir, getenv); ok {
+			fixes = append(fixes, fix)
+		}
+	}
+
+	if checks := failing["store.integrity"]; len(checks) > 0 {
+		fixes = append(fixes, repairIntegrity(ctx, daemonStateDir, getenv, checks)...)
+	}
+
+	if opts.Config != nil && (len(failing["config.settings"]) > 0 || len(failing["config.mcp"]) > 0) {
+		repaired, err := opts.Config.RepairConfigDrift()
+		for _, item := range repaired {
+			fixes = append(fixes, newFix("config."+item.Kind, item.File, "re-applied "+item.Pointer, nil))
+		}
+		if err != nil {
+			fixes = append(fixes, newFix("config", "", "re-apply tracked config edits", err))
+		}
+	}
+
+	recheck := Collect(ctx, opts)
+	recheck.Fixes = fixes
+	return recheck
+}
+
+// restartDaemon starts the daemon only when it is down; a daemon that answers
+// with some other error is left alone.
+func restartDaemon(ctx context.Context, opts Options, daemonStateDir string, getenv func(string) string) (Fix, bool) {
+	client, err := daemonclient.NewDefaultClient(daemonStateDir, getenv)
+	if err != nil {
+		return Fix{}, false
+	}
+	healthErr := client.Health(ctx)
+	if healthErr == nil || !daemonctl.IsDaemonStoppedOrUnavailable(healthErr) {
+		return Fix{}, false
+	}
+	err = startDaemonFn(ctx, daemonStateDir, opts.StoreRoot, opts.DisableAuth, opts.Stderr)
+	return newFix("daemon.health", daemonStateDir, "started daemon", err), true
+}
+
+func repairIntegrity(ctx context.Context, daemonStateDir string, getenv func(string) string, checks []Check) []Fix {
+	client, err := daemonclient.NewDefaultClient(daemonStateDir, getenv)
+	if err != nil {
+		return []Fix{newFix("store.integrity", "", "repair artifact store", err)}
+	}
+	fixes := make([]Fix, 0, len(checks))
+	for _, check := range checks {
+		res, err := client.Verify(ctx, daemonclient.VerifyRequest{
+			Workspace: daemonclient.WorkspaceSelector{WorkspaceID: check.Value},
+			Repair:    true,
+		})
+		repaired := 0
+		for _, ws := range res.Workspaces {
+			for _, issue := range ws.Issues {
+				if issue.Action != "" {
+					repaired++
+				}
+			}
+		}
+		fixes = append(fixes, newFix("store.integrity", check.Value, fmt.Sprintf("repaired %d issue(s)", repaired), err))
+	}
+	return fixes
+}
+
+func newFix(check, target, action string, err error) Fix {
+	fix := Fix{Check: check, Target: target, Action: action}
+	if err != nil {
+		fix.Error = err.Error()
+	}
+	return fix
+}
+
+// WriteFixes writes one "fix:" line per attempted repair.
+func WriteFixes(out io.Writer, fixes []Fix) error {
+	if len(fixes) == 0 {
+		return writeln(out, "fix: nothing to repair automatically")
+	}
+	for _, fix := range fixes {
+		line := "fix: " + fix.Check
+		if fix.Target != "" {
+			line += " " + fix.Target
+		}
+		line += ": " + fix.Action
+		if fix.Error != "" {
+			line += " (failed: " + fix.Error + ")"
+		}
+		if err := writeln(out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
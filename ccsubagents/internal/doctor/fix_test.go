@@ -0,0 +1,132 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/installer"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/paths"
+)
+
+type fakeConfigRepairer struct {
+	drift    []installer.ConfigDrift
+	repaired bool
+}
+
+func (f *fakeConfigRepairer) FindConfigDrift() ([]installer.ConfigDrift, error) {
+	if f.repaired {
+		return nil, nil
+	}
+	return f.drift, nil
+}
+
+func (f *fakeConfigRepairer) RepairConfigDrift() ([]installer.ConfigDrift, error) {
+	f.repaired = true
+	return f.drift, nil
+}
+
+func TestRun_FixRepairsFailingChecksAndRechecks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("daemon health probe uses a unix socket")
+	}
+	home := t.TempDir()
+	cwd := t.TempDir()
+	missingSocket := filepath.Join(os.TempDir(), "ccsubagents-doctor-missing.sock")
+	getenv := func(key string) string {
+		if key == "LOCAL_ARTIFACT_DAEMON_SOCKET" {
+			return missingSocket
+		}
+		return ""
+	}
+	stateDir := paths.Global(home).StateDir
+	daemonStateDir := paths.ResolveDaemonStateDir(home, getenv)
+
+	journal := filepath.Join(stateDir, "tx", "global-active.json")
+	if err := os.MkdirAll(filepath.Dir(journal), 0o755); err != nil {
+		t.Fatalf("mkdir tx: %v", err)
+	}
+	if err := os.WriteFile(journal, []byte("{}\n"), 0o600); err != nil {
+		t.Fatalf("write journal: %v", err)
+	}
+	tokenPath := filepath.Join(daemonStateDir, "daemon", "daemon.token")
+	pidPath := filepath.Join(daemonStateDir, "daemon", "processes", "web", "999999.pid")
+	if err := os.MkdirAll(filepath.Dir(pidPath), 0o755); err != nil {
+		t.Fatalf("mkdir processes: %v", err)
+	}
+	if err := os.WriteFile(tokenPath, []byte("secret"), 0o644); err != nil {
+		t.Fatalf("write token: %v", err)
+	}
+	masterKeyPath := filepath.Join(daemonStateDir, "daemon", "master.key")
+	if err := os.WriteFile(masterKeyPath, []byte(strings.Repeat("ab", 32)), 0o644); err != nil {
+		t.Fatalf("write master key: %v", err)
+	}
+	record, _ := json.Marshal(map[string]any{"pid": 999999, "start_id": "gone"})
+	if err := os.WriteFile(pidPath, record, 0o600); err != nil {
+		t.Fatalf("write pid file: %v", err)
+	}
+
+	originalStart := startDaemonFn
+	t.Cleanup(func() { startDaemonFn = originalStart })
+	started := 0
+	startDaemonFn = func(_ context.Context, gotStateDir, storeRoot string, disableAuth bool, _ io.Writer) error {
+		started++
+		if gotStateDir != daemonStateDir || storeRoot != "/store" || disableAuth {
+			t.Fatalf("unexpected start args: stateDir=%q storeRoot=%q disableAuth=%v", gotStateDir, storeRoot, disableAuth)
+		}
+		return nil
+	}
+
+	repairer := &fakeConfigRepairer{drift: []installer.ConfigDrift{{Kind: "mcp", File: filepath.Join(home, "mcp.json"), Pointer: "/servers/artifact-mcp"}}}
+	var out bytes.Buffer
+	_, err := Run(context.Background(), Options{
+		Home:      home,
+		CWD:       cwd,
+		Out:       &out,
+		Getenv:    getenv,
+		LookPath:  func(name string) (string, error) { return "/usr/bin/" + name, nil },
+		Config:    repairer,
+		Fix:       true,
+		StoreRoot: "/store",
+	})
+	if err != nil {
+		t.Fatalf("doctor run failed: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"fix: transaction.active " + journal + ": rolled back interrupted transaction",
+		"fix: process.stale " + pidPath + ": removed stale pid file",
+		"fix: daemon.token " + tokenPath,
+		"fix: daemon.master-key " + masterKeyPath + ": restricted master key to the owner",
+		"fix: daemon.health " + daemonStateDir + ": started daemon",
+		"fix: config.mcp " + filepath.Join(home, "mcp.json") + ": re-applied /servers/artifact-mcp",
+		"re-check:",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if started != 1 {
+		t.Fatalf("expected daemon to be started once, got %d", started)
+	}
+	recheck := got[strings.Index(got, "re-check:"):]
+	for _, fixed := range []string{"transaction.active=", "process.stale=", "config.mcp="} {
+		if strings.Contains(recheck, fixed) {
+			t.Fatalf("expected %q to be resolved after fixes, got:\n%s", fixed, recheck)
+		}
+	}
+	if _, statErr := os.Stat(journal); !os.IsNotExist(statErr) {
+		t.Fatalf("expected journal to be removed, stat err=%v", statErr)
+	}
+	info, statErr := os.Stat(tokenPath)
+	if statErr != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected token mode 0600, info=%v err=%v", info, statErr)
+	}
+}
@@ -197,6 +197,50 @@ func extractAgentsArchiveWithHookAndLimits(zipPath, destDir string, beforeWrite
 	return UniqueSorted(files), UniqueSorted(dirs), nil
 }
 
+// ListAgentsArchive reports the files ExtractAgentsArchiveWithHook would write
+// into destDir, without extracting anything.
+func ListAgentsArchive(zipPath, destDir string) ([]string, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer closeIgnore(r)
+
+	stripAgentsPrefix, err := shouldStripAgentsPrefix(r.File)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, file := range r.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		clean, err := cleanZipPath(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if clean == "" {
+			continue
+		}
+		if stripAgentsPrefix {
+			if clean == "agents" {
+				continue
+			}
+			clean = strings.TrimPrefix(clean, "agents/")
+			if strings.TrimSpace(clean) == "" || clean == "." {
+				continue
+			}
+		}
+		destPath := filepath.Clean(filepath.Join(destDir, filepath.FromSlash(clean)))
+		if !IsPathWithinDir(destPath, destDir) {
+			return nil, fmt.Errorf("archive path escapes destination: %s", file.Name)
+		}
+		files = append(files, destPath)
+	}
+	return UniqueSorted(files), nil
+}
+
 func minInt64(a, b int64) int64 {
 	if a < b {
 		return a
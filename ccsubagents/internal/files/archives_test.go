@@ -91,6 +91,33 @@ func TestExtractBundleBinaries_WritesRequestedFiles(t *testing.T) {
 	}
 }
 
+func TestListAgentsArchive_MatchesExtractionWithoutWriting(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "agents.zip")
+	destDir := filepath.Join(dir, "dest")
+	mustWriteZipFile(t, zipPath, map[string]string{
+		"agents/one.agent.md":        "one",
+		"agents/nested/two.agent.md": "two",
+	})
+
+	listed, err := ListAgentsArchive(zipPath, destDir)
+	if err != nil {
+		t.Fatalf("list agents archive: %v", err)
+	}
+	if _, err := os.Stat(destDir); !os.IsNotExist(err) {
+		t.Fatalf("listing must not create destination, stat err: %v", err)
+	}
+	extracted, _, err := ExtractAgentsArchiveWithHook(zipPath, destDir, nil, DefaultStateDirPerm, DefaultStateFilePerm)
+	if err != nil {
+		t.Fatalf("extract agents archive: %v", err)
+	}
+	if strings.Join(listed, ",") != strings.Join(extracted, ",") {
+		t.Fatalf("listed %v, extracted %v", listed, extracted)
+	}
+}
+
 func TestWriteZipEntry_RejectsOversizedEntryAndRemovesPartialFile(t *testing.T) {
 	t.Parallel()
 
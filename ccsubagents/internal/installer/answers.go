@@ -0,0 +1,172 @@
+package installer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pathutil "github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/paths"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
+)
+
+const (
+	targetSpecServerInsiders  = "insiders"
+	targetSpecServerStable    = "stable"
+	targetSpecDesktopInsiders = "desktop-insiders"
+	targetSpecDesktopStable   = "desktop-stable"
+	targetSpecCustomPrefix    = "custom:"
+
+	locationAnswerCwd      = "cwd"
+	locationAnswerRepoRoot = "repo-root"
+)
+
+// Answers pre-fills the interactive lifecycle prompts. Empty fields fall back
+// to prompting unless the runner is non-interactive.
+type Answers struct {
+	Targets          []string `json:"targets,omitempty"`
+	LocalMode        string   `json:"localMode,omitempty"`
+	Location         string   `json:"location,omitempty"`
+	ConfirmUninstall bool     `json:"confirmUninstall,omitempty"`
+}
+
+func LoadAnswersFile(path string) (Answers, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Answers{}, fmt.Errorf("read answers file %s: %w", path, err)
+	}
+	var answers Answers
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&answers); err != nil {
+		return Answers{}, fmt.Errorf("parse answers file %s: %w", path, err)
+	}
+	if err := answers.Validate(); err != nil {
+		return Answers{}, fmt.Errorf("answers file %s: %w", path, err)
+	}
+	return answers, nil
+}
+
+func (a Answers) Validate() error {
+	for _, spec := range a.Targets {
+		if err := validateInstallTargetSpec(spec); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(a.LocalMode) != "" {
+		if _, err := ParseLocalInstallMode(a.LocalMode); err != nil {
+			return err
+		}
+	}
+	switch strings.TrimSpace(a.Location) {
+	case "", locationAnswerCwd, locationAnswerRepoRoot:
+	default:
+		return fmt.Errorf("unknown location %q (expected: %s, %s)", a.Location, locationAnswerCwd, locationAnswerRepoRoot)
+	}
+	return nil
+}
+
+func ParseLocalInstallMode(raw string) (state.LocalInstallMode, error) {
+	switch strings.TrimSpace(raw) {
+	case string(state.LocalInstallModePersonal):
+		return state.LocalInstallModePersonal, nil
+	case string(state.LocalInstallModeTeam):
+		return state.LocalInstallModeTeam, nil
+	default:
+		return "", fmt.Errorf("unknown local mode %q (expected: personal, team)", raw)
+	}
+}
+
+func validateInstallTargetSpec(spec string) error {
+	trimmed := strings.TrimSpace(spec)
+	switch trimmed {
+	case targetSpecServerInsiders, targetSpecServerStable, targetSpecDesktopInsiders, targetSpecDesktopStable:
+		return nil
+	}
+	if strings.HasPrefix(trimmed, targetSpecCustomPrefix) {
+		if strings.TrimSpace(strings.TrimPrefix(trimmed, targetSpecCustomPrefix)) == "" {
+			return fmt.Errorf("install target %q is missing a path", spec)
+		}
+		return nil
+	}
+	for _, client := range additionalClientTargets {
+		if trimmed == string(client.kind) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown install target %q (expected: %s)", spec, strings.Join(installTargetSpecNames(), ", "))
+}
+
+func installTargetSpecNames() []string {
+	names := []string{targetSpecServerInsiders, targetSpecServerStable, targetSpecDesktopInsiders, targetSpecDesktopStable}
+	for _, client := range additionalClientTargets {
+		names = append(names, string(client.kind))
+	}
+	return append(names, targetSpecCustomPrefix+"<path>")
+}
+
+func resolveInstallTargetSpecs(home string, paths installPaths, specs []string) ([]installConfigTarget, error) {
+	targets := make([]installConfigTarget, 0, len(specs))
+	for _, spec := range specs {
+		if err := validateInstallTargetSpec(spec); err != nil {
+			return nil, err
+		}
+		trimmed := strings.TrimSpace(spec)
+		switch trimmed {
+		case targetSpecServerInsiders:
+			targets = append(targets, installConfigTarget{settingsPath: paths.insiders.settingsPath, mcpPath: paths.insiders.mcpPath})
+		case targetSpecServerStable:
+			targets = append(targets, installConfigTarget{settingsPath: paths.stable.settingsPath, mcpPath: paths.stable.mcpPath})
+		case targetSpecDesktopInsiders:
+			targets = append(targets, installConfigTarget{settingsPath: paths.desktopInsiders.settingsPath, mcpPath: paths.desktopInsiders.mcpPath})
+		case targetSpecDesktopStable:
+			targets = append(targets, installConfigTarget{settingsPath: paths.desktopStable.settingsPath, mcpPath: paths.desktopStable.mcpPath})
+		default:
+			if strings.HasPrefix(trimmed, targetSpecCustomPrefix) {
+				resolved := pathutil.ResolveConfiguredPath(home, strings.TrimPrefix(trimmed, targetSpecCustomPrefix))
+				targets = append(targets, makeCustomConfigTarget(resolved))
+				continue
+			}
+			targets = append(targets, makeClientConfigTarget(lookupClientTarget(clientKind(trimmed)), home))
+		}
+	}
+	return uniqueInstallTargets(targets), nil
+}
+
+// installTargetSpecs maps resolved targets back to replayable specs. It
+// reports false when a target has no spec form, for example a custom target
+// whose settings and MCP files do not share the custom layout.
+func installTargetSpecs(home string, paths installPaths, targets []installConfigTarget) ([]string, bool) {
+	known := map[string]installConfigTarget{
+		targetSpecServerInsiders:  {settingsPath: paths.insiders.settingsPath, mcpPath: paths.insiders.mcpPath},
+		targetSpecServerStable:    {settingsPath: paths.stable.settingsPath, mcpPath: paths.stable.mcpPath},
+		targetSpecDesktopInsiders: {settingsPath: paths.desktopInsiders.settingsPath, mcpPath: paths.desktopInsiders.mcpPath},
+		targetSpecDesktopStable:   {settingsPath: paths.desktopStable.settingsPath, mcpPath: paths.desktopStable.mcpPath},
+	}
+	specs := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if !target.isVSCode() {
+			specs = append(specs, string(target.client))
+			continue
+		}
+		matched := ""
+		for _, name := range []string{targetSpecServerInsiders, targetSpecServerStable, targetSpecDesktopInsiders, targetSpecDesktopStable} {
+			candidate := known[name]
+			if filepath.Clean(candidate.settingsPath) == filepath.Clean(target.settingsPath) && filepath.Clean(candidate.mcpPath) == filepath.Clean(target.mcpPath) {
+				matched = name
+				break
+			}
+		}
+		if matched == "" {
+			base, ok := trimConfigFileSuffix(target.settingsPath, "data", "Machine", "settings.json")
+			if !ok || makeCustomConfigTarget(base) != (installConfigTarget{settingsPath: filepath.Clean(target.settingsPath), mcpPath: filepath.Clean(target.mcpPath)}) {
+				return nil, false
+			}
+			matched = targetSpecCustomPrefix + toHomeTildePath(home, base)
+		}
+		specs = append(specs, matched)
+	}
+	return specs, true
+}
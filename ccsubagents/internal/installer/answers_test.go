@@ -0,0 +1,143 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
+)
+
+func TestRun_GlobalInstallNonInteractiveWithoutTargetsFails(t *testing.T) {
+	m := NewRunner()
+	m.homeDir = func() (string, error) { return t.TempDir(), nil }
+	m.getenv = func(string) string { return "" }
+	m.SetInstallPromptIO(errorReader{err: errors.New("prompt read should not occur")}, io.Discard)
+	m.SetNonInteractive(true)
+
+	err := m.Run(context.Background(), CommandInstall, ScopeGlobal)
+	if !errors.Is(err, ErrMissingAnswer) {
+		t.Fatalf("expected missing answer error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "--targets") {
+		t.Fatalf("expected error to point at --targets, got %v", err)
+	}
+}
+
+func TestConfirmTeamLocalUninstall_UsesAnswers(t *testing.T) {
+	m := NewRunner()
+	m.SetInstallPromptIO(errorReader{err: errors.New("prompt read should not occur")}, io.Discard)
+
+	m.SetNonInteractive(true)
+	if err := m.confirmTeamLocalUninstall(context.Background(), "/repo"); !errors.Is(err, ErrMissingAnswer) {
+		t.Fatalf("expected missing answer error, got %v", err)
+	}
+
+	m.SetAnswers(Answers{ConfirmUninstall: true})
+	if err := m.confirmTeamLocalUninstall(context.Background(), "/repo"); err != nil {
+		t.Fatalf("expected confirmation from answers, got %v", err)
+	}
+}
+
+func TestSelectLocalInstallMode_UsesAnswers(t *testing.T) {
+	m := NewRunner()
+	m.SetInstallPromptIO(errorReader{err: errors.New("prompt read should not occur")}, io.Discard)
+	m.SetNonInteractive(true)
+
+	if _, err := m.selectLocalInstallMode(context.Background()); !errors.Is(err, ErrMissingAnswer) {
+		t.Fatalf("expected missing answer error, got %v", err)
+	}
+
+	m.SetAnswers(Answers{LocalMode: "team"})
+	mode, err := m.selectLocalInstallMode(context.Background())
+	if err != nil || mode != state.LocalInstallModeTeam {
+		t.Fatalf("expected team mode from answers, got %q/%v", mode, err)
+	}
+}
+
+func TestInstallOrUpdate_RecordsAndReplaysTargetChoices(t *testing.T) {
+	home := t.TempDir()
+	agentsArchive := zipBytes(t, map[string]string{"agents/example.agent.md": "content"})
+	bundleArchive := zipBytes(t, bundleBinaryFiles("mcp-binary", "web-binary"))
+	paths := resolveInstallPaths(home)
+
+	m := statusTestManager(home, successReleaseHTTPClient(t, "v1.2.3", agentsArchive, bundleArchive), nil)
+	m.installBinary = func(src, dst string) error {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, binaryFilePerm)
+	}
+	targets, err := resolveInstallTargetSpecs(home, paths, []string{"stable", "custom:~/dev", "cursor"})
+	if err != nil {
+		t.Fatalf("resolve target specs: %v", err)
+	}
+	m.globalInstallTargets = targets
+
+	if err := m.installOrUpdate(context.Background(), false); err != nil {
+		t.Fatalf("install should succeed: %v", err)
+	}
+
+	stateDir := globalStateDirForTest(home)
+	tracked, err := state.LoadTrackedState(stateDir)
+	if err != nil {
+		t.Fatalf("load tracked state: %v", err)
+	}
+	if tracked.Choices == nil || strings.Join(tracked.Choices.Targets, ",") != "stable,custom:~/dev,cursor" {
+		t.Fatalf("unexpected recorded choices: %#v", tracked.Choices)
+	}
+
+	customMCPPath := filepath.Join(home, "dev", "data", "User", "mcp.json")
+	if err := os.Remove(customMCPPath); err != nil {
+		t.Fatalf("remove custom mcp config: %v", err)
+	}
+	tracked.ReleaseTag = "v1.0.0"
+	tracked.JSONEdits = state.TrackedJSONOps{}
+	if err := state.SaveTrackedState(stateDir, *tracked); err != nil {
+		t.Fatalf("save tracked state: %v", err)
+	}
+
+	if err := m.installOrUpdate(context.Background(), true); err != nil {
+		t.Fatalf("update should succeed: %v", err)
+	}
+	if _, err := os.Stat(customMCPPath); err != nil {
+		t.Fatalf("expected update to replay custom target, stat err: %v", err)
+	}
+	tracked, err = state.LoadTrackedState(stateDir)
+	if err != nil {
+		t.Fatalf("reload tracked state: %v", err)
+	}
+	if got := len(tracked.JSONEdits.AllMCPEdits()); got != 3 {
+		t.Fatalf("expected replayed update to track 3 mcp edits, got %d", got)
+	}
+}
+
+func TestAnswersValidate(t *testing.T) {
+	tests := []struct {
+		answers Answers
+		err     string
+	}{
+		{answers: Answers{Targets: []string{"insiders", "desktop-stable", "zed", "custom:/opt/code"}, LocalMode: "personal", Location: "repo-root"}},
+		{answers: Answers{Targets: []string{"custom:"}}, err: "missing a path"},
+		{answers: Answers{Targets: []string{"vim"}}, err: "unknown install target"},
+		{answers: Answers{LocalMode: "shared"}, err: "unknown local mode"},
+		{answers: Answers{Location: "home"}, err: "unknown location"},
+	}
+	for i, tc := range tests {
+		err := tc.answers.Validate()
+		if tc.err == "" {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("case %d: expected error containing %q, got %v", i, tc.err, err)
+		}
+	}
+}
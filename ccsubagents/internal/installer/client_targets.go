@@ -0,0 +1,128 @@
+package installer
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
+)
+
+type clientKind string
+
+const (
+	clientVSCode        clientKind = "vscode"
+	clientCursor        clientKind = "cursor"
+	clientClaudeDesktop clientKind = "claude-desktop"
+	clientZed           clientKind = "zed"
+	clientWindsurf      clientKind = "windsurf"
+)
+
+// clientTarget describes an MCP-capable client that global installs can
+// register with. Only clients with agentSettings get chat.agentFilesLocations
+// edits; the others receive the MCP server entry alone, and noAgentsReason
+// records why they have no agent definition location to point at.
+type clientTarget struct {
+	kind           clientKind
+	label          string
+	mcpFormat      config.MCPFormat
+	agentSettings  bool
+	noAgentsReason string
+	tildeCommand   bool
+	resolvePaths   func(home, goos string) configPaths
+}
+
+var vscodeClientTarget = clientTarget{
+	kind:          clientVSCode,
+	label:         "VS Code",
+	mcpFormat:     config.VSCodeMCPFormat,
+	agentSettings: true,
+	tildeCommand:  true,
+}
+
+var additionalClientTargets = []clientTarget{
+	{
+		kind:           clientCursor,
+		label:          "Cursor",
+		mcpFormat:      config.MCPServersFormat,
+		noAgentsReason: "Cursor has no user-level agent definition directory; project rules in .cursor/rules are not agents",
+		resolvePaths: func(home, _ string) configPaths {
+			return configPaths{mcpPath: filepath.Join(home, ".cursor", "mcp.json")}
+		},
+	},
+	{
+		kind:           clientClaudeDesktop,
+		label:          "Claude Desktop",
+		mcpFormat:      config.MCPServersFormat,
+		noAgentsReason: "Claude Desktop has no file-based agent or rules location",
+		resolvePaths: func(home, goos string) configPaths {
+			switch goos {
+			case "windows":
+				return configPaths{mcpPath: filepath.Join(home, "AppData", "Roaming", "Claude", "claude_desktop_config.json")}
+			case "darwin":
+				return configPaths{mcpPath: filepath.Join(home, "Library", "Application Support", "Claude", "claude_desktop_config.json")}
+			default:
+				return configPaths{mcpPath: filepath.Join(home, ".config", "Claude", "claude_desktop_config.json")}
+			}
+		},
+	},
+	{
+		kind:           clientZed,
+		label:          "Zed",
+		mcpFormat:      config.ZedMCPFormat,
+		noAgentsReason: "Zed keeps rules in its in-app Rules Library, not in files",
+		resolvePaths: func(home, goos string) configPaths {
+			if goos == "windows" {
+				return configPaths{mcpPath: filepath.Join(home, "AppData", "Roaming", "Zed", "settings.json")}
+			}
+			return configPaths{mcpPath: filepath.Join(home, ".config", "zed", "settings.json")}
+		},
+	},
+	{
+		kind:           clientWindsurf,
+		label:          "Windsurf",
+		mcpFormat:      config.MCPServersFormat,
+		noAgentsReason: "Windsurf has a single user-owned global_rules.md and no agent definition directory",
+		resolvePaths: func(home, _ string) configPaths {
+			return configPaths{mcpPath: filepath.Join(home, ".codeium", "windsurf", "mcp_config.json")}
+		},
+	},
+}
+
+func lookupClientTarget(kind clientKind) clientTarget {
+	for _, client := range additionalClientTargets {
+		if client.kind == kind {
+			return client
+		}
+	}
+	return vscodeClientTarget
+}
+
+func makeClientConfigTarget(client clientTarget, home string) installConfigTarget {
+	resolved := client.resolvePaths(home, runtime.GOOS)
+	return installConfigTarget{client: client.kind, mcpPath: resolved.mcpPath}
+}
+
+func (t installConfigTarget) clientTarget() clientTarget {
+	return lookupClientTarget(t.client)
+}
+
+func (t installConfigTarget) isVSCode() bool {
+	return t.clientTarget().kind == clientVSCode
+}
+
+func clientKindForMCPEdit(edit state.MCPEdit) clientKind {
+	trimmed := strings.TrimSpace(edit.Client)
+	if trimmed == "" {
+		return clientVSCode
+	}
+	return lookupClientTarget(clientKind(trimmed)).kind
+}
+
+func mcpCommandPathForClient(client clientTarget, home, binaryPath string) string {
+	if client.tildeCommand {
+		return toHomeTildePath(home, binaryPath)
+	}
+	return filepath.Clean(binaryPath)
+}
@@ -0,0 +1,187 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
+)
+
+func TestInstallOrUpdate_ClientTargetsUseClientMCPFormat(t *testing.T) {
+	home := t.TempDir()
+	agentsArchive := zipBytes(t, map[string]string{"agents/example.agent.md": "content"})
+	bundleArchive := zipBytes(t, bundleBinaryFiles("mcp-binary", "web-binary"))
+
+	cursor := makeClientConfigTarget(lookupClientTarget(clientCursor), home)
+	zed := makeClientConfigTarget(lookupClientTarget(clientZed), home)
+	if err := os.MkdirAll(filepath.Dir(zed.mcpPath), stateDirPerm); err != nil {
+		t.Fatalf("mkdir zed config dir: %v", err)
+	}
+	if err := writeJSONMap(zed.mcpPath, map[string]any{"theme": "One Dark"}); err != nil {
+		t.Fatalf("seed zed settings: %v", err)
+	}
+	zedSeed, err := os.ReadFile(zed.mcpPath)
+	if err != nil {
+		t.Fatalf("read zed seed: %v", err)
+	}
+
+	m := statusTestManager(home, successReleaseHTTPClient(t, "v1.2.3", agentsArchive, bundleArchive), nil)
+	m.installBinary = func(src, dst string) error {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, binaryFilePerm)
+	}
+	m.globalInstallTargets = []installConfigTarget{cursor, zed}
+
+	if err = m.installOrUpdate(context.Background(), false); err != nil {
+		t.Fatalf("install should succeed: %v", err)
+	}
+
+	mcpBinaryName, _ := localArtifactBinaryNames(runtime.GOOS)
+	wantCommand := filepath.Join(resolveInstallPaths(home).binaryDir, mcpBinaryName)
+
+	cursorRoot, err := readJSONMap(cursor.mcpPath)
+	if err != nil {
+		t.Fatalf("read cursor mcp config: %v", err)
+	}
+	cursorServer := mustMap(t, mustMap(t, cursorRoot["mcpServers"], "mcpServers")[config.MCPServerKey], "cursor artifact server")
+	if mustString(t, cursorServer["command"], "cursor command") != wantCommand {
+		t.Fatalf("expected absolute command for cursor, got %#v", cursorServer)
+	}
+
+	zedRoot, err := readJSONMap(zed.mcpPath)
+	if err != nil {
+		t.Fatalf("read zed settings: %v", err)
+	}
+	if zedRoot["theme"] != "One Dark" {
+		t.Fatalf("expected unrelated zed settings preserved, got %#v", zedRoot)
+	}
+	zedServer := mustMap(t, mustMap(t, zedRoot["context_servers"], "context_servers")[config.MCPServerKey], "zed artifact server")
+	if zedServer["source"] != "custom" || zedServer["command"] != wantCommand {
+		t.Fatalf("unexpected zed server entry: %#v", zedServer)
+	}
+
+	tracked, err := state.LoadTrackedState(globalStateDirForTest(home))
+	if err != nil {
+		t.Fatalf("load tracked state: %v", err)
+	}
+	if got := len(tracked.JSONEdits.AllSettingsEdits()); got != 0 {
+		t.Fatalf("expected no settings edits for non-VS Code clients, got %d", got)
+	}
+	edits := tracked.JSONEdits.AllMCPEdits()
+	if len(edits) != 2 {
+		t.Fatalf("expected 2 tracked mcp edits, got %d", len(edits))
+	}
+	if edits[0].Client != string(clientCursor) || edits[0].ServersKey != "mcpServers" {
+		t.Fatalf("unexpected cursor edit: %#v", edits[0])
+	}
+	if edits[1].Client != string(clientZed) || edits[1].ServersKey != "context_servers" {
+		t.Fatalf("unexpected zed edit: %#v", edits[1])
+	}
+
+	if err := m.uninstall(context.Background()); err != nil {
+		t.Fatalf("uninstall should succeed: %v", err)
+	}
+	cursorRoot, err = readJSONMap(cursor.mcpPath)
+	if err != nil {
+		t.Fatalf("read cursor mcp config after uninstall: %v", err)
+	}
+	if _, ok := cursorRoot["mcpServers"]; ok {
+		t.Fatalf("expected cursor mcpServers created by install to be removed, got %#v", cursorRoot)
+	}
+	zedAfter, err := os.ReadFile(zed.mcpPath)
+	if err != nil {
+		t.Fatalf("read zed settings after uninstall: %v", err)
+	}
+	if !bytes.Equal(zedAfter, zedSeed) {
+		t.Fatalf("expected zed settings restored exactly\nwant:\n%s\ngot:\n%s", zedSeed, zedAfter)
+	}
+}
+
+func TestResolveUpdateTargets_KeepsClientTargetsSeparate(t *testing.T) {
+	home := filepath.Join(string(os.PathSeparator), "home", "user")
+	paths := resolveInstallPaths(home)
+	cursorPath := filepath.Join(home, ".cursor", "mcp.json")
+
+	previous := &state.TrackedState{
+		JSONEdits: state.TrackedJSONOpsFromEdits(
+			[]state.SettingsEdit{{File: paths.stable.settingsPath}},
+			[]state.MCPEdit{
+				{File: cursorPath, Client: string(clientCursor), ServersKey: "mcpServers", Touched: true},
+				{File: paths.stable.mcpPath, Touched: true},
+			},
+		),
+	}
+
+	targets := resolveUpdateTargets(paths, previous)
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 update targets, got %#v", targets)
+	}
+	if targets[0].settingsPath != paths.stable.settingsPath || targets[0].mcpPath != paths.stable.mcpPath || !targets[0].isVSCode() {
+		t.Fatalf("expected VS Code target paired with stable settings, got %#v", targets[0])
+	}
+	if targets[1].client != clientCursor || targets[1].mcpPath != cursorPath || targets[1].settingsPath != "" {
+		t.Fatalf("expected cursor client target, got %#v", targets[1])
+	}
+}
+
+func TestPromptGlobalInstallTargets_SelectsAdditionalClients(t *testing.T) {
+	home := t.TempDir()
+	var promptOut bytes.Buffer
+	m := NewRunner()
+	m.SetInstallPromptIO(strings.NewReader("6,9\n"), &promptOut)
+
+	targets, err := m.promptGlobalInstallTargets(context.Background(), home, resolveInstallPaths(home))
+	if err != nil {
+		t.Fatalf("promptGlobalInstallTargets returned error: %v", err)
+	}
+	if len(targets) != 2 || targets[0].client != clientCursor || targets[1].client != clientWindsurf {
+		t.Fatalf("unexpected client targets: %#v", targets)
+	}
+	for _, want := range []string{"[6] Cursor", "[7] Claude Desktop", "[8] Zed", "[9] Windsurf"} {
+		if !strings.Contains(promptOut.String(), want) {
+			t.Fatalf("expected prompt output to contain %q, got %q", want, promptOut.String())
+		}
+	}
+}
+
+func TestInstallOrUpdate_ReportsWhyClientTargetsGetNoAgents(t *testing.T) {
+	home := t.TempDir()
+	agentsArchive := zipBytes(t, map[string]string{"agents/example.agent.md": "content"})
+	bundleArchive := zipBytes(t, bundleBinaryFiles("mcp-binary", "web-binary"))
+
+	var out bytes.Buffer
+	m := statusTestManager(home, successReleaseHTTPClient(t, "v1.2.3", agentsArchive, bundleArchive), &out)
+	m.verbose = true
+	targets, err := resolveInstallTargetSpecs(home, resolveInstallPaths(home), []string{"stable", "cursor", "zed", "windsurf"})
+	if err != nil {
+		t.Fatalf("resolve target specs: %v", err)
+	}
+	m.globalInstallTargets = targets
+
+	if err := m.installOrUpdate(context.Background(), false); err != nil {
+		t.Fatalf("install should succeed: %v", err)
+	}
+
+	status := out.String()
+	for _, want := range []string{
+		"Cursor gets no agent definitions: Cursor has no user-level agent definition directory",
+		"Zed gets no agent definitions: Zed keeps rules in its in-app Rules Library",
+		"Windsurf gets no agent definitions: Windsurf has a single user-owned global_rules.md",
+	} {
+		if !strings.Contains(status, want) {
+			t.Fatalf("expected status to contain %q, got:\n%s", want, status)
+		}
+	}
+	if strings.Contains(status, "VS Code gets no agent definitions") {
+		t.Fatalf("VS Code targets get agent definitions, got:\n%s", status)
+	}
+}
@@ -0,0 +1,262 @@
+package installer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/files"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/integration/txn"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
+)
+
+// changePreview is the dry-run flowEffects. It records each change the flow
+// asks for, collapsing repeated directory creations and skipping no-op edits.
+// Release assets are still downloaded and verified, into a temp dir, so that
+// the binaries and agent files listed are the ones apply would install.
+type changePreview struct {
+	preview     *txn.Preview
+	plannedDirs map[string]struct{}
+}
+
+func newChangePreview(preview *txn.Preview) *changePreview {
+	return &changePreview{preview: preview, plannedDirs: map[string]struct{}{}}
+}
+
+func (c *changePreview) ensureDir(path string) {
+	missing := []string{}
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, planned := c.plannedDirs[dir]; planned {
+			break
+		}
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	for idx := len(missing) - 1; idx >= 0; idx-- {
+		c.plannedDirs[missing[idx]] = struct{}{}
+		c.preview.Record(txn.Change{Kind: txn.ChangeCreateDir, Path: missing[idx]})
+	}
+}
+
+func (c *changePreview) writeFile(path string) {
+	c.ensureDir(filepath.Dir(path))
+	c.preview.Record(txn.Change{Kind: txn.ChangeWriteFile, Path: filepath.Clean(path)})
+}
+
+func (c *changePreview) editJSON(change config.JSONChange) {
+	if !change.Changed() {
+		return
+	}
+	c.ensureDir(filepath.Dir(change.File))
+	c.preview.Record(txn.Change{Kind: txn.ChangeEditJSON, Path: change.File, Pointer: change.Pointer, Before: change.Before, After: change.After})
+}
+
+func (c *changePreview) ignoreLines(kind txn.ChangeKind, path string, lines []string) {
+	if strings.TrimSpace(path) == "" || len(lines) == 0 {
+		return
+	}
+	if kind == txn.ChangeAddIgnore {
+		c.ensureDir(filepath.Dir(path))
+	}
+	c.preview.Record(txn.Change{Kind: kind, Path: path, Lines: append([]string{}, lines...)})
+}
+
+func (c *changePreview) begin(string, string, string, string) error { return nil }
+
+func (c *changePreview) commit() error { return nil }
+
+func (c *changePreview) end(*error) {}
+
+func (c *changePreview) downloadDir(string) string {
+	return ""
+}
+
+func (c *changePreview) mkdirAll(path string) error {
+	c.ensureDir(path)
+	return nil
+}
+
+func (c *changePreview) ensureDirWithinBase(path, _ string) error {
+	c.ensureDir(path)
+	return nil
+}
+
+func (c *changePreview) ensureParentDirWithinBase(path, _ string) error {
+	c.ensureDir(filepath.Dir(path))
+	return nil
+}
+
+func (c *changePreview) snapshotFile(string) error { return nil }
+
+func (c *changePreview) createdDirectories() []string { return nil }
+
+func (c *changePreview) installBinaries(ctx context.Context, bundleBinaries map[string]string, destinationDir, _, goos string) ([]string, error) {
+	binaryPaths := []string{}
+	for _, name := range bundleBinaryNames(bundleBinaries, goos) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		path := filepath.Join(destinationDir, name)
+		c.writeFile(path)
+		binaryPaths = append(binaryPaths, path)
+	}
+	return binaryPaths, nil
+}
+
+func (c *changePreview) extractAgents(zipPath, agentsDir string) ([]string, []string, error) {
+	agentFiles, err := files.ListAgentsArchive(zipPath, agentsDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, path := range agentFiles {
+		c.writeFile(path)
+	}
+	return agentFiles, nil, nil
+}
+
+func (c *changePreview) applySettingsEdit(path, agentsPath string, previous *state.TrackedState) (state.SettingsEdit, error) {
+	change, err := config.PreviewSettingsEdit(path, agentsPath, previous)
+	if err != nil {
+		return state.SettingsEdit{}, err
+	}
+	c.editJSON(change)
+	return state.SettingsEdit{File: path}, nil
+}
+
+func (c *changePreview) applyMCPEdit(path string, format config.MCPFormat, commandPath string, previous *state.TrackedState) (state.MCPEdit, error) {
+	change, err := config.PreviewMCPEdit(path, format, commandPath, previous)
+	if err != nil {
+		return state.MCPEdit{}, err
+	}
+	c.editJSON(change)
+	return state.MCPEdit{File: path}, nil
+}
+
+func (c *changePreview) applyLocalIgnoreRules(installRoot, repoRoot string, mode state.LocalInstallMode) ([]state.IgnoreEdit, error) {
+	ignoreFile, lines, err := config.PreviewLocalIgnoreRules(installRoot, repoRoot, mode)
+	if err != nil {
+		return nil, err
+	}
+	c.ignoreLines(txn.ChangeAddIgnore, ignoreFile, lines)
+	return nil, nil
+}
+
+func (c *changePreview) writePinnedVersion(path, versionTag string) error {
+	change, err := config.PreviewPinnedVersion(path, versionTag)
+	if err != nil {
+		return err
+	}
+	c.editJSON(change)
+	return nil
+}
+
+func (c *changePreview) removeStaleAgentFiles(oldFiles, newFiles []string, agentsDir string) error {
+	for _, path := range oldFiles {
+		clean := filepath.Clean(path)
+		if files.IsPathWithinDir(clean, agentsDir) && !containsCleanPath(newFiles, clean) {
+			if err := c.removeFile(clean); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *changePreview) removeFile(path string) error {
+	if _, err := os.Lstat(path); err != nil {
+		return nil
+	}
+	c.preview.Record(txn.Change{Kind: txn.ChangeRemoveFile, Path: filepath.Clean(path)})
+	return nil
+}
+
+func (c *changePreview) removeDir(path string) error {
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return nil
+	}
+	c.preview.Record(txn.Change{Kind: txn.ChangeRemoveDir, Path: filepath.Clean(path)})
+	return nil
+}
+
+func (c *changePreview) revertSettingsEdit(edit state.SettingsEdit) error {
+	change, err := config.PreviewSettingsRevert(edit)
+	if err != nil {
+		return err
+	}
+	c.editJSON(change)
+	return nil
+}
+
+func (c *changePreview) revertMCPEdit(edit state.MCPEdit) error {
+	change, err := config.PreviewMCPRevert(edit)
+	if err != nil {
+		return err
+	}
+	c.editJSON(change)
+	return nil
+}
+
+func (c *changePreview) revertIgnoreEdits(edits []state.IgnoreEdit) error {
+	reverts, err := config.PreviewIgnoreRevert(edits)
+	if err != nil {
+		return err
+	}
+	for _, edit := range reverts {
+		c.ignoreLines(txn.ChangeRemoveIgnore, edit.File, edit.AddedLines)
+	}
+	return nil
+}
+
+func (c *changePreview) saveTrackedState(stateDir string, _ state.TrackedState) error {
+	c.writeFile(filepath.Join(stateDir, state.TrackedFileName))
+	return nil
+}
+
+func (c *changePreview) stopDaemon(context.Context) error { return nil }
+
+func (r *Runner) reportPreview(preview *txn.Preview) error {
+	if r.statusOut == nil {
+		return nil
+	}
+	if r.jsonStatus() {
+		r.emitStatus(statusEvent{Type: "plan", Plan: preview})
+		if r.statusErr != nil {
+			return fmt.Errorf("write dry-run plan: %w", r.statusErr)
+		}
+		return nil
+	}
+
+	r.statusf("\nDry run: %s would make %d change(s); nothing was applied.\n", preview.Command, len(preview.Changes))
+	for _, change := range preview.Changes {
+		switch change.Kind {
+		case txn.ChangeEditJSON:
+			r.statusf("  %-13s %s %s\n", change.Kind, change.Path, change.Pointer)
+			r.statusf("                  - %s\n", jsonOrAbsent(change.Before))
+			r.statusf("                  + %s\n", jsonOrAbsent(change.After))
+		case txn.ChangeAddIgnore, txn.ChangeRemoveIgnore:
+			r.statusf("  %-13s %s\n", change.Kind, change.Path)
+			for _, line := range change.Lines {
+				r.statusf("                  %s\n", line)
+			}
+		default:
+			r.statusf("  %-13s %s\n", change.Kind, change.Path)
+		}
+	}
+	return nil
+}
+
+func jsonOrAbsent(raw json.RawMessage) string {
+	if raw == nil {
+		return "(absent)"
+	}
+	return string(raw)
+}
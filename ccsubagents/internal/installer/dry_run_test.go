@@ -0,0 +1,149 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/integration/txn"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
+)
+
+func TestRun_LocalInstallDryRunReportsChangesWithoutApplying(t *testing.T) {
+	home := t.TempDir()
+	repo := t.TempDir()
+	agentsArchive := zipBytes(t, map[string]string{"agents/example.agent.md": "content"})
+	bundleArchive := zipBytes(t, bundleBinaryFiles("mcp-binary", "web-binary"))
+
+	var out bytes.Buffer
+	m := statusTestManager(home, successReleaseHTTPClient(t, "v1.2.3", agentsArchive, bundleArchive), &out)
+	m.workingDir = func() (string, error) { return repo, nil }
+	m.runCommand = func(context.Context, string, ...string) ([]byte, error) { return []byte(repo + "\n"), nil }
+	m.SetInstallPromptIO(errorReader{err: io.ErrUnexpectedEOF}, io.Discard)
+	m.SetNonInteractive(true)
+	m.SetAnswers(Answers{LocalMode: "team"})
+	m.SetDryRun(true)
+	m.SetOutputFormat(OutputJSON)
+
+	if err := m.Run(context.Background(), CommandInstall, ScopeLocal); err != nil {
+		t.Fatalf("dry run should succeed: %v", err)
+	}
+
+	events := decodeStatusEvents(t, out.Bytes())
+	if len(events) == 0 || events[len(events)-1].Type != "plan" || events[len(events)-1].Plan == nil {
+		t.Fatalf("expected plan event last on status writer, got %#v", events)
+	}
+	preview := *events[len(events)-1].Plan
+	if preview.Command != "local-install" || len(preview.Steps) != 1 {
+		t.Fatalf("unexpected preview header: %#v", preview)
+	}
+
+	mcpPath := filepath.Join(repo, config.LocalMCPRelativePath)
+	gitignorePath := filepath.Join(repo, ".gitignore")
+	seen := map[txn.ChangeKind][]txn.Change{}
+	for _, change := range preview.Changes {
+		seen[change.Kind] = append(seen[change.Kind], change)
+	}
+	if !hasPreviewChange(seen[txn.ChangeWriteFile], filepath.Join(repo, config.LocalAgentsRelativePath, "example.agent.md")) {
+		t.Fatalf("expected agent file write in preview, got %#v", seen[txn.ChangeWriteFile])
+	}
+	if !hasPreviewChange(seen[txn.ChangeEditJSON], mcpPath) || seen[txn.ChangeEditJSON][0].Pointer != "/servers/artifact-mcp" {
+		t.Fatalf("expected mcp json edit in preview, got %#v", seen[txn.ChangeEditJSON])
+	}
+	ignore := seen[txn.ChangeAddIgnore]
+	if len(ignore) != 1 || ignore[0].Path != gitignorePath || strings.Join(ignore[0].Lines, ",") != config.LocalManagedDirRelativePath {
+		t.Fatalf("expected .gitignore lines in preview, got %#v", ignore)
+	}
+
+	for _, path := range []string{mcpPath, gitignorePath, filepath.Join(repo, config.LocalManagedDirRelativePath), filepath.Join(globalStateDirForTest(home), state.TrackedFileName)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("dry run must not create %s, stat err: %v", path, err)
+		}
+	}
+}
+
+func TestRun_GlobalUninstallDryRunListsRevertsInText(t *testing.T) {
+	home := t.TempDir()
+	stateDir := globalStateDirForTest(home)
+	agentPath := filepath.Join(globalAgentsDirForTest(home), "example.agent.md")
+	mcpPath := filepath.Join(home, "mcp.json")
+	if err := os.MkdirAll(filepath.Dir(agentPath), stateDirPerm); err != nil {
+		t.Fatalf("mkdir agents: %v", err)
+	}
+	if err := os.WriteFile(agentPath, []byte("content"), stateFilePerm); err != nil {
+		t.Fatalf("seed agent: %v", err)
+	}
+	if err := writeJSONMap(mcpPath, map[string]any{"servers": map[string]any{config.MCPServerKey: map[string]any{"command": "x"}}}); err != nil {
+		t.Fatalf("seed mcp: %v", err)
+	}
+	if err := state.SaveTrackedState(stateDir, state.TrackedState{
+		Version:    state.TrackedSchemaVersion,
+		ReleaseTag: "v1.0.0",
+		Managed:    state.ManagedState{Files: []string{agentPath}},
+		JSONEdits:  state.TrackedJSONOpsFromEdits(nil, []state.MCPEdit{{File: mcpPath, Key: config.MCPServerKey, Touched: true}}),
+	}); err != nil {
+		t.Fatalf("save tracked state: %v", err)
+	}
+
+	var out bytes.Buffer
+	m := statusTestManager(home, nil, &out)
+	m.SetDryRun(true)
+	if err := m.Run(context.Background(), CommandUninstall, ScopeGlobal); err != nil {
+		t.Fatalf("dry run should succeed: %v", err)
+	}
+
+	for _, want := range []string{"Dry run: global-uninstall", "remove-file", agentPath, "edit-json", "/servers/artifact-mcp", "+ (absent)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected text preview to contain %q, got:\n%s", want, out.String())
+		}
+	}
+	if _, err := os.Stat(agentPath); err != nil {
+		t.Fatalf("dry run must not remove managed files: %v", err)
+	}
+	root, err := readJSONMap(mcpPath)
+	if err != nil {
+		t.Fatalf("read mcp: %v", err)
+	}
+	if _, ok := mustMap(t, root["servers"], "servers")[config.MCPServerKey]; !ok {
+		t.Fatalf("dry run must not revert mcp config")
+	}
+}
+
+func TestRun_GlobalUpdateDryRunVerifiesAttestations(t *testing.T) {
+	home := t.TempDir()
+	agentsArchive := zipBytes(t, map[string]string{"agents/example.agent.md": "content"})
+	bundleArchive := zipBytes(t, bundleBinaryFiles("mcp-binary", "web-binary"))
+
+	var out bytes.Buffer
+	m := statusTestManager(home, successReleaseHTTPClient(t, "v1.2.3", agentsArchive, bundleArchive), &out)
+	m.runCommand = func(context.Context, string, ...string) ([]byte, error) {
+		return nil, errors.New("verification failed")
+	}
+	m.SetDryRun(true)
+
+	err := m.Run(context.Background(), CommandUpdate, ScopeGlobal)
+	if err == nil || !strings.Contains(err.Error(), "attestation verification failed") {
+		t.Fatalf("expected dry run to fail attestation verification like apply, got %v", err)
+	}
+	if strings.Contains(out.String(), "Dry run:") {
+		t.Fatalf("expected no plan after failed verification, got:\n%s", out.String())
+	}
+	if _, statErr := os.Stat(globalStateDirForTest(home)); !os.IsNotExist(statErr) {
+		t.Fatalf("dry run must not create the state directory, stat err: %v", statErr)
+	}
+}
+
+func hasPreviewChange(changes []txn.Change, path string) bool {
+	for _, change := range changes {
+		if change.Path == path {
+			return true
+		}
+	}
+	return false
+}
@@ -2,4 +2,7 @@ package installer
 
 import "errors"
 
-var ErrPinnedRequiresVersion = errors.New("--pinned requires --version")
+var (
+	ErrPinnedRequiresVersion = errors.New("--pinned requires --version")
+	ErrMissingAnswer         = errors.New("missing answer in non-interactive mode")
+)
@@ -16,7 +16,6 @@ import (
 
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/files"
-	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/integration/txn"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/paths"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/release"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
@@ -161,22 +160,13 @@ func (r *Runner) resolveInstallSettingsContext() (home, settingsRoot string, set
 	return home, settingsRoot, settings, nil
 }
 
-func (r *Runner) persistPendingPinWrite(mutations *files.MutationTracker) error {
+func (r *Runner) persistPendingPinWrite(fx flowEffects) error {
 	if r.pendingPinWrite == nil {
 		return nil
 	}
 
 	pending := *r.pendingPinWrite
-	if mutations != nil {
-		if err := mutations.EnsureParentDirWithinBase(pending.path, filepath.Dir(pending.path)); err != nil {
-			return err
-		}
-		if err := mutations.SnapshotFile(pending.path); err != nil {
-			return err
-		}
-	}
-
-	if err := config.WritePinnedVersion(pending.path, pending.versionTag, stateDirPerm, stateFilePerm); err != nil {
+	if err := fx.writePinnedVersion(pending.path, pending.versionTag); err != nil {
 		return err
 	}
 	r.reportDetail("saved pinned-version %s to %s settings (%s)", pending.versionTag, pending.scope, pending.path)
@@ -200,23 +190,52 @@ func resolveInstallTargets(paths installPaths, destination installDestination) (
 	}
 }
 
+func (r *Runner) resolveGlobalConfigTargets(home string, paths installPaths, previousGlobal *state.TrackedState, isUpdate bool) ([]installConfigTarget, error) {
+	if isUpdate {
+		if previousGlobal != nil && previousGlobal.Choices != nil && len(previousGlobal.Choices.Targets) > 0 {
+			targets, err := resolveInstallTargetSpecs(home, paths, previousGlobal.Choices.Targets)
+			if err != nil {
+				return nil, fmt.Errorf("replay tracked install targets: %w", err)
+			}
+			return targets, nil
+		}
+		return resolveUpdateTargets(paths, previousGlobal), nil
+	}
+	if len(r.globalInstallTargets) > 0 {
+		return uniqueInstallTargets(r.globalInstallTargets), nil
+	}
+	destination := r.installDestination
+	if destination == "" {
+		destination = installDestinationInsiders
+	}
+	return resolveInstallTargets(paths, destination)
+}
+
 func resolveUpdateTargets(paths installPaths, previous *state.TrackedState) []installConfigTarget {
 	if previous == nil {
 		return []installConfigTarget{{settingsPath: paths.insiders.settingsPath, mcpPath: paths.insiders.mcpPath}}
 	}
 
 	settingsEdits := previous.JSONEdits.AllSettingsEdits()
-	mcpEdits := previous.JSONEdits.AllMCPEdits()
+	mcpEdits := make([]state.MCPEdit, 0, len(previous.JSONEdits.AllMCPEdits()))
+	clientTargets := []installConfigTarget{}
+	for _, edit := range previous.JSONEdits.AllMCPEdits() {
+		if kind := clientKindForMCPEdit(edit); kind != clientVSCode {
+			clientTargets = append(clientTargets, installConfigTarget{client: kind, mcpPath: edit.File})
+			continue
+		}
+		mcpEdits = append(mcpEdits, edit)
+	}
 	count := len(settingsEdits)
 	if len(mcpEdits) > count {
 		count = len(mcpEdits)
 	}
 
-	if count == 0 {
+	if count == 0 && len(clientTargets) == 0 {
 		return []installConfigTarget{{settingsPath: paths.insiders.settingsPath, mcpPath: paths.insiders.mcpPath}}
 	}
 
-	targets := make([]installConfigTarget, 0, count)
+	targets := make([]installConfigTarget, 0, count+len(clientTargets))
 	for idx := 0; idx < count; idx++ {
 		target := installConfigTarget{}
 		if idx < len(settingsEdits) {
@@ -233,6 +252,7 @@ func resolveUpdateTargets(paths installPaths, previous *state.TrackedState) []in
 		}
 		targets = append(targets, target)
 	}
+	targets = append(targets, clientTargets...)
 
 	return uniqueInstallTargets(targets)
 }
@@ -243,17 +263,22 @@ func uniqueInstallTargets(targets []installConfigTarget) []installConfigTarget {
 	for _, target := range targets {
 		settingsPath := strings.TrimSpace(target.settingsPath)
 		mcpPath := strings.TrimSpace(target.mcpPath)
-		if settingsPath == "" || mcpPath == "" {
+		if mcpPath == "" || (settingsPath == "" && target.clientTarget().agentSettings) {
 			continue
 		}
-		key := filepath.Clean(settingsPath) + "\n" + filepath.Clean(mcpPath)
+		if settingsPath != "" {
+			settingsPath = filepath.Clean(settingsPath)
+		}
+		mcpPath = filepath.Clean(mcpPath)
+		key := string(target.client) + "\n" + settingsPath + "\n" + mcpPath
 		if _, exists := seen[key]; exists {
 			continue
 		}
 		seen[key] = struct{}{}
 		out = append(out, installConfigTarget{
-			settingsPath: filepath.Clean(settingsPath),
-			mcpPath:      filepath.Clean(mcpPath),
+			client:       target.client,
+			settingsPath: settingsPath,
+			mcpPath:      mcpPath,
 		})
 	}
 	return out
@@ -422,22 +447,23 @@ type fileSnapshotter interface {
 	SnapshotFile(string) error
 }
 
-func (r *Runner) installExtractedBinaries(ctx context.Context, bundleBinaries map[string]string, destinationDir string, mutations fileSnapshotter, permissionHintDir, goos string) ([]string, error) {
+// bundleBinaryNames lists the binaries installed from an extracted bundle: the
+// MCP and web binaries always, and the daemon when the bundle ships it.
+func bundleBinaryNames(bundleBinaries map[string]string, goos string) []string {
 	mcpBinaryName, webBinaryName := localArtifactBinaryNames(goos)
 	binaryNames := []string{mcpBinaryName, webBinaryName}
 	daemonBinaryName := ccsubagentsdBinaryName(goos)
 	if _, ok := bundleBinaries[daemonBinaryName]; ok {
 		binaryNames = append(binaryNames, daemonBinaryName)
 	}
+	return binaryNames
+}
 
-	binaryPaths := []string{
-		filepath.Join(destinationDir, mcpBinaryName),
-		filepath.Join(destinationDir, webBinaryName),
-	}
-	if len(binaryNames) > len(binaryPaths) {
-		for _, binaryName := range binaryNames[len(binaryPaths):] {
-			binaryPaths = append(binaryPaths, filepath.Join(destinationDir, binaryName))
-		}
+func (r *Runner) installExtractedBinaries(ctx context.Context, bundleBinaries map[string]string, destinationDir string, mutations fileSnapshotter, permissionHintDir, goos string) ([]string, error) {
+	binaryNames := bundleBinaryNames(bundleBinaries, goos)
+	binaryPaths := make([]string, 0, len(binaryNames))
+	for _, binaryName := range binaryNames {
+		binaryPaths = append(binaryPaths, filepath.Join(destinationDir, binaryName))
 	}
 
 	installer := r.installBinary
@@ -476,26 +502,17 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 	defer func() {
 		r.pendingPinWrite = nil
 	}()
+	fx := r.effects()
 
 	home, err := r.homeDir()
 	if err != nil {
 		return fmt.Errorf("determine home directory: %w", err)
 	}
-	getenv := r.getenv
-	if getenv == nil {
-		getenv = os.Getenv
-	}
-	layout := paths.Global(home)
-	if stateOverride := strings.TrimSpace(getenv(paths.EnvStateDir)); stateOverride != "" {
-		layout.StateDir = filepath.Clean(stateOverride)
-	}
-	if blobOverride := strings.TrimSpace(getenv(paths.EnvBlobDir)); blobOverride != "" {
-		layout.BlobDir = filepath.Clean(blobOverride)
-	}
+	layout := r.globalLayout(home)
 	paths := resolveInstallPaths(home)
 
 	stateDir := layout.StateDir
-	if err := os.MkdirAll(stateDir, stateDirPerm); err != nil {
+	if err := fx.mkdirAll(stateDir); err != nil {
 		return fmt.Errorf("create state directory %s: %w", stateDir, err)
 	}
 
@@ -505,22 +522,9 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 	}
 	previousGlobal := previousState.GlobalInstallSnapshot()
 
-	var configTargets []installConfigTarget
-	if isUpdate {
-		configTargets = resolveUpdateTargets(paths, previousGlobal)
-	} else {
-		if len(r.globalInstallTargets) > 0 {
-			configTargets = uniqueInstallTargets(r.globalInstallTargets)
-		} else {
-			destination := r.installDestination
-			if destination == "" {
-				destination = installDestinationInsiders
-			}
-			configTargets, err = resolveInstallTargets(paths, destination)
-			if err != nil {
-				return err
-			}
-		}
+	configTargets, err := r.resolveGlobalConfigTargets(home, paths, previousGlobal, isUpdate)
+	if err != nil {
+		return err
 	}
 
 	if err := ctx.Err(); err != nil {
@@ -537,7 +541,7 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 		return err
 	}
 	if isUpdate && previousGlobal != nil && rel.TagName == previousGlobal.ReleaseTag {
-		r.statusf("ccsubagents: already at latest version (%s). Nothing to do.\n", rel.TagName)
+		r.reportUpToDate(rel.TagName)
 		return nil
 	}
 
@@ -551,7 +555,7 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 
 	goos := runtime.GOOS
 	goarch := runtime.GOARCH
-	tmpDir, downloaded, err := r.downloadRequiredAssets(ctx, stateDir, rel, installAssetNamesForRuntime(), "download-*")
+	tmpDir, downloaded, err := r.downloadRequiredAssets(ctx, fx.downloadDir(stateDir), rel, installAssetNamesForRuntime(), "download-*")
 	if err != nil {
 		return err
 	}
@@ -573,45 +577,27 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 		return err
 	}
 
-	txSession, err := txn.Begin(stateDir, layout.BlobDir, "global", commandNameForInstallOrUpdate(isUpdate), []string{"mutations"})
-	if err != nil {
-		return err
-	}
-	defer txSession.Close()
-	if err := txSession.MarkApplied("mutations"); err != nil {
+	if err := fx.begin(stateDir, layout.BlobDir, "global", commandNameForInstallOrUpdate(isUpdate)); err != nil {
 		return err
 	}
-
-	rollback := txSession.NewRollback("mutations")
-	mutations := files.NewMutationTracker(rollback, stateDirPerm)
-	defer func() {
-		if retErr == nil {
-			return
-		}
-		if rollbackErr := rollback.Restore(); rollbackErr != nil {
-			retErr = fmt.Errorf("%w (rollback failed: %v)", retErr, rollbackErr)
-		}
-		if txRollbackErr := txSession.Rollback(); txRollbackErr != nil {
-			retErr = fmt.Errorf("%w (transaction rollback failed: %v)", retErr, txRollbackErr)
-		}
-	}()
+	defer fx.end(&retErr)
 
 	agentsDir := filepath.Join(layout.StateDir, "agents")
-	if err := mutations.EnsureDirWithinBase(filepath.Dir(agentsDir), layout.StateDir); err != nil {
+	if err := fx.ensureDirWithinBase(filepath.Dir(agentsDir), layout.StateDir); err != nil {
 		return err
 	}
-	if err := mutations.EnsureDirWithinBase(agentsDir, layout.StateDir); err != nil {
+	if err := fx.ensureDirWithinBase(agentsDir, layout.StateDir); err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(paths.binaryDir, stateDirPerm); err != nil {
+	if err := fx.mkdirAll(paths.binaryDir); err != nil {
 		if errors.Is(err, os.ErrPermission) {
 			return fmt.Errorf("create binary install directory %s: %w (requires privileges to write %s)", paths.binaryDir, err, paths.binaryDir)
 		}
 		return fmt.Errorf("create binary install directory %s: %w", paths.binaryDir, err)
 	}
 
-	binaryPaths, err := r.installExtractedBinaries(ctx, bundleBinaries, paths.binaryDir, mutations, paths.binaryDir, goos)
+	binaryPaths, err := fx.installBinaries(ctx, bundleBinaries, paths.binaryDir, paths.binaryDir, goos)
 	if err != nil {
 		return err
 	}
@@ -621,7 +607,7 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 		return err
 	}
 
-	extractedFiles, extractedDirs, err := files.ExtractAgentsArchiveWithHook(downloaded[assetAgentsZip], agentsDir, mutations.SnapshotFile, stateDirPerm, stateFilePerm)
+	extractedFiles, extractedDirs, err := fx.extractAgents(downloaded[assetAgentsZip], agentsDir)
 	if err != nil {
 		return fmt.Errorf("extract %s into %s: %w", assetAgentsZip, agentsDir, err)
 	}
@@ -629,24 +615,26 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 	r.reportDetail("extracted %d agent definitions", len(extractedFiles))
 
 	for _, target := range configTargets {
-		if err := mutations.EnsureParentDirWithinBase(target.settingsPath, filepath.Dir(target.settingsPath)); err != nil {
-			return err
-		}
-		if err := mutations.SnapshotFile(target.settingsPath); err != nil {
-			return err
+		if target.settingsPath != "" {
+			if err := fx.ensureParentDirWithinBase(target.settingsPath, filepath.Dir(target.settingsPath)); err != nil {
+				return err
+			}
+			if err := fx.snapshotFile(target.settingsPath); err != nil {
+				return err
+			}
 		}
 
-		if err := mutations.EnsureParentDirWithinBase(target.mcpPath, filepath.Dir(target.mcpPath)); err != nil {
+		if err := fx.ensureParentDirWithinBase(target.mcpPath, filepath.Dir(target.mcpPath)); err != nil {
 			return err
 		}
-		if err := mutations.SnapshotFile(target.mcpPath); err != nil {
+		if err := fx.snapshotFile(target.mcpPath); err != nil {
 			return err
 		}
 	}
 
 	settingsAgentPath := toHomeTildePath(home, agentsDir)
 	mcpBinaryName, _ := localArtifactBinaryNames(goos)
-	mcpCommandPath := toHomeTildePath(home, filepath.Join(paths.binaryDir, mcpBinaryName))
+	mcpBinaryPath := filepath.Join(paths.binaryDir, mcpBinaryName)
 
 	if err := ctx.Err(); err != nil {
 		return err
@@ -655,20 +643,31 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 	settingsEdits := make([]state.SettingsEdit, 0, len(configTargets))
 	mcpEdits := make([]state.MCPEdit, 0, len(configTargets))
 	for _, target := range configTargets {
-		settingsEdit, err := config.ApplySettingsEdit(target.settingsPath, settingsAgentPath, previousGlobal, stateFilePerm)
-		if err != nil {
-			return err
+		client := target.clientTarget()
+		if client.agentSettings {
+			settingsEdit, err := fx.applySettingsEdit(target.settingsPath, settingsAgentPath, previousGlobal)
+			if err != nil {
+				return err
+			}
+			settingsEdits = append(settingsEdits, settingsEdit)
+			r.reportDetail("updated settings: %s", target.settingsPath)
 		}
-		settingsEdits = append(settingsEdits, settingsEdit)
 
-		mcpEdit, err := config.ApplyMCPEdit(target.mcpPath, mcpCommandPath, previousGlobal, stateFilePerm)
+		mcpCommandPath := mcpCommandPathForClient(client, home, mcpBinaryPath)
+		mcpEdit, err := fx.applyMCPEdit(target.mcpPath, client.mcpFormat, mcpCommandPath, previousGlobal)
 		if err != nil {
 			return err
 		}
+		if target.isVSCode() {
+			r.reportDetail("updated mcp config: %s", target.mcpPath)
+		} else {
+			mcpEdit.Client = string(client.kind)
+			r.reportDetail("updated %s mcp config: %s", client.label, target.mcpPath)
+			if client.noAgentsReason != "" {
+				r.reportDetail("%s gets no agent definitions: %s", client.label, client.noAgentsReason)
+			}
+		}
 		mcpEdits = append(mcpEdits, mcpEdit)
-
-		r.reportDetail("updated settings: %s", target.settingsPath)
-		r.reportDetail("updated mcp config: %s", target.mcpPath)
 	}
 	r.reportStepOK("Updated VS Code settings and MCP config", "")
 
@@ -680,7 +679,7 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 		InstalledAt: r.now().UTC().Format(time.RFC3339),
 		Managed: state.ManagedState{
 			Files: files.UniqueSorted(append(append([]string{}, binaryPaths...), extractedFiles...)),
-			Dirs:  files.UniqueSorted(append(mutations.CreatedDirectories(), extractedDirs...)),
+			Dirs:  files.UniqueSorted(append(fx.createdDirectories(), extractedDirs...)),
 		},
 		AppliedSteps: []state.AppliedStep{{
 			ID:         "global.mutations",
@@ -690,6 +689,9 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 		}},
 		JSONEdits: state.TrackedJSONOpsFromEdits(settingsEdits, mcpEdits),
 	}
+	if specs, ok := installTargetSpecs(home, paths, configTargets); ok && len(specs) > 0 {
+		tracked.Choices = &state.InstallChoices{Targets: specs}
+	}
 	if previousState != nil {
 		tracked.Local = append(tracked.Local, previousState.Local...)
 	}
@@ -700,10 +702,10 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 		} else {
 			daemonPath := filepath.Join(paths.binaryDir, ccsubagentsdBinaryName(goos))
 			if containsCleanPath(previousGlobal.Managed.Files, daemonPath) && !containsCleanPath(binaryPaths, daemonPath) {
-				if err := mutations.SnapshotFile(daemonPath); err != nil {
+				if err := fx.snapshotFile(daemonPath); err != nil {
 					return err
 				}
-				if err := os.Remove(daemonPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				if err := fx.removeFile(daemonPath); err != nil && !errors.Is(err, os.ErrNotExist) {
 					return fmt.Errorf("remove stale managed daemon binary %s: %w", daemonPath, err)
 				}
 			}
@@ -711,21 +713,21 @@ func (r *Runner) installOrUpdate(ctx context.Context, isUpdate bool) (retErr err
 			if err := ctx.Err(); err != nil {
 				return err
 			}
-			if err := files.RemoveStaleAgentFilesWithHook(previousGlobal.Managed.Files, extractedFiles, agentsDir, rollback.CaptureFile); err != nil {
+			if err := fx.removeStaleAgentFiles(previousGlobal.Managed.Files, extractedFiles, agentsDir); err != nil {
 				return err
 			}
 			r.reportStepOK("Removed stale managed agent files", "")
 		}
 	}
 
-	if err := r.persistPendingPinWrite(mutations); err != nil {
+	if err := r.persistPendingPinWrite(fx); err != nil {
 		return err
 	}
 
-	if err := state.SaveTrackedState(stateDir, tracked); err != nil {
+	if err := fx.saveTrackedState(stateDir, tracked); err != nil {
 		return err
 	}
-	if err := txSession.Commit(); err != nil {
+	if err := fx.commit(); err != nil {
 		return err
 	}
 	r.reportDetail("saved tracked state: %s", filepath.Join(stateDir, state.TrackedFileName))
@@ -739,6 +741,7 @@ func (r *Runner) uninstall(ctx context.Context) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
+	fx := r.effects()
 
 	home, err := r.homeDir()
 	if err != nil {
@@ -769,7 +772,7 @@ func (r *Runner) uninstall(ctx context.Context) error {
 	} else {
 		r.reportStepOK("Checked tracked installation state", fmt.Sprintf("%s found", tracked.ReleaseTag))
 	}
-	if err := r.stopDaemonBeforeRemoval(ctx); err != nil {
+	if err := fx.stopDaemon(ctx); err != nil {
 		return err
 	}
 
@@ -804,7 +807,7 @@ func (r *Runner) uninstall(ctx context.Context) error {
 		if !files.IsAllowedManagedPath(clean, agentsDir, allowedBinaries) {
 			return fmt.Errorf("refusing to delete unsafe tracked path: %s", clean)
 		}
-		if err := os.Remove(clean); err != nil && !errors.Is(err, os.ErrNotExist) {
+		if err := fx.removeFile(clean); err != nil && !errors.Is(err, os.ErrNotExist) {
 			if errors.Is(err, os.ErrPermission) {
 				return fmt.Errorf("remove %s: %w (requires privileges for %s)", clean, err, paths.binaryDir)
 			}
@@ -820,7 +823,7 @@ func (r *Runner) uninstall(ctx context.Context) error {
 		if strings.TrimSpace(edit.File) == "" {
 			continue
 		}
-		if err := config.RevertSettingsEdit(edit, stateFilePerm); err != nil {
+		if err := fx.revertSettingsEdit(edit); err != nil {
 			return err
 		}
 	}
@@ -828,7 +831,7 @@ func (r *Runner) uninstall(ctx context.Context) error {
 		if strings.TrimSpace(edit.File) == "" {
 			continue
 		}
-		if err := config.RevertMCPEdit(edit, stateFilePerm); err != nil {
+		if err := fx.revertMCPEdit(edit); err != nil {
 			return err
 		}
 	}
@@ -846,7 +849,7 @@ func (r *Runner) uninstall(ctx context.Context) error {
 		if !files.IsAllowedManagedDirectory(clean, agentsDir, allowedConfigParentDirs) {
 			return fmt.Errorf("refusing to delete unsafe tracked directory: %s", clean)
 		}
-		if err := os.Remove(clean); err != nil {
+		if err := fx.removeDir(clean); err != nil {
 			if errors.Is(err, os.ErrNotExist) || files.IsDirNotEmptyError(err) {
 				continue
 			}
@@ -861,12 +864,12 @@ func (r *Runner) uninstall(ctx context.Context) error {
 	tracked.ClearGlobalInstall()
 	tracked.Version = state.TrackedSchemaVersion
 	if tracked.Empty() {
-		if err := os.Remove(filepath.Join(stateDir, state.TrackedFileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		if err := fx.removeFile(filepath.Join(stateDir, state.TrackedFileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
 			return fmt.Errorf("remove tracked state: %w", err)
 		}
 		r.reportStepOK("Updated tracked installation state", "removed")
 	} else {
-		if err := state.SaveTrackedState(stateDir, *tracked); err != nil {
+		if err := fx.saveTrackedState(stateDir, *tracked); err != nil {
 			return err
 		}
 		r.reportStepOK("Updated tracked installation state", "saved")
@@ -17,7 +17,6 @@ import (
 
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/files"
-	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/integration/txn"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/paths"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/release"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
@@ -64,28 +63,36 @@ func (r *Runner) installLocal(ctx context.Context) error {
 		return err
 	}
 
-	if err := os.MkdirAll(stateDir, stateDirPerm); err != nil {
+	if err := r.effects().mkdirAll(stateDir); err != nil {
 		return fmt.Errorf("create state directory %s: %w", stateDir, err)
 	}
 
-	tracked, err := state.LoadTrackedStateForInstall(stateDir)
+	cfg, err := r.resolveLocalInstallConfig(ctx, stateDir)
 	if err != nil {
 		return err
 	}
+	return r.installOrUpdateLocal(ctx, cfg)
+}
+
+func (r *Runner) resolveLocalInstallConfig(ctx context.Context, stateDir string) (localInstallConfig, error) {
+	tracked, err := state.LoadTrackedStateForInstall(stateDir)
+	if err != nil {
+		return localInstallConfig{}, err
+	}
 	if tracked == nil {
 		tracked = &state.TrackedState{Version: state.TrackedSchemaVersion}
 	}
 
 	location, err := r.resolveLocalScopeLocation(ctx, "install")
 	if err != nil {
-		return err
+		return localInstallConfig{}, err
 	}
 
 	mode := state.LocalInstallModePersonal
 	if location.inGitRepo {
-		mode, err = r.promptLocalInstallMode(ctx)
+		mode, err = r.selectLocalInstallMode(ctx)
 		if err != nil {
-			return err
+			return localInstallConfig{}, err
 		}
 	}
 
@@ -102,13 +109,13 @@ func (r *Runner) installLocal(ctx context.Context) error {
 		} else {
 			detected, err := config.DetectExistingTeamLocalSetup(location.installRoot, location.repoRoot)
 			if err != nil {
-				return err
+				return localInstallConfig{}, err
 			}
 			binaryOnly = detected
 		}
 	}
 
-	cfg := localInstallConfig{
+	return localInstallConfig{
 		isUpdate:   false,
 		location:   location,
 		mode:       mode,
@@ -116,37 +123,46 @@ func (r *Runner) installLocal(ctx context.Context) error {
 		stateDir:   stateDir,
 		state:      tracked,
 		previous:   previous,
-	}
-	return r.installOrUpdateLocal(ctx, cfg)
+	}, nil
 }
 
 func (r *Runner) updateLocal(ctx context.Context) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
+	cfg, err := r.resolveLocalUpdateConfig(ctx)
+	if err != nil || cfg == nil {
+		return err
+	}
+	return r.installOrUpdateLocal(ctx, *cfg)
+}
+
+// resolveLocalUpdateConfig returns nil when there is no tracked local install
+// to update.
+func (r *Runner) resolveLocalUpdateConfig(ctx context.Context) (*localInstallConfig, error) {
 	stateDir, err := r.localTrackedStateDir()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	location, err := r.resolveLocalScopeLocation(ctx, "update")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	tracked, err := state.LoadTrackedState(stateDir)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			r.reportStepOK("Checked tracked local installation", fmt.Sprintf("none found for %s", filepath.ToSlash(location.installRoot)))
-			return nil
+			return nil, nil
 		}
-		return err
+		return nil, err
 	}
 
 	existing, _ := tracked.LocalInstallForRoot(location.installRoot)
 	if existing == nil {
 		r.reportStepOK("Checked tracked local installation", fmt.Sprintf("none found for %s", filepath.ToSlash(location.installRoot)))
-		return nil
+		return nil, nil
 	}
 	clone := *existing
 	mode := clone.Mode
@@ -154,7 +170,7 @@ func (r *Runner) updateLocal(ctx context.Context) error {
 		mode = state.LocalInstallModePersonal
 	}
 
-	cfg := localInstallConfig{
+	return &localInstallConfig{
 		isUpdate:   true,
 		location:   location,
 		mode:       mode,
@@ -162,51 +178,25 @@ func (r *Runner) updateLocal(ctx context.Context) error {
 		stateDir:   stateDir,
 		state:      tracked,
 		previous:   &clone,
-	}
-	return r.installOrUpdateLocal(ctx, cfg)
+	}, nil
 }
 
 func (r *Runner) uninstallLocal(ctx context.Context) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	stateDir, err := r.localTrackedStateDir()
-	if err != nil {
-		return err
-	}
-
-	location, err := r.resolveLocalScopeLocation(ctx, "uninstall")
-	if err != nil {
-		return err
-	}
-
-	tracked, err := state.LoadTrackedState(stateDir)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			r.reportStepOK("Checked tracked local installation", fmt.Sprintf("none found for %s", filepath.ToSlash(location.installRoot)))
-			return nil
-		}
+	fx := r.effects()
+	stateDir, location, tracked, record, err := r.resolveLocalUninstallRecord(ctx)
+	if err != nil || record == nil {
 		return err
 	}
 
-	record, _ := tracked.LocalInstallForRoot(location.installRoot)
-	if record == nil {
-		r.reportStepOK("Checked tracked local installation", fmt.Sprintf("none found for %s", filepath.ToSlash(location.installRoot)))
-		return nil
-	}
-
-	if strings.TrimSpace(record.ReleaseTag) == "" {
-		r.reportStepOK("Checked tracked local installation", fmt.Sprintf("found for %s", filepath.ToSlash(location.installRoot)))
-	} else {
-		r.reportStepOK("Checked tracked local installation", fmt.Sprintf("%s found for %s", record.ReleaseTag, filepath.ToSlash(location.installRoot)))
-	}
-
 	if record.Mode == state.LocalInstallModeTeam {
 		if err := r.confirmTeamLocalUninstall(ctx, location.installRoot); err != nil {
 			return err
 		}
 	}
-	if err := r.stopDaemonBeforeRemoval(ctx); err != nil {
+	if err := fx.stopDaemon(ctx); err != nil {
 		return err
 	}
 
@@ -228,7 +218,7 @@ func (r *Runner) uninstallLocal(ctx context.Context) error {
 		if !files.IsAllowedManagedPath(clean, agentsDir, allowedBinaries) {
 			return fmt.Errorf("refusing to delete unsafe tracked path: %s", clean)
 		}
-		if err := os.Remove(clean); err != nil && !errors.Is(err, os.ErrNotExist) {
+		if err := fx.removeFile(clean); err != nil && !errors.Is(err, os.ErrNotExist) {
 			return fmt.Errorf("remove %s: %w", clean, err)
 		}
 	}
@@ -238,7 +228,7 @@ func (r *Runner) uninstallLocal(ctx context.Context) error {
 		if strings.TrimSpace(edit.File) == "" {
 			continue
 		}
-		if err := config.RevertSettingsEdit(edit, stateFilePerm); err != nil {
+		if err := fx.revertSettingsEdit(edit); err != nil {
 			return err
 		}
 	}
@@ -246,11 +236,11 @@ func (r *Runner) uninstallLocal(ctx context.Context) error {
 		if strings.TrimSpace(edit.File) == "" {
 			continue
 		}
-		if err := config.RevertMCPEdit(edit, stateFilePerm); err != nil {
+		if err := fx.revertMCPEdit(edit); err != nil {
 			return err
 		}
 	}
-	if err := config.RevertIgnoreEdits(record.IgnoreEdits, stateFilePerm); err != nil {
+	if err := fx.revertIgnoreEdits(record.IgnoreEdits); err != nil {
 		return err
 	}
 	r.reportStepOK("Reverted local configuration edits", "")
@@ -268,7 +258,7 @@ func (r *Runner) uninstallLocal(ctx context.Context) error {
 		if !files.IsAllowedManagedDirectory(clean, agentsDir, allowedConfigParentDirs) {
 			return fmt.Errorf("refusing to delete unsafe tracked directory: %s", clean)
 		}
-		if err := os.Remove(clean); err != nil {
+		if err := fx.removeDir(clean); err != nil {
 			if errors.Is(err, os.ErrNotExist) || files.IsDirNotEmptyError(err) {
 				continue
 			}
@@ -280,12 +270,12 @@ func (r *Runner) uninstallLocal(ctx context.Context) error {
 	tracked.RemoveLocalInstall(location.installRoot)
 	tracked.Version = state.TrackedSchemaVersion
 	if tracked.Empty() {
-		if err := os.Remove(filepath.Join(stateDir, state.TrackedFileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		if err := fx.removeFile(filepath.Join(stateDir, state.TrackedFileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
 			return fmt.Errorf("remove tracked state: %w", err)
 		}
 		r.reportStepOK("Updated tracked installation state", "removed")
 	} else {
-		if err := state.SaveTrackedState(stateDir, *tracked); err != nil {
+		if err := fx.saveTrackedState(stateDir, *tracked); err != nil {
 			return err
 		}
 		r.reportStepOK("Updated tracked installation state", "saved")
@@ -294,6 +284,42 @@ func (r *Runner) uninstallLocal(ctx context.Context) error {
 	return nil
 }
 
+// resolveLocalUninstallRecord returns a nil record when there is no tracked
+// local install to remove.
+func (r *Runner) resolveLocalUninstallRecord(ctx context.Context) (string, localScopeLocation, *state.TrackedState, *state.LocalInstall, error) {
+	stateDir, err := r.localTrackedStateDir()
+	if err != nil {
+		return "", localScopeLocation{}, nil, nil, err
+	}
+
+	location, err := r.resolveLocalScopeLocation(ctx, "uninstall")
+	if err != nil {
+		return "", localScopeLocation{}, nil, nil, err
+	}
+
+	tracked, err := state.LoadTrackedState(stateDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			r.reportStepOK("Checked tracked local installation", fmt.Sprintf("none found for %s", filepath.ToSlash(location.installRoot)))
+			return "", localScopeLocation{}, nil, nil, nil
+		}
+		return "", localScopeLocation{}, nil, nil, err
+	}
+
+	record, _ := tracked.LocalInstallForRoot(location.installRoot)
+	if record == nil {
+		r.reportStepOK("Checked tracked local installation", fmt.Sprintf("none found for %s", filepath.ToSlash(location.installRoot)))
+		return "", localScopeLocation{}, nil, nil, nil
+	}
+
+	if strings.TrimSpace(record.ReleaseTag) == "" {
+		r.reportStepOK("Checked tracked local installation", fmt.Sprintf("found for %s", filepath.ToSlash(location.installRoot)))
+	} else {
+		r.reportStepOK("Checked tracked local installation", fmt.Sprintf("%s found for %s", record.ReleaseTag, filepath.ToSlash(location.installRoot)))
+	}
+	return stateDir, location, tracked, record, nil
+}
+
 func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfig) (retErr error) {
 	if err := ctx.Err(); err != nil {
 		return err
@@ -301,6 +327,7 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 	defer func() {
 		r.pendingPinWrite = nil
 	}()
+	fx := r.effects()
 
 	previousSettingsRoot := r.installSettingsRoot
 	r.installSettingsRoot = cfg.location.installRoot
@@ -332,7 +359,7 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 	if !cfg.binaryOnly {
 		requiredAssets = append(requiredAssets, assetAgentsZip)
 	}
-	tmpDir, downloaded, err := r.downloadRequiredAssets(ctx, cfg.stateDir, rel, requiredAssets, "download-local-*")
+	tmpDir, downloaded, err := r.downloadRequiredAssets(ctx, fx.downloadDir(cfg.stateDir), rel, requiredAssets, "download-local-*")
 	if err != nil {
 		return err
 	}
@@ -368,35 +395,17 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 	if stateOverride := strings.TrimSpace(getenv(paths.EnvBlobDir)); stateOverride != "" {
 		blobDir = filepath.Clean(stateOverride)
 	}
-	txSession, err := txn.Begin(cfg.stateDir, blobDir, scopeID, commandName, []string{"mutations"})
-	if err != nil {
-		return err
-	}
-	defer txSession.Close()
-	if err := txSession.MarkApplied("mutations"); err != nil {
+	if err := fx.begin(cfg.stateDir, blobDir, scopeID, commandName); err != nil {
 		return err
 	}
-
-	rollback := txSession.NewRollback("mutations")
-	mutations := files.NewMutationTracker(rollback, stateDirPerm)
-	defer func() {
-		if retErr == nil {
-			return
-		}
-		if rollbackErr := rollback.Restore(); rollbackErr != nil {
-			retErr = fmt.Errorf("%w (rollback failed: %v)", retErr, rollbackErr)
-		}
-		if txRollbackErr := txSession.Rollback(); txRollbackErr != nil {
-			retErr = fmt.Errorf("%w (transaction rollback failed: %v)", retErr, txRollbackErr)
-		}
-	}()
+	defer fx.end(&retErr)
 
 	managedDir := filepath.Join(cfg.location.installRoot, config.LocalManagedDirRelativePath)
-	if err := mutations.EnsureDirWithinBase(managedDir, cfg.location.installRoot); err != nil {
+	if err := fx.ensureDirWithinBase(managedDir, cfg.location.installRoot); err != nil {
 		return err
 	}
 
-	binaryPaths, err := r.installExtractedBinaries(ctx, bundleBinaries, managedDir, mutations, "", goos)
+	binaryPaths, err := fx.installBinaries(ctx, bundleBinaries, managedDir, "", goos)
 	if err != nil {
 		return err
 	}
@@ -404,10 +413,10 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 	if cfg.isUpdate && cfg.previous != nil {
 		daemonPath := filepath.Join(managedDir, ccsubagentsdBinaryName(goos))
 		if containsCleanPath(cfg.previous.Managed.Files, daemonPath) && !containsCleanPath(binaryPaths, daemonPath) {
-			if err := mutations.SnapshotFile(daemonPath); err != nil {
+			if err := fx.snapshotFile(daemonPath); err != nil {
 				return err
 			}
-			if err := os.Remove(daemonPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			if err := fx.removeFile(daemonPath); err != nil && !errors.Is(err, os.ErrNotExist) {
 				return fmt.Errorf("remove stale managed local daemon binary %s: %w", daemonPath, err)
 			}
 		}
@@ -418,14 +427,14 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 	mcpEdits := []state.MCPEdit{}
 	if !cfg.binaryOnly {
 		agentsDir := filepath.Join(cfg.location.installRoot, config.LocalAgentsRelativePath)
-		if err := mutations.EnsureDirWithinBase(filepath.Dir(agentsDir), cfg.location.installRoot); err != nil {
+		if err := fx.ensureDirWithinBase(filepath.Dir(agentsDir), cfg.location.installRoot); err != nil {
 			return err
 		}
-		if err := mutations.EnsureDirWithinBase(agentsDir, cfg.location.installRoot); err != nil {
+		if err := fx.ensureDirWithinBase(agentsDir, cfg.location.installRoot); err != nil {
 			return err
 		}
 
-		extractedFiles, extractedDirs, err = files.ExtractAgentsArchiveWithHook(downloaded[assetAgentsZip], agentsDir, mutations.SnapshotFile, stateDirPerm, stateFilePerm)
+		extractedFiles, extractedDirs, err = fx.extractAgents(downloaded[assetAgentsZip], agentsDir)
 		if err != nil {
 			return fmt.Errorf("extract %s into %s: %w", assetAgentsZip, agentsDir, err)
 		}
@@ -433,10 +442,10 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 		r.reportDetail("extracted %d local agent definitions", len(extractedFiles))
 
 		mcpPath := filepath.Join(cfg.location.installRoot, config.LocalMCPRelativePath)
-		if err := mutations.EnsureParentDirWithinBase(mcpPath, cfg.location.installRoot); err != nil {
+		if err := fx.ensureParentDirWithinBase(mcpPath, cfg.location.installRoot); err != nil {
 			return err
 		}
-		if err := mutations.SnapshotFile(mcpPath); err != nil {
+		if err := fx.snapshotFile(mcpPath); err != nil {
 			return err
 		}
 
@@ -444,7 +453,7 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 		if cfg.previous != nil {
 			previousState = &state.TrackedState{JSONEdits: cfg.previous.JSONEdits}
 		}
-		mcpEdit, err := config.ApplyMCPEdit(mcpPath, config.LocalMCPCommand(goos), previousState, stateFilePerm)
+		mcpEdit, err := fx.applyMCPEdit(mcpPath, config.VSCodeMCPFormat, config.LocalMCPCommand(goos), previousState)
 		if err != nil {
 			return err
 		}
@@ -453,7 +462,7 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 		r.reportDetail("updated workspace MCP config: %s", mcpPath)
 
 		if cfg.isUpdate && cfg.previous != nil {
-			if err := files.RemoveStaleAgentFilesWithHook(cfg.previous.Managed.Files, extractedFiles, agentsDir, mutations.SnapshotFile); err != nil {
+			if err := fx.removeStaleAgentFiles(cfg.previous.Managed.Files, extractedFiles, agentsDir); err != nil {
 				return err
 			}
 			r.reportStepOK("Removed stale managed local agent files", "")
@@ -472,11 +481,11 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 			return err
 		}
 		if ignoreFile != "" {
-			if err := mutations.SnapshotFile(ignoreFile); err != nil {
+			if err := fx.snapshotFile(ignoreFile); err != nil {
 				return err
 			}
 		}
-		newEdits, err := config.ApplyLocalIgnoreRules(cfg.location.installRoot, cfg.location.repoRoot, cfg.mode, stateDirPerm, stateFilePerm)
+		newEdits, err := fx.applyLocalIgnoreRules(cfg.location.installRoot, cfg.location.repoRoot, cfg.mode)
 		if err != nil {
 			return err
 		}
@@ -507,7 +516,7 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 		IgnoreEdits: ignoreEdits,
 	}
 	managedFiles := append(append([]string{}, binaryPaths...), extractedFiles...)
-	managedDirs := append(mutations.CreatedDirectories(), extractedDirs...)
+	managedDirs := append(fx.createdDirectories(), extractedDirs...)
 	if cfg.previous != nil && !cfg.isUpdate {
 		managedFiles = append(managedFiles, cfg.previous.Managed.Files...)
 		managedDirs = append(managedDirs, cfg.previous.Managed.Dirs...)
@@ -522,17 +531,17 @@ func (r *Runner) installOrUpdateLocal(ctx context.Context, cfg localInstallConfi
 		record.JSONEdits = cfg.previous.JSONEdits.Clone()
 	}
 
-	if err := r.persistPendingPinWrite(mutations); err != nil {
+	if err := r.persistPendingPinWrite(fx); err != nil {
 		return err
 	}
 
 	cfg.state.Version = state.TrackedSchemaVersion
 	cfg.state.SetLocalInstall(record)
 
-	if err := state.SaveTrackedState(cfg.stateDir, *cfg.state); err != nil {
+	if err := fx.saveTrackedState(cfg.stateDir, *cfg.state); err != nil {
 		return err
 	}
-	if err := txSession.Commit(); err != nil {
+	if err := fx.commit(); err != nil {
 		return err
 	}
 	r.reportStepOK("Updated tracked installation state", "saved")
@@ -561,6 +570,21 @@ func (r *Runner) resolveLocalScopeLocation(ctx context.Context, action string) (
 		return localScopeLocation{installRoot: cwd, inGitRepo: inRepo, repoRoot: repoRoot}, nil
 	}
 
+	switch strings.TrimSpace(r.answers.Location) {
+	case locationAnswerCwd:
+		return localScopeLocation{installRoot: cwd, inGitRepo: true, repoRoot: repoRoot}, nil
+	case locationAnswerRepoRoot:
+		return localScopeLocation{installRoot: repoRoot, inGitRepo: true, repoRoot: repoRoot}, nil
+	}
+	if r.nonInteractive {
+		if action != "install" {
+			if root, ok := r.trackedLocalInstallRoot(cwd, repoRoot); ok {
+				return localScopeLocation{installRoot: root, inGitRepo: true, repoRoot: repoRoot}, nil
+			}
+		}
+		return localScopeLocation{}, missingAnswerError(fmt.Sprintf("local %s location", action), "\"location\": \"cwd\" or \"repo-root\" in --answers")
+	}
+
 	input := r.promptIn
 	if input == nil {
 		input = os.Stdin
@@ -622,6 +646,29 @@ func (r *Runner) resolveLocalScopeLocation(ctx context.Context, action string) (
 	}
 }
 
+// trackedLocalInstallRoot replays the location chosen at install time when
+// exactly one of the candidate roots has a tracked local install.
+func (r *Runner) trackedLocalInstallRoot(cwd, repoRoot string) (string, bool) {
+	stateDir, err := r.localTrackedStateDir()
+	if err != nil {
+		return "", false
+	}
+	tracked, err := state.LoadTrackedState(stateDir)
+	if err != nil {
+		return "", false
+	}
+	matches := []string{}
+	for _, candidate := range []string{cwd, repoRoot} {
+		if existing, _ := tracked.LocalInstallForRoot(candidate); existing != nil {
+			matches = append(matches, filepath.Clean(candidate))
+		}
+	}
+	if len(matches) != 1 {
+		return "", false
+	}
+	return matches[0], true
+}
+
 func (r *Runner) detectGitRepoRoot(ctx context.Context, cwd string) (string, bool) {
 	runner := r.runCommand
 	if runner == nil {
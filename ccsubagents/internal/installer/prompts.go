@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	pathutil "github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/paths"
@@ -80,6 +81,27 @@ func describeGlobalInstallTargetPath(home string, target installConfigTarget) st
 	return fmt.Sprintf("settings: %s; mcp: %s", settingsDisplay, mcpDisplay)
 }
 
+func missingAnswerError(question, hint string) error {
+	return fmt.Errorf("%w: %s (pass %s)", ErrMissingAnswer, question, hint)
+}
+
+func (r *Runner) selectGlobalInstallTargets(ctx context.Context, home string, paths installPaths) ([]installConfigTarget, error) {
+	if len(r.answers.Targets) > 0 {
+		targets, err := resolveInstallTargetSpecs(home, paths, r.answers.Targets)
+		if err != nil {
+			return nil, err
+		}
+		if len(targets) == 0 {
+			return nil, errors.New("no valid global install targets in answers")
+		}
+		return targets, nil
+	}
+	if r.nonInteractive {
+		return nil, missingAnswerError("global install targets", "--targets or \"targets\" in --answers")
+	}
+	return r.promptGlobalInstallTargets(ctx, home, paths)
+}
+
 func (r *Runner) promptGlobalInstallTargets(ctx context.Context, home string, paths installPaths) ([]installConfigTarget, error) {
 	input := r.promptIn
 	if input == nil {
@@ -120,6 +142,9 @@ func (r *Runner) promptGlobalInstallTargets(ctx context.Context, home string, pa
 		fmt.Fprintf(&prompt, "[3] VS Code Desktop — Insiders  (%s)\n", desktopInsidersDisplay)
 		fmt.Fprintf(&prompt, "[4] VS Code Desktop — Stable    (%s)\n", desktopStableDisplay)
 		prompt.WriteString("[5] Custom path(s)\n")
+		for idx, client := range additionalClientTargets {
+			fmt.Fprintf(&prompt, "[%d] %-29s (%s)\n", idx+6, client.label, toHomeTildePath(home, makeClientConfigTarget(client, home).mcpPath))
+		}
 		prompt.WriteString("\n")
 		prompt.WriteString("Choice (comma-separated, e.g. 1,2): ")
 
@@ -135,20 +160,20 @@ func (r *Runner) promptGlobalInstallTargets(ctx context.Context, home string, pa
 		choices := parseCommaSeparatedChoices(line)
 		selected := map[string]struct{}{}
 		valid := true
+		maxChoice := 5 + len(additionalClientTargets)
 		for _, choice := range choices {
-			switch choice {
-			case "1", "2", "3", "4", "5":
-				selected[choice] = struct{}{}
-			default:
-				valid = false
+			if n, convErr := strconv.Atoi(choice); convErr == nil && n >= 1 && n <= maxChoice {
+				selected[strconv.Itoa(n)] = struct{}{}
+				continue
 			}
+			valid = false
 		}
 
 		if len(selected) == 0 || !valid {
 			if errors.Is(err, io.EOF) {
 				return nil, errors.New("global install target selection canceled")
 			}
-			if _, writeErr := io.WriteString(output, "Invalid selection. Enter comma-separated values using 1 through 5.\n\n"); writeErr != nil {
+			if _, writeErr := fmt.Fprintf(output, "Invalid selection. Enter comma-separated values using 1 through %d.\n\n", maxChoice); writeErr != nil {
 				return nil, fmt.Errorf("write install target prompt: %w", writeErr)
 			}
 			continue
@@ -167,6 +192,11 @@ func (r *Runner) promptGlobalInstallTargets(ctx context.Context, home string, pa
 		if _, ok := selected["4"]; ok {
 			targets = append(targets, installConfigTarget{settingsPath: paths.desktopStable.settingsPath, mcpPath: paths.desktopStable.mcpPath})
 		}
+		for idx, client := range additionalClientTargets {
+			if _, ok := selected[strconv.Itoa(idx+6)]; ok {
+				targets = append(targets, makeClientConfigTarget(client, home))
+			}
+		}
 		if _, ok := selected["5"]; ok {
 			if _, err := io.WriteString(output, "Enter custom target path(s), comma-separated: "); err != nil {
 				return nil, fmt.Errorf("write custom target prompt: %w", err)
@@ -211,6 +241,16 @@ func (r *Runner) promptGlobalInstallTargets(ctx context.Context, home string, pa
 	}
 }
 
+func (r *Runner) selectLocalInstallMode(ctx context.Context) (state.LocalInstallMode, error) {
+	if strings.TrimSpace(r.answers.LocalMode) != "" {
+		return ParseLocalInstallMode(r.answers.LocalMode)
+	}
+	if r.nonInteractive {
+		return "", missingAnswerError("local install mode", "--local-mode or \"localMode\" in --answers")
+	}
+	return r.promptLocalInstallMode(ctx)
+}
+
 func (r *Runner) promptLocalInstallMode(ctx context.Context) (state.LocalInstallMode, error) {
 	input := r.promptIn
 	if input == nil {
@@ -260,6 +300,12 @@ func (r *Runner) promptLocalInstallMode(ctx context.Context) (state.LocalInstall
 }
 
 func (r *Runner) confirmTeamLocalUninstall(ctx context.Context, installRoot string) error {
+	if r.answers.ConfirmUninstall {
+		return ctx.Err()
+	}
+	if r.nonInteractive {
+		return missingAnswerError(fmt.Sprintf("confirmation to uninstall team local install at %s", installRoot), "--yes or \"confirmUninstall\" in --answers")
+	}
 	input := r.promptIn
 	if input == nil {
 		input = os.Stdin
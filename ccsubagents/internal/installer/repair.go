@@ -0,0 +1,137 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
+)
+
+// ConfigDrift is a tracked settings or MCP edit whose key has since been
+// removed from its file. Edits whose config directory is gone are not drift:
+// the client itself was removed.
+type ConfigDrift struct {
+	Kind    string
+	File    string
+	Pointer string
+	repair  func() error
+}
+
+// FindConfigDrift compares every settings and MCP edit in the tracked state
+// with the files on disk.
+func (r *Runner) FindConfigDrift() ([]ConfigDrift, error) {
+	return r.configDrift()
+}
+
+// RepairConfigDrift re-applies the tracked edits FindConfigDrift reports and
+// returns the ones it restored. Tracked state is left as is: the restored keys
+// are the ones it already records.
+func (r *Runner) RepairConfigDrift() ([]ConfigDrift, error) {
+	drift, err := r.configDrift()
+	if err != nil {
+		return nil, err
+	}
+	repaired := make([]ConfigDrift, 0, len(drift))
+	var errs []error
+	for _, item := range drift {
+		if err := item.repair(); err != nil {
+			errs = append(errs, fmt.Errorf("re-apply %s edit to %s: %w", item.Kind, item.File, err))
+			continue
+		}
+		repaired = append(repaired, item)
+	}
+	return repaired, errors.Join(errs...)
+}
+
+func (r *Runner) configDrift() ([]ConfigDrift, error) {
+	stateDir, err := r.localTrackedStateDir()
+	if err != nil {
+		return nil, err
+	}
+	tracked, err := state.LoadTrackedStateForInstall(stateDir)
+	if err != nil || tracked == nil {
+		return nil, err
+	}
+	home, err := r.homeDir()
+	if err != nil {
+		return nil, fmt.Errorf("determine home directory: %w", err)
+	}
+
+	drift := []ConfigDrift{}
+	if global := tracked.GlobalInstallSnapshot(); global != nil {
+		mcpBinaryName, _ := localArtifactBinaryNames(runtime.GOOS)
+		mcpBinaryPath := filepath.Join(resolveInstallPaths(home).binaryDir, mcpBinaryName)
+		for _, edit := range global.JSONEdits.AllSettingsEdits() {
+			found, err := settingsDrift(edit)
+			if err != nil {
+				return nil, err
+			}
+			drift = append(drift, found...)
+		}
+		for _, edit := range global.JSONEdits.AllMCPEdits() {
+			client := lookupClientTarget(clientKindForMCPEdit(edit))
+			found, err := mcpDrift(edit, client.mcpFormat, mcpCommandPathForClient(client, home, mcpBinaryPath))
+			if err != nil {
+				return nil, err
+			}
+			drift = append(drift, found...)
+		}
+	}
+	for _, local := range tracked.Local {
+		for _, edit := range local.JSONEdits.AllMCPEdits() {
+			found, err := mcpDrift(edit, config.VSCodeMCPFormat, config.LocalMCPCommand(runtime.GOOS))
+			if err != nil {
+				return nil, err
+			}
+			drift = append(drift, found...)
+		}
+	}
+	return drift, nil
+}
+
+func settingsDrift(edit state.SettingsEdit) ([]ConfigDrift, error) {
+	if !edit.Added || !parentDirExists(edit.File) {
+		return nil, nil
+	}
+	present, err := config.SettingsEditPresent(edit)
+	if err != nil || present {
+		return nil, err
+	}
+	return []ConfigDrift{{
+		Kind:    "settings",
+		File:    edit.File,
+		Pointer: "/" + config.SettingsAgentPathKey,
+		repair: func() error {
+			_, err := config.ApplySettingsEdit(edit.File, edit.AgentPath, nil, stateFilePerm)
+			return err
+		},
+	}}, nil
+}
+
+func mcpDrift(edit state.MCPEdit, format config.MCPFormat, commandPath string) ([]ConfigDrift, error) {
+	if !edit.Touched || !parentDirExists(edit.File) {
+		return nil, nil
+	}
+	present, err := config.MCPEditPresent(edit)
+	if err != nil || present {
+		return nil, err
+	}
+	return []ConfigDrift{{
+		Kind:    "mcp",
+		File:    edit.File,
+		Pointer: "/" + format.ServersKey + "/" + edit.Key,
+		repair: func() error {
+			_, err := config.ApplyMCPEditWithFormat(edit.File, format, commandPath, nil, stateFilePerm)
+			return err
+		},
+	}}, nil
+}
+
+func parentDirExists(path string) bool {
+	info, err := os.Stat(filepath.Dir(path))
+	return err == nil && info.IsDir()
+}
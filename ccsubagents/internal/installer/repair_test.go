@@ -0,0 +1,79 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
+)
+
+func TestRepairConfigDrift_ReappliesMissingTrackedEdits(t *testing.T) {
+	home := t.TempDir()
+	stateDir := globalStateDirForTest(home)
+	mcpPath := filepath.Join(home, "Code", "User", "mcp.json")
+	settingsPath := filepath.Join(home, "Code", "User", "settings.json")
+	goneClientPath := filepath.Join(home, "removed-client", "mcp.json")
+	if err := os.MkdirAll(filepath.Dir(mcpPath), stateDirPerm); err != nil {
+		t.Fatalf("mkdir client config: %v", err)
+	}
+	if err := writeJSONMap(mcpPath, map[string]any{"servers": map[string]any{"other": map[string]any{"command": "x"}}}); err != nil {
+		t.Fatalf("seed mcp: %v", err)
+	}
+	if err := writeJSONMap(settingsPath, map[string]any{}); err != nil {
+		t.Fatalf("seed settings: %v", err)
+	}
+	if err := os.MkdirAll(stateDir, stateDirPerm); err != nil {
+		t.Fatalf("mkdir state: %v", err)
+	}
+	if err := state.SaveTrackedState(stateDir, state.TrackedState{
+		Version:    state.TrackedSchemaVersion,
+		ReleaseTag: "v1.0.0",
+		JSONEdits: state.TrackedJSONOpsFromEdits(
+			[]state.SettingsEdit{{File: settingsPath, AgentPath: "~/agents", Added: true}},
+			[]state.MCPEdit{
+				{File: mcpPath, Key: config.MCPServerKey, Touched: true},
+				{File: goneClientPath, Key: config.MCPServerKey, Touched: true},
+			},
+		),
+	}); err != nil {
+		t.Fatalf("save tracked state: %v", err)
+	}
+
+	m := statusTestManager(home, nil, nil)
+	drift, err := m.FindConfigDrift()
+	if err != nil {
+		t.Fatalf("FindConfigDrift returned error: %v", err)
+	}
+	if len(drift) != 2 {
+		t.Fatalf("expected settings and mcp drift only, got %#v", drift)
+	}
+
+	repaired, err := m.RepairConfigDrift()
+	if err != nil {
+		t.Fatalf("RepairConfigDrift returned error: %v", err)
+	}
+	if len(repaired) != 2 {
+		t.Fatalf("expected two repaired edits, got %#v", repaired)
+	}
+	root, err := readJSONMap(mcpPath)
+	if err != nil {
+		t.Fatalf("read mcp: %v", err)
+	}
+	servers := mustMap(t, root["servers"], "servers")
+	if _, ok := servers[config.MCPServerKey]; !ok {
+		t.Fatalf("expected %s to be re-applied, got %#v", config.MCPServerKey, servers)
+	}
+	if _, ok := servers["other"]; !ok {
+		t.Fatalf("expected unrelated servers to be kept, got %#v", servers)
+	}
+	if _, err := os.Stat(goneClientPath); !os.IsNotExist(err) {
+		t.Fatalf("edits for removed clients must not recreate files, stat err=%v", err)
+	}
+
+	drift, err = m.FindConfigDrift()
+	if err != nil || len(drift) != 0 {
+		t.Fatalf("expected no drift after repair, got %#v err=%v", drift, err)
+	}
+}
@@ -36,35 +36,24 @@ func (r *Runner) runGlobal(ctx context.Context, command Command) error {
 		return fmt.Errorf("determine home directory: %w", err)
 	}
 
-	getenv := r.getenv
-	if getenv == nil {
-		getenv = os.Getenv
-	}
-
-	layout := paths.Global(home)
-	if stateOverride := strings.TrimSpace(getenv(paths.EnvStateDir)); stateOverride != "" {
-		layout.StateDir = filepath.Clean(stateOverride)
-	}
-	if blobOverride := strings.TrimSpace(getenv(paths.EnvBlobDir)); blobOverride != "" {
-		layout.BlobDir = filepath.Clean(blobOverride)
-	}
+	layout := r.globalLayout(home)
 
 	flowCommand := "global-" + string(command)
 	stepID := flowCommand + ".flow"
 
 	switch command {
 	case CommandInstall:
-		return executeFlowWithEngine(ctx, layout.StateDir, layout.BlobDir, "global-flow", flowCommand, stepID, func(stepCtx context.Context) error {
+		return r.executeFlow(ctx, layout.StateDir, layout.BlobDir, "global-flow", flowCommand, stepID, func(stepCtx context.Context) error {
 			return r.runGlobalInstall(stepCtx)
 		})
 	case CommandUpdate:
 		r.globalInstallTargets = nil
-		return executeFlowWithEngine(ctx, layout.StateDir, layout.BlobDir, "global-flow", flowCommand, stepID, func(stepCtx context.Context) error {
+		return r.executeFlow(ctx, layout.StateDir, layout.BlobDir, "global-flow", flowCommand, stepID, func(stepCtx context.Context) error {
 			return r.installOrUpdate(stepCtx, true)
 		})
 	case CommandUninstall:
 		r.globalInstallTargets = nil
-		return executeFlowWithEngine(ctx, layout.StateDir, layout.BlobDir, "global-flow", flowCommand, stepID, func(stepCtx context.Context) error {
+		return r.executeFlow(ctx, layout.StateDir, layout.BlobDir, "global-flow", flowCommand, stepID, func(stepCtx context.Context) error {
 			return r.uninstall(stepCtx)
 		})
 	default:
@@ -79,7 +68,7 @@ func (r *Runner) runGlobalInstall(ctx context.Context) error {
 	}
 
 	paths := resolveInstallPaths(home)
-	targets, err := r.promptGlobalInstallTargets(ctx, home, paths)
+	targets, err := r.selectGlobalInstallTargets(ctx, home, paths)
 	if err != nil {
 		return err
 	}
@@ -88,6 +77,21 @@ func (r *Runner) runGlobalInstall(ctx context.Context) error {
 	return r.installOrUpdate(ctx, false)
 }
 
+func (r *Runner) globalLayout(home string) paths.PathSet {
+	getenv := r.getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	layout := paths.Global(home)
+	if stateOverride := strings.TrimSpace(getenv(paths.EnvStateDir)); stateOverride != "" {
+		layout.StateDir = filepath.Clean(stateOverride)
+	}
+	if blobOverride := strings.TrimSpace(getenv(paths.EnvBlobDir)); blobOverride != "" {
+		layout.BlobDir = filepath.Clean(blobOverride)
+	}
+	return layout
+}
+
 func (r *Runner) runLocal(ctx context.Context, command Command) error {
 	stateDir, err := r.localTrackedStateDir()
 	if err != nil {
@@ -108,15 +112,15 @@ func (r *Runner) runLocal(ctx context.Context, command Command) error {
 
 	switch command {
 	case CommandInstall:
-		return executeFlowWithEngine(ctx, stateDir, blobDir, "local-flow", flowCommand, stepID, func(stepCtx context.Context) error {
+		return r.executeFlow(ctx, stateDir, blobDir, "local-flow", flowCommand, stepID, func(stepCtx context.Context) error {
 			return r.installLocal(stepCtx)
 		})
 	case CommandUpdate:
-		return executeFlowWithEngine(ctx, stateDir, blobDir, "local-flow", flowCommand, stepID, func(stepCtx context.Context) error {
+		return r.executeFlow(ctx, stateDir, blobDir, "local-flow", flowCommand, stepID, func(stepCtx context.Context) error {
 			return r.updateLocal(stepCtx)
 		})
 	case CommandUninstall:
-		return executeFlowWithEngine(ctx, stateDir, blobDir, "local-flow", flowCommand, stepID, func(stepCtx context.Context) error {
+		return r.executeFlow(ctx, stateDir, blobDir, "local-flow", flowCommand, stepID, func(stepCtx context.Context) error {
 			return r.uninstallLocal(stepCtx)
 		})
 	default:
@@ -81,6 +81,11 @@ type Runner struct {
 	verbose               bool
 	globalInstallTargets  []installConfigTarget
 	installDestination    installDestination
+	answers               Answers
+	nonInteractive        bool
+	dryRun                bool
+	preview               *changePreview // set while a dry run walks a flow; step reports are held back
+	outputFormat          OutputFormat
 }
 
 type installPaths struct {
@@ -97,6 +102,7 @@ type configPaths struct {
 }
 
 type installConfigTarget struct {
+	client       clientKind
 	settingsPath string
 	mcpPath      string
 }
@@ -143,6 +149,22 @@ func (r *Runner) SetPinned(pinned bool) {
 	r.pinRequested = pinned
 }
 
+func (r *Runner) SetAnswers(answers Answers) {
+	r.answers = answers
+}
+
+func (r *Runner) SetNonInteractive(nonInteractive bool) {
+	r.nonInteractive = nonInteractive
+}
+
+func (r *Runner) SetDryRun(dryRun bool) {
+	r.dryRun = dryRun
+}
+
+func (r *Runner) SetOutputFormat(format OutputFormat) {
+	r.outputFormat = format
+}
+
 func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
 	cmd := exec.CommandContext(ctx, name, args...)
 	var stderr bytes.Buffer
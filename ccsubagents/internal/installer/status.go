@@ -1,12 +1,49 @@
 package installer
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/integration/txn"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/release"
 )
 
+type OutputFormat string
+
+const (
+	OutputText OutputFormat = "text"
+	OutputJSON OutputFormat = "json"
+)
+
+func ParseOutputFormat(raw string) (OutputFormat, error) {
+	switch strings.TrimSpace(raw) {
+	case "", string(OutputText):
+		return OutputText, nil
+	case string(OutputJSON):
+		return OutputJSON, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (expected: text, json)", raw)
+	}
+}
+
+// statusEvent is one line of the newline-delimited JSON status stream written
+// instead of text when the output format is OutputJSON.
+type statusEvent struct {
+	Type    string       `json:"type"`
+	Status  string       `json:"status,omitempty"`
+	Summary string       `json:"summary,omitempty"`
+	Detail  string       `json:"detail,omitempty"`
+	Message string       `json:"message,omitempty"`
+	Version string       `json:"version,omitempty"`
+	Details []string     `json:"details,omitempty"`
+	Plan    *txn.Preview `json:"plan,omitempty"`
+}
+
+func (r *Runner) jsonStatus() bool {
+	return r.outputFormat == OutputJSON
+}
+
 func (r *Runner) statusf(format string, args ...any) {
 	if r.statusOut == nil || r.statusErr != nil {
 		return
@@ -16,11 +53,39 @@ func (r *Runner) statusf(format string, args ...any) {
 	}
 }
 
+func (r *Runner) emitStatus(event statusEvent) {
+	if r.statusOut == nil || r.statusErr != nil {
+		return
+	}
+	if err := json.NewEncoder(r.statusOut).Encode(event); err != nil {
+		r.statusErr = err
+	}
+}
+
 func (r *Runner) reportVersionHeader(tag string) {
+	if r.jsonStatus() {
+		r.emitStatus(statusEvent{Type: "version", Version: tag})
+		return
+	}
 	r.statusf("ccsubagents %s\n", tag)
 }
 
+func (r *Runner) reportUpToDate(tag string) {
+	if r.jsonStatus() {
+		r.emitStatus(statusEvent{Type: "up-to-date", Version: tag})
+		return
+	}
+	r.statusf("ccsubagents: already at latest version (%s). Nothing to do.\n", tag)
+}
+
 func (r *Runner) reportStepOK(summary, trailing string) {
+	if r.preview != nil {
+		return
+	}
+	if r.jsonStatus() {
+		r.emitStatus(statusEvent{Type: "step", Status: "ok", Summary: summary, Detail: strings.TrimSpace(trailing)})
+		return
+	}
 	if strings.TrimSpace(trailing) == "" {
 		r.statusf("✓ %s\n", summary)
 		return
@@ -29,31 +94,57 @@ func (r *Runner) reportStepOK(summary, trailing string) {
 }
 
 func (r *Runner) reportStepFail(summary string) {
+	if r.jsonStatus() {
+		r.emitStatus(statusEvent{Type: "step", Status: "failed", Summary: summary})
+		return
+	}
 	r.statusf("✗ %s\n", summary)
 }
 
 func (r *Runner) reportDetail(format string, args ...any) {
-	if !r.verbose {
+	if !r.verbose || r.preview != nil {
+		return
+	}
+	if r.jsonStatus() {
+		r.emitStatus(statusEvent{Type: "detail", Message: fmt.Sprintf(format, args...)})
 		return
 	}
 	r.statusf("  %s\n", fmt.Sprintf(format, args...))
 }
 
 func (r *Runner) reportMessageLine(format string, args ...any) {
+	if r.jsonStatus() {
+		r.emitStatus(statusEvent{Type: "message", Message: fmt.Sprintf(format, args...)})
+		return
+	}
 	r.statusf("  %s\n", fmt.Sprintf(format, args...))
 }
 
 func (r *Runner) reportWarning(headline string, details ...string) {
-	r.statusf("\n⚠ %s\n", headline)
+	kept := make([]string, 0, len(details))
 	for _, detail := range details {
-		if strings.TrimSpace(detail) == "" {
-			continue
+		if strings.TrimSpace(detail) != "" {
+			kept = append(kept, detail)
 		}
+	}
+	if r.jsonStatus() {
+		r.emitStatus(statusEvent{Type: "warning", Summary: headline, Details: kept})
+		return
+	}
+	r.statusf("\n⚠ %s\n", headline)
+	for _, detail := range kept {
 		r.statusf("  %s\n", detail)
 	}
 }
 
 func (r *Runner) reportCompletion(command string) {
+	if r.preview != nil {
+		return
+	}
+	if r.jsonStatus() {
+		r.emitStatus(statusEvent{Type: "complete", Summary: command})
+		return
+	}
 	r.statusf("%s complete.\n", command)
 }
 
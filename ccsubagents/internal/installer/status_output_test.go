@@ -3,6 +3,7 @@ package installer
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -106,6 +107,54 @@ func TestInstallOrUpdate_ReportsInstallProgress(t *testing.T) {
 	}
 }
 
+func decodeStatusEvents(t *testing.T, raw []byte) []statusEvent {
+	t.Helper()
+	events := []statusEvent{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	for decoder.More() {
+		var event statusEvent
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("expected newline-delimited JSON status, got %q: %v", raw, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestInstallOrUpdate_ReportsJSONStatusEvents(t *testing.T) {
+	home := t.TempDir()
+	agentsArchive := zipBytes(t, map[string]string{"agents/example.agent.md": "content"})
+	bundleArchive := zipBytes(t, bundleBinaryFiles("mcp-binary", "web-binary"))
+
+	var out bytes.Buffer
+	m := statusTestManager(home, successReleaseHTTPClient(t, "v1.2.3", agentsArchive, bundleArchive), &out)
+	m.SetOutputFormat(OutputJSON)
+
+	if err := m.installOrUpdate(context.Background(), false); err != nil {
+		t.Fatalf("install should succeed: %v", err)
+	}
+
+	events := decodeStatusEvents(t, out.Bytes())
+	if len(events) < 3 {
+		t.Fatalf("expected several status events, got %#v", events)
+	}
+	if first := events[0]; first.Type != "version" || first.Version != "v1.2.3" {
+		t.Fatalf("expected version event first, got %#v", first)
+	}
+	if last := events[len(events)-1]; last.Type != "complete" || last.Summary != "Install" {
+		t.Fatalf("expected completion event last, got %#v", last)
+	}
+	found := false
+	for _, event := range events {
+		if event.Type == "step" && event.Status == "ok" && event.Summary == "Downloaded release assets" && event.Detail == "v1.2.3" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected download step event, got %#v", events)
+	}
+}
+
 func TestInstallOrUpdate_ReportsUpdateCleanupProgress(t *testing.T) {
 	home := t.TempDir()
 	agentsDir := globalAgentsDirForTest(home)
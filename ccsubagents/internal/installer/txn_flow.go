@@ -2,17 +2,28 @@ package installer
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/config"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/files"
 	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/integration/txn"
+	"github.com/CeraCharlesCC/CCSubAgents/ccsubagents/internal/state"
 )
 
 var engineExecute = func(ctx context.Context, engine txn.Engine, plan txn.Plan) error {
 	return engine.Execute(ctx, plan)
 }
 
-func executeFlowWithEngine(ctx context.Context, stateDir, blobDir, scopeID, command, stepID string, apply func(context.Context) error) error {
-	engine := txn.Engine{StateDir: stateDir, BlobDir: blobDir}
-	return engineExecute(ctx, engine, txn.Plan{
+var enginePreview = func(ctx context.Context, engine txn.Engine, plan txn.Plan) (*txn.Preview, error) {
+	return engine.Preview(ctx, plan)
+}
+
+// flowPlan wraps a flow as a single engine step. Apply runs the flow for real;
+// Preview runs the same flow with the runner's effects swapped for a recorder.
+func (r *Runner) flowPlan(scopeID, command, stepID string, apply func(context.Context) error) txn.Plan {
+	return txn.Plan{
 		ScopeID: scopeID,
 		Command: command,
 		Steps: []txn.Step{{
@@ -26,6 +37,198 @@ func executeFlowWithEngine(ctx context.Context, stateDir, blobDir, scopeID, comm
 				}
 				return apply(stepCtx)
 			},
+			Preview: func(stepCtx context.Context, preview *txn.Preview) error {
+				if apply == nil {
+					return nil
+				}
+				r.preview = newChangePreview(preview)
+				defer func() {
+					r.preview = nil
+				}()
+				return apply(stepCtx)
+			},
 		}},
-	})
+	}
+}
+
+// executeFlow runs the flow through the engine, or only previews it when the
+// runner is in dry-run mode.
+func (r *Runner) executeFlow(ctx context.Context, stateDir, blobDir, scopeID, command, stepID string, apply func(context.Context) error) error {
+	engine := txn.Engine{StateDir: stateDir, BlobDir: blobDir}
+	plan := r.flowPlan(scopeID, command, stepID, apply)
+	if !r.dryRun {
+		return engineExecute(ctx, engine, plan)
+	}
+	result, err := enginePreview(ctx, engine, plan)
+	if err != nil {
+		return err
+	}
+	return r.reportPreview(result)
+}
+
+// flowEffects is every change the install, update and uninstall flows make.
+// The flows are written once against it, so a dry run walks the same decisions
+// as apply and differs only in recording changes instead of making them.
+type flowEffects interface {
+	begin(stateDir, blobDir, scopeID, command string) error
+	commit() error
+	// end closes the transaction from begin, rolling it back if *retErr is set.
+	end(retErr *error)
+	downloadDir(stateDir string) string
+	mkdirAll(path string) error
+	ensureDirWithinBase(path, base string) error
+	ensureParentDirWithinBase(path, base string) error
+	snapshotFile(path string) error
+	createdDirectories() []string
+	installBinaries(ctx context.Context, bundleBinaries map[string]string, destinationDir, permissionHintDir, goos string) ([]string, error)
+	extractAgents(zipPath, agentsDir string) (filesOut []string, dirsOut []string, err error)
+	applySettingsEdit(path, agentsPath string, previous *state.TrackedState) (state.SettingsEdit, error)
+	applyMCPEdit(path string, format config.MCPFormat, commandPath string, previous *state.TrackedState) (state.MCPEdit, error)
+	applyLocalIgnoreRules(installRoot, repoRoot string, mode state.LocalInstallMode) ([]state.IgnoreEdit, error)
+	writePinnedVersion(path, versionTag string) error
+	removeStaleAgentFiles(oldFiles, newFiles []string, agentsDir string) error
+	removeFile(path string) error
+	removeDir(path string) error
+	revertSettingsEdit(edit state.SettingsEdit) error
+	revertMCPEdit(edit state.MCPEdit) error
+	revertIgnoreEdits(edits []state.IgnoreEdit) error
+	saveTrackedState(stateDir string, tracked state.TrackedState) error
+	stopDaemon(ctx context.Context) error
+}
+
+func (r *Runner) effects() flowEffects {
+	if r.preview != nil {
+		return r.preview
+	}
+	return &liveEffects{r: r}
+}
+
+type liveEffects struct {
+	r         *Runner
+	session   *txn.Session
+	rollback  *files.Rollback
+	mutations *files.MutationTracker
+}
+
+func (e *liveEffects) begin(stateDir, blobDir, scopeID, command string) error {
+	session, err := txn.Begin(stateDir, blobDir, scopeID, command, []string{"mutations"})
+	if err != nil {
+		return err
+	}
+	if err := session.MarkApplied("mutations"); err != nil {
+		session.Close()
+		return err
+	}
+	e.session = session
+	e.rollback = session.NewRollback("mutations")
+	e.mutations = files.NewMutationTracker(e.rollback, stateDirPerm)
+	return nil
+}
+
+func (e *liveEffects) commit() error {
+	return e.session.Commit()
+}
+
+func (e *liveEffects) end(retErr *error) {
+	if e.session == nil {
+		return
+	}
+	defer e.session.Close()
+	if *retErr == nil {
+		return
+	}
+	if rollbackErr := e.rollback.Restore(); rollbackErr != nil {
+		*retErr = fmt.Errorf("%w (rollback failed: %v)", *retErr, rollbackErr)
+	}
+	if txRollbackErr := e.session.Rollback(); txRollbackErr != nil {
+		*retErr = fmt.Errorf("%w (transaction rollback failed: %v)", *retErr, txRollbackErr)
+	}
+}
+
+func (e *liveEffects) downloadDir(stateDir string) string {
+	return stateDir
+}
+
+func (e *liveEffects) mkdirAll(path string) error {
+	return os.MkdirAll(path, stateDirPerm)
+}
+
+func (e *liveEffects) ensureDirWithinBase(path, base string) error {
+	return e.mutations.EnsureDirWithinBase(path, base)
+}
+
+func (e *liveEffects) ensureParentDirWithinBase(path, base string) error {
+	return e.mutations.EnsureParentDirWithinBase(path, base)
+}
+
+func (e *liveEffects) snapshotFile(path string) error {
+	return e.mutations.SnapshotFile(path)
+}
+
+func (e *liveEffects) createdDirectories() []string {
+	return e.mutations.CreatedDirectories()
+}
+
+func (e *liveEffects) installBinaries(ctx context.Context, bundleBinaries map[string]string, destinationDir, permissionHintDir, goos string) ([]string, error) {
+	return e.r.installExtractedBinaries(ctx, bundleBinaries, destinationDir, e.mutations, permissionHintDir, goos)
+}
+
+func (e *liveEffects) extractAgents(zipPath, agentsDir string) ([]string, []string, error) {
+	return files.ExtractAgentsArchiveWithHook(zipPath, agentsDir, e.mutations.SnapshotFile, stateDirPerm, stateFilePerm)
+}
+
+func (e *liveEffects) applySettingsEdit(path, agentsPath string, previous *state.TrackedState) (state.SettingsEdit, error) {
+	return config.ApplySettingsEdit(path, agentsPath, previous, stateFilePerm)
+}
+
+func (e *liveEffects) applyMCPEdit(path string, format config.MCPFormat, commandPath string, previous *state.TrackedState) (state.MCPEdit, error) {
+	return config.ApplyMCPEditWithFormat(path, format, commandPath, previous, stateFilePerm)
+}
+
+func (e *liveEffects) applyLocalIgnoreRules(installRoot, repoRoot string, mode state.LocalInstallMode) ([]state.IgnoreEdit, error) {
+	return config.ApplyLocalIgnoreRules(installRoot, repoRoot, mode, stateDirPerm, stateFilePerm)
+}
+
+func (e *liveEffects) writePinnedVersion(path, versionTag string) error {
+	if e.mutations != nil {
+		if err := e.mutations.EnsureParentDirWithinBase(path, filepath.Dir(path)); err != nil {
+			return err
+		}
+		if err := e.mutations.SnapshotFile(path); err != nil {
+			return err
+		}
+	}
+	return config.WritePinnedVersion(path, versionTag, stateDirPerm, stateFilePerm)
+}
+
+func (e *liveEffects) removeStaleAgentFiles(oldFiles, newFiles []string, agentsDir string) error {
+	return files.RemoveStaleAgentFilesWithHook(oldFiles, newFiles, agentsDir, e.mutations.SnapshotFile)
+}
+
+func (e *liveEffects) removeFile(path string) error {
+	return os.Remove(path)
+}
+
+func (e *liveEffects) removeDir(path string) error {
+	return os.Remove(path)
+}
+
+func (e *liveEffects) revertSettingsEdit(edit state.SettingsEdit) error {
+	return config.RevertSettingsEdit(edit, stateFilePerm)
+}
+
+func (e *liveEffects) revertMCPEdit(edit state.MCPEdit) error {
+	return config.RevertMCPEdit(edit, stateFilePerm)
+}
+
+func (e *liveEffects) revertIgnoreEdits(edits []state.IgnoreEdit) error {
+	return config.RevertIgnoreEdits(edits, stateFilePerm)
+}
+
+func (e *liveEffects) saveTrackedState(stateDir string, tracked state.TrackedState) error {
+	return state.SaveTrackedState(stateDir, tracked)
+}
+
+func (e *liveEffects) stopDaemon(ctx context.Context) error {
+	return e.r.stopDaemonBeforeRemoval(ctx)
 }
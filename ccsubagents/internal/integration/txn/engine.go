@@ -12,6 +12,7 @@ type Step struct {
 	DependsOn []string
 	Apply     func(context.Context, *Session) error
 	Verify    func(context.Context, *Session) error
+	Preview   func(context.Context, *Preview) error
 }
 
 type Plan struct {
@@ -29,13 +30,7 @@ func (e Engine) Execute(ctx context.Context, plan Plan) error {
 	if plan.ScopeID == "" {
 		return fmt.Errorf("plan scope is required")
 	}
-	nodes := make([]action.Node, 0, len(plan.Steps))
-	stepsByID := make(map[string]Step, len(plan.Steps))
-	for _, step := range plan.Steps {
-		nodes = append(nodes, action.Node{ID: step.ID, DependsOn: step.DependsOn})
-		stepsByID[step.ID] = step
-	}
-	order, err := action.TopoSort(nodes)
+	order, stepsByID, err := plan.order()
 	if err != nil {
 		return err
 	}
@@ -72,3 +67,17 @@ func (e Engine) Execute(ctx context.Context, plan Plan) error {
 	}
 	return session.Commit()
 }
+
+func (p Plan) order() ([]string, map[string]Step, error) {
+	nodes := make([]action.Node, 0, len(p.Steps))
+	stepsByID := make(map[string]Step, len(p.Steps))
+	for _, step := range p.Steps {
+		nodes = append(nodes, action.Node{ID: step.ID, DependsOn: step.DependsOn})
+		stepsByID[step.ID] = step
+	}
+	order, err := action.TopoSort(nodes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, stepsByID, nil
+}
@@ -96,3 +96,47 @@ func TestRecover_RollsBackStaleJournal(t *testing.T) {
 		t.Fatalf("expected recovered file content, got %q", string(b))
 	}
 }
+
+func TestEngine_PreviewRecordsChangesWithoutJournal(t *testing.T) {
+	stateDir := filepath.Join(t.TempDir(), "state")
+	applied := false
+
+	engine := Engine{StateDir: stateDir, BlobDir: filepath.Join(t.TempDir(), "blob")}
+	preview, err := engine.Preview(context.Background(), Plan{
+		ScopeID: "global",
+		Command: "install",
+		Steps: []Step{
+			{
+				ID:        "second",
+				DependsOn: []string{"first"},
+				Apply: func(context.Context, *Session) error {
+					applied = true
+					return nil
+				},
+				Preview: func(_ context.Context, p *Preview) error {
+					p.Record(Change{Kind: ChangeRemoveFile, Path: "/tmp/b"})
+					return nil
+				},
+			},
+			{
+				ID: "first",
+				Preview: func(_ context.Context, p *Preview) error {
+					p.Record(Change{Kind: ChangeWriteFile, Path: "/tmp/a"})
+					return nil
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if applied {
+		t.Fatal("preview must not run Apply")
+	}
+	if len(preview.Changes) != 2 || preview.Changes[0].Step != "first" || preview.Changes[1].Step != "second" {
+		t.Fatalf("unexpected preview changes: %#v", preview.Changes)
+	}
+	if _, err := os.Stat(stateDir); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("preview must not create state dir, stat err: %v", err)
+	}
+}
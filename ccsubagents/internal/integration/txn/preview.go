@@ -0,0 +1,75 @@
+package txn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+type ChangeKind string
+
+const (
+	ChangeCreateDir    ChangeKind = "create-dir"
+	ChangeWriteFile    ChangeKind = "write-file"
+	ChangeRemoveFile   ChangeKind = "remove-file"
+	ChangeRemoveDir    ChangeKind = "remove-dir"
+	ChangeEditJSON     ChangeKind = "edit-json"
+	ChangeAddIgnore    ChangeKind = "add-ignore"
+	ChangeRemoveIgnore ChangeKind = "remove-ignore"
+)
+
+// Change is a single mutation a step reports it would make. Pointer, Before
+// and After are set for JSON edits; Lines for ignore-file edits.
+type Change struct {
+	Step    string          `json:"step"`
+	Kind    ChangeKind      `json:"kind"`
+	Path    string          `json:"path"`
+	Pointer string          `json:"pointer,omitempty"`
+	Before  json.RawMessage `json:"before,omitempty"`
+	After   json.RawMessage `json:"after,omitempty"`
+	Lines   []string        `json:"lines,omitempty"`
+}
+
+type Preview struct {
+	ScopeID string   `json:"scope"`
+	Command string   `json:"command"`
+	Steps   []string `json:"steps"`
+	Changes []Change `json:"changes"`
+
+	step string
+}
+
+// Record appends change, attributing it to the step currently being previewed.
+func (p *Preview) Record(change Change) {
+	change.Step = p.step
+	p.Changes = append(p.Changes, change)
+}
+
+// Preview walks plan in execution order and lets each step report the changes
+// it would make. It takes no lock and writes no journal, so steps must not
+// mutate anything from their Preview callback.
+func (e Engine) Preview(ctx context.Context, plan Plan) (*Preview, error) {
+	if plan.ScopeID == "" {
+		return nil, fmt.Errorf("plan scope is required")
+	}
+	order, stepsByID, err := plan.order()
+	if err != nil {
+		return nil, err
+	}
+	preview := &Preview{ScopeID: plan.ScopeID, Command: plan.Command, Steps: order, Changes: []Change{}}
+	for _, id := range order {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		step := stepsByID[id]
+		if step.Preview == nil {
+			continue
+		}
+		preview.step = id
+		if err := step.Preview(ctx, preview); err != nil {
+			return nil, err
+		}
+	}
+	preview.step = ""
+	return preview, nil
+}
@@ -45,6 +45,18 @@ func Recover(stateDir, scopeID string) error {
 	return nil
 }
 
+// RecoverIdle is Recover for callers outside a transaction: it holds the scope
+// lock while rolling back, so a journal that belongs to a live run is left
+// alone.
+func RecoverIdle(stateDir, scopeID string) error {
+	release, err := acquireLock(stateDir, scopeID)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return Recover(stateDir, scopeID)
+}
+
 func Begin(stateDir, blobDir, scopeID, command string, actionIDs []string) (*Session, error) {
 	if err := Recover(stateDir, scopeID); err != nil {
 		return nil, err
@@ -20,15 +20,29 @@ type AppliedStep struct {
 }
 
 type TrackedState struct {
-	Version      int            `json:"version"`
-	Repo         string         `json:"repo"`
-	ReleaseID    int64          `json:"releaseId"`
-	ReleaseTag   string         `json:"releaseTag"`
-	InstalledAt  string         `json:"installedAt"`
-	Managed      ManagedState   `json:"managed"`
-	AppliedSteps []AppliedStep  `json:"appliedSteps,omitempty"`
-	JSONEdits    TrackedJSONOps `json:"jsonEdits"`
-	Local        []LocalInstall `json:"local,omitempty"`
+	Version      int             `json:"version"`
+	Repo         string          `json:"repo"`
+	ReleaseID    int64           `json:"releaseId"`
+	ReleaseTag   string          `json:"releaseTag"`
+	InstalledAt  string          `json:"installedAt"`
+	Managed      ManagedState    `json:"managed"`
+	AppliedSteps []AppliedStep   `json:"appliedSteps,omitempty"`
+	JSONEdits    TrackedJSONOps  `json:"jsonEdits"`
+	Choices      *InstallChoices `json:"choices,omitempty"`
+	Local        []LocalInstall  `json:"local,omitempty"`
+}
+
+// InstallChoices records the answers a lifecycle run resolved, so update can
+// replay them without prompting.
+type InstallChoices struct {
+	Targets []string `json:"targets,omitempty"`
+}
+
+func (c *InstallChoices) Clone() *InstallChoices {
+	if c == nil {
+		return nil
+	}
+	return &InstallChoices{Targets: slices.Clone(c.Targets)}
 }
 
 type LocalInstallMode string
@@ -74,14 +88,23 @@ type SettingsEdit struct {
 	AgentPath string `json:"agentPath"`
 	Mode      string `json:"mode,omitempty"`
 	Added     bool   `json:"added"`
+	// CreatedLocations is set when the edit also created the enclosing
+	// chat.agentFilesLocations object, so reverting removes it again.
+	CreatedLocations bool `json:"createdLocations,omitempty"`
 }
 
 type MCPEdit struct {
 	File        string          `json:"file"`
+	Client      string          `json:"client,omitempty"`
+	ServersKey  string          `json:"serversKey,omitempty"`
 	Key         string          `json:"key"`
 	Touched     bool            `json:"touched"`
 	HadPrevious bool            `json:"hadPrevious"`
 	Previous    json.RawMessage `json:"previous,omitempty"`
+	// PreviousText is the replaced entry exactly as it appeared in the file,
+	// comments and layout included.
+	PreviousText   string `json:"previousText,omitempty"`
+	CreatedServers bool   `json:"createdServers,omitempty"`
 }
 
 func cloneMCPEdit(edit MCPEdit) MCPEdit {
@@ -200,6 +223,7 @@ func (state *TrackedState) GlobalInstallSnapshot() *TrackedState {
 		},
 		AppliedSteps: slices.Clone(state.AppliedSteps),
 		JSONEdits:    state.JSONEdits.Clone(),
+		Choices:      state.Choices.Clone(),
 	}
 }
 
@@ -250,6 +274,7 @@ func (state *TrackedState) ClearGlobalInstall() {
 	state.Managed = ManagedState{}
 	state.AppliedSteps = nil
 	state.JSONEdits = TrackedJSONOps{}
+	state.Choices = nil
 }
 
 func (state *TrackedState) Empty() bool {
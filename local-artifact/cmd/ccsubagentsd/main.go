@@ -8,9 +8,14 @@ import (
 	"runtime"
 
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/config"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/blobstore"
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
 )
 
+// version is set at release build time via -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
 	storeRoot, err := config.ResolveStoreRoot()
 	if err != nil {
@@ -45,16 +50,35 @@ func main() {
 	flag.StringVar(&cfg.APIAddr, "api-addr", defaultAddr, "daemon API TCP address")
 	flag.StringVar(&cfg.WebAddr, "web-addr", "", "optional web UI listen address (localhost only)")
 	flag.StringVar(&cfg.Token, "token", defaultToken, "daemon auth token")
+	flag.IntVar(&cfg.Limits.MaxOpenWorkspaces, "max-open-workspaces", daemon.DefaultMaxOpenWorkspaces, "maximum workspace databases kept open at once")
+	flag.DurationVar(&cfg.Limits.IdleTimeout, "workspace-idle-timeout", daemon.DefaultWorkspaceIdleTimeout, "close workspace databases unused for this long (negative disables)")
+	flag.DurationVar(&cfg.Limits.WALCheckpointInterval, "wal-checkpoint-interval", daemon.DefaultWALCheckpointInterval, "interval between SQLite WAL checkpoints (negative disables)")
 	flag.Parse()
 	if ccSettings.NoAuth {
 		cfg.Token = ""
 		cfg.DisableAuth = true
 	}
+	cfg.Quota = artifacts.Quota{
+		MaxTotalBytes:      ccSettings.Quota.MaxTotalBytes,
+		MaxArtifactBytes:   ccSettings.Quota.MaxArtifactBytes,
+		MaxVersionsPerName: ccSettings.Quota.MaxVersionsPerName,
+	}
+	cfg.Compression = blobstore.NewCompressionPolicy(ccSettings.Compression)
+	cfg.Expiry = artifacts.ExpiryPolicy(ccSettings.TTL)
+	cfg.JSONSchemas = ccSettings.JSONSchemas
+	cfg.EncryptAtRest = ccSettings.EncryptAtRest
+	cfg.Backup = daemon.BackupPolicy{
+		Dir:       ccSettings.Backup.Dir,
+		Interval:  ccSettings.Backup.Interval,
+		Retention: ccSettings.Backup.Retention,
+	}
+	cfg.WorkspaceIdentity = config.WorkspaceIdentityForRoot
 
 	if runtime.GOOS == "windows" {
 		cfg.APISocket = ""
 	}
 	cfg.Stderr = os.Stderr
+	cfg.Version = version
 
 	if err := daemon.Run(context.Background(), cfg); err != nil {
 		fmt.Fprintln(os.Stderr, "ccsubagentsd error:", err)
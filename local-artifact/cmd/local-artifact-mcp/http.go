@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/mcp"
+)
+
+const mcpHTTPPath = "/mcp"
+
+// serveMCPHTTP serves the Streamable HTTP transport on addr until ctx is done.
+// Requests need the daemon token whenever auth is enabled.
+func serveMCPHTTP(ctx context.Context, addr, token string, newServer func() *mcp.Server, stderr io.Writer) error {
+	if !isLoopbackHostPort(addr) {
+		return fmt.Errorf("mcp http address must bind localhost only: %s", addr)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(mcpHTTPPath, daemon.AuthMiddleware(token, mcp.NewHTTPHandler(ctx, newServer), daemon.AuthOptions{}))
+	httpServer := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.Serve(listener)
+	}()
+	writeln(stderr, "local-artifact-mcp listening on http://"+listener.Addr().String()+mcpHTTPPath)
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return ctx.Err()
+	}
+}
+
+func isLoopbackHostPort(addr string) bool {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(addr))
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	parsed := net.ParseIP(host)
+	return parsed != nil && parsed.IsLoopback()
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -52,6 +53,10 @@ func mainExitCode() int {
 }
 
 func run() error {
+	serveHTTP := flag.Bool("http", false, "serve MCP over Streamable HTTP instead of stdio")
+	httpAddr := flag.String("http-addr", config.ResolveMCPHTTPAddr(), "Streamable HTTP listen address (localhost only)")
+	flag.Parse()
+
 	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
 	defer stop()
 
@@ -84,7 +89,19 @@ func run() error {
 			return fmt.Errorf("cannot start ccsubagentsd: %w", err)
 		}
 
-		srv := mcp.NewWithClient(root, client)
+		if *serveHTTP {
+			token := ""
+			if !ccSettings.NoAuth {
+				token = config.ResolveDaemonToken(stateDir)
+			}
+			newServer := func() *mcp.Server { return newMCPServer(root, client, ccSettings) }
+			if err := serveMCPHTTP(ctx, *httpAddr, token, newServer, os.Stderr); err != nil {
+				return fmt.Errorf("server error: %w", err)
+			}
+			return nil
+		}
+
+		srv := newMCPServer(root, client, ccSettings)
 
 		// MCP stdio transport requires newline-delimited JSON-RPC messages on stdout.
 		// Write any diagnostics only to stderr.
@@ -95,6 +112,14 @@ func run() error {
 	})
 }
 
+// newMCPServer builds the server for either transport with the settings
+// that shape tool behavior applied.
+func newMCPServer(root string, client *daemon.Client, settings config.CCSubagentsSettings) *mcp.Server {
+	srv := mcp.NewWithClient(root, client)
+	srv.SetReadMaxBytes(settings.ReadMaxBytes)
+	return srv
+}
+
 func runWithAutostartedWebChild(autostartWeb bool, stderr io.Writer, runFn func() error) error {
 	if stderr == nil {
 		stderr = os.Stderr
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/config"
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
 )
@@ -264,6 +268,60 @@ func TestStopChildProcess_GracefulErrorFallsBackToForce(t *testing.T) {
 	}
 }
 
+func TestNewMCPServer_StdioAppliesReadMaxBytes(t *testing.T) {
+	root := t.TempDir()
+	engine, err := daemon.NewEngine(root)
+	if err != nil {
+		t.Fatalf("new daemon engine: %v", err)
+	}
+	t.Cleanup(func() { _ = engine.Close() })
+	h := httptest.NewServer(daemon.NewServer(engine, "mcp-main-test").Routes())
+	t.Cleanup(h.Close)
+	client := daemon.NewHTTPClient(h.URL, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := client.SaveText(ctx, daemon.SaveTextRequest{
+		Workspace: daemon.WorkspaceSelector{WorkspaceID: workspaces.GlobalWorkspaceID},
+		Name:      "log/build",
+		Text:      strings.Repeat("x", 64),
+	}); err != nil {
+		t.Fatalf("save text: %v", err)
+	}
+
+	srv := newMCPServer(root, client, config.CCSubagentsSettings{ReadMaxBytes: 16})
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() { _ = srv.Serve(ctx, inR, outW) }()
+	t.Cleanup(func() {
+		_ = inW.Close()
+		_ = outR.Close()
+	})
+
+	call := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_artifact","arguments":{"name":"log/build"}}}` + "\n"
+	if _, err := io.WriteString(inW, call); err != nil {
+		t.Fatalf("write tools/call: %v", err)
+	}
+	line, err := bufio.NewReader(outR).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	var resp struct {
+		Result struct {
+			StructuredContent struct {
+				TotalBytes int64 `json:"totalBytes"`
+				Truncated  bool  `json:"truncated"`
+			} `json:"structuredContent"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(line, &resp); err != nil {
+		t.Fatalf("decode response %s: %v", line, err)
+	}
+	if got := resp.Result.StructuredContent; got.TotalBytes != 64 || !got.Truncated {
+		t.Fatalf("expected the stdio server to cap get_artifact at read-max-bytes, got %s", line)
+	}
+}
+
 func resetMCPMainHooks(t *testing.T) {
 	t.Helper()
 	origStartLocalArtifactWebFn := startLocalArtifactWebFn
@@ -9,6 +9,8 @@ import (
 	"runtime"
 
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/config"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/blobstore"
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
 )
 
@@ -77,6 +79,21 @@ func run() error {
 		Token:       token,
 		DisableAuth: ccSettings.NoAuth,
 		Stderr:      os.Stderr,
+		Quota: artifacts.Quota{
+			MaxTotalBytes:      ccSettings.Quota.MaxTotalBytes,
+			MaxArtifactBytes:   ccSettings.Quota.MaxArtifactBytes,
+			MaxVersionsPerName: ccSettings.Quota.MaxVersionsPerName,
+		},
+		Compression:   blobstore.NewCompressionPolicy(ccSettings.Compression),
+		Expiry:        artifacts.ExpiryPolicy(ccSettings.TTL),
+		JSONSchemas:   ccSettings.JSONSchemas,
+		EncryptAtRest: ccSettings.EncryptAtRest,
+		Backup: daemon.BackupPolicy{
+			Dir:       ccSettings.Backup.Dir,
+			Interval:  ccSettings.Backup.Interval,
+			Retention: ccSettings.Backup.Retention,
+		},
+		WorkspaceIdentity: config.WorkspaceIdentityForRoot,
 	})
 	if err != nil {
 		return fmt.Errorf("web daemon error: %w", err)
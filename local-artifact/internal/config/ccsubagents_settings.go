@@ -5,9 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
 )
 
 const ccsubagentsConfigDirEnv = "CCSUBAGENTS_CONFIG_DIR"
@@ -16,6 +21,43 @@ type CCSubagentsSettings struct {
 	AutostartWebUI bool
 	NoAuth         bool
 	WebUIPort      int
+	Quota          QuotaSettings
+	// Compression maps MIME patterns ("text/*", "application/json", "*") to
+	// the on-disk encoding of new blobs, "gzip" or "none".
+	Compression map[string]string
+	// TTL maps name patterns ("scratch/*", an exact name, "*") to the default
+	// time to live of saves that set no expiry; 0 disables a pattern.
+	TTL map[string]time.Duration
+	// JSONSchemas maps name patterns to the JSON Schema that saves under them
+	// must satisfy. A null schema exempts the names a pattern matches.
+	JSONSchemas map[string]json.RawMessage
+	// ReadMaxBytes caps the data get_artifact returns when the caller sets no
+	// maxBytes; 0 disables the cap.
+	ReadMaxBytes int64
+	// EncryptAtRest encrypts new blobs with per-workspace keys.
+	EncryptAtRest bool
+	Backup        BackupSettings
+}
+
+// DefaultReadMaxBytes is ReadMaxBytes when no settings file sets
+// read-max-bytes.
+const DefaultReadMaxBytes int64 = 64 << 10
+
+// BackupSettings mirrors the "backup" object of settings.json. A zero
+// Interval disables scheduled snapshots; zero Retention and an empty Dir
+// leave the daemon defaults.
+type BackupSettings struct {
+	Interval  time.Duration
+	Retention int
+	Dir       string
+}
+
+// QuotaSettings mirrors the "quota" object of settings.json. Zero fields are
+// unlimited.
+type QuotaSettings struct {
+	MaxTotalBytes      int64
+	MaxArtifactBytes   int64
+	MaxVersionsPerName int
 }
 
 type ccsubagentsSettingsPatch struct {
@@ -25,6 +67,32 @@ type ccsubagentsSettingsPatch struct {
 	NoAuth            bool
 	HasWebUIPort      bool
 	WebUIPort         int
+
+	HasMaxTotalBytes      bool
+	MaxTotalBytes         int64
+	HasMaxArtifactBytes   bool
+	MaxArtifactBytes      int64
+	HasMaxVersionsPerName bool
+	MaxVersionsPerName    int
+
+	Compression map[string]string
+	TTL         map[string]time.Duration
+	JSONSchemas map[string]json.RawMessage
+
+	HasReadMaxBytes bool
+	ReadMaxBytes    int64
+
+	HasEncryptAtRest bool
+	EncryptAtRest    bool
+
+	HasBackupInterval  bool
+	BackupInterval     time.Duration
+	HasBackupRetention bool
+	BackupRetention    int
+	HasBackupDir       bool
+	BackupDir          string
+
+	WorkspaceIdentity string
 }
 
 func ResolveCCSubagentsSettings() (CCSubagentsSettings, error) {
@@ -79,13 +147,16 @@ func resolveMergedCCSubagentsSettings(home, cwd string) (CCSubagentsSettings, er
 	if err != nil {
 		return CCSubagentsSettings{}, fmt.Errorf("read settings file %s: %w", globalPath, err)
 	}
+	if globalPatch.WorkspaceIdentity != "" {
+		return CCSubagentsSettings{}, fmt.Errorf("read settings file %s: key workspace-identity is only read from a workspace's settings.json", globalPath)
+	}
 
 	localPatch, err := readCCSubagentsSettingsPatch(localPath)
 	if err != nil {
 		return CCSubagentsSettings{}, fmt.Errorf("read settings file %s: %w", localPath, err)
 	}
 
-	settings := CCSubagentsSettings{}
+	settings := CCSubagentsSettings{ReadMaxBytes: DefaultReadMaxBytes}
 	applyPatch := func(patch ccsubagentsSettingsPatch) {
 		if patch.HasAutostartWebUI {
 			settings.AutostartWebUI = patch.AutostartWebUI
@@ -96,6 +167,48 @@ func resolveMergedCCSubagentsSettings(home, cwd string) (CCSubagentsSettings, er
 		if patch.HasWebUIPort {
 			settings.WebUIPort = patch.WebUIPort
 		}
+		if patch.HasMaxTotalBytes {
+			settings.Quota.MaxTotalBytes = patch.MaxTotalBytes
+		}
+		if patch.HasMaxArtifactBytes {
+			settings.Quota.MaxArtifactBytes = patch.MaxArtifactBytes
+		}
+		if patch.HasMaxVersionsPerName {
+			settings.Quota.MaxVersionsPerName = patch.MaxVersionsPerName
+		}
+		if patch.HasReadMaxBytes {
+			settings.ReadMaxBytes = patch.ReadMaxBytes
+		}
+		if patch.HasEncryptAtRest {
+			settings.EncryptAtRest = patch.EncryptAtRest
+		}
+		if patch.HasBackupInterval {
+			settings.Backup.Interval = patch.BackupInterval
+		}
+		if patch.HasBackupRetention {
+			settings.Backup.Retention = patch.BackupRetention
+		}
+		if patch.HasBackupDir {
+			settings.Backup.Dir = patch.BackupDir
+		}
+		for pattern, encoding := range patch.Compression {
+			if settings.Compression == nil {
+				settings.Compression = map[string]string{}
+			}
+			settings.Compression[pattern] = encoding
+		}
+		for pattern, ttl := range patch.TTL {
+			if settings.TTL == nil {
+				settings.TTL = map[string]time.Duration{}
+			}
+			settings.TTL[pattern] = ttl
+		}
+		for pattern, schema := range patch.JSONSchemas {
+			if settings.JSONSchemas == nil {
+				settings.JSONSchemas = map[string]json.RawMessage{}
+			}
+			settings.JSONSchemas[pattern] = schema
+		}
 	}
 
 	applyPatch(globalPatch)
@@ -166,5 +279,288 @@ func readCCSubagentsSettingsPatch(path string) (ccsubagentsSettingsPatch, error)
 		patch.WebUIPort = port
 	}
 
+	if raw, ok := root["quota"]; ok {
+		if err := readQuotaPatch(raw, &patch); err != nil {
+			return ccsubagentsSettingsPatch{}, err
+		}
+	}
+
+	if raw, ok := root["compression"]; ok {
+		compression, err := readCompressionPatch(raw)
+		if err != nil {
+			return ccsubagentsSettingsPatch{}, err
+		}
+		patch.Compression = compression
+	}
+
+	if raw, ok := root["ttl"]; ok {
+		ttl, err := readTTLPatch(raw)
+		if err != nil {
+			return ccsubagentsSettingsPatch{}, err
+		}
+		patch.TTL = ttl
+	}
+
+	if raw, ok := root["json-schemas"]; ok {
+		schemas, err := readJSONSchemasPatch(raw, filepath.Dir(path))
+		if err != nil {
+			return ccsubagentsSettingsPatch{}, err
+		}
+		patch.JSONSchemas = schemas
+	}
+
+	if raw, ok := root["read-max-bytes"]; ok {
+		size, err := parseByteSize(raw)
+		if err != nil {
+			return ccsubagentsSettingsPatch{}, fmt.Errorf("key read-max-bytes %v", err)
+		}
+		patch.HasReadMaxBytes = true
+		patch.ReadMaxBytes = size
+	}
+
+	if raw, ok := root["encrypt-at-rest"]; ok {
+		var enabled bool
+		if err := json.Unmarshal(raw, &enabled); err != nil {
+			return ccsubagentsSettingsPatch{}, fmt.Errorf("key encrypt-at-rest must be a boolean")
+		}
+		patch.HasEncryptAtRest = true
+		patch.EncryptAtRest = enabled
+	}
+
+	if raw, ok := root["backup"]; ok {
+		if err := readBackupPatch(raw, &patch); err != nil {
+			return ccsubagentsSettingsPatch{}, err
+		}
+	}
+
+	if raw, ok := root["workspace-identity"]; ok {
+		var mode string
+		if err := json.Unmarshal(raw, &mode); err != nil {
+			return ccsubagentsSettingsPatch{}, fmt.Errorf("key workspace-identity must be \"roots\" or \"git-remote\"")
+		}
+		switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+		case workspaces.IdentityModeRoots, workspaces.IdentityModeGitRemote:
+			patch.WorkspaceIdentity = mode
+		default:
+			return ccsubagentsSettingsPatch{}, fmt.Errorf("key workspace-identity must be \"roots\" or \"git-remote\"")
+		}
+	}
+
 	return patch, nil
 }
+
+// WorkspaceIdentityForRoot returns the workspace-identity mode set in the
+// workspace-local settings.json of a workspace root, or "roots" when unset.
+// The global settings file is not consulted, so each repository opts in on
+// its own.
+func WorkspaceIdentityForRoot(root string) (string, error) {
+	localPath := filepath.Join(resolveWorkspaceRoot(root), "settings.json")
+	patch, err := readCCSubagentsSettingsPatch(localPath)
+	if err != nil {
+		return "", fmt.Errorf("read settings file %s: %w", localPath, err)
+	}
+	if patch.WorkspaceIdentity == "" {
+		return workspaces.IdentityModeRoots, nil
+	}
+	return patch.WorkspaceIdentity, nil
+}
+
+func readBackupPatch(raw json.RawMessage, patch *ccsubagentsSettingsPatch) error {
+	var backup map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &backup); err != nil || backup == nil {
+		return fmt.Errorf("key backup must be an object")
+	}
+	if raw, ok := backup["interval"]; ok {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return fmt.Errorf("key backup.interval must be a duration such as \"24h\"")
+		}
+		interval, err := time.ParseDuration(strings.TrimSpace(text))
+		if err != nil || interval < 0 {
+			return fmt.Errorf("key backup.interval must be a duration such as \"24h\"")
+		}
+		if interval > 0 && interval < time.Minute {
+			return fmt.Errorf("key backup.interval must be at least 1m, or 0 to disable")
+		}
+		patch.HasBackupInterval = true
+		patch.BackupInterval = interval
+	}
+	if raw, ok := backup["retention"]; ok {
+		var count int
+		if err := json.Unmarshal(raw, &count); err != nil || count < 1 {
+			return fmt.Errorf("key backup.retention must be a positive integer")
+		}
+		patch.HasBackupRetention = true
+		patch.BackupRetention = count
+	}
+	if raw, ok := backup["dir"]; ok {
+		var dir string
+		if err := json.Unmarshal(raw, &dir); err != nil || !filepath.IsAbs(strings.TrimSpace(dir)) {
+			return fmt.Errorf("key backup.dir must be an absolute path")
+		}
+		patch.HasBackupDir = true
+		patch.BackupDir = filepath.Clean(strings.TrimSpace(dir))
+	}
+	return nil
+}
+
+func readCompressionPatch(raw json.RawMessage) (map[string]string, error) {
+	var entries map[string]string
+	if err := json.Unmarshal(raw, &entries); err != nil || entries == nil {
+		return nil, fmt.Errorf("key compression must be an object mapping MIME types to \"gzip\" or \"none\"")
+	}
+	out := make(map[string]string, len(entries))
+	for pattern, encoding := range entries {
+		key := strings.ToLower(strings.TrimSpace(pattern))
+		major, minor, ok := strings.Cut(key, "/")
+		if key != "*" && (!ok || major == "" || major == "*" || minor == "") {
+			return nil, fmt.Errorf("key compression.%s must be a MIME type, \"type/*\" or \"*\"", pattern)
+		}
+		switch value := strings.ToLower(strings.TrimSpace(encoding)); value {
+		case "gzip", "none":
+			out[key] = value
+		case "zstd":
+			return nil, fmt.Errorf("key compression.%s: zstd is not supported, use \"gzip\" or \"none\"", pattern)
+		default:
+			return nil, fmt.Errorf("key compression.%s must be \"gzip\" or \"none\"", pattern)
+		}
+	}
+	return out, nil
+}
+
+func readTTLPatch(raw json.RawMessage) (map[string]time.Duration, error) {
+	var entries map[string]string
+	if err := json.Unmarshal(raw, &entries); err != nil || entries == nil {
+		return nil, fmt.Errorf("key ttl must be an object mapping name patterns to durations such as \"24h\"")
+	}
+	out := make(map[string]time.Duration, len(entries))
+	for pattern, text := range entries {
+		key := strings.TrimSpace(pattern)
+		if key == "" || strings.Contains(strings.TrimSuffix(key, "*"), "*") {
+			return nil, fmt.Errorf("key ttl.%s must be a name, a prefix ending in \"*\", or \"*\"", pattern)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(text))
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("key ttl.%s must be a duration such as \"24h\"", pattern)
+		}
+		if ttl > 0 && ttl < time.Minute {
+			return nil, fmt.Errorf("key ttl.%s must be at least 1m, or 0 to disable", pattern)
+		}
+		out[key] = ttl
+	}
+	return out, nil
+}
+
+// readJSONSchemasPatch reads the "json-schemas" object. Each schema is inline,
+// a path to a schema file relative to the settings file, or null.
+func readJSONSchemasPatch(raw json.RawMessage, dir string) (map[string]json.RawMessage, error) {
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil || entries == nil {
+		return nil, fmt.Errorf("key json-schemas must be an object mapping name patterns to schemas")
+	}
+	out := make(map[string]json.RawMessage, len(entries))
+	for pattern, value := range entries {
+		key := strings.TrimSpace(pattern)
+		if key == "" || strings.Contains(strings.TrimSuffix(key, "*"), "*") {
+			return nil, fmt.Errorf("key json-schemas.%s must be a name, a prefix ending in \"*\", or \"*\"", pattern)
+		}
+		trimmed := bytes.TrimSpace(value)
+		switch {
+		case bytes.Equal(trimmed, []byte("null")):
+			out[key] = nil
+		case bytes.HasPrefix(trimmed, []byte("{")):
+			out[key] = trimmed
+		case bytes.HasPrefix(trimmed, []byte(`"`)):
+			var schemaPath string
+			if err := json.Unmarshal(trimmed, &schemaPath); err != nil || strings.TrimSpace(schemaPath) == "" {
+				return nil, fmt.Errorf("key json-schemas.%s must be a schema object, a file path or null", pattern)
+			}
+			if !filepath.IsAbs(schemaPath) {
+				schemaPath = filepath.Join(dir, schemaPath)
+			}
+			b, err := os.ReadFile(schemaPath)
+			if err != nil {
+				return nil, fmt.Errorf("key json-schemas.%s: %w", pattern, err)
+			}
+			if !json.Valid(b) {
+				return nil, fmt.Errorf("key json-schemas.%s: %s is not valid JSON", pattern, schemaPath)
+			}
+			out[key] = bytes.TrimSpace(b)
+		default:
+			return nil, fmt.Errorf("key json-schemas.%s must be a schema object, a file path or null", pattern)
+		}
+	}
+	return out, nil
+}
+
+func readQuotaPatch(raw json.RawMessage, patch *ccsubagentsSettingsPatch) error {
+	var quota map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &quota); err != nil || quota == nil {
+		return fmt.Errorf("key quota must be an object")
+	}
+	if raw, ok := quota["max-total-bytes"]; ok {
+		size, err := parseByteSize(raw)
+		if err != nil {
+			return fmt.Errorf("key quota.max-total-bytes %v", err)
+		}
+		patch.HasMaxTotalBytes = true
+		patch.MaxTotalBytes = size
+	}
+	if raw, ok := quota["max-artifact-bytes"]; ok {
+		size, err := parseByteSize(raw)
+		if err != nil {
+			return fmt.Errorf("key quota.max-artifact-bytes %v", err)
+		}
+		patch.HasMaxArtifactBytes = true
+		patch.MaxArtifactBytes = size
+	}
+	if raw, ok := quota["max-versions-per-name"]; ok {
+		var count int
+		if err := json.Unmarshal(raw, &count); err != nil || count < 0 {
+			return fmt.Errorf("key quota.max-versions-per-name must be a non-negative integer")
+		}
+		patch.HasMaxVersionsPerName = true
+		patch.MaxVersionsPerName = count
+	}
+	return nil
+}
+
+var byteSizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseByteSize accepts a non-negative integer number of bytes or a string
+// such as "512MiB" or "2GB".
+func parseByteSize(raw json.RawMessage) (int64, error) {
+	const usage = "must be a non-negative byte count or a size such as \"512MiB\""
+	var n int64
+	if err := json.Unmarshal(raw, &n); err == nil {
+		if n < 0 {
+			return 0, errors.New(usage)
+		}
+		return n, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return 0, errors.New(usage)
+	}
+	text = strings.TrimSpace(text)
+	factor := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(text, unit.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, unit.suffix))
+			factor = unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/factor {
+		return 0, errors.New(usage)
+	}
+	return n * factor, nil
+}
@@ -5,6 +5,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
 )
 
 func writeSettingsFile(t *testing.T, path, content string) {
@@ -22,7 +25,7 @@ func TestResolveMergedCCSubagentsSettings_LocalOverridesGlobal(t *testing.T) {
 	cwd := t.TempDir()
 
 	globalPath, localPath := resolveCCSubagentsSettingsPaths(home, cwd)
-	writeSettingsFile(t, globalPath, `{"autostart-webui": true, "no-auth": false, "webui-port": 19131}`)
+	writeSettingsFile(t, globalPath, `{"autostart-webui": true, "no-auth": false, "webui-port": 19131, "encrypt-at-rest": true}`)
 	writeSettingsFile(t, localPath, `{"no-auth": true, "webui-port": 19130}`)
 
 	settings, err := resolveMergedCCSubagentsSettings(home, cwd)
@@ -38,6 +41,143 @@ func TestResolveMergedCCSubagentsSettings_LocalOverridesGlobal(t *testing.T) {
 	if settings.WebUIPort != 19130 {
 		t.Fatalf("webui-port mismatch: got=%d want=%d", settings.WebUIPort, 19130)
 	}
+	if !settings.EncryptAtRest {
+		t.Fatalf("expected encrypt-at-rest to remain true from global settings")
+	}
+}
+
+func TestResolveMergedCCSubagentsSettings_QuotaMergesPerKey(t *testing.T) {
+	home := t.TempDir()
+	cwd := t.TempDir()
+
+	globalPath, localPath := resolveCCSubagentsSettingsPaths(home, cwd)
+	writeSettingsFile(t, globalPath, `{"quota": {"max-total-bytes": "2GiB", "max-artifact-bytes": 1048576}}`)
+	writeSettingsFile(t, localPath, `{"quota": {"max-artifact-bytes": "10MB", "max-versions-per-name": 50}}`)
+
+	settings, err := resolveMergedCCSubagentsSettings(home, cwd)
+	if err != nil {
+		t.Fatalf("resolveMergedCCSubagentsSettings returned error: %v", err)
+	}
+	want := QuotaSettings{MaxTotalBytes: 2 << 30, MaxArtifactBytes: 10_000_000, MaxVersionsPerName: 50}
+	if settings.Quota != want {
+		t.Fatalf("quota mismatch: got=%+v want=%+v", settings.Quota, want)
+	}
+}
+
+func TestResolveMergedCCSubagentsSettings_CompressionMergesPerMimeType(t *testing.T) {
+	home := t.TempDir()
+	cwd := t.TempDir()
+
+	globalPath, localPath := resolveCCSubagentsSettingsPaths(home, cwd)
+	writeSettingsFile(t, globalPath, `{"compression": {"text/*": "gzip", "application/json": "gzip"}}`)
+	writeSettingsFile(t, localPath, `{"compression": {"application/json": "none", "*": "GZIP"}}`)
+
+	settings, err := resolveMergedCCSubagentsSettings(home, cwd)
+	if err != nil {
+		t.Fatalf("resolveMergedCCSubagentsSettings returned error: %v", err)
+	}
+	want := map[string]string{"text/*": "gzip", "application/json": "none", "*": "gzip"}
+	if len(settings.Compression) != len(want) {
+		t.Fatalf("compression mismatch: got=%v want=%v", settings.Compression, want)
+	}
+	for pattern, encoding := range want {
+		if settings.Compression[pattern] != encoding {
+			t.Fatalf("compression mismatch: got=%v want=%v", settings.Compression, want)
+		}
+	}
+}
+
+func TestResolveMergedCCSubagentsSettings_TTLMergesPerPattern(t *testing.T) {
+	home := t.TempDir()
+	cwd := t.TempDir()
+
+	globalPath, localPath := resolveCCSubagentsSettingsPaths(home, cwd)
+	writeSettingsFile(t, globalPath, `{"ttl": {"scratch/*": "24h", "tmp/*": "1h"}}`)
+	writeSettingsFile(t, localPath, `{"ttl": {"tmp/*": "0", "review/notes": "72h"}}`)
+
+	settings, err := resolveMergedCCSubagentsSettings(home, cwd)
+	if err != nil {
+		t.Fatalf("resolveMergedCCSubagentsSettings returned error: %v", err)
+	}
+	want := map[string]time.Duration{"scratch/*": 24 * time.Hour, "tmp/*": 0, "review/notes": 72 * time.Hour}
+	if len(settings.TTL) != len(want) {
+		t.Fatalf("ttl mismatch: got=%v want=%v", settings.TTL, want)
+	}
+	for pattern, ttl := range want {
+		if got, ok := settings.TTL[pattern]; !ok || got != ttl {
+			t.Fatalf("ttl mismatch: got=%v want=%v", settings.TTL, want)
+		}
+	}
+
+	writeSettingsFile(t, localPath, `{"ttl": {"scratch/*/notes": "1h"}}`)
+	if _, err := resolveMergedCCSubagentsSettings(home, cwd); err == nil {
+		t.Fatalf("expected an inner wildcard to be rejected")
+	}
+}
+
+func TestResolveMergedCCSubagentsSettings_JSONSchemasInlineAndFromFile(t *testing.T) {
+	home := t.TempDir()
+	cwd := t.TempDir()
+
+	globalPath, localPath := resolveCCSubagentsSettingsPaths(home, cwd)
+	writeSettingsFile(t, globalPath, `{"json-schemas": {"review/*": {"type": "object"}, "todo/*": {"type": "array"}}}`)
+	writeSettingsFile(t, filepath.Join(filepath.Dir(localPath), "schemas", "matrix.json"), `{"type": "object", "required": ["rows"]}`)
+	writeSettingsFile(t, localPath, `{"json-schemas": {"todo/*": null, "matrix/*": "schemas/matrix.json"}}`)
+
+	settings, err := resolveMergedCCSubagentsSettings(home, cwd)
+	if err != nil {
+		t.Fatalf("resolveMergedCCSubagentsSettings returned error: %v", err)
+	}
+	want := map[string]string{"review/*": `{"type": "object"}`, "todo/*": "", "matrix/*": `{"type": "object", "required": ["rows"]}`}
+	if len(settings.JSONSchemas) != len(want) {
+		t.Fatalf("json-schemas mismatch: got=%v", settings.JSONSchemas)
+	}
+	for pattern, schema := range want {
+		if got, ok := settings.JSONSchemas[pattern]; !ok || string(got) != schema {
+			t.Fatalf("json-schemas[%s]=%q, want %q", pattern, got, schema)
+		}
+	}
+
+	writeSettingsFile(t, localPath, `{"json-schemas": {"matrix/*": "schemas/missing.json"}}`)
+	if _, err := resolveMergedCCSubagentsSettings(home, cwd); err == nil {
+		t.Fatalf("expected a missing schema file to be rejected")
+	}
+}
+
+func TestResolveMergedCCSubagentsSettings_BackupMergesPerKey(t *testing.T) {
+	home := t.TempDir()
+	cwd := t.TempDir()
+	dir := filepath.Join(t.TempDir(), "snapshots")
+
+	globalPath, localPath := resolveCCSubagentsSettingsPaths(home, cwd)
+	writeSettingsFile(t, globalPath, `{"backup": {"interval": "24h", "retention": 14}}`)
+	writeSettingsFile(t, localPath, `{"backup": {"interval": "6h", "dir": "`+filepath.ToSlash(dir)+`"}}`)
+
+	settings, err := resolveMergedCCSubagentsSettings(home, cwd)
+	if err != nil {
+		t.Fatalf("resolveMergedCCSubagentsSettings returned error: %v", err)
+	}
+	want := BackupSettings{Interval: 6 * time.Hour, Retention: 14, Dir: dir}
+	if settings.Backup != want {
+		t.Fatalf("backup mismatch: got=%+v want=%+v", settings.Backup, want)
+	}
+}
+
+func TestWorkspaceIdentityForRoot_ReadsOnlyLocalSettings(t *testing.T) {
+	t.Setenv(ccsubagentsConfigDirEnv, t.TempDir())
+	globalPath, _ := resolveCCSubagentsSettingsPaths(t.TempDir(), t.TempDir())
+	writeSettingsFile(t, globalPath, `{"workspace-identity": "git-remote"}`)
+
+	root := t.TempDir()
+	mode, err := WorkspaceIdentityForRoot(root)
+	if err != nil || mode != workspaces.IdentityModeRoots {
+		t.Fatalf("expected roots without a local setting, got %q (%v)", mode, err)
+	}
+	writeSettingsFile(t, filepath.Join(root, "ccsubagents", "settings.json"), `{"workspace-identity": "Git-Remote"}`)
+	mode, err = WorkspaceIdentityForRoot(root)
+	if err != nil || mode != workspaces.IdentityModeGitRemote {
+		t.Fatalf("expected git-remote from the local setting, got %q (%v)", mode, err)
+	}
 }
 
 func TestResolveMergedCCSubagentsSettings_MissingFilesDefaults(t *testing.T) {
@@ -57,6 +197,27 @@ func TestResolveMergedCCSubagentsSettings_MissingFilesDefaults(t *testing.T) {
 	if settings.WebUIPort != 0 {
 		t.Fatalf("expected default webui-port to be unset (0), got %d", settings.WebUIPort)
 	}
+	if settings.ReadMaxBytes != DefaultReadMaxBytes {
+		t.Fatalf("expected default read-max-bytes %d, got %d", DefaultReadMaxBytes, settings.ReadMaxBytes)
+	}
+}
+
+func TestResolveMergedCCSubagentsSettings_ReadMaxBytesLocalOverridesGlobal(t *testing.T) {
+	home := t.TempDir()
+	cwd := t.TempDir()
+
+	globalPath, localPath := resolveCCSubagentsSettingsPaths(home, cwd)
+	writeSettingsFile(t, globalPath, `{"read-max-bytes": "1MiB"}`)
+	settings, err := resolveMergedCCSubagentsSettings(home, cwd)
+	if err != nil || settings.ReadMaxBytes != 1<<20 {
+		t.Fatalf("expected read-max-bytes 1MiB, got %d (%v)", settings.ReadMaxBytes, err)
+	}
+
+	writeSettingsFile(t, localPath, `{"read-max-bytes": 0}`)
+	settings, err = resolveMergedCCSubagentsSettings(home, cwd)
+	if err != nil || settings.ReadMaxBytes != 0 {
+		t.Fatalf("expected local read-max-bytes 0 to disable the cap, got %d (%v)", settings.ReadMaxBytes, err)
+	}
 }
 
 func TestResolveCCSubagentsSettingsPaths_ConfigDirOverride(t *testing.T) {
@@ -82,8 +243,23 @@ func TestResolveMergedCCSubagentsSettings_ValidationErrors(t *testing.T) {
 	}{
 		{name: "autostart type", content: `{"autostart-webui": "yes"}`, key: "autostart-webui"},
 		{name: "no-auth type", content: `{"no-auth": "true"}`, key: "no-auth"},
+		{name: "encrypt-at-rest type", content: `{"encrypt-at-rest": 1}`, key: "encrypt-at-rest"},
 		{name: "webui-port type", content: `{"webui-port": "19130"}`, key: "webui-port"},
 		{name: "webui-port range", content: `{"webui-port": 70000}`, key: "webui-port"},
+		{name: "quota type", content: `{"quota": 5}`, key: "quota"},
+		{name: "quota size", content: `{"quota": {"max-total-bytes": "lots"}}`, key: "quota.max-total-bytes"},
+		{name: "quota versions", content: `{"quota": {"max-versions-per-name": -1}}`, key: "quota.max-versions-per-name"},
+		{name: "compression type", content: `{"compression": "gzip"}`, key: "compression"},
+		{name: "compression pattern", content: `{"compression": {"*/json": "gzip"}}`, key: "compression.*/json"},
+		{name: "compression zstd", content: `{"compression": {"text/*": "zstd"}}`, key: "compression.text/*"},
+		{name: "read-max-bytes", content: `{"read-max-bytes": "a lot"}`, key: "read-max-bytes"},
+		{name: "backup type", content: `{"backup": true}`, key: "backup"},
+		{name: "backup interval", content: `{"backup": {"interval": "daily"}}`, key: "backup.interval"},
+		{name: "backup interval floor", content: `{"backup": {"interval": "10s"}}`, key: "backup.interval"},
+		{name: "backup retention", content: `{"backup": {"retention": 0}}`, key: "backup.retention"},
+		{name: "workspace-identity", content: `{"workspace-identity": "remote"}`, key: "workspace-identity"},
+		{name: "workspace-identity global", content: `{"workspace-identity": "git-remote"}`, key: "workspace-identity"},
+		{name: "backup dir", content: `{"backup": {"dir": "snapshots"}}`, key: "backup.dir"},
 	}
 
 	for _, tc := range tests {
@@ -18,9 +18,11 @@ const (
 	daemonSocketEnv = "LOCAL_ARTIFACT_DAEMON_SOCKET"
 	daemonAddrEnv   = "LOCAL_ARTIFACT_DAEMON_ADDR"
 	daemonTokenEnv  = "LOCAL_ARTIFACT_DAEMON_TOKEN"
+	mcpHTTPAddrEnv  = "LOCAL_ARTIFACT_MCP_HTTP_ADDR"
 
 	defaultWebAddr          = "127.0.0.1:19130"
 	defaultDaemonAddr       = "127.0.0.1:19131"
+	defaultMCPHTTPAddr      = "127.0.0.1:19132"
 	defaultDaemonSocketName = "ccsubagentsd.sock"
 )
 
@@ -101,6 +103,13 @@ func ResolveDaemonAddr() string {
 	return defaultDaemonAddr
 }
 
+func ResolveMCPHTTPAddr() string {
+	if addr := strings.TrimSpace(os.Getenv(mcpHTTPAddrEnv)); addr != "" {
+		return addr
+	}
+	return defaultMCPHTTPAddr
+}
+
 func ResolveDaemonToken(stateDir string) string {
 	if token := strings.TrimSpace(os.Getenv(daemonTokenEnv)); token != "" {
 		return token
@@ -0,0 +1,102 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+)
+
+// MaxBatchOps bounds the number of writes in one batch.
+const MaxBatchOps = 100
+
+// BatchOp is one write of Repository.Batch: a save of Version and Data or,
+// when Delete is set, a tombstone for Selector. A non-empty ExpectedPrevRef
+// must match the latest ref of the name when the op is applied, including
+// writes made earlier in the same batch.
+type BatchOp struct {
+	Delete          bool
+	Version         ArtifactVersion
+	Data            []byte
+	Selector        Selector
+	ExpectedPrevRef string
+	// Quota is the limit a save is held to inside the batch transaction, as
+	// SaveOptions.Quota.
+	Quota Quota
+}
+
+// BatchWrite is one write of Service.Batch. Exactly one field is set.
+type BatchWrite struct {
+	Text   *SaveTextInput
+	Blob   *SaveBlobInput
+	Delete *DeleteInput
+}
+
+type DeleteInput struct {
+	Selector        Selector
+	ExpectedPrevRef string
+}
+
+// Batch validates every write before applying them together, so either all
+// of them land or none do. Quotas are checked per write against the store
+// plus the writes before it in the batch, and again inside the transaction
+// that applies them.
+func (s *Service) Batch(ctx context.Context, writes []BatchWrite) ([]ArtifactVersion, error) {
+	if len(writes) == 0 {
+		return nil, fmt.Errorf("%w: batch has no writes", ErrInvalidInput)
+	}
+	if len(writes) > MaxBatchOps {
+		return nil, fmt.Errorf("%w: batch has %d writes, limit is %d", ErrInvalidInput, len(writes), MaxBatchOps)
+	}
+	ops := make([]BatchOp, 0, len(writes))
+	pending := newBatchQuota()
+	for i, w := range writes {
+		op, err := s.batchOp(ctx, w, pending)
+		if err != nil {
+			return nil, fmt.Errorf("batch write %d: %w", i, err)
+		}
+		ops = append(ops, op)
+	}
+	out, err := s.repo.Batch(ctx, ops)
+	if err == nil {
+		s.Announce(out...)
+	}
+	return out, err
+}
+
+func (s *Service) batchOp(ctx context.Context, w BatchWrite, pending *batchQuota) (BatchOp, error) {
+	set := 0
+	for _, ok := range []bool{w.Text != nil, w.Blob != nil, w.Delete != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return BatchOp{}, fmt.Errorf("%w: each write needs exactly one of text, blob or delete", ErrInvalidInput)
+	}
+	switch {
+	case w.Text != nil:
+		a, data, opts, err := s.textVersion(ctx, *w.Text, pending)
+		if err != nil {
+			return BatchOp{}, err
+		}
+		return BatchOp{Version: a, Data: data, ExpectedPrevRef: opts.ExpectedPrevRef, Quota: opts.Quota}, nil
+	case w.Blob != nil:
+		a, data, opts, err := s.blobVersion(ctx, *w.Blob, pending)
+		if err != nil {
+			return BatchOp{}, err
+		}
+		return BatchOp{Version: a, Data: data, ExpectedPrevRef: opts.ExpectedPrevRef, Quota: opts.Quota}, nil
+	default:
+		sel, err := normalizeSelector(w.Delete.Selector)
+		if err != nil {
+			return BatchOp{}, err
+		}
+		op := BatchOp{Delete: true, Selector: sel}
+		if w.Delete.ExpectedPrevRef != "" {
+			if op.ExpectedPrevRef, err = normalizeAndValidateRef(w.Delete.ExpectedPrevRef); err != nil {
+				return BatchOp{}, err
+			}
+		}
+		pending.addDelete(sel.Name)
+		return op, nil
+	}
+}
@@ -0,0 +1,106 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestServiceBatch_AppliesWritesInOrder(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	old, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/old", Text: "stale"})
+	if err != nil {
+		t.Fatalf("seed save failed: %v", err)
+	}
+	out, err := svc.Batch(ctx, []BatchWrite{
+		{Text: &SaveTextInput{Name: "plan/current", Text: "plan"}},
+		{Blob: &SaveBlobInput{Name: "plan/todo", Data: []byte(`[]`), MimeType: "application/json"}},
+		{Delete: &DeleteInput{Selector: Selector{Name: "plan/old"}, ExpectedPrevRef: old.Ref}},
+	})
+	if err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+	if len(out) != 3 || out[0].Name != "plan/current" || out[1].Kind != ArtifactKindFile || out[2].Name != "plan/old" {
+		t.Fatalf("unexpected batch result: %+v", out)
+	}
+	if _, err := svc.Resolve(ctx, "plan/old"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected deleted name, got %v", err)
+	}
+	if _, err := svc.Resolve(ctx, "plan/todo"); err != nil {
+		t.Fatalf("expected saved name, got %v", err)
+	}
+}
+
+func TestServiceBatch_FailedWriteAppliesNothing(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	current, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/current", Text: "v1"})
+	if err != nil {
+		t.Fatalf("seed save failed: %v", err)
+	}
+
+	_, err = svc.Batch(ctx, []BatchWrite{
+		{Text: &SaveTextInput{Name: "plan/summary", Text: "summary"}},
+		{Text: &SaveTextInput{Name: "plan/current", Text: "v2", ExpectedPrevRef: "20260101T000000Z-aaaaaaaaaaaaaaaa"}},
+	})
+	if !errors.Is(err, ErrConflict) || !strings.Contains(err.Error(), "batch write 1") {
+		t.Fatalf("expected conflict on write 1, got %v", err)
+	}
+	if _, err := svc.Resolve(ctx, "plan/summary"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected earlier write to be rolled back, got %v", err)
+	}
+	if ref, err := svc.Resolve(ctx, "plan/current"); err != nil || ref != current.Ref {
+		t.Fatalf("expected current ref %q to remain, got %q err=%v", current.Ref, ref, err)
+	}
+
+	_, err = svc.Batch(ctx, []BatchWrite{
+		{Text: &SaveTextInput{Name: "plan/summary", Text: "summary"}},
+		{Text: &SaveTextInput{Name: "plan/bad", Text: " "}},
+	})
+	if !errors.Is(err, ErrInvalidInput) || !strings.Contains(err.Error(), "batch write 1") {
+		t.Fatalf("expected invalid input on write 1, got %v", err)
+	}
+	if _, err := svc.Batch(ctx, []BatchWrite{{}}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected empty write to be rejected, got %v", err)
+	}
+}
+
+func TestServiceBatch_QuotaCountsEarlierWrites(t *testing.T) {
+	ctx := context.Background()
+
+	svc := newQuotaService(Quota{MaxTotalBytes: 10})
+	_, err := svc.Batch(ctx, []BatchWrite{
+		{Text: &SaveTextInput{Name: "plan/a", Text: "123456"}},
+		{Text: &SaveTextInput{Name: "plan/b", Text: "123456"}},
+		{Text: &SaveTextInput{Name: "plan/c", Text: "abcdef"}},
+	})
+	if !errors.Is(err, ErrQuotaExceeded) || !strings.Contains(err.Error(), "batch write 2") {
+		t.Fatalf("expected the third write to exceed the byte quota, got %v", err)
+	}
+
+	svc = newQuotaService(Quota{MaxVersionsPerName: 2})
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/a", Text: "one"}); err != nil {
+		t.Fatalf("seed save: %v", err)
+	}
+	_, err = svc.Batch(ctx, []BatchWrite{
+		{Text: &SaveTextInput{Name: "plan/a", Text: "two"}},
+		{Text: &SaveTextInput{Name: "plan/a", Text: "three"}},
+	})
+	if !errors.Is(err, ErrQuotaExceeded) || !strings.Contains(err.Error(), "batch write 1") {
+		t.Fatalf("expected the second write to exceed the version quota, got %v", err)
+	}
+	if _, err := svc.Batch(ctx, []BatchWrite{
+		{Text: &SaveTextInput{Name: "plan/a", Text: "two"}},
+		{Delete: &DeleteInput{Selector: Selector{Name: "plan/a"}}},
+		{Text: &SaveTextInput{Name: "plan/a", Text: "fresh"}},
+		{Text: &SaveTextInput{Name: "plan/a", Text: "fresher"}},
+	}); err != nil {
+		t.Fatalf("a delete in the batch must restart the version count: %v", err)
+	}
+}
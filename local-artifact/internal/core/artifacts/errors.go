@@ -15,4 +15,6 @@ var (
 	ErrInvalidName                 = errors.New("invalid name")
 	ErrInvalidRef                  = errors.New("invalid ref")
 	ErrAliasExists                 = errors.New("alias already exists")
+	ErrQuotaExceeded               = errors.New("quota exceeded")
+	ErrNotShared                   = errors.New("not shared with this workspace")
 )
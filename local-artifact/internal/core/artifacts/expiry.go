@@ -0,0 +1,81 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExpiryPolicy gives names a default TTL when a save sets no expiry. Keys are
+// an exact name, a prefix pattern such as "scratch/*", or "*". An exact name
+// wins over the longest matching prefix, which wins over "*".
+type ExpiryPolicy map[string]time.Duration
+
+// ExpiryRepository is implemented by repositories that can tombstone expired
+// names.
+type ExpiryRepository interface {
+	ExpireNames(ctx context.Context, now time.Time) ([]ArtifactVersion, error)
+}
+
+func (p ExpiryPolicy) TTLFor(name string) time.Duration {
+	_, ttl, _ := MatchNamePattern(p, name)
+	return ttl
+}
+
+// MatchNamePattern picks the entry of patterns that applies to name: the
+// exact name, else the longest "prefix*", else "*". It returns the matching
+// pattern with its value.
+func MatchNamePattern[T any](patterns map[string]T, name string) (string, T, bool) {
+	if v, ok := patterns[name]; ok {
+		return name, v, true
+	}
+	var (
+		best    T
+		bestKey string
+		bestLen = -1
+	)
+	for pattern, v := range patterns {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if !ok || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if len(prefix) > bestLen {
+			best, bestKey, bestLen = v, pattern, len(prefix)
+		}
+	}
+	return bestKey, best, bestLen >= 0
+}
+
+func (s *Service) SetExpiryPolicy(p ExpiryPolicy) {
+	s.expiry = p
+}
+
+// ExpireNames tombstones the names whose latest version expired at or before
+// now.
+func (s *Service) ExpireNames(ctx context.Context, now time.Time) ([]ArtifactVersion, error) {
+	repo, ok := s.repo.(ExpiryRepository)
+	if !ok {
+		return nil, nil
+	}
+	out, err := repo.ExpireNames(ctx, now)
+	s.Announce(out...)
+	return out, err
+}
+
+// expiresAt picks the expiry of a new version: the requested one, or the
+// policy default for name. A zero result means the version does not expire.
+func (s *Service) expiresAt(name string, requested, createdAt time.Time) (*time.Time, error) {
+	if requested.IsZero() {
+		ttl := s.expiry.TTLFor(name)
+		if ttl <= 0 {
+			return nil, nil
+		}
+		requested = createdAt.Add(ttl)
+	}
+	requested = requested.UTC().Truncate(time.Second)
+	if !requested.After(createdAt) {
+		return nil, fmt.Errorf("%w: expiresAt must be in the future", ErrInvalidInput)
+	}
+	return &requested, nil
+}
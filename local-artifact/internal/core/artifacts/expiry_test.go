@@ -0,0 +1,66 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExpiryPolicy_TTLForPrefersExactThenLongestPrefix(t *testing.T) {
+	policy := ExpiryPolicy{
+		"*":              72 * time.Hour,
+		"scratch/*":      24 * time.Hour,
+		"scratch/keep/*": 0,
+		"scratch/notes":  time.Hour,
+	}
+	tests := map[string]time.Duration{
+		"plan/a":            72 * time.Hour,
+		"scratch/dump":      24 * time.Hour,
+		"scratch/keep/spec": 0,
+		"scratch/notes":     time.Hour,
+	}
+	for name, want := range tests {
+		if got := policy.TTLFor(name); got != want {
+			t.Fatalf("TTLFor(%q)=%v, want %v", name, got, want)
+		}
+	}
+	if got := ExpiryPolicy(nil).TTLFor("scratch/dump"); got != 0 {
+		t.Fatalf("empty policy TTL=%v, want 0", got)
+	}
+}
+
+func TestServiceSave_AppliesExpiryPolicyAndRequestedExpiry(t *testing.T) {
+	svc := newQuotaService(Quota{})
+	svc.SetExpiryPolicy(ExpiryPolicy{"scratch/*": time.Hour})
+	ctx := context.Background()
+
+	scratch, err := svc.SaveText(ctx, SaveTextInput{Name: "scratch/dump", Text: "x"})
+	if err != nil {
+		t.Fatalf("save scratch: %v", err)
+	}
+	if scratch.ExpiresAt == nil || !scratch.ExpiresAt.Equal(scratch.CreatedAt.Add(time.Hour)) {
+		t.Fatalf("expected policy expiry one hour after %v, got %v", scratch.CreatedAt, scratch.ExpiresAt)
+	}
+	plan, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/a", Text: "x"})
+	if err != nil {
+		t.Fatalf("save plan: %v", err)
+	}
+	if plan.ExpiresAt != nil {
+		t.Fatalf("expected no expiry outside the policy, got %v", plan.ExpiresAt)
+	}
+
+	requested := time.Now().Add(48 * time.Hour)
+	overridden, err := svc.SaveText(ctx, SaveTextInput{Name: "scratch/dump", Text: "y", ExpiresAt: requested})
+	if err != nil {
+		t.Fatalf("save with expiresAt: %v", err)
+	}
+	if overridden.ExpiresAt == nil || !overridden.ExpiresAt.Equal(requested.UTC().Truncate(time.Second)) {
+		t.Fatalf("expected requested expiry %v, got %v", requested, overridden.ExpiresAt)
+	}
+
+	_, err = svc.SaveText(ctx, SaveTextInput{Name: "plan/b", Text: "x", ExpiresAt: time.Now().Add(-time.Minute)})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for a past expiry, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package artifacts
+
+// Integrity issue kinds reported by a store verification.
+const (
+	IssueMissingBlob    = "missing_blob"
+	IssueCorruptBlob    = "corrupt_blob"
+	IssueDanglingLatest = "dangling_latest"
+	IssueDanglingParent = "dangling_parent"
+	IssueOrphanBlob     = "orphan_blob"
+)
+
+// IntegrityIssue describes one inconsistency between the metadata database
+// and the blob store. Action is set when a repair changed something.
+type IntegrityIssue struct {
+	Kind   string `json:"kind"`
+	Ref    string `json:"ref,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Digest string `json:"digest,omitempty"`
+	Detail string `json:"detail"`
+	Action string `json:"action,omitempty"`
+}
+
+// IntegrityReport summarizes one workspace. Versions quarantined by an
+// earlier repair are counted in Quarantined and not reported again.
+type IntegrityReport struct {
+	Versions    int              `json:"versions"`
+	Blobs       int              `json:"blobs"`
+	Quarantined int              `json:"quarantined"`
+	Issues      []IntegrityIssue `json:"issues"`
+	Repaired    bool             `json:"repaired"`
+}
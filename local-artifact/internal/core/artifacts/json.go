@@ -0,0 +1,136 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+const JSONMimeType = "application/json"
+
+// JSONValidator checks the payload of every save against the JSON Schema
+// registered for the name, if there is one. Names without a schema pass.
+type JSONValidator interface {
+	ValidateJSON(name string, data []byte) error
+}
+
+func (s *Service) SetJSONValidator(v JSONValidator) {
+	s.jsonValidator = v
+}
+
+// IsJSONMimeType reports whether mimeType is application/json or a +json
+// type such as application/ld+json.
+func IsJSONMimeType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	return mediaType == JSONMimeType || strings.HasSuffix(mediaType, "+json")
+}
+
+type SaveJSONInput struct {
+	Name            string
+	JSON            []byte
+	ExpectedPrevRef string
+	ExpiresAt       time.Time
+}
+
+// SaveJSON stores a JSON document as application/json after checking that it
+// parses and matches any schema registered for the name.
+func (s *Service) SaveJSON(ctx context.Context, in SaveJSONInput) (ArtifactVersion, error) {
+	name, err := normalizeAndValidateName(in.Name)
+	if err != nil {
+		return ArtifactVersion{}, err
+	}
+	data := bytes.TrimSpace(in.JSON)
+	if _, err := decodeJSON(data); err != nil {
+		return ArtifactVersion{}, err
+	}
+	a, data, opts, err := s.newVersion(ctx, name, ArtifactKindText, JSONMimeType, "", data, SaveOptions{ExpectedPrevRef: in.ExpectedPrevRef, ExpiresAt: in.ExpiresAt}, nil)
+	if err != nil {
+		return ArtifactVersion{}, err
+	}
+	return s.announced(s.repo.Save(ctx, a, data, opts))
+}
+
+type PatchJSONInput struct {
+	Name string
+	// Patch is an RFC 6902 JSON Patch, or an RFC 7386 merge patch when Merge
+	// is set.
+	Patch           []byte
+	Merge           bool
+	ExpectedPrevRef string
+	ExpiresAt       time.Time
+}
+
+// patchJSONAttempts bounds how often PatchJSON re-reads and re-applies a
+// patch that lost a race with another write.
+const patchJSONAttempts = 3
+
+// PatchJSON applies a patch to the latest version of a JSON artifact and
+// saves the result as a new version. The save is conditional on the version
+// that was patched, so concurrent writes are never lost: with ExpectedPrevRef
+// a race fails with ErrConflict, without it the patch is retried against the
+// newer version.
+func (s *Service) PatchJSON(ctx context.Context, in PatchJSONInput) (ArtifactVersion, error) {
+	name, err := normalizeAndValidateName(in.Name)
+	if err != nil {
+		return ArtifactVersion{}, err
+	}
+	expected := strings.TrimSpace(in.ExpectedPrevRef)
+	if expected != "" {
+		if expected, err = normalizeAndValidateRef(expected); err != nil {
+			return ArtifactVersion{}, err
+		}
+	}
+	if len(bytes.TrimSpace(in.Patch)) == 0 {
+		return ArtifactVersion{}, fmt.Errorf("%w: patch is required", ErrInvalidInput)
+	}
+
+	for attempt := 1; ; attempt++ {
+		out, err := s.patchJSONOnce(ctx, name, expected, in)
+		if err == nil || expected != "" || attempt == patchJSONAttempts || !errors.Is(err, ErrConflict) || errors.Is(err, errJSONTestFailed) {
+			return out, err
+		}
+	}
+}
+
+// errJSONTestFailed marks a failed "test" op, a conflict that re-reading the
+// artifact cannot resolve.
+var errJSONTestFailed = errors.New("test failed")
+
+func (s *Service) patchJSONOnce(ctx context.Context, name, expected string, in PatchJSONInput) (ArtifactVersion, error) {
+	cur, data, err := s.repo.Get(ctx, Selector{Name: name})
+	if err != nil {
+		return ArtifactVersion{}, err
+	}
+	if cur.Tombstone {
+		return ArtifactVersion{}, ErrNotFound
+	}
+	if !IsJSONMimeType(cur.MimeType) {
+		return ArtifactVersion{}, fmt.Errorf("%w: %s is %s, not JSON", ErrInvalidInput, name, cur.MimeType)
+	}
+	if expected != "" && expected != cur.Ref {
+		return ArtifactVersion{}, fmt.Errorf("%w: expectedPrevRef=%q current=%q", ErrConflict, expected, cur.Ref)
+	}
+
+	var patched []byte
+	if in.Merge {
+		patched, err = ApplyJSONMergePatch(data, in.Patch)
+	} else {
+		patched, err = ApplyJSONPatch(data, in.Patch)
+	}
+	if err != nil {
+		return ArtifactVersion{}, err
+	}
+
+	a, patched, opts, err := s.newVersion(ctx, name, cur.Kind, cur.MimeType, cur.Filename, patched, SaveOptions{ExpectedPrevRef: cur.Ref, ExpiresAt: in.ExpiresAt}, nil)
+	if err != nil {
+		return ArtifactVersion{}, err
+	}
+	return s.announced(s.repo.Save(ctx, a, patched, opts))
+}
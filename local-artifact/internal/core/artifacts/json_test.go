@@ -0,0 +1,96 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type prefixValidator string
+
+func (p prefixValidator) ValidateJSON(name string, data []byte) error {
+	if strings.HasPrefix(name, string(p)) && !strings.Contains(string(data), `"verdict"`) {
+		return fmt.Errorf("%w: %s must have a verdict", ErrInvalidInput, name)
+	}
+	return nil
+}
+
+// racingRepo saves a competing version right before the first conditional
+// save it sees, as a concurrent writer would.
+type racingRepo struct {
+	*memoryRepo
+	raced bool
+}
+
+func (r *racingRepo) Save(ctx context.Context, a ArtifactVersion, data []byte, opts SaveOptions) (ArtifactVersion, error) {
+	if !r.raced && opts.ExpectedPrevRef != "" {
+		r.raced = true
+		other := a
+		other.Ref = "20260216T101059Z-ffffffffffffffff"
+		if _, err := r.memoryRepo.Save(ctx, other, []byte(`{"verdict":"rejected","by":"other"}`), SaveOptions{}); err != nil {
+			return ArtifactVersion{}, err
+		}
+	}
+	return r.memoryRepo.Save(ctx, a, data, opts)
+}
+
+func TestServiceSaveJSON_ValidatesSyntaxAndSchema(t *testing.T) {
+	svc := NewService(newMemoryRepo())
+	svc.SetJSONValidator(prefixValidator("review/"))
+	ctx := context.Background()
+
+	if _, err := svc.SaveJSON(ctx, SaveJSONInput{Name: "review/r1", JSON: []byte(`{"verdict":`)}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected invalid JSON to be rejected, got %v", err)
+	}
+	if _, err := svc.SaveJSON(ctx, SaveJSONInput{Name: "review/r1", JSON: []byte(`{"ok":true}`)}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected schema violation, got %v", err)
+	}
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "review/r1", Text: "approved"}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected text saves to be validated too, got %v", err)
+	}
+	a, err := svc.SaveJSON(ctx, SaveJSONInput{Name: "review/r1", JSON: []byte(` {"verdict":"approved"} `)})
+	if err != nil {
+		t.Fatalf("SaveJSON: %v", err)
+	}
+	if a.MimeType != JSONMimeType || a.Kind != ArtifactKindText {
+		t.Fatalf("unexpected JSON artifact: %+v", a)
+	}
+}
+
+func TestServicePatchJSON_UsesCAS(t *testing.T) {
+	repo := &racingRepo{memoryRepo: newMemoryRepo()}
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	first, err := svc.SaveJSON(ctx, SaveJSONInput{Name: "review/r1", JSON: []byte(`{"verdict":"pending"}`)})
+	if err != nil {
+		t.Fatalf("SaveJSON: %v", err)
+	}
+
+	_, err = svc.PatchJSON(ctx, PatchJSONInput{Name: "review/r1", Patch: []byte(`{"verdict":"approved"}`), Merge: true, ExpectedPrevRef: first.Ref})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected a raced patch with expectedPrevRef to conflict, got %v", err)
+	}
+
+	repo.raced = false
+	patched, err := svc.PatchJSON(ctx, PatchJSONInput{Name: "review/r1", Patch: []byte(`[{"op":"add","path":"/notes","value":["lgtm"]}]`)})
+	if err != nil {
+		t.Fatalf("expected patch to be retried after the race, got %v", err)
+	}
+	_, data, err := svc.Get(ctx, Selector{Ref: patched.Ref})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got, want := string(data), `{"by":"other","notes":["lgtm"],"verdict":"rejected"}`; got != want {
+		t.Fatalf("patched document %s, want %s", got, want)
+	}
+
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "notes/plain", Text: "hi"}); err != nil {
+		t.Fatalf("SaveText: %v", err)
+	}
+	if _, err := svc.PatchJSON(ctx, PatchJSONInput{Name: "notes/plain", Patch: []byte(`{}`), Merge: true}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected patching a non-JSON artifact to fail, got %v", err)
+	}
+}
@@ -0,0 +1,409 @@
+package artifacts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// decodeJSON keeps numbers as json.Number so that patching a document does
+// not round them through float64.
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON: %v", ErrInvalidInput, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("%w: invalid JSON: trailing data", ErrInvalidInput)
+	}
+	return doc, nil
+}
+
+func encodeJSON(doc any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("%w: encode JSON: %v", ErrInternal, err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// JSONPointer returns the JSON value at the RFC 6901 pointer ptr of data. An
+// empty pointer selects the whole document.
+func JSONPointer(data []byte, ptr string) ([]byte, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := parseJSONPointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	value, err := lookupJSON(doc, tokens)
+	if err != nil {
+		return nil, err
+	}
+	return encodeJSON(value)
+}
+
+func parseJSONPointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("%w: JSON pointer %q must be empty or start with /", ErrInvalidInput, ptr)
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, tok := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func lookupJSON(doc any, tokens []string) (any, error) {
+	cur := doc
+	for i, tok := range tokens {
+		switch node := cur.(type) {
+		case map[string]any:
+			next, ok := node[tok]
+			if !ok {
+				return nil, fmt.Errorf("%w: JSON pointer %s", ErrNotFound, pointerString(tokens[:i+1]))
+			}
+			cur = next
+		case []any:
+			idx, err := arrayIndex(tok, len(node))
+			if err != nil {
+				return nil, fmt.Errorf("%w: JSON pointer %s", ErrNotFound, pointerString(tokens[:i+1]))
+			}
+			cur = node[idx]
+		default:
+			return nil, fmt.Errorf("%w: JSON pointer %s", ErrNotFound, pointerString(tokens[:i+1]))
+		}
+	}
+	return cur, nil
+}
+
+func pointerString(tokens []string) string {
+	var b strings.Builder
+	for _, tok := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(tok, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// arrayIndex parses an array index below n. RFC 6901 forbids leading zeros.
+func arrayIndex(tok string, n int) (int, error) {
+	if tok == "" || (len(tok) > 1 && tok[0] == '0') {
+		return 0, fmt.Errorf("%w: bad array index %q", ErrInvalidInput, tok)
+	}
+	idx, err := strconv.Atoi(tok)
+	if err != nil || idx < 0 || idx >= n {
+		return 0, fmt.Errorf("%w: array index %q out of range", ErrInvalidInput, tok)
+	}
+	return idx, nil
+}
+
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch to data. The patch is applied
+// as a whole: a failing op, including a failed "test", leaves data unchanged.
+func ApplyJSONPatch(data, patch []byte) ([]byte, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: JSON patch must be an array of operations", ErrInvalidInput)
+	}
+	for i, op := range ops {
+		if doc, err = applyJSONPatchOp(doc, op); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				// A missing path is a bad patch, not a missing artifact.
+				err = fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+			}
+			return nil, fmt.Errorf("patch op %d (%s): %w", i, op.Op, err)
+		}
+	}
+	return encodeJSON(doc)
+}
+
+func applyJSONPatchOp(doc any, op jsonPatchOp) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: path is required", ErrInvalidInput)
+	}
+	path, err := parseJSONPointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	value := func() (any, error) {
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: value is required", ErrInvalidInput)
+		}
+		return decodeJSON(op.Value)
+	}
+	from := func() ([]string, error) {
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: from is required", ErrInvalidInput)
+		}
+		return parseJSONPointer(*op.From)
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return addJSON(doc, path, v)
+	case "remove":
+		doc, _, err := removeJSON(doc, path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return v, nil
+		}
+		if doc, _, err = removeJSON(doc, path); err != nil {
+			return nil, err
+		}
+		return addJSON(doc, path, v)
+	case "move":
+		src, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(src) && pointerString(path[:len(src)]) == pointerString(src) {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidInput)
+		}
+		doc, v, err := removeJSON(doc, src)
+		if err != nil {
+			return nil, err
+		}
+		return addJSON(doc, path, v)
+	case "copy":
+		src, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := lookupJSON(doc, src)
+		if err != nil {
+			return nil, err
+		}
+		return addJSON(doc, path, cloneJSON(v))
+	case "test":
+		want, err := value()
+		if err != nil {
+			return nil, err
+		}
+		got, err := lookupJSON(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(got, want) {
+			return nil, fmt.Errorf("%w: %w at %s", ErrConflict, errJSONTestFailed, *op.Path)
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidInput, op.Op)
+	}
+}
+
+// addJSON implements the "add" op: it sets a member, inserts into an array
+// ("-" appends), or replaces the whole document for an empty path.
+func addJSON(doc any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	parent, err := lookupJSON(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = v
+		return doc, nil
+	case []any:
+		idx := len(node)
+		if last != "-" {
+			if idx, err = arrayIndex(last, len(node)+1); err != nil {
+				return nil, err
+			}
+		}
+		grown := make([]any, 0, len(node)+1)
+		grown = append(grown, node[:idx]...)
+		grown = append(grown, v)
+		grown = append(grown, node[idx:]...)
+		return setJSON(doc, path[:len(path)-1], grown)
+	default:
+		return nil, fmt.Errorf("%w: JSON pointer %s", ErrNotFound, pointerString(path))
+	}
+}
+
+// removeJSON removes the value at path and returns it.
+func removeJSON(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidInput)
+	}
+	parent, err := lookupJSON(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		v, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: JSON pointer %s", ErrNotFound, pointerString(path))
+		}
+		delete(node, last)
+		return doc, v, nil
+	case []any:
+		idx, err := arrayIndex(last, len(node))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: JSON pointer %s", ErrNotFound, pointerString(path))
+		}
+		v := node[idx]
+		shrunk := append(append([]any{}, node[:idx]...), node[idx+1:]...)
+		doc, err = setJSON(doc, path[:len(path)-1], shrunk)
+		return doc, v, err
+	default:
+		return nil, nil, fmt.Errorf("%w: JSON pointer %s", ErrNotFound, pointerString(path))
+	}
+}
+
+// setJSON replaces the existing value at path, which is how a resized array
+// is put back into its parent.
+func setJSON(doc any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	parent, err := lookupJSON(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = v
+	case []any:
+		idx, err := arrayIndex(last, len(node))
+		if err != nil {
+			return nil, err
+		}
+		node[idx] = v
+	}
+	return doc, nil
+}
+
+// ApplyJSONMergePatch applies an RFC 7386 JSON Merge Patch to data.
+func ApplyJSONMergePatch(data, patch []byte) ([]byte, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodeJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+	return encodeJSON(mergeJSON(doc, p))
+}
+
+func mergeJSON(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergeJSON(t[k], v)
+	}
+	return t
+}
+
+func cloneJSON(v any) any {
+	switch node := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(node))
+		for k, child := range node {
+			out[k] = cloneJSON(child)
+		}
+		return out
+	case []any:
+		out := make([]any, len(node))
+		for i, child := range node {
+			out[i] = cloneJSON(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// jsonEqual compares decoded JSON values, treating numbers by value so that
+// 1 and 1.0 are equal.
+func jsonEqual(a, b any) bool {
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, okx := new(big.Float).SetString(string(x))
+		fy, oky := new(big.Float).SetString(string(y))
+		if !okx || !oky {
+			return x == y
+		}
+		return fx.Cmp(fy) == 0
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,101 @@
+package artifacts
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJSONPointer_SelectsSubtrees(t *testing.T) {
+	doc := []byte(`{"foo":["bar","baz"],"":0,"a/b":1,"m~n":8,"big":12345678901234567890}`)
+	tests := []struct {
+		ptr  string
+		want string
+	}{
+		{ptr: "", want: `{"":0,"a/b":1,"big":12345678901234567890,"foo":["bar","baz"],"m~n":8}`},
+		{ptr: "/foo", want: `["bar","baz"]`},
+		{ptr: "/foo/0", want: `"bar"`},
+		{ptr: "/", want: `0`},
+		{ptr: "/a~1b", want: `1`},
+		{ptr: "/m~0n", want: `8`},
+		{ptr: "/big", want: `12345678901234567890`},
+	}
+	for _, tc := range tests {
+		got, err := JSONPointer(doc, tc.ptr)
+		if err != nil {
+			t.Fatalf("JSONPointer(%q): %v", tc.ptr, err)
+		}
+		if string(got) != tc.want {
+			t.Fatalf("JSONPointer(%q)=%s, want %s", tc.ptr, got, tc.want)
+		}
+	}
+
+	for _, ptr := range []string{"/missing", "/foo/2", "/foo/01", "/foo/0/x"} {
+		if _, err := JSONPointer(doc, ptr); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("JSONPointer(%q) err=%v, want ErrNotFound", ptr, err)
+		}
+	}
+	if _, err := JSONPointer(doc, "foo"); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected relative pointer to be rejected, got %v", err)
+	}
+}
+
+func TestApplyJSONPatch_Operations(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{name: "add member", doc: `{"a":1}`, patch: `[{"op":"add","path":"/b","value":[1]}]`, want: `{"a":1,"b":[1]}`},
+		{name: "insert into array", doc: `{"l":[1,3]}`, patch: `[{"op":"add","path":"/l/1","value":2}]`, want: `{"l":[1,2,3]}`},
+		{name: "append to array", doc: `{"l":[1]}`, patch: `[{"op":"add","path":"/l/-","value":2}]`, want: `{"l":[1,2]}`},
+		{name: "remove array element", doc: `[1,2,3]`, patch: `[{"op":"remove","path":"/1"}]`, want: `[1,3]`},
+		{name: "replace", doc: `{"s":"todo"}`, patch: `[{"op":"replace","path":"/s","value":"done"}]`, want: `{"s":"done"}`},
+		{name: "replace root", doc: `{"s":1}`, patch: `[{"op":"replace","path":"","value":[]}]`, want: `[]`},
+		{name: "move", doc: `{"a":{"x":1},"b":{}}`, patch: `[{"op":"move","from":"/a/x","path":"/b/y"}]`, want: `{"a":{},"b":{"y":1}}`},
+		{name: "copy", doc: `{"a":{"x":1}}`, patch: `[{"op":"copy","from":"/a","path":"/b"},{"op":"add","path":"/b/y","value":2}]`, want: `{"a":{"x":1},"b":{"x":1,"y":2}}`},
+		{name: "test numbers by value", doc: `{"n":1}`, patch: `[{"op":"test","path":"/n","value":1.0},{"op":"remove","path":"/n"}]`, want: `{}`},
+	}
+	for _, tc := range tests {
+		got, err := ApplyJSONPatch([]byte(tc.doc), []byte(tc.patch))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if string(got) != tc.want {
+			t.Fatalf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestApplyJSONPatch_Errors(t *testing.T) {
+	doc := []byte(`{"a":{"b":1},"l":[1]}`)
+	if _, err := ApplyJSONPatch(doc, []byte(`[{"op":"test","path":"/a/b","value":2}]`)); !errors.Is(err, ErrConflict) || !errors.Is(err, errJSONTestFailed) {
+		t.Fatalf("expected failed test to conflict, got %v", err)
+	}
+	for _, patch := range []string{
+		`{"op":"add"}`,
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"add","path":"/l/5","value":1}]`,
+		`[{"op":"move","from":"/a","path":"/a/c"}]`,
+		`[{"op":"nope","path":"/a"}]`,
+		`[{"op":"add","path":"/x"}]`,
+	} {
+		if _, err := ApplyJSONPatch(doc, []byte(patch)); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("patch %s: err=%v, want ErrInvalidInput", patch, err)
+		}
+	}
+}
+
+func TestApplyJSONMergePatch(t *testing.T) {
+	got, err := ApplyJSONMergePatch(
+		[]byte(`{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`),
+		[]byte(`{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`),
+	)
+	if err != nil {
+		t.Fatalf("merge patch: %v", err)
+	}
+	want := `{"author":{"givenName":"John"},"content":"This will be unchanged","phoneNumber":"+01-123-456-7890","tags":["example"],"title":"Hello!"}`
+	if string(got) != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
@@ -21,6 +21,9 @@ type ArtifactVersion struct {
 	CreatedAt time.Time    `json:"createdAt"`
 	PrevRef   string       `json:"prevRef,omitempty"`
 	Tombstone bool         `json:"tombstone,omitempty"`
+	// ExpiresAt is when the name is tombstoned if no newer version replaces
+	// this one.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 // Artifact remains as an alias for compatibility with existing call sites.
@@ -33,3 +36,13 @@ func (a ArtifactVersion) URIByRef() string {
 func URIByName(nameEscaped string) string {
 	return "artifact://name/" + nameEscaped
 }
+
+// WorkspaceURIByName qualifies a name URI with the workspace ("global", an ID
+// or an alias) it is read from.
+func WorkspaceURIByName(workspace, nameEscaped string) string {
+	return "artifact://ws/" + workspace + "/name/" + nameEscaped
+}
+
+func WorkspaceURIByRef(workspace, ref string) string {
+	return "artifact://ws/" + workspace + "/ref/" + ref
+}
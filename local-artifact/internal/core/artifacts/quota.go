@@ -0,0 +1,146 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+)
+
+// Quota limits what a workspace may store. Zero fields are unlimited.
+type Quota struct {
+	MaxTotalBytes      int64 `json:"maxTotalBytes,omitempty"`
+	MaxArtifactBytes   int64 `json:"maxArtifactBytes,omitempty"`
+	MaxVersionsPerName int   `json:"maxVersionsPerName,omitempty"`
+}
+
+func (q Quota) IsZero() bool {
+	return q == Quota{}
+}
+
+// Usage summarizes the storage held by a workspace. Bytes counts each stored
+// payload once, matching the deduplicated blob store.
+type Usage struct {
+	Artifacts int64 `json:"artifacts"`
+	Versions  int64 `json:"versions"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// UsageRepository is implemented by repositories that can report storage
+// usage. MaxTotalBytes and MaxVersionsPerName are only enforced for them.
+type UsageRepository interface {
+	Usage(ctx context.Context) (Usage, error)
+	// VersionCount counts the live versions of name since it was last deleted.
+	VersionCount(ctx context.Context, name string) (int, error)
+	HasPayload(ctx context.Context, sha256 string) (bool, error)
+}
+
+func (s *Service) SetQuota(q Quota) {
+	s.quota = q
+}
+
+func (s *Service) Quota() Quota {
+	return s.quota
+}
+
+func (s *Service) Usage(ctx context.Context) (Usage, error) {
+	repo, ok := s.repo.(UsageRepository)
+	if !ok {
+		return Usage{}, fmt.Errorf("%w: repository does not report usage", ErrInternal)
+	}
+	return repo.Usage(ctx)
+}
+
+// batchQuota tallies what the writes of a batch checked so far will add, so
+// each write is checked against the store as it will be once they land.
+type batchQuota struct {
+	bytes    int64
+	payloads map[string]struct{}
+	// versions counts the saves of each name since its last delete in the
+	// batch; restarted marks names whose stored history a delete ended.
+	versions  map[string]int
+	restarted map[string]struct{}
+}
+
+func newBatchQuota() *batchQuota {
+	return &batchQuota{payloads: map[string]struct{}{}, versions: map[string]int{}, restarted: map[string]struct{}{}}
+}
+
+// addSave records a checked save; newBytes is what it adds to the store.
+func (b *batchQuota) addSave(name, sha string, newBytes int64) {
+	b.payloads[sha] = struct{}{}
+	b.bytes += newBytes
+	b.versions[name]++
+}
+
+// addDelete records a tombstone. Deletes by ref are not tallied, which can
+// only make later version checks in the batch stricter.
+func (b *batchQuota) addDelete(name string) {
+	if name == "" {
+		return
+	}
+	b.versions[name] = 0
+	b.restarted[name] = struct{}{}
+}
+
+// checkQuota checks a save of size bytes under name before its payload is
+// stored. pending is nil outside a batch.
+func (s *Service) checkQuota(ctx context.Context, name, sha string, size int64, pending *batchQuota) error {
+	q := s.quota
+	if q.MaxArtifactBytes > 0 && size > q.MaxArtifactBytes {
+		return fmt.Errorf("%w: artifact is %d bytes, limit is %d bytes per artifact", ErrQuotaExceeded, size, q.MaxArtifactBytes)
+	}
+	repo, ok := s.repo.(UsageRepository)
+	if !ok {
+		return nil
+	}
+	return checkStoredQuota(ctx, repo, q, name, sha, size, pending)
+}
+
+// CheckStoredQuota checks the total-bytes and versions-per-name limits of q
+// for a save of size bytes under name, against the store repo reads.
+// Repositories call it inside the transaction that inserts the version.
+func CheckStoredQuota(ctx context.Context, repo UsageRepository, q Quota, name, sha string, size int64) error {
+	return checkStoredQuota(ctx, repo, q, name, sha, size, nil)
+}
+
+func checkStoredQuota(ctx context.Context, repo UsageRepository, q Quota, name, sha string, size int64, pending *batchQuota) error {
+	// A single save reads an empty tally; its nil maps are never written.
+	tally := pending
+	if tally == nil {
+		tally = &batchQuota{}
+	}
+	if q.MaxVersionsPerName > 0 {
+		count := tally.versions[name]
+		if _, restarted := tally.restarted[name]; !restarted {
+			stored, err := repo.VersionCount(ctx, name)
+			if err != nil {
+				return err
+			}
+			count += stored
+		}
+		if count >= q.MaxVersionsPerName {
+			return fmt.Errorf("%w: %s already has %d versions, limit is %d; delete it to start a new history", ErrQuotaExceeded, name, count, q.MaxVersionsPerName)
+		}
+	}
+	var newBytes int64
+	if q.MaxTotalBytes > 0 {
+		stored, err := repo.HasPayload(ctx, sha)
+		if err != nil {
+			return err
+		}
+		if _, batched := tally.payloads[sha]; !stored && !batched {
+			usage, err := repo.Usage(ctx)
+			if err != nil {
+				return err
+			}
+			used := usage.Bytes + tally.bytes
+			if used+size > q.MaxTotalBytes {
+				return fmt.Errorf("%w: workspace stores %d bytes, saving %d more would exceed the %d byte limit", ErrQuotaExceeded, used, size, q.MaxTotalBytes)
+			}
+			newBytes = size
+		}
+	}
+	if pending != nil {
+		pending.addSave(name, sha, newBytes)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// usageMemoryRepo adds usage reporting to memoryRepo. Deleting a name resets
+// its version count, like a tombstone does in the sqlite repository.
+type usageMemoryRepo struct {
+	*memoryRepo
+	versions map[string]int
+}
+
+func newUsageMemoryRepo() *usageMemoryRepo {
+	return &usageMemoryRepo{memoryRepo: newMemoryRepo(), versions: map[string]int{}}
+}
+
+func (r *usageMemoryRepo) Save(ctx context.Context, a ArtifactVersion, data []byte, opts SaveOptions) (ArtifactVersion, error) {
+	saved, err := r.memoryRepo.Save(ctx, a, data, opts)
+	if err == nil {
+		r.versions[a.Name]++
+	}
+	return saved, err
+}
+
+func (r *usageMemoryRepo) Delete(ctx context.Context, sel Selector) (ArtifactVersion, error) {
+	deleted, err := r.memoryRepo.Delete(ctx, sel)
+	if err == nil {
+		r.versions[deleted.Name] = 0
+	}
+	return deleted, err
+}
+
+func (r *usageMemoryRepo) Usage(context.Context) (Usage, error) {
+	usage := Usage{Artifacts: int64(len(r.byName))}
+	seen := map[string]bool{}
+	for _, v := range r.byRef {
+		usage.Versions++
+		if !seen[v.SHA256] {
+			seen[v.SHA256] = true
+			usage.Bytes += v.SizeBytes
+		}
+	}
+	return usage, nil
+}
+
+func (r *usageMemoryRepo) VersionCount(_ context.Context, name string) (int, error) {
+	return r.versions[name], nil
+}
+
+func (r *usageMemoryRepo) HasPayload(_ context.Context, sha string) (bool, error) {
+	for _, v := range r.byRef {
+		if v.SHA256 == sha {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newQuotaService(q Quota) *Service {
+	svc := NewService(newUsageMemoryRepo())
+	n := 0
+	svc.refGenerator = func() (string, error) {
+		n++
+		return fmt.Sprintf("20260216T101010Z-%016x", n), nil
+	}
+	svc.SetQuota(q)
+	return svc
+}
+
+func TestServiceQuota_RejectsOversizedArtifact(t *testing.T) {
+	svc := newQuotaService(Quota{MaxArtifactBytes: 4})
+	ctx := context.Background()
+
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/a", Text: "four"}); err != nil {
+		t.Fatalf("save at limit: %v", err)
+	}
+	_, err := svc.SaveBlob(ctx, SaveBlobInput{Name: "files/b", Data: []byte("fives"), MimeType: "application/octet-stream"})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+}
+
+func TestServiceQuota_TotalBytesAllowsDeduplicatedPayloads(t *testing.T) {
+	svc := newQuotaService(Quota{MaxTotalBytes: 10})
+	ctx := context.Background()
+
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/a", Text: "12345678"}); err != nil {
+		t.Fatalf("first save: %v", err)
+	}
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/b", Text: "12345678"}); err != nil {
+		t.Fatalf("identical payload must not count twice: %v", err)
+	}
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/c", Text: "abc"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+
+	usage, err := svc.Usage(ctx)
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	if usage.Bytes != 8 || usage.Artifacts != 2 || usage.Versions != 2 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestServiceQuota_MaxVersionsPerNameResetsAfterDelete(t *testing.T) {
+	svc := newQuotaService(Quota{MaxVersionsPerName: 2})
+	ctx := context.Background()
+
+	for _, text := range []string{"one", "two"} {
+		if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/a", Text: text}); err != nil {
+			t.Fatalf("save %s: %v", text, err)
+		}
+	}
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/a", Text: "three"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/other", Text: "one"}); err != nil {
+		t.Fatalf("other names are unaffected: %v", err)
+	}
+	if _, err := svc.Delete(ctx, Selector{Name: "plan/a"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/a", Text: "fresh"}); err != nil {
+		t.Fatalf("save after delete: %v", err)
+	}
+}
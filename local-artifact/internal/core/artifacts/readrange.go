@@ -0,0 +1,125 @@
+package artifacts
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ReadRange selects part of an artifact body: a byte range, a line range, or
+// the first or last lines. Only one kind of selection may be set; the zero
+// value selects the whole body. MaxBytes caps whatever is selected.
+type ReadRange struct {
+	Offset int64
+	// Length is a byte count; 0 reads to the end.
+	Length int64
+	// StartLine and EndLine are 1-based and inclusive; an EndLine of 0 reads
+	// to the last line.
+	StartLine int
+	EndLine   int
+	Head      int
+	Tail      int
+	// MaxBytes is 0 for no cap.
+	MaxBytes int64
+}
+
+// RangeResult is the selected part of a body. Offset is where Data starts in
+// the body, so the next page of a byte read starts at Offset+len(Data).
+type RangeResult struct {
+	Data       []byte
+	Offset     int64
+	TotalBytes int64
+	TotalLines int
+	// Truncated reports that MaxBytes cut the selection short.
+	Truncated bool
+}
+
+// SliceRange applies r to data. Lines end at \n; a final line without one
+// still counts. Line selections past the last line are empty rather than an
+// error, so that tail-following readers need not know the line count.
+func SliceRange(data []byte, r ReadRange) (RangeResult, error) {
+	if r.Offset < 0 || r.Length < 0 || r.StartLine < 0 || r.EndLine < 0 || r.Head < 0 || r.Tail < 0 || r.MaxBytes < 0 {
+		return RangeResult{}, fmt.Errorf("%w: range values must not be negative", ErrInvalidInput)
+	}
+	selections := 0
+	for _, set := range []bool{r.Offset > 0 || r.Length > 0, r.StartLine > 0 || r.EndLine > 0, r.Head > 0, r.Tail > 0} {
+		if set {
+			selections++
+		}
+	}
+	if selections > 1 {
+		return RangeResult{}, fmt.Errorf("%w: use only one of offset/length, startLine/endLine, head and tail", ErrInvalidInput)
+	}
+	if r.EndLine > 0 && r.StartLine > r.EndLine {
+		return RangeResult{}, fmt.Errorf("%w: startLine %d is after endLine %d", ErrInvalidInput, r.StartLine, r.EndLine)
+	}
+
+	size := int64(len(data))
+	starts := lineStarts(data)
+	out := RangeResult{TotalBytes: size, TotalLines: len(starts)}
+	lineOffset := func(line int) int64 {
+		if line < 1 {
+			return 0
+		}
+		if line > len(starts) {
+			return size
+		}
+		return int64(starts[line-1])
+	}
+
+	begin, end := int64(0), size
+	switch {
+	case r.Offset > 0 || r.Length > 0:
+		if r.Offset > size {
+			return RangeResult{}, fmt.Errorf("%w: offset %d is past the end of %d bytes", ErrInvalidInput, r.Offset, size)
+		}
+		begin = r.Offset
+		if r.Length > 0 && r.Length < size-begin {
+			end = begin + r.Length
+		}
+	case r.StartLine > 0 || r.EndLine > 0:
+		begin = lineOffset(r.StartLine)
+		if r.EndLine > 0 {
+			end = lineOffset(r.EndLine + 1)
+		}
+	case r.Head > 0:
+		end = lineOffset(r.Head + 1)
+	case r.Tail > 0:
+		begin = lineOffset(len(starts) - r.Tail + 1)
+	}
+
+	if r.MaxBytes > 0 && end-begin > r.MaxBytes {
+		end = runeBoundary(data, begin+r.MaxBytes, begin)
+		out.Truncated = true
+	}
+	out.Data = data[begin:end]
+	out.Offset = begin
+	return out, nil
+}
+
+func lineStarts(data []byte) []int {
+	if len(data) == 0 {
+		return nil
+	}
+	starts := []int{0}
+	for i := 0; ; {
+		j := bytes.IndexByte(data[i:], '\n')
+		if j < 0 || i+j+1 == len(data) {
+			return starts
+		}
+		i += j + 1
+		starts = append(starts, i)
+	}
+}
+
+// runeBoundary moves end back to the start of a UTF-8 sequence so that a cut
+// does not split a character. Binary data may have no boundary nearby; then
+// end is kept.
+func runeBoundary(data []byte, end, begin int64) int64 {
+	for back := int64(0); back < utf8.UTFMax && end-back > begin; back++ {
+		if utf8.RuneStart(data[end-back]) {
+			return end - back
+		}
+	}
+	return end
+}
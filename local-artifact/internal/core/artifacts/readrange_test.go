@@ -0,0 +1,64 @@
+package artifacts
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSliceRange_SelectsBytesLinesHeadAndTail(t *testing.T) {
+	body := []byte("one\ntwo\nthree\nfour")
+	tests := []struct {
+		name       string
+		r          ReadRange
+		want       string
+		wantOffset int64
+		truncated  bool
+	}{
+		{name: "whole", r: ReadRange{}, want: "one\ntwo\nthree\nfour"},
+		{name: "bytes", r: ReadRange{Offset: 4, Length: 3}, want: "two", wantOffset: 4},
+		{name: "bytes to end", r: ReadRange{Offset: 14}, want: "four", wantOffset: 14},
+		{name: "lines", r: ReadRange{StartLine: 2, EndLine: 3}, want: "two\nthree\n", wantOffset: 4},
+		{name: "lines to end", r: ReadRange{StartLine: 3}, want: "three\nfour", wantOffset: 8},
+		{name: "lines past end", r: ReadRange{StartLine: 9}, want: "", wantOffset: 18},
+		{name: "head", r: ReadRange{Head: 1}, want: "one\n"},
+		{name: "tail", r: ReadRange{Tail: 2}, want: "three\nfour", wantOffset: 8},
+		{name: "tail beyond start", r: ReadRange{Tail: 10}, want: "one\ntwo\nthree\nfour"},
+		{name: "capped", r: ReadRange{Tail: 2, MaxBytes: 5}, want: "three", wantOffset: 8, truncated: true},
+	}
+	for _, tc := range tests {
+		got, err := SliceRange(body, tc.r)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if string(got.Data) != tc.want || got.Offset != tc.wantOffset || got.Truncated != tc.truncated {
+			t.Fatalf("%s: got %q offset=%d truncated=%v", tc.name, got.Data, got.Offset, got.Truncated)
+		}
+		if got.TotalBytes != 18 || got.TotalLines != 4 {
+			t.Fatalf("%s: totals bytes=%d lines=%d", tc.name, got.TotalBytes, got.TotalLines)
+		}
+	}
+}
+
+func TestSliceRange_CapKeepsUTF8Whole(t *testing.T) {
+	got, err := SliceRange([]byte("aé"), ReadRange{MaxBytes: 2})
+	if err != nil {
+		t.Fatalf("slice: %v", err)
+	}
+	if string(got.Data) != "a" || !got.Truncated {
+		t.Fatalf("expected cut before the two-byte rune, got %q truncated=%v", got.Data, got.Truncated)
+	}
+}
+
+func TestSliceRange_RejectsInvalidSelections(t *testing.T) {
+	for _, r := range []ReadRange{
+		{Offset: -1},
+		{Offset: 2, Head: 1},
+		{Head: 1, Tail: 1},
+		{StartLine: 3, EndLine: 2},
+		{Offset: 99},
+	} {
+		if _, err := SliceRange([]byte("abc\n"), r); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("SliceRange(%+v) err=%v, want ErrInvalidInput", r, err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package artifacts
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Selector struct {
 	Ref  string `json:"ref,omitempty"`
@@ -9,6 +12,13 @@ type Selector struct {
 
 type SaveOptions struct {
 	ExpectedPrevRef string
+	// ExpiresAt requests an expiry for the new version. The service resolves
+	// it, or the policy default, into ArtifactVersion.ExpiresAt, which is what
+	// repositories store.
+	ExpiresAt time.Time
+	// Quota is enforced again inside the write transaction by repositories
+	// that can, so concurrent saves cannot both pass the service's check.
+	Quota Quota
 }
 
 // Repository persists immutable versions and mutable name pointers.
@@ -19,4 +29,7 @@ type Repository interface {
 	List(ctx context.Context, prefix string, limit int) ([]ArtifactVersion, error)
 	ListVersions(ctx context.Context, name string, limit int) ([]ArtifactVersion, error)
 	Delete(ctx context.Context, sel Selector) (ArtifactVersion, error)
+	// Batch applies ops in order, all or none, and returns the version each
+	// one wrote.
+	Batch(ctx context.Context, ops []BatchOp) ([]ArtifactVersion, error)
 }
@@ -8,12 +8,19 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Service struct {
-	repo         Repository
-	refGenerator func() (string, error)
+	repo          Repository
+	refGenerator  func() (string, error)
+	quota         Quota
+	expiry        ExpiryPolicy
+	jsonValidator JSONValidator
+
+	watchMu sync.Mutex
+	watch   watchers
 }
 
 func NewService(repo Repository) *Service {
@@ -25,22 +32,32 @@ type SaveTextInput struct {
 	Text            string
 	MimeType        string
 	ExpectedPrevRef string
+	// ExpiresAt overrides the policy TTL of the name; zero keeps the default.
+	ExpiresAt time.Time
 }
 
 func (s *Service) SaveText(ctx context.Context, in SaveTextInput) (ArtifactVersion, error) {
-	name, err := normalizeAndValidateName(in.Name)
+	a, data, opts, err := s.textVersion(ctx, in, nil)
 	if err != nil {
 		return ArtifactVersion{}, err
 	}
+	return s.announced(s.repo.Save(ctx, a, data, opts))
+}
+
+func (s *Service) textVersion(ctx context.Context, in SaveTextInput, pending *batchQuota) (ArtifactVersion, []byte, SaveOptions, error) {
+	name, err := normalizeAndValidateName(in.Name)
+	if err != nil {
+		return ArtifactVersion{}, nil, SaveOptions{}, err
+	}
 	if strings.TrimSpace(in.Text) == "" {
-		return ArtifactVersion{}, fmt.Errorf("%w: text is required", ErrInvalidInput)
+		return ArtifactVersion{}, nil, SaveOptions{}, fmt.Errorf("%w: text is required", ErrInvalidInput)
 	}
 	mime := strings.TrimSpace(in.MimeType)
 	if mime == "" {
 		mime = "text/plain; charset=utf-8"
 	}
 	data := []byte(in.Text)
-	return s.saveWithOptions(ctx, name, ArtifactKindText, mime, "", data, SaveOptions{ExpectedPrevRef: in.ExpectedPrevRef})
+	return s.newVersion(ctx, name, ArtifactKindText, mime, "", data, SaveOptions{ExpectedPrevRef: in.ExpectedPrevRef, ExpiresAt: in.ExpiresAt}, pending)
 }
 
 type SaveBlobInput struct {
@@ -49,16 +66,25 @@ type SaveBlobInput struct {
 	MimeType        string
 	Filename        string
 	ExpectedPrevRef string
+	ExpiresAt       time.Time
 }
 
 func (s *Service) SaveBlob(ctx context.Context, in SaveBlobInput) (ArtifactVersion, error) {
-	name, err := normalizeAndValidateName(in.Name)
+	a, data, opts, err := s.blobVersion(ctx, in, nil)
 	if err != nil {
 		return ArtifactVersion{}, err
 	}
+	return s.announced(s.repo.Save(ctx, a, data, opts))
+}
+
+func (s *Service) blobVersion(ctx context.Context, in SaveBlobInput, pending *batchQuota) (ArtifactVersion, []byte, SaveOptions, error) {
+	name, err := normalizeAndValidateName(in.Name)
+	if err != nil {
+		return ArtifactVersion{}, nil, SaveOptions{}, err
+	}
 	mime := strings.TrimSpace(in.MimeType)
 	if mime == "" {
-		return ArtifactVersion{}, fmt.Errorf("%w: mimeType is required", ErrInvalidInput)
+		return ArtifactVersion{}, nil, SaveOptions{}, fmt.Errorf("%w: mimeType is required", ErrInvalidInput)
 	}
 	kind := ArtifactKindFile
 	if strings.HasPrefix(strings.ToLower(mime), "image/") {
@@ -66,27 +92,45 @@ func (s *Service) SaveBlob(ctx context.Context, in SaveBlobInput) (ArtifactVersi
 	} else if strings.HasPrefix(strings.ToLower(mime), "text/") {
 		kind = ArtifactKindText
 	}
-	return s.saveWithOptions(ctx, name, kind, mime, strings.TrimSpace(in.Filename), in.Data, SaveOptions{ExpectedPrevRef: in.ExpectedPrevRef})
+	return s.newVersion(ctx, name, kind, mime, strings.TrimSpace(in.Filename), in.Data, SaveOptions{ExpectedPrevRef: in.ExpectedPrevRef, ExpiresAt: in.ExpiresAt}, pending)
 }
 
-func (s *Service) saveWithOptions(ctx context.Context, name string, kind ArtifactKind, mime string, filename string, data []byte, opts SaveOptions) (ArtifactVersion, error) {
+// newVersion checks a save against the quota and builds the version to store,
+// with its ref, digest and expiry. pending is the tally of a batch, or nil.
+func (s *Service) newVersion(ctx context.Context, name string, kind ArtifactKind, mime string, filename string, data []byte, opts SaveOptions, pending *batchQuota) (ArtifactVersion, []byte, SaveOptions, error) {
 	if data == nil {
 		data = []byte{}
 	}
 	if strings.TrimSpace(opts.ExpectedPrevRef) != "" {
 		normExpected, err := normalizeAndValidateRef(opts.ExpectedPrevRef)
 		if err != nil {
-			return ArtifactVersion{}, err
+			return ArtifactVersion{}, nil, SaveOptions{}, err
 		}
 		opts.ExpectedPrevRef = normExpected
 	}
 
+	if s.jsonValidator != nil {
+		if err := s.jsonValidator.ValidateJSON(name, data); err != nil {
+			return ArtifactVersion{}, nil, SaveOptions{}, err
+		}
+	}
+
+	createdAt := nowUTCSecond()
+	expiresAt, err := s.expiresAt(name, opts.ExpiresAt, createdAt)
+	if err != nil {
+		return ArtifactVersion{}, nil, SaveOptions{}, err
+	}
+
 	ref, err := s.refGenerator()
 	if err != nil {
-		return ArtifactVersion{}, fmt.Errorf("%w: generate ref: %v", ErrInternal, err)
+		return ArtifactVersion{}, nil, SaveOptions{}, fmt.Errorf("%w: generate ref: %v", ErrInternal, err)
 	}
 	sum := sha256.Sum256(data)
 	shaHex := hex.EncodeToString(sum[:])
+	if err := s.checkQuota(ctx, name, shaHex, int64(len(data)), pending); err != nil {
+		return ArtifactVersion{}, nil, SaveOptions{}, err
+	}
+	opts.Quota = s.quota
 
 	a := ArtifactVersion{
 		Ref:       ref,
@@ -96,10 +140,11 @@ func (s *Service) saveWithOptions(ctx context.Context, name string, kind Artifac
 		Filename:  filename,
 		SizeBytes: int64(len(data)),
 		SHA256:    shaHex,
-		CreatedAt: nowUTCSecond(),
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
 	}
 
-	return s.repo.Save(ctx, a, data, opts)
+	return a, data, opts, nil
 }
 
 func (s *Service) Resolve(ctx context.Context, name string) (string, error) {
@@ -123,7 +168,7 @@ func (s *Service) Delete(ctx context.Context, sel Selector) (ArtifactVersion, er
 	if err != nil {
 		return ArtifactVersion{}, err
 	}
-	return s.repo.Delete(ctx, normSel)
+	return s.announced(s.repo.Delete(ctx, normSel))
 }
 
 func (s *Service) List(ctx context.Context, prefix string, limit int) ([]ArtifactVersion, error) {
@@ -3,6 +3,7 @@ package artifacts
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sort"
 	"strings"
 	"testing"
@@ -120,6 +121,43 @@ func (r *memoryRepo) Delete(_ context.Context, sel Selector) (ArtifactVersion, e
 	return a, nil
 }
 
+// Batch applies ops to copies of the maps and keeps them only if every op
+// succeeds.
+func (r *memoryRepo) Batch(ctx context.Context, ops []BatchOp) ([]ArtifactVersion, error) {
+	work := &memoryRepo{byRef: map[string]ArtifactVersion{}, data: map[string][]byte{}, byName: map[string]string{}}
+	for k, v := range r.byRef {
+		work.byRef[k] = v
+	}
+	for k, v := range r.data {
+		work.data[k] = v
+	}
+	for k, v := range r.byName {
+		work.byName[k] = v
+	}
+	out := make([]ArtifactVersion, 0, len(ops))
+	for i, op := range ops {
+		var (
+			a   ArtifactVersion
+			err error
+		)
+		if op.Delete {
+			if op.ExpectedPrevRef != "" && op.ExpectedPrevRef != work.byName[op.Selector.Name] {
+				err = ErrConflict
+			} else {
+				a, err = work.Delete(ctx, op.Selector)
+			}
+		} else {
+			a, err = work.Save(ctx, op.Version, op.Data, SaveOptions{ExpectedPrevRef: op.ExpectedPrevRef})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("batch write %d: %w", i, err)
+		}
+		out = append(out, a)
+	}
+	*r = *work
+	return out, nil
+}
+
 func TestServiceSaveText_SameNameCreatesPrevRefChain(t *testing.T) {
 	repo := newMemoryRepo()
 	svc := NewService(repo)
@@ -70,6 +70,11 @@ func ValidateSelector(sel Selector) error {
 	return err
 }
 
+// NormalizeName trims name and checks it is a valid artifact name.
+func NormalizeName(name string) (string, error) {
+	return normalizeAndValidateName(name)
+}
+
 func normalizePrefix(prefix string) (string, error) {
 	prefix = strings.TrimSpace(prefix)
 	if prefix == "" {
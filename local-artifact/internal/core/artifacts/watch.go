@@ -0,0 +1,50 @@
+package artifacts
+
+// watchers holds the callbacks registered with Service.Watch.
+type watchers struct {
+	next int
+	fns  map[int]func(ArtifactVersion)
+}
+
+// Watch calls fn with every version the service writes, tombstones included,
+// once the write has committed. fn runs on the writer's goroutine and must
+// not block. The returned func stops the calls.
+func (s *Service) Watch(fn func(ArtifactVersion)) func() {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.watch.fns == nil {
+		s.watch.fns = map[int]func(ArtifactVersion){}
+	}
+	id := s.watch.next
+	s.watch.next++
+	s.watch.fns[id] = fn
+	return func() {
+		s.watchMu.Lock()
+		defer s.watchMu.Unlock()
+		delete(s.watch.fns, id)
+	}
+}
+
+// Announce reports versions written without going through the service, such
+// as tombstones from an expiry sweep, to its watchers.
+func (s *Service) Announce(versions ...ArtifactVersion) {
+	s.watchMu.Lock()
+	fns := make([]func(ArtifactVersion), 0, len(s.watch.fns))
+	for _, fn := range s.watch.fns {
+		fns = append(fns, fn)
+	}
+	s.watchMu.Unlock()
+	for _, v := range versions {
+		for _, fn := range fns {
+			fn(v)
+		}
+	}
+}
+
+// announced passes the result of a write through Announce when it succeeded.
+func (s *Service) announced(v ArtifactVersion, err error) (ArtifactVersion, error) {
+	if err == nil {
+		s.Announce(v)
+	}
+	return v, err
+}
@@ -0,0 +1,40 @@
+package artifacts
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestServiceWatch_ReportsEveryCommittedWrite(t *testing.T) {
+	svc := NewService(newMemoryRepo())
+	ctx := context.Background()
+
+	var seen []string
+	stop := svc.Watch(func(v ArtifactVersion) { seen = append(seen, v.Name) })
+
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/a", Text: "v1"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/a", Text: "v2"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/a", Text: " "}); err == nil {
+		t.Fatal("expected an invalid save to fail")
+	}
+	if _, err := svc.Batch(ctx, []BatchWrite{
+		{Text: &SaveTextInput{Name: "plan/b", Text: "b"}},
+		{Delete: &DeleteInput{Selector: Selector{Name: "plan/a"}}},
+	}); err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	stop()
+	if _, err := svc.SaveText(ctx, SaveTextInput{Name: "plan/c", Text: "c"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	want := []string{"plan/a", "plan/a", "plan/b", "plan/a"}
+	if strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Fatalf("watched=%v want=%v", seen, want)
+	}
+}
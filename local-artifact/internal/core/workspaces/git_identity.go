@@ -0,0 +1,78 @@
+package workspaces
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Identity modes select how a workspace root is keyed: by its absolute path,
+// the default, or by its repository's git remote.
+const (
+	IdentityModeRoots     = "roots"
+	IdentityModeGitRemote = "git-remote"
+)
+
+// NormalizeGitRemote reduces a git remote URL to "host/path" so the https,
+// ssh and scp-like spellings of one repository compare equal:
+// "git@github.com:Owner/Repo.git" and "https://github.com/Owner/Repo" both
+// become "github.com/Owner/Repo". Local-path remotes keep their path.
+func NormalizeGitRemote(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("empty git remote")
+	}
+
+	var host, repoPath string
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return "", err
+		}
+		if u.Scheme == "file" {
+			repoPath = u.Path
+		} else {
+			host = u.Hostname()
+			repoPath = u.Path
+		}
+	} else if at, colon := strings.Index(raw, "@"), strings.Index(raw, ":"); colon > 1 && !strings.Contains(raw[:colon], "/") && (at < 0 || at < colon) {
+		// scp-like syntax: [user@]host:path
+		host = raw[at+1 : colon]
+		repoPath = raw[colon+1:]
+	} else {
+		repoPath = strings.ReplaceAll(raw, `\`, "/")
+	}
+
+	host = strings.ToLower(strings.TrimSpace(host))
+	repoPath = strings.TrimSuffix(strings.Trim(path.Clean("/"+repoPath), "/"), ".git")
+	if repoPath == "" || repoPath == "." {
+		return "", errors.New("git remote has no repository path")
+	}
+	if host == "" {
+		return "/" + repoPath, nil
+	}
+	return host + "/" + repoPath, nil
+}
+
+// GitIdentityKey names a workspace root by repository rather than by where it
+// is checked out: the normalized remote plus the root's path inside the
+// repository ("." for the top level).
+func GitIdentityKey(normalizedRemote, repoRelativePath string) string {
+	rel := path.Clean("/" + strings.ReplaceAll(repoRelativePath, `\`, "/"))
+	if rel == "/" {
+		rel = "."
+	} else {
+		rel = strings.TrimPrefix(rel, "/")
+	}
+	return "git:" + normalizedRemote + "#" + rel
+}
+
+// ComputeIdentityWorkspaceID derives a workspace ID from the identity keys of
+// every root in a workspace.
+func ComputeIdentityWorkspaceID(keys []string) string {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	return ComputeWorkspaceID(sorted)
+}
@@ -13,6 +13,35 @@ type Workspace struct {
 	LastSeenAt  time.Time
 }
 
+// Identity maps a location-independent workspace key, such as a git remote
+// plus repository path, to the workspace it resolves to.
+type Identity struct {
+	Key         string
+	WorkspaceID string
+	CreatedAt   time.Time
+}
+
+// ShareAllWorkspaces as a share target makes an artifact readable from every
+// workspace.
+const ShareAllWorkspaces = "*"
+
+// Share lets TargetWorkspaceID read every version of Name from
+// SourceWorkspaceID.
+type Share struct {
+	SourceWorkspaceID string
+	Name              string
+	TargetWorkspaceID string
+	CreatedAt         time.Time
+}
+
+// Alias is a short, stable name for a workspace, usable wherever a workspace
+// is named in an artifact URI.
+type Alias struct {
+	Alias       string
+	WorkspaceID string
+	CreatedAt   time.Time
+}
+
 type Registry interface {
 	EnsureWorkspace(ctx context.Context, workspaceID string, roots []string, owner string) error
 	ListWorkspaces(ctx context.Context) ([]Workspace, error)
@@ -0,0 +1,86 @@
+package blobstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+// Encoding is how a blob's bytes are stored on disk. Digests always hash the
+// uncompressed content.
+type Encoding string
+
+const (
+	EncodingNone Encoding = "none"
+	EncodingGzip Encoding = "gzip"
+)
+
+// gzipSuffix marks a gzip-encoded blob: blobs/<aa>/<digest>.gz.
+const gzipSuffix = ".gz"
+
+// CompressionPolicy maps MIME types to encodings. Keys are an exact type
+// such as "application/json", a wildcard subtype such as "text/*", or "*".
+// The most specific key wins; unmatched types are stored as is.
+type CompressionPolicy map[string]Encoding
+
+// NewCompressionPolicy builds a policy from the validated "compression"
+// object of settings.json.
+func NewCompressionPolicy(settings map[string]string) CompressionPolicy {
+	if len(settings) == 0 {
+		return nil
+	}
+	policy := make(CompressionPolicy, len(settings))
+	for pattern, encoding := range settings {
+		policy[pattern] = Encoding(encoding)
+	}
+	return policy
+}
+
+func (p CompressionPolicy) EncodingFor(mimeType string) Encoding {
+	if len(p) == 0 {
+		return EncodingNone
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(mimeType))
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = parsed
+	}
+	if enc, ok := p[mediaType]; ok {
+		return enc
+	}
+	if major, _, ok := strings.Cut(mediaType, "/"); ok {
+		if enc, ok := p[major+"/*"]; ok {
+			return enc
+		}
+	}
+	if enc, ok := p["*"]; ok {
+		return enc
+	}
+	return EncodingNone
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: decompress: %v", errUndecodable, err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decompress: %v", errUndecodable, err)
+	}
+	return out, nil
+}
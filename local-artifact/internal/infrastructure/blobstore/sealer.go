@@ -0,0 +1,111 @@
+package blobstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// sealedSuffix marks an encrypted blob: blobs/<aa>/<address>.enc, where the
+// address is a keyed hash of the content digest.
+const sealedSuffix = ".enc"
+
+// encodingSealed is what locate reports for an encrypted blob. The encoding
+// of the plaintext is recorded inside the sealed header.
+const encodingSealed Encoding = "sealed"
+
+const sealedFormatV1 = 1
+
+// errUndecodable marks stored bytes that cannot be decrypted or decompressed.
+var errUndecodable = errors.New("blob cannot be decoded")
+
+// Sealer encrypts blobs with AES-GCM under a workspace data key. Sealed blobs
+// are named by an HMAC of their digest, so identical content still dedupes
+// while file names no longer reveal content hashes.
+type Sealer struct {
+	aead   cipher.AEAD
+	macKey []byte
+}
+
+func NewSealer(dataKey []byte) (*Sealer, error) {
+	if len(dataKey) != 32 {
+		return nil, fmt.Errorf("data key must be 32 bytes, got %d", len(dataKey))
+	}
+	block, err := aes.NewCipher(deriveKey(dataKey, "ccsubagents blob encryption"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead, macKey: deriveKey(dataKey, "ccsubagents blob address")}, nil
+}
+
+func deriveKey(key []byte, label string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+func (s *Sealer) address(digest string) string {
+	raw, err := hex.DecodeString(digest)
+	if err != nil {
+		raw = []byte(digest)
+	}
+	mac := hmac.New(sha256.New, s.macKey)
+	mac.Write(raw)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal lays out a sealed blob as: format byte, encoding byte, nonce,
+// ciphertext. The header and address are authenticated, so a sealed file
+// moved to another address fails to open.
+func (s *Sealer) seal(address string, enc Encoding, payload []byte) ([]byte, error) {
+	header := []byte{sealedFormatV1, encodingByte(enc)}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(append([]byte{}, header...), nonce...)
+	return s.aead.Seal(out, nonce, payload, append(header, address...)), nil
+}
+
+func (s *Sealer) open(address string, b []byte) (Encoding, []byte, error) {
+	headerLen := 2 + s.aead.NonceSize()
+	if len(b) < headerLen || b[0] != sealedFormatV1 {
+		return "", nil, fmt.Errorf("%w: unknown sealed format", errUndecodable)
+	}
+	enc, ok := byteEncoding(b[1])
+	if !ok {
+		return "", nil, fmt.Errorf("%w: unknown sealed encoding %d", errUndecodable, b[1])
+	}
+	aad := append([]byte{b[0], b[1]}, address...)
+	payload, err := s.aead.Open(nil, b[2:headerLen], b[headerLen:], aad)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", errUndecodable, err)
+	}
+	return enc, payload, nil
+}
+
+func encodingByte(enc Encoding) byte {
+	if enc == EncodingGzip {
+		return 1
+	}
+	return 0
+}
+
+func byteEncoding(b byte) (Encoding, bool) {
+	switch b {
+	case 0:
+		return EncodingNone, true
+	case 1:
+		return EncodingGzip, true
+	}
+	return "", false
+}
@@ -4,19 +4,34 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
 type Store struct {
-	root string
+	root       string
+	sealer     *Sealer
+	sealWrites bool
 }
 
 func New(root string) *Store {
 	return &Store{root: root}
 }
 
+// SetSealer lets the store read encrypted blobs and, with sealWrites, encrypt
+// new ones. Call it before the store is shared; plaintext blobs stay readable.
+func (s *Store) SetSealer(sealer *Sealer, sealWrites bool) {
+	s.sealer = sealer
+	s.sealWrites = sealer != nil && sealWrites
+}
+
+// HasSealer reports whether the store can read encrypted blobs.
+func (s *Store) HasSealer() bool {
+	return s.sealer != nil
+}
+
 func (s *Store) Path(digest string) string {
 	digest = strings.ToLower(strings.TrimSpace(digest))
 	if len(digest) < 2 {
@@ -25,17 +40,59 @@ func (s *Store) Path(digest string) string {
 	return filepath.Join(s.root, digest[:2], digest)
 }
 
+// Address returns the keyed name under which an encrypted copy of digest is
+// stored, or "" when the store has no sealer.
+func (s *Store) Address(digest string) string {
+	digest, err := normalizeDigest(digest)
+	if err != nil || s.sealer == nil {
+		return ""
+	}
+	return s.sealer.address(digest)
+}
+
 func (s *Store) Put(digest string, data []byte) error {
+	return s.PutEncoded(digest, data, EncodingNone)
+}
+
+// PutEncoded stores data under digest, compressed with enc when that makes it
+// smaller. A blob already stored under either encoding is kept as it is. A
+// store that seals writes only dedupes against encrypted copies.
+func (s *Store) PutEncoded(digest string, data []byte, enc Encoding) error {
 	digest, err := normalizeDigest(digest)
 	if err != nil {
 		return err
 	}
+	if s.sealWrites {
+		return s.putSealed(digest, data, enc)
+	}
+	if existing, err := s.Get(digest); err == nil {
+		if string(existing) != string(data) {
+			return fmt.Errorf("digest collision for %s", digest)
+		}
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
 	target := s.Path(digest)
-	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+	payload, enc, err := encodePayload(data, enc)
+	if err != nil {
 		return err
 	}
+	if enc == EncodingGzip {
+		target += gzipSuffix
+	}
+	return writeAtomic(target, payload)
+}
 
-	if existing, err := os.ReadFile(target); err == nil {
+func (s *Store) putSealed(digest string, data []byte, enc Encoding) error {
+	address := s.sealer.address(digest)
+	target := s.Path(address) + sealedSuffix
+	if b, err := os.ReadFile(target); err == nil {
+		existing, err := s.decode(address, encodingSealed, b)
+		if err != nil {
+			return fmt.Errorf("read sealed blob %s: %w", digest, err)
+		}
 		if string(existing) != string(data) {
 			return fmt.Errorf("digest collision for %s", digest)
 		}
@@ -44,6 +101,38 @@ func (s *Store) Put(digest string, data []byte) error {
 		return err
 	}
 
+	payload, enc, err := encodePayload(data, enc)
+	if err != nil {
+		return err
+	}
+	sealed, err := s.sealer.seal(address, enc, payload)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(target, sealed)
+}
+
+// encodePayload compresses data with enc, falling back to the raw bytes when
+// compression does not make them smaller.
+func encodePayload(data []byte, enc Encoding) ([]byte, Encoding, error) {
+	if enc != EncodingGzip {
+		return data, EncodingNone, nil
+	}
+	compressed, err := gzipBytes(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(compressed) >= len(data) {
+		return data, EncodingNone, nil
+	}
+	return compressed, EncodingGzip, nil
+}
+
+func writeAtomic(target string, payload []byte) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
 	tmp, err := os.CreateTemp(filepath.Dir(target), ".blob-*")
 	if err != nil {
 		return err
@@ -51,7 +140,7 @@ func (s *Store) Put(digest string, data []byte) error {
 	tmpName := tmp.Name()
 	defer removeIfExists(tmpName)
 
-	if _, err := tmp.Write(data); err != nil {
+	if _, err := tmp.Write(payload); err != nil {
 		closeIgnore(tmp)
 		return err
 	}
@@ -68,7 +157,7 @@ func (s *Store) Put(digest string, data []byte) error {
 			return nil
 		}
 		if existing, readErr := os.ReadFile(target); readErr == nil {
-			if string(existing) == string(data) {
+			if string(existing) == string(payload) {
 				return nil
 			}
 		}
@@ -83,18 +172,108 @@ func (s *Store) Put(digest string, data []byte) error {
 	return nil
 }
 
+// Get returns the decrypted, uncompressed content of the blob. A missing blob
+// returns an error satisfying os.IsNotExist.
 func (s *Store) Get(digest string) ([]byte, error) {
-	digest, err := normalizeDigest(digest)
+	path, enc, err := s.locate(digest)
 	if err != nil {
 		return nil, err
 	}
-	b, err := os.ReadFile(s.Path(digest))
+	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return s.decode(s.Address(digest), enc, b)
+}
+
+// decode turns stored bytes back into content. Failures wrap errUndecodable.
+func (s *Store) decode(address string, enc Encoding, b []byte) ([]byte, error) {
+	if enc == encodingSealed {
+		var err error
+		if enc, b, err = s.sealer.open(address, b); err != nil {
+			return nil, err
+		}
+	}
+	if enc == EncodingGzip {
+		return gunzipBytes(b)
+	}
 	return b, nil
 }
 
+// Locate returns the file holding the blob, whatever its encoding.
+func (s *Store) Locate(digest string) (string, error) {
+	path, _, err := s.locate(digest)
+	return path, err
+}
+
+type blobLocation struct {
+	path string
+	enc  Encoding
+}
+
+// locate prefers an encrypted copy, then the raw and gzip layouts.
+func (s *Store) locate(digest string) (string, Encoding, error) {
+	digest, err := normalizeDigest(digest)
+	if err != nil {
+		return "", "", err
+	}
+	var candidates []blobLocation
+	if s.sealer != nil {
+		candidates = append(candidates, blobLocation{s.Path(s.sealer.address(digest)) + sealedSuffix, encodingSealed})
+	}
+	candidates = append(candidates,
+		blobLocation{s.Path(digest), EncodingNone},
+		blobLocation{s.Path(digest) + gzipSuffix, EncodingGzip},
+	)
+	var firstErr error
+	for _, c := range candidates {
+		_, err := os.Stat(c.path)
+		if err == nil {
+			return c.path, c.enc, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", err
+		}
+		if c.enc == EncodingNone {
+			firstErr = err
+		}
+	}
+	return "", "", firstErr
+}
+
+// Usage counts the blobs under the store root. Temporary files left by
+// interrupted writes are included since they occupy disk space too.
+type Usage struct {
+	Files int64
+	Bytes int64
+}
+
+func (s *Store) Usage() (Usage, error) {
+	var usage Usage
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		usage.Files++
+		usage.Bytes += info.Size()
+		return nil
+	})
+	return usage, err
+}
+
 func normalizeDigest(digest string) (string, error) {
 	digest = strings.ToLower(strings.TrimSpace(digest))
 	if len(digest) != 64 {
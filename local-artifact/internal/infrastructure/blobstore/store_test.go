@@ -1,10 +1,13 @@
 package blobstore
 
 import (
+	"bytes"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -60,3 +63,215 @@ func TestStoreDedupesSingleFilePath(t *testing.T) {
 		t.Fatalf("expected exactly one blob file %q, got: %+v", digest, entries)
 	}
 }
+
+func TestStoreVerifyWalkAndQuarantine(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	data := []byte("payload")
+	digest := digestFor(data)
+	if err := s.Put(digest, data); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+	stray := filepath.Join(root, digest[:2], ".blob-123")
+	if err := os.WriteFile(stray, []byte("partial"), 0o644); err != nil {
+		t.Fatalf("write stray: %v", err)
+	}
+
+	if ok, err := s.Verify(digest); err != nil || !ok {
+		t.Fatalf("verify intact blob: ok=%v err=%v", ok, err)
+	}
+	if err := os.WriteFile(s.Path(digest), []byte("tampered"), 0o644); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if ok, err := s.Verify(digest); err != nil || ok {
+		t.Fatalf("verify tampered blob: ok=%v err=%v", ok, err)
+	}
+	if _, err := s.Verify(digestFor([]byte("absent"))); !os.IsNotExist(err) {
+		t.Fatalf("verify missing blob: err=%v", err)
+	}
+
+	digests := map[string]string{}
+	if err := s.Walk(func(e Entry) error {
+		digests[filepath.Base(e.Path)] = e.Digest
+		return nil
+	}); err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	if len(digests) != 2 || digests[digest] != digest || digests[".blob-123"] != "" {
+		t.Fatalf("unexpected walk entries: %+v", digests)
+	}
+
+	dir := filepath.Join(t.TempDir(), "quarantine")
+	first, err := Quarantine(s.Path(digest), dir)
+	if err != nil {
+		t.Fatalf("quarantine: %v", err)
+	}
+	if err := s.Put(digest, data); err != nil {
+		t.Fatalf("re-put after quarantine: %v", err)
+	}
+	second, err := Quarantine(s.Path(digest), dir)
+	if err != nil {
+		t.Fatalf("second quarantine: %v", err)
+	}
+	if first == second || filepath.Dir(second) != dir {
+		t.Fatalf("quarantine paths collide: %q %q", first, second)
+	}
+}
+
+func TestStoreGzipEncoding(t *testing.T) {
+	root := t.TempDir()
+	s := New(root)
+	text := []byte(strings.Repeat("=== RUN TestSomething\n--- PASS: TestSomething\n", 200))
+	digest := digestFor(text)
+
+	if err := s.PutEncoded(digest, text, EncodingGzip); err != nil {
+		t.Fatalf("put gzip failed: %v", err)
+	}
+	info, err := os.Stat(s.Path(digest) + gzipSuffix)
+	if err != nil {
+		t.Fatalf("expected gzip blob: %v", err)
+	}
+	if info.Size() >= int64(len(text)) {
+		t.Fatalf("gzip blob not smaller: %d >= %d", info.Size(), len(text))
+	}
+	if err := s.Put(digest, text); err != nil {
+		t.Fatalf("raw put of gzip blob failed: %v", err)
+	}
+	if _, err := os.Stat(s.Path(digest)); !os.IsNotExist(err) {
+		t.Fatalf("raw put should dedupe against gzip blob: err=%v", err)
+	}
+	got, err := s.Get(digest)
+	if err != nil || !bytes.Equal(got, text) {
+		t.Fatalf("get gzip blob: err=%v equal=%v", err, bytes.Equal(got, text))
+	}
+	if ok, err := s.Verify(digest); err != nil || !ok {
+		t.Fatalf("verify gzip blob: ok=%v err=%v", ok, err)
+	}
+
+	// Incompressible payloads stay raw, and raw blobs stay readable.
+	random := make([]byte, 256)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatalf("rand: %v", err)
+	}
+	rawDigest := digestFor(random)
+	if err := s.PutEncoded(rawDigest, random, EncodingGzip); err != nil {
+		t.Fatalf("put incompressible failed: %v", err)
+	}
+	if _, err := os.Stat(s.Path(rawDigest)); err != nil {
+		t.Fatalf("expected raw blob for incompressible payload: %v", err)
+	}
+	if got, err := s.Get(rawDigest); err != nil || !bytes.Equal(got, random) {
+		t.Fatalf("get raw blob: err=%v", err)
+	}
+
+	digests := map[string]bool{}
+	if err := s.Walk(func(e Entry) error {
+		digests[e.Digest] = true
+		return nil
+	}); err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	if len(digests) != 2 || !digests[digest] || !digests[rawDigest] {
+		t.Fatalf("unexpected walk digests: %+v", digests)
+	}
+
+	if err := os.WriteFile(s.Path(digest)+gzipSuffix, []byte("not gzip"), 0o644); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if ok, err := s.Verify(digest); err != nil || ok {
+		t.Fatalf("verify corrupt gzip blob: ok=%v err=%v", ok, err)
+	}
+	if _, err := s.Get(digest); err == nil {
+		t.Fatalf("expected get of corrupt gzip blob to fail")
+	}
+}
+
+func TestCompressionPolicyEncodingFor(t *testing.T) {
+	policy := NewCompressionPolicy(map[string]string{
+		"text/*":           "gzip",
+		"text/csv":         "none",
+		"application/json": "gzip",
+	})
+	tests := map[string]Encoding{
+		"text/plain; charset=utf-8": EncodingGzip,
+		"TEXT/CSV":                  EncodingNone,
+		"application/json":          EncodingGzip,
+		"image/png":                 EncodingNone,
+		"":                          EncodingNone,
+	}
+	for mimeType, want := range tests {
+		if got := policy.EncodingFor(mimeType); got != want {
+			t.Fatalf("EncodingFor(%q)=%q want %q", mimeType, got, want)
+		}
+	}
+	policy["*"] = EncodingGzip
+	if got := policy.EncodingFor("image/png"); got != EncodingGzip {
+		t.Fatalf("catch-all not applied: %q", got)
+	}
+}
+
+func TestStoreSealedBlobs(t *testing.T) {
+	root := t.TempDir()
+	plain := []byte("written before encryption was enabled")
+	plainDigest := digestFor(plain)
+	if err := New(root).Put(plainDigest, plain); err != nil {
+		t.Fatalf("plain put failed: %v", err)
+	}
+
+	sealer, err := NewSealer(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("new sealer: %v", err)
+	}
+	s := New(root)
+	s.SetSealer(sealer, true)
+	text := []byte(strings.Repeat("secret build output\n", 100))
+	digest := digestFor(text)
+	if err := s.PutEncoded(digest, text, EncodingGzip); err != nil {
+		t.Fatalf("sealed put failed: %v", err)
+	}
+	if err := s.PutEncoded(digest, text, EncodingGzip); err != nil {
+		t.Fatalf("sealed re-put failed: %v", err)
+	}
+	address := s.Address(digest)
+	if address == "" || address == digest {
+		t.Fatalf("unexpected sealed address %q", address)
+	}
+	sealed, err := os.ReadFile(s.Path(address) + sealedSuffix)
+	if err != nil {
+		t.Fatalf("expected sealed blob: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Fatalf("sealed blob holds plaintext")
+	}
+	if _, err := os.Stat(s.Path(digest)); !os.IsNotExist(err) {
+		t.Fatalf("plaintext copy written: err=%v", err)
+	}
+	if got, err := s.Get(digest); err != nil || !bytes.Equal(got, text) {
+		t.Fatalf("get sealed blob: err=%v", err)
+	}
+	if got, err := s.Get(plainDigest); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("get plaintext blob: err=%v", err)
+	}
+	if _, err := New(root).Get(digest); !os.IsNotExist(err) {
+		t.Fatalf("store without sealer should not find sealed blob: err=%v", err)
+	}
+
+	entries := map[string]Entry{}
+	if err := s.Walk(func(e Entry) error {
+		entries[filepath.Base(e.Path)] = e
+		return nil
+	}); err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	if e := entries[address+sealedSuffix]; e.Address != address || e.Digest != "" {
+		t.Fatalf("unexpected sealed walk entry: %+v", e)
+	}
+
+	sealed[len(sealed)-1] ^= 0xff
+	if err := os.WriteFile(s.Path(address)+sealedSuffix, sealed, 0o644); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if ok, err := s.Verify(digest); err != nil || ok {
+		t.Fatalf("verify tampered sealed blob: ok=%v err=%v", ok, err)
+	}
+}
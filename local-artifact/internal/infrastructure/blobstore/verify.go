@@ -0,0 +1,109 @@
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is a file found under the store root. Digest is empty for files not
+// laid out like a blob, such as temp files left by interrupted writes.
+// Encrypted blobs carry their keyed Address instead of a Digest.
+type Entry struct {
+	Digest  string
+	Address string
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Walk calls fn for every regular file under the store root.
+func (s *Store) Walk(fn func(Entry) error) error {
+	return filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		entry := Entry{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+		name := d.Name()
+		if stem, ok := strings.CutSuffix(name, sealedSuffix); ok {
+			if address, err := normalizeDigest(stem); err == nil && s.Path(address)+sealedSuffix == path {
+				entry.Address = address
+			}
+			return fn(entry)
+		}
+		suffix := ""
+		if strings.HasSuffix(name, gzipSuffix) {
+			name, suffix = strings.TrimSuffix(name, gzipSuffix), gzipSuffix
+		}
+		if digest, err := normalizeDigest(name); err == nil && s.Path(digest)+suffix == path {
+			entry.Digest = digest
+		}
+		return fn(entry)
+	})
+}
+
+// Verify hashes the stored blob, decrypted and decompressed, and reports
+// whether its content matches digest. A blob that fails to decrypt or
+// decompress is reported as a mismatch. A missing blob returns an error
+// satisfying os.IsNotExist.
+func (s *Store) Verify(digest string) (bool, error) {
+	path, enc, err := s.locate(digest)
+	if err != nil {
+		return false, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	data, err := s.decode(s.Address(digest), enc, b)
+	if errors.Is(err, errUndecodable) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) == strings.ToLower(strings.TrimSpace(digest)), nil
+}
+
+// Quarantine moves the file at path into dir, keeping its base name unless a
+// file of that name was quarantined before. It returns the new location.
+func Quarantine(path, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Base(path)
+	target := filepath.Join(dir, base)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(target); errors.Is(err, fs.ErrNotExist) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		target = filepath.Join(dir, base+"."+strconv.Itoa(i))
+	}
+	if err := os.Rename(path, target); err != nil {
+		return "", fmt.Errorf("quarantine %s: %w", path, err)
+	}
+	return target, nil
+}
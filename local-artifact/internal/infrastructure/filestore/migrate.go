@@ -0,0 +1,147 @@
+package filestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+// Importer receives replayed versions. *sqlite.ArtifactRepository
+// implements it.
+type Importer interface {
+	HasVersion(ctx context.Context, ref string) (bool, error)
+	LatestRef(ctx context.Context, name string) (string, error)
+	Import(ctx context.Context, a artifacts.ArtifactVersion, data []byte) error
+}
+
+type MigrateReport struct {
+	Names    int            `json:"names"`
+	Versions int            `json:"versions"`
+	Imported int            `json:"imported"`
+	Existing int            `json:"existing"`
+	Skipped  []MigrateIssue `json:"skipped"`
+	DryRun   bool           `json:"dryRun"`
+}
+
+type MigrateIssue struct {
+	Name   string `json:"name"`
+	Ref    string `json:"ref,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// migrateHistoryLimit bounds the versions replayed per name. ListVersions
+// preallocates its limit, and no early build kept histories near this long.
+const migrateHistoryLimit = 10000
+
+// IsLegacyRoot reports whether root looks like a filestore written by early
+// builds. Opening any other directory with the Store would create its layout.
+func IsLegacyRoot(root string) bool {
+	info, err := os.Stat(filepath.Join(root, "names.json"))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// Migrate replays every named history in src into dst, oldest version first,
+// keeping refs, prevRef links, timestamps and tombstones. Versions dst already
+// has are counted as existing, so running it again resumes or does nothing.
+// A name whose history in dst has diverged is skipped from that point on.
+func Migrate(ctx context.Context, src *Store, dst Importer, dryRun bool) (MigrateReport, error) {
+	report := MigrateReport{Skipped: []MigrateIssue{}, DryRun: dryRun}
+	heads, err := src.List(ctx, "", math.MaxInt32)
+	if err != nil {
+		return report, fmt.Errorf("list legacy store: %w", err)
+	}
+	for _, head := range heads {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Names++
+		if err := migrateName(ctx, src, dst, head.Name, dryRun, &report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func migrateName(ctx context.Context, src *Store, dst Importer, name string, dryRun bool, report *MigrateReport) error {
+	chain, err := src.ListVersions(ctx, name, migrateHistoryLimit)
+	if err != nil {
+		return fmt.Errorf("list legacy versions of %s: %w", name, err)
+	}
+	if len(chain) == migrateHistoryLimit && strings.TrimSpace(chain[len(chain)-1].PrevRef) != "" {
+		report.Skipped = append(report.Skipped, MigrateIssue{Name: name, Ref: chain[len(chain)-1].PrevRef, Detail: fmt.Sprintf("history longer than %d versions; older versions skipped", migrateHistoryLimit)})
+	}
+	slices.Reverse(chain)
+
+	prev := ""
+	pending := false
+	for _, v := range chain {
+		report.Versions++
+		v.Name = name
+		exists, err := dst.HasVersion(ctx, v.Ref)
+		if err != nil {
+			return err
+		}
+		if exists {
+			report.Existing++
+			prev = v.Ref
+			continue
+		}
+
+		var data []byte
+		if !v.Tombstone {
+			_, data, err = src.Get(ctx, artifacts.Selector{Ref: v.Ref})
+			if errors.Is(err, artifacts.ErrNotFound) {
+				report.Skipped = append(report.Skipped, MigrateIssue{Name: name, Ref: v.Ref, Detail: "payload is missing"})
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("read legacy version %s: %w", v.Ref, err)
+			}
+			sum := sha256.Sum256(data)
+			digest := hex.EncodeToString(sum[:])
+			if recorded := strings.ToLower(strings.TrimSpace(v.SHA256)); recorded != "" && recorded != digest {
+				report.Skipped = append(report.Skipped, MigrateIssue{Name: name, Ref: v.Ref, Detail: "payload does not match recorded sha256"})
+				continue
+			}
+			v.SHA256 = digest
+			v.SizeBytes = int64(len(data))
+		}
+		v.PrevRef = prev
+
+		if dryRun {
+			if !pending {
+				latest, err := dst.LatestRef(ctx, name)
+				if err != nil {
+					return err
+				}
+				if latest != prev {
+					report.Skipped = append(report.Skipped, divergedIssue(name, v.Ref))
+					return nil
+				}
+			}
+			pending = true
+		} else if err := dst.Import(ctx, v, data); err != nil {
+			if errors.Is(err, artifacts.ErrConflict) {
+				report.Skipped = append(report.Skipped, divergedIssue(name, v.Ref))
+				return nil
+			}
+			return fmt.Errorf("import %s: %w", v.Ref, err)
+		}
+		report.Imported++
+		prev = v.Ref
+	}
+	return nil
+}
+
+func divergedIssue(name, ref string) MigrateIssue {
+	return MigrateIssue{Name: name, Ref: ref, Detail: "name already has newer history in the workspace; remaining versions skipped"}
+}
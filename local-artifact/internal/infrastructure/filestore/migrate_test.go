@@ -0,0 +1,85 @@
+package filestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/sqlite"
+)
+
+func saveLegacy(t *testing.T, store *Store, ref, name, payload string, tombstone bool) {
+	t.Helper()
+	a := newTextArtifact(ref, name, payload)
+	sum := sha256.Sum256([]byte(payload))
+	a.SHA256 = hex.EncodeToString(sum[:])
+	a.CreatedAt = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	a.Tombstone = tombstone
+	if _, err := store.Save(context.Background(), a, []byte(payload), artifacts.SaveOptions{}); err != nil {
+		t.Fatalf("save %s: %v", ref, err)
+	}
+}
+
+func TestMigrate_ReplaysHistoryIdempotently(t *testing.T) {
+	ctx := context.Background()
+	legacyRoot := t.TempDir()
+	legacy := New(legacyRoot)
+	saveLegacy(t, legacy, "20250601T000000Z-aaaaaaaaaaaaaaaa", "plan/a", "a1", false)
+	saveLegacy(t, legacy, "20250601T000001Z-aaaaaaaaaaaaaaab", "plan/a", "a2", false)
+	saveLegacy(t, legacy, "20250601T000000Z-bbbbbbbbbbbbbbbb", "plan/b", "b1", false)
+	saveLegacy(t, legacy, "20250601T000001Z-bbbbbbbbbbbbbbbc", "plan/b", "", true)
+	if !IsLegacyRoot(legacyRoot) || IsLegacyRoot(t.TempDir()) {
+		t.Fatalf("IsLegacyRoot mismatch")
+	}
+
+	repo, err := sqlite.NewArtifactRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("new repo: %v", err)
+	}
+	t.Cleanup(func() { _ = repo.Close() })
+
+	report, err := Migrate(ctx, legacy, repo, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if report.Names != 2 || report.Imported != 4 || len(report.Skipped) != 0 {
+		t.Fatalf("dry run report: %+v", report)
+	}
+	if _, err := repo.Resolve(ctx, "plan/a"); !errors.Is(err, artifacts.ErrNotFound) {
+		t.Fatalf("dry run wrote to workspace: err=%v", err)
+	}
+
+	report, err = Migrate(ctx, legacy, repo, false)
+	if err != nil || report.Imported != 4 || report.Existing != 0 {
+		t.Fatalf("migrate: report=%+v err=%v", report, err)
+	}
+	meta, data, err := repo.Get(ctx, artifacts.Selector{Name: "plan/a"})
+	if err != nil || string(data) != "a2" || meta.Ref != "20250601T000001Z-aaaaaaaaaaaaaaab" || meta.PrevRef != "20250601T000000Z-aaaaaaaaaaaaaaaa" {
+		t.Fatalf("plan/a after migrate: meta=%+v data=%q err=%v", meta, data, err)
+	}
+	if !meta.CreatedAt.Equal(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("createdAt not preserved: %v", meta.CreatedAt)
+	}
+	if _, err := repo.Resolve(ctx, "plan/b"); !errors.Is(err, artifacts.ErrNotFound) {
+		t.Fatalf("tombstoned plan/b should stay deleted: err=%v", err)
+	}
+
+	report, err = Migrate(ctx, legacy, repo, false)
+	if err != nil || report.Imported != 0 || report.Existing != 4 {
+		t.Fatalf("rerun: report=%+v err=%v", report, err)
+	}
+
+	// New legacy versions resume the chain; a workspace that moved on wins.
+	saveLegacy(t, legacy, "20250601T000002Z-aaaaaaaaaaaaaaac", "plan/a", "a3", false)
+	if _, err := repo.Delete(ctx, artifacts.Selector{Name: "plan/a"}); err != nil {
+		t.Fatalf("delete in workspace: %v", err)
+	}
+	report, err = Migrate(ctx, legacy, repo, false)
+	if err != nil || report.Imported != 0 || len(report.Skipped) != 1 || report.Skipped[0].Ref != "20250601T000002Z-aaaaaaaaaaaaaaac" {
+		t.Fatalf("diverged: report=%+v err=%v", report, err)
+	}
+}
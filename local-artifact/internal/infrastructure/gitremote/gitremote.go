@@ -0,0 +1,149 @@
+// Package gitremote finds the repository that contains a directory and its
+// remote URL by reading git's files directly, so no git binary is needed.
+package gitremote
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrNotRepository = errors.New("not inside a git repository")
+	ErrNoRemote      = errors.New("git repository has no remote")
+)
+
+// Repo is the repository around a directory. Remote is the URL of "origin",
+// or of the first remote by name when there is no origin.
+type Repo struct {
+	TopLevel string
+	Remote   string
+}
+
+// Locate walks up from dir to the nearest ".git" directory or file. Linked
+// worktrees and submodules are followed to the git directory that holds
+// their config.
+func Locate(dir string) (Repo, error) {
+	cur, err := filepath.Abs(dir)
+	if err != nil {
+		return Repo{}, err
+	}
+	for {
+		dotGit := filepath.Join(cur, ".git")
+		info, err := os.Stat(dotGit)
+		if err == nil {
+			gitDir := dotGit
+			if !info.IsDir() {
+				if gitDir, err = readGitFile(dotGit); err != nil {
+					return Repo{}, err
+				}
+			}
+			remote, err := readRemote(filepath.Join(commonDir(gitDir), "config"))
+			if err != nil {
+				return Repo{}, err
+			}
+			return Repo{TopLevel: cur, Remote: remote}, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return Repo{}, err
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return Repo{}, fmt.Errorf("%w: %s", ErrNotRepository, dir)
+		}
+		cur = parent
+	}
+}
+
+// readGitFile resolves a ".git" file ("gitdir: <path>"), as written for
+// linked worktrees and submodules.
+func readGitFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(b))
+	target, ok := strings.CutPrefix(line, "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("%s: unrecognized .git file", path)
+	}
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return filepath.Clean(target), nil
+}
+
+// commonDir returns the directory shared by every worktree of gitDir.
+func commonDir(gitDir string) string {
+	b, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := strings.TrimSpace(string(b))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return filepath.Clean(common)
+}
+
+// readRemote reads remote URLs from a git config file. Only the subset of
+// the format git writes for remotes is understood; includes are ignored.
+func readRemote(configPath string) (string, error) {
+	f, err := os.Open(configPath)
+	if err != nil {
+		return "", err
+	}
+	defer closeIgnore(f)
+
+	urls := map[string]string{}
+	remote := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			remote = ""
+			header := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "["), "]"))
+			section, name, ok := strings.Cut(header, " ")
+			if ok && strings.EqualFold(section, "remote") {
+				remote = strings.Trim(strings.TrimSpace(name), `"`)
+			}
+			continue
+		}
+		if remote == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "url") {
+			continue
+		}
+		if _, seen := urls[remote]; !seen {
+			urls[remote] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if url, ok := urls["origin"]; ok && url != "" {
+		return url, nil
+	}
+	names := make([]string, 0, len(urls))
+	for name, url := range urls {
+		if url != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", ErrNoRemote
+	}
+	sort.Strings(names)
+	return urls[names[0]], nil
+}
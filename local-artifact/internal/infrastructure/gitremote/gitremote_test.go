@@ -0,0 +1,81 @@
+package gitremote
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestLocate_FollowsWorktreesToTheSharedConfig(t *testing.T) {
+	base := t.TempDir()
+	main := filepath.Join(base, "main")
+	writeFile(t, filepath.Join(main, ".git", "config"), `[core]
+	bare = false
+[remote "upstream"]
+	url = https://example.com/fork/repo.git
+[remote "origin"]
+	url = git@github.com:Owner/Repo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+`)
+	writeFile(t, filepath.Join(main, ".git", "worktrees", "wt", "commondir"), "../..\n")
+	worktree := filepath.Join(base, "wt")
+	writeFile(t, filepath.Join(worktree, ".git"), "gitdir: "+filepath.Join(main, ".git", "worktrees", "wt")+"\n")
+	if err := os.MkdirAll(filepath.Join(worktree, "services", "api"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	for _, dir := range []string{main, filepath.Join(worktree, "services", "api")} {
+		repo, err := Locate(dir)
+		if err != nil {
+			t.Fatalf("locate %s: %v", dir, err)
+		}
+		if repo.Remote != "git@github.com:Owner/Repo.git" {
+			t.Fatalf("remote for %s = %q, want origin", dir, repo.Remote)
+		}
+	}
+	repo, err := Locate(filepath.Join(worktree, "services", "api"))
+	if err != nil || repo.TopLevel != worktree {
+		t.Fatalf("top level = %q (%v), want %q", repo.TopLevel, err, worktree)
+	}
+}
+
+func TestLocate_Errors(t *testing.T) {
+	if _, err := Locate(t.TempDir()); !errors.Is(err, ErrNotRepository) {
+		t.Fatalf("expected ErrNotRepository, got %v", err)
+	}
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".git", "config"), "[core]\n\tbare = false\n")
+	if _, err := Locate(dir); !errors.Is(err, ErrNoRemote) {
+		t.Fatalf("expected ErrNoRemote, got %v", err)
+	}
+}
+
+func TestNormalizeGitRemote_EquatesSpellings(t *testing.T) {
+	for _, raw := range []string{
+		"git@github.com:Owner/Repo.git",
+		"https://github.com/Owner/Repo",
+		"https://user@GitHub.com/Owner/Repo.git/",
+		"ssh://git@github.com:22/Owner/Repo.git",
+	} {
+		got, err := workspaces.NormalizeGitRemote(raw)
+		if err != nil || got != "github.com/Owner/Repo" {
+			t.Fatalf("NormalizeGitRemote(%q) = %q (%v)", raw, got, err)
+		}
+	}
+	if got, err := workspaces.NormalizeGitRemote("/srv/git/repo.git"); err != nil || got != "/srv/git/repo" {
+		t.Fatalf("local remote normalized to %q (%v)", got, err)
+	}
+}
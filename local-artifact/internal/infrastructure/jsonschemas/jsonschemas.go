@@ -0,0 +1,65 @@
+// Package jsonschemas validates JSON artifacts against the JSON Schemas that
+// settings.json registers per name pattern.
+package jsonschemas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	jsonschema "github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+// Registry implements artifacts.JSONValidator. Patterns follow
+// artifacts.MatchNamePattern; a pattern without a schema exempts the names it
+// matches from a broader one.
+type Registry struct {
+	schemas map[string]*jsonschema.Schema
+}
+
+// Compile compiles every schema up front, so that a bad schema stops the
+// daemon from starting instead of failing saves later.
+func Compile(schemas map[string]json.RawMessage) (*Registry, error) {
+	r := &Registry{schemas: make(map[string]*jsonschema.Schema, len(schemas))}
+	for pattern, raw := range schemas {
+		if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			r.schemas[pattern] = nil
+			continue
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("json schema for %s: %w", pattern, err)
+		}
+		url := "artifact-schema:///" + pattern
+		compiler := jsonschema.NewCompiler()
+		if err := compiler.AddResource(url, doc); err != nil {
+			return nil, fmt.Errorf("json schema for %s: %w", pattern, err)
+		}
+		schema, err := compiler.Compile(url)
+		if err != nil {
+			return nil, fmt.Errorf("json schema for %s: %w", pattern, err)
+		}
+		r.schemas[pattern] = schema
+	}
+	return r, nil
+}
+
+func (r *Registry) ValidateJSON(name string, data []byte) error {
+	if r == nil {
+		return nil
+	}
+	pattern, schema, ok := artifacts.MatchNamePattern(r.schemas, name)
+	if !ok || schema == nil {
+		return nil
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %s must be JSON matching the schema for %s", artifacts.ErrInvalidInput, name, pattern)
+	}
+	if err := schema.Validate(doc); err != nil {
+		return fmt.Errorf("%w: %s does not match the schema for %s: %v", artifacts.ErrInvalidInput, name, pattern, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package jsonschemas
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+func TestRegistry_ValidatesByNamePattern(t *testing.T) {
+	r, err := Compile(map[string]json.RawMessage{
+		"review/*":       json.RawMessage(`{"type":"object","required":["verdict"],"properties":{"verdict":{"enum":["approved","rejected"]}}}`),
+		"review/draft/*": json.RawMessage(`null`),
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	if err := r.ValidateJSON("review/r1", []byte(`{"verdict":"approved"}`)); err != nil {
+		t.Fatalf("expected valid document, got %v", err)
+	}
+	err = r.ValidateJSON("review/r1", []byte(`{"verdict":"maybe"}`))
+	if !errors.Is(err, artifacts.ErrInvalidInput) || !strings.Contains(err.Error(), "review/*") {
+		t.Fatalf("expected schema violation naming the pattern, got %v", err)
+	}
+	if err := r.ValidateJSON("review/r1", []byte(`approved`)); !errors.Is(err, artifacts.ErrInvalidInput) {
+		t.Fatalf("expected non-JSON payload to be rejected, got %v", err)
+	}
+	for _, name := range []string{"review/draft/r2", "plan/p1"} {
+		if err := r.ValidateJSON(name, []byte(`not json`)); err != nil {
+			t.Fatalf("expected %s to be exempt, got %v", name, err)
+		}
+	}
+}
+
+func TestCompile_RejectsInvalidSchema(t *testing.T) {
+	if _, err := Compile(map[string]json.RawMessage{"x/*": json.RawMessage(`{"type":"nonsense"}`)}); err == nil || !strings.Contains(err.Error(), "x/*") {
+		t.Fatalf("expected invalid schema to fail, got %v", err)
+	}
+}
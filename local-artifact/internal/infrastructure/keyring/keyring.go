@@ -0,0 +1,397 @@
+// Package keyring manages the keys behind encrypted blob stores. A daemon
+// holds one master key in its state dir; every workspace gets a random data
+// key that is stored on disk wrapped (AES-GCM encrypted) by the master key.
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	MasterKeyFileName = "master.key"
+	// nextMasterKeyFileName holds the new master key while a rotation is in
+	// progress. Its presence at startup means a rotation was interrupted.
+	nextMasterKeyFileName = "master.key.next"
+	WorkspaceKeyFileName  = "keyring.json"
+
+	keySize         = 32
+	wrapLabel       = "ccsubagents workspace data key v1"
+	fingerprintInfo = "ccsubagents master key id"
+)
+
+var (
+	// ErrMasterKeyMissing means a workspace has a wrapped data key but no
+	// loaded master key can unwrap it.
+	ErrMasterKeyMissing = errors.New("master key for encrypted workspace is not available")
+	// ErrKeyFilePermissions means a key file can be read by other users.
+	ErrKeyFilePermissions = errors.New("key file is readable by other users")
+)
+
+type masterKey struct {
+	id  string
+	key []byte
+}
+
+// Keyring holds the master keys loaded from a daemon state dir.
+type Keyring struct {
+	dir string
+
+	mu      sync.RWMutex
+	masters []masterKey // current first, then an interrupted rotation's next key
+}
+
+type workspaceKeyFile struct {
+	Version     int    `json:"version"`
+	MasterKeyID string `json:"masterKeyId"`
+	WrappedKey  string `json:"wrappedKey"`
+}
+
+// RotateReport lists what a master key rotation changed.
+type RotateReport struct {
+	MasterKeyID string   `json:"masterKeyId"`
+	Rewrapped   []string `json:"rewrapped"`
+}
+
+// Open loads the master keys kept in dir. A missing key file is not an error:
+// the keyring is then disabled until Enable creates one.
+func Open(dir string) (*Keyring, error) {
+	k := &Keyring{dir: dir}
+	for _, name := range []string{MasterKeyFileName, nextMasterKeyFileName} {
+		mk, err := readMasterKey(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		k.masters = append(k.masters, mk)
+	}
+	return k, nil
+}
+
+// MasterKeyPath returns the master key file location for a state dir.
+func MasterKeyPath(dir string) string {
+	return filepath.Join(dir, MasterKeyFileName)
+}
+
+// Enable creates the master key file if the keyring has none yet.
+func (k *Keyring) Enable() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.masters) > 0 {
+		return nil
+	}
+	mk, err := createMasterKey(MasterKeyPath(k.dir))
+	if err != nil {
+		return err
+	}
+	k.masters = []masterKey{mk}
+	return nil
+}
+
+// Enabled reports whether a master key is loaded.
+func (k *Keyring) Enabled() bool {
+	if k == nil {
+		return false
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.masters) > 0
+}
+
+// MasterKeyID returns the fingerprint of the current master key, or "".
+func (k *Keyring) MasterKeyID() string {
+	if k == nil {
+		return ""
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if len(k.masters) == 0 {
+		return ""
+	}
+	return k.masters[0].id
+}
+
+// DataKey returns the data key of the workspace stored at root. With create
+// it generates and wraps one when the workspace has none; otherwise it
+// returns nil for a workspace that was never encrypted.
+func (k *Keyring) DataKey(root string, create bool) ([]byte, error) {
+	path := filepath.Join(root, WorkspaceKeyFileName)
+	if k == nil {
+		k = &Keyring{}
+	}
+	// Holding the read lock keeps Rotate from retiring the master key that
+	// wraps a key file written here.
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for {
+		file, err := readWorkspaceKeyFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			if !create {
+				return nil, nil
+			}
+			dataKey, err := k.createDataKeyLocked(path)
+			if errors.Is(err, os.ErrExist) {
+				// Another opener won the race; use its key.
+				create = false
+				continue
+			}
+			return dataKey, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, mk := range k.masters {
+			if mk.id == file.MasterKeyID {
+				return unwrapKey(mk.key, file.WrappedKey)
+			}
+		}
+		return nil, fmt.Errorf("%w: %s is wrapped by master key %s", ErrMasterKeyMissing, path, file.MasterKeyID)
+	}
+}
+
+func (k *Keyring) createDataKeyLocked(path string) ([]byte, error) {
+	if len(k.masters) == 0 {
+		return nil, fmt.Errorf("%w: cannot create %s", ErrMasterKeyMissing, path)
+	}
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	if err := writeWorkspaceKeyFile(path, k.masters[0], dataKey, true); err != nil {
+		return nil, err
+	}
+	return dataKey, nil
+}
+
+// Rotate replaces the master key and rewraps the data key of every workspace
+// root given. Data keys and blobs are unchanged. An interrupted rotation is
+// finished by running Rotate again, which reuses the pending key.
+func (k *Keyring) Rotate(roots []string) (RotateReport, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.masters) == 0 {
+		return RotateReport{}, errors.New("encryption is not enabled: no master key")
+	}
+
+	nextPath := filepath.Join(k.dir, nextMasterKeyFileName)
+	next, err := readMasterKey(nextPath)
+	if errors.Is(err, os.ErrNotExist) {
+		next, err = createMasterKey(nextPath)
+	}
+	if err != nil {
+		return RotateReport{}, err
+	}
+	masters := []masterKey{k.masters[0], next}
+	if len(k.masters) > 1 && k.masters[1].id != next.id {
+		masters = append(masters, k.masters[1])
+	}
+	k.masters = masters
+
+	report := RotateReport{MasterKeyID: next.id, Rewrapped: []string{}}
+	for _, root := range roots {
+		path := filepath.Join(root, WorkspaceKeyFileName)
+		file, err := readWorkspaceKeyFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		if file.MasterKeyID == next.id {
+			continue
+		}
+		var dataKey []byte
+		for _, mk := range masters {
+			if mk.id == file.MasterKeyID {
+				dataKey, err = unwrapKey(mk.key, file.WrappedKey)
+				break
+			}
+		}
+		if err != nil {
+			return report, fmt.Errorf("unwrap %s: %w", path, err)
+		}
+		if dataKey == nil {
+			return report, fmt.Errorf("%w: %s is wrapped by master key %s", ErrMasterKeyMissing, path, file.MasterKeyID)
+		}
+		if err := writeWorkspaceKeyFile(path, next, dataKey, false); err != nil {
+			return report, err
+		}
+		report.Rewrapped = append(report.Rewrapped, root)
+	}
+
+	if err := os.Rename(nextPath, MasterKeyPath(k.dir)); err != nil {
+		return report, err
+	}
+	k.masters = []masterKey{next}
+	return report, nil
+}
+
+func readMasterKey(path string) (masterKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return masterKey{}, err
+	}
+	if err := checkKeyFileMode(path, info); err != nil {
+		return masterKey{}, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return masterKey{}, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(key) != keySize {
+		return masterKey{}, fmt.Errorf("master key %s must hold %d hex-encoded bytes", path, keySize)
+	}
+	return masterKey{id: fingerprint(key), key: key}, nil
+}
+
+func createMasterKey(path string) (masterKey, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return masterKey{}, err
+	}
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return masterKey{}, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return masterKey{}, err
+	}
+	if _, err := f.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		closeIgnore(f)
+		return masterKey{}, err
+	}
+	if err := f.Sync(); err != nil {
+		closeIgnore(f)
+		return masterKey{}, err
+	}
+	if err := f.Close(); err != nil {
+		return masterKey{}, err
+	}
+	return masterKey{id: fingerprint(key), key: key}, nil
+}
+
+// checkKeyFileMode applies the daemon.token rule: no access for group or
+// others. Windows has no such mode bits.
+func checkKeyFileMode(path string, info os.FileInfo) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if mode := info.Mode().Perm(); mode&0o077 != 0 {
+		return fmt.Errorf("%w: %s has mode %#o, run chmod 600 on it", ErrKeyFilePermissions, path, mode)
+	}
+	return nil
+}
+
+func fingerprint(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(fingerprintInfo))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func readWorkspaceKeyFile(path string) (workspaceKeyFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return workspaceKeyFile{}, err
+	}
+	var file workspaceKeyFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return workspaceKeyFile{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if file.Version != 1 || file.MasterKeyID == "" || file.WrappedKey == "" {
+		return workspaceKeyFile{}, fmt.Errorf("parse %s: unsupported key file", path)
+	}
+	return file, nil
+}
+
+// writeWorkspaceKeyFile wraps dataKey with mk and writes it atomically. With
+// exclusive it fails with os.ErrExist instead of replacing a key file.
+func writeWorkspaceKeyFile(path string, mk masterKey, dataKey []byte, exclusive bool) error {
+	wrapped, err := wrapKey(mk.key, dataKey)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(workspaceKeyFile{Version: 1, MasterKeyID: mk.id, WrappedKey: wrapped}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".keyring-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer removeIfExists(tmpName)
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		closeIgnore(tmp)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		closeIgnore(tmp)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if exclusive {
+		// A hard link fails if the target exists, unlike rename.
+		if err := os.Link(tmpName, path); err != nil {
+			return err
+		}
+		return nil
+	}
+	return os.Rename(tmpName, path)
+}
+
+func wrapKey(master, dataKey []byte) (string, error) {
+	aead, err := newAEAD(master)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, dataKey, []byte(wrapLabel))
+	return hex.EncodeToString(sealed), nil
+}
+
+func unwrapKey(master []byte, wrapped string) ([]byte, error) {
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	b, err := hex.DecodeString(wrapped)
+	if err != nil || len(b) < aead.NonceSize() {
+		return nil, errors.New("malformed wrapped key")
+	}
+	dataKey, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(wrapLabel))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,99 @@
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestKeyringDataKeyLifecycleAndRotation(t *testing.T) {
+	stateDir := t.TempDir()
+	workspace := t.TempDir()
+
+	k, err := Open(stateDir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if k.Enabled() {
+		t.Fatalf("keyring without master key should be disabled")
+	}
+	if key, err := k.DataKey(workspace, false); err != nil || key != nil {
+		t.Fatalf("unencrypted workspace: key=%x err=%v", key, err)
+	}
+	if _, err := k.DataKey(workspace, true); !errors.Is(err, ErrMasterKeyMissing) {
+		t.Fatalf("creating a data key without master key: err=%v", err)
+	}
+
+	if err := k.Enable(); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	info, err := os.Stat(MasterKeyPath(stateDir))
+	if err != nil {
+		t.Fatalf("stat master key: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("master key mode=%#o want 0600", info.Mode().Perm())
+	}
+	dataKey, err := k.DataKey(workspace, true)
+	if err != nil || len(dataKey) != keySize {
+		t.Fatalf("create data key: len=%d err=%v", len(dataKey), err)
+	}
+	oldID := k.MasterKeyID()
+
+	report, err := k.Rotate([]string{workspace, t.TempDir()})
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if report.MasterKeyID == oldID || len(report.Rewrapped) != 1 || report.Rewrapped[0] != workspace {
+		t.Fatalf("unexpected rotate report: %+v (old id %s)", report, oldID)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, nextMasterKeyFileName)); !os.IsNotExist(err) {
+		t.Fatalf("pending master key left behind: %v", err)
+	}
+
+	reopened, err := Open(stateDir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if reopened.MasterKeyID() != report.MasterKeyID {
+		t.Fatalf("reopened master key id=%s want %s", reopened.MasterKeyID(), report.MasterKeyID)
+	}
+	got, err := reopened.DataKey(workspace, false)
+	if err != nil || !bytes.Equal(got, dataKey) {
+		t.Fatalf("data key changed across rotation: err=%v", err)
+	}
+
+	other, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("open other: %v", err)
+	}
+	if err := other.Enable(); err != nil {
+		t.Fatalf("enable other: %v", err)
+	}
+	if _, err := other.DataKey(workspace, true); !errors.Is(err, ErrMasterKeyMissing) {
+		t.Fatalf("foreign master key should not unwrap: err=%v", err)
+	}
+}
+
+func TestKeyringRejectsReadableMasterKey(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on windows")
+	}
+	stateDir := t.TempDir()
+	k, err := Open(stateDir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := k.Enable(); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	if err := os.Chmod(MasterKeyPath(stateDir), 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if _, err := Open(stateDir); !errors.Is(err, ErrKeyFilePermissions) {
+		t.Fatalf("expected permission error, got %v", err)
+	}
+}
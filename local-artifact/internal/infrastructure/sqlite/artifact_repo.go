@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
@@ -20,6 +21,7 @@ type ArtifactRepository struct {
 	workspaceRoot string
 	db            *sql.DB
 	blobs         *blobstore.Store
+	compression   blobstore.CompressionPolicy
 }
 
 func NewArtifactRepository(workspaceRoot string) (*ArtifactRepository, error) {
@@ -41,21 +43,25 @@ func (r *ArtifactRepository) Close() error {
 	return r.db.Close()
 }
 
-func (r *ArtifactRepository) Save(ctx context.Context, a artifacts.ArtifactVersion, data []byte, opts artifacts.SaveOptions) (artifacts.ArtifactVersion, error) {
-	if data == nil {
-		data = []byte{}
-	}
-	if a.CreatedAt.IsZero() {
-		a.CreatedAt = time.Now().UTC().Truncate(time.Second)
-	}
+// SetCompression picks the on-disk encoding of new blobs by MIME type. Call it
+// before the repository is shared; existing blobs keep their encoding.
+func (r *ArtifactRepository) SetCompression(policy blobstore.CompressionPolicy) {
+	r.compression = policy
+}
 
-	if !a.Tombstone {
-		if strings.TrimSpace(a.SHA256) == "" {
-			return artifacts.ArtifactVersion{}, fmt.Errorf("%w: sha256 is required", artifacts.ErrInvalidInput)
-		}
-		if err := r.blobs.Put(a.SHA256, data); err != nil {
-			return artifacts.ArtifactVersion{}, err
-		}
+// SetSealer lets the repository read encrypted blobs and, with sealWrites,
+// encrypt new ones. Like SetCompression, call it before sharing the repository.
+func (r *ArtifactRepository) SetSealer(sealer *blobstore.Sealer, sealWrites bool) {
+	r.blobs.SetSealer(sealer, sealWrites)
+}
+
+func (r *ArtifactRepository) Checkpoint(ctx context.Context) error {
+	return checkpointWAL(ctx, r.db)
+}
+
+func (r *ArtifactRepository) Save(ctx context.Context, a artifacts.ArtifactVersion, data []byte, opts artifacts.SaveOptions) (artifacts.ArtifactVersion, error) {
+	if err := r.storePayload(&a, data); err != nil {
+		return artifacts.ArtifactVersion{}, err
 	}
 
 	var lastErr error
@@ -68,6 +74,7 @@ func (r *ArtifactRepository) Save(ctx context.Context, a artifacts.ArtifactVersi
 			return artifacts.ArtifactVersion{}, err
 		}
 		lastErr = err
+		busyRetries.Add(1)
 		time.Sleep(time.Duration(10+attempt*20) * time.Millisecond)
 	}
 	if lastErr != nil {
@@ -76,6 +83,24 @@ func (r *ArtifactRepository) Save(ctx context.Context, a artifacts.ArtifactVersi
 	return artifacts.ArtifactVersion{}, fmt.Errorf("%w: save failed", artifacts.ErrInternal)
 }
 
+// storePayload writes the blob of a version before its metadata, so a crash
+// in between leaves at most an orphan blob.
+func (r *ArtifactRepository) storePayload(a *artifacts.ArtifactVersion, data []byte) error {
+	if data == nil {
+		data = []byte{}
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+	if a.Tombstone {
+		return nil
+	}
+	if strings.TrimSpace(a.SHA256) == "" {
+		return fmt.Errorf("%w: sha256 is required", artifacts.ErrInvalidInput)
+	}
+	return r.blobs.PutEncoded(a.SHA256, data, r.compression.EncodingFor(a.MimeType))
+}
+
 func (r *ArtifactRepository) saveOnce(ctx context.Context, a artifacts.ArtifactVersion, opts artifacts.SaveOptions) (artifacts.ArtifactVersion, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -83,6 +108,17 @@ func (r *ArtifactRepository) saveOnce(ctx context.Context, a artifacts.ArtifactV
 	}
 	defer rollbackIgnore(tx)
 
+	out, err := saveInTx(ctx, tx, a, opts.ExpectedPrevRef, opts.Quota)
+	if err != nil {
+		return artifacts.ArtifactVersion{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return artifacts.ArtifactVersion{}, err
+	}
+	return out, nil
+}
+
+func saveInTx(ctx context.Context, tx *sql.Tx, a artifacts.ArtifactVersion, expectedPrevRef string, quota artifacts.Quota) (artifacts.ArtifactVersion, error) {
 	now := a.CreatedAt.UTC().Format(time.RFC3339)
 	if _, err := tx.ExecContext(ctx, `
 		INSERT INTO artifacts(name, latest_version_id, deleted, created_at, updated_at, deleted_at)
@@ -91,6 +127,13 @@ func (r *ArtifactRepository) saveOnce(ctx context.Context, a artifacts.ArtifactV
 	`, a.Name, now, now); err != nil {
 		return artifacts.ArtifactVersion{}, err
 	}
+	// Transactions begin immediate, so no other write can commit between
+	// this check and the insert below.
+	if !a.Tombstone {
+		if err := artifacts.CheckStoredQuota(ctx, usageQueries{tx}, quota, a.Name, a.SHA256, a.SizeBytes); err != nil {
+			return artifacts.ArtifactVersion{}, err
+		}
+	}
 
 	var currentLatest sql.NullString
 	var deleted int
@@ -101,7 +144,7 @@ func (r *ArtifactRepository) saveOnce(ctx context.Context, a artifacts.ArtifactV
 		return artifacts.ArtifactVersion{}, err
 	}
 
-	expected := strings.TrimSpace(opts.ExpectedPrevRef)
+	expected := strings.TrimSpace(expectedPrevRef)
 	if expected != "" {
 		current := strings.TrimSpace(currentLatest.String)
 		if expected != current {
@@ -132,6 +175,15 @@ func (r *ArtifactRepository) saveOnce(ctx context.Context, a artifacts.ArtifactV
 		}
 		return artifacts.ArtifactVersion{}, err
 	}
+	if a.ExpiresAt != nil {
+		expiresAt := a.ExpiresAt.UTC().Truncate(time.Second)
+		a.ExpiresAt = &expiresAt
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO version_expiry(version_id, expires_at) VALUES (?, ?);
+		`, a.Ref, expiresAt.Format(time.RFC3339)); err != nil {
+			return artifacts.ArtifactVersion{}, err
+		}
+	}
 
 	if _, err := tx.ExecContext(ctx, `
 		UPDATE artifacts
@@ -140,10 +192,6 @@ func (r *ArtifactRepository) saveOnce(ctx context.Context, a artifacts.ArtifactV
 	`, a.Ref, a.CreatedAt.UTC().Format(time.RFC3339), a.Name); err != nil {
 		return artifacts.ArtifactVersion{}, err
 	}
-
-	if err := tx.Commit(); err != nil {
-		return artifacts.ArtifactVersion{}, err
-	}
 	return a, nil
 }
 
@@ -207,9 +255,10 @@ func (r *ArtifactRepository) List(ctx context.Context, prefix string, limit int)
 		pattern = escapeLikePrefix(prefix) + "%"
 	}
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT v.version_id, v.name, v.parent_version_id, v.kind, v.mime_type, v.filename, v.size_bytes, v.payload_sha256, v.created_at, v.tombstone
+		SELECT v.version_id, v.name, v.parent_version_id, v.kind, v.mime_type, v.filename, v.size_bytes, v.payload_sha256, v.created_at, v.tombstone, x.expires_at
 		FROM artifacts a
 		JOIN versions v ON v.version_id = a.latest_version_id
+		LEFT JOIN version_expiry x ON x.version_id = v.version_id
 		WHERE a.deleted = 0 AND a.name LIKE ? ESCAPE '\'
 		ORDER BY a.name ASC
 		LIMIT ?;
@@ -287,6 +336,7 @@ func (r *ArtifactRepository) Delete(ctx context.Context, sel artifacts.Selector)
 			return artifacts.ArtifactVersion{}, err
 		}
 		lastErr = err
+		busyRetries.Add(1)
 		time.Sleep(time.Duration(10+attempt*20) * time.Millisecond)
 	}
 	if lastErr != nil {
@@ -302,6 +352,17 @@ func (r *ArtifactRepository) deleteOnce(ctx context.Context, sel artifacts.Selec
 	}
 	defer rollbackIgnore(tx)
 
+	out, err := deleteInTx(ctx, tx, sel, "")
+	if err != nil {
+		return artifacts.ArtifactVersion{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return artifacts.ArtifactVersion{}, err
+	}
+	return out, nil
+}
+
+func deleteInTx(ctx context.Context, tx *sql.Tx, sel artifacts.Selector, expectedPrevRef string) (artifacts.ArtifactVersion, error) {
 	name := strings.TrimSpace(sel.Name)
 	ref := strings.TrimSpace(sel.Ref)
 	if name == "" {
@@ -330,6 +391,9 @@ func (r *ArtifactRepository) deleteOnce(ctx context.Context, sel artifacts.Selec
 	if ref != "" && strings.TrimSpace(currentLatest.String) != ref {
 		return artifacts.ArtifactVersion{}, artifacts.ErrNotFound
 	}
+	if expected := strings.TrimSpace(expectedPrevRef); expected != "" && expected != strings.TrimSpace(currentLatest.String) {
+		return artifacts.ArtifactVersion{}, fmt.Errorf("%w: expectedPrevRef=%q current=%q", artifacts.ErrConflict, expected, strings.TrimSpace(currentLatest.String))
+	}
 
 	kind := artifacts.ArtifactKindText
 	mimeType := "application/octet-stream"
@@ -358,10 +422,6 @@ func (r *ArtifactRepository) deleteOnce(ctx context.Context, sel artifacts.Selec
 		return artifacts.ArtifactVersion{}, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return artifacts.ArtifactVersion{}, err
-	}
-
 	return artifacts.ArtifactVersion{
 		Ref:       tombRef,
 		Name:      name,
@@ -374,11 +434,78 @@ func (r *ArtifactRepository) deleteOnce(ctx context.Context, sel artifacts.Selec
 	}, nil
 }
 
+func (r *ArtifactRepository) Usage(ctx context.Context) (artifacts.Usage, error) {
+	return usageQueries{r.db}.Usage(ctx)
+}
+
+func (r *ArtifactRepository) VersionCount(ctx context.Context, name string) (int, error) {
+	return usageQueries{r.db}.VersionCount(ctx, name)
+}
+
+func (r *ArtifactRepository) HasPayload(ctx context.Context, sha256 string) (bool, error) {
+	return usageQueries{r.db}.HasPayload(ctx, sha256)
+}
+
+// usageQueries reports usage through a database or an open transaction; the
+// latter lets saves enforce quotas against what they are about to commit.
+type usageQueries struct {
+	q interface {
+		QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	}
+}
+
+func (u usageQueries) Usage(ctx context.Context) (artifacts.Usage, error) {
+	var usage artifacts.Usage
+	if err := u.q.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM artifacts WHERE deleted = 0 AND latest_version_id IS NOT NULL),
+			(SELECT COUNT(*) FROM versions WHERE tombstone = 0),
+			(SELECT COALESCE(SUM(size_bytes), 0) FROM (
+				SELECT MAX(size_bytes) AS size_bytes FROM versions
+				WHERE payload_sha256 IS NOT NULL
+				GROUP BY payload_sha256
+			));
+	`).Scan(&usage.Artifacts, &usage.Versions, &usage.Bytes); err != nil {
+		return artifacts.Usage{}, err
+	}
+	return usage, nil
+}
+
+func (u usageQueries) VersionCount(ctx context.Context, name string) (int, error) {
+	var count int
+	// Versions are never removed, so rowid order is insertion order and the
+	// latest tombstone marks where the current history starts.
+	if err := u.q.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM versions
+		WHERE name = ? AND tombstone = 0
+		AND rowid > COALESCE((SELECT MAX(rowid) FROM versions WHERE name = ? AND tombstone = 1), 0);
+	`, name, name).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (u usageQueries) HasPayload(ctx context.Context, sha256 string) (bool, error) {
+	var exists int
+	if err := u.q.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM versions WHERE payload_sha256 = ?);
+	`, strings.ToLower(strings.TrimSpace(sha256))).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists != 0, nil
+}
+
+// VersionMeta returns the version with ref without reading its payload.
+func (r *ArtifactRepository) VersionMeta(ctx context.Context, ref string) (artifacts.ArtifactVersion, error) {
+	return r.getVersionMeta(ctx, strings.TrimSpace(ref))
+}
+
 func (r *ArtifactRepository) getVersionMeta(ctx context.Context, ref string) (artifacts.ArtifactVersion, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT version_id, name, parent_version_id, kind, mime_type, filename, size_bytes, payload_sha256, created_at, tombstone
-		FROM versions
-		WHERE version_id = ?;
+		SELECT v.version_id, v.name, v.parent_version_id, v.kind, v.mime_type, v.filename, v.size_bytes, v.payload_sha256, v.created_at, v.tombstone, x.expires_at
+		FROM versions v
+		LEFT JOIN version_expiry x ON x.version_id = v.version_id
+		WHERE v.version_id = ?;
 	`, ref)
 	a, err := scanVersionRow(row)
 	if err != nil {
@@ -399,11 +526,12 @@ func scanVersion(rows *sql.Rows) (artifacts.ArtifactVersion, error) {
 		sha       sql.NullString
 		createdAt string
 		tomb      int
+		expiresAt sql.NullString
 	)
-	if err := rows.Scan(&versionID, &name, &parent, &kind, &mimeType, &filename, &sizeBytes, &sha, &createdAt, &tomb); err != nil {
+	if err := rows.Scan(&versionID, &name, &parent, &kind, &mimeType, &filename, &sizeBytes, &sha, &createdAt, &tomb, &expiresAt); err != nil {
 		return artifacts.ArtifactVersion{}, err
 	}
-	return buildVersion(versionID, name, parent, kind, mimeType, filename, sizeBytes, sha, createdAt, tomb)
+	return buildVersion(versionID, name, parent, kind, mimeType, filename, sizeBytes, sha, createdAt, tomb, expiresAt)
 }
 
 func scanVersionRow(row *sql.Row) (artifacts.ArtifactVersion, error) {
@@ -418,21 +546,30 @@ func scanVersionRow(row *sql.Row) (artifacts.ArtifactVersion, error) {
 		sha       sql.NullString
 		createdAt string
 		tomb      int
+		expiresAt sql.NullString
 	)
-	if err := row.Scan(&versionID, &name, &parent, &kind, &mimeType, &filename, &sizeBytes, &sha, &createdAt, &tomb); err != nil {
+	if err := row.Scan(&versionID, &name, &parent, &kind, &mimeType, &filename, &sizeBytes, &sha, &createdAt, &tomb, &expiresAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return artifacts.ArtifactVersion{}, artifacts.ErrNotFound
 		}
 		return artifacts.ArtifactVersion{}, err
 	}
-	return buildVersion(versionID, name, parent, kind, mimeType, filename, sizeBytes, sha, createdAt, tomb)
+	return buildVersion(versionID, name, parent, kind, mimeType, filename, sizeBytes, sha, createdAt, tomb, expiresAt)
 }
 
-func buildVersion(versionID, name string, parent sql.NullString, kind, mimeType, filename string, sizeBytes int64, sha sql.NullString, createdAt string, tomb int) (artifacts.ArtifactVersion, error) {
+func buildVersion(versionID, name string, parent sql.NullString, kind, mimeType, filename string, sizeBytes int64, sha sql.NullString, createdAt string, tomb int, expiresAt sql.NullString) (artifacts.ArtifactVersion, error) {
 	parsed, err := time.Parse(time.RFC3339, createdAt)
 	if err != nil {
 		return artifacts.ArtifactVersion{}, err
 	}
+	var expires *time.Time
+	if expiresAt.Valid {
+		t, err := time.Parse(time.RFC3339, expiresAt.String)
+		if err != nil {
+			return artifacts.ArtifactVersion{}, err
+		}
+		expires = &t
+	}
 	return artifacts.ArtifactVersion{
 		Ref:       versionID,
 		Name:      name,
@@ -444,6 +581,7 @@ func buildVersion(versionID, name string, parent sql.NullString, kind, mimeType,
 		SHA256:    strings.TrimSpace(sha.String),
 		CreatedAt: parsed,
 		Tombstone: tomb != 0,
+		ExpiresAt: expires,
 	}, nil
 }
 
@@ -467,6 +605,40 @@ func escapeLikePrefix(v string) string {
 	return b.String()
 }
 
+var busyRetries atomic.Uint64
+
+// BusyRetries reports how many writes were retried after SQLite returned a
+// busy or locked error, across every repository in the process.
+func BusyRetries() uint64 {
+	return busyRetries.Load()
+}
+
+// WorkspaceBlobUsage reports the size of the blob store of a workspace
+// without opening its database.
+func WorkspaceBlobUsage(workspaceRoot string) (blobstore.Usage, error) {
+	return blobstore.New(filepath.Join(workspaceRoot, "blobs")).Usage()
+}
+
+// WorkspaceUsage reports the quota usage of a workspace through a read-only
+// connection that is closed before returning, so callers can report on a
+// workspace without opening its repository. A workspace that has no
+// database yet has no usage.
+func WorkspaceUsage(ctx context.Context, workspaceRoot string) (artifacts.Usage, error) {
+	path := filepath.Join(workspaceRoot, "meta.sqlite")
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return artifacts.Usage{}, nil
+		}
+		return artifacts.Usage{}, err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return artifacts.Usage{}, err
+	}
+	defer closeDBIgnore(db)
+	return usageQueries{db}.Usage(ctx)
+}
+
 func isRetryableBusyErr(err error) bool {
 	if err == nil {
 		return false
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -71,3 +72,86 @@ func TestArtifactRepository_ConcurrentExpectedPrevRefOneConflict(t *testing.T) {
 		t.Fatalf("expected one success and one conflict, got successes=%d conflicts=%d", successes, conflicts)
 	}
 }
+
+func TestArtifactRepository_ConcurrentSavesRespectQuota(t *testing.T) {
+	repo := newArtifactRepo(t)
+	svc := artifacts.NewService(repo)
+	svc.SetQuota(artifacts.Quota{MaxTotalBytes: 10, MaxVersionsPerName: 3})
+	ctx := context.Background()
+
+	const writers = 8
+	errs := make([]error, writers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = svc.SaveText(ctx, artifacts.SaveTextInput{Name: "plan/quota-race", Text: fmt.Sprintf("%04d", i)})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	saved := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			saved++
+		case !errors.Is(err, artifacts.ErrQuotaExceeded):
+			t.Fatalf("unexpected save error: %v", err)
+		}
+	}
+	usage, err := repo.Usage(ctx)
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	if saved != 2 || usage.Bytes != 8 || usage.Versions != 2 {
+		t.Fatalf("saved=%d usage=%+v, want two 4-byte saves within the 10 byte quota", saved, usage)
+	}
+}
+
+func TestArtifactRepository_BatchesRacingSavesRespectQuota(t *testing.T) {
+	repo := newArtifactRepo(t)
+	svc := artifacts.NewService(repo)
+	svc.SetQuota(artifacts.Quota{MaxTotalBytes: 10})
+	ctx := context.Background()
+
+	const writers = 8
+	errs := make([]error, writers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if i%2 == 0 {
+				_, errs[i] = svc.SaveText(ctx, artifacts.SaveTextInput{Name: fmt.Sprintf("plan/save-%d", i), Text: fmt.Sprintf("s%02d", i)})
+				return
+			}
+			_, errs[i] = svc.Batch(ctx, []artifacts.BatchWrite{
+				{Text: &artifacts.SaveTextInput{Name: fmt.Sprintf("plan/batch-%d", i), Text: fmt.Sprintf("a%02d", i)}},
+				{Text: &artifacts.SaveTextInput{Name: fmt.Sprintf("plan/batch-%d", i), Text: fmt.Sprintf("b%02d", i)}},
+			})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, artifacts.ErrQuotaExceeded) {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+	}
+	usage, err := repo.Usage(ctx)
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	if usage.Bytes > 10 {
+		t.Fatalf("usage=%+v exceeds the 10 byte quota", usage)
+	}
+}
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -173,6 +174,83 @@ func TestArtifactRepository_DeleteByHeadRef_Tombstones(t *testing.T) {
 	}
 }
 
+func TestArtifactRepository_ExpireNamesTombstonesExpiredLatestVersions(t *testing.T) {
+	repo := newArtifactRepo(t)
+	ctx := context.Background()
+	created := time.Date(2026, 2, 16, 12, 0, 0, 0, time.UTC)
+	expiring := func(ref, name string, expiresAt time.Time) artifacts.ArtifactVersion {
+		v := makeVersion(ref, name, "text/plain", []byte(ref), created)
+		v.ExpiresAt = &expiresAt
+		out, err := repo.Save(ctx, v, []byte(ref), artifacts.SaveOptions{})
+		if err != nil {
+			t.Fatalf("save %s: %v", name, err)
+		}
+		return out
+	}
+
+	expiring("20260216T120000Z-aaaaaaaaaaaaaaaa", "scratch/due", created.Add(time.Hour))
+	expiring("20260216T120000Z-bbbbbbbbbbbbbbbb", "scratch/later", created.Add(3*time.Hour))
+	expiring("20260216T120000Z-cccccccccccccccc", "scratch/renewed", created.Add(time.Hour))
+	mustSaveVersion(t, ctx, repo, "20260216T120001Z-dddddddddddddddd", "scratch/renewed", "text/plain", []byte("kept"), created, artifacts.SaveOptions{})
+
+	got, _, err := repo.Get(ctx, artifacts.Selector{Name: "scratch/later"})
+	if err != nil || got.ExpiresAt == nil || !got.ExpiresAt.Equal(created.Add(3*time.Hour)) {
+		t.Fatalf("expected stored expiry, got %+v (%v)", got, err)
+	}
+
+	expired, err := repo.ExpireNames(ctx, created.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("expire names: %v", err)
+	}
+	if len(expired) != 1 || expired[0].Name != "scratch/due" || !expired[0].Tombstone {
+		t.Fatalf("unexpected expired names: %+v", expired)
+	}
+	if _, err := repo.Resolve(ctx, "scratch/due"); !errors.Is(err, artifacts.ErrNotFound) {
+		t.Fatalf("expected expired name to be gone, got %v", err)
+	}
+	for _, name := range []string{"scratch/later", "scratch/renewed"} {
+		if _, err := repo.Resolve(ctx, name); err != nil {
+			t.Fatalf("expected %s to survive: %v", name, err)
+		}
+	}
+}
+
+func TestArtifactRepository_BatchIsAllOrNothing(t *testing.T) {
+	repo := newArtifactRepo(t)
+	ctx := context.Background()
+	created := time.Date(2026, 2, 16, 12, 0, 0, 0, time.UTC)
+	put := func(ref, name, body string) artifacts.BatchOp {
+		return artifacts.BatchOp{Version: makeVersion(ref, name, "text/plain", []byte(body), created), Data: []byte(body)}
+	}
+
+	old := mustSaveVersion(t, ctx, repo, "20260216T120000Z-aaaaaaaaaaaaaaaa", "plan/old", "text/plain", []byte("old"), created, artifacts.SaveOptions{})
+	out, err := repo.Batch(ctx, []artifacts.BatchOp{
+		put("20260216T120001Z-bbbbbbbbbbbbbbbb", "plan/current", "plan v1"),
+		put("20260216T120001Z-cccccccccccccccc", "plan/current", "plan v2"),
+		{Delete: true, Selector: artifacts.Selector{Name: "plan/old"}, ExpectedPrevRef: old.Ref},
+	})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if len(out) != 3 || out[1].PrevRef != out[0].Ref || !out[2].Tombstone {
+		t.Fatalf("unexpected batch result: %+v", out)
+	}
+
+	_, err = repo.Batch(ctx, []artifacts.BatchOp{
+		put("20260216T120002Z-dddddddddddddddd", "plan/summary", "summary"),
+		{Delete: true, Selector: artifacts.Selector{Name: "plan/current"}, ExpectedPrevRef: out[0].Ref},
+	})
+	if !errors.Is(err, artifacts.ErrConflict) || !strings.Contains(err.Error(), "batch write 1") {
+		t.Fatalf("expected conflict on batch write 1, got %v", err)
+	}
+	if _, err := repo.Resolve(ctx, "plan/summary"); !errors.Is(err, artifacts.ErrNotFound) {
+		t.Fatalf("expected rolled back save, got %v", err)
+	}
+	if ref, err := repo.Resolve(ctx, "plan/current"); err != nil || ref != out[1].Ref {
+		t.Fatalf("expected plan/current at %q, got %q (%v)", out[1].Ref, ref, err)
+	}
+}
+
 func TestArtifactRepository_List_LiteralWildcardPrefixes(t *testing.T) {
 	ctx := context.Background()
 	tests := []struct {
@@ -229,3 +307,48 @@ func TestEscapeLikePrefix_EscapesSpecialCharacters(t *testing.T) {
 		t.Fatalf("escapeLikePrefix mismatch: got=%q want=%q", got, want)
 	}
 }
+
+func TestArtifactRepository_UsageAndVersionCount(t *testing.T) {
+	repo := newArtifactRepo(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	first := []byte("first")
+	second := []byte("second")
+	saves := []artifacts.ArtifactVersion{
+		makeVersion("20260216T120000Z-aaaaaaaaaaaaaaaa", "plan/a", "text/plain", first, now),
+		makeVersion("20260216T120001Z-bbbbbbbbbbbbbbbb", "plan/a", "text/plain", second, now),
+		makeVersion("20260216T120002Z-cccccccccccccccc", "plan/b", "text/plain", first, now),
+	}
+	payloads := [][]byte{first, second, first}
+	for i, v := range saves {
+		if _, err := repo.Save(ctx, v, payloads[i], artifacts.SaveOptions{}); err != nil {
+			t.Fatalf("save %s: %v", v.Ref, err)
+		}
+	}
+
+	usage, err := repo.Usage(ctx)
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	want := artifacts.Usage{Artifacts: 2, Versions: 3, Bytes: int64(len(first) + len(second))}
+	if usage != want {
+		t.Fatalf("usage=%+v want %+v", usage, want)
+	}
+	if ok, err := repo.HasPayload(ctx, shaFor(second)); err != nil || !ok {
+		t.Fatalf("HasPayload(second)=%v err=%v", ok, err)
+	}
+	if ok, err := repo.HasPayload(ctx, shaFor([]byte("missing"))); err != nil || ok {
+		t.Fatalf("HasPayload(missing)=%v err=%v", ok, err)
+	}
+
+	if count, err := repo.VersionCount(ctx, "plan/a"); err != nil || count != 2 {
+		t.Fatalf("VersionCount before delete=%d err=%v", count, err)
+	}
+	if _, err := repo.Delete(ctx, artifacts.Selector{Name: "plan/a"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if count, err := repo.VersionCount(ctx, "plan/a"); err != nil || count != 0 {
+		t.Fatalf("VersionCount after delete=%d err=%v", count, err)
+	}
+}
@@ -0,0 +1,240 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	msqlite "modernc.org/sqlite"
+)
+
+// SnapshotStats describes one workspace copied into a snapshot.
+type SnapshotStats struct {
+	Versions int   `json:"versions"`
+	Blobs    int   `json:"blobs"`
+	Bytes    int64 `json:"bytes"`
+	// Missing counts referenced blobs that were already gone; see Verify.
+	Missing int `json:"missing,omitempty"`
+}
+
+type onlineBackuper interface {
+	NewBackup(dstURI string) (*msqlite.Backup, error)
+	NewRestore(srcURI string) (*msqlite.Backup, error)
+}
+
+// backupDB copies db into a new database file at dst with SQLite's online
+// backup API, which yields a consistent copy while writers keep running.
+func backupDB(ctx context.Context, db *sql.DB, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("backup target %s already exists", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if err := withOnlineBackup(ctx, db, func(b onlineBackuper) (*msqlite.Backup, error) {
+		return b.NewBackup(dst)
+	}); err != nil {
+		return err
+	}
+	// The copy inherits WAL mode; switch it back so it is a single file.
+	copied, err := sql.Open("sqlite", dst)
+	if err != nil {
+		return err
+	}
+	defer closeDBIgnore(copied)
+	_, err = copied.ExecContext(ctx, `PRAGMA journal_mode = DELETE;`)
+	return err
+}
+
+// restoreDB overwrites the contents of db with the database file at src. Other
+// connections see the restored pages from their next transaction.
+func restoreDB(ctx context.Context, db *sql.DB, src string) error {
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+	return withOnlineBackup(ctx, db, func(b onlineBackuper) (*msqlite.Backup, error) {
+		return b.NewRestore(src)
+	})
+}
+
+func withOnlineBackup(ctx context.Context, db *sql.DB, start func(onlineBackuper) (*msqlite.Backup, error)) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeConnIgnore(conn)
+	return conn.Raw(func(driverConn any) error {
+		b, ok := driverConn.(onlineBackuper)
+		if !ok {
+			return errors.New("sqlite driver does not support online backup")
+		}
+		backup, err := start(b)
+		if err != nil {
+			return err
+		}
+		if _, err := backup.Step(-1); err != nil {
+			if finishErr := backup.Finish(); finishErr != nil {
+				_ = finishErr
+			}
+			return err
+		}
+		return backup.Finish()
+	})
+}
+
+// Backup writes a consistent copy of the registry database to dst.
+func (r *WorkspaceRegistry) Backup(ctx context.Context, dst string) error {
+	return backupDB(ctx, r.db, dst)
+}
+
+// Restore replaces the registry with the database file at src, upgrading it
+// if the snapshot predates the current schema.
+func (r *WorkspaceRegistry) Restore(ctx context.Context, src string) error {
+	if err := restoreDB(ctx, r.db, src); err != nil {
+		return err
+	}
+	return applySchema(ctx, r.db, registrySchema, registrySchemaVersion)
+}
+
+// Snapshot copies the workspace into dir: meta.sqlite through the online
+// backup API, then every blob the copied database references, hard-linked
+// when dir is on the same filesystem and copied otherwise. Blobs are never
+// rewritten in place, so a link is as stable as a copy.
+func (r *ArtifactRepository) Snapshot(ctx context.Context, dir string) (SnapshotStats, error) {
+	metaPath := filepath.Join(dir, "meta.sqlite")
+	if err := backupDB(ctx, r.db, metaPath); err != nil {
+		return SnapshotStats{}, fmt.Errorf("back up meta.sqlite: %w", err)
+	}
+
+	snapshot, err := sql.Open("sqlite", metaPath)
+	if err != nil {
+		return SnapshotStats{}, err
+	}
+	defer closeDBIgnore(snapshot)
+	var stats SnapshotStats
+	if err := snapshot.QueryRowContext(ctx, `SELECT COUNT(*) FROM versions;`).Scan(&stats.Versions); err != nil {
+		return SnapshotStats{}, err
+	}
+	rows, err := snapshot.QueryContext(ctx, `
+		SELECT DISTINCT payload_sha256 FROM versions
+		WHERE tombstone = 0 AND payload_sha256 IS NOT NULL AND payload_sha256 != '';
+	`)
+	if err != nil {
+		return SnapshotStats{}, err
+	}
+	defer closeRowsIgnore(rows)
+
+	blobRoot := filepath.Join(r.workspaceRoot, "blobs")
+	for rows.Next() {
+		var digest string
+		if err := rows.Scan(&digest); err != nil {
+			return SnapshotStats{}, err
+		}
+		path, err := r.blobs.Locate(digest)
+		if os.IsNotExist(err) {
+			stats.Missing++
+			continue
+		}
+		if err != nil {
+			return SnapshotStats{}, fmt.Errorf("locate blob %s: %w", digest, err)
+		}
+		rel, err := filepath.Rel(blobRoot, path)
+		if err != nil {
+			return SnapshotStats{}, err
+		}
+		size, err := linkOrCopy(path, filepath.Join(dir, "blobs", rel))
+		if err != nil {
+			return SnapshotStats{}, fmt.Errorf("copy blob %s: %w", digest, err)
+		}
+		stats.Blobs++
+		stats.Bytes += size
+	}
+	if err := rows.Err(); err != nil {
+		return SnapshotStats{}, err
+	}
+	return stats, nil
+}
+
+// Restore replaces the workspace with a copy made by Snapshot. Blobs are
+// added first, so every version the restored database names is readable;
+// blobs only the current state references are left for verify to collect.
+func (r *ArtifactRepository) Restore(ctx context.Context, dir string) error {
+	metaPath := filepath.Join(dir, "meta.sqlite")
+	if _, err := os.Stat(metaPath); err != nil {
+		return err
+	}
+	blobRoot := filepath.Join(r.workspaceRoot, "blobs")
+	snapshotBlobs := filepath.Join(dir, "blobs")
+	err := filepath.WalkDir(snapshotBlobs, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == snapshotBlobs {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(snapshotBlobs, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(blobRoot, rel)
+		if _, err := os.Lstat(target); err == nil {
+			return nil
+		}
+		_, err = linkOrCopy(path, target)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("restore blobs: %w", err)
+	}
+	if err := restoreDB(ctx, r.db, metaPath); err != nil {
+		return fmt.Errorf("restore meta.sqlite: %w", err)
+	}
+	return applySchema(ctx, r.db, metaSchema, metaSchemaVersion)
+}
+
+func linkOrCopy(src, dst string) (int64, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return 0, err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return info.Size(), nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer closeFileIgnore(in)
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".blob-*")
+	if err != nil {
+		return 0, err
+	}
+	tmpName := tmp.Name()
+	defer removeIfExists(tmpName)
+	n, err := io.Copy(tmp, in)
+	if err != nil {
+		closeFileIgnore(tmp)
+		return 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		closeFileIgnore(tmp)
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	return n, os.Rename(tmpName, dst)
+}
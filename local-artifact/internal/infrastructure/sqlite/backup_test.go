@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+func TestArtifactRepository_SnapshotAndRestore(t *testing.T) {
+	repo := newArtifactRepo(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	firstRef := "20260216T120000Z-aaaaaaaaaaaaaaaa"
+	laterRef := "20260216T120001Z-bbbbbbbbbbbbbbbb"
+	mustSaveVersion(t, ctx, repo, firstRef, "plan/a", "text/plain", []byte("a1"), now, artifacts.SaveOptions{})
+	mustSaveVersion(t, ctx, repo, "20260216T120000Z-cccccccccccccccc", "plan/b", "text/plain", []byte("a1"), now, artifacts.SaveOptions{})
+
+	dir := filepath.Join(t.TempDir(), "snap")
+	stats, err := repo.Snapshot(ctx, dir)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if stats.Versions != 2 || stats.Blobs != 1 || stats.Bytes != 2 || stats.Missing != 0 {
+		t.Fatalf("unexpected snapshot stats: %+v", stats)
+	}
+	if _, err := repo.Snapshot(ctx, dir); err == nil {
+		t.Fatalf("expected snapshot into an existing directory to fail")
+	}
+
+	mustSaveVersion(t, ctx, repo, laterRef, "plan/a", "text/plain", []byte("a2"), now.Add(time.Second), artifacts.SaveOptions{})
+	if _, err := repo.Delete(ctx, artifacts.Selector{Name: "plan/b"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	restored := newArtifactRepo(t)
+	if err := restored.Restore(ctx, dir); err != nil {
+		t.Fatalf("restore into empty workspace: %v", err)
+	}
+	if err := repo.Restore(ctx, dir); err != nil {
+		t.Fatalf("restore in place: %v", err)
+	}
+	for _, r := range []*ArtifactRepository{repo, restored} {
+		for _, name := range []string{"plan/a", "plan/b"} {
+			got, data, err := r.Get(ctx, artifacts.Selector{Name: name})
+			if err != nil {
+				t.Fatalf("get %s after restore: %v", name, err)
+			}
+			if string(data) != "a1" || got.Ref == laterRef {
+				t.Fatalf("unexpected %s after restore: ref=%s data=%q", name, got.Ref, data)
+			}
+		}
+	}
+}
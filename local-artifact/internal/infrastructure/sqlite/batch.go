@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+// Batch applies ops in one transaction. Blobs are written first, as in Save,
+// so a failed batch can leave unreferenced blobs but never partial metadata.
+func (r *ArtifactRepository) Batch(ctx context.Context, ops []artifacts.BatchOp) ([]artifacts.ArtifactVersion, error) {
+	for i := range ops {
+		if ops[i].Delete {
+			continue
+		}
+		if err := r.storePayload(&ops[i].Version, ops[i].Data); err != nil {
+			return nil, fmt.Errorf("batch write %d: %w", i, err)
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		out, err := r.batchOnce(ctx, ops)
+		if err == nil {
+			return out, nil
+		}
+		if !isRetryableBusyErr(err) {
+			return nil, err
+		}
+		lastErr = err
+		busyRetries.Add(1)
+		time.Sleep(time.Duration(10+attempt*20) * time.Millisecond)
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("%w: batch failed", artifacts.ErrInternal)
+}
+
+func (r *ArtifactRepository) batchOnce(ctx context.Context, ops []artifacts.BatchOp) ([]artifacts.ArtifactVersion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackIgnore(tx)
+
+	out := make([]artifacts.ArtifactVersion, 0, len(ops))
+	for i, op := range ops {
+		var a artifacts.ArtifactVersion
+		if op.Delete {
+			a, err = deleteInTx(ctx, tx, op.Selector, op.ExpectedPrevRef)
+		} else {
+			a, err = saveInTx(ctx, tx, op.Version, op.ExpectedPrevRef, op.Quota)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("batch write %d: %w", i, err)
+		}
+		out = append(out, a)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+// ExpireNames tombstones every name whose latest version expired at or
+// before now and returns the tombstones. A name saved again after it was
+// selected keeps its new version.
+func (r *ArtifactRepository) ExpireNames(ctx context.Context, now time.Time) ([]artifacts.ArtifactVersion, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.name, a.latest_version_id
+		FROM artifacts a
+		JOIN version_expiry x ON x.version_id = a.latest_version_id
+		WHERE a.deleted = 0 AND x.expires_at <= ?
+		ORDER BY x.expires_at ASC, a.name ASC;
+	`, now.UTC().Truncate(time.Second).Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	var due []artifacts.Selector
+	for rows.Next() {
+		var sel artifacts.Selector
+		if err := rows.Scan(&sel.Name, &sel.Ref); err != nil {
+			closeRowsIgnore(rows)
+			return nil, err
+		}
+		due = append(due, sel)
+	}
+	if err := rows.Err(); err != nil {
+		closeRowsIgnore(rows)
+		return nil, err
+	}
+	closeRowsIgnore(rows)
+
+	out := make([]artifacts.ArtifactVersion, 0, len(due))
+	for _, sel := range due {
+		tomb, err := r.Delete(ctx, sel)
+		if errors.Is(err, artifacts.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, tomb)
+	}
+	return out, nil
+}
@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+// HasVersion reports whether a version with ref exists, tombstones included.
+func (r *ArtifactRepository) HasVersion(ctx context.Context, ref string) (bool, error) {
+	var exists int
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM versions WHERE version_id = ?);
+	`, strings.TrimSpace(ref)).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists != 0, nil
+}
+
+// LatestRef returns the newest version of name, which is a tombstone when
+// the name is deleted. It returns "" for unknown names.
+func (r *ArtifactRepository) LatestRef(ctx context.Context, name string) (string, error) {
+	var latest sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT latest_version_id FROM artifacts WHERE name = ?;`, name).Scan(&latest)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(latest.String), nil
+}
+
+// Import stores a version exactly as given, keeping its ref, PrevRef and
+// CreatedAt. It is meant for replaying history from another store: the name's
+// current latest version must equal a.PrevRef, and a tombstone leaves the
+// name deleted.
+func (r *ArtifactRepository) Import(ctx context.Context, a artifacts.ArtifactVersion, data []byte) error {
+	if !a.Tombstone {
+		if strings.TrimSpace(a.SHA256) == "" {
+			return fmt.Errorf("%w: sha256 is required", artifacts.ErrInvalidInput)
+		}
+		if err := r.blobs.PutEncoded(a.SHA256, data, r.compression.EncodingFor(a.MimeType)); err != nil {
+			return err
+		}
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		err := r.importOnce(ctx, a)
+		if err == nil {
+			return nil
+		}
+		if !isRetryableBusyErr(err) {
+			return err
+		}
+		lastErr = err
+		busyRetries.Add(1)
+		time.Sleep(time.Duration(10+attempt*20) * time.Millisecond)
+	}
+	return lastErr
+}
+
+func (r *ArtifactRepository) importOnce(ctx context.Context, a artifacts.ArtifactVersion) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollbackIgnore(tx)
+
+	stamp := a.CreatedAt.UTC().Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO artifacts(name, latest_version_id, deleted, created_at, updated_at, deleted_at)
+		VALUES (?, NULL, 0, ?, ?, NULL)
+		ON CONFLICT(name) DO NOTHING;
+	`, a.Name, stamp, stamp); err != nil {
+		return err
+	}
+	var currentLatest sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT latest_version_id FROM artifacts WHERE name = ?;`, a.Name).Scan(&currentLatest); err != nil {
+		return err
+	}
+	prev := strings.TrimSpace(a.PrevRef)
+	if current := strings.TrimSpace(currentLatest.String); current != prev {
+		return fmt.Errorf("%w: %s has latest=%q, import expects %q", artifacts.ErrConflict, a.Name, current, prev)
+	}
+
+	var parent, payload any
+	if prev != "" {
+		parent = prev
+	}
+	if sha := strings.ToLower(strings.TrimSpace(a.SHA256)); sha != "" && !a.Tombstone {
+		payload = sha
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO versions(version_id, name, parent_version_id, kind, mime_type, filename, size_bytes, payload_sha256, created_at, tombstone)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`, a.Ref, a.Name, parent, string(a.Kind), a.MimeType, a.Filename, a.SizeBytes, payload, stamp, boolToInt(a.Tombstone)); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "unique") {
+			return fmt.Errorf("%w: ref already exists", artifacts.ErrConflict)
+		}
+		return err
+	}
+
+	var deletedAt any
+	if a.Tombstone {
+		deletedAt = stamp
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE artifacts
+		SET latest_version_id = ?, deleted = ?, updated_at = ?, deleted_at = ?
+		WHERE name = ?;
+	`, a.Ref, boolToInt(a.Tombstone), stamp, deletedAt, a.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -2,6 +2,8 @@ package sqlite
 
 import (
 	"database/sql"
+	"errors"
+	"os"
 )
 
 func closeDBIgnore(db *sql.DB) {
@@ -30,3 +32,27 @@ func closeRowsIgnore(rows *sql.Rows) {
 		_ = err
 	}
 }
+
+func closeFileIgnore(f *os.File) {
+	if f == nil {
+		return
+	}
+	if err := f.Close(); err != nil {
+		_ = err
+	}
+}
+
+func closeConnIgnore(conn *sql.Conn) {
+	if conn == nil {
+		return
+	}
+	if err := conn.Close(); err != nil {
+		_ = err
+	}
+}
+
+func removeIfExists(path string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		_ = err
+	}
+}
@@ -1,6 +1,7 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -9,18 +10,27 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// registrySchema and metaSchema are the current schemas; older databases
+// are upgraded to them when opened or restored.
+const (
+	registrySchema        = registrySchemaV3
+	registrySchemaVersion = 3
+	metaSchema            = metaSchemaV2
+	metaSchemaVersion     = 2
+)
+
 func OpenMetaDB(workspaceRoot string) (*sql.DB, error) {
 	if err := os.MkdirAll(workspaceRoot, 0o755); err != nil {
 		return nil, err
 	}
-	return openSQLite(filepath.Join(workspaceRoot, "meta.sqlite"), metaSchemaV1, 1)
+	return openSQLite(filepath.Join(workspaceRoot, "meta.sqlite"), metaSchema, metaSchemaVersion)
 }
 
 func OpenRegistryDB(baseRoot string) (*sql.DB, error) {
 	if err := os.MkdirAll(baseRoot, 0o755); err != nil {
 		return nil, err
 	}
-	return openSQLite(filepath.Join(baseRoot, "registry.sqlite"), registrySchemaV1, 1)
+	return openSQLite(filepath.Join(baseRoot, "registry.sqlite"), registrySchema, registrySchemaVersion)
 }
 
 func openSQLite(path string, schema string, targetUserVersion int) (*sql.DB, error) {
@@ -34,24 +44,39 @@ func openSQLite(path string, schema string, targetUserVersion int) (*sql.DB, err
 		return nil, err
 	}
 
-	var userVersion int
-	if err := db.QueryRow("PRAGMA user_version;").Scan(&userVersion); err != nil {
+	if err := applySchema(context.Background(), db, schema, targetUserVersion); err != nil {
 		closeDBIgnore(db)
-		return nil, err
+		return nil, fmt.Errorf("%w for %s", err, path)
 	}
-	if userVersion == 0 {
-		if _, err := db.Exec(schema); err != nil {
-			closeDBIgnore(db)
-			return nil, err
-		}
-		if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d;", targetUserVersion)); err != nil {
-			closeDBIgnore(db)
-			return nil, err
-		}
-	} else if userVersion != targetUserVersion {
-		closeDBIgnore(db)
-		return nil, fmt.Errorf("unsupported user_version %d for %s", userVersion, path)
+	return db, nil
+}
+
+// applySchema creates or upgrades the schema. Schemas are written with
+// idempotent statements, so an older version is upgraded by applying the
+// current one; a newer version is refused.
+func applySchema(ctx context.Context, db *sql.DB, schema string, targetUserVersion int) error {
+	var userVersion int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version;").Scan(&userVersion); err != nil {
+		return err
+	}
+	if userVersion > targetUserVersion {
+		return fmt.Errorf("unsupported user_version %d", userVersion)
+	}
+	if userVersion == targetUserVersion {
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return err
 	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d;", targetUserVersion))
+	return err
+}
 
-	return db, nil
+// checkpointWAL folds the write-ahead log back into the database file and
+// truncates it, so long-lived connections do not leave large WAL files behind.
+func checkpointWAL(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE);"); err != nil {
+		return fmt.Errorf("wal checkpoint: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,13 @@
+package sqlite
+
+// metaSchemaV2 adds optional per-version expiry. A name expires when its
+// latest version carries an expires_at in the past. Every statement is
+// idempotent, so applying it upgrades a version 1 database.
+const metaSchemaV2 = metaSchemaV1 + `
+CREATE TABLE IF NOT EXISTS version_expiry (
+	version_id TEXT PRIMARY KEY,
+	expires_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_version_expiry_expires_at ON version_expiry(expires_at);
+`
@@ -0,0 +1,14 @@
+package sqlite
+
+// registrySchemaV2 adds the table that maps workspace identity keys, such as
+// a git remote plus repository path, to workspace IDs. Every statement is
+// idempotent, so applying it upgrades a version 1 registry.
+const registrySchemaV2 = registrySchemaV1 + `
+CREATE TABLE IF NOT EXISTS workspace_identities (
+identity_key TEXT PRIMARY KEY,
+workspace_id TEXT NOT NULL,
+created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_identities_workspace ON workspace_identities(workspace_id);
+`
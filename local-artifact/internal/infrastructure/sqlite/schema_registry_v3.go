@@ -0,0 +1,21 @@
+package sqlite
+
+// registrySchemaV3 adds workspace aliases and the artifact shares that let one
+// workspace read named artifacts from another.
+const registrySchemaV3 = registrySchemaV2 + `
+CREATE TABLE IF NOT EXISTS workspace_aliases (
+alias TEXT PRIMARY KEY,
+workspace_id TEXT NOT NULL,
+created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS artifact_shares (
+source_workspace_id TEXT NOT NULL,
+name TEXT NOT NULL,
+target_workspace_id TEXT NOT NULL,
+created_at TEXT NOT NULL,
+PRIMARY KEY (source_workspace_id, name, target_workspace_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifact_shares_target ON artifact_shares(target_workspace_id);
+`
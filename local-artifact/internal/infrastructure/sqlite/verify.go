@@ -0,0 +1,394 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/blobstore"
+)
+
+// orphanBlobGrace skips unreferenced blobs this young. Save writes the blob
+// before it commits the version row, so a fresh blob may just be in flight.
+var orphanBlobGrace = time.Minute
+
+type verifyVersion struct {
+	seq       int64
+	ref       string
+	name      string
+	parent    string
+	digest    string
+	tombstone bool
+}
+
+// quarantineRecord is one line of quarantine/versions.jsonl.
+type quarantineRecord struct {
+	Ref           string    `json:"ref"`
+	Name          string    `json:"name"`
+	Digest        string    `json:"digest"`
+	Reason        string    `json:"reason"`
+	QuarantinedAt time.Time `json:"quarantinedAt"`
+}
+
+// Verify checks that every version's blob exists and matches its digest,
+// that latest and parent pointers resolve, and that no blob is unreferenced.
+// With repair it quarantines corrupt blobs and orphans under
+// <workspace>/quarantine, moves names off unreadable latest versions and
+// clears dangling parent links.
+func (r *ArtifactRepository) Verify(ctx context.Context, repair bool) (artifacts.IntegrityReport, error) {
+	versions, err := r.loadVerifyVersions(ctx)
+	if err != nil {
+		return artifacts.IntegrityReport{}, err
+	}
+	byRef := make(map[string]verifyVersion, len(versions))
+	referenced := map[string]bool{}
+	for _, v := range versions {
+		byRef[v.ref] = v
+		if v.digest != "" {
+			referenced[v.digest] = true
+		}
+	}
+
+	quarantined, err := loadQuarantinedRefs(r.quarantineManifest())
+	if err != nil {
+		return artifacts.IntegrityReport{}, err
+	}
+
+	report := artifacts.IntegrityReport{Versions: len(versions), Repaired: repair}
+	bad := map[string]string{}
+	blobState := map[string]string{}
+	var corruptDigests []string
+	for _, v := range versions {
+		if quarantined[v.ref] {
+			bad[v.ref] = "quarantined"
+			report.Quarantined++
+			continue
+		}
+		if v.tombstone || v.digest == "" {
+			continue
+		}
+		state, checked := blobState[v.digest]
+		if !checked {
+			state, err = r.checkBlob(v.digest)
+			if err != nil {
+				return artifacts.IntegrityReport{}, err
+			}
+			blobState[v.digest] = state
+			if state == artifacts.IssueCorruptBlob {
+				corruptDigests = append(corruptDigests, v.digest)
+			}
+		}
+		if state == "" {
+			continue
+		}
+		bad[v.ref] = state
+		detail := "blob is missing"
+		if state == artifacts.IssueCorruptBlob {
+			detail = "blob content does not match payload_sha256"
+		}
+		report.Issues = append(report.Issues, artifacts.IntegrityIssue{Kind: state, Ref: v.ref, Name: v.name, Digest: v.digest, Detail: detail})
+	}
+
+	var danglingParents []string
+	for _, v := range versions {
+		if v.parent == "" {
+			continue
+		}
+		if _, ok := byRef[v.parent]; !ok {
+			danglingParents = append(danglingParents, v.ref)
+			report.Issues = append(report.Issues, artifacts.IntegrityIssue{
+				Kind:   artifacts.IssueDanglingParent,
+				Ref:    v.ref,
+				Name:   v.name,
+				Detail: fmt.Sprintf("parent %s does not exist", v.parent),
+			})
+		}
+	}
+
+	latest, err := r.loadLatestPointers(ctx)
+	if err != nil {
+		return artifacts.IntegrityReport{}, err
+	}
+	var brokenNames []string
+	for _, name := range sortedKeys(latest) {
+		ref := latest[name]
+		v, ok := byRef[ref]
+		switch {
+		case !ok || v.name != name:
+			brokenNames = append(brokenNames, name)
+			report.Issues = append(report.Issues, artifacts.IntegrityIssue{
+				Kind:   artifacts.IssueDanglingLatest,
+				Ref:    ref,
+				Name:   name,
+				Detail: "latest_version_id does not resolve to a version of this name",
+			})
+		case bad[ref] != "":
+			brokenNames = append(brokenNames, name)
+		}
+	}
+
+	// Encrypted blobs are named by a keyed hash of their digest.
+	referencedAddresses := map[string]bool{}
+	for digest := range referenced {
+		if address := r.blobs.Address(digest); address != "" {
+			referencedAddresses[address] = true
+		}
+	}
+	var orphans []blobstore.Entry
+	cutoff := time.Now().Add(-orphanBlobGrace)
+	if err := r.blobs.Walk(func(entry blobstore.Entry) error {
+		report.Blobs++
+		if entry.Digest != "" && referenced[entry.Digest] {
+			return nil
+		}
+		if entry.Address != "" && (referencedAddresses[entry.Address] || !r.blobs.HasSealer()) {
+			// Without the workspace key an encrypted blob cannot be matched
+			// to a version, so it is never treated as an orphan.
+			return nil
+		}
+		if entry.ModTime.After(cutoff) {
+			return nil
+		}
+		orphans = append(orphans, entry)
+		return nil
+	}); err != nil {
+		return artifacts.IntegrityReport{}, fmt.Errorf("walk blobs: %w", err)
+	}
+	for _, entry := range orphans {
+		report.Issues = append(report.Issues, artifacts.IntegrityIssue{
+			Kind:   artifacts.IssueOrphanBlob,
+			Digest: entry.Digest,
+			Detail: fmt.Sprintf("%s is not referenced by any version", entry.Path),
+		})
+	}
+
+	if !repair || len(report.Issues) == 0 {
+		return report, nil
+	}
+	actions, err := r.repair(ctx, versions, bad, corruptDigests, brokenNames, danglingParents, orphans)
+	if err != nil {
+		return report, err
+	}
+	for i := range report.Issues {
+		issue := &report.Issues[i]
+		switch issue.Kind {
+		case artifacts.IssueOrphanBlob:
+			issue.Action = actions["orphan:"+issue.Detail]
+		case artifacts.IssueDanglingLatest:
+			issue.Action = actions["name:"+issue.Name]
+		case artifacts.IssueDanglingParent:
+			issue.Action = actions["parent:"+issue.Ref]
+		default:
+			issue.Action = actions["version:"+issue.Ref]
+			if moved := actions["name:"+issue.Name]; moved != "" && latest[issue.Name] == issue.Ref {
+				issue.Action += "; " + moved
+			}
+		}
+	}
+	return report, nil
+}
+
+func (r *ArtifactRepository) checkBlob(digest string) (string, error) {
+	ok, err := r.blobs.Verify(digest)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return artifacts.IssueMissingBlob, nil
+		}
+		return "", fmt.Errorf("verify blob %s: %w", digest, err)
+	}
+	if !ok {
+		return artifacts.IssueCorruptBlob, nil
+	}
+	return "", nil
+}
+
+func (r *ArtifactRepository) repair(ctx context.Context, versions []verifyVersion, bad map[string]string, corruptDigests, brokenNames, danglingParents []string, orphans []blobstore.Entry) (map[string]string, error) {
+	actions := map[string]string{}
+	quarantineDir := filepath.Join(r.workspaceRoot, "quarantine")
+
+	for _, digest := range corruptDigests {
+		path, err := r.blobs.Locate(digest)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := blobstore.Quarantine(path, filepath.Join(quarantineDir, "blobs")); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	var records []quarantineRecord
+	for _, v := range versions {
+		if reason := bad[v.ref]; reason != "" && reason != "quarantined" {
+			records = append(records, quarantineRecord{Ref: v.ref, Name: v.name, Digest: v.digest, Reason: reason, QuarantinedAt: now})
+			actions["version:"+v.ref] = "quarantined"
+		}
+	}
+	if err := appendQuarantineRecords(r.quarantineManifest(), records); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackIgnore(tx)
+	stamp := now.Format(time.RFC3339)
+	for _, name := range brokenNames {
+		if target := healthyLatest(versions, bad, name); target != "" {
+			if _, err := tx.ExecContext(ctx, `UPDATE artifacts SET latest_version_id = ?, updated_at = ? WHERE name = ?;`, target, stamp, name); err != nil {
+				return nil, err
+			}
+			actions["name:"+name] = "latest moved to " + target
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE artifacts SET latest_version_id = NULL, deleted = 1, updated_at = ?, deleted_at = ? WHERE name = ?;`, stamp, stamp, name); err != nil {
+			return nil, err
+		}
+		actions["name:"+name] = "name marked deleted"
+	}
+	for _, ref := range danglingParents {
+		if _, err := tx.ExecContext(ctx, `UPDATE versions SET parent_version_id = NULL WHERE version_id = ?;`, ref); err != nil {
+			return nil, err
+		}
+		actions["parent:"+ref] = "parent link cleared"
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range orphans {
+		target, err := blobstore.Quarantine(entry.Path, filepath.Join(quarantineDir, "orphans"))
+		if err != nil {
+			return nil, err
+		}
+		actions["orphan:"+fmt.Sprintf("%s is not referenced by any version", entry.Path)] = "moved to " + target
+	}
+	return actions, nil
+}
+
+// healthyLatest walks the versions of name from newest to oldest and returns
+// the first readable one. It stops at a tombstone, since anything older was
+// deleted by the user.
+func healthyLatest(versions []verifyVersion, bad map[string]string, name string) string {
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if v.name != name {
+			continue
+		}
+		if v.tombstone {
+			return ""
+		}
+		if bad[v.ref] == "" {
+			return v.ref
+		}
+	}
+	return ""
+}
+
+func (r *ArtifactRepository) quarantineManifest() string {
+	return filepath.Join(r.workspaceRoot, "quarantine", "versions.jsonl")
+}
+
+func loadQuarantinedRefs(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]bool{}, nil
+		}
+		return nil, err
+	}
+	out := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var record quarantineRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		out[record.Ref] = true
+	}
+	return out, nil
+}
+
+func appendQuarantineRecords(path string, records []quarantineRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			closeFileIgnore(f)
+			return err
+		}
+	}
+	return f.Close()
+}
+
+func (r *ArtifactRepository) loadVerifyVersions(ctx context.Context) ([]verifyVersion, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rowid, version_id, name, parent_version_id, payload_sha256, tombstone
+		FROM versions
+		ORDER BY rowid;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRowsIgnore(rows)
+	var out []verifyVersion
+	for rows.Next() {
+		var v verifyVersion
+		var parent, digest sql.NullString
+		var tomb int
+		if err := rows.Scan(&v.seq, &v.ref, &v.name, &parent, &digest, &tomb); err != nil {
+			return nil, err
+		}
+		v.parent = strings.TrimSpace(parent.String)
+		v.digest = strings.ToLower(strings.TrimSpace(digest.String))
+		v.tombstone = tomb != 0
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+func (r *ArtifactRepository) loadLatestPointers(ctx context.Context) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT name, latest_version_id FROM artifacts WHERE latest_version_id IS NOT NULL;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRowsIgnore(rows)
+	out := map[string]string{}
+	for rows.Next() {
+		var name, ref string
+		if err := rows.Scan(&name, &ref); err != nil {
+			return nil, err
+		}
+		out[name] = strings.TrimSpace(ref)
+	}
+	return out, rows.Err()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+func TestArtifactRepository_VerifyReportsAndRepairsDamage(t *testing.T) {
+	repo := newArtifactRepo(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	goodRef := "20260216T120000Z-aaaaaaaaaaaaaaaa"
+	corruptRef := "20260216T120001Z-bbbbbbbbbbbbbbbb"
+	missingRef := "20260216T120002Z-cccccccccccccccc"
+	mustSaveVersion(t, ctx, repo, goodRef, "plan/a", "text/plain", []byte("a1"), now, artifacts.SaveOptions{})
+	mustSaveVersion(t, ctx, repo, corruptRef, "plan/a", "text/plain", []byte("a2"), now.Add(time.Second), artifacts.SaveOptions{})
+	mustSaveVersion(t, ctx, repo, missingRef, "plan/b", "text/plain", []byte("b1"), now, artifacts.SaveOptions{})
+
+	if err := os.WriteFile(repo.blobs.Path(shaFor([]byte("a2"))), []byte("tampered"), 0o644); err != nil {
+		t.Fatalf("corrupt blob: %v", err)
+	}
+	if err := os.Remove(repo.blobs.Path(shaFor([]byte("b1")))); err != nil {
+		t.Fatalf("remove blob: %v", err)
+	}
+	if _, err := repo.db.ExecContext(ctx, `UPDATE versions SET parent_version_id = 'gone' WHERE version_id = ?;`, goodRef); err != nil {
+		t.Fatalf("break parent: %v", err)
+	}
+	orphan := repo.blobs.Path(shaFor([]byte("orphan")))
+	if err := os.MkdirAll(filepath.Dir(orphan), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(orphan, []byte("orphan"), 0o644); err != nil {
+		t.Fatalf("write orphan: %v", err)
+	}
+	old := now.Add(-time.Hour)
+	if err := os.Chtimes(orphan, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	report, err := repo.Verify(ctx, false)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	kinds := map[string]int{}
+	for _, issue := range report.Issues {
+		kinds[issue.Kind]++
+		if issue.Action != "" {
+			t.Fatalf("dry verify recorded action: %+v", issue)
+		}
+	}
+	want := map[string]int{
+		artifacts.IssueCorruptBlob:    1,
+		artifacts.IssueMissingBlob:    1,
+		artifacts.IssueDanglingParent: 1,
+		artifacts.IssueOrphanBlob:     1,
+	}
+	if len(kinds) != len(want) {
+		t.Fatalf("issues=%+v", report.Issues)
+	}
+	for kind, n := range want {
+		if kinds[kind] != n {
+			t.Fatalf("issue %s count=%d want %d: %+v", kind, kinds[kind], n, report.Issues)
+		}
+	}
+	if report.Versions != 3 {
+		t.Fatalf("versions=%d want 3", report.Versions)
+	}
+
+	report, err = repo.Verify(ctx, true)
+	if err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+	for _, issue := range report.Issues {
+		if issue.Action == "" {
+			t.Fatalf("issue not repaired: %+v", issue)
+		}
+	}
+
+	if meta, data, err := repo.Get(ctx, artifacts.Selector{Name: "plan/a"}); err != nil || meta.Ref != goodRef || string(data) != "a1" {
+		t.Fatalf("plan/a after repair: ref=%q data=%q err=%v", meta.Ref, data, err)
+	}
+	if _, err := repo.Resolve(ctx, "plan/b"); !errors.Is(err, artifacts.ErrNotFound) {
+		t.Fatalf("plan/b after repair: err=%v want not found", err)
+	}
+	manifest, err := os.ReadFile(filepath.Join(repo.workspaceRoot, "quarantine", "versions.jsonl"))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	if lines := strings.Count(string(manifest), "\n"); lines != 2 {
+		t.Fatalf("manifest lines=%d want 2:\n%s", lines, manifest)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Fatalf("orphan still in blob store: err=%v", err)
+	}
+
+	report, err = repo.Verify(ctx, false)
+	if err != nil {
+		t.Fatalf("reverify: %v", err)
+	}
+	if len(report.Issues) != 0 || report.Quarantined != 2 {
+		t.Fatalf("after repair: quarantined=%d issues=%+v", report.Quarantined, report.Issues)
+	}
+}
@@ -32,6 +32,10 @@ func (r *WorkspaceRegistry) Close() error {
 	return r.db.Close()
 }
 
+func (r *WorkspaceRegistry) Checkpoint(ctx context.Context) error {
+	return checkpointWAL(ctx, r.db)
+}
+
 func (r *WorkspaceRegistry) EnsureWorkspace(ctx context.Context, workspaceID string, roots []string, owner string) error {
 	workspaceID = strings.TrimSpace(workspaceID)
 	if workspaceID == "" {
@@ -99,6 +103,74 @@ func (r *WorkspaceRegistry) GetWorkspace(ctx context.Context, workspaceID string
 	return ws, nil
 }
 
+// ResolveIdentity returns the workspace an identity key is mapped to. A new
+// key is mapped to fallbackWorkspaceID first, so concurrent first uses agree.
+func (r *WorkspaceRegistry) ResolveIdentity(ctx context.Context, key, fallbackWorkspaceID string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", errors.New("identity key is required")
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO workspace_identities(identity_key, workspace_id, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(identity_key) DO NOTHING;
+	`, key, strings.TrimSpace(fallbackWorkspaceID), now); err != nil {
+		return "", err
+	}
+	var workspaceID string
+	err := r.db.QueryRowContext(ctx, `SELECT workspace_id FROM workspace_identities WHERE identity_key = ?;`, key).Scan(&workspaceID)
+	return workspaceID, err
+}
+
+// MapIdentity points an identity key at workspaceID, replacing any mapping.
+func (r *WorkspaceRegistry) MapIdentity(ctx context.Context, key, workspaceID string) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return errors.New("identity key is required")
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO workspace_identities(identity_key, workspace_id, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(identity_key) DO UPDATE SET
+			workspace_id = excluded.workspace_id,
+			created_at = excluded.created_at;
+	`, key, strings.TrimSpace(workspaceID), now)
+	return err
+}
+
+func (r *WorkspaceRegistry) ListIdentities(ctx context.Context) ([]workspaces.Identity, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT identity_key, workspace_id, created_at
+		FROM workspace_identities
+		ORDER BY identity_key ASC;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRowsIgnore(rows)
+
+	out := make([]workspaces.Identity, 0)
+	for rows.Next() {
+		var (
+			identity  workspaces.Identity
+			createdAt string
+		)
+		if err := rows.Scan(&identity.Key, &identity.WorkspaceID, &createdAt); err != nil {
+			return nil, err
+		}
+		if identity.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func scanWorkspace(rows *sql.Rows) (workspaces.Workspace, error) {
 	var (
 		workspaceID string
@@ -2,8 +2,14 @@ package sqlite
 
 import (
 	"context"
+	"errors"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
 )
 
 func TestWorkspaceRegistry_EnsureAndList(t *testing.T) {
@@ -58,3 +64,116 @@ func TestWorkspaceRegistry_EnsureAndList(t *testing.T) {
 		t.Fatalf("expected deterministic ordering by workspace_id, got %q then %q", items[0].WorkspaceID, items[1].WorkspaceID)
 	}
 }
+
+func TestWorkspaceRegistry_IdentityMapping(t *testing.T) {
+	registry, err := NewWorkspaceRegistry(t.TempDir())
+	if err != nil {
+		t.Fatalf("new registry: %v", err)
+	}
+	t.Cleanup(func() {
+		if closeErr := registry.Close(); closeErr != nil {
+			t.Fatalf("close registry: %v", closeErr)
+		}
+	})
+	ctx := context.Background()
+	key := "git:github.com/owner/repo#."
+	first, second := strings.Repeat("a", 64), strings.Repeat("b", 64)
+
+	got, err := registry.ResolveIdentity(ctx, key, first)
+	if err != nil || got != first {
+		t.Fatalf("first resolve = %q (%v), want fallback", got, err)
+	}
+	got, err = registry.ResolveIdentity(ctx, key, second)
+	if err != nil || got != first {
+		t.Fatalf("second resolve = %q (%v), want existing mapping", got, err)
+	}
+	if err := registry.MapIdentity(ctx, key, second); err != nil {
+		t.Fatalf("map identity: %v", err)
+	}
+	items, err := registry.ListIdentities(ctx)
+	if err != nil {
+		t.Fatalf("list identities: %v", err)
+	}
+	if len(items) != 1 || items[0].Key != key || items[0].WorkspaceID != second {
+		t.Fatalf("unexpected identities: %+v", items)
+	}
+}
+
+func TestOpenRegistryDB_UpgradesVersion1(t *testing.T) {
+	root := t.TempDir()
+	db, err := openSQLite(filepath.Join(root, "registry.sqlite"), registrySchemaV1, 1)
+	if err != nil {
+		t.Fatalf("open v1 registry: %v", err)
+	}
+	closeDBIgnore(db)
+
+	registry, err := NewWorkspaceRegistry(root)
+	if err != nil {
+		t.Fatalf("open registry over v1: %v", err)
+	}
+	t.Cleanup(func() {
+		if closeErr := registry.Close(); closeErr != nil {
+			t.Fatalf("close registry: %v", closeErr)
+		}
+	})
+	if _, err := registry.ResolveIdentity(context.Background(), "git:example.com/repo#.", "global"); err != nil {
+		t.Fatalf("resolve identity after upgrade: %v", err)
+	}
+}
+
+func TestWorkspaceRegistry_SharesAndAliases(t *testing.T) {
+	registry, err := NewWorkspaceRegistry(t.TempDir())
+	if err != nil {
+		t.Fatalf("new registry: %v", err)
+	}
+	t.Cleanup(func() {
+		if closeErr := registry.Close(); closeErr != nil {
+			t.Fatalf("close registry: %v", closeErr)
+		}
+	})
+	ctx := context.Background()
+	reader, other := strings.Repeat("a", 64), strings.Repeat("b", 64)
+
+	for _, share := range []workspaces.Share{
+		{SourceWorkspaceID: "global", Name: "standards/go", TargetWorkspaceID: workspaces.ShareAllWorkspaces, CreatedAt: time.Now()},
+		{SourceWorkspaceID: "global", Name: "design/api", TargetWorkspaceID: reader, CreatedAt: time.Now()},
+	} {
+		if err := registry.Share(ctx, share); err != nil {
+			t.Fatalf("share %s: %v", share.Name, err)
+		}
+	}
+	tests := []struct {
+		name, target string
+		want         bool
+	}{
+		{"standards/go", other, true},
+		{"design/api", reader, true},
+		{"design/api", other, false},
+		{"design/private", reader, false},
+	}
+	for _, tc := range tests {
+		got, err := registry.ShareAllowed(ctx, "global", tc.name, tc.target)
+		if err != nil || got != tc.want {
+			t.Fatalf("ShareAllowed(%s, %s) = %v (%v), want %v", tc.name, tc.target, got, err, tc.want)
+		}
+	}
+	if shares, err := registry.ListShares(ctx, other); err != nil || len(shares) != 1 {
+		t.Fatalf("expected one usable share for other, got %+v (%v)", shares, err)
+	}
+	if removed, err := registry.Unshare(ctx, workspaces.Share{SourceWorkspaceID: "global", Name: "design/api", TargetWorkspaceID: reader}); err != nil || !removed {
+		t.Fatalf("unshare: removed=%v err=%v", removed, err)
+	}
+
+	if err := registry.SetAlias(ctx, "team", reader); err != nil {
+		t.Fatalf("set alias: %v", err)
+	}
+	if got, err := registry.ResolveAlias(ctx, "team"); err != nil || got != reader {
+		t.Fatalf("resolve alias = %q (%v)", got, err)
+	}
+	if removed, err := registry.RemoveAlias(ctx, "team"); err != nil || !removed {
+		t.Fatalf("remove alias: removed=%v err=%v", removed, err)
+	}
+	if _, err := registry.ResolveAlias(ctx, "team"); !errors.Is(err, artifacts.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after removal, got %v", err)
+	}
+}
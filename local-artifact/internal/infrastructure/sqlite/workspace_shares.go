@@ -0,0 +1,143 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
+)
+
+// SetAlias points alias at workspaceID, replacing any previous target.
+func (r *WorkspaceRegistry) SetAlias(ctx context.Context, alias, workspaceID string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO workspace_aliases(alias, workspace_id, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(alias) DO UPDATE SET
+			workspace_id = excluded.workspace_id,
+			created_at = excluded.created_at;
+	`, strings.TrimSpace(alias), strings.TrimSpace(workspaceID), now)
+	return err
+}
+
+// RemoveAlias deletes alias and reports whether it existed.
+func (r *WorkspaceRegistry) RemoveAlias(ctx context.Context, alias string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM workspace_aliases WHERE alias = ?;`, strings.TrimSpace(alias))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *WorkspaceRegistry) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	var workspaceID string
+	err := r.db.QueryRowContext(ctx, `SELECT workspace_id FROM workspace_aliases WHERE alias = ?;`, strings.TrimSpace(alias)).Scan(&workspaceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", artifacts.ErrNotFound
+	}
+	return workspaceID, err
+}
+
+func (r *WorkspaceRegistry) ListAliases(ctx context.Context) ([]workspaces.Alias, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT alias, workspace_id, created_at
+		FROM workspace_aliases
+		ORDER BY alias ASC;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRowsIgnore(rows)
+
+	out := make([]workspaces.Alias, 0)
+	for rows.Next() {
+		var (
+			alias     workspaces.Alias
+			createdAt string
+		)
+		if err := rows.Scan(&alias.Alias, &alias.WorkspaceID, &createdAt); err != nil {
+			return nil, err
+		}
+		if alias.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, alias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Share records that share.TargetWorkspaceID may read share.Name from
+// share.SourceWorkspaceID. Sharing again keeps the original creation time.
+func (r *WorkspaceRegistry) Share(ctx context.Context, share workspaces.Share) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO artifact_shares(source_workspace_id, name, target_workspace_id, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(source_workspace_id, name, target_workspace_id) DO NOTHING;
+	`, share.SourceWorkspaceID, share.Name, share.TargetWorkspaceID, share.CreatedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// Unshare removes a share and reports whether it existed.
+func (r *WorkspaceRegistry) Unshare(ctx context.Context, share workspaces.Share) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM artifact_shares
+		WHERE source_workspace_id = ? AND name = ? AND target_workspace_id = ?;
+	`, share.SourceWorkspaceID, share.Name, share.TargetWorkspaceID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ShareAllowed reports whether targetWorkspaceID may read name from
+// sourceWorkspaceID, through a share to it or to every workspace.
+func (r *WorkspaceRegistry) ShareAllowed(ctx context.Context, sourceWorkspaceID, name, targetWorkspaceID string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM artifact_shares
+		WHERE source_workspace_id = ? AND name = ? AND target_workspace_id IN (?, ?);
+	`, sourceWorkspaceID, name, targetWorkspaceID, workspaces.ShareAllWorkspaces).Scan(&n)
+	return n > 0, err
+}
+
+// ListShares returns the shares a workspace grants or can use.
+func (r *WorkspaceRegistry) ListShares(ctx context.Context, workspaceID string) ([]workspaces.Share, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT source_workspace_id, name, target_workspace_id, created_at
+		FROM artifact_shares
+		WHERE source_workspace_id = ? OR target_workspace_id IN (?, ?)
+		ORDER BY source_workspace_id ASC, name ASC, target_workspace_id ASC;
+	`, workspaceID, workspaceID, workspaces.ShareAllWorkspaces)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRowsIgnore(rows)
+
+	out := make([]workspaces.Share, 0)
+	for rows.Next() {
+		var (
+			share     workspaces.Share
+			createdAt string
+		)
+		if err := rows.Scan(&share.SourceWorkspaceID, &share.Name, &share.TargetWorkspaceID, &createdAt); err != nil {
+			return nil, err
+		}
+		if share.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, share)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
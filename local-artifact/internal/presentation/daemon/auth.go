@@ -13,6 +13,8 @@ const TokenCookieName = "ccsubagentsd_token"
 type AuthOptions struct {
 	AllowQueryBootstrap bool
 	SkipPathPrefix      string
+	// OnUnauthorized is called for every request rejected with 401.
+	OnUnauthorized func()
 }
 
 func AuthMiddleware(token string, next http.Handler, options AuthOptions) http.Handler {
@@ -54,6 +56,9 @@ func AuthMiddleware(token string, next http.Handler, options AuthOptions) http.H
 			}
 		}
 
+		if options.OnUnauthorized != nil {
+			options.OnUnauthorized()
+		}
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusUnauthorized)
 		if err := json.NewEncoder(w).Encode(Envelope{OK: false, Error: &EnvelopeError{Code: CodeUnauthorized, Message: "missing or invalid token"}}); err != nil {
@@ -9,9 +9,10 @@ import (
 )
 
 func TestAuthMiddleware_UnauthorizedWithoutToken(t *testing.T) {
+	metrics := NewMetrics()
 	h := AuthMiddleware("secret", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
-	}), AuthOptions{})
+	}), AuthOptions{OnUnauthorized: metrics.RecordAuthFailure})
 
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -19,6 +20,9 @@ func TestAuthMiddleware_UnauthorizedWithoutToken(t *testing.T) {
 	if rr.Code != http.StatusUnauthorized {
 		t.Fatalf("expected 401, got %d", rr.Code)
 	}
+	if got := metrics.AuthFailures(); got != 1 {
+		t.Fatalf("auth failures=%d want 1", got)
+	}
 }
 
 func TestAuthMiddleware_QueryBootstrapSetsCookieAndRedirects(t *testing.T) {
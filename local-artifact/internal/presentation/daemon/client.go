@@ -108,6 +108,26 @@ func (c *Client) SaveBlob(ctx context.Context, req SaveBlobRequest) (artifacts.A
 	return out.Artifact, nil
 }
 
+func (c *Client) SaveJSON(ctx context.Context, req SaveJSONRequest) (artifacts.ArtifactVersion, error) {
+	var out struct {
+		Artifact artifacts.ArtifactVersion `json:"artifact"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/save_json", req, &out); err != nil {
+		return artifacts.ArtifactVersion{}, err
+	}
+	return out.Artifact, nil
+}
+
+func (c *Client) PatchJSON(ctx context.Context, req PatchJSONRequest) (artifacts.ArtifactVersion, error) {
+	var out struct {
+		Artifact artifacts.ArtifactVersion `json:"artifact"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/patch_json", req, &out); err != nil {
+		return artifacts.ArtifactVersion{}, err
+	}
+	return out.Artifact, nil
+}
+
 func (c *Client) Resolve(ctx context.Context, req ResolveRequest) (ResolveResponse, error) {
 	var out ResolveResponse
 	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/resolve", req, &out); err != nil {
@@ -140,6 +160,110 @@ func (c *Client) Delete(ctx context.Context, req DeleteRequest) (DeleteResponse,
 	return out, nil
 }
 
+func (c *Client) Batch(ctx context.Context, req BatchRequest) (BatchResponse, error) {
+	var out BatchResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/batch", req, &out); err != nil {
+		return BatchResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) Verify(ctx context.Context, req VerifyRequest) (VerifyResponse, error) {
+	var out VerifyResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/verify", req, &out); err != nil {
+		return VerifyResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) MigrateLegacy(ctx context.Context, req MigrateLegacyRequest) (MigrateLegacyResponse, error) {
+	var out MigrateLegacyResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/migrate_legacy", req, &out); err != nil {
+		return MigrateLegacyResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) RotateKey(ctx context.Context) (RotateKeyResponse, error) {
+	var out RotateKeyResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/rotate_key", map[string]any{}, &out); err != nil {
+		return RotateKeyResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) Backup(ctx context.Context, req BackupRequest) (BackupResponse, error) {
+	var out BackupResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/backup", req, &out); err != nil {
+		return BackupResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) ListSnapshots(ctx context.Context) (ListSnapshotsResponse, error) {
+	var out ListSnapshotsResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/snapshots", map[string]any{}, &out); err != nil {
+		return ListSnapshotsResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) Restore(ctx context.Context, req RestoreRequest) (RestoreResponse, error) {
+	var out RestoreResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/restore", req, &out); err != nil {
+		return RestoreResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) ListIdentities(ctx context.Context) (ListIdentitiesResponse, error) {
+	var out ListIdentitiesResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/identities", map[string]any{}, &out); err != nil {
+		return ListIdentitiesResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) MapIdentity(ctx context.Context, req MapIdentityRequest) (WorkspaceIdentity, error) {
+	var out WorkspaceIdentity
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/map_identity", req, &out); err != nil {
+		return WorkspaceIdentity{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) Share(ctx context.Context, req ShareRequest) (ShareResponse, error) {
+	var out ShareResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/share", req, &out); err != nil {
+		return ShareResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) ListShares(ctx context.Context, req ListSharesRequest) (ListSharesResponse, error) {
+	var out ListSharesResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/artifacts/shares", req, &out); err != nil {
+		return ListSharesResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) ListAliases(ctx context.Context) (ListAliasesResponse, error) {
+	var out ListAliasesResponse
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/aliases", map[string]any{}, &out); err != nil {
+		return ListAliasesResponse{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) SetAlias(ctx context.Context, req SetAliasRequest) (WorkspaceAlias, error) {
+	var out WorkspaceAlias
+	if err := c.do(ctx, http.MethodPost, "/daemon/v1/maintenance/alias", req, &out); err != nil {
+		return WorkspaceAlias{}, err
+	}
+	return out, nil
+}
+
 func (c *Client) Shutdown(ctx context.Context) (ShutdownResponse, error) {
 	var out ShutdownResponse
 	if err := c.do(ctx, http.MethodPost, "/daemon/v1/control/shutdown", map[string]any{}, &out); err != nil {
@@ -179,6 +303,10 @@ func (c *Client) do(ctx context.Context, method, path string, reqBody any, out a
 	if reqBody != nil {
 		httpReq.Header.Set("Content-Type", "application/json")
 	}
+	progress := transferProgressFrom(ctx)
+	if progress != nil && reqBody != nil {
+		httpReq.Body = io.NopCloser(&progressReader{r: body, phase: TransferUpload, total: httpReq.ContentLength, fn: progress})
+	}
 	if strings.TrimSpace(c.token) != "" {
 		httpReq.Header.Set("Authorization", "Bearer "+c.token)
 	}
@@ -189,12 +317,16 @@ func (c *Client) do(ctx context.Context, method, path string, reqBody any, out a
 	}
 	defer closeResponseBody(resp)
 
+	var respBody io.Reader = resp.Body
+	if progress != nil {
+		respBody = &progressReader{r: resp.Body, phase: TransferDownload, total: max(resp.ContentLength, 0), fn: progress}
+	}
 	var env struct {
 		OK    bool            `json:"ok"`
 		Data  json.RawMessage `json:"data"`
 		Error *EnvelopeError  `json:"error"`
 	}
-	if err := json.NewDecoder(io.LimitReader(resp.Body, DefaultMaxRequestBytes)).Decode(&env); err != nil {
+	if err := json.NewDecoder(io.LimitReader(respBody, DefaultMaxRequestBytes)).Decode(&env); err != nil {
 		return fmt.Errorf("decode daemon response: %w", err)
 	}
 
@@ -4,28 +4,88 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"log"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/blobstore"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/filestore"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/keyring"
 	artsqlite "github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/sqlite"
 )
 
 type serviceEntry struct {
 	service *artifacts.Service
-	closeFn func() error
+	handle  *workspaceHandle
 }
 
+// EngineLimits bounds the resources held by per-workspace services. Zero
+// values select the defaults; a negative IdleTimeout or
+// WALCheckpointInterval disables that policy.
+type EngineLimits struct {
+	MaxOpenWorkspaces     int
+	IdleTimeout           time.Duration
+	WALCheckpointInterval time.Duration
+}
+
+const (
+	DefaultMaxOpenWorkspaces     = 32
+	DefaultWorkspaceIdleTimeout  = 10 * time.Minute
+	DefaultWALCheckpointInterval = 5 * time.Minute
+)
+
+// engineMaintenanceInterval is how often the engine looks for idle
+// workspaces, due WAL checkpoints, expiry sweeps and scheduled snapshots.
+var engineMaintenanceInterval = 30 * time.Second
+
+// engineLogf reports maintenance failures, which have no caller to return to.
+var engineLogf = log.Printf
+
 type Engine struct {
 	baseStoreRoot string
 	registry      workspaces.Registry
 
-	mu      sync.Mutex
-	service map[string]serviceEntry
+	mu       sync.Mutex
+	service  map[string]serviceEntry
+	limits   EngineLimits
+	quota    artifacts.Quota
+	compress blobstore.CompressionPolicy
+	keys     *keyring.Keyring
+	encrypt  bool
+	backup   BackupPolicy
+	expiry   artifacts.ExpiryPolicy
+	schemas  artifacts.JSONValidator
+	closed   bool
+
+	// snapMu serializes snapshots, restores and snapshot pruning.
+	snapMu       sync.Mutex
+	lastSnapshot time.Time
+
+	evictions       atomic.Uint64
+	lastCheckpoint  time.Time
+	lastExpirySweep time.Time
+	stopMaint       chan struct{}
+	maintDone       chan struct{}
+
+	usageMu     sync.Mutex
+	usage       []WorkspaceStatus
+	usageExpiry time.Time
+
+	identityMu    sync.Mutex
+	identityMode  WorkspaceIdentityModeFunc
+	identityCache map[string]cachedIdentity
 }
 
+// blobUsageTTL bounds how often status and metrics requests walk the blob
+// stores of every workspace.
+const blobUsageTTL = time.Minute
+
 func NewEngine(baseStoreRoot string) (*Engine, error) {
 	registry, err := artsqlite.NewWorkspaceRegistry(baseStoreRoot)
 	if err != nil {
@@ -35,19 +95,98 @@ func NewEngine(baseStoreRoot string) (*Engine, error) {
 		baseStoreRoot: baseStoreRoot,
 		registry:      registry,
 		service:       map[string]serviceEntry{},
+		limits:        EngineLimits{}.withDefaults(),
 	}, nil
 }
 
-func (e *Engine) Close() error {
+func (l EngineLimits) withDefaults() EngineLimits {
+	if l.MaxOpenWorkspaces <= 0 {
+		l.MaxOpenWorkspaces = DefaultMaxOpenWorkspaces
+	}
+	if l.IdleTimeout == 0 {
+		l.IdleTimeout = DefaultWorkspaceIdleTimeout
+	}
+	if l.WALCheckpointInterval == 0 {
+		l.WALCheckpointInterval = DefaultWALCheckpointInterval
+	}
+	return l
+}
+
+func (e *Engine) SetLimits(limits EngineLimits) {
+	e.mu.Lock()
+	e.limits = limits.withDefaults()
+	e.mu.Unlock()
+	e.enforceOpenLimit()
+}
+
+// SetQuota sets the storage quota of every workspace. It applies to services
+// created afterwards, so call it before serving requests.
+func (e *Engine) SetQuota(q artifacts.Quota) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.quota = q
+}
+
+// SetJSONValidator sets the JSON Schemas that saves are checked against. Like
+// SetQuota it applies to workspaces opened afterwards.
+func (e *Engine) SetJSONValidator(v artifacts.JSONValidator) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.schemas = v
+}
+
+// SetCompression sets the MIME-type compression policy for new blobs. Like
+// SetQuota it applies to workspaces opened afterwards.
+func (e *Engine) SetCompression(policy blobstore.CompressionPolicy) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	e.compress = policy
+}
+
+// SetEncryption sets the keyring used to read encrypted workspaces and
+// whether new blobs are encrypted. Like SetQuota it applies to workspaces
+// opened afterwards.
+func (e *Engine) SetEncryption(keys *keyring.Keyring, encrypt bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.keys = keys
+	e.encrypt = encrypt
+}
+
+func (e *Engine) encryption() (*keyring.Keyring, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.keys, e.encrypt
+}
+
+func (e *Engine) Quota() artifacts.Quota {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.quota
+}
+
+func (e *Engine) maxOpenWorkspaces() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.limits.MaxOpenWorkspaces
+}
 
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	stop, done := e.stopMaint, e.maintDone
+	e.stopMaint, e.maintDone = nil, nil
+	e.closed = true
+	e.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	var firstErr error
 	for _, entry := range e.service {
-		if entry.closeFn == nil {
-			continue
-		}
-		if err := entry.closeFn(); err != nil && firstErr == nil {
+		if err := entry.handle.close(); err != nil && firstErr == nil {
 			firstErr = err
 		}
 	}
@@ -60,11 +199,153 @@ func (e *Engine) Close() error {
 	return firstErr
 }
 
+// startMaintenance runs idle eviction, WAL checkpoints, expiry sweeps and
+// scheduled snapshots in the background until Close.
+func (e *Engine) startMaintenance() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopMaint != nil || e.closed {
+		return
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	e.stopMaint, e.maintDone = stop, done
+	e.lastCheckpoint = engineNowFn()
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(engineMaintenanceInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				e.maintain(context.Background())
+			}
+		}
+	}()
+}
+
+func (e *Engine) maintain(ctx context.Context) {
+	now := engineNowFn()
+	e.mu.Lock()
+	limits := e.limits
+	checkpointDue := limits.WALCheckpointInterval > 0 && !now.Before(e.lastCheckpoint.Add(limits.WALCheckpointInterval))
+	if checkpointDue {
+		e.lastCheckpoint = now
+	}
+	e.mu.Unlock()
+
+	if limits.IdleTimeout > 0 {
+		e.evictIdle(now.Add(-limits.IdleTimeout))
+	}
+	if checkpointDue {
+		if err := e.checkpoint(ctx); err != nil {
+			engineLogf("event=wal_checkpoint_failed error=%q", err.Error())
+		}
+	}
+	if e.expirySweepDue(now) {
+		if _, err := e.expireNames(ctx, now); err != nil {
+			engineLogf("event=expiry_sweep_failed error=%q", err.Error())
+		}
+	}
+	if e.snapshotDue(now) {
+		if _, err := e.snapshot(ctx, "", true); err != nil {
+			engineLogf("event=scheduled_snapshot_failed error=%q", err.Error())
+		}
+	}
+}
+
+func (e *Engine) handles() []*workspaceHandle {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]*workspaceHandle, 0, len(e.service))
+	for _, entry := range e.service {
+		out = append(out, entry.handle)
+	}
+	return out
+}
+
+// evictIdle closes every workspace database that has no in-flight call and
+// was last used before cutoff.
+func (e *Engine) evictIdle(cutoff time.Time) {
+	for _, h := range e.handles() {
+		evicted, err := h.evictIfIdle(cutoff)
+		if evicted {
+			e.evictions.Add(1)
+		}
+		if err != nil {
+			engineLogf("event=workspace_close_failed workspace=%s error=%q", h.id, err.Error())
+		}
+	}
+}
+
+// enforceOpenLimit closes the least recently used idle databases until at
+// most MaxOpenWorkspaces remain open. Workspaces with in-flight calls are
+// never closed, so the limit can be exceeded briefly under load.
+func (e *Engine) enforceOpenLimit() {
+	e.mu.Lock()
+	limit := e.limits.MaxOpenWorkspaces
+	e.mu.Unlock()
+
+	type candidate struct {
+		handle   *workspaceHandle
+		lastUsed time.Time
+	}
+	var open []candidate
+	for _, h := range e.handles() {
+		h.mu.Lock()
+		if h.repo != nil {
+			open = append(open, candidate{handle: h, lastUsed: h.lastUsed})
+		}
+		h.mu.Unlock()
+	}
+	if len(open) <= limit {
+		return
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].lastUsed.Before(open[j].lastUsed) })
+	excess := len(open) - limit
+	for _, c := range open {
+		if excess == 0 {
+			return
+		}
+		evicted, err := c.handle.evictIfIdle(time.Time{})
+		if evicted {
+			e.evictions.Add(1)
+			excess--
+		}
+		if err != nil {
+			engineLogf("event=workspace_close_failed workspace=%s error=%q", c.handle.id, err.Error())
+		}
+	}
+}
+
+// checkpoint truncates the WAL of the registry and every open workspace.
+func (e *Engine) checkpoint(ctx context.Context) error {
+	var firstErr error
+	if checkpointer, ok := e.registry.(interface{ Checkpoint(context.Context) error }); ok {
+		if err := checkpointer.Checkpoint(ctx); err != nil {
+			firstErr = err
+		}
+	}
+	for _, h := range e.handles() {
+		if err := h.checkpoint(ctx); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("workspace %s: %w", h.id, err)
+		}
+	}
+	return firstErr
+}
+
 func (e *Engine) resolveWorkspace(ctx context.Context, sel WorkspaceSelector, owner string) (string, *artifacts.Service, error) {
 	workspaceID, roots, err := normalizeWorkspaceSelector(sel)
 	if err != nil {
 		return "", nil, err
 	}
+	if len(roots) > 0 {
+		if workspaceID, err = e.resolveRootsWorkspace(ctx, workspaceID, roots); err != nil {
+			return "", nil, err
+		}
+	}
 	if err := e.registry.EnsureWorkspace(ctx, workspaceID, roots, owner); err != nil {
 		return "", nil, err
 	}
@@ -113,33 +394,201 @@ func (e *Engine) serviceForWorkspaceID(ctx context.Context, workspaceID string)
 	}
 
 	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil, errEngineClosed
+	}
 	if entry, ok := e.service[workspaceID]; ok {
-		e.mu.Unlock()
 		return entry.service, nil
 	}
-	e.mu.Unlock()
+	handle := &workspaceHandle{engine: e, id: workspaceID, root: e.workspaceRoot(workspaceID), compress: e.compress, keys: e.keys, encrypt: e.encrypt}
+	entry := serviceEntry{service: artifacts.NewService(handle), handle: handle}
+	entry.service.SetQuota(e.quota)
+	entry.service.SetExpiryPolicy(e.expiry)
+	entry.service.SetJSONValidator(e.schemas)
+	e.service[workspaceID] = entry
+	return entry.service, nil
+}
+
+func (e *Engine) workspaceRoot(workspaceID string) string {
+	if workspaceID == workspaces.GlobalWorkspaceID {
+		return e.baseStoreRoot
+	}
+	return filepath.Join(e.baseStoreRoot, workspaceID)
+}
+
+func (e *Engine) openWorkspaceIDs() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ids := make([]string, 0, len(e.service))
+	for id, entry := range e.service {
+		if entry.handle.isOpen() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
 
-	workspaceRoot := e.baseStoreRoot
-	if workspaceID != workspaces.GlobalWorkspaceID {
-		workspaceRoot = filepath.Join(e.baseStoreRoot, workspaceID)
+// workspaceStatus reports blob store and quota usage for the global workspace
+// and every registered one. Results are cached for blobUsageTTL. Usage is read
+// without taking workspace handles, so a status scrape neither opens idle
+// workspaces nor keeps them from being evicted.
+func (e *Engine) workspaceStatus(ctx context.Context) ([]WorkspaceStatus, error) {
+	e.usageMu.Lock()
+	defer e.usageMu.Unlock()
+	if e.usage != nil && time.Now().Before(e.usageExpiry) {
+		return e.markOpen(e.usage), nil
 	}
-	repo, err := artsqlite.NewArtifactRepository(workspaceRoot)
+
+	ids, err := e.knownWorkspaceIDs(ctx)
 	if err != nil {
 		return nil, err
 	}
-	entry := serviceEntry{
-		service: artifacts.NewService(repo),
-		closeFn: repo.Close,
+
+	out := make([]WorkspaceStatus, 0, len(ids))
+	for _, id := range ids {
+		usage, err := artsqlite.WorkspaceBlobUsage(e.workspaceRoot(id))
+		if err != nil {
+			return nil, fmt.Errorf("blob usage for %s: %w", id, err)
+		}
+		logical, err := artsqlite.WorkspaceUsage(ctx, e.workspaceRoot(id))
+		if err != nil {
+			return nil, fmt.Errorf("usage for %s: %w", id, err)
+		}
+		out = append(out, WorkspaceStatus{WorkspaceID: id, BlobFiles: usage.Files, BlobBytes: usage.Bytes, Usage: logical})
 	}
+	e.usage = out
+	e.usageExpiry = time.Now().Add(blobUsageTTL)
+	return e.markOpen(out), nil
+}
 
+// knownWorkspaceIDs returns the global workspace and every registered one.
+func (e *Engine) knownWorkspaceIDs(ctx context.Context) ([]string, error) {
+	registered, err := e.registry.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{workspaces.GlobalWorkspaceID}
+	for _, ws := range registered {
+		if ws.WorkspaceID != workspaces.GlobalWorkspaceID {
+			ids = append(ids, ws.WorkspaceID)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// verify checks the workspace named by sel, or every known workspace when
+// sel is empty. Workspaces are verified through their cached handles so a
+// repair never races an eviction.
+func (e *Engine) verify(ctx context.Context, sel WorkspaceSelector, repair bool) ([]WorkspaceIntegrity, error) {
+	var ids []string
+	if strings.TrimSpace(sel.WorkspaceID) == "" && len(sel.Roots) == 0 {
+		known, err := e.knownWorkspaceIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ids = known
+	} else {
+		workspaceID, _, err := normalizeWorkspaceSelector(sel)
+		if err != nil {
+			return nil, err
+		}
+		ids = []string{workspaceID}
+	}
+
+	out := make([]WorkspaceIntegrity, 0, len(ids))
+	for _, id := range ids {
+		handle, err := e.handleForWorkspaceID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		report, err := handle.Verify(ctx, repair)
+		if err != nil {
+			return nil, fmt.Errorf("verify workspace %s: %w", id, err)
+		}
+		out = append(out, WorkspaceIntegrity{WorkspaceID: id, IntegrityReport: report})
+	}
+	if repair {
+		e.invalidateUsage()
+	}
+	return out, nil
+}
+
+// migrateLegacy replays a legacy filestore into the selected workspace.
+func (e *Engine) migrateLegacy(ctx context.Context, req MigrateLegacyRequest, owner string) (MigrateLegacyResponse, error) {
+	root := strings.TrimSpace(req.LegacyRoot)
+	if root == "" || !filepath.IsAbs(root) {
+		return MigrateLegacyResponse{}, fmt.Errorf("%w: legacyRoot must be an absolute path", artifacts.ErrInvalidInput)
+	}
+	if !filestore.IsLegacyRoot(root) {
+		return MigrateLegacyResponse{}, fmt.Errorf("%w: %s is not a legacy artifact store (no names.json)", artifacts.ErrInvalidInput, root)
+	}
+	workspaceID, _, err := e.resolveWorkspace(ctx, req.Workspace, owner)
+	if err != nil {
+		return MigrateLegacyResponse{}, err
+	}
+	handle, err := e.handleForWorkspaceID(ctx, workspaceID)
+	if err != nil {
+		return MigrateLegacyResponse{}, err
+	}
+	report, err := filestore.Migrate(ctx, filestore.New(root), handle, req.DryRun)
+	if err != nil {
+		return MigrateLegacyResponse{}, err
+	}
+	if report.Imported > 0 && !req.DryRun {
+		e.invalidateUsage()
+	}
+	return MigrateLegacyResponse{WorkspaceID: workspaceID, MigrateReport: report}, nil
+}
+
+// rotateKey replaces the master key and rewraps the data key of every known
+// workspace that has one.
+func (e *Engine) rotateKey(ctx context.Context) (RotateKeyResponse, error) {
+	keys, _ := e.encryption()
+	if !keys.Enabled() {
+		return RotateKeyResponse{}, fmt.Errorf("%w: encryption at rest is not enabled", artifacts.ErrInvalidInput)
+	}
+	ids, err := e.knownWorkspaceIDs(ctx)
+	if err != nil {
+		return RotateKeyResponse{}, err
+	}
+	roots := make([]string, len(ids))
+	idByRoot := make(map[string]string, len(ids))
+	for i, id := range ids {
+		roots[i] = e.workspaceRoot(id)
+		idByRoot[roots[i]] = id
+	}
+	report, err := keys.Rotate(roots)
+	if err != nil {
+		return RotateKeyResponse{}, fmt.Errorf("rotate master key: %w", err)
+	}
+	out := RotateKeyResponse{MasterKeyID: report.MasterKeyID, Rewrapped: make([]string, 0, len(report.Rewrapped))}
+	for _, root := range report.Rewrapped {
+		out.Rewrapped = append(out.Rewrapped, idByRoot[root])
+	}
+	return out, nil
+}
+
+func (e *Engine) handleForWorkspaceID(ctx context.Context, workspaceID string) (*workspaceHandle, error) {
+	if _, err := e.serviceForWorkspaceID(ctx, workspaceID); err != nil {
+		return nil, err
+	}
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	if existing, ok := e.service[workspaceID]; ok {
-		if closeErr := repo.Close(); closeErr != nil {
-			_ = closeErr
-		}
-		return existing.service, nil
+	return e.service[workspaceID].handle, nil
+}
+
+func (e *Engine) markOpen(in []WorkspaceStatus) []WorkspaceStatus {
+	open := map[string]bool{}
+	for _, id := range e.openWorkspaceIDs() {
+		open[id] = true
 	}
-	e.service[workspaceID] = entry
-	return entry.service, nil
+	out := make([]WorkspaceStatus, len(in))
+	for i, ws := range in {
+		ws.Open = open[ws.WorkspaceID]
+		out[i] = ws
+	}
+	return out
 }
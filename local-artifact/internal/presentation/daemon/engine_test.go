@@ -0,0 +1,287 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
+)
+
+func mustEngineService(t *testing.T, engine *Engine, workspaceID string) *artifacts.Service {
+	t.Helper()
+	_, svc, err := engine.resolveWorkspace(context.Background(), WorkspaceSelector{WorkspaceID: workspaceID}, "test")
+	if err != nil {
+		t.Fatalf("resolve workspace %s: %v", workspaceID, err)
+	}
+	return svc
+}
+
+func mustEngineSave(t *testing.T, svc *artifacts.Service, name, text string) {
+	t.Helper()
+	if _, err := svc.SaveText(context.Background(), artifacts.SaveTextInput{Name: name, Text: text}); err != nil {
+		t.Fatalf("save %s: %v", name, err)
+	}
+}
+
+func setEngineClock(t *testing.T, now *time.Time) {
+	t.Helper()
+	prev := engineNowFn
+	engineNowFn = func() time.Time { return *now }
+	t.Cleanup(func() { engineNowFn = prev })
+}
+
+func TestEngine_EvictsLeastRecentlyUsedOverOpenLimit(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	setEngineClock(t, &now)
+	engine := newDaemonEngine(t)
+	engine.SetLimits(EngineLimits{MaxOpenWorkspaces: 2})
+
+	wsA, wsB, wsC := strings.Repeat("a", 64), strings.Repeat("b", 64), strings.Repeat("c", 64)
+	svcA := mustEngineService(t, engine, wsA)
+	mustEngineSave(t, svcA, "plan/a", "a")
+	for _, id := range []string{wsB, wsC} {
+		now = now.Add(time.Second)
+		mustEngineSave(t, mustEngineService(t, engine, id), "plan/x", id)
+	}
+
+	if got := engine.openWorkspaceIDs(); strings.Join(got, ",") != wsB+","+wsC {
+		t.Fatalf("open workspaces=%v, want the two most recently used", got)
+	}
+	if got := engine.evictions.Load(); got != 1 {
+		t.Fatalf("evictions=%d want 1", got)
+	}
+
+	// The cached service reopens its database transparently.
+	now = now.Add(time.Second)
+	if _, data, err := svcA.Get(context.Background(), artifacts.Selector{Name: "plan/a"}); err != nil || string(data) != "a" {
+		t.Fatalf("get after eviction: data=%q err=%v", data, err)
+	}
+	if got := engine.openWorkspaceIDs(); strings.Join(got, ",") != wsA+","+wsC {
+		t.Fatalf("open workspaces=%v after reopen", got)
+	}
+}
+
+func TestEngine_IdleEvictionSkipsInFlightCalls(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	setEngineClock(t, &now)
+	engine := newDaemonEngine(t)
+	engine.SetLimits(EngineLimits{IdleTimeout: time.Minute, WALCheckpointInterval: -1})
+
+	busy, idle := strings.Repeat("a", 64), strings.Repeat("b", 64)
+	mustEngineSave(t, mustEngineService(t, engine, busy), "plan/x", "busy")
+	mustEngineSave(t, mustEngineService(t, engine, idle), "plan/x", "idle")
+
+	engine.mu.Lock()
+	handle := engine.service[busy].handle
+	engine.mu.Unlock()
+	if _, err := handle.acquire(); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	engine.maintain(context.Background())
+	if got := engine.openWorkspaceIDs(); len(got) != 1 || got[0] != busy {
+		t.Fatalf("open workspaces=%v, want only the in-flight one", got)
+	}
+
+	handle.release()
+	now = now.Add(2 * time.Minute)
+	engine.maintain(context.Background())
+	if got := engine.openWorkspaceIDs(); len(got) != 0 {
+		t.Fatalf("open workspaces=%v, want none after release", got)
+	}
+}
+
+func TestEngine_WorkspaceStatusDoesNotOpenWorkspaces(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	setEngineClock(t, &now)
+	engine := newDaemonEngine(t)
+	engine.SetLimits(EngineLimits{IdleTimeout: time.Minute, WALCheckpointInterval: -1})
+
+	wsA, wsB := strings.Repeat("a", 64), strings.Repeat("b", 64)
+	mustEngineSave(t, mustEngineService(t, engine, wsA), "plan/x", "hello")
+	mustEngineSave(t, mustEngineService(t, engine, wsB), "plan/x", "hi")
+	now = now.Add(2 * time.Minute)
+	engine.maintain(context.Background())
+	if got := engine.openWorkspaceIDs(); len(got) != 0 {
+		t.Fatalf("open workspaces=%v, want none before status", got)
+	}
+
+	status, err := engine.workspaceStatus(context.Background())
+	if err != nil {
+		t.Fatalf("workspaceStatus: %v", err)
+	}
+	if got := engine.openWorkspaceIDs(); len(got) != 0 {
+		t.Fatalf("open workspaces=%v after status, want none", got)
+	}
+	want := map[string]int64{workspaces.GlobalWorkspaceID: 0, wsA: int64(len("hello")), wsB: int64(len("hi"))}
+	if len(status) != len(want) {
+		t.Fatalf("status=%+v, want %d workspaces", status, len(want))
+	}
+	for _, ws := range status {
+		if ws.Open || ws.Usage.Bytes != want[ws.WorkspaceID] {
+			t.Fatalf("unexpected status for %s: %+v", ws.WorkspaceID, ws)
+		}
+	}
+}
+
+func TestEngine_CheckpointTruncatesWAL(t *testing.T) {
+	engine := newDaemonEngine(t)
+	svc := mustEngineService(t, engine, workspaces.GlobalWorkspaceID)
+	for i := 0; i < 20; i++ {
+		mustEngineSave(t, svc, "plan/wal", strings.Repeat("x", 4096))
+	}
+
+	walPath := filepath.Join(engine.baseStoreRoot, "meta.sqlite-wal")
+	if info, err := os.Stat(walPath); err != nil || info.Size() == 0 {
+		t.Fatalf("expected non-empty wal before checkpoint: err=%v", err)
+	}
+	if err := engine.checkpoint(context.Background()); err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+	if info, err := os.Stat(walPath); err == nil && info.Size() != 0 {
+		t.Fatalf("wal size=%d after checkpoint, want 0", info.Size())
+	}
+}
+
+type failingCheckpointRegistry struct {
+	workspaces.Registry
+}
+
+func (failingCheckpointRegistry) Checkpoint(context.Context) error {
+	return errors.New("disk full")
+}
+
+func TestEngine_MaintenanceLogsCheckpointFailures(t *testing.T) {
+	var logged []string
+	prev := engineLogf
+	engineLogf = func(format string, args ...any) { logged = append(logged, fmt.Sprintf(format, args...)) }
+	t.Cleanup(func() { engineLogf = prev })
+
+	engine := newDaemonEngine(t)
+	engine.SetLimits(EngineLimits{IdleTimeout: -1, WALCheckpointInterval: time.Minute})
+	registry := engine.registry
+	engine.registry = failingCheckpointRegistry{Registry: registry}
+	t.Cleanup(func() { engine.registry = registry })
+
+	engine.maintain(context.Background())
+	if len(logged) != 1 || !strings.Contains(logged[0], "event=wal_checkpoint_failed") || !strings.Contains(logged[0], "disk full") {
+		t.Fatalf("logged=%q, want one checkpoint failure", logged)
+	}
+}
+
+func TestEngine_IdentityCacheIsBounded(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	setEngineClock(t, &now)
+	engine := newDaemonEngine(t)
+	engine.SetWorkspaceIdentityMode(func(string) (string, error) { return workspaces.IdentityModeRoots, nil })
+
+	fill := func(expires time.Time) {
+		engine.identityCache = map[string]cachedIdentity{}
+		for i := 0; i < maxIdentityCacheEntries; i++ {
+			engine.identityCache[fmt.Sprintf("%064x", i)] = cachedIdentity{workspaceID: "global", expires: expires}
+		}
+	}
+	rootsID := strings.Repeat("f", 64)
+	for _, expires := range []time.Time{now.Add(-time.Second), now.Add(time.Minute)} {
+		fill(expires)
+		if _, err := engine.resolveRootsWorkspace(context.Background(), rootsID, []string{"file:///tmp/repo"}); err != nil {
+			t.Fatalf("resolve: %v", err)
+		}
+		if got := len(engine.identityCache); got != 1 {
+			t.Fatalf("identity cache holds %d entries, want only the new one", got)
+		}
+	}
+}
+
+func TestEngine_ScheduledSnapshotsArePruned(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	setEngineClock(t, &now)
+	engine := newDaemonEngine(t)
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	engine.SetBackupPolicy(BackupPolicy{Dir: dir, Interval: time.Hour, Retention: 2})
+	mustEngineSave(t, mustEngineService(t, engine, workspaces.GlobalWorkspaceID), "plan/a", "a")
+
+	manual, err := engine.snapshot(context.Background(), "", false)
+	if err != nil {
+		t.Fatalf("manual snapshot: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		engine.maintain(context.Background())
+		now = now.Add(30 * time.Minute)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "stale"+snapshotPartialSuffix), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	engine.maintain(context.Background())
+
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		t.Fatalf("list snapshots: %v", err)
+	}
+	var ids []string
+	for _, s := range snapshots {
+		ids = append(ids, s.ID)
+	}
+	want := []string{"20260102T050405Z", "20260102T040405Z", manual.ID}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Fatalf("snapshots=%v want %v", ids, want)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != len(want) {
+		t.Fatalf("expected partial snapshots to be removed, got %d entries (%v)", len(entries), err)
+	}
+}
+
+func TestEngine_MaintenanceExpiresNamesInClosedWorkspaces(t *testing.T) {
+	now := time.Now()
+	setEngineClock(t, &now)
+	engine := newDaemonEngine(t)
+	engine.SetExpiryPolicy(artifacts.ExpiryPolicy{"scratch/*": time.Hour})
+	ctx := context.Background()
+
+	workspaceID := strings.Repeat("e", 64)
+	svc := mustEngineService(t, engine, workspaceID)
+	mustEngineSave(t, svc, "scratch/dump", "x")
+	mustEngineSave(t, svc, "plan/keep", "y")
+	if err := engine.registry.EnsureWorkspace(ctx, workspaceID, nil, "test"); err != nil {
+		t.Fatalf("register workspace: %v", err)
+	}
+	engine.evictIdle(now.Add(time.Minute))
+
+	now = now.Add(2 * time.Hour)
+	engine.maintain(ctx)
+	if _, err := svc.Resolve(ctx, "scratch/dump"); !errors.Is(err, artifacts.ErrNotFound) {
+		t.Fatalf("expected scratch artifact to expire, got %v", err)
+	}
+	if _, err := svc.Resolve(ctx, "plan/keep"); err != nil {
+		t.Fatalf("expected artifact without ttl to survive: %v", err)
+	}
+	engine.evictIdle(now.Add(time.Minute))
+	mustEngineSave(t, svc, "scratch/dump", "again")
+	engine.maintain(ctx)
+	if _, err := svc.Resolve(ctx, "scratch/dump"); err != nil {
+		t.Fatalf("expected the next sweep to wait for its interval: %v", err)
+	}
+}
+
+func TestEngine_ClosedEngineRejectsCalls(t *testing.T) {
+	engine, err := NewEngine(t.TempDir())
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	svc := mustEngineService(t, engine, workspaces.GlobalWorkspaceID)
+	if err := engine.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, err := svc.List(context.Background(), "", 10); err == nil {
+		t.Fatalf("expected error from service of closed engine")
+	}
+}
@@ -13,8 +13,10 @@ const (
 	CodeConflict           = "CONFLICT"
 	CodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"
 	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
 	CodeInternal           = "INTERNAL"
 	CodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	CodeQuotaExceeded      = "QUOTA_EXCEEDED"
 )
 
 type Envelope struct {
@@ -35,8 +37,12 @@ func mapCoreError(err error) (int, *EnvelopeError) {
 		return http.StatusOK, nil
 	case errors.Is(err, artifacts.ErrNotFound):
 		return http.StatusNotFound, &EnvelopeError{Code: CodeNotFound, Message: err.Error()}
+	case errors.Is(err, artifacts.ErrNotShared):
+		return http.StatusForbidden, &EnvelopeError{Code: CodeForbidden, Message: err.Error()}
 	case errors.Is(err, artifacts.ErrAliasExists), errors.Is(err, artifacts.ErrConflict):
 		return http.StatusConflict, &EnvelopeError{Code: CodeConflict, Message: err.Error()}
+	case errors.Is(err, artifacts.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage, &EnvelopeError{Code: CodeQuotaExceeded, Message: err.Error()}
 	case errors.Is(err, artifacts.ErrInvalidInput),
 		errors.Is(err, artifacts.ErrNameRequired),
 		errors.Is(err, artifacts.ErrRefRequired),
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 
@@ -18,6 +19,7 @@ func TestMapCoreError(t *testing.T) {
 		{name: "invalid", err: artifacts.ErrInvalidInput, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidInput},
 		{name: "not found", err: artifacts.ErrNotFound, wantStatus: http.StatusNotFound, wantCode: CodeNotFound},
 		{name: "conflict", err: artifacts.ErrConflict, wantStatus: http.StatusConflict, wantCode: CodeConflict},
+		{name: "quota", err: fmt.Errorf("%w: too big", artifacts.ErrQuotaExceeded), wantStatus: http.StatusInsufficientStorage, wantCode: CodeQuotaExceeded},
 		{name: "internal", err: errors.New("boom"), wantStatus: http.StatusInternalServerError, wantCode: CodeInternal},
 	}
 
@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+// expirySweepInterval is how often the engine tombstones expired names in
+// every known workspace.
+var expirySweepInterval = 5 * time.Minute
+
+// SetExpiryPolicy sets the default TTLs by name pattern. Like SetQuota it
+// applies to workspaces opened afterwards.
+func (e *Engine) SetExpiryPolicy(policy artifacts.ExpiryPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expiry = policy
+}
+
+func (e *Engine) expirySweepDue(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if now.Before(e.lastExpirySweep.Add(expirySweepInterval)) {
+		return false
+	}
+	e.lastExpirySweep = now
+	return true
+}
+
+// expireNames tombstones the expired names of every known workspace. It
+// keeps going past a failing workspace and returns the first error.
+func (e *Engine) expireNames(ctx context.Context, now time.Time) ([]artifacts.ArtifactVersion, error) {
+	ids, err := e.knownWorkspaceIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var expired []artifacts.ArtifactVersion
+	var firstErr error
+	for _, id := range ids {
+		handle, err := e.handleForWorkspaceID(ctx, id)
+		if err == nil {
+			var out []artifacts.ArtifactVersion
+			out, err = handle.expire(ctx, now)
+			expired = append(expired, out...)
+			// The sweep bypasses the service, so its watchers hear of the
+			// tombstones here.
+			if svc, svcErr := e.serviceForWorkspaceID(ctx, id); svcErr == nil {
+				svc.Announce(out...)
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("expire workspace %s: %w", id, err)
+		}
+	}
+	if len(expired) > 0 {
+		e.invalidateUsage()
+	}
+	return expired, firstErr
+}
+
+// saveExpiry turns the ttl or expiresAt of a save request into the expiry
+// passed to the service; zero leaves the name's policy default.
+func saveExpiry(ttl string, expiresAt *time.Time) (time.Time, error) {
+	ttl = strings.TrimSpace(ttl)
+	if ttl != "" && expiresAt != nil {
+		return time.Time{}, fmt.Errorf("%w: ttl and expiresAt are mutually exclusive", artifacts.ErrInvalidInput)
+	}
+	if expiresAt != nil {
+		return *expiresAt, nil
+	}
+	if ttl == "" {
+		return time.Time{}, nil
+	}
+	d, err := time.ParseDuration(ttl)
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("%w: ttl must be a positive duration such as \"24h\"", artifacts.ErrInvalidInput)
+	}
+	return engineNowFn().Add(d), nil
+}
@@ -61,3 +61,9 @@ func removeIfExists(path string) {
 		_ = err
 	}
 }
+
+func removeAllIgnore(path string) {
+	if err := os.RemoveAll(path); err != nil {
+		_ = err
+	}
+}
@@ -0,0 +1,200 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request duration
+// histogram exposed on /daemon/v1/metrics.
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type routeStats struct {
+	byCode  map[int]uint64
+	buckets []uint64
+	count   uint64
+	errors  uint64
+	sum     time.Duration
+	max     time.Duration
+}
+
+// Metrics collects request and auth counters for the daemon. The zero value
+// is not usable; use NewMetrics.
+type Metrics struct {
+	mu     sync.Mutex
+	routes map[string]*routeStats
+
+	authFailures atomic.Uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{routes: map[string]*routeStats{}}
+}
+
+func (m *Metrics) RecordAuthFailure() {
+	m.authFailures.Add(1)
+}
+
+func (m *Metrics) AuthFailures() uint64 {
+	return m.authFailures.Load()
+}
+
+func (m *Metrics) observe(route string, status int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.routes[route]
+	if stats == nil {
+		stats = &routeStats{byCode: map[int]uint64{}, buckets: make([]uint64, len(latencyBuckets))}
+		m.routes[route] = stats
+	}
+	stats.byCode[status]++
+	stats.count++
+	if status >= http.StatusBadRequest {
+		stats.errors++
+	}
+	stats.sum += elapsed
+	stats.max = max(stats.max, elapsed)
+	seconds := elapsed.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			stats.buckets[i]++
+		}
+	}
+}
+
+// Instrument records the status and latency of every request under route.
+func (m *Metrics) Instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		started := time.Now()
+		next(rec, r)
+		m.observe(route, rec.status, time.Since(started))
+	}
+}
+
+func (m *Metrics) routeMetrics() []RouteMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]RouteMetrics, 0, len(m.routes))
+	for route, stats := range m.routes {
+		item := RouteMetrics{
+			Route:     route,
+			Requests:  stats.count,
+			Errors:    stats.errors,
+			MaxMillis: float64(stats.max.Microseconds()) / 1000,
+		}
+		if stats.count > 0 {
+			item.AvgMillis = float64(stats.sum.Microseconds()) / 1000 / float64(stats.count)
+		}
+		out = append(out, item)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Route < out[j].Route })
+	return out
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// writePrometheus renders the status snapshot and request histograms in the
+// Prometheus text exposition format.
+func (m *Metrics) writePrometheus(w io.Writer, status StatusResponse) error {
+	var b strings.Builder
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("ccsubagentsd_build_info", "gauge", "Daemon build information.")
+	fmt.Fprintf(&b, "ccsubagentsd_build_info{version=%s} 1\n", promLabel(status.Version))
+	metric("ccsubagentsd_process_pid", "gauge", "Daemon process id.")
+	fmt.Fprintf(&b, "ccsubagentsd_process_pid %d\n", status.PID)
+	metric("ccsubagentsd_start_time_seconds", "gauge", "Daemon start time since the unix epoch.")
+	fmt.Fprintf(&b, "ccsubagentsd_start_time_seconds %d\n", status.StartedAt.Unix())
+	metric("ccsubagentsd_uptime_seconds", "gauge", "Seconds since the daemon started.")
+	fmt.Fprintf(&b, "ccsubagentsd_uptime_seconds %d\n", status.UptimeSeconds)
+	metric("ccsubagentsd_open_workspaces", "gauge", "Workspace services with an open database.")
+	fmt.Fprintf(&b, "ccsubagentsd_open_workspaces %d\n", len(status.OpenWorkspaces))
+	metric("ccsubagentsd_max_open_workspaces", "gauge", "Limit on workspace databases held open at once.")
+	fmt.Fprintf(&b, "ccsubagentsd_max_open_workspaces %d\n", status.MaxOpenWorkspaces)
+	metric("ccsubagentsd_workspace_evictions_total", "counter", "Workspace databases closed for being idle or over the open limit.")
+	fmt.Fprintf(&b, "ccsubagentsd_workspace_evictions_total %d\n", status.WorkspaceEvictions)
+	metric("ccsubagentsd_sqlite_busy_retries_total", "counter", "Writes retried after SQLite reported busy or locked.")
+	fmt.Fprintf(&b, "ccsubagentsd_sqlite_busy_retries_total %d\n", status.SQLiteBusyRetries)
+	metric("ccsubagentsd_auth_failures_total", "counter", "Requests rejected for a missing or invalid token.")
+	fmt.Fprintf(&b, "ccsubagentsd_auth_failures_total %d\n", status.AuthFailures)
+
+	metric("ccsubagentsd_blob_store_bytes", "gauge", "Bytes stored in the blob store of each workspace.")
+	for _, ws := range status.Workspaces {
+		fmt.Fprintf(&b, "ccsubagentsd_blob_store_bytes{workspace=%s} %d\n", promLabel(ws.WorkspaceID), ws.BlobBytes)
+	}
+	metric("ccsubagentsd_quota_max_total_bytes", "gauge", "Configured per-workspace storage quota in bytes, 0 when unlimited.")
+	fmt.Fprintf(&b, "ccsubagentsd_quota_max_total_bytes %d\n", status.Quota.MaxTotalBytes)
+	metric("ccsubagentsd_blob_store_files", "gauge", "Files in the blob store of each workspace.")
+	for _, ws := range status.Workspaces {
+		fmt.Fprintf(&b, "ccsubagentsd_blob_store_files{workspace=%s} %d\n", promLabel(ws.WorkspaceID), ws.BlobFiles)
+	}
+
+	m.mu.Lock()
+	routes := make([]string, 0, len(m.routes))
+	for route := range m.routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	metric("ccsubagentsd_http_requests_total", "counter", "HTTP requests by route and status code.")
+	for _, route := range routes {
+		stats := m.routes[route]
+		codes := make([]int, 0, len(stats.byCode))
+		for code := range stats.byCode {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&b, "ccsubagentsd_http_requests_total{route=%s,code=\"%d\"} %d\n", promLabel(route), code, stats.byCode[code])
+		}
+	}
+	metric("ccsubagentsd_http_request_duration_seconds", "histogram", "HTTP request latency by route.")
+	for _, route := range routes {
+		stats := m.routes[route]
+		label := promLabel(route)
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(&b, "ccsubagentsd_http_request_duration_seconds_bucket{route=%s,le=\"%s\"} %d\n", label, strconv.FormatFloat(bound, 'g', -1, 64), stats.buckets[i])
+		}
+		fmt.Fprintf(&b, "ccsubagentsd_http_request_duration_seconds_bucket{route=%s,le=\"+Inf\"} %d\n", label, stats.count)
+		fmt.Fprintf(&b, "ccsubagentsd_http_request_duration_seconds_sum{route=%s} %s\n", label, strconv.FormatFloat(stats.sum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(&b, "ccsubagentsd_http_request_duration_seconds_count{route=%s} %d\n", label, stats.count)
+	}
+	m.mu.Unlock()
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func promLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
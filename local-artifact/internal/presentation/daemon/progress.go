@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"context"
+	"io"
+)
+
+const (
+	TransferUpload   = "upload"
+	TransferDownload = "download"
+)
+
+// TransferProgressFunc receives byte counts while a request body is sent and
+// while the response body is read. total is 0 when the size is unknown.
+type TransferProgressFunc func(phase string, done, total int64)
+
+type transferProgressKey struct{}
+
+// WithTransferProgress makes client calls made with ctx report transfer
+// progress to fn.
+func WithTransferProgress(ctx context.Context, fn TransferProgressFunc) context.Context {
+	return context.WithValue(ctx, transferProgressKey{}, fn)
+}
+
+func transferProgressFrom(ctx context.Context) TransferProgressFunc {
+	fn, _ := ctx.Value(transferProgressKey{}).(TransferProgressFunc)
+	return fn
+}
+
+type progressReader struct {
+	r     io.Reader
+	phase string
+	done  int64
+	total int64
+	fn    TransferProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.done += int64(n)
+		p.fn(p.phase, p.done, p.total)
+	}
+	return n, err
+}
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -16,6 +17,9 @@ import (
 
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/blobstore"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/jsonschemas"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/keyring"
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/web"
 )
 
@@ -30,7 +34,28 @@ type RunConfig struct {
 	WebAddr     string
 	Token       string
 	DisableAuth bool
+	Version     string
 	Stderr      io.Writer
+
+	// Limits bounds open workspace databases; zero values use the defaults.
+	Limits EngineLimits
+	// Quota limits what each workspace may store; zero fields are unlimited.
+	Quota artifacts.Quota
+	// Compression picks the on-disk encoding of new blobs by MIME type.
+	Compression blobstore.CompressionPolicy
+	// Expiry gives names a default TTL by pattern when a save sets none.
+	Expiry artifacts.ExpiryPolicy
+	// JSONSchemas maps name patterns to the JSON Schema saves under them must
+	// satisfy; a nil schema exempts a pattern. Run fails on an invalid schema.
+	JSONSchemas map[string]json.RawMessage
+	// EncryptAtRest encrypts new blobs with per-workspace keys wrapped by the
+	// master key in StateDir/daemon. Encrypted workspaces are readable either way.
+	EncryptAtRest bool
+	// Backup configures snapshots; Dir defaults to StateDir/snapshots.
+	Backup BackupPolicy
+	// WorkspaceIdentity looks up the identity mode of a workspace root; nil
+	// keys every workspace by its roots.
+	WorkspaceIdentity WorkspaceIdentityModeFunc
 }
 
 type apiAlreadyListeningError struct {
@@ -95,9 +120,41 @@ func Run(ctx context.Context, cfg RunConfig) error {
 			_ = closeErr
 		}
 	}()
+	engine.SetLimits(cfg.Limits)
+	engine.SetQuota(cfg.Quota)
+	engine.SetCompression(cfg.Compression)
+	engine.SetExpiryPolicy(cfg.Expiry)
+	if len(cfg.JSONSchemas) > 0 {
+		schemas, err := jsonschemas.Compile(cfg.JSONSchemas)
+		if err != nil {
+			return fmt.Errorf("load json-schemas: %w", err)
+		}
+		engine.SetJSONValidator(schemas)
+	}
+	keys, err := keyring.Open(filepath.Join(cfg.StateDir, "daemon"))
+	if err != nil {
+		return fmt.Errorf("load master key: %w", err)
+	}
+	if cfg.EncryptAtRest {
+		if err := keys.Enable(); err != nil {
+			return fmt.Errorf("create master key: %w", err)
+		}
+	}
+	engine.SetEncryption(keys, cfg.EncryptAtRest)
+	backup := cfg.Backup
+	if backup.Dir == "" {
+		backup.Dir = filepath.Join(cfg.StateDir, "snapshots")
+	}
+	engine.SetBackupPolicy(backup)
+	engine.SetWorkspaceIdentityMode(cfg.WorkspaceIdentity)
+	engine.startMaintenance()
 
 	daemonServer := NewServer(engine, "daemon")
-	apiHandler := AuthMiddleware(token, daemonServer.Routes(), AuthOptions{SkipPathPrefix: "/daemon/v1/health"})
+	daemonServer.SetVersion(cfg.Version)
+	apiHandler := AuthMiddleware(token, daemonServer.Routes(), AuthOptions{
+		SkipPathPrefix: "/daemon/v1/health",
+		OnUnauthorized: daemonServer.Metrics().RecordAuthFailure,
+	})
 
 	apiListener, apiAddress, err := listenAPI(cfg)
 	if err != nil {
@@ -157,9 +214,14 @@ func Run(ctx context.Context, cfg RunConfig) error {
 		webMux := http.NewServeMux()
 		webMux.Handle("/daemon/v1/", daemonServer.Routes())
 		webMux.Handle("/", webServer.Handler())
-		webHandler := AuthMiddleware(token, webMux, AuthOptions{AllowQueryBootstrap: true, SkipPathPrefix: "/daemon/v1/health"})
+		webHandler := AuthMiddleware(token, webMux, AuthOptions{
+			AllowQueryBootstrap: true,
+			SkipPathPrefix:      "/daemon/v1/health",
+			OnUnauthorized:      daemonServer.Metrics().RecordAuthFailure,
+		})
 
 		webHTTPServer = &http.Server{Addr: cfg.WebAddr, Handler: webHandler}
+		webHTTPServer.RegisterOnShutdown(webServer.CloseStreams)
 		webErrCh = make(chan error, 1)
 		go func() {
 			webErrCh <- webHTTPServer.ListenAndServe()
@@ -5,11 +5,15 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	artsqlite "github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/sqlite"
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/jsonbody"
 )
 
@@ -18,6 +22,9 @@ type Server struct {
 	owner           string
 	maxRequestBytes int64
 	shutdownFn      func()
+	version         string
+	startedAt       time.Time
+	metrics         *Metrics
 	mu              sync.RWMutex
 }
 
@@ -25,7 +32,13 @@ func NewServer(engine *Engine, owner string) *Server {
 	if strings.TrimSpace(owner) == "" {
 		owner = "daemon"
 	}
-	return &Server{engine: engine, owner: owner, maxRequestBytes: DefaultMaxRequestBytes}
+	return &Server{engine: engine, owner: owner, maxRequestBytes: DefaultMaxRequestBytes, startedAt: time.Now().UTC(), metrics: NewMetrics()}
+}
+
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = strings.TrimSpace(version)
 }
 
 func (s *Server) SetShutdownFunc(fn func()) {
@@ -40,16 +53,42 @@ func (s *Server) shutdownFunc() func() {
 	return s.shutdownFn
 }
 
+// Metrics returns the collector the routes report to, so that auth
+// middleware in front of them can record failures too.
+func (s *Server) Metrics() *Metrics {
+	return s.metrics
+}
+
 func (s *Server) Routes() http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/daemon/v1/health", s.handleHealth)
-	mux.HandleFunc("/daemon/v1/control/shutdown", s.handleShutdown)
-	mux.HandleFunc("/daemon/v1/artifacts/save_text", s.handleSaveText)
-	mux.HandleFunc("/daemon/v1/artifacts/save_blob", s.handleSaveBlob)
-	mux.HandleFunc("/daemon/v1/artifacts/resolve", s.handleResolve)
-	mux.HandleFunc("/daemon/v1/artifacts/get", s.handleGet)
-	mux.HandleFunc("/daemon/v1/artifacts/list", s.handleList)
-	mux.HandleFunc("/daemon/v1/artifacts/delete", s.handleDelete)
+	handle := func(route string, handler http.HandlerFunc) {
+		mux.HandleFunc(route, s.metrics.Instrument(route, handler))
+	}
+	handle("/daemon/v1/health", s.handleHealth)
+	handle("/daemon/v1/status", s.handleStatus)
+	handle("/daemon/v1/metrics", s.handleMetrics)
+	handle("/daemon/v1/control/shutdown", s.handleShutdown)
+	handle("/daemon/v1/maintenance/verify", s.handleVerify)
+	handle("/daemon/v1/maintenance/migrate_legacy", s.handleMigrateLegacy)
+	handle("/daemon/v1/maintenance/rotate_key", s.handleRotateKey)
+	handle("/daemon/v1/maintenance/backup", s.handleBackup)
+	handle("/daemon/v1/maintenance/snapshots", s.handleListSnapshots)
+	handle("/daemon/v1/maintenance/restore", s.handleRestore)
+	handle("/daemon/v1/maintenance/identities", s.handleListIdentities)
+	handle("/daemon/v1/maintenance/map_identity", s.handleMapIdentity)
+	handle("/daemon/v1/maintenance/aliases", s.handleListAliases)
+	handle("/daemon/v1/maintenance/alias", s.handleSetAlias)
+	handle("/daemon/v1/artifacts/save_text", s.handleSaveText)
+	handle("/daemon/v1/artifacts/save_blob", s.handleSaveBlob)
+	handle("/daemon/v1/artifacts/save_json", s.handleSaveJSON)
+	handle("/daemon/v1/artifacts/patch_json", s.handlePatchJSON)
+	handle("/daemon/v1/artifacts/resolve", s.handleResolve)
+	handle("/daemon/v1/artifacts/share", s.handleShare)
+	handle("/daemon/v1/artifacts/shares", s.handleListShares)
+	handle("/daemon/v1/artifacts/get", s.handleGet)
+	handle("/daemon/v1/artifacts/list", s.handleList)
+	handle("/daemon/v1/artifacts/delete", s.handleDelete)
+	handle("/daemon/v1/artifacts/batch", s.handleBatch)
 	return mux
 }
 
@@ -86,6 +125,64 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	s.writeOK(w, http.StatusOK, HealthResponse{Status: "ok"})
 }
 
+func (s *Server) statusSnapshot(ctx context.Context) (StatusResponse, error) {
+	s.mu.RLock()
+	version := s.version
+	s.mu.RUnlock()
+	workspaceStatus, err := s.engine.workspaceStatus(ctx)
+	if err != nil {
+		return StatusResponse{}, err
+	}
+	keys, encrypt := s.engine.encryption()
+	return StatusResponse{
+		Status:             "ok",
+		PID:                os.Getpid(),
+		Version:            version,
+		StartedAt:          s.startedAt,
+		UptimeSeconds:      int64(time.Since(s.startedAt).Seconds()),
+		OpenWorkspaces:     s.engine.openWorkspaceIDs(),
+		MaxOpenWorkspaces:  s.engine.maxOpenWorkspaces(),
+		WorkspaceEvictions: s.engine.evictions.Load(),
+		Quota:              s.engine.Quota(),
+		EncryptAtRest:      encrypt,
+		MasterKeyID:        keys.MasterKeyID(),
+		Workspaces:         workspaceStatus,
+		Routes:             s.metrics.routeMetrics(),
+		SQLiteBusyRetries:  artsqlite.BusyRetries(),
+		AuthFailures:       s.metrics.AuthFailures(),
+	}, nil
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	status, err := s.statusSnapshot(r.Context())
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, status)
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	status, err := s.statusSnapshot(r.Context())
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := s.metrics.writePrometheus(w, status); err != nil {
+		log.Printf("event=write_metrics_failed error=%q", err.Error())
+	}
+}
+
 func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
 	if !ensurePost(w, r) {
 		return
@@ -96,6 +193,210 @@ func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req VerifyRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	reports, err := s.engine.verify(r.Context(), req.Workspace, req.Repair)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, VerifyResponse{Workspaces: reports})
+}
+
+func (s *Server) handleMigrateLegacy(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req MigrateLegacyRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.migrateLegacy(r.Context(), req, s.owner)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
+func (s *Server) handleRotateKey(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req struct{}
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.rotateKey(r.Context())
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req BackupRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.snapshot(r.Context(), req.Dir, false)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
+func (s *Server) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req struct{}
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.snapshots()
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req RestoreRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.restore(r.Context(), req)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
+func (s *Server) handleListIdentities(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req struct{}
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.identities(r.Context())
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
+func (s *Server) handleMapIdentity(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req MapIdentityRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.mapIdentity(r.Context(), req)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
+func (s *Server) handleListAliases(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req struct{}
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.aliases(r.Context())
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
+func (s *Server) handleSetAlias(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req SetAliasRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.setAlias(r.Context(), req)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
+func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req ShareRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.share(r.Context(), req, s.owner)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
+func (s *Server) handleListShares(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req ListSharesRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := s.engine.listShares(r.Context(), req.Workspace, s.owner)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, out)
+}
+
 func writeMethodNotAllowed(w http.ResponseWriter, allowed string) {
 	if strings.TrimSpace(allowed) != "" {
 		w.Header().Set("Allow", allowed)
@@ -120,6 +421,11 @@ func (s *Server) handleSaveText(w http.ResponseWriter, r *http.Request) {
 		s.writeErr(w, err)
 		return
 	}
+	expiresAt, err := saveExpiry(req.TTL, req.ExpiresAt)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
 	_, svc, err := s.resolveService(r.Context(), req.Workspace)
 	if err != nil {
 		s.writeErr(w, err)
@@ -130,6 +436,7 @@ func (s *Server) handleSaveText(w http.ResponseWriter, r *http.Request) {
 		Text:            req.Text,
 		MimeType:        req.MimeType,
 		ExpectedPrevRef: req.ExpectedPrevRef,
+		ExpiresAt:       expiresAt,
 	})
 	if err != nil {
 		s.writeErr(w, err)
@@ -152,6 +459,11 @@ func (s *Server) handleSaveBlob(w http.ResponseWriter, r *http.Request) {
 		s.writeErr(w, fmt.Errorf("%w: dataBase64 is not valid base64", artifacts.ErrInvalidInput))
 		return
 	}
+	expiresAt, err := saveExpiry(req.TTL, req.ExpiresAt)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
 	_, svc, err := s.resolveService(r.Context(), req.Workspace)
 	if err != nil {
 		s.writeErr(w, err)
@@ -163,6 +475,81 @@ func (s *Server) handleSaveBlob(w http.ResponseWriter, r *http.Request) {
 		MimeType:        req.MimeType,
 		Filename:        req.Filename,
 		ExpectedPrevRef: req.ExpectedPrevRef,
+		ExpiresAt:       expiresAt,
+	})
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, map[string]any{"artifact": a})
+}
+
+func (s *Server) handleSaveJSON(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req SaveJSONRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	expiresAt, err := saveExpiry(req.TTL, req.ExpiresAt)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	_, svc, err := s.resolveService(r.Context(), req.Workspace)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	a, err := svc.SaveJSON(r.Context(), artifacts.SaveJSONInput{
+		Name:            req.Name,
+		JSON:            req.JSON,
+		ExpectedPrevRef: req.ExpectedPrevRef,
+		ExpiresAt:       expiresAt,
+	})
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, map[string]any{"artifact": a})
+}
+
+func (s *Server) handlePatchJSON(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req PatchJSONRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	merge := false
+	switch strings.TrimSpace(req.Format) {
+	case "", PatchFormatJSONPatch:
+	case PatchFormatMergePatch:
+		merge = true
+	default:
+		s.writeErr(w, fmt.Errorf("%w: format must be %s or %s", artifacts.ErrInvalidInput, PatchFormatJSONPatch, PatchFormatMergePatch))
+		return
+	}
+	expiresAt, err := saveExpiry(req.TTL, req.ExpiresAt)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	_, svc, err := s.resolveService(r.Context(), req.Workspace)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	a, err := svc.PatchJSON(r.Context(), artifacts.PatchJSONInput{
+		Name:            req.Name,
+		Patch:           req.Patch,
+		Merge:           merge,
+		ExpectedPrevRef: req.ExpectedPrevRef,
+		ExpiresAt:       expiresAt,
 	})
 	if err != nil {
 		s.writeErr(w, err)
@@ -202,18 +589,35 @@ func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
 		s.writeErr(w, err)
 		return
 	}
-	_, svc, err := s.resolveService(r.Context(), req.Workspace)
+	a, data, err := s.engine.getShared(r.Context(), req, s.owner)
 	if err != nil {
 		s.writeErr(w, err)
 		return
 	}
-	sel := artifacts.Selector{Ref: req.Selector.Ref, Name: req.Selector.Name}
-	a, data, err := svc.Get(r.Context(), sel)
+	if req.Pointer != "" {
+		if !artifacts.IsJSONMimeType(a.MimeType) {
+			s.writeErr(w, fmt.Errorf("%w: pointer needs a JSON artifact, %s is %s", artifacts.ErrInvalidInput, a.Name, a.MimeType))
+			return
+		}
+		if data, err = artifacts.JSONPointer(data, req.Pointer); err != nil {
+			s.writeErr(w, err)
+			return
+		}
+	}
+	part, err := artifacts.SliceRange(data, req.ReadRange())
 	if err != nil {
 		s.writeErr(w, err)
 		return
 	}
-	s.writeOK(w, http.StatusOK, GetResponse{Artifact: a, DataBase64: base64.StdEncoding.EncodeToString(data)})
+	s.writeOK(w, http.StatusOK, GetResponse{
+		Artifact:   a,
+		DataBase64: base64.StdEncoding.EncodeToString(part.Data),
+		Pointer:    req.Pointer,
+		Offset:     part.Offset,
+		TotalBytes: part.TotalBytes,
+		TotalLines: part.TotalLines,
+		Truncated:  part.Truncated,
+	})
 }
 
 func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
@@ -259,3 +663,78 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 	}
 	s.writeOK(w, http.StatusOK, DeleteResponse{Deleted: true, Artifact: a})
 }
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if !ensurePost(w, r) {
+		return
+	}
+	var req BatchRequest
+	if err := jsonbody.DecodeStrictJSON(r, s.maxRequestBytes, &req); err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	writes := make([]artifacts.BatchWrite, 0, len(req.Ops))
+	for i, op := range req.Ops {
+		write, err := batchWrite(op)
+		if err != nil {
+			s.writeErr(w, fmt.Errorf("batch write %d: %w", i, err))
+			return
+		}
+		writes = append(writes, write)
+	}
+	_, svc, err := s.resolveService(r.Context(), req.Workspace)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	out, err := svc.Batch(r.Context(), writes)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+	s.writeOK(w, http.StatusOK, BatchResponse{Artifacts: out})
+}
+
+func batchWrite(op BatchOp) (artifacts.BatchWrite, error) {
+	switch strings.TrimSpace(op.Op) {
+	case "put":
+		expiresAt, err := saveExpiry(op.TTL, op.ExpiresAt)
+		if err != nil {
+			return artifacts.BatchWrite{}, err
+		}
+		if op.DataBase64 == "" {
+			return artifacts.BatchWrite{Text: &artifacts.SaveTextInput{
+				Name:            op.Name,
+				Text:            op.Text,
+				MimeType:        op.MimeType,
+				ExpectedPrevRef: op.ExpectedPrevRef,
+				ExpiresAt:       expiresAt,
+			}}, nil
+		}
+		if op.Text != "" {
+			return artifacts.BatchWrite{}, fmt.Errorf("%w: put takes text or dataBase64, not both", artifacts.ErrInvalidInput)
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(op.DataBase64))
+		if err != nil {
+			return artifacts.BatchWrite{}, fmt.Errorf("%w: dataBase64 is not valid base64", artifacts.ErrInvalidInput)
+		}
+		return artifacts.BatchWrite{Blob: &artifacts.SaveBlobInput{
+			Name:            op.Name,
+			Data:            data,
+			MimeType:        op.MimeType,
+			Filename:        op.Filename,
+			ExpectedPrevRef: op.ExpectedPrevRef,
+			ExpiresAt:       expiresAt,
+		}}, nil
+	case "delete":
+		if op.Text != "" || op.DataBase64 != "" || op.TTL != "" || op.ExpiresAt != nil {
+			return artifacts.BatchWrite{}, fmt.Errorf("%w: delete takes only name or ref and expectedPrevRef", artifacts.ErrInvalidInput)
+		}
+		return artifacts.BatchWrite{Delete: &artifacts.DeleteInput{
+			Selector:        artifacts.Selector{Ref: op.Ref, Name: op.Name},
+			ExpectedPrevRef: op.ExpectedPrevRef,
+		}}, nil
+	default:
+		return artifacts.BatchWrite{}, fmt.Errorf("%w: op must be put or delete, got %q", artifacts.ErrInvalidInput, op.Op)
+	}
+}
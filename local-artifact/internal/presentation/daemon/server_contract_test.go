@@ -1,11 +1,23 @@
 package daemon
 
 import (
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/filestore"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/jsonschemas"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/keyring"
 )
 
 func TestServerContract_SaveResolveGetListDeleteRoundTrip(t *testing.T) {
@@ -78,6 +90,517 @@ func TestServerContract_ExpectedPrevRefConflict(t *testing.T) {
 	}
 }
 
+func TestServerContract_BatchCommitsAllOrNothing(t *testing.T) {
+	h := newDaemonHTTPHarness(t)
+
+	plan, err := h.client.SaveText(h.ctx, SaveTextRequest{Workspace: h.workspace, Name: "plan/current", Text: "v1"})
+	if err != nil {
+		t.Fatalf("seed save: %v", err)
+	}
+	out, err := h.client.Batch(h.ctx, BatchRequest{Workspace: h.workspace, Ops: []BatchOp{
+		{Op: "put", Name: "plan/current", Text: "v2", ExpectedPrevRef: plan.Ref},
+		{Op: "put", Name: "plan/todo", DataBase64: base64.StdEncoding.EncodeToString([]byte(`["a"]`)), MimeType: "application/json"},
+		{Op: "put", Name: "plan/summary", Text: "done", TTL: "1h"},
+	}})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if len(out.Artifacts) != 3 || out.Artifacts[0].PrevRef != plan.Ref || out.Artifacts[2].ExpiresAt == nil {
+		t.Fatalf("unexpected batch output: %+v", out.Artifacts)
+	}
+
+	_, err = h.client.Batch(h.ctx, BatchRequest{Workspace: h.workspace, Ops: []BatchOp{
+		{Op: "put", Name: "plan/current", Text: "v3"},
+		{Op: "delete", Name: "plan/todo", ExpectedPrevRef: plan.Ref},
+	}})
+	var remoteErr *RemoteError
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeConflict || !strings.Contains(remoteErr.Message, "batch write 1") {
+		t.Fatalf("expected conflict on batch write 1, got %v", err)
+	}
+	resolved, err := h.client.Resolve(h.ctx, ResolveRequest{Workspace: h.workspace, Name: "plan/current"})
+	if err != nil || resolved.Ref != out.Artifacts[0].Ref {
+		t.Fatalf("expected plan/current to stay at %s, got %+v (%v)", out.Artifacts[0].Ref, resolved, err)
+	}
+
+	_, err = h.client.Batch(h.ctx, BatchRequest{Workspace: h.workspace, Ops: []BatchOp{{Op: "rename", Name: "plan/current"}}})
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeInvalidInput {
+		t.Fatalf("expected invalid input for unknown op, got %v", err)
+	}
+}
+
+func TestServerContract_GetReturnsRangesWithTotals(t *testing.T) {
+	h := newDaemonHTTPHarness(t)
+
+	if _, err := h.client.SaveText(h.ctx, SaveTextRequest{Workspace: h.workspace, Name: "log/build", Text: "l1\nl2\nl3\nl4\n"}); err != nil {
+		t.Fatalf("save text: %v", err)
+	}
+
+	got, err := h.client.Get(h.ctx, GetRequest{Workspace: h.workspace, Selector: Selector{Name: "log/build"}, Tail: 2, MaxBytes: 4})
+	if err != nil {
+		t.Fatalf("get tail: %v", err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(got.DataBase64)
+	if string(data) != "l3\nl" || got.Offset != 6 || got.TotalBytes != 12 || got.TotalLines != 4 || !got.Truncated {
+		t.Fatalf("unexpected tail read: %+v data=%q", got, data)
+	}
+
+	got, err = h.client.Get(h.ctx, GetRequest{Workspace: h.workspace, Selector: Selector{Name: "log/build"}, Offset: 3, Length: 2})
+	if err != nil {
+		t.Fatalf("get bytes: %v", err)
+	}
+	if data, _ := base64.StdEncoding.DecodeString(got.DataBase64); string(data) != "l2" || got.Truncated {
+		t.Fatalf("unexpected byte read: %+v data=%q", got, data)
+	}
+
+	_, err = h.client.Get(h.ctx, GetRequest{Workspace: h.workspace, Selector: Selector{Name: "log/build"}, Head: 1, Tail: 1})
+	var remoteErr *RemoteError
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeInvalidInput {
+		t.Fatalf("expected conflicting selections to be invalid input, got %v", err)
+	}
+}
+
+func TestServerContract_JSONArtifactsValidatePatchAndPointer(t *testing.T) {
+	engine := newDaemonEngine(t)
+	schemas, err := jsonschemas.Compile(map[string]json.RawMessage{
+		"review/*": json.RawMessage(`{"type":"object","required":["verdict"],"properties":{"verdict":{"enum":["pending","approved","rejected"]}}}`),
+	})
+	if err != nil {
+		t.Fatalf("compile schemas: %v", err)
+	}
+	engine.SetJSONValidator(schemas)
+	httpServer := httptest.NewServer(NewServer(engine, "test").Routes())
+	t.Cleanup(httpServer.Close)
+	client := NewHTTPClient(httpServer.URL, "")
+	ctx := context.Background()
+	workspace := WorkspaceSelector{WorkspaceID: workspaces.GlobalWorkspaceID}
+
+	var remoteErr *RemoteError
+	_, err = client.SaveJSON(ctx, SaveJSONRequest{Workspace: workspace, Name: "review/r1", JSON: json.RawMessage(`{"verdict":"maybe"}`)})
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeInvalidInput {
+		t.Fatalf("expected schema violation, got %v", err)
+	}
+	first, err := client.SaveJSON(ctx, SaveJSONRequest{Workspace: workspace, Name: "review/r1", JSON: json.RawMessage(`{"verdict":"pending","findings":[{"file":"a.go"}]}`)})
+	if err != nil {
+		t.Fatalf("save json: %v", err)
+	}
+	if first.MimeType != artifacts.JSONMimeType {
+		t.Fatalf("unexpected mime type %q", first.MimeType)
+	}
+
+	_, err = client.PatchJSON(ctx, PatchJSONRequest{Workspace: workspace, Name: "review/r1", Patch: json.RawMessage(`[{"op":"replace","path":"/verdict","value":"lgtm"}]`)})
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeInvalidInput {
+		t.Fatalf("expected patched document to be validated, got %v", err)
+	}
+	patched, err := client.PatchJSON(ctx, PatchJSONRequest{
+		Workspace:       workspace,
+		Name:            "review/r1",
+		Patch:           json.RawMessage(`[{"op":"test","path":"/verdict","value":"pending"},{"op":"replace","path":"/verdict","value":"approved"},{"op":"add","path":"/findings/-","value":{"file":"b.go"}}]`),
+		ExpectedPrevRef: first.Ref,
+	})
+	if err != nil {
+		t.Fatalf("patch json: %v", err)
+	}
+	if patched.PrevRef != first.Ref {
+		t.Fatalf("expected patch to chain from %s, got %+v", first.Ref, patched)
+	}
+	_, err = client.PatchJSON(ctx, PatchJSONRequest{Workspace: workspace, Name: "review/r1", Patch: json.RawMessage(`{"verdict":"rejected"}`), Format: PatchFormatMergePatch, ExpectedPrevRef: first.Ref})
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeConflict {
+		t.Fatalf("expected stale expectedPrevRef to conflict, got %v", err)
+	}
+
+	got, err := client.Get(ctx, GetRequest{Workspace: workspace, Selector: Selector{Name: "review/r1"}, Pointer: "/findings/1/file"})
+	if err != nil {
+		t.Fatalf("get pointer: %v", err)
+	}
+	if data, _ := base64.StdEncoding.DecodeString(got.DataBase64); string(data) != `"b.go"` || got.Pointer != "/findings/1/file" {
+		t.Fatalf("unexpected pointer read: %+v data=%s", got, data)
+	}
+	_, err = client.Get(ctx, GetRequest{Workspace: workspace, Selector: Selector{Name: "review/r1"}, Pointer: "/findings/9"})
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeNotFound {
+		t.Fatalf("expected missing pointer to be not found, got %v", err)
+	}
+}
+
+func TestServerContract_QuotaExceededUsesDistinctCode(t *testing.T) {
+	engine := newDaemonEngine(t)
+	engine.SetQuota(artifacts.Quota{MaxVersionsPerName: 1})
+	httpServer := httptest.NewServer(NewServer(engine, "test").Routes())
+	t.Cleanup(httpServer.Close)
+	client := NewHTTPClient(httpServer.URL, "")
+	ctx := context.Background()
+	workspace := WorkspaceSelector{WorkspaceID: workspaces.GlobalWorkspaceID}
+
+	if _, err := client.SaveText(ctx, SaveTextRequest{Workspace: workspace, Name: "plan/q", Text: "first"}); err != nil {
+		t.Fatalf("first save: %v", err)
+	}
+	_, err := client.SaveText(ctx, SaveTextRequest{Workspace: workspace, Name: "plan/q", Text: "second"})
+	var remoteErr *RemoteError
+	if !errors.As(err, &remoteErr) {
+		t.Fatalf("expected remote error, got %v", err)
+	}
+	if remoteErr.Code != CodeQuotaExceeded || remoteErr.HTTPStatus != http.StatusInsufficientStorage {
+		t.Fatalf("unexpected remote error: %+v", remoteErr)
+	}
+}
+
+func TestServerContract_VerifyReportsAndRepairsMissingBlob(t *testing.T) {
+	engine := newDaemonEngine(t)
+	httpServer := httptest.NewServer(NewServer(engine, "test").Routes())
+	t.Cleanup(httpServer.Close)
+	client := NewHTTPClient(httpServer.URL, "")
+	ctx := context.Background()
+	workspace := WorkspaceSelector{WorkspaceID: workspaces.GlobalWorkspaceID}
+
+	saved, err := client.SaveText(ctx, SaveTextRequest{Workspace: workspace, Name: "plan/verify", Text: "payload"})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	blob := filepath.Join(engine.baseStoreRoot, "blobs", saved.SHA256[:2], saved.SHA256)
+	if err := os.Remove(blob); err != nil {
+		t.Fatalf("remove blob: %v", err)
+	}
+
+	out, err := client.Verify(ctx, VerifyRequest{})
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(out.Workspaces) != 1 || out.Workspaces[0].WorkspaceID != workspaces.GlobalWorkspaceID {
+		t.Fatalf("unexpected workspaces: %+v", out.Workspaces)
+	}
+	issues := out.Workspaces[0].Issues
+	if len(issues) != 1 || issues[0].Kind != artifacts.IssueMissingBlob || issues[0].Ref != saved.Ref {
+		t.Fatalf("unexpected issues: %+v", issues)
+	}
+
+	out, err = client.Verify(ctx, VerifyRequest{Workspace: workspace, Repair: true})
+	if err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+	if issues := out.Workspaces[0].Issues; len(issues) != 1 || issues[0].Action == "" {
+		t.Fatalf("unexpected repair result: %+v", issues)
+	}
+	if _, err := client.Resolve(ctx, ResolveRequest{Workspace: workspace, Name: "plan/verify"}); err == nil {
+		t.Fatal("expected unreadable name to be deleted by repair")
+	}
+}
+
+func TestServerContract_MigrateLegacyImportsFilestore(t *testing.T) {
+	h := newDaemonHTTPHarness(t)
+	legacyRoot := t.TempDir()
+	legacy := filestore.New(legacyRoot)
+	if _, err := legacy.Save(h.ctx, artifacts.ArtifactVersion{
+		Ref:      "20250601T000000Z-aaaaaaaaaaaaaaaa",
+		Name:     "plan/legacy",
+		Kind:     artifacts.ArtifactKindText,
+		MimeType: "text/plain",
+	}, []byte("from early build"), artifacts.SaveOptions{}); err != nil {
+		t.Fatalf("seed legacy store: %v", err)
+	}
+
+	_, err := h.client.MigrateLegacy(h.ctx, MigrateLegacyRequest{Workspace: h.workspace, LegacyRoot: t.TempDir()})
+	var remoteErr *RemoteError
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeInvalidInput {
+		t.Fatalf("expected invalid input for non-legacy dir, got %v", err)
+	}
+
+	out, err := h.client.MigrateLegacy(h.ctx, MigrateLegacyRequest{Workspace: h.workspace, LegacyRoot: legacyRoot})
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if out.WorkspaceID != workspaces.GlobalWorkspaceID || out.Imported != 1 {
+		t.Fatalf("unexpected migrate response: %+v", out)
+	}
+	resolved, err := h.client.Resolve(h.ctx, ResolveRequest{Workspace: h.workspace, Name: "plan/legacy"})
+	if err != nil || resolved.Ref != "20250601T000000Z-aaaaaaaaaaaaaaaa" {
+		t.Fatalf("resolve migrated name: ref=%q err=%v", resolved.Ref, err)
+	}
+}
+
+func TestServerContract_EncryptAtRestAndRotateKey(t *testing.T) {
+	plain := newDaemonHTTPHarness(t)
+	_, err := plain.client.RotateKey(plain.ctx)
+	var remoteErr *RemoteError
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeInvalidInput {
+		t.Fatalf("expected invalid input without encryption, got %v", err)
+	}
+
+	engine := newDaemonEngine(t)
+	keys, err := keyring.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("open keyring: %v", err)
+	}
+	if err := keys.Enable(); err != nil {
+		t.Fatalf("enable keyring: %v", err)
+	}
+	engine.SetEncryption(keys, true)
+	httpServer := httptest.NewServer(NewServer(engine, "test").Routes())
+	t.Cleanup(httpServer.Close)
+	client := NewHTTPClient(httpServer.URL, "")
+	ctx := context.Background()
+	workspace := WorkspaceSelector{WorkspaceID: workspaces.GlobalWorkspaceID}
+
+	if _, err := client.SaveText(ctx, SaveTextRequest{Workspace: workspace, Name: "plan/secret", Text: "api key: hunter2"}); err != nil {
+		t.Fatalf("save text: %v", err)
+	}
+	blobFiles := 0
+	if err := filepath.WalkDir(filepath.Join(engine.baseStoreRoot, "blobs"), func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		blobFiles++
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(b), "hunter2") || !strings.HasSuffix(path, ".enc") {
+			t.Fatalf("blob %s is not encrypted", path)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("walk blobs: %v", err)
+	}
+	if blobFiles != 1 {
+		t.Fatalf("expected one blob file, got %d", blobFiles)
+	}
+
+	rotated, err := client.RotateKey(ctx)
+	if err != nil {
+		t.Fatalf("rotate key: %v", err)
+	}
+	if len(rotated.Rewrapped) != 1 || rotated.Rewrapped[0] != workspaces.GlobalWorkspaceID {
+		t.Fatalf("unexpected rotate response: %+v", rotated)
+	}
+	engine.evictIdle(time.Time{})
+
+	got, err := client.Get(ctx, GetRequest{Workspace: workspace, Selector: Selector{Name: "plan/secret"}})
+	if err != nil {
+		t.Fatalf("get after rotation: %v", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(got.DataBase64)
+	if err != nil || string(payload) != "api key: hunter2" {
+		t.Fatalf("unexpected payload after rotation: %q err=%v", payload, err)
+	}
+}
+
+func TestServerContract_BackupAndRestore(t *testing.T) {
+	engine := newDaemonEngine(t)
+	engine.SetBackupPolicy(BackupPolicy{Dir: filepath.Join(t.TempDir(), "snapshots")})
+	httpServer := httptest.NewServer(NewServer(engine, "test").Routes())
+	t.Cleanup(httpServer.Close)
+	client := NewHTTPClient(httpServer.URL, "")
+	ctx := context.Background()
+	workspace := WorkspaceSelector{WorkspaceID: workspaces.GlobalWorkspaceID}
+
+	if _, err := client.SaveText(ctx, SaveTextRequest{Workspace: workspace, Name: "plan/a", Text: "before"}); err != nil {
+		t.Fatalf("save text: %v", err)
+	}
+	backup, err := client.Backup(ctx, BackupRequest{})
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if !backup.Registry || len(backup.Workspaces) != 1 || backup.Workspaces[0].Versions != 1 || backup.Workspaces[0].Blobs != 1 {
+		t.Fatalf("unexpected backup response: %+v", backup)
+	}
+	if _, err := os.Stat(filepath.Join(backup.Path, snapshotManifestName)); err != nil {
+		t.Fatalf("manifest missing: %v", err)
+	}
+	if _, err := client.Backup(ctx, BackupRequest{Dir: "relative"}); err == nil {
+		t.Fatalf("expected relative backup dir to be rejected")
+	}
+
+	if _, err := client.SaveText(ctx, SaveTextRequest{Workspace: workspace, Name: "plan/a", Text: "after"}); err != nil {
+		t.Fatalf("save text: %v", err)
+	}
+	if _, err := client.SaveText(ctx, SaveTextRequest{Workspace: workspace, Name: "plan/b", Text: "new"}); err != nil {
+		t.Fatalf("save text: %v", err)
+	}
+
+	listed, err := client.ListSnapshots(ctx)
+	if err != nil {
+		t.Fatalf("list snapshots: %v", err)
+	}
+	if len(listed.Snapshots) != 1 || listed.Snapshots[0].ID != backup.ID {
+		t.Fatalf("unexpected snapshot list: %+v", listed)
+	}
+
+	_, err = client.Restore(ctx, RestoreRequest{Snapshot: backup.ID, Workspace: WorkspaceSelector{WorkspaceID: strings.Repeat("f", 64)}})
+	var remoteErr *RemoteError
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeNotFound {
+		t.Fatalf("expected not found for a workspace outside the snapshot, got %v", err)
+	}
+	restored, err := client.Restore(ctx, RestoreRequest{Snapshot: backup.ID, Workspace: workspace})
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.Registry || len(restored.Workspaces) != 1 {
+		t.Fatalf("unexpected restore response: %+v", restored)
+	}
+
+	got, err := client.Get(ctx, GetRequest{Workspace: workspace, Selector: Selector{Name: "plan/a"}})
+	if err != nil {
+		t.Fatalf("get after restore: %v", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(got.DataBase64)
+	if err != nil || string(payload) != "before" {
+		t.Fatalf("unexpected payload after restore: %q (%v)", payload, err)
+	}
+	if _, err := client.Get(ctx, GetRequest{Workspace: workspace, Selector: Selector{Name: "plan/b"}}); !errors.As(err, &remoteErr) || remoteErr.Code != CodeNotFound {
+		t.Fatalf("expected plan/b to be gone after restore, got %v", err)
+	}
+}
+
+func TestServerContract_GitRemoteIdentitySharesWorkspaceAcrossClones(t *testing.T) {
+	engine := newDaemonEngine(t)
+	httpServer := httptest.NewServer(NewServer(engine, "test").Routes())
+	t.Cleanup(httpServer.Close)
+	client := NewHTTPClient(httpServer.URL, "")
+	ctx := context.Background()
+
+	clone := func(name, remote string) string {
+		dir := filepath.Join(t.TempDir(), name)
+		if err := os.MkdirAll(filepath.Join(dir, ".git"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		config := "[remote \"origin\"]\n\turl = " + remote + "\n"
+		if err := os.WriteFile(filepath.Join(dir, ".git", "config"), []byte(config), 0o644); err != nil {
+			t.Fatalf("write git config: %v", err)
+		}
+		return dir
+	}
+	rootsOf := func(dir string) WorkspaceSelector {
+		return WorkspaceSelector{Roots: []string{"file://" + filepath.ToSlash(dir)}}
+	}
+	first := clone("first", "git@github.com:owner/repo.git")
+	second := clone("second", "https://github.com/owner/repo")
+	other := clone("other", "https://github.com/owner/other.git")
+
+	if _, err := client.SaveText(ctx, SaveTextRequest{Workspace: rootsOf(first), Name: "plan/shared", Text: "from first"}); err != nil {
+		t.Fatalf("save text: %v", err)
+	}
+	if _, err := client.Get(ctx, GetRequest{Workspace: rootsOf(second), Selector: Selector{Name: "plan/shared"}}); err == nil {
+		t.Fatalf("expected clones to be separate workspaces without git-remote identity")
+	}
+
+	engine.SetWorkspaceIdentityMode(func(string) (string, error) { return workspaces.IdentityModeGitRemote, nil })
+	for _, dir := range []string{first, second} {
+		got, err := client.Get(ctx, GetRequest{Workspace: rootsOf(dir), Selector: Selector{Name: "plan/shared"}})
+		if err != nil {
+			t.Fatalf("get from %s: %v", filepath.Base(dir), err)
+		}
+		payload, err := base64.StdEncoding.DecodeString(got.DataBase64)
+		if err != nil || string(payload) != "from first" {
+			t.Fatalf("unexpected payload from %s: %q (%v)", filepath.Base(dir), payload, err)
+		}
+	}
+	if _, err := client.Get(ctx, GetRequest{Workspace: rootsOf(other), Selector: Selector{Name: "plan/shared"}}); err == nil {
+		t.Fatalf("expected a different repository to get its own workspace")
+	}
+
+	listed, err := client.ListIdentities(ctx)
+	if err != nil {
+		t.Fatalf("list identities: %v", err)
+	}
+	if len(listed.Identities) != 2 || listed.Identities[1].Key != "git:github.com/owner/repo#." {
+		t.Fatalf("unexpected identities: %+v", listed.Identities)
+	}
+
+	_, err = client.MapIdentity(ctx, MapIdentityRequest{Key: "github.com/owner/repo", Workspace: WorkspaceSelector{WorkspaceID: workspaces.GlobalWorkspaceID}})
+	var remoteErr *RemoteError
+	if !errors.As(err, &remoteErr) || remoteErr.Code != CodeInvalidInput {
+		t.Fatalf("expected invalid input for a non-identity key, got %v", err)
+	}
+	if _, err := client.MapIdentity(ctx, MapIdentityRequest{Key: listed.Identities[0].Key, Workspace: WorkspaceSelector{WorkspaceID: listed.Identities[1].WorkspaceID}}); err != nil {
+		t.Fatalf("map identity: %v", err)
+	}
+	if _, err := client.Get(ctx, GetRequest{Workspace: rootsOf(other), Selector: Selector{Name: "plan/shared"}}); err != nil {
+		t.Fatalf("expected remapped repository to see the shared workspace: %v", err)
+	}
+}
+
+func TestServerContract_SharedGlobalArtifactReadableAcrossWorkspaces(t *testing.T) {
+	engine := newDaemonEngine(t)
+	httpServer := httptest.NewServer(NewServer(engine, "test").Routes())
+	t.Cleanup(httpServer.Close)
+	client := NewHTTPClient(httpServer.URL, "")
+	ctx := context.Background()
+
+	global := WorkspaceSelector{WorkspaceID: workspaces.GlobalWorkspaceID}
+	reader := WorkspaceSelector{Roots: []string{"file:///tmp/reader"}}
+	saved, err := client.SaveText(ctx, SaveTextRequest{Workspace: global, Name: "style/guide", Text: "tabs"})
+	if err != nil {
+		t.Fatalf("save shared: %v", err)
+	}
+	private, err := client.SaveText(ctx, SaveTextRequest{Workspace: global, Name: "private/notes", Text: "secret"})
+	if err != nil {
+		t.Fatalf("save private: %v", err)
+	}
+
+	expectCode := func(err error, code string) {
+		t.Helper()
+		var remoteErr *RemoteError
+		if !errors.As(err, &remoteErr) || remoteErr.Code != code {
+			t.Fatalf("expected %s, got %v", code, err)
+		}
+	}
+	_, err = client.Get(ctx, GetRequest{Workspace: reader, Source: "global", Selector: Selector{Name: "style/guide"}})
+	expectCode(err, CodeForbidden)
+
+	shared, err := client.Share(ctx, ShareRequest{Workspace: global, Name: "style/guide"})
+	if err != nil {
+		t.Fatalf("share: %v", err)
+	}
+	if shared.URI != "artifact://ws/global/name/style%2Fguide" || shared.Share.TargetWorkspaceID != workspaces.ShareAllWorkspaces {
+		t.Fatalf("unexpected share: %+v", shared)
+	}
+	_, err = client.Share(ctx, ShareRequest{Workspace: global, Name: "missing"})
+	expectCode(err, CodeNotFound)
+	_, err = client.Share(ctx, ShareRequest{Workspace: global, Name: "bad\nname", Revoke: true})
+	expectCode(err, CodeInvalidInput)
+
+	if _, err := client.SetAlias(ctx, SetAliasRequest{Alias: "team", Workspace: global}); err != nil {
+		t.Fatalf("set alias: %v", err)
+	}
+	for _, source := range []string{"global", "team"} {
+		got, err := client.Get(ctx, GetRequest{Workspace: reader, Source: source, Selector: Selector{Name: "style/guide"}})
+		if err != nil {
+			t.Fatalf("get shared via %s: %v", source, err)
+		}
+		payload, err := base64.StdEncoding.DecodeString(got.DataBase64)
+		if err != nil || string(payload) != "tabs" {
+			t.Fatalf("unexpected payload via %s: %q (%v)", source, payload, err)
+		}
+	}
+	if _, err := client.Get(ctx, GetRequest{Workspace: reader, Source: "team", Selector: Selector{Ref: saved.Ref}}); err != nil {
+		t.Fatalf("get shared by ref: %v", err)
+	}
+	_, err = client.Get(ctx, GetRequest{Workspace: reader, Source: "global", Selector: Selector{Name: "private/notes"}})
+	expectCode(err, CodeForbidden)
+	// Unshared and unknown refs fail alike, without naming the artifact.
+	for _, ref := range []string{private.Ref, "20260101T000000Z-0123456789abcdef"} {
+		_, err = client.Get(ctx, GetRequest{Workspace: reader, Source: "global", Selector: Selector{Ref: ref}})
+		expectCode(err, CodeForbidden)
+		if strings.Contains(err.Error(), "private/notes") {
+			t.Fatalf("expected the error not to name the artifact, got %v", err)
+		}
+	}
+	_, err = client.Get(ctx, GetRequest{Workspace: reader, Source: "nobody", Selector: Selector{Name: "style/guide"}})
+	expectCode(err, CodeNotFound)
+
+	listed, err := client.ListShares(ctx, ListSharesRequest{Workspace: reader})
+	if err != nil {
+		t.Fatalf("list shares: %v", err)
+	}
+	if len(listed.Shares) != 1 || listed.Shares[0].Name != "style/guide" {
+		t.Fatalf("unexpected shares: %+v", listed.Shares)
+	}
+
+	if _, err := client.Share(ctx, ShareRequest{Workspace: global, Name: "style/guide", Revoke: true}); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	_, err = client.Get(ctx, GetRequest{Workspace: reader, Source: "global", Selector: Selector{Name: "style/guide"}})
+	expectCode(err, CodeForbidden)
+}
+
 func TestServerContract_MethodNotAllowedUsesEnvelope(t *testing.T) {
 	engine := newDaemonEngine(t)
 	handler := NewServer(engine, "test").Routes()
@@ -130,6 +653,99 @@ func TestServerContract_MethodNotAllowedUsesEnvelope(t *testing.T) {
 	}
 }
 
+func TestServerContract_HealthReportsOnlyLiveness(t *testing.T) {
+	engine := newDaemonEngine(t)
+	server := NewServer(engine, "test")
+	server.SetVersion("v1.2.3")
+
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/daemon/v1/health", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status mismatch: got=%d want=%d", rr.Code, http.StatusOK)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode health: %v", err)
+	}
+	if len(body.Data) != 1 || body.Data["status"] != "ok" {
+		t.Fatalf("health must report only status, got %v", body.Data)
+	}
+}
+
+func TestServerContract_StatusAndMetricsReportActivity(t *testing.T) {
+	engine := newDaemonEngine(t)
+	server := NewServer(engine, "test")
+	server.SetVersion("v1.2.3")
+	handler := server.Routes()
+
+	saveReq := httptest.NewRequest(http.MethodPost, "/daemon/v1/artifacts/save_text", strings.NewReader(`{"workspace":{"workspaceID":"global"},"name":"plan/metrics","text":"hello"}`))
+	saveReq.Header.Set("Content-Type", "application/json")
+	saveRR := httptest.NewRecorder()
+	handler.ServeHTTP(saveRR, saveReq)
+	if saveRR.Code != http.StatusOK {
+		t.Fatalf("save status=%d body=%s", saveRR.Code, saveRR.Body.String())
+	}
+	server.Metrics().RecordAuthFailure()
+
+	statusRR := httptest.NewRecorder()
+	handler.ServeHTTP(statusRR, httptest.NewRequest(http.MethodGet, "/daemon/v1/status", nil))
+	if statusRR.Code != http.StatusOK {
+		t.Fatalf("status endpoint=%d body=%s", statusRR.Code, statusRR.Body.String())
+	}
+	var body struct {
+		Data StatusResponse `json:"data"`
+	}
+	if err := json.Unmarshal(statusRR.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	status := body.Data
+	if status.Version != "v1.2.3" || status.PID != os.Getpid() || status.AuthFailures != 1 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if len(status.OpenWorkspaces) != 1 || status.OpenWorkspaces[0] != "global" {
+		t.Fatalf("unexpected open workspaces: %+v", status.OpenWorkspaces)
+	}
+	if len(status.Workspaces) == 0 || status.Workspaces[0].WorkspaceID != "global" || !status.Workspaces[0].Open || status.Workspaces[0].BlobBytes != int64(len("hello")) || status.Workspaces[0].Usage.Bytes != int64(len("hello")) {
+		t.Fatalf("unexpected workspace usage: %+v", status.Workspaces)
+	}
+	var saveRoute *RouteMetrics
+	for i := range status.Routes {
+		if status.Routes[i].Route == "/daemon/v1/artifacts/save_text" {
+			saveRoute = &status.Routes[i]
+		}
+	}
+	if saveRoute == nil || saveRoute.Requests != 1 || saveRoute.Errors != 0 {
+		t.Fatalf("unexpected route metrics: %+v", status.Routes)
+	}
+
+	metricsRR := httptest.NewRecorder()
+	handler.ServeHTTP(metricsRR, httptest.NewRequest(http.MethodGet, "/daemon/v1/metrics", nil))
+	if metricsRR.Code != http.StatusOK {
+		t.Fatalf("metrics endpoint=%d", metricsRR.Code)
+	}
+	if got := metricsRR.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Fatalf("content-type=%q", got)
+	}
+	text := metricsRR.Body.String()
+	for _, want := range []string{
+		`ccsubagentsd_build_info{version="v1.2.3"} 1`,
+		`ccsubagentsd_open_workspaces 1`,
+		`ccsubagentsd_auth_failures_total 1`,
+		`ccsubagentsd_sqlite_busy_retries_total `,
+		`ccsubagentsd_blob_store_bytes{workspace="global"} 5`,
+		`ccsubagentsd_http_requests_total{route="/daemon/v1/artifacts/save_text",code="200"} 1`,
+		`ccsubagentsd_http_request_duration_seconds_count{route="/daemon/v1/artifacts/save_text"} 1`,
+		`# TYPE ccsubagentsd_http_request_duration_seconds histogram`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, text)
+		}
+	}
+}
+
 func TestServerRejectsOversizedJSONBody_NotTruncationEOF(t *testing.T) {
 	engine := newDaemonEngine(t)
 
@@ -0,0 +1,244 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
+)
+
+// shareRegistry is implemented by registries that keep workspace aliases and
+// the share grants checked on cross-workspace reads.
+type shareRegistry interface {
+	SetAlias(ctx context.Context, alias, workspaceID string) error
+	RemoveAlias(ctx context.Context, alias string) (bool, error)
+	ResolveAlias(ctx context.Context, alias string) (string, error)
+	ListAliases(ctx context.Context) ([]workspaces.Alias, error)
+	Share(ctx context.Context, share workspaces.Share) error
+	Unshare(ctx context.Context, share workspaces.Share) (bool, error)
+	ShareAllowed(ctx context.Context, sourceWorkspaceID, name, targetWorkspaceID string) (bool, error)
+	ListShares(ctx context.Context, workspaceID string) ([]workspaces.Share, error)
+}
+
+var aliasPattern = regexp.MustCompile(`^[a-z][a-z0-9._-]{0,62}$`)
+
+func (e *Engine) shares() (shareRegistry, error) {
+	registry, ok := e.registry.(shareRegistry)
+	if !ok {
+		return nil, fmt.Errorf("%w: workspace registry does not support sharing", artifacts.ErrInvalidInput)
+	}
+	return registry, nil
+}
+
+// lookupWorkspace resolves a workspace named in a URI or share: "global", a
+// workspace ID or an alias.
+func (e *Engine) lookupWorkspace(ctx context.Context, ref string) (string, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref == "" {
+		return "", fmt.Errorf("%w: workspace is required", artifacts.ErrInvalidInput)
+	}
+	if id, _, err := normalizeWorkspaceSelector(WorkspaceSelector{WorkspaceID: ref}); err == nil {
+		return id, nil
+	}
+	if !aliasPattern.MatchString(ref) {
+		return "", fmt.Errorf("%w: workspace must be global, a workspace ID or an alias", artifacts.ErrInvalidInput)
+	}
+	registry, err := e.shares()
+	if err != nil {
+		return "", err
+	}
+	id, err := registry.ResolveAlias(ctx, ref)
+	if errors.Is(err, artifacts.ErrNotFound) {
+		return "", fmt.Errorf("%w: workspace alias %q", artifacts.ErrNotFound, ref)
+	}
+	return id, err
+}
+
+// getShared reads an artifact from req.Source on behalf of the workspace in
+// req.Workspace. Reads from another workspace need a share grant for the
+// artifact's name, checked before any payload is read; the source's blobs
+// are read in place. A ref that does not exist fails like an unshared one so
+// refs cannot be probed across workspaces.
+func (e *Engine) getShared(ctx context.Context, req GetRequest, owner string) (artifacts.ArtifactVersion, []byte, error) {
+	readerID, svc, err := e.resolveWorkspace(ctx, req.Workspace, owner)
+	if err != nil {
+		return artifacts.ArtifactVersion{}, nil, err
+	}
+	sel := artifacts.Selector{Ref: req.Selector.Ref, Name: req.Selector.Name}
+	if strings.TrimSpace(req.Source) == "" {
+		return svc.Get(ctx, sel)
+	}
+	sourceID, err := e.lookupWorkspace(ctx, req.Source)
+	if err != nil {
+		return artifacts.ArtifactVersion{}, nil, err
+	}
+	if sourceID == readerID {
+		return svc.Get(ctx, sel)
+	}
+	if err := artifacts.ValidateSelector(sel); err != nil {
+		return artifacts.ArtifactVersion{}, nil, err
+	}
+	registry, err := e.shares()
+	if err != nil {
+		return artifacts.ArtifactVersion{}, nil, err
+	}
+	if sourceID != workspaces.GlobalWorkspaceID {
+		if _, err := e.registry.GetWorkspace(ctx, sourceID); err != nil {
+			if errors.Is(err, artifacts.ErrNotFound) {
+				return artifacts.ArtifactVersion{}, nil, fmt.Errorf("%w: workspace %s", artifacts.ErrNotFound, sourceID)
+			}
+			return artifacts.ArtifactVersion{}, nil, err
+		}
+	}
+	selected := strings.TrimSpace(sel.Name)
+	name := selected
+	if name == "" {
+		selected = strings.TrimSpace(sel.Ref)
+		handle, err := e.handleForWorkspaceID(ctx, sourceID)
+		if err != nil {
+			return artifacts.ArtifactVersion{}, nil, err
+		}
+		meta, err := handle.VersionMeta(ctx, selected)
+		if err != nil && !errors.Is(err, artifacts.ErrNotFound) {
+			return artifacts.ArtifactVersion{}, nil, err
+		}
+		name = meta.Name
+	}
+	allowed := false
+	if name != "" {
+		if allowed, err = registry.ShareAllowed(ctx, sourceID, name, readerID); err != nil {
+			return artifacts.ArtifactVersion{}, nil, err
+		}
+	}
+	if !allowed {
+		return artifacts.ArtifactVersion{}, nil, fmt.Errorf("%w: %s in workspace %s", artifacts.ErrNotShared, selected, sourceID)
+	}
+	sourceSvc, err := e.serviceForWorkspaceID(ctx, sourceID)
+	if err != nil {
+		return artifacts.ArtifactVersion{}, nil, err
+	}
+	return sourceSvc.Get(ctx, sel)
+}
+
+// share grants, or with req.Revoke withdraws, read access to a name in the
+// source workspace. The grant covers every version of the name.
+func (e *Engine) share(ctx context.Context, req ShareRequest, owner string) (ShareResponse, error) {
+	registry, err := e.shares()
+	if err != nil {
+		return ShareResponse{}, err
+	}
+	sourceID, svc, err := e.resolveWorkspace(ctx, req.Workspace, owner)
+	if err != nil {
+		return ShareResponse{}, err
+	}
+	name, err := artifacts.NormalizeName(req.Name)
+	if err != nil {
+		return ShareResponse{}, err
+	}
+	target := workspaces.ShareAllWorkspaces
+	if with := strings.TrimSpace(req.With); with != "" && with != workspaces.ShareAllWorkspaces {
+		if target, err = e.lookupWorkspace(ctx, with); err != nil {
+			return ShareResponse{}, err
+		}
+		if target == sourceID {
+			return ShareResponse{}, fmt.Errorf("%w: cannot share a workspace with itself", artifacts.ErrInvalidInput)
+		}
+	}
+	grant := workspaces.Share{SourceWorkspaceID: sourceID, Name: name, TargetWorkspaceID: target, CreatedAt: engineNowFn().UTC().Truncate(time.Second)}
+	out := ShareResponse{
+		Share: WorkspaceShare{SourceWorkspaceID: sourceID, Name: name, TargetWorkspaceID: target, CreatedAt: grant.CreatedAt},
+		URI:   artifacts.WorkspaceURIByName(sourceID, url.PathEscape(name)),
+	}
+	if req.Revoke {
+		removed, err := registry.Unshare(ctx, grant)
+		if err != nil {
+			return ShareResponse{}, err
+		}
+		if !removed {
+			return ShareResponse{}, fmt.Errorf("%w: %s is not shared with %s", artifacts.ErrNotFound, name, target)
+		}
+		out.Revoked = true
+		return out, nil
+	}
+	if _, err := svc.Resolve(ctx, name); err != nil {
+		return ShareResponse{}, err
+	}
+	if err := registry.Share(ctx, grant); err != nil {
+		return ShareResponse{}, err
+	}
+	return out, nil
+}
+
+// listShares returns the grants a workspace has made and those it can read.
+func (e *Engine) listShares(ctx context.Context, sel WorkspaceSelector, owner string) (ListSharesResponse, error) {
+	workspaceID, _, err := e.resolveWorkspace(ctx, sel, owner)
+	if err != nil {
+		return ListSharesResponse{}, err
+	}
+	out := ListSharesResponse{WorkspaceID: workspaceID, Shares: []WorkspaceShare{}}
+	registry, ok := e.registry.(shareRegistry)
+	if !ok {
+		return out, nil
+	}
+	items, err := registry.ListShares(ctx, workspaceID)
+	if err != nil {
+		return ListSharesResponse{}, err
+	}
+	for _, item := range items {
+		out.Shares = append(out.Shares, WorkspaceShare{SourceWorkspaceID: item.SourceWorkspaceID, Name: item.Name, TargetWorkspaceID: item.TargetWorkspaceID, CreatedAt: item.CreatedAt})
+	}
+	return out, nil
+}
+
+// setAlias points an alias at a workspace, or removes it when req.Remove is
+// set.
+func (e *Engine) setAlias(ctx context.Context, req SetAliasRequest) (WorkspaceAlias, error) {
+	registry, err := e.shares()
+	if err != nil {
+		return WorkspaceAlias{}, err
+	}
+	alias := strings.ToLower(strings.TrimSpace(req.Alias))
+	if !aliasPattern.MatchString(alias) || alias == workspaces.GlobalWorkspaceID {
+		return WorkspaceAlias{}, fmt.Errorf("%w: alias must start with a letter and use only a-z, 0-9, '.', '_' or '-' (max 63)", artifacts.ErrInvalidInput)
+	}
+	if req.Remove {
+		removed, err := registry.RemoveAlias(ctx, alias)
+		if err != nil {
+			return WorkspaceAlias{}, err
+		}
+		if !removed {
+			return WorkspaceAlias{}, fmt.Errorf("%w: workspace alias %q", artifacts.ErrNotFound, alias)
+		}
+		return WorkspaceAlias{Alias: alias}, nil
+	}
+	workspaceID, _, err := normalizeWorkspaceSelector(req.Workspace)
+	if err != nil {
+		return WorkspaceAlias{}, err
+	}
+	if err := registry.SetAlias(ctx, alias, workspaceID); err != nil {
+		return WorkspaceAlias{}, err
+	}
+	return WorkspaceAlias{Alias: alias, WorkspaceID: workspaceID, CreatedAt: engineNowFn().UTC().Truncate(time.Second)}, nil
+}
+
+func (e *Engine) aliases(ctx context.Context) (ListAliasesResponse, error) {
+	out := ListAliasesResponse{Aliases: []WorkspaceAlias{}}
+	registry, ok := e.registry.(shareRegistry)
+	if !ok {
+		return out, nil
+	}
+	items, err := registry.ListAliases(ctx)
+	if err != nil {
+		return ListAliasesResponse{}, err
+	}
+	for _, item := range items {
+		out.Aliases = append(out.Aliases, WorkspaceAlias{Alias: item.Alias, WorkspaceID: item.WorkspaceID, CreatedAt: item.CreatedAt})
+	}
+	return out, nil
+}
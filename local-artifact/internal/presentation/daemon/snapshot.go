@@ -0,0 +1,379 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/keyring"
+	artsqlite "github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/sqlite"
+)
+
+// BackupPolicy configures snapshots. Dir is where snapshots are written; a
+// positive Interval also takes one on that schedule, keeping the newest
+// Retention scheduled snapshots. Snapshots taken on request are never pruned.
+type BackupPolicy struct {
+	Dir       string
+	Interval  time.Duration
+	Retention int
+}
+
+const DefaultBackupRetention = 7
+
+const (
+	snapshotManifestName  = "manifest.json"
+	snapshotPartialSuffix = ".partial"
+	snapshotIDLayout      = "20060102T150405Z"
+)
+
+type SnapshotManifest struct {
+	Version    int                 `json:"version"`
+	ID         string              `json:"id"`
+	CreatedAt  time.Time           `json:"createdAt"`
+	Scheduled  bool                `json:"scheduled,omitempty"`
+	Registry   bool                `json:"registry"`
+	Workspaces []SnapshotWorkspace `json:"workspaces"`
+}
+
+// SnapshotWorkspace describes one workspace in a snapshot. Encrypted
+// workspaces carry their wrapped data key; the master key is not included.
+type SnapshotWorkspace struct {
+	WorkspaceID string `json:"workspaceID"`
+	Encrypted   bool   `json:"encrypted,omitempty"`
+	artsqlite.SnapshotStats
+}
+
+// SetBackupPolicy sets where snapshots go and how often they are taken. The
+// schedule resumes from the newest snapshot already in Dir.
+func (e *Engine) SetBackupPolicy(policy BackupPolicy) {
+	if policy.Retention <= 0 {
+		policy.Retention = DefaultBackupRetention
+	}
+	var last time.Time
+	if snapshots, err := listSnapshots(policy.Dir); err == nil && len(snapshots) > 0 {
+		last = snapshots[0].CreatedAt
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.backup = policy
+	e.lastSnapshot = last
+}
+
+func (e *Engine) backupPolicy() BackupPolicy {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.backup
+}
+
+// snapshot copies the registry and every known workspace into a new
+// directory under dir, or the policy dir when dir is empty. The copy is
+// assembled under a ".partial" name and renamed once the manifest is written.
+func (e *Engine) snapshot(ctx context.Context, dir string, scheduled bool) (BackupResponse, error) {
+	policy := e.backupPolicy()
+	if dir == "" {
+		dir = policy.Dir
+	} else if !filepath.IsAbs(dir) {
+		return BackupResponse{}, fmt.Errorf("%w: dir must be an absolute path", artifacts.ErrInvalidInput)
+	}
+	if dir == "" {
+		return BackupResponse{}, fmt.Errorf("%w: no snapshot directory is configured", artifacts.ErrInvalidInput)
+	}
+
+	e.snapMu.Lock()
+	defer e.snapMu.Unlock()
+	ids, err := e.knownWorkspaceIDs(ctx)
+	if err != nil {
+		return BackupResponse{}, err
+	}
+
+	now := engineNowFn().UTC().Truncate(time.Second)
+	id := now.Format(snapshotIDLayout)
+	final := filepath.Join(dir, id)
+	for i := 1; pathExists(final) || pathExists(final+snapshotPartialSuffix); i++ {
+		id = fmt.Sprintf("%s-%d", now.Format(snapshotIDLayout), i)
+		final = filepath.Join(dir, id)
+	}
+	partial := final + snapshotPartialSuffix
+	if err := os.MkdirAll(partial, 0o700); err != nil {
+		return BackupResponse{}, err
+	}
+	done := false
+	defer func() {
+		if !done {
+			removeAllIgnore(partial)
+		}
+	}()
+
+	manifest := SnapshotManifest{Version: 1, ID: id, CreatedAt: now, Scheduled: scheduled, Workspaces: []SnapshotWorkspace{}}
+	if backuper, ok := e.registry.(interface {
+		Backup(context.Context, string) error
+	}); ok {
+		if err := backuper.Backup(ctx, filepath.Join(partial, "registry.sqlite")); err != nil {
+			return BackupResponse{}, fmt.Errorf("back up registry: %w", err)
+		}
+		manifest.Registry = true
+	}
+	for _, wsID := range ids {
+		handle, err := e.handleForWorkspaceID(ctx, wsID)
+		if err != nil {
+			return BackupResponse{}, err
+		}
+		wsDir := filepath.Join(partial, "workspaces", wsID)
+		stats, err := handle.Snapshot(ctx, wsDir)
+		if err != nil {
+			return BackupResponse{}, fmt.Errorf("snapshot workspace %s: %w", wsID, err)
+		}
+		encrypted, err := copyKeyFile(filepath.Join(e.workspaceRoot(wsID), keyring.WorkspaceKeyFileName), filepath.Join(wsDir, keyring.WorkspaceKeyFileName))
+		if err != nil {
+			return BackupResponse{}, fmt.Errorf("snapshot workspace %s: %w", wsID, err)
+		}
+		manifest.Workspaces = append(manifest.Workspaces, SnapshotWorkspace{WorkspaceID: wsID, Encrypted: encrypted, SnapshotStats: stats})
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return BackupResponse{}, err
+	}
+	if err := os.WriteFile(filepath.Join(partial, snapshotManifestName), append(b, '\n'), 0o600); err != nil {
+		return BackupResponse{}, err
+	}
+	if err := os.Rename(partial, final); err != nil {
+		return BackupResponse{}, err
+	}
+	done = true
+
+	if scheduled {
+		if err := pruneSnapshots(dir, policy.Retention); err != nil {
+			return BackupResponse{}, fmt.Errorf("prune snapshots: %w", err)
+		}
+	}
+	return BackupResponse{Path: final, SnapshotManifest: manifest}, nil
+}
+
+// restore replaces workspaces with their state in a snapshot: the selected
+// one, or every workspace in the snapshot plus the registry when the
+// selector is empty. Workspaces created after the snapshot are left alone.
+func (e *Engine) restore(ctx context.Context, req RestoreRequest) (RestoreResponse, error) {
+	dir, err := e.resolveSnapshot(req.Snapshot)
+	if err != nil {
+		return RestoreResponse{}, err
+	}
+	manifest, err := readSnapshotManifest(dir)
+	if err != nil {
+		return RestoreResponse{}, fmt.Errorf("%w: %s is not a snapshot: %v", artifacts.ErrInvalidInput, dir, err)
+	}
+
+	full := strings.TrimSpace(req.Workspace.WorkspaceID) == "" && len(req.Workspace.Roots) == 0
+	targets := manifest.Workspaces
+	if !full {
+		workspaceID, _, err := normalizeWorkspaceSelector(req.Workspace)
+		if err != nil {
+			return RestoreResponse{}, err
+		}
+		targets = nil
+		for _, ws := range manifest.Workspaces {
+			if ws.WorkspaceID == workspaceID {
+				targets = append(targets, ws)
+			}
+		}
+		if len(targets) == 0 {
+			return RestoreResponse{}, fmt.Errorf("%w: snapshot %s has no workspace %s", artifacts.ErrNotFound, manifest.ID, workspaceID)
+		}
+	}
+
+	e.snapMu.Lock()
+	defer e.snapMu.Unlock()
+	defer e.invalidateUsage()
+	out := RestoreResponse{SnapshotID: manifest.ID, Workspaces: []string{}}
+	if full && manifest.Registry {
+		if restorer, ok := e.registry.(interface {
+			Restore(context.Context, string) error
+		}); ok {
+			if err := restorer.Restore(ctx, filepath.Join(dir, "registry.sqlite")); err != nil {
+				return out, fmt.Errorf("restore registry: %w", err)
+			}
+			out.Registry = true
+		}
+	}
+	for _, ws := range targets {
+		wsDir := filepath.Join(dir, "workspaces", ws.WorkspaceID)
+		if ws.Encrypted {
+			// Keep a current key file: it wraps the same data key, possibly
+			// under a newer master key than the snapshot's copy.
+			if _, err := copyKeyFile(filepath.Join(wsDir, keyring.WorkspaceKeyFileName), filepath.Join(e.workspaceRoot(ws.WorkspaceID), keyring.WorkspaceKeyFileName)); err != nil {
+				return out, fmt.Errorf("restore workspace %s: %w", ws.WorkspaceID, err)
+			}
+		}
+		handle, err := e.handleForWorkspaceID(ctx, ws.WorkspaceID)
+		if err != nil {
+			return out, err
+		}
+		if err := handle.Restore(ctx, wsDir); err != nil {
+			return out, fmt.Errorf("restore workspace %s: %w", ws.WorkspaceID, err)
+		}
+		out.Workspaces = append(out.Workspaces, ws.WorkspaceID)
+	}
+	return out, nil
+}
+
+// snapshots lists the snapshots in the policy dir, newest first.
+func (e *Engine) snapshots() (ListSnapshotsResponse, error) {
+	dir := e.backupPolicy().Dir
+	if dir == "" {
+		return ListSnapshotsResponse{}, fmt.Errorf("%w: no snapshot directory is configured", artifacts.ErrInvalidInput)
+	}
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		return ListSnapshotsResponse{}, err
+	}
+	return ListSnapshotsResponse{Dir: dir, Snapshots: snapshots}, nil
+}
+
+// resolveSnapshot accepts an absolute snapshot path or the ID of a snapshot
+// in the policy dir.
+func (e *Engine) resolveSnapshot(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	switch {
+	case ref == "":
+		return "", fmt.Errorf("%w: snapshot is required", artifacts.ErrInvalidInput)
+	case filepath.IsAbs(ref):
+		return filepath.Clean(ref), nil
+	case strings.ContainsAny(ref, `/\`) || ref == "." || ref == "..":
+		return "", fmt.Errorf("%w: snapshot must be an ID or an absolute path", artifacts.ErrInvalidInput)
+	}
+	dir := e.backupPolicy().Dir
+	if dir == "" {
+		return "", fmt.Errorf("%w: no snapshot directory is configured", artifacts.ErrInvalidInput)
+	}
+	return filepath.Join(dir, ref), nil
+}
+
+// snapshotDue reports whether a scheduled snapshot should run now, and
+// records the attempt so a failing snapshot is not retried every tick.
+func (e *Engine) snapshotDue(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.backup.Interval <= 0 || e.backup.Dir == "" || now.Before(e.lastSnapshot.Add(e.backup.Interval)) {
+		return false
+	}
+	e.lastSnapshot = now
+	return true
+}
+
+func (e *Engine) invalidateUsage() {
+	e.usageMu.Lock()
+	e.usage = nil
+	e.usageMu.Unlock()
+}
+
+func readSnapshotManifest(dir string) (SnapshotManifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, snapshotManifestName))
+	if err != nil {
+		return SnapshotManifest{}, err
+	}
+	var manifest SnapshotManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return SnapshotManifest{}, err
+	}
+	if manifest.Version != 1 {
+		return SnapshotManifest{}, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+	return manifest, nil
+}
+
+// listSnapshots returns the complete snapshots in dir, newest first.
+func listSnapshots(dir string) ([]SnapshotManifest, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []SnapshotManifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := []SnapshotManifest{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasSuffix(entry.Name(), snapshotPartialSuffix) {
+			continue
+		}
+		manifest, err := readSnapshotManifest(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		out = append(out, manifest)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out, nil
+}
+
+// pruneSnapshots removes scheduled snapshots beyond the newest retain, and
+// partial directories left by interrupted snapshots. Callers hold snapMu.
+func pruneSnapshots(dir string, retain int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasSuffix(entry.Name(), snapshotPartialSuffix) {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for _, s := range snapshots {
+		if !s.Scheduled {
+			continue
+		}
+		if kept < retain {
+			kept++
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, s.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyKeyFile copies a workspace key file if src exists and dst does not.
+// It reports whether dst holds a key file afterwards.
+func copyKeyFile(src, dst string) (bool, error) {
+	if pathExists(dst) {
+		return true, nil
+	}
+	b, err := os.ReadFile(src)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(dst, b, 0o600); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func pathExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
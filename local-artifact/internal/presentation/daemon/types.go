@@ -1,6 +1,12 @@
 package daemon
 
-import "github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/filestore"
+)
 
 const (
 	DefaultMaxRequestBytes int64 = 12 << 20 // 12 MiB
@@ -17,6 +23,10 @@ type SaveTextRequest struct {
 	Text            string            `json:"text"`
 	MimeType        string            `json:"mimeType,omitempty"`
 	ExpectedPrevRef string            `json:"expectedPrevRef,omitempty"`
+	// TTL ("24h") or ExpiresAt makes the name expire; both override the
+	// default TTL policy.
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 type SaveBlobRequest struct {
@@ -26,6 +36,36 @@ type SaveBlobRequest struct {
 	MimeType        string            `json:"mimeType"`
 	Filename        string            `json:"filename,omitempty"`
 	ExpectedPrevRef string            `json:"expectedPrevRef,omitempty"`
+	TTL             string            `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time        `json:"expiresAt,omitempty"`
+}
+
+// SaveJSONRequest saves a JSON document as application/json. JSON is the
+// document itself, not a string holding it.
+type SaveJSONRequest struct {
+	Workspace       WorkspaceSelector `json:"workspace"`
+	Name            string            `json:"name"`
+	JSON            json.RawMessage   `json:"json"`
+	ExpectedPrevRef string            `json:"expectedPrevRef,omitempty"`
+	TTL             string            `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time        `json:"expiresAt,omitempty"`
+}
+
+const (
+	PatchFormatJSONPatch  = "json-patch"
+	PatchFormatMergePatch = "merge-patch"
+)
+
+// PatchJSONRequest patches the latest version of a JSON artifact. Format is
+// "json-patch" (RFC 6902, the default) or "merge-patch" (RFC 7386).
+type PatchJSONRequest struct {
+	Workspace       WorkspaceSelector `json:"workspace"`
+	Name            string            `json:"name"`
+	Patch           json.RawMessage   `json:"patch"`
+	Format          string            `json:"format,omitempty"`
+	ExpectedPrevRef string            `json:"expectedPrevRef,omitempty"`
+	TTL             string            `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time        `json:"expiresAt,omitempty"`
 }
 
 type ResolveRequest struct {
@@ -46,11 +86,46 @@ type Selector struct {
 type GetRequest struct {
 	Workspace WorkspaceSelector `json:"workspace"`
 	Selector  Selector          `json:"selector"`
+	// Source names the workspace to read from ("global", an ID or an alias)
+	// when it differs from Workspace, the reading workspace.
+	Source string `json:"source,omitempty"`
+	// Pointer is an RFC 6901 JSON pointer into a JSON artifact; the response
+	// data is then the selected value only.
+	Pointer string `json:"pointer,omitempty"`
+	// Offset/Length, StartLine/EndLine, Head and Tail select part of the
+	// data, after Pointer; MaxBytes caps it. See artifacts.ReadRange.
+	Offset    int64 `json:"offset,omitempty"`
+	Length    int64 `json:"length,omitempty"`
+	StartLine int   `json:"startLine,omitempty"`
+	EndLine   int   `json:"endLine,omitempty"`
+	Head      int   `json:"head,omitempty"`
+	Tail      int   `json:"tail,omitempty"`
+	MaxBytes  int64 `json:"maxBytes,omitempty"`
+}
+
+func (r GetRequest) ReadRange() artifacts.ReadRange {
+	return artifacts.ReadRange{
+		Offset:    r.Offset,
+		Length:    r.Length,
+		StartLine: r.StartLine,
+		EndLine:   r.EndLine,
+		Head:      r.Head,
+		Tail:      r.Tail,
+		MaxBytes:  r.MaxBytes,
+	}
 }
 
+// GetResponse describes the returned data against the whole body, or the
+// Pointer value: Offset is where the data starts, and Truncated reports that
+// MaxBytes cut the selection short.
 type GetResponse struct {
 	Artifact   artifacts.ArtifactVersion `json:"artifact"`
 	DataBase64 string                    `json:"dataBase64"`
+	Pointer    string                    `json:"pointer,omitempty"`
+	Offset     int64                     `json:"offset,omitempty"`
+	TotalBytes int64                     `json:"totalBytes"`
+	TotalLines int                       `json:"totalLines"`
+	Truncated  bool                      `json:"truncated,omitempty"`
 }
 
 type ListRequest struct {
@@ -73,10 +148,202 @@ type DeleteResponse struct {
 	Artifact artifacts.ArtifactVersion `json:"artifact"`
 }
 
+// BatchOp is one write of a batch. Op "put" saves Text or, for binary
+// content, DataBase64 with a MimeType; Op "delete" tombstones Name or Ref.
+type BatchOp struct {
+	Op              string     `json:"op"`
+	Name            string     `json:"name,omitempty"`
+	Ref             string     `json:"ref,omitempty"`
+	Text            string     `json:"text,omitempty"`
+	DataBase64      string     `json:"dataBase64,omitempty"`
+	MimeType        string     `json:"mimeType,omitempty"`
+	Filename        string     `json:"filename,omitempty"`
+	ExpectedPrevRef string     `json:"expectedPrevRef,omitempty"`
+	TTL             string     `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time `json:"expiresAt,omitempty"`
+}
+
+type BatchRequest struct {
+	Workspace WorkspaceSelector `json:"workspace"`
+	Ops       []BatchOp         `json:"ops"`
+}
+
+// BatchResponse lists the version each op wrote, in request order.
+type BatchResponse struct {
+	Artifacts []artifacts.ArtifactVersion `json:"artifacts"`
+}
+
+// HealthResponse is served without authentication, so it carries nothing but
+// liveness; process details are on the status endpoint.
 type HealthResponse struct {
 	Status string `json:"status"`
 }
 
+type StatusResponse struct {
+	Status             string            `json:"status"`
+	PID                int               `json:"pid"`
+	Version            string            `json:"version,omitempty"`
+	StartedAt          time.Time         `json:"startedAt"`
+	UptimeSeconds      int64             `json:"uptimeSeconds"`
+	OpenWorkspaces     []string          `json:"openWorkspaces"`
+	MaxOpenWorkspaces  int               `json:"maxOpenWorkspaces"`
+	WorkspaceEvictions uint64            `json:"workspaceEvictions"`
+	Quota              artifacts.Quota   `json:"quota"`
+	EncryptAtRest      bool              `json:"encryptAtRest"`
+	MasterKeyID        string            `json:"masterKeyID,omitempty"`
+	Workspaces         []WorkspaceStatus `json:"workspaces"`
+	Routes             []RouteMetrics    `json:"routes"`
+	SQLiteBusyRetries  uint64            `json:"sqliteBusyRetries"`
+	AuthFailures       uint64            `json:"authFailures"`
+}
+
+// WorkspaceStatus reports both the on-disk blob store and the logical usage
+// that quotas are enforced against; they differ with compression and dedup.
+type WorkspaceStatus struct {
+	WorkspaceID string          `json:"workspaceID"`
+	Open        bool            `json:"open"`
+	BlobFiles   int64           `json:"blobFiles"`
+	BlobBytes   int64           `json:"blobBytes"`
+	Usage       artifacts.Usage `json:"usage"`
+}
+
+type RouteMetrics struct {
+	Route     string  `json:"route"`
+	Requests  uint64  `json:"requests"`
+	Errors    uint64  `json:"errors"`
+	AvgMillis float64 `json:"avgMillis"`
+	MaxMillis float64 `json:"maxMillis"`
+}
+
+// VerifyRequest checks one workspace, or every known workspace when
+// Workspace is empty.
+type VerifyRequest struct {
+	Workspace WorkspaceSelector `json:"workspace"`
+	Repair    bool              `json:"repair,omitempty"`
+}
+
+type VerifyResponse struct {
+	Workspaces []WorkspaceIntegrity `json:"workspaces"`
+}
+
+type WorkspaceIntegrity struct {
+	WorkspaceID string `json:"workspaceID"`
+	artifacts.IntegrityReport
+}
+
+// MigrateLegacyRequest imports a filestore directory written by early builds
+// into a workspace. LegacyRoot must be an absolute path on the daemon host.
+type MigrateLegacyRequest struct {
+	Workspace  WorkspaceSelector `json:"workspace"`
+	LegacyRoot string            `json:"legacyRoot"`
+	DryRun     bool              `json:"dryRun,omitempty"`
+}
+
+type MigrateLegacyResponse struct {
+	WorkspaceID string `json:"workspaceID"`
+	filestore.MigrateReport
+}
+
+// RotateKeyResponse lists the workspaces whose data key was rewrapped under
+// the new master key.
+type RotateKeyResponse struct {
+	MasterKeyID string   `json:"masterKeyID"`
+	Rewrapped   []string `json:"rewrapped"`
+}
+
+// BackupRequest takes a snapshot. Dir, when set, must be an absolute path on
+// the daemon host; it defaults to the configured snapshot directory.
+type BackupRequest struct {
+	Dir string `json:"dir,omitempty"`
+}
+
+type BackupResponse struct {
+	Path string `json:"path"`
+	SnapshotManifest
+}
+
+type ListSnapshotsResponse struct {
+	Dir       string             `json:"dir"`
+	Snapshots []SnapshotManifest `json:"snapshots"`
+}
+
+// RestoreRequest restores from a snapshot ID or absolute path. An empty
+// workspace selector restores every workspace in the snapshot and the
+// registry.
+type RestoreRequest struct {
+	Snapshot  string            `json:"snapshot"`
+	Workspace WorkspaceSelector `json:"workspace"`
+}
+
+type RestoreResponse struct {
+	SnapshotID string   `json:"snapshotID"`
+	Registry   bool     `json:"registry"`
+	Workspaces []string `json:"workspaces"`
+}
+
+// WorkspaceIdentity maps a git identity key ("git:<remote>#<path>") to the
+// workspace it resolves to.
+type WorkspaceIdentity struct {
+	Key         string    `json:"key"`
+	WorkspaceID string    `json:"workspaceID"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ListIdentitiesResponse struct {
+	Identities []WorkspaceIdentity `json:"identities"`
+}
+
+type MapIdentityRequest struct {
+	Key       string            `json:"key"`
+	Workspace WorkspaceSelector `json:"workspace"`
+}
+
+type ShareRequest struct {
+	Workspace WorkspaceSelector `json:"workspace"`
+	Name      string            `json:"name"`
+	// With is "*" (the default) for every workspace, or a workspace ID or alias.
+	With   string `json:"with,omitempty"`
+	Revoke bool   `json:"revoke,omitempty"`
+}
+
+type WorkspaceShare struct {
+	SourceWorkspaceID string    `json:"sourceWorkspaceID"`
+	Name              string    `json:"name"`
+	TargetWorkspaceID string    `json:"targetWorkspaceID"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+type ShareResponse struct {
+	Share   WorkspaceShare `json:"share"`
+	URI     string         `json:"uri"`
+	Revoked bool           `json:"revoked,omitempty"`
+}
+
+type ListSharesRequest struct {
+	Workspace WorkspaceSelector `json:"workspace"`
+}
+
+type ListSharesResponse struct {
+	WorkspaceID string           `json:"workspaceID"`
+	Shares      []WorkspaceShare `json:"shares"`
+}
+
+type WorkspaceAlias struct {
+	Alias       string    `json:"alias"`
+	WorkspaceID string    `json:"workspaceID,omitempty"`
+	CreatedAt   time.Time `json:"createdAt,omitempty"`
+}
+
+type SetAliasRequest struct {
+	Alias     string            `json:"alias"`
+	Workspace WorkspaceSelector `json:"workspace"`
+	Remove    bool              `json:"remove,omitempty"`
+}
+
+type ListAliasesResponse struct {
+	Aliases []WorkspaceAlias `json:"aliases"`
+}
+
 type ShutdownResponse struct {
 	Status string `json:"status"`
 }
@@ -0,0 +1,331 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/blobstore"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/keyring"
+	artsqlite "github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/sqlite"
+)
+
+var errEngineClosed = errors.New("daemon engine is closed")
+
+var (
+	engineNowFn       = time.Now
+	openWorkspaceRepo = artsqlite.NewArtifactRepository
+)
+
+// workspaceHandle is the artifacts.Repository behind a cached workspace
+// service. It opens the workspace database on first use, counts in-flight
+// calls, and lets the engine close the database while no call is running.
+// The next call transparently reopens it, so services handed out to the web
+// UI stay valid across evictions.
+type workspaceHandle struct {
+	engine   *Engine
+	id       string
+	root     string
+	compress blobstore.CompressionPolicy
+	keys     *keyring.Keyring
+	encrypt  bool
+
+	mu       sync.Mutex
+	repo     *artsqlite.ArtifactRepository
+	refs     int
+	lastUsed time.Time
+	closed   bool
+}
+
+func (h *workspaceHandle) acquire() (*artsqlite.ArtifactRepository, error) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, errEngineClosed
+	}
+	opened := false
+	if h.repo == nil {
+		repo, err := openWorkspaceRepo(h.root)
+		if err != nil {
+			h.mu.Unlock()
+			return nil, err
+		}
+		repo.SetCompression(h.compress)
+		if err := h.attachSealer(repo); err != nil {
+			closeIgnore(repo)
+			h.mu.Unlock()
+			return nil, err
+		}
+		h.repo = repo
+		opened = true
+	}
+	h.refs++
+	h.lastUsed = engineNowFn()
+	repo := h.repo
+	h.mu.Unlock()
+
+	if opened {
+		h.engine.enforceOpenLimit()
+	}
+	return repo, nil
+}
+
+// attachSealer unwraps the workspace data key, creating one when new blobs
+// are encrypted. A workspace that was encrypted earlier stays readable after
+// encryption is turned off, as long as the master key is still present.
+func (h *workspaceHandle) attachSealer(repo *artsqlite.ArtifactRepository) error {
+	dataKey, err := h.keys.DataKey(h.root, h.encrypt)
+	if err != nil {
+		return fmt.Errorf("workspace %s: %w", h.id, err)
+	}
+	if dataKey == nil {
+		return nil
+	}
+	sealer, err := blobstore.NewSealer(dataKey)
+	if err != nil {
+		return fmt.Errorf("workspace %s: %w", h.id, err)
+	}
+	repo.SetSealer(sealer, h.encrypt)
+	return nil
+}
+
+func (h *workspaceHandle) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.refs--
+	h.lastUsed = engineNowFn()
+}
+
+func (h *workspaceHandle) isOpen() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.repo != nil
+}
+
+// evictIfIdle closes the database when no call is in flight and, for a
+// non-zero cutoff, it was last used before cutoff.
+func (h *workspaceHandle) evictIfIdle(cutoff time.Time) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.repo == nil || h.refs > 0 {
+		return false, nil
+	}
+	if !cutoff.IsZero() && h.lastUsed.After(cutoff) {
+		return false, nil
+	}
+	err := h.repo.Close()
+	h.repo = nil
+	return true, err
+}
+
+// checkpoint runs a WAL checkpoint if the database is open. It never reopens
+// an evicted database and does not count as use for idle eviction.
+func (h *workspaceHandle) checkpoint(ctx context.Context) error {
+	h.mu.Lock()
+	if h.repo == nil || h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.refs++
+	repo := h.repo
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		h.refs--
+		h.mu.Unlock()
+	}()
+	return repo.Checkpoint(ctx)
+}
+
+// expire tombstones expired names without counting as use: an open database
+// keeps its idle deadline and a closed one is closed again afterwards.
+func (h *workspaceHandle) expire(ctx context.Context, now time.Time) ([]artifacts.ArtifactVersion, error) {
+	h.mu.Lock()
+	wasOpen, lastUsed := h.repo != nil, h.lastUsed
+	h.mu.Unlock()
+	repo, err := h.acquire()
+	if err != nil {
+		return nil, err
+	}
+	out, err := repo.ExpireNames(ctx, now)
+	h.mu.Lock()
+	h.refs--
+	if wasOpen {
+		h.lastUsed = lastUsed
+	}
+	h.mu.Unlock()
+	if !wasOpen {
+		if _, closeErr := h.evictIfIdle(time.Time{}); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return out, err
+}
+
+func (h *workspaceHandle) close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	if h.repo == nil {
+		return nil
+	}
+	err := h.repo.Close()
+	h.repo = nil
+	return err
+}
+
+func (h *workspaceHandle) Save(ctx context.Context, a artifacts.ArtifactVersion, data []byte, opts artifacts.SaveOptions) (artifacts.ArtifactVersion, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return artifacts.ArtifactVersion{}, err
+	}
+	defer h.release()
+	return repo.Save(ctx, a, data, opts)
+}
+
+func (h *workspaceHandle) Resolve(ctx context.Context, name string) (string, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer h.release()
+	return repo.Resolve(ctx, name)
+}
+
+func (h *workspaceHandle) Get(ctx context.Context, sel artifacts.Selector) (artifacts.ArtifactVersion, []byte, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return artifacts.ArtifactVersion{}, nil, err
+	}
+	defer h.release()
+	return repo.Get(ctx, sel)
+}
+
+func (h *workspaceHandle) List(ctx context.Context, prefix string, limit int) ([]artifacts.ArtifactVersion, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer h.release()
+	return repo.List(ctx, prefix, limit)
+}
+
+func (h *workspaceHandle) ListVersions(ctx context.Context, name string, limit int) ([]artifacts.ArtifactVersion, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer h.release()
+	return repo.ListVersions(ctx, name, limit)
+}
+
+func (h *workspaceHandle) Delete(ctx context.Context, sel artifacts.Selector) (artifacts.ArtifactVersion, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return artifacts.ArtifactVersion{}, err
+	}
+	defer h.release()
+	return repo.Delete(ctx, sel)
+}
+
+func (h *workspaceHandle) Batch(ctx context.Context, ops []artifacts.BatchOp) ([]artifacts.ArtifactVersion, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer h.release()
+	return repo.Batch(ctx, ops)
+}
+
+func (h *workspaceHandle) Usage(ctx context.Context) (artifacts.Usage, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return artifacts.Usage{}, err
+	}
+	defer h.release()
+	return repo.Usage(ctx)
+}
+
+func (h *workspaceHandle) VersionCount(ctx context.Context, name string) (int, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return 0, err
+	}
+	defer h.release()
+	return repo.VersionCount(ctx, name)
+}
+
+func (h *workspaceHandle) HasPayload(ctx context.Context, sha256 string) (bool, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return false, err
+	}
+	defer h.release()
+	return repo.HasPayload(ctx, sha256)
+}
+
+func (h *workspaceHandle) Verify(ctx context.Context, repair bool) (artifacts.IntegrityReport, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return artifacts.IntegrityReport{}, err
+	}
+	defer h.release()
+	return repo.Verify(ctx, repair)
+}
+
+func (h *workspaceHandle) Snapshot(ctx context.Context, dir string) (artsqlite.SnapshotStats, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return artsqlite.SnapshotStats{}, err
+	}
+	defer h.release()
+	return repo.Snapshot(ctx, dir)
+}
+
+func (h *workspaceHandle) Restore(ctx context.Context, dir string) error {
+	repo, err := h.acquire()
+	if err != nil {
+		return err
+	}
+	defer h.release()
+	return repo.Restore(ctx, dir)
+}
+
+func (h *workspaceHandle) HasVersion(ctx context.Context, ref string) (bool, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return false, err
+	}
+	defer h.release()
+	return repo.HasVersion(ctx, ref)
+}
+
+func (h *workspaceHandle) VersionMeta(ctx context.Context, ref string) (artifacts.ArtifactVersion, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return artifacts.ArtifactVersion{}, err
+	}
+	defer h.release()
+	return repo.VersionMeta(ctx, ref)
+}
+
+func (h *workspaceHandle) LatestRef(ctx context.Context, name string) (string, error) {
+	repo, err := h.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer h.release()
+	return repo.LatestRef(ctx, name)
+}
+
+func (h *workspaceHandle) Import(ctx context.Context, a artifacts.ArtifactVersion, data []byte) error {
+	repo, err := h.acquire()
+	if err != nil {
+		return err
+	}
+	defer h.release()
+	return repo.Import(ctx, a, data)
+}
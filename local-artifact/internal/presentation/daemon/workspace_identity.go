@@ -0,0 +1,199 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/workspaces"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/infrastructure/gitremote"
+)
+
+// WorkspaceIdentityModeFunc reports the identity mode a workspace root opts
+// into, workspaces.IdentityModeRoots or workspaces.IdentityModeGitRemote.
+type WorkspaceIdentityModeFunc func(rootPath string) (string, error)
+
+// identityRegistry is implemented by registries that keep a mapping table from
+// identity keys to workspace IDs.
+type identityRegistry interface {
+	ResolveIdentity(ctx context.Context, key, fallbackWorkspaceID string) (string, error)
+	MapIdentity(ctx context.Context, key, workspaceID string) error
+	ListIdentities(ctx context.Context) ([]workspaces.Identity, error)
+}
+
+// identityCacheTTL bounds how long a roots-to-workspace resolution is reused
+// before settings and git config are read again.
+const identityCacheTTL = time.Minute
+
+// maxIdentityCacheEntries bounds the resolution cache; expired entries are
+// dropped first, and the cache is cleared if it is still full.
+const maxIdentityCacheEntries = 1024
+
+var locateGitRepoFn = gitremote.Locate
+
+type cachedIdentity struct {
+	workspaceID string
+	expires     time.Time
+}
+
+// SetWorkspaceIdentityMode sets how the mode of each workspace root is
+// looked up. Without it every workspace is keyed by its roots.
+func (e *Engine) SetWorkspaceIdentityMode(mode WorkspaceIdentityModeFunc) {
+	e.identityMu.Lock()
+	defer e.identityMu.Unlock()
+	e.identityMode = mode
+	e.identityCache = nil
+}
+
+// resolveRootsWorkspace maps normalized roots to a workspace ID. When every
+// root opts into git-remote identity and lies in a repository with a remote,
+// the workspace is keyed by remote and repository-relative path, so clones,
+// worktrees and container mounts of one repository share it. The first
+// resolution of a key adopts the roots workspace if it already exists, so
+// switching modes keeps existing artifacts. Otherwise rootsID is returned.
+func (e *Engine) resolveRootsWorkspace(ctx context.Context, rootsID string, roots []string) (string, error) {
+	registry, ok := e.registry.(identityRegistry)
+	if !ok {
+		return rootsID, nil
+	}
+	now := engineNowFn()
+	e.identityMu.Lock()
+	mode := e.identityMode
+	cached, hit := e.identityCache[rootsID]
+	e.identityMu.Unlock()
+	if mode == nil {
+		return rootsID, nil
+	}
+	if hit && now.Before(cached.expires) {
+		return cached.workspaceID, nil
+	}
+
+	workspaceID := rootsID
+	if key, ok := gitIdentityKey(mode, roots); ok {
+		fallback := workspaces.ComputeIdentityWorkspaceID([]string{key})
+		if _, err := e.registry.GetWorkspace(ctx, rootsID); err == nil {
+			fallback = rootsID
+		} else if !errors.Is(err, artifacts.ErrNotFound) {
+			return "", err
+		}
+		resolved, err := registry.ResolveIdentity(ctx, key, fallback)
+		if err != nil {
+			return "", fmt.Errorf("resolve workspace identity: %w", err)
+		}
+		workspaceID = resolved
+	}
+
+	e.identityMu.Lock()
+	if e.identityCache == nil {
+		e.identityCache = map[string]cachedIdentity{}
+	}
+	if len(e.identityCache) >= maxIdentityCacheEntries {
+		for id, entry := range e.identityCache {
+			if !now.Before(entry.expires) {
+				delete(e.identityCache, id)
+			}
+		}
+		if len(e.identityCache) >= maxIdentityCacheEntries {
+			clear(e.identityCache)
+		}
+	}
+	e.identityCache[rootsID] = cachedIdentity{workspaceID: workspaceID, expires: now.Add(identityCacheTTL)}
+	e.identityMu.Unlock()
+	return workspaceID, nil
+}
+
+// gitIdentityKey builds the identity key of a set of roots, or reports false
+// when any root is keyed by path.
+func gitIdentityKey(mode WorkspaceIdentityModeFunc, roots []string) (string, bool) {
+	keys := make([]string, 0, len(roots))
+	for _, root := range roots {
+		dir, err := rootURIPath(root)
+		if err != nil {
+			return "", false
+		}
+		if m, err := mode(dir); err != nil || m != workspaces.IdentityModeGitRemote {
+			return "", false
+		}
+		repo, err := locateGitRepoFn(dir)
+		if err != nil {
+			return "", false
+		}
+		remote, err := workspaces.NormalizeGitRemote(repo.Remote)
+		if err != nil {
+			return "", false
+		}
+		rel, err := filepath.Rel(repo.TopLevel, dir)
+		if err != nil {
+			return "", false
+		}
+		keys = append(keys, workspaces.GitIdentityKey(remote, filepath.ToSlash(rel)))
+	}
+	if len(keys) == 0 {
+		return "", false
+	}
+	return strings.Join(keys, "\n"), true
+}
+
+// rootURIPath converts a normalized file:// root URI to a local path.
+func rootURIPath(root string) (string, error) {
+	u, err := url.Parse(root)
+	if err != nil {
+		return "", err
+	}
+	p := u.Path
+	if runtime.GOOS == "windows" {
+		if len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+			p = p[1:]
+		}
+		if u.Host != "" {
+			p = "//" + u.Host + p
+		}
+	}
+	return filepath.FromSlash(p), nil
+}
+
+func (e *Engine) identities(ctx context.Context) (ListIdentitiesResponse, error) {
+	registry, ok := e.registry.(identityRegistry)
+	if !ok {
+		return ListIdentitiesResponse{Identities: []WorkspaceIdentity{}}, nil
+	}
+	items, err := registry.ListIdentities(ctx)
+	if err != nil {
+		return ListIdentitiesResponse{}, err
+	}
+	out := ListIdentitiesResponse{Identities: make([]WorkspaceIdentity, 0, len(items))}
+	for _, item := range items {
+		out.Identities = append(out.Identities, WorkspaceIdentity{Key: item.Key, WorkspaceID: item.WorkspaceID, CreatedAt: item.CreatedAt})
+	}
+	return out, nil
+}
+
+// mapIdentity points an identity key at an existing workspace, for example
+// to merge a second clone's workspace into the one the key resolved to.
+func (e *Engine) mapIdentity(ctx context.Context, req MapIdentityRequest) (WorkspaceIdentity, error) {
+	registry, ok := e.registry.(identityRegistry)
+	if !ok {
+		return WorkspaceIdentity{}, fmt.Errorf("%w: workspace registry does not support identities", artifacts.ErrInvalidInput)
+	}
+	key := strings.TrimSpace(req.Key)
+	if !strings.HasPrefix(key, "git:") {
+		return WorkspaceIdentity{}, fmt.Errorf("%w: key must be a git identity key", artifacts.ErrInvalidInput)
+	}
+	workspaceID, _, err := normalizeWorkspaceSelector(req.Workspace)
+	if err != nil {
+		return WorkspaceIdentity{}, err
+	}
+	if err := registry.MapIdentity(ctx, key, workspaceID); err != nil {
+		return WorkspaceIdentity{}, err
+	}
+	e.identityMu.Lock()
+	e.identityCache = nil
+	e.identityMu.Unlock()
+	return WorkspaceIdentity{Key: key, WorkspaceID: workspaceID, CreatedAt: engineNowFn().UTC().Truncate(time.Second)}, nil
+}
@@ -0,0 +1,564 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SessionIDHeader = "Mcp-Session-Id"
+
+	maxHTTPMessageBytes = 8 << 20
+	sseKeepAlive        = 25 * time.Second
+	sessionStreamBuffer = 64
+	// sessionIdleTimeout ends sessions whose client went away without a
+	// DELETE. A session with a request or stream open is never idle.
+	sessionIdleTimeout = 30 * time.Minute
+)
+
+// HTTPHandler serves the MCP Streamable HTTP transport. Each session gets its
+// own Server, so roots and client capabilities stay per client while the
+// daemon behind them is shared.
+type HTTPHandler struct {
+	ctx         context.Context
+	newServer   func() *Server
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+}
+
+func NewHTTPHandler(ctx context.Context, newServer func() *Server) *HTTPHandler {
+	h := &HTTPHandler{ctx: ctx, newServer: newServer, idleTimeout: sessionIdleTimeout, sessions: map[string]*httpSession{}}
+	go h.expireIdleLoop()
+	return h
+}
+
+func (h *HTTPHandler) expireIdleLoop() {
+	ticker := time.NewTicker(h.idleTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.expireIdle(now)
+		}
+	}
+}
+
+// expireIdle ends every session that has had nothing open since before
+// now minus the idle timeout.
+func (h *HTTPHandler) expireIdle(now time.Time) {
+	h.mu.Lock()
+	expired := []*httpSession{}
+	for id, sess := range h.sessions {
+		if sess.idleSince(now) >= h.idleTimeout {
+			delete(h.sessions, id)
+			expired = append(expired, sess)
+		}
+	}
+	h.mu.Unlock()
+	for _, sess := range expired {
+		sess.cancel()
+		log.Printf("event=http_session_expired session=%s", sess.id)
+	}
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := validateOrigin(r); err != nil {
+		writeHTTPError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		h.handlePost(w, r)
+	case http.MethodGet:
+		h.handleGet(w, r)
+	case http.MethodDelete:
+		h.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeHTTPError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *HTTPHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxHTTPMessageBytes+1))
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, "read request body: "+err.Error())
+		return
+	}
+	if len(body) > maxHTTPMessageBytes {
+		writeHTTPError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+	messages, err := splitJSONRPCBatch(body)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, "parse JSON-RPC message: "+err.Error())
+		return
+	}
+
+	var requestIDs []string
+	initialize := false
+	for _, msg := range messages {
+		var envelope struct {
+			ID     json.RawMessage `json:"id,omitempty"`
+			Method string          `json:"method,omitempty"`
+		}
+		if err := json.Unmarshal(msg, &envelope); err != nil {
+			writeHTTPError(w, http.StatusBadRequest, "parse JSON-RPC message: "+err.Error())
+			return
+		}
+		if envelope.Method == "initialize" {
+			initialize = true
+		}
+		if envelope.Method != "" && len(envelope.ID) > 0 {
+			requestIDs = append(requestIDs, jsonRPCIDKey(envelope.ID))
+		}
+	}
+
+	sess, status, err := h.sessionForPost(r, initialize)
+	if err != nil {
+		writeHTTPError(w, status, err.Error())
+		return
+	}
+	defer sess.release()
+	if initialize {
+		w.Header().Set(SessionIDHeader, sess.id)
+	}
+
+	if len(requestIDs) == 0 {
+		for _, msg := range messages {
+			sess.server.handleIncomingLine(sess.ctx, msg, sess.reqCh)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	responses := make(chan []byte, len(requestIDs))
+	sess.await(requestIDs, responses)
+	defer sess.forget(requestIDs)
+	stream := sess.openStream()
+	defer sess.closeStream(stream)
+
+	for _, msg := range messages {
+		sess.server.handleIncomingLine(sess.ctx, msg, sess.reqCh)
+	}
+
+	writeSSEHeaders(w)
+	flusher.Flush()
+	for pending := len(requestIDs); pending > 0; {
+		select {
+		case msg := <-responses:
+			pending--
+			if msg == nil {
+				// The request was cancelled and gets no response.
+				continue
+			}
+			if err := writeSSEMessage(w, flusher, msg); err != nil {
+				return
+			}
+		case msg := <-stream:
+			if err := writeSSEMessage(w, flusher, msg); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-sess.ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *HTTPHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeHTTPError(w, http.StatusNotAcceptable, "GET requires Accept: text/event-stream")
+		return
+	}
+	sess, status, err := h.sessionForRequest(r)
+	if err != nil {
+		writeHTTPError(w, status, err.Error())
+		return
+	}
+	defer sess.release()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	stream, ok := sess.openStandalone()
+	if !ok {
+		writeHTTPError(w, http.StatusConflict, "session already has an open event stream")
+		return
+	}
+	defer sess.closeStandalone(stream)
+
+	writeSSEHeaders(w)
+	flusher.Flush()
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case msg := <-stream:
+			if err := writeSSEMessage(w, flusher, msg); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-sess.ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *HTTPHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sess, status, err := h.sessionForRequest(r)
+	if err != nil {
+		writeHTTPError(w, status, err.Error())
+		return
+	}
+	defer sess.release()
+	h.mu.Lock()
+	delete(h.sessions, sess.id)
+	h.mu.Unlock()
+	sess.cancel()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) sessionForPost(r *http.Request, initialize bool) (*httpSession, int, error) {
+	if strings.TrimSpace(r.Header.Get(SessionIDHeader)) != "" {
+		if initialize {
+			return nil, http.StatusBadRequest, errors.New("initialize must not carry a session id")
+		}
+		return h.sessionForRequest(r)
+	}
+	if !initialize {
+		return nil, http.StatusBadRequest, fmt.Errorf("missing %s header", SessionIDHeader)
+	}
+	sess, err := h.startSession()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return sess, http.StatusOK, nil
+}
+
+// sessionForRequest and sessionForPost return the session acquired; the
+// caller releases it when the request is done.
+func (h *HTTPHandler) sessionForRequest(r *http.Request) (*httpSession, int, error) {
+	id := strings.TrimSpace(r.Header.Get(SessionIDHeader))
+	if id == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("missing %s header", SessionIDHeader)
+	}
+	h.mu.Lock()
+	sess, ok := h.sessions[id]
+	if ok {
+		sess.acquire()
+	}
+	h.mu.Unlock()
+	if !ok || sess.ctx.Err() != nil {
+		if ok {
+			sess.release()
+		}
+		return nil, http.StatusNotFound, errors.New("unknown or expired session")
+	}
+	return sess, http.StatusOK, nil
+}
+
+func (h *HTTPHandler) startSession() (*httpSession, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("generate session id: %w", err)
+	}
+	ctx, cancel := context.WithCancel(h.ctx)
+	sess := &httpSession{
+		id:      hex.EncodeToString(buf),
+		server:  h.newServer(),
+		reqCh:   make(chan jsonRPCRequest, 16),
+		ctx:     ctx,
+		cancel:  cancel,
+		waiters: map[string]chan []byte{},
+		inUse:   1,
+	}
+	enc := json.NewEncoder(sess)
+	enc.SetEscapeHTML(false)
+	sess.server.enc = enc
+	sess.server.onCancelled = sess.cancelled
+
+	h.mu.Lock()
+	h.sessions[sess.id] = sess
+	h.mu.Unlock()
+	go func() {
+		if err := sess.server.dispatch(ctx, sess.reqCh, nil); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("event=http_session_failed session=%s error=%q", sess.id, err.Error())
+		}
+		h.mu.Lock()
+		delete(h.sessions, sess.id)
+		h.mu.Unlock()
+	}()
+	log.Printf("event=http_session_started session=%s", sess.id)
+	return sess, nil
+}
+
+// httpSession routes what its Server writes: responses go to the POST that
+// carried the request, everything else to the GET stream when one is open,
+// then to the newest POST stream, and is queued otherwise.
+type httpSession struct {
+	id     string
+	server *Server
+	reqCh  chan jsonRPCRequest
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	waiters    map[string]chan []byte
+	streams    []chan []byte
+	standalone chan []byte
+	backlog    [][]byte
+	inUse      int
+	lastUsed   time.Time
+}
+
+func (s *httpSession) acquire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inUse++
+}
+
+func (s *httpSession) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inUse--
+	s.lastUsed = time.Now()
+}
+
+func (s *httpSession) idleSince(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inUse > 0 {
+		return 0
+	}
+	return now.Sub(s.lastUsed)
+}
+
+// Write receives one encoded message per call from the Server's encoder.
+func (s *httpSession) Write(p []byte) (int, error) {
+	msg := bytes.TrimSpace(bytes.Clone(p))
+	var envelope struct {
+		ID     json.RawMessage `json:"id,omitempty"`
+		Method string          `json:"method,omitempty"`
+	}
+	if err := json.Unmarshal(msg, &envelope); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if envelope.Method == "" && len(envelope.ID) > 0 {
+		key := jsonRPCIDKey(envelope.ID)
+		if ch, ok := s.waiters[key]; ok {
+			delete(s.waiters, key)
+			ch <- msg
+			return len(p), nil
+		}
+	}
+	s.sendLocked(msg)
+	return len(p), nil
+}
+
+func (s *httpSession) sendLocked(msg []byte) {
+	target := s.standalone
+	if target == nil && len(s.streams) > 0 {
+		target = s.streams[len(s.streams)-1]
+	}
+	if target != nil {
+		select {
+		case target <- msg:
+			return
+		default:
+		}
+	}
+	if len(s.backlog) >= sessionStreamBuffer {
+		log.Printf("event=http_session_backlog_full session=%s dropped=1", s.id)
+		s.backlog = s.backlog[1:]
+	}
+	s.backlog = append(s.backlog, msg)
+}
+
+func (s *httpSession) await(ids []string, ch chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.waiters[id] = ch
+	}
+}
+
+// cancelled stops waiting for a request the client cancelled so the POST that
+// carried it can finish.
+func (s *httpSession) cancelled(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ch, ok := s.waiters[id]; ok {
+		delete(s.waiters, id)
+		ch <- nil
+	}
+}
+
+func (s *httpSession) forget(ids []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.waiters, id)
+	}
+}
+
+func (s *httpSession) openStream() chan []byte {
+	stream := make(chan []byte, sessionStreamBuffer)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams = append(s.streams, stream)
+	if s.standalone == nil {
+		s.flushBacklogLocked(stream)
+	}
+	return stream
+}
+
+func (s *httpSession) closeStream(stream chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, open := range s.streams {
+		if open == stream {
+			s.streams = append(s.streams[:i], s.streams[i+1:]...)
+			break
+		}
+	}
+	s.requeueLocked(stream)
+}
+
+func (s *httpSession) openStandalone() (chan []byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.standalone != nil {
+		return nil, false
+	}
+	s.standalone = make(chan []byte, sessionStreamBuffer)
+	s.flushBacklogLocked(s.standalone)
+	return s.standalone, true
+}
+
+func (s *httpSession) closeStandalone(stream chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.standalone == stream {
+		s.standalone = nil
+	}
+	s.requeueLocked(stream)
+}
+
+func (s *httpSession) flushBacklogLocked(stream chan []byte) {
+	for len(s.backlog) > 0 {
+		select {
+		case stream <- s.backlog[0]:
+			s.backlog = s.backlog[1:]
+		default:
+			return
+		}
+	}
+}
+
+// requeueLocked hands messages left on a closed stream to the next one.
+func (s *httpSession) requeueLocked(stream chan []byte) {
+	for {
+		select {
+		case msg := <-stream:
+			s.sendLocked(msg)
+		default:
+			return
+		}
+	}
+}
+
+func splitJSONRPCBatch(body []byte) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, errors.New("empty body")
+	}
+	if trimmed[0] != '[' {
+		return []json.RawMessage{trimmed}, nil
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(trimmed, &batch); err != nil {
+		return nil, err
+	}
+	if len(batch) == 0 {
+		return nil, errors.New("empty batch")
+	}
+	return batch, nil
+}
+
+// validateOrigin rejects browser requests from non-loopback origins, which
+// guards the local server against DNS rebinding.
+func validateOrigin(r *http.Request) error {
+	origin := strings.TrimSpace(r.Header.Get("Origin"))
+	if origin == "" {
+		return nil
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" {
+		return errors.New("cross-origin request blocked")
+	}
+	host := parsed.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return errors.New("cross-origin request blocked")
+}
+
+func writeSSEHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeSSEMessage(w io.Writer, flusher http.Flusher, msg []byte) error {
+	if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+func writeHTTPError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	body := jsonRPCResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &jsonRPCError{Code: -32000, Message: message}}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("event=write_http_error_failed error=%q", err.Error())
+	}
+}
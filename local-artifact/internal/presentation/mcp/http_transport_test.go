@@ -0,0 +1,298 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
+)
+
+const httpTestToken = "test-token"
+
+type httpTestClient struct {
+	t       *testing.T
+	handler *HTTPHandler
+	baseURL string
+	session string
+}
+
+func newHTTPTestServer(t *testing.T) *httpTestClient {
+	t.Helper()
+	root := t.TempDir()
+	return newHTTPTestServerWith(t, func() *Server { return newDaemonBackedServerAtRoot(t, root) })
+}
+
+func newHTTPTestServerWith(t *testing.T, newServer func() *Server) *httpTestClient {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := NewHTTPHandler(ctx, newServer)
+	srv := httptest.NewServer(daemon.AuthMiddleware(httpTestToken, handler, daemon.AuthOptions{}))
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+	return &httpTestClient{t: t, handler: handler, baseURL: srv.URL}
+}
+
+func (c *httpTestClient) post(v any) *http.Response {
+	c.t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		c.t.Fatalf("marshal request: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, c.baseURL, bytes.NewReader(body))
+	if err != nil {
+		c.t.Fatalf("build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+httpTestToken)
+	if c.session != "" {
+		req.Header.Set(SessionIDHeader, c.session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("post: %v", err)
+	}
+	return resp
+}
+
+func (c *httpTestClient) request(v any) []protocolMessage {
+	c.t.Helper()
+	resp := c.post(v)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		c.t.Fatalf("expected 200, got %d: %s", resp.StatusCode, b)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		c.t.Fatalf("expected event stream, got %q", got)
+	}
+	if id := resp.Header.Get(SessionIDHeader); id != "" {
+		c.session = id
+	}
+	var out []protocolMessage
+	readSSE(c.t, resp.Body, func(msg protocolMessage) bool {
+		out = append(out, msg)
+		return true
+	})
+	return out
+}
+
+func (c *httpTestClient) notify(v any) {
+	c.t.Helper()
+	resp := c.post(v)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		b, _ := io.ReadAll(resp.Body)
+		c.t.Fatalf("expected 202, got %d: %s", resp.StatusCode, b)
+	}
+}
+
+func readSSE(t *testing.T, r io.Reader, onMessage func(protocolMessage) bool) {
+	t.Helper()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var msg protocolMessage
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			t.Errorf("decode sse message %q: %v", data, err)
+			return
+		}
+		if !onMessage(msg) {
+			return
+		}
+	}
+}
+
+func TestHTTPHandler_SessionRoundTripWithServerRequestsOnEventStream(t *testing.T) {
+	c := newHTTPTestServer(t)
+
+	initMsgs := c.request(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "initialize",
+		"params":  map[string]any{"capabilities": map[string]any{"roots": map[string]any{}}},
+	})
+	if c.session == "" {
+		t.Fatalf("expected %s header on initialize response", SessionIDHeader)
+	}
+	if len(initMsgs) != 1 || string(initMsgs[0].ID) != "1" || len(initMsgs[0].Result) == 0 {
+		t.Fatalf("unexpected initialize response: %+v", initMsgs)
+	}
+
+	getReq, err := http.NewRequest(http.MethodGet, c.baseURL, nil)
+	if err != nil {
+		t.Fatalf("build get: %v", err)
+	}
+	getReq.Header.Set("Accept", "text/event-stream")
+	getReq.Header.Set("Authorization", "Bearer "+httpTestToken)
+	getReq.Header.Set(SessionIDHeader, c.session)
+	getResp, err := http.DefaultClient.Do(getReq)
+	if err != nil {
+		t.Fatalf("open event stream: %v", err)
+	}
+	defer getResp.Body.Close()
+	if getResp.StatusCode != http.StatusOK {
+		t.Fatalf("expected event stream, got %d", getResp.StatusCode)
+	}
+	serverRequests := make(chan protocolMessage, 4)
+	go readSSE(t, getResp.Body, func(msg protocolMessage) bool {
+		serverRequests <- msg
+		return true
+	})
+
+	c.notify(map[string]any{"jsonrpc": "2.0", "method": "notifications/initialized"})
+
+	var rootsReq protocolMessage
+	select {
+	case rootsReq = <-serverRequests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for roots/list on the event stream")
+	}
+	if rootsReq.Method != "roots/list" {
+		t.Fatalf("expected roots/list request, got %+v", rootsReq)
+	}
+	c.notify(map[string]any{"jsonrpc": "2.0", "id": json.RawMessage(rootsReq.ID), "result": map[string]any{"roots": []any{}}})
+
+	pingMsgs := c.request([]any{
+		map[string]any{"jsonrpc": "2.0", "id": "a", "method": "ping"},
+		map[string]any{"jsonrpc": "2.0", "id": "b", "method": "tools/list"},
+	})
+	if len(pingMsgs) != 2 {
+		t.Fatalf("expected responses for both batched requests, got %+v", pingMsgs)
+	}
+
+	delReq, err := http.NewRequest(http.MethodDelete, c.baseURL, nil)
+	if err != nil {
+		t.Fatalf("build delete: %v", err)
+	}
+	delReq.Header.Set("Authorization", "Bearer "+httpTestToken)
+	delReq.Header.Set(SessionIDHeader, c.session)
+	delResp, err := http.DefaultClient.Do(delReq)
+	if err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	delResp.Body.Close()
+	if delResp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 on delete, got %d", delResp.StatusCode)
+	}
+	resp := c.post(map[string]any{"jsonrpc": "2.0", "id": 9, "method": "ping"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 after session delete, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPHandler_RejectsMissingSessionTokenAndForeignOrigin(t *testing.T) {
+	c := newHTTPTestServer(t)
+
+	resp := c.post(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "ping"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without session, got %d", resp.StatusCode)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post without token: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", resp.StatusCode)
+	}
+
+	req, err = http.NewRequest(http.MethodPost, c.baseURL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+httpTestToken)
+	req.Header.Set("Origin", "https://evil.example")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post with foreign origin: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for foreign origin, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPHandler_ExpiresIdleSessions(t *testing.T) {
+	c := newHTTPTestServer(t)
+	c.request(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{}})
+	if c.session == "" {
+		t.Fatalf("expected %s header on initialize response", SessionIDHeader)
+	}
+
+	c.handler.expireIdle(time.Now())
+	if msgs := c.request(map[string]any{"jsonrpc": "2.0", "id": 2, "method": "ping"}); len(msgs) != 1 {
+		t.Fatalf("expected a recently used session to survive, got %+v", msgs)
+	}
+
+	c.handler.expireIdle(time.Now().Add(sessionIdleTimeout))
+	c.handler.mu.Lock()
+	remaining := len(c.handler.sessions)
+	c.handler.mu.Unlock()
+	if remaining != 0 {
+		t.Fatalf("expected idle session to be removed, %d left", remaining)
+	}
+	resp := c.post(map[string]any{"jsonrpc": "2.0", "id": 3, "method": "ping"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an expired session, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPHandler_CancelledRequestEndsItsPost(t *testing.T) {
+	started := make(chan struct{}, 1)
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(blocking.Close)
+	root := t.TempDir()
+	c := newHTTPTestServerWith(t, func() *Server { return NewWithClient(root, daemon.NewHTTPClient(blocking.URL, "")) })
+	c.request(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{}})
+
+	done := make(chan []protocolMessage, 1)
+	go func() {
+		done <- c.request(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      2,
+			"method":  "tools/call",
+			"params":  map[string]any{"name": toolArtifactGet, "arguments": map[string]any{"name": "big"}},
+		})
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the daemon call to start")
+	}
+
+	c.notify(map[string]any{"jsonrpc": "2.0", "method": "notifications/cancelled", "params": map[string]any{"requestId": 2}})
+	select {
+	case msgs := <-done:
+		if len(msgs) != 0 {
+			t.Fatalf("expected no response for the cancelled request, got %+v", msgs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("POST carrying the cancelled request did not return")
+	}
+}
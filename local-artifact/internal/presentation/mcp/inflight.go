@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+)
+
+const maxConcurrentRequests = 8
+
+var errRequestCancelled = errors.New("request cancelled by client")
+
+type cancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
+// trackRequest derives the context a request runs under. Requests with an id
+// can be cancelled by the client until release is called.
+func (s *Server) trackRequest(ctx context.Context, id json.RawMessage) (context.Context, func()) {
+	reqCtx, cancel := context.WithCancelCause(ctx)
+	if len(id) == 0 {
+		return reqCtx, func() { cancel(nil) }
+	}
+	key := jsonRPCIDKey(id)
+	s.inflightMu.Lock()
+	s.inflight[key] = cancel
+	s.inflightMu.Unlock()
+	return reqCtx, func() {
+		s.inflightMu.Lock()
+		delete(s.inflight, key)
+		s.inflightMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancelInFlight handles notifications/cancelled. Unknown or finished ids are
+// ignored, as the spec allows the notification to race the response.
+func (s *Server) cancelInFlight(params json.RawMessage) {
+	var p cancelledParams
+	if err := json.Unmarshal(params, &p); err != nil || len(p.RequestID) == 0 {
+		return
+	}
+	key := jsonRPCIDKey(p.RequestID)
+	s.inflightMu.Lock()
+	cancel, ok := s.inflight[key]
+	s.inflightMu.Unlock()
+	if !ok {
+		return
+	}
+	cancel(errRequestCancelled)
+	log.Printf("event=request_cancelled id=%s reason=%q", key, p.Reason)
+	if s.onCancelled != nil {
+		s.onCancelled(key)
+	}
+}
+
+// respond writes the response for msg unless it is a notification or the
+// client cancelled it; cancelled requests get no response.
+func (s *Server) respond(ctx context.Context, msg jsonRPCRequest, result any, rpcErr *jsonRPCError) {
+	if len(msg.ID) == 0 || errors.Is(context.Cause(ctx), errRequestCancelled) {
+		return
+	}
+	s.writeResponseAndLog(msg.Method, msg.ID, result, rpcErr)
+}
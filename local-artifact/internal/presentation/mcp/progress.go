@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
+)
+
+const progressInterval = 100 * time.Millisecond
+
+// toolProgressPhases names the daemon transfer that dominates each tool worth
+// reporting progress for.
+var toolProgressPhases = map[string]string{
+	toolArtifactSaveText:  daemon.TransferUpload,
+	toolArtifactSaveBlob:  daemon.TransferUpload,
+	toolArtifactBatch:     daemon.TransferUpload,
+	toolArtifactSaveJSON:  daemon.TransferUpload,
+	toolArtifactPatchJSON: daemon.TransferUpload,
+	toolArtifactGet:       daemon.TransferDownload,
+}
+
+var progressMessages = map[string]string{
+	daemon.TransferUpload:   "saving artifact",
+	daemon.TransferDownload: "reading artifact",
+}
+
+type requestMeta struct {
+	ProgressToken json.RawMessage `json:"progressToken,omitempty"`
+}
+
+type progressReporter struct {
+	server *Server
+	token  json.RawMessage
+	phase  string
+
+	mu   sync.Mutex
+	sent int64
+	last time.Time
+}
+
+// withProgress makes daemon calls under ctx emit notifications/progress for
+// token, at most once per progressInterval plus a final one.
+func (s *Server) withProgress(ctx context.Context, token json.RawMessage, phase string) context.Context {
+	r := &progressReporter{server: s, token: token, phase: phase}
+	return daemon.WithTransferProgress(ctx, r.report)
+}
+
+func (r *progressReporter) report(phase string, done, total int64) {
+	if phase != r.phase {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	final := total > 0 && done >= total
+	if done <= r.sent || (!final && time.Since(r.last) < progressInterval) {
+		return
+	}
+	r.sent = done
+	r.last = time.Now()
+
+	params := map[string]any{
+		"progressToken": r.token,
+		"progress":      done,
+		"message":       progressMessages[phase],
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+	if err := r.server.writeJSON(map[string]any{"jsonrpc": "2.0", "method": "notifications/progress", "params": params}); err != nil {
+		log.Printf("event=write_progress_failed error=%q", err.Error())
+	}
+}
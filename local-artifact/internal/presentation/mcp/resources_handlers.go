@@ -49,7 +49,7 @@ func (s *Server) handleResourcesRead(ctx context.Context, params json.RawMessage
 		return resourceReadErrorResult("", "invalid params: uri is required"), nil
 	}
 
-	sel, err := selectorFromURI(uri)
+	sel, source, err := selectorFromURI(uri)
 	if err != nil {
 		if isRecoverableReadErr(err) {
 			return resourceReadErrorResult(uri, resourceReadErrorMessage(err)), nil
@@ -57,7 +57,7 @@ func (s *Server) handleResourcesRead(ctx context.Context, params json.RawMessage
 		return nil, rpcErrorFromErr(err)
 	}
 
-	got, err := s.daemon().Get(ctx, daemon.GetRequest{Workspace: s.currentWorkspace(ctx), Selector: daemon.Selector{Ref: sel.Ref, Name: sel.Name}})
+	got, err := s.daemon().Get(ctx, daemon.GetRequest{Workspace: s.currentWorkspace(ctx), Source: source, Selector: daemon.Selector{Ref: sel.Ref, Name: sel.Name}})
 	if err != nil {
 		if isRecoverableReadErr(err) {
 			return resourceReadErrorResult(uri, resourceReadErrorMessage(err)), nil
@@ -104,34 +104,52 @@ func resourceReadErrorResult(uri, msg string) map[string]any {
 	}
 }
 
-func selectorFromURI(raw string) (artifacts.Selector, error) {
+// selectorFromURI parses artifact://ref/<ref>, artifact://name/<name> and the
+// workspace-qualified artifact://ws/<workspace>/ref/<ref> and
+// artifact://ws/<workspace>/name/<name>. The workspace ("global", an ID or an
+// alias) is returned separately and is empty for unqualified URIs.
+func selectorFromURI(raw string) (artifacts.Selector, string, error) {
 	u, err := url.Parse(raw)
 	if err != nil {
-		return artifacts.Selector{}, fmt.Errorf("%w: invalid uri", artifacts.ErrInvalidInput)
+		return artifacts.Selector{}, "", fmt.Errorf("%w: invalid uri", artifacts.ErrInvalidInput)
 	}
 	if u.Scheme != "artifact" {
-		return artifacts.Selector{}, fmt.Errorf("%w: unsupported uri scheme %q", artifacts.ErrUnsupportedURI, u.Scheme)
+		return artifacts.Selector{}, "", fmt.Errorf("%w: unsupported uri scheme %q", artifacts.ErrUnsupportedURI, u.Scheme)
 	}
 
 	kind := u.Host
 	val := strings.TrimPrefix(u.Path, "/")
+	workspace := ""
+	if kind == "ws" {
+		parts := strings.SplitN(strings.TrimPrefix(u.EscapedPath(), "/"), "/", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return artifacts.Selector{}, "", fmt.Errorf("%w: ws uri must be artifact://ws/<workspace>/name/<name> or artifact://ws/<workspace>/ref/<ref>", artifacts.ErrInvalidInput)
+		}
+		workspace, kind = parts[0], parts[1]
+		if val, err = url.PathUnescape(parts[2]); err != nil {
+			return artifacts.Selector{}, "", fmt.Errorf("%w: invalid ws uri encoding", artifacts.ErrInvalidInput)
+		}
+	}
 	switch kind {
 	case "ref":
 		if val == "" {
-			return artifacts.Selector{}, fmt.Errorf("%w: ref uri missing value", artifacts.ErrInvalidInput)
+			return artifacts.Selector{}, "", fmt.Errorf("%w: ref uri missing value", artifacts.ErrInvalidInput)
 		}
-		return artifacts.Selector{Ref: val}, nil
+		return artifacts.Selector{Ref: val}, workspace, nil
 	case "name":
 		if val == "" {
-			return artifacts.Selector{}, fmt.Errorf("%w: name uri missing value", artifacts.ErrInvalidInput)
+			return artifacts.Selector{}, "", fmt.Errorf("%w: name uri missing value", artifacts.ErrInvalidInput)
+		}
+		if workspace != "" {
+			return artifacts.Selector{Name: val}, workspace, nil
 		}
 		name, err := url.PathUnescape(val)
 		if err != nil {
-			return artifacts.Selector{}, fmt.Errorf("%w: invalid name uri encoding", artifacts.ErrInvalidInput)
+			return artifacts.Selector{}, "", fmt.Errorf("%w: invalid name uri encoding", artifacts.ErrInvalidInput)
 		}
-		return artifacts.Selector{Name: name}, nil
+		return artifacts.Selector{Name: name}, "", nil
 	default:
-		return artifacts.Selector{}, fmt.Errorf("%w: unsupported artifact uri host %q", artifacts.ErrUnsupportedURI, kind)
+		return artifacts.Selector{}, "", fmt.Errorf("%w: unsupported artifact uri host %q", artifacts.ErrUnsupportedURI, kind)
 	}
 }
 
@@ -147,10 +165,11 @@ func rpcErrorFromErr(err error) *jsonRPCError {
 func isRecoverableReadErr(err error) bool {
 	var remoteErr *daemon.RemoteError
 	if errors.As(err, &remoteErr) {
-		return remoteErr.Code == daemon.CodeNotFound || remoteErr.Code == daemon.CodeInvalidInput
+		return remoteErr.Code == daemon.CodeNotFound || remoteErr.Code == daemon.CodeInvalidInput || remoteErr.Code == daemon.CodeForbidden
 	}
 
 	return errors.Is(err, artifacts.ErrNotFound) ||
+		errors.Is(err, artifacts.ErrNotShared) ||
 		errors.Is(err, artifacts.ErrInvalidInput) ||
 		errors.Is(err, artifacts.ErrNameRequired) ||
 		errors.Is(err, artifacts.ErrRefRequired) ||
@@ -18,6 +18,8 @@ type Server struct {
 	baseStoreRoot       string
 	daemonClient        *daemon.Client
 	workspaceOverrideID string
+	// readMaxBytes caps get_artifact data when the call sets no maxBytes.
+	readMaxBytes int64
 
 	sessionMu sync.RWMutex
 	workspace daemon.WorkspaceSelector
@@ -34,6 +36,12 @@ type Server struct {
 	pendingMu sync.Mutex
 	pending   map[string]chan jsonRPCResponse
 	requestID int64
+
+	inflightMu sync.Mutex
+	inflight   map[string]context.CancelCauseFunc
+	// onCancelled, when set, learns the id of each request the client
+	// cancelled, since no response will be written for it.
+	onCancelled func(id string)
 }
 
 func New(baseStoreRoot string) *Server {
@@ -71,9 +79,14 @@ func NewWithClient(baseStoreRoot string, daemonClient *daemon.Client) *Server {
 		workspace:           workspace,
 		sessionResolved:     sessionResolved,
 		pending:             map[string]chan jsonRPCResponse{},
+		inflight:            map[string]context.CancelCauseFunc{},
 	}
 }
 
+func (s *Server) SetReadMaxBytes(n int64) {
+	s.readMaxBytes = n
+}
+
 func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
 	enc := json.NewEncoder(out)
 	enc.SetEscapeHTML(false)
@@ -83,7 +96,18 @@ func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
 	reqCh := make(chan jsonRPCRequest, 16)
 	errCh := make(chan error, 1)
 	go s.readLoop(ctx, r, reqCh, errCh)
+	return s.dispatch(ctx, reqCh, errCh)
+}
 
+// dispatch handles queued requests until ctx ends or the transport reports
+// on errCh. Up to maxConcurrentRequests run at once, each with its own
+// context so notifications/cancelled can stop it. Request contexts derive
+// from ctx, and dispatch waits for every request before returning so none
+// writes to the transport after it is gone.
+func (s *Server) dispatch(ctx context.Context, reqCh <-chan jsonRPCRequest, errCh <-chan error) error {
+	slots := make(chan struct{}, maxConcurrentRequests)
+	var wg sync.WaitGroup
+	defer wg.Wait()
 	for {
 		select {
 		case <-ctx.Done():
@@ -94,7 +118,27 @@ func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
 			}
 			return err
 		case msg := <-reqCh:
-			s.handleRequest(ctx, msg)
+			switch msg.Method {
+			case "initialize":
+				// Capabilities must be in place before any later request runs.
+				s.handleRequest(ctx, msg)
+				continue
+			case "notifications/initialized":
+				s.setInitialized(true)
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			reqCtx, release := s.trackRequest(ctx, msg.ID)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				defer release()
+				s.handleRequest(reqCtx, msg)
+			}()
 		}
 	}
 }
@@ -150,6 +194,12 @@ func (s *Server) handleIncomingLine(ctx context.Context, line []byte, reqCh chan
 		log.Printf("event=incoming_unmarshal_failed stage=request error=%q", err.Error())
 		return
 	}
+	// Cancellation skips the queue so it reaches requests that are holding
+	// every dispatch slot.
+	if msg.Method == "notifications/cancelled" {
+		s.cancelInFlight(msg.Params)
+		return
+	}
 
 	select {
 	case <-ctx.Done():
@@ -159,56 +209,34 @@ func (s *Server) handleIncomingLine(ctx context.Context, line []byte, reqCh chan
 }
 
 func (s *Server) handleRequest(ctx context.Context, msg jsonRPCRequest) {
-	isNotification := len(msg.ID) == 0
-
 	switch msg.Method {
 	case "initialize":
 		res, rpcErr := s.handleInitialize(msg.Params)
-		if !isNotification {
-			s.writeResponseAndLog(msg.Method, msg.ID, res, rpcErr)
-		}
+		s.respond(ctx, msg, res, rpcErr)
 	case "notifications/initialized":
 		s.setInitialized(true)
 		s.resolveSessionStore(ctx, false)
 	case "notifications/roots/list_changed":
 		s.resolveSessionStore(ctx, true)
 	case "ping":
-		if !isNotification {
-			s.writeResponseAndLog(msg.Method, msg.ID, map[string]any{}, nil)
-		}
+		s.respond(ctx, msg, map[string]any{}, nil)
 	case "tools/list":
 		res, rpcErr := s.handleToolsList(msg.Params)
-		if !isNotification {
-			s.writeResponseAndLog(msg.Method, msg.ID, res, rpcErr)
-		}
+		s.respond(ctx, msg, res, rpcErr)
 	case "tools/call":
 		res, rpcErr := s.handleToolsCall(ctx, msg.Params)
-		if !isNotification {
-			s.writeResponseAndLog(msg.Method, msg.ID, res, rpcErr)
-		}
+		s.respond(ctx, msg, res, rpcErr)
 	case "resources/list":
 		res, rpcErr := s.handleResourcesList(ctx, msg.Params)
-		if !isNotification {
-			s.writeResponseAndLog(msg.Method, msg.ID, res, rpcErr)
-		}
+		s.respond(ctx, msg, res, rpcErr)
 	case "resources/read":
 		res, rpcErr := s.handleResourcesRead(ctx, msg.Params)
-		if !isNotification {
-			s.writeResponseAndLog(msg.Method, msg.ID, res, rpcErr)
-		}
+		s.respond(ctx, msg, res, rpcErr)
 	case "resources/templates/list":
-		res := map[string]any{"resourceTemplates": []any{}}
-		if !isNotification {
-			s.writeResponseAndLog(msg.Method, msg.ID, res, nil)
-		}
+		s.respond(ctx, msg, map[string]any{"resourceTemplates": []any{}}, nil)
 	case "prompts/list":
-		res := map[string]any{"prompts": []any{}}
-		if !isNotification {
-			s.writeResponseAndLog(msg.Method, msg.ID, res, nil)
-		}
+		s.respond(ctx, msg, map[string]any{"prompts": []any{}}, nil)
 	default:
-		if !isNotification {
-			s.writeResponseAndLog(msg.Method, msg.ID, nil, &jsonRPCError{Code: -32601, Message: "Method not found"})
-		}
+		s.respond(ctx, msg, nil, &jsonRPCError{Code: -32601, Message: "Method not found"})
 	}
 }
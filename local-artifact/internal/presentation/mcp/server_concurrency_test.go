@@ -0,0 +1,166 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	daemonapi "github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
+)
+
+func TestServe_PingAnsweredWhileToolCallBlocksAndCancelStopsIt(t *testing.T) {
+	started := make(chan struct{}, 1)
+	aborted := make(chan struct{}, 1)
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+		aborted <- struct{}{}
+	}))
+	t.Cleanup(blocking.Close)
+
+	h := newProtocolHarnessWithServer(t, NewWithClient(t.TempDir(), daemonapi.NewHTTPClient(blocking.URL, "")))
+	h.send(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": toolArtifactGet, "arguments": map[string]any{"name": "big"}},
+	})
+	select {
+	case <-started:
+	case <-time.After(protocolRecvTimeout):
+		t.Fatal("timed out waiting for the daemon call to start")
+	}
+
+	h.send(map[string]any{"jsonrpc": "2.0", "id": 2, "method": "ping"})
+	if msg := h.recv(); string(msg.ID) != "2" {
+		t.Fatalf("expected ping response while get_artifact is in flight, got %+v", msg)
+	}
+
+	h.send(map[string]any{"jsonrpc": "2.0", "method": "notifications/cancelled", "params": map[string]any{"requestId": 1, "reason": "user abort"}})
+	select {
+	case <-aborted:
+	case <-time.After(protocolRecvTimeout):
+		t.Fatal("expected cancellation to abort the in-flight daemon call")
+	}
+
+	h.send(map[string]any{"jsonrpc": "2.0", "id": 3, "method": "ping"})
+	if msg := h.recv(); string(msg.ID) != "3" {
+		t.Fatalf("expected no response for the cancelled request, got %+v", msg)
+	}
+}
+
+func TestServe_SaveWithProgressTokenEmitsProgressNotifications(t *testing.T) {
+	h := newProtocolHarness(t, t.TempDir())
+	data := strings.Repeat("x", 512<<10)
+	h.send(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      7,
+		"method":  "tools/call",
+		"params": map[string]any{
+			"name":      toolArtifactSaveBlob,
+			"arguments": map[string]any{"name": "plan/big", "dataBase64": base64.StdEncoding.EncodeToString([]byte(data)), "mimeType": "text/plain"},
+			"_meta":     map[string]any{"progressToken": "save-1"},
+		},
+	})
+
+	type progressParams struct {
+		ProgressToken string `json:"progressToken"`
+		Progress      int64  `json:"progress"`
+		Total         int64  `json:"total"`
+	}
+	var updates []progressParams
+	for {
+		msg := h.recv()
+		if msg.Method == "notifications/progress" {
+			var p progressParams
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				t.Fatalf("decode progress params: %v", err)
+			}
+			updates = append(updates, p)
+			continue
+		}
+		if string(msg.ID) != "7" || msg.Error != nil {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+		break
+	}
+
+	if len(updates) == 0 {
+		t.Fatal("expected at least one progress notification")
+	}
+	for i, p := range updates {
+		if p.ProgressToken != "save-1" {
+			t.Fatalf("unexpected progress token: %+v", p)
+		}
+		if i > 0 && p.Progress <= updates[i-1].Progress {
+			t.Fatalf("progress must increase: %+v", updates)
+		}
+	}
+	if last := updates[len(updates)-1]; last.Total == 0 || last.Progress != last.Total {
+		t.Fatalf("expected final progress to reach total, got %+v", last)
+	}
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServe_WaitsForInFlightRequestsWhenContextEnds(t *testing.T) {
+	started := make(chan struct{}, 1)
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(blocking.Close)
+
+	s := NewWithClient(t.TempDir(), daemonapi.NewHTTPClient(blocking.URL, ""))
+	inR, inW := io.Pipe()
+	t.Cleanup(func() { _ = inW.Close() })
+	out := &lockedBuffer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- s.Serve(ctx, inR, out) }()
+
+	if _, err := io.WriteString(inW, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+toolArtifactGet+`","arguments":{"name":"big"}}}`+"\n"); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(protocolRecvTimeout):
+		t.Fatal("timed out waiting for the daemon call to start")
+	}
+
+	cancel()
+	select {
+	case <-serveErr:
+	case <-time.After(protocolRecvTimeout):
+		t.Fatal("Serve did not return after its context ended")
+	}
+	// The request's handler has finished, so its response is already out.
+	if got := out.String(); !strings.Contains(got, `"id":1`) {
+		t.Fatalf("expected the in-flight request to finish before Serve returned, got %q", got)
+	}
+}
@@ -58,7 +58,10 @@ func TestServerSaveResolveListAndGetPrevRefVersioning(t *testing.T) {
 	if oldGetResp.IsError {
 		t.Fatalf("get old by ref unexpectedly returned tool error: %+v", oldGetResp)
 	}
-	oldGetOut := requireSaveOut(t, oldGetResp.StructuredContent)
+	oldGetOut, ok := oldGetResp.StructuredContent.(getOut)
+	if !ok {
+		t.Fatalf("expected getOut, got %T", oldGetResp.StructuredContent)
+	}
 	if oldGetOut.Ref != firstOut.Ref {
 		t.Fatalf("expected old get ref=%q, got %q", firstOut.Ref, oldGetOut.Ref)
 	}
@@ -124,3 +127,133 @@ func mustRawJSON(t *testing.T, value any) json.RawMessage {
 	}
 	return b
 }
+
+func TestServerBatchArtifacts_AppliesAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	s := newDaemonBackedServer(t)
+
+	respAny, rpcErr := s.handleToolsCall(ctx, mustRawJSON(t, map[string]any{
+		"name": toolArtifactBatch,
+		"arguments": map[string]any{"ops": []map[string]any{
+			{"op": "put", "name": "plan/current", "text": "plan"},
+			{"op": "put", "name": "plan/summary", "text": "summary", "ttl": "24h"},
+		}},
+	}))
+	if rpcErr != nil {
+		t.Fatalf("batch rpc error: %+v", rpcErr)
+	}
+	resp := requireToolOK(t, requireToolResult(t, respAny))
+	out := requireMap(t, resp.StructuredContent, "batch structured content")
+	items, ok := out["artifacts"].([]batchItemOut)
+	if !ok || len(items) != 2 || items[1].ExpiresAt == "" {
+		t.Fatalf("unexpected batch artifacts: %#v", out["artifacts"])
+	}
+
+	respAny, rpcErr = s.handleToolsCall(ctx, mustRawJSON(t, map[string]any{
+		"name": toolArtifactBatch,
+		"arguments": map[string]any{"ops": []map[string]any{
+			{"op": "delete", "name": "plan/summary"},
+			{"op": "put", "name": "plan/current", "text": "stale", "expectedPrevRef": items[1].Ref},
+		}},
+	}))
+	if rpcErr != nil {
+		t.Fatalf("conflicting batch rpc error: %+v", rpcErr)
+	}
+	requireContentTextContains(t, requireToolErr(t, requireToolResult(t, respAny)), "batch write 1")
+
+	resolveAny, rpcErr := s.toolResolve(ctx, mustRawJSON(t, map[string]any{"name": "plan/summary"}))
+	if rpcErr != nil {
+		t.Fatalf("resolve rpc error: %+v", rpcErr)
+	}
+	requireToolOK(t, requireToolResult(t, resolveAny))
+}
+
+func TestServerJSONArtifacts_PatchAndPointerRead(t *testing.T) {
+	ctx := context.Background()
+	s := newDaemonBackedServer(t)
+
+	respAny, rpcErr := s.handleToolsCall(ctx, mustRawJSON(t, map[string]any{
+		"name":      toolArtifactSaveJSON,
+		"arguments": map[string]any{"name": "review/r1", "json": map[string]any{"verdict": "pending", "findings": []any{}}},
+	}))
+	if rpcErr != nil {
+		t.Fatalf("save json rpc error: %+v", rpcErr)
+	}
+	first := requireSaveOut(t, requireToolOK(t, requireToolResult(t, respAny)).StructuredContent)
+	if first.MimeType != "application/json" {
+		t.Fatalf("unexpected mime type %q", first.MimeType)
+	}
+
+	respAny, rpcErr = s.handleToolsCall(ctx, mustRawJSON(t, map[string]any{
+		"name": toolArtifactPatchJSON,
+		"arguments": map[string]any{
+			"name":            "review/r1",
+			"patch":           []map[string]any{{"op": "add", "path": "/findings/-", "value": map[string]any{"file": "a.go"}}},
+			"expectedPrevRef": first.Ref,
+		},
+	}))
+	if rpcErr != nil {
+		t.Fatalf("patch json rpc error: %+v", rpcErr)
+	}
+	patched := requireSaveOut(t, requireToolOK(t, requireToolResult(t, respAny)).StructuredContent)
+	if patched.PrevRef != first.Ref {
+		t.Fatalf("expected patch to chain from %s, got %+v", first.Ref, patched)
+	}
+
+	respAny, rpcErr = s.handleToolsCall(ctx, mustRawJSON(t, map[string]any{
+		"name":      toolArtifactPatchJSON,
+		"arguments": map[string]any{"name": "review/r1", "patch": map[string]any{"verdict": "approved"}, "format": "merge-patch", "expectedPrevRef": first.Ref},
+	}))
+	if rpcErr != nil {
+		t.Fatalf("stale patch rpc error: %+v", rpcErr)
+	}
+	requireToolErr(t, requireToolResult(t, respAny))
+
+	respAny, rpcErr = s.handleToolsCall(ctx, mustRawJSON(t, map[string]any{
+		"name":      toolArtifactGet,
+		"arguments": map[string]any{"name": "review/r1", "pointer": "/findings/0/file"},
+	}))
+	if rpcErr != nil {
+		t.Fatalf("get pointer rpc error: %+v", rpcErr)
+	}
+	requireContentTextContains(t, requireToolOK(t, requireToolResult(t, respAny)), `"a.go"`)
+}
+
+func TestServerGetArtifact_CapsAndPagesLargeText(t *testing.T) {
+	ctx := context.Background()
+	s := newDaemonBackedServer(t)
+	s.SetReadMaxBytes(8)
+
+	if _, rpcErr := s.toolSaveText(ctx, mustRawJSON(t, map[string]any{"name": "log/build", "text": "line 1\nline 2\nline 3\n"})); rpcErr != nil {
+		t.Fatalf("save rpc error: %+v", rpcErr)
+	}
+
+	respAny, rpcErr := s.toolGet(ctx, mustRawJSON(t, map[string]any{"name": "log/build"}))
+	if rpcErr != nil {
+		t.Fatalf("get rpc error: %+v", rpcErr)
+	}
+	resp := requireToolOK(t, requireToolResult(t, respAny))
+	out, ok := resp.StructuredContent.(getOut)
+	if !ok || !out.Truncated || out.TotalBytes != 21 || out.TotalLines != 3 {
+		t.Fatalf("unexpected capped get output: %#v", resp.StructuredContent)
+	}
+	requireContentTextContains(t, resp, "continue with offset=8")
+
+	respAny, rpcErr = s.toolGet(ctx, mustRawJSON(t, map[string]any{"name": "log/build", "tail": 1}))
+	if rpcErr != nil {
+		t.Fatalf("tail rpc error: %+v", rpcErr)
+	}
+	resp = requireToolOK(t, requireToolResult(t, respAny))
+	if out, ok := resp.StructuredContent.(getOut); !ok || out.Truncated || out.Offset != 14 {
+		t.Fatalf("unexpected tail output: %#v", resp.StructuredContent)
+	}
+	requireContentTextContains(t, resp, "line 3")
+
+	respAny, rpcErr = s.toolGet(ctx, mustRawJSON(t, map[string]any{"name": "log/build", "maxBytes": 0}))
+	if rpcErr != nil {
+		t.Fatalf("uncapped rpc error: %+v", rpcErr)
+	}
+	if out, ok := requireToolOK(t, requireToolResult(t, respAny)).StructuredContent.(getOut); !ok || out.Truncated {
+		t.Fatalf("expected maxBytes=0 to lift the cap: %#v", out)
+	}
+}
@@ -45,6 +45,11 @@ const (
 
 func newProtocolHarness(t *testing.T, baseStoreRoot string) *protocolHarness {
 	t.Helper()
+	return newProtocolHarnessWithServer(t, newDaemonBackedServerAtRoot(t, baseStoreRoot))
+}
+
+func newProtocolHarnessWithServer(t *testing.T, s *Server) *protocolHarness {
+	t.Helper()
 
 	serverInR, serverInW := io.Pipe()
 	serverOutR, serverOutW := io.Pipe()
@@ -60,7 +65,6 @@ func newProtocolHarness(t *testing.T, baseStoreRoot string) *protocolHarness {
 		cancel:    cancel,
 	}
 
-	s := newDaemonBackedServerAtRoot(t, baseStoreRoot)
 	go func() {
 		h.serveErr <- s.Serve(ctx, serverInR, serverOutW)
 	}()
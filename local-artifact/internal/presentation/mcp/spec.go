@@ -1,5 +1,10 @@
 package mcp
 
+import (
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
+)
+
 const ProtocolVersion = "2025-11-25"
 
 const (
@@ -7,17 +12,21 @@ const (
 	serverTitle        = "Local Artifact Store"
 	serverVersion      = "0.1.0"
 	serverDescription  = "Completely local MCP server that lets agents save and retrieve named artifacts (text, files, images)."
-	serverInstructions = "Use save_artifact_text or save_artifact_blob to persist an artifact under a name. Re-saving the same name creates a new ref linked by prevRef and moves the name to the latest ref. Use get_artifact with name or ref to retrieve, delete_artifact to remove an artifact, and get_artifact_list to inspect current aliases. Use todo to read or write a deterministic <artifact>/todo list with optional expectedPrevRef conflict protection."
+	serverInstructions = "Use save_artifact_text or save_artifact_blob to persist an artifact under a name. Re-saving the same name creates a new ref linked by prevRef and moves the name to the latest ref. Use get_artifact with name or ref to retrieve, delete_artifact to remove an artifact, and get_artifact_list to inspect current aliases. Use todo to read or write a deterministic <artifact>/todo list with optional expectedPrevRef conflict protection. Use save_artifact_json for structured results and patch_artifact_json to update them in place; get_artifact with pointer reads part of a JSON artifact. get_artifact returns at most maxBytes of data; page through large artifacts with offset, startLine/endLine, head or tail. Use batch_artifacts to save and delete several artifacts atomically, for example a plan, its todo list and a summary. Use share_artifact to let other workspaces read an artifact; they fetch it with get_artifact uri=artifact://ws/<workspace>/name/<name>."
 )
 
 const (
-	toolArtifactSaveText = "save_artifact_text"
-	toolArtifactSaveBlob = "save_artifact_blob"
-	toolArtifactResolve  = "resolve_artifact"
-	toolArtifactGet      = "get_artifact"
-	toolArtifactList     = "get_artifact_list"
-	toolArtifactDelete   = "delete_artifact"
-	toolArtifactTodo     = "todo"
+	toolArtifactSaveText  = "save_artifact_text"
+	toolArtifactSaveBlob  = "save_artifact_blob"
+	toolArtifactResolve   = "resolve_artifact"
+	toolArtifactGet       = "get_artifact"
+	toolArtifactList      = "get_artifact_list"
+	toolArtifactDelete    = "delete_artifact"
+	toolArtifactTodo      = "todo"
+	toolArtifactShare     = "share_artifact"
+	toolArtifactBatch     = "batch_artifacts"
+	toolArtifactSaveJSON  = "save_artifact_json"
+	toolArtifactPatchJSON = "patch_artifact_json"
 )
 
 const (
@@ -67,9 +76,11 @@ func toolDefinitions() []toolDef {
 			Description: "Save UTF-8 text under a name and return a stable ref and artifact:// URIs.",
 			InputSchema: objectSchema(
 				map[string]any{
-					"name":     stringProp("Artifact name/alias (e.g. plan/task-123)."),
-					"text":     stringProp("Text content to save."),
-					"mimeType": stringProp("Optional MIME type. Defaults to text/plain; charset=utf-8."),
+					"name":      stringProp("Artifact name/alias (e.g. plan/task-123)."),
+					"text":      stringProp("Text content to save."),
+					"mimeType":  stringProp("Optional MIME type. Defaults to text/plain; charset=utf-8."),
+					"ttl":       ttlProp(),
+					"expiresAt": expiresAtProp(),
 				},
 				"name", "text",
 			),
@@ -86,12 +97,56 @@ func toolDefinitions() []toolDef {
 					"dataBase64": stringProp("Base64-encoded bytes."),
 					"mimeType":   stringProp("MIME type (e.g., image/png, application/pdf, text/markdown)."),
 					"filename":   stringProp("Optional original filename."),
+					"ttl":        ttlProp(),
+					"expiresAt":  expiresAtProp(),
 				},
 				"name", "dataBase64", "mimeType",
 			),
 			OutputSchema: saveOutputSchema(),
 			Annotations:  readOnlyHint(false),
 		},
+		{
+			Name:        toolArtifactSaveJSON,
+			Title:       "Save JSON artifact",
+			Description: "Save a JSON document as application/json. Names with a registered JSON Schema are rejected unless the document matches it.",
+			InputSchema: objectSchema(
+				map[string]any{
+					"name":            stringProp("Artifact name/alias (e.g. review/task-123)."),
+					"json":            map[string]any{"description": "JSON document to save."},
+					"expectedPrevRef": stringProp("Optional stale-write guard. Must match the latest ref of the name."),
+					"ttl":             ttlProp(),
+					"expiresAt":       expiresAtProp(),
+				},
+				"name", "json",
+			),
+			OutputSchema: saveOutputSchema(),
+			Annotations:  readOnlyHint(false),
+		},
+		{
+			Name:        toolArtifactPatchJSON,
+			Title:       "Patch JSON artifact",
+			Description: "Apply an RFC 6902 JSON Patch or an RFC 7386 merge patch to the latest version of a JSON artifact and save the result as a new version. Concurrent writes are never lost: with expectedPrevRef a race fails with a conflict, without it the patch is re-applied to the newer version.",
+			InputSchema: objectSchema(
+				map[string]any{
+					"name": stringProp("Artifact name/alias."),
+					"patch": map[string]any{
+						"type":        []string{"array", "object"},
+						"description": "JSON Patch operations such as [{\"op\":\"replace\",\"path\":\"/status\",\"value\":\"done\"}], or a merge patch object.",
+					},
+					"format": map[string]any{
+						"type":        "string",
+						"enum":        []string{daemon.PatchFormatJSONPatch, daemon.PatchFormatMergePatch},
+						"description": "Defaults to json-patch.",
+					},
+					"expectedPrevRef": stringProp("Optional stale-write guard. Must match the latest ref of the name."),
+					"ttl":             ttlProp(),
+					"expiresAt":       expiresAtProp(),
+				},
+				"name", "patch",
+			),
+			OutputSchema: saveOutputSchema(),
+			Annotations:  readOnlyHint(false),
+		},
 		{
 			Name:        toolArtifactResolve,
 			Title:       "Resolve name to ref",
@@ -108,11 +163,20 @@ func toolDefinitions() []toolDef {
 		{
 			Name:        toolArtifactGet,
 			Title:       "Get artifact",
-			Description: "Fetch an artifact by ref or name. For binary, returns embedded resource (base64) unless mode=image.",
+			Description: "Fetch an artifact by ref, name or artifact:// uri. A uri of the form artifact://ws/<workspace>/name/<name> reads an artifact another workspace has shared. For binary, returns embedded resource (base64) unless mode=image. Data is capped at maxBytes (a server default applies); totalBytes, totalLines and truncated in the result show what is left, so large artifacts can be paged with offset/length, startLine/endLine, head or tail.",
 			InputSchema: objectSchema(
 				map[string]any{
-					"ref":  map[string]any{"type": "string"},
-					"name": map[string]any{"type": "string"},
+					"ref":       map[string]any{"type": "string"},
+					"name":      map[string]any{"type": "string"},
+					"uri":       stringProp("artifact:// URI; exclusive with ref and name."),
+					"pointer":   stringProp("Optional RFC 6901 JSON pointer such as /findings/0 to return only that part of a JSON artifact."),
+					"offset":    integerProp("Byte offset to start reading at."),
+					"length":    integerProp("Number of bytes to read from offset; reads to the end when omitted."),
+					"startLine": integerProp("First line to read, 1-based; exclusive with offset/length, head and tail."),
+					"endLine":   integerProp("Last line to read, inclusive; reads to the last line when omitted."),
+					"head":      integerProp("Read only the first N lines."),
+					"tail":      integerProp("Read only the last N lines."),
+					"maxBytes":  integerProp("Cap on returned bytes, overriding the server default; 0 for no cap."),
 					"mode": map[string]any{
 						"type":        "string",
 						"enum":        []string{modeAuto, modeText, modeResource, modeImage, modeMeta},
@@ -157,6 +221,55 @@ func toolDefinitions() []toolDef {
 			OutputSchema: deleteOutputSchema(),
 			Annotations:  readOnlyHint(false),
 		},
+		{
+			Name:        toolArtifactBatch,
+			Title:       "Batch artifact writes",
+			Description: "Apply several puts and deletes in order as one transaction: either all of them take effect or none do. A failed op reports its index as \"batch write <n>\".",
+			InputSchema: objectSchema(
+				map[string]any{
+					"ops": map[string]any{
+						"type":     "array",
+						"minItems": 1,
+						"maxItems": artifacts.MaxBatchOps,
+						"items":    batchOpSchema(),
+					},
+				},
+				"ops",
+			),
+			OutputSchema: objectSchema(
+				map[string]any{
+					"artifacts": map[string]any{
+						"type":  "array",
+						"items": batchItemOutputSchema(),
+					},
+				},
+				"artifacts",
+			),
+			Annotations: readOnlyHint(false),
+		},
+		{
+			Name:        toolArtifactShare,
+			Title:       "Share artifact",
+			Description: "Let other workspaces read an artifact of this workspace by name, or revoke that. Returns the artifact://ws/ URI they read it with.",
+			InputSchema: objectSchema(
+				map[string]any{
+					"name":   stringProp("Artifact name/alias to share."),
+					"with":   stringProp("Workspace ID or alias to share with. Defaults to * (every workspace)."),
+					"revoke": map[string]any{"type": "boolean", "description": "Withdraw the share instead of granting it."},
+				},
+				"name",
+			),
+			OutputSchema: objectSchema(
+				map[string]any{
+					"name":    map[string]any{"type": "string"},
+					"with":    map[string]any{"type": "string"},
+					"uri":     map[string]any{"type": "string"},
+					"revoked": map[string]any{"type": "boolean"},
+				},
+				"name", "with", "uri",
+			),
+			Annotations: readOnlyHint(false),
+		},
 		{
 			Name:         toolArtifactTodo,
 			Title:        "Read/write TODO list",
@@ -190,6 +303,20 @@ func stringProp(description string) map[string]any {
 	return prop
 }
 
+func integerProp(description string) map[string]any {
+	return map[string]any{"type": "integer", "minimum": 0, "description": description}
+}
+
+func ttlProp() map[string]any {
+	return stringProp("Optional time to live such as 24h or 30m for scratch artifacts. The name is deleted once it expires, unless saved again. Overrides any default TTL for the name; exclusive with expiresAt.")
+}
+
+func expiresAtProp() map[string]any {
+	prop := stringProp("Optional RFC 3339 time at which the name is deleted, unless saved again.")
+	prop["format"] = "date-time"
+	return prop
+}
+
 func saveOutputSchema() map[string]any {
 	return objectSchema(
 		map[string]any{
@@ -201,11 +328,40 @@ func saveOutputSchema() map[string]any {
 			"uriByName": map[string]any{"type": "string"},
 			"uriByRef":  map[string]any{"type": "string"},
 			"prevRef":   map[string]any{"type": "string"},
+			"expiresAt": map[string]any{"type": "string"},
 		},
 		"name", "ref", "kind", "mimeType", "uriByName", "uriByRef",
 	)
 }
 
+func batchOpSchema() map[string]any {
+	return objectSchema(
+		map[string]any{
+			"op": map[string]any{
+				"type":        "string",
+				"enum":        []string{"put", "delete"},
+				"description": "put saves text, or dataBase64 with mimeType; delete removes name or ref.",
+			},
+			"name":            stringProp("Artifact name/alias."),
+			"ref":             stringProp("Artifact ref to delete; only for delete."),
+			"text":            stringProp("Text content to save."),
+			"dataBase64":      stringProp("Base64-encoded bytes to save instead of text."),
+			"mimeType":        stringProp("MIME type. Required with dataBase64; defaults to text/plain; charset=utf-8 for text."),
+			"filename":        stringProp("Optional original filename."),
+			"expectedPrevRef": stringProp("Optional stale-write guard. Must match the latest ref of the name, including writes earlier in the batch."),
+			"ttl":             ttlProp(),
+			"expiresAt":       expiresAtProp(),
+		},
+		"op",
+	)
+}
+
+func batchItemOutputSchema() map[string]any {
+	schema := saveOutputSchema()
+	schema["properties"].(map[string]any)["deleted"] = map[string]any{"type": "boolean"}
+	return schema
+}
+
 func resolveOutputSchema() map[string]any {
 	return objectSchema(
 		map[string]any{
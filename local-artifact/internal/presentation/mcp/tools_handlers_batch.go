@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
+)
+
+type batchArgs struct {
+	Ops []daemon.BatchOp `json:"ops"`
+}
+
+type batchItemOut struct {
+	saveOut
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+func (s *Server) toolBatch(ctx context.Context, argsRaw json.RawMessage) (any, *jsonRPCError) {
+	var args batchArgs
+	if err := json.Unmarshal(argsRaw, &args); err != nil {
+		return toolError("Invalid arguments: expected {ops: [{op, name?, ref?, text?, dataBase64?, mimeType?, filename?, expectedPrevRef?, ttl?, expiresAt?}]}"), nil
+	}
+
+	res, err := s.daemon().Batch(ctx, daemon.BatchRequest{
+		Workspace: s.currentWorkspace(ctx),
+		Ops:       args.Ops,
+	})
+	if err != nil {
+		return toolErrorFromErr(err), nil
+	}
+
+	items := make([]batchItemOut, 0, len(res.Artifacts))
+	content := []any{}
+	saved, deleted := 0, 0
+	for _, a := range res.Artifacts {
+		nameEsc := url.PathEscape(a.Name)
+		items = append(items, batchItemOut{saveOut: toSaveOut(a, nameEsc), Deleted: a.Tombstone})
+		if a.Tombstone {
+			deleted++
+			continue
+		}
+		saved++
+		content = append(content, resourceLink(a.Name, artifacts.URIByName(nameEsc), a.MimeType, a.SizeBytes))
+	}
+	content = append([]any{textContent(fmt.Sprintf("saved %d, deleted %d", saved, deleted))}, content...)
+
+	return toolResult{
+		Content:           content,
+		StructuredContent: map[string]any{"artifacts": items},
+	}, nil
+}
@@ -0,0 +1,80 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
+)
+
+type saveJSONArgs struct {
+	Name            string          `json:"name"`
+	JSON            json.RawMessage `json:"json"`
+	ExpectedPrevRef string          `json:"expectedPrevRef,omitempty"`
+	TTL             string          `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time      `json:"expiresAt,omitempty"`
+}
+
+type patchJSONArgs struct {
+	Name            string          `json:"name"`
+	Patch           json.RawMessage `json:"patch"`
+	Format          string          `json:"format,omitempty"`
+	ExpectedPrevRef string          `json:"expectedPrevRef,omitempty"`
+	TTL             string          `json:"ttl,omitempty"`
+	ExpiresAt       *time.Time      `json:"expiresAt,omitempty"`
+}
+
+func (s *Server) toolSaveJSON(ctx context.Context, argsRaw json.RawMessage) (any, *jsonRPCError) {
+	var args saveJSONArgs
+	if err := json.Unmarshal(argsRaw, &args); err != nil {
+		return toolError("Invalid arguments: expected {name, json, expectedPrevRef?, ttl?, expiresAt?}"), nil
+	}
+
+	a, err := s.daemon().SaveJSON(ctx, daemon.SaveJSONRequest{
+		Workspace:       s.currentWorkspace(ctx),
+		Name:            args.Name,
+		JSON:            args.JSON,
+		ExpectedPrevRef: args.ExpectedPrevRef,
+		TTL:             args.TTL,
+		ExpiresAt:       args.ExpiresAt,
+	})
+	if err != nil {
+		return toolErrorFromErr(err), nil
+	}
+	return savedJSONResult(a), nil
+}
+
+func (s *Server) toolPatchJSON(ctx context.Context, argsRaw json.RawMessage) (any, *jsonRPCError) {
+	var args patchJSONArgs
+	if err := json.Unmarshal(argsRaw, &args); err != nil {
+		return toolError("Invalid arguments: expected {name, patch, format?, expectedPrevRef?, ttl?, expiresAt?}"), nil
+	}
+
+	a, err := s.daemon().PatchJSON(ctx, daemon.PatchJSONRequest{
+		Workspace:       s.currentWorkspace(ctx),
+		Name:            args.Name,
+		Patch:           args.Patch,
+		Format:          args.Format,
+		ExpectedPrevRef: args.ExpectedPrevRef,
+		TTL:             args.TTL,
+		ExpiresAt:       args.ExpiresAt,
+	})
+	if err != nil {
+		return toolErrorFromErr(err), nil
+	}
+	return savedJSONResult(a), nil
+}
+
+func savedJSONResult(a artifacts.ArtifactVersion) toolResult {
+	nameEsc := url.PathEscape(a.Name)
+	return toolResult{
+		Content: []any{
+			textContent("saved"),
+			resourceLink(a.Name, artifacts.URIByName(nameEsc), a.MimeType, a.SizeBytes),
+		},
+		StructuredContent: toSaveOut(a, nameEsc),
+	}
+}
@@ -17,9 +17,29 @@ type resolveArgs struct {
 }
 
 type getArgs struct {
-	Ref  string `json:"ref,omitempty"`
-	Name string `json:"name,omitempty"`
-	Mode string `json:"mode,omitempty"`
+	Ref     string `json:"ref,omitempty"`
+	Name    string `json:"name,omitempty"`
+	URI     string `json:"uri,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	Pointer string `json:"pointer,omitempty"`
+
+	Offset    int64  `json:"offset,omitempty"`
+	Length    int64  `json:"length,omitempty"`
+	StartLine int    `json:"startLine,omitempty"`
+	EndLine   int    `json:"endLine,omitempty"`
+	Head      int    `json:"head,omitempty"`
+	Tail      int    `json:"tail,omitempty"`
+	MaxBytes  *int64 `json:"maxBytes,omitempty"`
+}
+
+// getOut is the artifact metadata plus where the returned data sits in the
+// whole body.
+type getOut struct {
+	saveOut
+	Offset     int64 `json:"offset,omitempty"`
+	TotalBytes int64 `json:"totalBytes"`
+	TotalLines int   `json:"totalLines"`
+	Truncated  bool  `json:"truncated,omitempty"`
 }
 
 type listArgs struct {
@@ -60,18 +80,45 @@ func (s *Server) toolResolve(ctx context.Context, argsRaw json.RawMessage) (any,
 func (s *Server) toolGet(ctx context.Context, argsRaw json.RawMessage) (any, *jsonRPCError) {
 	var args getArgs
 	if err := json.Unmarshal(argsRaw, &args); err != nil {
-		return toolError("Invalid arguments: expected {ref?, name?, mode?}"), nil
+		return toolError("Invalid arguments: expected {ref?, name?, uri?, mode?, pointer?, offset?, length?, startLine?, endLine?, head?, tail?, maxBytes?}"), nil
+	}
+
+	maxBytes := s.readMaxBytes
+	if args.MaxBytes != nil {
+		maxBytes = *args.MaxBytes
+	}
+
+	sel := daemon.Selector{Ref: args.Ref, Name: args.Name}
+	source := ""
+	if uri := strings.TrimSpace(args.URI); uri != "" {
+		if args.Ref != "" || args.Name != "" {
+			return toolError("invalid input: uri cannot be combined with ref or name"), nil
+		}
+		parsed, ws, err := selectorFromURI(uri)
+		if err != nil {
+			return toolErrorFromErr(err), nil
+		}
+		sel, source = daemon.Selector{Ref: parsed.Ref, Name: parsed.Name}, ws
 	}
 
-	getOut, err := s.daemon().Get(ctx, daemon.GetRequest{
+	got, err := s.daemon().Get(ctx, daemon.GetRequest{
 		Workspace: s.currentWorkspace(ctx),
-		Selector:  daemon.Selector{Ref: args.Ref, Name: args.Name},
+		Selector:  sel,
+		Source:    source,
+		Pointer:   args.Pointer,
+		Offset:    args.Offset,
+		Length:    args.Length,
+		StartLine: args.StartLine,
+		EndLine:   args.EndLine,
+		Head:      args.Head,
+		Tail:      args.Tail,
+		MaxBytes:  maxBytes,
 	})
 	if err != nil {
 		return toolErrorFromErr(err), nil
 	}
-	a := getOut.Artifact
-	data, err := base64.StdEncoding.DecodeString(getOut.DataBase64)
+	a := got.Artifact
+	data, err := base64.StdEncoding.DecodeString(got.DataBase64)
 	if err != nil {
 		return toolError("internal error: invalid daemon payload"), nil
 	}
@@ -82,7 +129,18 @@ func (s *Server) toolGet(ctx context.Context, argsRaw json.RawMessage) (any, *js
 	}
 
 	nameEsc := url.PathEscape(a.Name)
-	meta := toSaveOut(a, nameEsc)
+	meta := getOut{
+		saveOut:    toSaveOut(a, nameEsc),
+		Offset:     got.Offset,
+		TotalBytes: got.TotalBytes,
+		TotalLines: got.TotalLines,
+		Truncated:  got.Truncated,
+	}
+	if source != "" {
+		meta.URIByName = artifacts.WorkspaceURIByName(source, nameEsc)
+		meta.URIByRef = artifacts.WorkspaceURIByRef(source, a.Ref)
+	}
+	partial := meta.Offset > 0 || meta.Offset+int64(len(data)) < meta.TotalBytes
 
 	lowerMime := strings.ToLower(a.MimeType)
 	isText := strings.HasPrefix(lowerMime, "text/") || a.Kind == artifacts.ArtifactKindText
@@ -107,7 +165,9 @@ func (s *Server) toolGet(ctx context.Context, argsRaw json.RawMessage) (any, *js
 	case modeText:
 		content = append(content, textContent(string(data)))
 	case modeImage:
-		if !isImage {
+		if meta.Truncated {
+			content = append(content, textContent(fmt.Sprintf("image is %d bytes, over maxBytes %d; pass a larger maxBytes to fetch it", meta.TotalBytes, maxBytes)))
+		} else if !isImage {
 			content = append(content, embeddedBlob(meta.URIByRef, a.MimeType, data))
 		} else {
 			content = append(content, imageContent(a.MimeType, data))
@@ -118,11 +178,25 @@ func (s *Server) toolGet(ctx context.Context, argsRaw json.RawMessage) (any, *js
 		return toolError("mode must be one of " + modeAuto + "|" + modeText + "|" + modeResource + "|" + modeImage + "|" + modeMeta), nil
 	}
 
+	if partial && mode != modeImage {
+		content = append(content, textContent(rangeNote(meta, len(data))))
+	}
 	content = append(content, resourceLink(a.Name, meta.URIByName, a.MimeType, a.SizeBytes))
 
 	return toolResult{Content: content, StructuredContent: meta}, nil
 }
 
+// rangeNote tells an agent which part of the artifact it got and how to read
+// on.
+func rangeNote(meta getOut, n int) string {
+	end := meta.Offset + int64(n)
+	note := fmt.Sprintf("bytes %d-%d of %d (%d lines)", meta.Offset, end, meta.TotalBytes, meta.TotalLines)
+	if meta.Truncated {
+		note += fmt.Sprintf("; truncated, continue with offset=%d", end)
+	}
+	return note
+}
+
 func (s *Server) toolList(ctx context.Context, argsRaw json.RawMessage) (any, *jsonRPCError) {
 	var args listArgs
 	if len(argsRaw) > 0 {
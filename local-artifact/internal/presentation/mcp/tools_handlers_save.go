@@ -5,22 +5,27 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"net/url"
+	"time"
 
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
 )
 
 type saveTextArgs struct {
-	Name     string `json:"name"`
-	Text     string `json:"text"`
-	MimeType string `json:"mimeType,omitempty"`
+	Name      string     `json:"name"`
+	Text      string     `json:"text"`
+	MimeType  string     `json:"mimeType,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 type saveBlobArgs struct {
-	Name       string `json:"name"`
-	DataBase64 string `json:"dataBase64"`
-	MimeType   string `json:"mimeType"`
-	Filename   string `json:"filename,omitempty"`
+	Name       string     `json:"name"`
+	DataBase64 string     `json:"dataBase64"`
+	MimeType   string     `json:"mimeType"`
+	Filename   string     `json:"filename,omitempty"`
+	TTL        string     `json:"ttl,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
 }
 
 type saveOut struct {
@@ -32,9 +37,14 @@ type saveOut struct {
 	URIByName string `json:"uriByName"`
 	URIByRef  string `json:"uriByRef"`
 	PrevRef   string `json:"prevRef,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
 }
 
 func toSaveOut(a artifacts.Artifact, nameEscaped string) saveOut {
+	expiresAt := ""
+	if a.ExpiresAt != nil {
+		expiresAt = a.ExpiresAt.UTC().Format(time.RFC3339)
+	}
 	return saveOut{
 		Name:      a.Name,
 		Ref:       a.Ref,
@@ -44,13 +54,14 @@ func toSaveOut(a artifacts.Artifact, nameEscaped string) saveOut {
 		URIByName: artifacts.URIByName(nameEscaped),
 		URIByRef:  a.URIByRef(),
 		PrevRef:   a.PrevRef,
+		ExpiresAt: expiresAt,
 	}
 }
 
 func (s *Server) toolSaveText(ctx context.Context, argsRaw json.RawMessage) (any, *jsonRPCError) {
 	var args saveTextArgs
 	if err := json.Unmarshal(argsRaw, &args); err != nil {
-		return toolError("Invalid arguments: expected {name, text, mimeType?}"), nil
+		return toolError("Invalid arguments: expected {name, text, mimeType?, ttl?, expiresAt?}"), nil
 	}
 
 	a, err := s.daemon().SaveText(ctx, daemon.SaveTextRequest{
@@ -58,6 +69,8 @@ func (s *Server) toolSaveText(ctx context.Context, argsRaw json.RawMessage) (any
 		Name:      args.Name,
 		Text:      args.Text,
 		MimeType:  args.MimeType,
+		TTL:       args.TTL,
+		ExpiresAt: args.ExpiresAt,
 	})
 	if err != nil {
 		return toolErrorFromErr(err), nil
@@ -78,7 +91,7 @@ func (s *Server) toolSaveText(ctx context.Context, argsRaw json.RawMessage) (any
 func (s *Server) toolSaveBlob(ctx context.Context, argsRaw json.RawMessage) (any, *jsonRPCError) {
 	var args saveBlobArgs
 	if err := json.Unmarshal(argsRaw, &args); err != nil {
-		return toolError("Invalid arguments: expected {name, dataBase64, mimeType, filename?}"), nil
+		return toolError("Invalid arguments: expected {name, dataBase64, mimeType, filename?, ttl?, expiresAt?}"), nil
 	}
 
 	data, err := base64.StdEncoding.DecodeString(args.DataBase64)
@@ -92,6 +105,8 @@ func (s *Server) toolSaveBlob(ctx context.Context, argsRaw json.RawMessage) (any
 		DataBase64: base64.StdEncoding.EncodeToString(data),
 		MimeType:   args.MimeType,
 		Filename:   args.Filename,
+		TTL:        args.TTL,
+		ExpiresAt:  args.ExpiresAt,
 	})
 	if err != nil {
 		return toolErrorFromErr(err), nil
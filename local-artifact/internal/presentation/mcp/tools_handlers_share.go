@@ -0,0 +1,45 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
+)
+
+type shareArgs struct {
+	Name   string `json:"name"`
+	With   string `json:"with,omitempty"`
+	Revoke bool   `json:"revoke,omitempty"`
+}
+
+func (s *Server) toolShare(ctx context.Context, argsRaw json.RawMessage) (any, *jsonRPCError) {
+	var args shareArgs
+	if err := json.Unmarshal(argsRaw, &args); err != nil {
+		return toolError("Invalid arguments: expected {name, with?, revoke?}"), nil
+	}
+
+	shared, err := s.daemon().Share(ctx, daemon.ShareRequest{
+		Workspace: s.currentWorkspace(ctx),
+		Name:      args.Name,
+		With:      args.With,
+		Revoke:    args.Revoke,
+	})
+	if err != nil {
+		return toolErrorFromErr(err), nil
+	}
+
+	status := "shared"
+	if shared.Revoked {
+		status = "revoked"
+	}
+	return toolResult{
+		Content: []any{textContent(status + " " + shared.URI)},
+		StructuredContent: map[string]any{
+			"name":    shared.Share.Name,
+			"with":    shared.Share.TargetWorkspaceID,
+			"uri":     shared.URI,
+			"revoked": shared.Revoked,
+		},
+	}, nil
+}
@@ -3,6 +3,7 @@ package mcp
 import (
 	"encoding/base64"
 	"errors"
+	"strings"
 
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
 	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/presentation/daemon"
@@ -24,10 +25,14 @@ func toolErrorFromErr(err error) toolResult {
 			return toolError("not found")
 		case daemon.CodeConflict:
 			return toolError("conflict: " + remoteErr.Message)
+		case daemon.CodeQuotaExceeded:
+			return quotaToolError(remoteErr.Message)
 		case daemon.CodeInvalidInput:
 			return toolError("invalid input: " + remoteErr.Message)
 		case daemon.CodeUnauthorized:
 			return toolError("unauthorized: " + remoteErr.Message)
+		case daemon.CodeForbidden:
+			return toolError("forbidden: " + remoteErr.Message)
 		case daemon.CodeServiceUnavailable:
 			return toolError("internal error: service unavailable: " + remoteErr.Message)
 		default:
@@ -38,8 +43,12 @@ func toolErrorFromErr(err error) toolResult {
 	switch {
 	case errors.Is(err, artifacts.ErrNotFound):
 		return toolError("not found")
+	case errors.Is(err, artifacts.ErrNotShared):
+		return toolError("forbidden: " + err.Error())
 	case errors.Is(err, artifacts.ErrAliasExists), errors.Is(err, artifacts.ErrConflict):
 		return toolError("conflict: " + err.Error())
+	case errors.Is(err, artifacts.ErrQuotaExceeded):
+		return quotaToolError(err.Error())
 	case errors.Is(err, artifacts.ErrInvalidInput),
 		errors.Is(err, artifacts.ErrNameRequired),
 		errors.Is(err, artifacts.ErrRefRequired),
@@ -54,6 +63,13 @@ func toolErrorFromErr(err error) toolResult {
 	}
 }
 
+// quotaToolError tells the agent that retrying will not help, so a runaway
+// save loop stops instead of hammering the daemon.
+func quotaToolError(msg string) toolResult {
+	msg = strings.TrimPrefix(msg, artifacts.ErrQuotaExceeded.Error()+": ")
+	return toolError("quota exceeded: " + msg + " (retrying will not succeed; delete artifacts or ask the user to raise the quota in settings.json)")
+}
+
 func textContent(text string) map[string]any {
 	return map[string]any{"type": "text", "text": text}
 }
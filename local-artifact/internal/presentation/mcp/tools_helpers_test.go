@@ -20,3 +20,45 @@ func TestToolErrorFromErr_DaemonUnauthorized(t *testing.T) {
 		t.Fatalf("expected daemon auth message, got %q", msg)
 	}
 }
+
+func TestToolErrorFromErr_DaemonQuotaExceeded(t *testing.T) {
+	result := toolErrorFromErr(&daemon.RemoteError{Code: daemon.CodeQuotaExceeded, Message: "quota exceeded: artifact is 20 bytes, limit is 10 bytes per artifact"})
+	if !result.IsError {
+		t.Fatalf("expected tool error, got %+v", result)
+	}
+	msg := firstContentText(result)
+	if !strings.HasPrefix(msg, "quota exceeded: artifact is 20 bytes") {
+		t.Fatalf("expected single quota prefix, got %q", msg)
+	}
+	if !strings.Contains(msg, "retrying will not succeed") {
+		t.Fatalf("expected no-retry hint, got %q", msg)
+	}
+}
+
+func TestSelectorFromURI_WorkspaceQualified(t *testing.T) {
+	tests := []struct {
+		uri           string
+		wantName      string
+		wantRef       string
+		wantWorkspace string
+	}{
+		{uri: "artifact://name/plan%2Ftask", wantName: "plan/task"},
+		{uri: "artifact://ref/abc", wantRef: "abc"},
+		{uri: "artifact://ws/global/name/style%2Fguide", wantName: "style/guide", wantWorkspace: "global"},
+		{uri: "artifact://ws/team/ref/abc", wantRef: "abc", wantWorkspace: "team"},
+	}
+	for _, tc := range tests {
+		sel, workspace, err := selectorFromURI(tc.uri)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.uri, err)
+		}
+		if sel.Name != tc.wantName || sel.Ref != tc.wantRef || workspace != tc.wantWorkspace {
+			t.Fatalf("%s: got %+v in %q", tc.uri, sel, workspace)
+		}
+	}
+	for _, uri := range []string{"artifact://ws/global", "artifact://ws//name/x", "artifact://ws/global/tag/x"} {
+		if _, _, err := selectorFromURI(uri); err == nil {
+			t.Fatalf("%s: expected error", uri)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 type callToolParams struct {
 	Name      string          `json:"name"`
 	Arguments json.RawMessage `json:"arguments"`
+	Meta      requestMeta     `json:"_meta"`
 }
 
 type toolResult struct {
@@ -49,6 +50,18 @@ func initializeToolRegistry() {
 				return s.toolSaveBlob(ctx, args)
 			},
 		},
+		{
+			Metadata: toolRegistryMetadata{CanonicalName: toolArtifactSaveJSON, Aliases: []string{"artifact.save_json"}},
+			Handler: func(s *Server, ctx context.Context, args json.RawMessage) (any, *jsonRPCError) {
+				return s.toolSaveJSON(ctx, args)
+			},
+		},
+		{
+			Metadata: toolRegistryMetadata{CanonicalName: toolArtifactPatchJSON, Aliases: []string{"artifact.patch_json"}},
+			Handler: func(s *Server, ctx context.Context, args json.RawMessage) (any, *jsonRPCError) {
+				return s.toolPatchJSON(ctx, args)
+			},
+		},
 		{
 			Metadata: toolRegistryMetadata{CanonicalName: toolArtifactResolve, Aliases: []string{"artifact.resolve"}},
 			Handler: func(s *Server, ctx context.Context, args json.RawMessage) (any, *jsonRPCError) {
@@ -73,6 +86,18 @@ func initializeToolRegistry() {
 				return s.toolDelete(ctx, args)
 			},
 		},
+		{
+			Metadata: toolRegistryMetadata{CanonicalName: toolArtifactBatch, Aliases: []string{"artifact.batch"}},
+			Handler: func(s *Server, ctx context.Context, args json.RawMessage) (any, *jsonRPCError) {
+				return s.toolBatch(ctx, args)
+			},
+		},
+		{
+			Metadata: toolRegistryMetadata{CanonicalName: toolArtifactShare, Aliases: []string{"artifact.share"}},
+			Handler: func(s *Server, ctx context.Context, args json.RawMessage) (any, *jsonRPCError) {
+				return s.toolShare(ctx, args)
+			},
+		},
 		{
 			Metadata: toolRegistryMetadata{CanonicalName: toolArtifactTodo, Aliases: []string{"artifact.todo"}},
 			Handler: func(s *Server, ctx context.Context, args json.RawMessage) (any, *jsonRPCError) {
@@ -116,6 +141,9 @@ func (s *Server) handleToolsCall(ctx context.Context, params json.RawMessage) (a
 		}
 		return toolError("Invalid arguments: " + err.Error()), nil
 	}
+	if phase, ok := toolProgressPhases[entry.Metadata.CanonicalName]; ok && len(p.Meta.ProgressToken) > 0 {
+		ctx = s.withProgress(ctx, p.Meta.ProgressToken, phase)
+	}
 
 	return entry.Handler(s, ctx, p.Arguments)
 }
@@ -0,0 +1,376 @@
+package web
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+const maxRenderBytes = 2 * mebibyte
+const detailVersionLimit = 50
+
+const (
+	detailViewMarkdown = "markdown"
+	detailViewCode     = "code"
+	detailViewText     = "text"
+	detailViewTodo     = "todo"
+	detailViewImage    = "image"
+	detailViewBinary   = "binary"
+	detailViewTooLarge = "too-large"
+)
+
+// safeImageMimeTypes are the raster formats previewed inline. SVG is left
+// out because it can carry script.
+var safeImageMimeTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type detailArtifact struct {
+	pageItem
+	Filename  string
+	PrevRef   string
+	Tombstone bool
+}
+
+type detailVersion struct {
+	Ref       string
+	CreatedAt string
+	SizeBytes int64
+	Tombstone bool
+	Current   bool
+	ViewURL   string
+	DiffURL   string
+}
+
+type detailTodo struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
+
+type detailPageData struct {
+	Nonce       string
+	CSRFToken   string
+	Subspace    string
+	IndexURL    string
+	Artifact    detailArtifact
+	View        string
+	Language    string
+	Rendered    template.HTML
+	Todos       []detailTodo
+	ImageURL    string
+	DownloadURL string
+	EditURL     string
+	Versions    []detailVersion
+	Compare     string
+	DiffRows    []diffRow
+	DiffError   string
+	Error       string
+	GeneratedAt string
+}
+
+func (s *Server) handleArtifactDetail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	query := r.URL.Query()
+	subspace := normalizeSubspaceSelector(query.Get("subspace"))
+	if subspace == "" {
+		subspace = globalSubspaceSelector
+	}
+	data := detailPageData{
+		Subspace:    subspace,
+		IndexURL:    indexRedirectBase(subspace, "", listSortNameAsc, ""),
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	svc, err := s.serviceFromQuerySubspace(subspace)
+	if err != nil {
+		data.Error = err.Error()
+		renderDetail(w, r, http.StatusBadRequest, data)
+		return
+	}
+	selector, err := parseSingleSelector(query)
+	if err != nil {
+		data.Error = err.Error()
+		renderDetail(w, r, http.StatusBadRequest, data)
+		return
+	}
+	artifact, payload, err := svc.Get(r.Context(), selector)
+	if err != nil {
+		data.Error = err.Error()
+		status := http.StatusBadRequest
+		if errors.Is(err, artifacts.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		renderDetail(w, r, status, data)
+		return
+	}
+
+	data.Artifact = detailArtifact{
+		pageItem: pageItem{
+			Name:      artifact.Name,
+			Ref:       artifact.Ref,
+			Kind:      string(artifact.Kind),
+			MimeType:  artifact.MimeType,
+			SizeBytes: artifact.SizeBytes,
+			SHA256:    artifact.SHA256,
+			CreatedAt: artifact.CreatedAt.Format(time.RFC3339),
+		},
+		Filename:  artifact.Filename,
+		PrevRef:   artifact.PrevRef,
+		Tombstone: artifact.Tombstone,
+	}
+	data.DownloadURL = artifactURL("/api/artifact-content", subspace, artifact.Ref, nil)
+	if artifact.Kind == artifacts.ArtifactKindText {
+		data.EditURL = editURL(subspace, artifact.Ref)
+	}
+	classifyArtifactView(&data, subspace, artifact, payload)
+
+	if artifact.Name != "" {
+		versions, err := svc.ListVersions(r.Context(), artifact.Name, detailVersionLimit)
+		if err == nil {
+			data.Versions = detailVersions(subspace, artifact.Ref, versions)
+		}
+	}
+
+	if compare := strings.TrimSpace(query.Get("compare")); compare != "" {
+		data.Compare = compare
+		base, basePayload, err := svc.Get(r.Context(), artifacts.Selector{Ref: compare})
+		switch {
+		case err != nil:
+			data.DiffError = "compare: " + err.Error()
+		case !isTextual(base, basePayload) || !isTextual(artifact, payload):
+			data.DiffError = "only text content can be compared"
+		default:
+			lines, ok := diffLines(string(basePayload), string(payload))
+			if !ok {
+				data.DiffError = "content is too large to diff"
+			} else {
+				data.DiffRows = sideBySide(lines)
+			}
+		}
+	}
+
+	renderDetail(w, r, http.StatusOK, data)
+}
+
+func (s *Server) handleArtifactImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	svc, err := s.serviceFromQuerySubspace(r.URL.Query().Get("subspace"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	selector, err := parseSingleSelector(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	artifact, payload, err := svc.Get(r.Context(), selector)
+	if err != nil {
+		if errors.Is(err, artifacts.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": artifacts.ErrNotFound.Error()})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	mimeType := normalizeMimeType(artifact.MimeType)
+	if !safeImageMimeTypes[mimeType] {
+		writeJSON(w, http.StatusUnsupportedMediaType, map[string]any{"error": "not a previewable image"})
+		return
+	}
+
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Content-Disposition", "inline")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.WriteHeader(http.StatusOK)
+	writePayload(w, payload)
+}
+
+func classifyArtifactView(data *detailPageData, subspace string, artifact artifacts.Artifact, payload []byte) {
+	mimeType := normalizeMimeType(artifact.MimeType)
+	switch {
+	case artifact.Kind == artifacts.ArtifactKindImage || safeImageMimeTypes[mimeType]:
+		if safeImageMimeTypes[mimeType] {
+			data.View = detailViewImage
+			data.ImageURL = artifactURL("/artifact/image", subspace, artifact.Ref, nil)
+			return
+		}
+		data.View = detailViewBinary
+		return
+	case int64(len(payload)) > maxRenderBytes:
+		data.View = detailViewTooLarge
+		return
+	case !isTextual(artifact, payload):
+		data.View = detailViewBinary
+		return
+	}
+
+	text := string(payload)
+	if todos, ok := parseTodoList(payload); ok {
+		data.View = detailViewTodo
+		data.Todos = todos
+		return
+	}
+	if isMarkdownArtifact(mimeType, artifact) {
+		data.View = detailViewMarkdown
+		data.Rendered = renderMarkdown(text)
+		return
+	}
+	if lang := languageForArtifact(mimeType, artifact.Filename); lang != nil {
+		if lang.name == "json" {
+			var pretty bytes.Buffer
+			if err := json.Indent(&pretty, payload, "", "  "); err == nil {
+				text = pretty.String()
+			}
+		}
+		data.View = detailViewCode
+		data.Language = lang.name
+		data.Rendered = highlightCode(lang, text)
+		return
+	}
+	data.View = detailViewText
+	data.Rendered = highlightCode(nil, text)
+}
+
+func isTextual(artifact artifacts.Artifact, payload []byte) bool {
+	if artifact.Kind == artifacts.ArtifactKindImage {
+		return false
+	}
+	mimeType := normalizeMimeType(artifact.MimeType)
+	if artifact.Kind == artifacts.ArtifactKindText || strings.HasPrefix(mimeType, "text/") {
+		return utf8.Valid(payload)
+	}
+	if _, ok := codeLanguageByMime[mimeType]; ok {
+		return utf8.Valid(payload)
+	}
+	return utf8.Valid(payload) && !bytes.ContainsRune(payload, 0)
+}
+
+func isMarkdownArtifact(mimeType string, artifact artifacts.Artifact) bool {
+	if mimeType == "text/markdown" || mimeType == "text/x-markdown" {
+		return true
+	}
+	for _, name := range []string{artifact.Filename, artifact.Name} {
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".md", ".markdown":
+			return true
+		}
+	}
+	return strings.HasPrefix(artifact.Name, "plan/") && (mimeType == "" || mimeType == "text/plain")
+}
+
+// parseTodoList recognises the JSON written by the MCP todo tool.
+func parseTodoList(payload []byte) ([]detailTodo, bool) {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.DisallowUnknownFields()
+	var todos []detailTodo
+	if err := decoder.Decode(&todos); err != nil || len(todos) == 0 {
+		return nil, false
+	}
+	for _, todo := range todos {
+		switch todo.Status {
+		case "not-started", "in-progress", "completed":
+		default:
+			return nil, false
+		}
+		if strings.TrimSpace(todo.Title) == "" {
+			return nil, false
+		}
+	}
+	return todos, true
+}
+
+func detailVersions(subspace, currentRef string, versions []artifacts.ArtifactVersion) []detailVersion {
+	out := make([]detailVersion, 0, len(versions))
+	for _, v := range versions {
+		item := detailVersion{
+			Ref:       v.Ref,
+			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+			SizeBytes: v.SizeBytes,
+			Tombstone: v.Tombstone,
+			Current:   v.Ref == currentRef,
+		}
+		if !v.Tombstone {
+			item.ViewURL = artifactURL("/artifact", subspace, v.Ref, nil)
+			if v.PrevRef != "" {
+				item.DiffURL = artifactURL("/artifact", subspace, v.Ref, url.Values{"compare": {v.PrevRef}})
+			}
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func artifactURL(path, subspace, ref string, extra url.Values) string {
+	values := url.Values{"subspace": {subspace}, "ref": {ref}}
+	for key, vals := range extra {
+		values[key] = vals
+	}
+	return path + "?" + values.Encode()
+}
+
+func newScriptNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf), nil
+}
+
+func renderDetail(w http.ResponseWriter, r *http.Request, status int, data detailPageData) {
+	token, err := ensureCSRFToken(w, r)
+	if err != nil {
+		http.Error(w, "csrf setup error", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := newScriptNonce()
+	if err != nil {
+		http.Error(w, "nonce setup error", http.StatusInternalServerError)
+		return
+	}
+	data.CSRFToken = token
+	data.Nonce = nonce
+
+	var body bytes.Buffer
+	if err := artifactTemplate.Execute(&body, data); err != nil {
+		http.Error(w, "render error", http.StatusInternalServerError)
+		return
+	}
+	setPageSecurityHeaders(w, nonce)
+	w.WriteHeader(status)
+	writePayload(w, body.Bytes())
+}
+
+func setPageSecurityHeaders(w http.ResponseWriter, nonce string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; script-src 'nonce-"+nonce+"'; form-action 'self'; base-uri 'none'; frame-ancestors 'none'")
+}
@@ -0,0 +1,136 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+func detailPath(name string, extra url.Values) string {
+	values := url.Values{"subspace": {globalSubspaceSelector}, "name": {name}}
+	for key, vals := range extra {
+		values[key] = vals
+	}
+	return "/artifact?" + values.Encode()
+}
+
+func TestArtifactDetailRendersMarkdownWithNonceCSP(t *testing.T) {
+	h := newWebHarness(t)
+	if _, err := h.svc(globalSubspaceSelector).SaveText(context.Background(), artifacts.SaveTextInput{
+		Name:     "plan/readme",
+		Text:     "# Plan\n\n<script>alert(1)</script>\n\n- [ ] step",
+		MimeType: "text/markdown",
+	}); err != nil {
+		t.Fatalf("save markdown: %v", err)
+	}
+
+	rr := h.request(http.MethodGet, detailPath("plan/readme", nil), nil, nil)
+	assertStatus(t, rr, http.StatusOK)
+
+	body := rr.Body.String()
+	if !strings.Contains(body, "<h1>Plan</h1>") {
+		t.Fatalf("expected rendered heading, body=%s", body)
+	}
+	if strings.Contains(body, "<script>alert(1)</script>") {
+		t.Fatalf("markdown html was not escaped")
+	}
+	csp := rr.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "script-src 'nonce-") || !strings.Contains(csp, "frame-ancestors 'none'") {
+		t.Fatalf("unexpected csp: %q", csp)
+	}
+	if strings.Count(body, "<script nonce=") != strings.Count(body, "<script") {
+		t.Fatalf("every script tag must carry the nonce")
+	}
+}
+
+func TestArtifactDetailPrettyPrintsTodoAndJSON(t *testing.T) {
+	h := newWebHarness(t)
+	h.mustSaveText(globalSubspaceSelector, "plan/todo", `[{"id":1,"title":"Write tests","status":"in-progress"}]`)
+	if _, err := h.svc(globalSubspaceSelector).SaveText(context.Background(), artifacts.SaveTextInput{
+		Name:     "data/config",
+		Text:     `{"enabled":true}`,
+		MimeType: "application/json",
+	}); err != nil {
+		t.Fatalf("save json: %v", err)
+	}
+
+	todoRR := h.request(http.MethodGet, detailPath("plan/todo", nil), nil, nil)
+	assertStatus(t, todoRR, http.StatusOK)
+	if body := todoRR.Body.String(); !strings.Contains(body, `<li class="in-progress">`) || !strings.Contains(body, "Write tests") {
+		t.Fatalf("expected todo list rendering, body=%s", body)
+	}
+
+	jsonRR := h.request(http.MethodGet, detailPath("data/config", nil), nil, nil)
+	assertStatus(t, jsonRR, http.StatusOK)
+	if body := jsonRR.Body.String(); !strings.Contains(body, `<span class="tok-key">&#34;enabled&#34;</span>: <span class="tok-lit">true</span>`) {
+		t.Fatalf("expected highlighted pretty json, body=%s", body)
+	}
+}
+
+func TestArtifactDetailShowsSideBySideDiff(t *testing.T) {
+	h := newWebHarness(t)
+	first := h.mustSaveText(globalSubspaceSelector, "notes/diff", "alpha\nbeta\n")
+	h.mustSaveText(globalSubspaceSelector, "notes/diff", "alpha\ngamma\n")
+
+	rr := h.request(http.MethodGet, detailPath("notes/diff", url.Values{"compare": {first.Ref}}), nil, nil)
+	assertStatus(t, rr, http.StatusOK)
+
+	body := rr.Body.String()
+	for _, want := range []string{`<td class="del">beta</td>`, `<td class="ins">gamma</td>`, "diff with previous"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in body=%s", want, body)
+		}
+	}
+}
+
+func TestArtifactDetailNotFound(t *testing.T) {
+	h := newWebHarness(t)
+
+	rr := h.request(http.MethodGet, detailPath("missing/artifact", nil), nil, nil)
+	assertStatus(t, rr, http.StatusNotFound)
+}
+
+func TestArtifactImageServesOnlySafeRasterTypes(t *testing.T) {
+	h := newWebHarness(t)
+	svc := h.svc(globalSubspaceSelector)
+	png, err := svc.SaveBlob(context.Background(), artifacts.SaveBlobInput{
+		Name:     "img/ok",
+		Data:     []byte("\x89PNG\r\n\x1a\n"),
+		MimeType: "image/png",
+		Filename: "ok.png",
+	})
+	if err != nil {
+		t.Fatalf("save png: %v", err)
+	}
+	svg, err := svc.SaveBlob(context.Background(), artifacts.SaveBlobInput{
+		Name:     "img/svg",
+		Data:     []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`),
+		MimeType: "image/svg+xml",
+		Filename: "bad.svg",
+	})
+	if err != nil {
+		t.Fatalf("save svg: %v", err)
+	}
+
+	okRR := h.request(http.MethodGet, artifactURL("/artifact/image", globalSubspaceSelector, png.Ref, nil), nil, nil)
+	assertStatus(t, okRR, http.StatusOK)
+	if got := okRR.Header().Get("Content-Type"); got != "image/png" {
+		t.Fatalf("content-type=%q", got)
+	}
+	if got := okRR.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Fatalf("x-content-type-options=%q", got)
+	}
+
+	svgRR := h.request(http.MethodGet, artifactURL("/artifact/image", globalSubspaceSelector, svg.Ref, nil), nil, nil)
+	assertStatus(t, svgRR, http.StatusUnsupportedMediaType)
+
+	detailRR := h.request(http.MethodGet, detailPath("img/ok", nil), nil, nil)
+	assertStatus(t, detailRR, http.StatusOK)
+	if !strings.Contains(detailRR.Body.String(), `<img src="/artifact/image?`) {
+		t.Fatalf("expected inline image preview, body=%s", detailRR.Body.String())
+	}
+}
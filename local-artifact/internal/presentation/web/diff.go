@@ -0,0 +1,138 @@
+package web
+
+import "strings"
+
+const maxDiffCells = 4_000_000
+
+type diffOp int
+
+const (
+	diffEqual diffOp = iota
+	diffDelete
+	diffInsert
+)
+
+type diffLine struct {
+	Op   diffOp
+	Text string
+	// Old and New are 1-based line numbers; 0 means the line is absent on
+	// that side.
+	Old int
+	New int
+}
+
+// diffLines computes a line diff from the longest common subsequence. ok is
+// false when the inputs are too large for the quadratic table.
+func diffLines(oldText, newText string) ([]diffLine, bool) {
+	a := splitDiffLines(oldText)
+	b := splitDiffLines(newText)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	if (len(midA)+1)*(len(midB)+1) > maxDiffCells {
+		return nil, false
+	}
+
+	// lcs[i][j] is the LCS length of midA[i:] and midB[j:].
+	lcs := make([][]int32, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	out := make([]diffLine, 0, len(a)+len(b)-prefix-suffix)
+	for k := 0; k < prefix; k++ {
+		out = append(out, diffLine{Op: diffEqual, Text: a[k], Old: k + 1, New: k + 1})
+	}
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			out = append(out, diffLine{Op: diffEqual, Text: midA[i], Old: prefix + i + 1, New: prefix + j + 1})
+			i++
+			j++
+		case i < len(midA) && (j == len(midB) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, diffLine{Op: diffDelete, Text: midA[i], Old: prefix + i + 1})
+			i++
+		default:
+			out = append(out, diffLine{Op: diffInsert, Text: midB[j], New: prefix + j + 1})
+			j++
+		}
+	}
+	for k := 0; k < suffix; k++ {
+		out = append(out, diffLine{Op: diffEqual, Text: a[len(a)-suffix+k], Old: len(a) - suffix + k + 1, New: len(b) - suffix + k + 1})
+	}
+	return out, true
+}
+
+func splitDiffLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+type diffRowCell struct {
+	Line  int
+	Text  string
+	Class string
+}
+
+// diffRow is one line of a side-by-side diff. A missing side has Line 0.
+type diffRow struct {
+	Left  diffRowCell
+	Right diffRowCell
+}
+
+// sideBySide pairs deletions with the insertions that follow them so that a
+// changed line shows up on one row.
+func sideBySide(lines []diffLine) []diffRow {
+	rows := make([]diffRow, 0, len(lines))
+	for i := 0; i < len(lines); {
+		if lines[i].Op == diffEqual {
+			rows = append(rows, diffRow{
+				Left:  diffRowCell{Line: lines[i].Old, Text: lines[i].Text},
+				Right: diffRowCell{Line: lines[i].New, Text: lines[i].Text},
+			})
+			i++
+			continue
+		}
+		var dels, ins []diffLine
+		for i < len(lines) && lines[i].Op == diffDelete {
+			dels = append(dels, lines[i])
+			i++
+		}
+		for i < len(lines) && lines[i].Op == diffInsert {
+			ins = append(ins, lines[i])
+			i++
+		}
+		for k := 0; k < max(len(dels), len(ins)); k++ {
+			var row diffRow
+			if k < len(dels) {
+				row.Left = diffRowCell{Line: dels[k].Old, Text: dels[k].Text, Class: "del"}
+			}
+			if k < len(ins) {
+				row.Right = diffRowCell{Line: ins[k].New, Text: ins[k].Text, Class: "ins"}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
@@ -0,0 +1,273 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+type editConflict struct {
+	TheirRef       string
+	TheirCreatedAt string
+	TheirURL       string
+	Blocks         []mergeBlock
+	Conflicts      int
+}
+
+type editPageData struct {
+	Nonce       string
+	CSRFToken   string
+	Subspace    string
+	IndexURL    string
+	DetailURL   string
+	Name        string
+	BaseRef     string
+	MimeType    string
+	Text        string
+	Conflict    *editConflict
+	HasMarkers  bool
+	Error       string
+	GeneratedAt string
+}
+
+func (s *Server) handleArtifactEdit(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleArtifactEditForm(w, r)
+	case http.MethodPost:
+		s.handleArtifactEditSubmit(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (s *Server) handleArtifactEditForm(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	subspace := normalizeSubspaceSelector(query.Get("subspace"))
+	if subspace == "" {
+		subspace = globalSubspaceSelector
+	}
+	data := newEditPageData(subspace)
+
+	svc, err := s.serviceFromQuerySubspace(subspace)
+	if err != nil {
+		data.Error = err.Error()
+		renderEdit(w, r, http.StatusBadRequest, data)
+		return
+	}
+	selector, err := parseSingleSelector(query)
+	if err != nil {
+		data.Error = err.Error()
+		renderEdit(w, r, http.StatusBadRequest, data)
+		return
+	}
+	artifact, payload, err := svc.Get(r.Context(), selector)
+	if err != nil {
+		data.Error = err.Error()
+		status := http.StatusBadRequest
+		if errors.Is(err, artifacts.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		renderEdit(w, r, status, data)
+		return
+	}
+	data.Name = artifact.Name
+	data.DetailURL = artifactURL("/artifact", subspace, artifact.Ref, nil)
+	if err := editableArtifact(artifact, payload); err != nil {
+		data.Error = err.Error()
+		renderEdit(w, r, http.StatusBadRequest, data)
+		return
+	}
+	// Edits always build on the latest version so that ExpectedPrevRef
+	// protects against overwriting concurrent saves.
+	if selector.Ref != "" {
+		latest, latestPayload, err := svc.Get(r.Context(), artifacts.Selector{Name: artifact.Name})
+		if err != nil {
+			data.Error = err.Error()
+			renderEdit(w, r, http.StatusConflict, data)
+			return
+		}
+		artifact, payload = latest, latestPayload
+	}
+
+	data.BaseRef = artifact.Ref
+	data.MimeType = artifact.MimeType
+	data.Text = string(payload)
+	data.DetailURL = artifactURL("/artifact", subspace, artifact.Ref, nil)
+	renderEdit(w, r, http.StatusOK, data)
+}
+
+func (s *Server) handleArtifactEditSubmit(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxInsertUploadBytes+maxInsertUploadOverheadBytes)
+	if err := r.ParseForm(); err != nil {
+		errMsg := "invalid form data"
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			errMsg = "content too large (max 10 MiB)"
+		}
+		data := newEditPageData(globalSubspaceSelector)
+		data.Error = errMsg
+		renderEdit(w, r, http.StatusBadRequest, data)
+		return
+	}
+
+	subspace := normalizeSubspaceSelector(r.FormValue("subspace"))
+	if subspace == "" {
+		subspace = globalSubspaceSelector
+	}
+	data := newEditPageData(subspace)
+	data.Name = strings.TrimSpace(r.FormValue("name"))
+	data.BaseRef = strings.TrimSpace(r.FormValue("baseRef"))
+	data.MimeType = strings.TrimSpace(r.FormValue("mimeType"))
+	data.Text = r.FormValue("text")
+	if data.BaseRef != "" {
+		data.DetailURL = artifactURL("/artifact", subspace, data.BaseRef, nil)
+	}
+
+	if err := validateMutationOrigin(r); err != nil {
+		data.Error = err.Error()
+		renderEdit(w, r, http.StatusForbidden, data)
+		return
+	}
+	if err := validateCSRFToken(r); err != nil {
+		data.Error = err.Error()
+		renderEdit(w, r, http.StatusForbidden, data)
+		return
+	}
+	if data.Name == "" || data.BaseRef == "" {
+		data.Error = "name and baseRef are required"
+		renderEdit(w, r, http.StatusBadRequest, data)
+		return
+	}
+	// A merged retry pre-fills the textarea with conflict markers; saving them
+	// unresolved must be a deliberate choice, not a second click on Save.
+	if hasConflictMarkers(data.Text) && r.FormValue("keepMarkers") != "1" {
+		data.HasMarkers = true
+		data.Error = "content still contains conflict markers; resolve them or confirm saving them as-is"
+		renderEdit(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+
+	svc, err := s.serviceFromSelectedSubspace(subspace)
+	if err != nil {
+		data.Error = err.Error()
+		renderEdit(w, r, http.StatusBadRequest, data)
+		return
+	}
+	saved, err := svc.SaveText(r.Context(), artifacts.SaveTextInput{
+		Name:            data.Name,
+		Text:            data.Text,
+		MimeType:        data.MimeType,
+		ExpectedPrevRef: data.BaseRef,
+	})
+	if err == nil {
+		http.Redirect(w, r, artifactURL("/artifact", subspace, saved.Ref, nil), http.StatusSeeOther)
+		return
+	}
+	if !errors.Is(err, artifacts.ErrConflict) {
+		data.Error = err.Error()
+		renderEdit(w, r, http.StatusBadRequest, data)
+		return
+	}
+
+	latest, theirPayload, err := svc.Get(r.Context(), artifacts.Selector{Name: data.Name})
+	if err != nil {
+		if errors.Is(err, artifacts.ErrNotFound) {
+			data.Error = "artifact was deleted after you started editing; copy your text before leaving this page"
+		} else {
+			data.Error = err.Error()
+		}
+		renderEdit(w, r, http.StatusConflict, data)
+		return
+	}
+	_, basePayload, err := svc.Get(r.Context(), artifacts.Selector{Ref: data.BaseRef})
+	if err != nil {
+		data.Error = "conflict: cannot load the version you started from: " + err.Error()
+		renderEdit(w, r, http.StatusConflict, data)
+		return
+	}
+	if err := editableArtifact(latest, theirPayload); err != nil {
+		data.Error = "conflict: newer version is not editable: " + err.Error()
+		renderEdit(w, r, http.StatusConflict, data)
+		return
+	}
+
+	merged, ok := merge3(string(basePayload), data.Text, string(theirPayload))
+	if !ok {
+		data.Error = "conflict: content is too large to merge"
+		renderEdit(w, r, http.StatusConflict, data)
+		return
+	}
+	data.Conflict = &editConflict{
+		TheirRef:       latest.Ref,
+		TheirCreatedAt: latest.CreatedAt.Format(time.RFC3339),
+		TheirURL:       artifactURL("/artifact", subspace, latest.Ref, nil),
+		Blocks:         merged.Blocks,
+		Conflicts:      merged.Conflicts,
+	}
+	data.BaseRef = latest.Ref
+	data.Text = merged.Text(latest.Ref)
+	data.DetailURL = data.Conflict.TheirURL
+	renderEdit(w, r, http.StatusConflict, data)
+}
+
+func editableArtifact(artifact artifacts.Artifact, payload []byte) error {
+	if artifact.Kind != artifacts.ArtifactKindText {
+		return errors.New("only text artifacts can be edited")
+	}
+	if !utf8.Valid(payload) || bytes.ContainsRune(payload, 0) {
+		return errors.New("artifact content is not editable text")
+	}
+	return nil
+}
+
+func hasConflictMarkers(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") {
+			return true
+		}
+	}
+	return false
+}
+
+func newEditPageData(subspace string) editPageData {
+	return editPageData{
+		Subspace:    subspace,
+		IndexURL:    indexRedirectBase(subspace, "", listSortNameAsc, ""),
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func editURL(subspace, ref string) string {
+	return artifactURL("/artifact/edit", subspace, ref, nil)
+}
+
+func renderEdit(w http.ResponseWriter, r *http.Request, status int, data editPageData) {
+	token, err := ensureCSRFToken(w, r)
+	if err != nil {
+		http.Error(w, "csrf setup error", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := newScriptNonce()
+	if err != nil {
+		http.Error(w, "nonce setup error", http.StatusInternalServerError)
+		return
+	}
+	data.CSRFToken = token
+	data.Nonce = nonce
+
+	var body bytes.Buffer
+	if err := editTemplate.Execute(&body, data); err != nil {
+		http.Error(w, "render error", http.StatusInternalServerError)
+		return
+	}
+	setPageSecurityHeaders(w, nonce)
+	w.WriteHeader(status)
+	writePayload(w, body.Bytes())
+}
@@ -0,0 +1,140 @@
+package web
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+func editForm(name, baseRef, text string) url.Values {
+	return url.Values{
+		"subspace": {globalSubspaceSelector},
+		"name":     {name},
+		"baseRef":  {baseRef},
+		"text":     {text},
+	}
+}
+
+func TestArtifactEditFormLoadsLatestText(t *testing.T) {
+	h := newWebHarness(t)
+	first := h.mustSaveText(globalSubspaceSelector, "plan/edit", "one\n")
+	latest := h.mustSaveText(globalSubspaceSelector, "plan/edit", "two\n")
+
+	rr := h.request(http.MethodGet, editURL(globalSubspaceSelector, first.Ref), nil, nil)
+	assertStatus(t, rr, http.StatusOK)
+
+	body := rr.Body.String()
+	if !strings.Contains(body, `name="baseRef" value="`+latest.Ref+`"`) {
+		t.Fatalf("expected latest ref as baseRef, body=%s", body)
+	}
+	if !strings.Contains(body, ">\ntwo\n</textarea>") {
+		t.Fatalf("expected latest text in textarea, body=%s", body)
+	}
+}
+
+func TestArtifactEditRejectsNonTextArtifacts(t *testing.T) {
+	h := newWebHarness(t)
+	blob, err := h.svc(globalSubspaceSelector).SaveBlob(context.Background(), artifacts.SaveBlobInput{
+		Name:     "files/blob",
+		Data:     []byte{1, 2, 3},
+		MimeType: "application/octet-stream",
+	})
+	if err != nil {
+		t.Fatalf("save blob: %v", err)
+	}
+
+	rr := h.request(http.MethodGet, editURL(globalSubspaceSelector, blob.Ref), nil, nil)
+	assertStatus(t, rr, http.StatusBadRequest)
+}
+
+func TestArtifactEditSavesWithExpectedPrevRef(t *testing.T) {
+	h := newWebHarness(t)
+	base := h.mustSaveText(globalSubspaceSelector, "plan/edit", "one\n")
+
+	rr := h.postForm("/artifact/edit", editForm("plan/edit", base.Ref, "one\ntwo\n"), true)
+	assertStatus(t, rr, http.StatusSeeOther)
+
+	saved, payload := h.mustGetByName(globalSubspaceSelector, "plan/edit")
+	if string(payload) != "one\ntwo\n" || saved.PrevRef != base.Ref {
+		t.Fatalf("unexpected save: prevRef=%q payload=%q", saved.PrevRef, payload)
+	}
+	assertRedirectContains(t, rr, "ref="+url.QueryEscape(saved.Ref))
+}
+
+func TestArtifactEditConflictShowsThreeWayMerge(t *testing.T) {
+	h := newWebHarness(t)
+	base := h.mustSaveText(globalSubspaceSelector, "plan/edit", "a\nb\nc\n")
+	theirs := h.mustSaveText(globalSubspaceSelector, "plan/edit", "a\ntheirs\nc\n")
+
+	rr := h.postForm("/artifact/edit", editForm("plan/edit", base.Ref, "a\nmine\nc\n"), true)
+	assertStatus(t, rr, http.StatusConflict)
+
+	body := html.UnescapeString(rr.Body.String())
+	if !strings.Contains(body, `name="baseRef" value="`+theirs.Ref+`"`) {
+		t.Fatalf("retry must build on the newer version, body=%s", body)
+	}
+	want := "a\n<<<<<<< yours\nmine\n||||||| base\nb\n=======\ntheirs\n>>>>>>> " + theirs.Ref + "\nc\n</textarea>"
+	if !strings.Contains(body, want) {
+		t.Fatalf("expected merged text with conflict markers, body=%s", body)
+	}
+	if !strings.Contains(body, `<td class="conflict">mine`) {
+		t.Fatalf("expected three-way conflict row, body=%s", body)
+	}
+
+	_, payload := h.mustGetByName(globalSubspaceSelector, "plan/edit")
+	if string(payload) != "a\ntheirs\nc\n" {
+		t.Fatalf("conflicting edit must not be saved, got %q", payload)
+	}
+}
+
+func TestArtifactEditRefusesUnresolvedConflictMarkers(t *testing.T) {
+	h := newWebHarness(t)
+	base := h.mustSaveText(globalSubspaceSelector, "plan/edit", "a\n")
+	text := "<<<<<<< yours\nmine\n=======\ntheirs\n>>>>>>> " + base.Ref + "\n"
+
+	rr := h.postForm("/artifact/edit", editForm("plan/edit", base.Ref, text), true)
+	assertStatus(t, rr, http.StatusUnprocessableEntity)
+	if !strings.Contains(rr.Body.String(), `name="keepMarkers"`) {
+		t.Fatalf("expected confirmation checkbox, body=%s", rr.Body.String())
+	}
+	if _, payload := h.mustGetByName(globalSubspaceSelector, "plan/edit"); string(payload) != "a\n" {
+		t.Fatalf("edit with markers must not be saved, got %q", payload)
+	}
+
+	form := editForm("plan/edit", base.Ref, text)
+	form.Set("keepMarkers", "1")
+	rr = h.postForm("/artifact/edit", form, true)
+	assertStatus(t, rr, http.StatusSeeOther)
+	if _, payload := h.mustGetByName(globalSubspaceSelector, "plan/edit"); string(payload) != text {
+		t.Fatalf("confirmed edit must be saved, got %q", payload)
+	}
+}
+
+func TestArtifactEditRejectsMissingCSRFAndForeignOrigin(t *testing.T) {
+	h := newWebHarness(t)
+	base := h.mustSaveText(globalSubspaceSelector, "plan/edit", "one\n")
+
+	rr := h.postForm("/artifact/edit", editForm("plan/edit", base.Ref, "two\n"), false)
+	assertStatus(t, rr, http.StatusForbidden)
+
+	form := editForm("plan/edit", base.Ref, "two\n")
+	token := mustCSRFToken(t)
+	form.Set(csrfFieldName, token)
+	rr = h.request(http.MethodPost, "/artifact/edit", strings.NewReader(form.Encode()), func(req *http.Request) {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", "https://attacker.invalid")
+		req.Host = "127.0.0.1:8080"
+		req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: token})
+	})
+	assertStatus(t, rr, http.StatusForbidden)
+
+	_, payload := h.mustGetByName(globalSubspaceSelector, "plan/edit")
+	if string(payload) != "one\n" {
+		t.Fatalf("rejected edit must not be saved, got %q", payload)
+	}
+}
@@ -0,0 +1,204 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+const (
+	liveEventSaved   = "saved"
+	liveEventDeleted = "deleted"
+	liveEventTodo    = "todo"
+)
+
+const liveSubscriberBuffer = 64
+const liveRetryMillis = 3000
+
+var liveKeepAliveInterval = 15 * time.Second
+
+type liveEvent struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Ref       string `json:"ref,omitempty"`
+	PrevRef   string `json:"prevRef,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	MimeType  string `json:"mimeType,omitempty"`
+	SizeBytes int64  `json:"sizeBytes,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+	At        string `json:"at"`
+}
+
+// liveFeed relays the writes of one subspace to the browsers subscribed to
+// it. It watches the subspace's service, so every version is reported as it
+// commits and nothing is read from the store. Writes that bypass the service,
+// such as another process opening the store directly, are not reported.
+type liveFeed struct {
+	unwatch func()
+
+	mu          sync.Mutex
+	subscribers map[chan liveEvent]struct{}
+}
+
+func (s *Server) subscribeLive(subspace string, svc *artifacts.Service) (<-chan liveEvent, func()) {
+	ch := make(chan liveEvent, liveSubscriberBuffer)
+
+	s.liveMu.Lock()
+	feed := s.liveFeeds[subspace]
+	if feed == nil {
+		feed = &liveFeed{subscribers: map[chan liveEvent]struct{}{}}
+		feed.unwatch = svc.Watch(feed.publish)
+		s.liveFeeds[subspace] = feed
+	}
+	feed.mu.Lock()
+	feed.subscribers[ch] = struct{}{}
+	feed.mu.Unlock()
+	s.liveMu.Unlock()
+
+	unsubscribe := func() {
+		s.liveMu.Lock()
+		defer s.liveMu.Unlock()
+		feed.mu.Lock()
+		if _, ok := feed.subscribers[ch]; ok {
+			delete(feed.subscribers, ch)
+			close(ch)
+		}
+		remaining := len(feed.subscribers)
+		feed.mu.Unlock()
+		if remaining == 0 && s.liveFeeds[subspace] == feed {
+			feed.unwatch()
+			delete(s.liveFeeds, subspace)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// CloseStreams ends every open live-update stream. http.Server.Shutdown
+// waits for active requests, so callers register this with
+// RegisterOnShutdown.
+func (s *Server) CloseStreams() {
+	s.liveMu.Lock()
+	defer s.liveMu.Unlock()
+	for subspace, feed := range s.liveFeeds {
+		feed.unwatch()
+		feed.mu.Lock()
+		for ch := range feed.subscribers {
+			delete(feed.subscribers, ch)
+			close(ch)
+		}
+		feed.mu.Unlock()
+		delete(s.liveFeeds, subspace)
+	}
+}
+
+func (f *liveFeed) publish(v artifacts.ArtifactVersion) {
+	f.broadcast(liveEventFor(v))
+}
+
+// broadcast drops subscribers that have fallen behind; their stream ends and
+// the browser reconnects with a fresh page state.
+func (f *liveFeed) broadcast(event liveEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// liveEventFor describes a written version: a delete for a tombstone, a todo
+// update for todo lists and a save otherwise.
+func liveEventFor(v artifacts.ArtifactVersion) liveEvent {
+	at := time.Now().UTC().Format(time.RFC3339)
+	if v.Tombstone {
+		return liveEvent{Type: liveEventDeleted, Name: v.Name, PrevRef: v.PrevRef, At: at}
+	}
+	eventType := liveEventSaved
+	if isTodoArtifactName(v.Name) {
+		eventType = liveEventTodo
+	}
+	return liveEvent{
+		Type:      eventType,
+		Name:      v.Name,
+		Ref:       v.Ref,
+		PrevRef:   v.PrevRef,
+		Kind:      string(v.Kind),
+		MimeType:  v.MimeType,
+		SizeBytes: v.SizeBytes,
+		CreatedAt: v.CreatedAt.Format(time.RFC3339),
+		At:        at,
+	}
+}
+
+func isTodoArtifactName(name string) bool {
+	return name == "todo" || strings.HasSuffix(name, "/todo")
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "streaming unsupported"})
+		return
+	}
+
+	subspace := normalizeSubspaceSelector(r.URL.Query().Get("subspace"))
+	if subspace == "" {
+		subspace = globalSubspaceSelector
+	}
+	svc, err := s.serviceFromQuerySubspace(subspace)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	events, unsubscribe := s.subscribeLive(subspace, svc)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	ready, _ := json.Marshal(map[string]string{"subspace": subspace})
+	if _, err := fmt.Fprintf(w, "retry: %d\nevent: ready\ndata: %s\n\n", liveRetryMillis, ready); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(liveKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			payload, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: artifact\ndata: %s\n\n", payload); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
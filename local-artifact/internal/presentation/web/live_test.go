@@ -0,0 +1,151 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+func TestLiveEventForReportsSavesTodosAndDeletes(t *testing.T) {
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		version artifacts.ArtifactVersion
+		want    liveEvent
+	}{
+		{
+			version: artifacts.ArtifactVersion{Name: "plan/a", Ref: "r2", PrevRef: "r1", Kind: artifacts.ArtifactKindText, CreatedAt: base},
+			want:    liveEvent{Type: liveEventSaved, Name: "plan/a", Ref: "r2", PrevRef: "r1", Kind: "text"},
+		},
+		{
+			version: artifacts.ArtifactVersion{Name: "plan/todo", Ref: "r3", CreatedAt: base},
+			want:    liveEvent{Type: liveEventTodo, Name: "plan/todo", Ref: "r3"},
+		},
+		{
+			version: artifacts.ArtifactVersion{Name: "plan/gone", Ref: "r5", PrevRef: "r4", Tombstone: true, CreatedAt: base},
+			want:    liveEvent{Type: liveEventDeleted, Name: "plan/gone", PrevRef: "r4"},
+		},
+	}
+	for _, tc := range tests {
+		got := liveEventFor(tc.version)
+		if got.Type != tc.want.Type || got.Name != tc.want.Name || got.Ref != tc.want.Ref || got.PrevRef != tc.want.PrevRef || got.Kind != tc.want.Kind {
+			t.Fatalf("liveEventFor(%+v)=%+v want %+v", tc.version, got, tc.want)
+		}
+	}
+}
+
+func TestEventsStreamPushesArtifactChanges(t *testing.T) {
+	h := newWebHarness(t)
+	h.mustSaveText(globalSubspaceSelector, "plan/existing", "v1")
+	srv := httptest.NewServer(h.s.Handler())
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events?subspace=global", nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("content-type=%q", got)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if name, _ := readSSEEvent(t, reader); name != "ready" {
+		t.Fatalf("first event=%q want ready", name)
+	}
+	// The feed is subscribed before ready is sent, and back-to-back saves are
+	// each reported.
+	v2 := h.mustSaveText(globalSubspaceSelector, "plan/existing", "v2")
+	v3 := h.mustSaveText(globalSubspaceSelector, "plan/existing", "v3")
+	for _, want := range []artifacts.ArtifactVersion{v2, v3} {
+		event := readLiveEvent(t, reader)
+		if event.Type != liveEventSaved || event.Name != "plan/existing" || event.Ref != want.Ref || event.PrevRef != want.PrevRef {
+			t.Fatalf("unexpected save event: %+v want ref %s", event, want.Ref)
+		}
+	}
+
+	h.mustSaveText(globalSubspaceSelector, "plan/todo", `[{"id":1,"title":"x","status":"not-started"}]`)
+	if event := readLiveEvent(t, reader); event.Type != liveEventTodo || event.Name != "plan/todo" {
+		t.Fatalf("unexpected todo event: %+v", event)
+	}
+
+	if _, err := h.svc(globalSubspaceSelector).Delete(context.Background(), artifacts.Selector{Name: "plan/existing"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if event := readLiveEvent(t, reader); event.Type != liveEventDeleted || event.Name != "plan/existing" {
+		t.Fatalf("unexpected delete event: %+v", event)
+	}
+}
+
+func TestEventsRejectsInvalidSubspace(t *testing.T) {
+	h := newWebHarness(t)
+
+	rr := h.request(http.MethodGet, "/events?subspace=not-a-hash", nil, nil)
+	assertStatus(t, rr, http.StatusBadRequest)
+}
+
+func readLiveEvent(t *testing.T, reader *bufio.Reader) liveEvent {
+	t.Helper()
+	name, data := readSSEEvent(t, reader)
+	if name != "artifact" {
+		t.Fatalf("event=%q want artifact", name)
+	}
+	var event liveEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatalf("decode event %q: %v", data, err)
+	}
+	return event
+}
+
+func readSSEEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+	type result struct {
+		name, data string
+		err        error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var name, data string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				done <- result{err: err}
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case line == "":
+				if name != "" || data != "" {
+					done <- result{name: name, data: data}
+					return
+				}
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatalf("read stream: %v", res.err)
+		}
+		return res.name, res.data
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for event")
+		return "", ""
+	}
+}
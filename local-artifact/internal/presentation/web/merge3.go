@@ -0,0 +1,185 @@
+package web
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	mergeBlockSame     = "same"
+	mergeBlockOurs     = "ours"
+	mergeBlockTheirs   = "theirs"
+	mergeBlockBoth     = "both"
+	mergeBlockConflict = "conflict"
+)
+
+// mergeHunk replaces base[Start:End] with Lines on one side of a merge.
+type mergeHunk struct {
+	Start int
+	End   int
+	Lines []string
+	ours  bool
+}
+
+type mergeBlock struct {
+	Kind   string
+	Base   []string
+	Ours   []string
+	Theirs []string
+}
+
+type mergeResult struct {
+	Blocks    []mergeBlock
+	Conflicts int
+}
+
+// merge3 is a line-based three-way merge in the style of diff3. Changes that
+// touch or overlap on both sides become conflicts unless they are identical.
+// ok is false when either diff is too large to compute.
+func merge3(base, ours, theirs string) (mergeResult, bool) {
+	baseLines := splitDiffLines(base)
+	ourHunks, ok := mergeHunks(base, ours, true)
+	if !ok {
+		return mergeResult{}, false
+	}
+	theirHunks, ok := mergeHunks(base, theirs, false)
+	if !ok {
+		return mergeResult{}, false
+	}
+	hunks := append(ourHunks, theirHunks...)
+	sort.SliceStable(hunks, func(i, j int) bool {
+		if hunks[i].Start != hunks[j].Start {
+			return hunks[i].Start < hunks[j].Start
+		}
+		return hunks[i].End < hunks[j].End
+	})
+
+	var result mergeResult
+	pos := 0
+	for i := 0; i < len(hunks); {
+		start, end := hunks[i].Start, hunks[i].End
+		cluster := []mergeHunk{hunks[i]}
+		i++
+		for i < len(hunks) && hunks[i].Start <= end {
+			end = max(end, hunks[i].End)
+			cluster = append(cluster, hunks[i])
+			i++
+		}
+		if pos < start {
+			result.Blocks = append(result.Blocks, mergeBlock{Kind: mergeBlockSame, Base: baseLines[pos:start]})
+		}
+
+		var ourSide, theirSide []mergeHunk
+		for _, h := range cluster {
+			if h.ours {
+				ourSide = append(ourSide, h)
+			} else {
+				theirSide = append(theirSide, h)
+			}
+		}
+		block := mergeBlock{
+			Base:   baseLines[start:end],
+			Ours:   applyMergeHunks(baseLines, start, end, ourSide),
+			Theirs: applyMergeHunks(baseLines, start, end, theirSide),
+		}
+		switch {
+		case len(theirSide) == 0:
+			block.Kind = mergeBlockOurs
+		case len(ourSide) == 0:
+			block.Kind = mergeBlockTheirs
+		case equalLines(block.Ours, block.Theirs):
+			block.Kind = mergeBlockBoth
+		default:
+			block.Kind = mergeBlockConflict
+			result.Conflicts++
+		}
+		result.Blocks = append(result.Blocks, block)
+		pos = end
+	}
+	if pos < len(baseLines) {
+		result.Blocks = append(result.Blocks, mergeBlock{Kind: mergeBlockSame, Base: baseLines[pos:]})
+	}
+	return result, true
+}
+
+func mergeHunks(base, side string, ours bool) ([]mergeHunk, bool) {
+	lines, ok := diffLines(base, side)
+	if !ok {
+		return nil, false
+	}
+	var hunks []mergeHunk
+	basePos := 0
+	for i := 0; i < len(lines); {
+		if lines[i].Op == diffEqual {
+			basePos = lines[i].Old
+			i++
+			continue
+		}
+		h := mergeHunk{Start: basePos, End: basePos, ours: ours}
+		for i < len(lines) && lines[i].Op != diffEqual {
+			if lines[i].Op == diffDelete {
+				h.End = lines[i].Old
+			} else {
+				h.Lines = append(h.Lines, lines[i].Text)
+			}
+			i++
+		}
+		basePos = h.End
+		hunks = append(hunks, h)
+	}
+	return hunks, true
+}
+
+func applyMergeHunks(base []string, start, end int, hunks []mergeHunk) []string {
+	out := []string{}
+	pos := start
+	for _, h := range hunks {
+		out = append(out, base[pos:h.Start]...)
+		out = append(out, h.Lines...)
+		pos = h.End
+	}
+	return append(out, base[pos:end]...)
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Text renders the merge with diff3-style conflict markers so the user can
+// resolve the remaining conflicts by hand.
+func (m mergeResult) Text(theirLabel string) string {
+	var b strings.Builder
+	write := func(lines []string) {
+		for _, line := range lines {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	for _, block := range m.Blocks {
+		switch block.Kind {
+		case mergeBlockSame:
+			write(block.Base)
+		case mergeBlockOurs, mergeBlockBoth:
+			write(block.Ours)
+		case mergeBlockTheirs:
+			write(block.Theirs)
+		default:
+			b.WriteString("<<<<<<< yours\n")
+			write(block.Ours)
+			b.WriteString("||||||| base\n")
+			write(block.Base)
+			b.WriteString("=======\n")
+			write(block.Theirs)
+			b.WriteString(">>>>>>> " + theirLabel + "\n")
+		}
+	}
+	return b.String()
+}
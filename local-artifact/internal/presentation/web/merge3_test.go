@@ -0,0 +1,44 @@
+package web
+
+import "testing"
+
+func TestMerge3AppliesNonOverlappingChanges(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	ours := "A\nb\nc\nd\ne\n"
+	theirs := "a\nb\nc\nd\nE\nf\n"
+
+	merged, ok := merge3(base, ours, theirs)
+	if !ok {
+		t.Fatalf("merge3 reported too large")
+	}
+	if merged.Conflicts != 0 {
+		t.Fatalf("conflicts=%d want 0", merged.Conflicts)
+	}
+	if got, want := merged.Text("r2"), "A\nb\nc\nd\nE\nf\n"; got != want {
+		t.Fatalf("merged text=%q want %q", got, want)
+	}
+}
+
+func TestMerge3IdenticalChangesDoNotConflict(t *testing.T) {
+	merged, ok := merge3("a\nb\n", "a\nB\n", "a\nB\n")
+	if !ok || merged.Conflicts != 0 {
+		t.Fatalf("ok=%v conflicts=%d", ok, merged.Conflicts)
+	}
+	if got := merged.Text("r2"); got != "a\nB\n" {
+		t.Fatalf("merged text=%q", got)
+	}
+}
+
+func TestMerge3MarksOverlappingChanges(t *testing.T) {
+	merged, ok := merge3("a\nb\nc\n", "a\nmine\nc\n", "a\ntheirs\nc\n")
+	if !ok {
+		t.Fatalf("merge3 reported too large")
+	}
+	if merged.Conflicts != 1 {
+		t.Fatalf("conflicts=%d want 1", merged.Conflicts)
+	}
+	want := "a\n<<<<<<< yours\nmine\n||||||| base\nb\n=======\ntheirs\n>>>>>>> r2\nc\n"
+	if got := merged.Text("r2"); got != want {
+		t.Fatalf("merged text=%q want %q", got, want)
+	}
+}
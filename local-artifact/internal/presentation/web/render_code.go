@@ -0,0 +1,267 @@
+package web
+
+import (
+	"html"
+	"html/template"
+	"path/filepath"
+	"strings"
+)
+
+// codeLanguage is just enough about a language to colour keywords, strings,
+// comments and numbers.
+type codeLanguage struct {
+	name          string
+	keywords      map[string]struct{}
+	literals      map[string]struct{}
+	lineComments  []string
+	blockComments [][2]string
+	quotes        string
+	jsonKeys      bool
+}
+
+func words(s string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, w := range strings.Fields(s) {
+		set[w] = struct{}{}
+	}
+	return set
+}
+
+var codeLanguages = map[string]*codeLanguage{
+	"go": {
+		name:          "go",
+		keywords:      words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var"),
+		literals:      words("true false nil iota"),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        "\"'`",
+	},
+	"javascript": {
+		name:          "javascript",
+		keywords:      words("async await break case catch class const continue debugger default delete do else export extends finally for from function if import in instanceof interface let new of return static super switch this throw try type typeof var void while with yield"),
+		literals:      words("true false null undefined NaN Infinity"),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        "\"'`",
+	},
+	"python": {
+		name:         "python",
+		keywords:     words("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield"),
+		literals:     words("True False None self"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	},
+	"shell": {
+		name:         "shell",
+		keywords:     words("case do done elif else esac export fi for function if in local readonly return select set then until while"),
+		literals:     words("true false"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	},
+	"rust": {
+		name:          "rust",
+		keywords:      words("as async await break const continue crate dyn else enum extern fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while"),
+		literals:      words("true false None Some Ok Err"),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        "\"",
+	},
+	"c": {
+		name:          "c",
+		keywords:      words("abstract auto break case catch char class const continue default delete do double else enum extends extern final finally float for goto if implements import int long namespace new package private protected public register return short signed sizeof static struct switch template this throw throws try typedef union unsigned using virtual void volatile while"),
+		literals:      words("true false null nullptr NULL"),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        "\"'",
+	},
+	"sql": {
+		name:          "sql",
+		keywords:      words("select from where and or not insert into values update set delete create table index view drop alter add join left right inner outer on group by order having limit offset as distinct union all primary key foreign references default begin commit rollback SELECT FROM WHERE AND OR NOT INSERT INTO VALUES UPDATE SET DELETE CREATE TABLE INDEX VIEW DROP ALTER ADD JOIN LEFT RIGHT INNER OUTER ON GROUP BY ORDER HAVING LIMIT OFFSET AS DISTINCT UNION ALL PRIMARY KEY FOREIGN REFERENCES DEFAULT BEGIN COMMIT ROLLBACK"),
+		literals:      words("null true false NULL TRUE FALSE"),
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        "'\"",
+	},
+	"yaml": {
+		name:         "yaml",
+		literals:     words("true false null yes no on off ~"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	},
+	"json": {
+		name:     "json",
+		literals: words("true false null"),
+		quotes:   "\"",
+		jsonKeys: true,
+	},
+}
+
+var codeLanguageAliases = map[string]string{
+	"golang": "go", "js": "javascript", "jsx": "javascript", "ts": "javascript", "tsx": "javascript",
+	"typescript": "javascript", "mjs": "javascript", "cjs": "javascript", "py": "python",
+	"sh": "shell", "bash": "shell", "zsh": "shell", "console": "shell", "shellscript": "shell",
+	"rs": "rust", "cpp": "c", "c++": "c", "h": "c", "hpp": "c", "cc": "c", "java": "c", "cs": "c",
+	"csharp": "c", "kotlin": "c", "kt": "c", "swift": "c", "yml": "yaml", "jsonc": "json",
+}
+
+var codeLanguageByMime = map[string]string{
+	"text/x-go":              "go",
+	"application/javascript": "javascript",
+	"text/javascript":        "javascript",
+	"application/typescript": "javascript",
+	"text/typescript":        "javascript",
+	"text/x-python":          "python",
+	"application/x-python":   "python",
+	"application/x-sh":       "shell",
+	"text/x-sh":              "shell",
+	"text/x-shellscript":     "shell",
+	"text/x-rust":            "rust",
+	"text/x-c":               "c",
+	"text/x-csrc":            "c",
+	"text/x-c++src":          "c",
+	"text/x-java":            "c",
+	"text/x-java-source":     "c",
+	"application/sql":        "sql",
+	"text/x-sql":             "sql",
+	"application/x-yaml":     "yaml",
+	"application/yaml":       "yaml",
+	"text/yaml":              "yaml",
+	"text/x-yaml":            "yaml",
+	"application/json":       "json",
+	"application/ld+json":    "json",
+}
+
+func languageForFence(lang string) *codeLanguage {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if alias, ok := codeLanguageAliases[lang]; ok {
+		lang = alias
+	}
+	return codeLanguages[lang]
+}
+
+// languageForArtifact picks a language from the mime type, falling back to
+// the filename extension.
+func languageForArtifact(mimeType, filename string) *codeLanguage {
+	if name, ok := codeLanguageByMime[normalizeMimeType(mimeType)]; ok {
+		return codeLanguages[name]
+	}
+	if ext := strings.TrimPrefix(filepath.Ext(filename), "."); ext != "" {
+		if lang := languageForFence(ext); lang != nil {
+			return lang
+		}
+	}
+	return nil
+}
+
+func normalizeMimeType(mimeType string) string {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if idx := strings.IndexByte(mimeType, ';'); idx >= 0 {
+		mimeType = strings.TrimSpace(mimeType[:idx])
+	}
+	return mimeType
+}
+
+// highlightCode escapes src and wraps tokens in spans with the classes
+// tok-kw, tok-lit, tok-str, tok-key, tok-com and tok-num. A nil language
+// only escapes.
+func highlightCode(lang *codeLanguage, src string) template.HTML {
+	if lang == nil {
+		return template.HTML(html.EscapeString(src))
+	}
+	var b strings.Builder
+	span := func(class, text string) {
+		b.WriteString(`<span class="tok-` + class + `">` + html.EscapeString(text) + "</span>")
+	}
+
+	for i := 0; i < len(src); {
+		rest := src[i:]
+		if comment, ok := matchComment(lang, rest); ok {
+			span("com", comment)
+			i += len(comment)
+			continue
+		}
+		c := src[i]
+		if strings.IndexByte(lang.quotes, c) >= 0 {
+			end := scanString(rest, c)
+			class := "str"
+			if lang.jsonKeys && strings.HasPrefix(strings.TrimLeft(src[i+end:], " \t"), ":") {
+				class = "key"
+			}
+			span(class, rest[:end])
+			i += end
+			continue
+		}
+		if isDigit(c) && (i == 0 || !isWordByte(src[i-1])) {
+			end := 1
+			for end < len(rest) && (isWordByte(rest[end]) || rest[end] == '.') {
+				end++
+			}
+			span("num", rest[:end])
+			i += end
+			continue
+		}
+		if isWordByte(c) && c < 0x80 {
+			end := 1
+			for end < len(rest) && isWordByte(rest[end]) && rest[end] < 0x80 {
+				end++
+			}
+			word := rest[:end]
+			if _, ok := lang.keywords[word]; ok {
+				span("kw", word)
+			} else if _, ok := lang.literals[word]; ok {
+				span("lit", word)
+			} else {
+				b.WriteString(html.EscapeString(word))
+			}
+			i += end
+			continue
+		}
+		b.WriteString(html.EscapeString(src[i : i+1]))
+		i++
+	}
+	return template.HTML(b.String())
+}
+
+func matchComment(lang *codeLanguage, rest string) (string, bool) {
+	for _, prefix := range lang.lineComments {
+		if strings.HasPrefix(rest, prefix) {
+			if end := strings.IndexByte(rest, '\n'); end >= 0 {
+				return rest[:end], true
+			}
+			return rest, true
+		}
+	}
+	for _, pair := range lang.blockComments {
+		if strings.HasPrefix(rest, pair[0]) {
+			if end := strings.Index(rest[len(pair[0]):], pair[1]); end >= 0 {
+				return rest[:len(pair[0])+end+len(pair[1])], true
+			}
+			return rest, true
+		}
+	}
+	return "", false
+}
+
+// scanString returns the length of the string literal opening rest. Only
+// backtick strings may span lines.
+func scanString(rest string, quote byte) int {
+	for j := 1; j < len(rest); j++ {
+		switch rest[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case '\n':
+			if quote != '`' {
+				return j
+			}
+		case quote:
+			return j + 1
+		}
+	}
+	return len(rest)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
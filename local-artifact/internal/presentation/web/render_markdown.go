@@ -0,0 +1,543 @@
+package web
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// renderMarkdown turns markdown into HTML. Raw HTML in the source is escaped,
+// never passed through, and links keep only http, https, mailto and relative
+// targets, so the output is safe to embed as is.
+func renderMarkdown(src string) template.HTML {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var b strings.Builder
+	renderMarkdownBlocks(&b, strings.Split(src, "\n"), false)
+	return template.HTML(b.String())
+}
+
+var (
+	mdHeadingPattern   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?[ \t#]*$`)
+	mdFencePattern     = regexp.MustCompile("^ {0,3}(```+|~~~+)[ \t]*([^`\\s]*)")
+	mdRulePattern      = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdListItemPattern  = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$`)
+	mdQuotePattern     = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	mdTableSepPattern  = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdTaskPattern      = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	mdInlineURLPattern = regexp.MustCompile(`^https?://[^\s<>]*[^\s<>.,:;!?'")\]]`)
+)
+
+// renderMarkdownBlocks renders lines as block elements. tight drops the <p>
+// around paragraphs, as inside compact list items.
+func renderMarkdownBlocks(b *strings.Builder, lines []string, tight bool) {
+	var para []string
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		text := renderMarkdownInline(strings.Join(para, "\n"))
+		if tight {
+			b.WriteString(text)
+		} else {
+			b.WriteString("<p>" + text + "</p>\n")
+		}
+		para = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		if m := mdFencePattern.FindStringSubmatch(line); m != nil {
+			flush()
+			fence := m[1]
+			var body []string
+			i++
+			for ; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) && strings.Trim(strings.TrimSpace(lines[i]), fence[:1]) == "" {
+					break
+				}
+				body = append(body, lines[i])
+			}
+			writeCodeBlock(b, m[2], strings.Join(body, "\n"))
+			continue
+		}
+
+		if m := mdHeadingPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + renderMarkdownInline(m[2]) + "</h" + level + ">\n")
+			continue
+		}
+
+		if mdRulePattern.MatchString(line) {
+			flush()
+			b.WriteString("<hr>\n")
+			continue
+		}
+
+		if mdQuotePattern.MatchString(line) {
+			flush()
+			var quoted []string
+			for ; i < len(lines); i++ {
+				m := mdQuotePattern.FindStringSubmatch(lines[i])
+				if m == nil {
+					break
+				}
+				quoted = append(quoted, m[1])
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			renderMarkdownBlocks(b, quoted, false)
+			b.WriteString("</blockquote>\n")
+			continue
+		}
+
+		if mdListItemPattern.MatchString(line) && (len(para) == 0 || isListStart(line)) {
+			flush()
+			end := listBlockEnd(lines, i)
+			writeList(b, lines[i:end])
+			i = end - 1
+			continue
+		}
+
+		if strings.Contains(line, "|") && i+1 < len(lines) && mdTableSepPattern.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-") {
+			flush()
+			end := i + 2
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "" && strings.Contains(lines[end], "|") {
+				end++
+			}
+			writeTable(b, lines[i], lines[i+1], lines[i+2:end])
+			i = end - 1
+			continue
+		}
+
+		para = append(para, strings.TrimSpace(line))
+	}
+	flush()
+}
+
+// isListStart reports whether a list line may interrupt a paragraph: bullets
+// always can, ordered lists only when they start at 1.
+func isListStart(line string) bool {
+	m := mdListItemPattern.FindStringSubmatch(line)
+	if m == nil || strings.TrimSpace(m[3]) == "" {
+		return false
+	}
+	marker := m[2]
+	if marker == "-" || marker == "*" || marker == "+" {
+		return true
+	}
+	return strings.TrimRight(marker, ".)") == "1"
+}
+
+// listBlockEnd returns the index just past the list that starts at start.
+// Indented lines and lazy continuations belong to the list; a blank line ends
+// it unless the list carries on after it.
+func listBlockEnd(lines []string, start int) int {
+	baseIndent := indentWidth(lines[start])
+	i := start + 1
+	for i < len(lines) {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			next := i + 1
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next < len(lines) && (indentWidth(lines[next]) > baseIndent || (mdListItemPattern.MatchString(lines[next]) && indentWidth(lines[next]) == baseIndent)) {
+				i = next
+				continue
+			}
+			return i
+		}
+		if indentWidth(line) <= baseIndent && !mdListItemPattern.MatchString(line) &&
+			(mdHeadingPattern.MatchString(line) || mdFencePattern.MatchString(line) || mdRulePattern.MatchString(line) || mdQuotePattern.MatchString(line)) {
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+func writeList(b *strings.Builder, lines []string) {
+	first := mdListItemPattern.FindStringSubmatch(lines[0])
+	baseIndent := indentWidth(lines[0])
+	ordered := first[2] != "-" && first[2] != "*" && first[2] != "+"
+
+	type listItem struct {
+		body  []string
+		loose bool
+	}
+	var items []*listItem
+	var current *listItem
+	for _, line := range lines {
+		if m := mdListItemPattern.FindStringSubmatch(line); m != nil && indentWidth(line) == baseIndent {
+			current = &listItem{body: []string{m[3]}}
+			items = append(items, current)
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			current.body = append(current.body, "")
+			continue
+		}
+		current.body = append(current.body, dedent(line, baseIndent+2))
+	}
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	b.WriteString("<" + tag)
+	if ordered {
+		if start, err := strconv.Atoi(strings.TrimRight(first[2], ".)")); err == nil && start != 1 {
+			b.WriteString(` start="` + strconv.Itoa(start) + `"`)
+		}
+	}
+	b.WriteString(">\n")
+	for _, item := range items {
+		for len(item.body) > 0 && item.body[len(item.body)-1] == "" {
+			item.body = item.body[:len(item.body)-1]
+		}
+		for _, line := range item.body {
+			if line == "" {
+				item.loose = true
+			}
+		}
+		b.WriteString("<li")
+		if m := mdTaskPattern.FindStringSubmatch(item.body[0]); m != nil {
+			item.body[0] = item.body[0][len(m[0]):]
+			b.WriteString(` class="task"><input type="checkbox" disabled`)
+			if m[1] != " " {
+				b.WriteString(" checked")
+			}
+			b.WriteString("> ")
+		} else {
+			b.WriteString(">")
+		}
+		renderMarkdownBlocks(b, item.body, !item.loose)
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+}
+
+func writeTable(b *strings.Builder, header, separator string, rows []string) {
+	var aligns []string
+	for _, cell := range splitTableRow(separator) {
+		cell = strings.TrimSpace(cell)
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "right")
+		case strings.HasPrefix(cell, ":"):
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+	writeRow := func(cells []string, tag string) {
+		b.WriteString("<tr>")
+		for idx := range aligns {
+			cell := ""
+			if idx < len(cells) {
+				cell = cells[idx]
+			}
+			b.WriteString("<" + tag)
+			if aligns[idx] != "" {
+				b.WriteString(` style="text-align:` + aligns[idx] + `"`)
+			}
+			b.WriteString(">" + renderMarkdownInline(strings.TrimSpace(cell)) + "</" + tag + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("<table>\n<thead>\n")
+	writeRow(splitTableRow(header), "th")
+	b.WriteString("</thead>\n<tbody>\n")
+	for _, row := range rows {
+		writeRow(splitTableRow(row), "td")
+	}
+	b.WriteString("</tbody>\n</table>\n")
+}
+
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		if row[i] == '\\' && i+1 < len(row) && row[i+1] == '|' {
+			cell.WriteByte('|')
+			i++
+			continue
+		}
+		if row[i] == '|' {
+			cells = append(cells, cell.String())
+			cell.Reset()
+			continue
+		}
+		cell.WriteByte(row[i])
+	}
+	return append(cells, cell.String())
+}
+
+func writeCodeBlock(b *strings.Builder, lang, body string) {
+	b.WriteString(`<pre class="code"`)
+	if lang != "" {
+		b.WriteString(` data-lang="` + html.EscapeString(lang) + `"`)
+	}
+	b.WriteString("><code>")
+	b.WriteString(string(highlightCode(languageForFence(lang), body)))
+	b.WriteString("</code></pre>\n")
+}
+
+func indentWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+func dedent(line string, width int) string {
+	removed := 0
+	for i, r := range line {
+		if removed >= width || (r != ' ' && r != '\t') {
+			return line[i:]
+		}
+		if r == '\t' {
+			removed += 4 - removed%4
+		} else {
+			removed++
+		}
+	}
+	return ""
+}
+
+// renderMarkdownInline renders emphasis, code spans, links and autolinks and
+// escapes everything else.
+func renderMarkdownInline(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isMarkdownPunct(text[i+1]):
+			b.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '\\' && i+1 < len(text) && text[i+1] == '\n':
+			b.WriteString("<br>\n")
+			i += 2
+			continue
+		case c == '`':
+			run := countRun(text[i:], '`')
+			closer := strings.Index(text[i+run:], strings.Repeat("`", run))
+			if closer >= 0 {
+				code := text[i+run : i+run+closer]
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += run + closer + run
+				continue
+			}
+			b.WriteString(strings.Repeat("`", run))
+			i += run
+			continue
+		case c == '*' || c == '_' || c == '~':
+			if n, ok := renderEmphasis(&b, text, i); ok {
+				i = n
+				continue
+			}
+		case c == '!' && i+1 < len(text) && text[i+1] == '[':
+			if label, target, n, ok := parseMarkdownLink(text, i+1); ok {
+				// Remote images are not fetched; the image becomes a link.
+				writeMarkdownLink(&b, target, html.EscapeString(label))
+				i = n
+				continue
+			}
+		case c == '[':
+			if label, target, n, ok := parseMarkdownLink(text, i); ok {
+				writeMarkdownLink(&b, target, renderMarkdownInline(label))
+				i = n
+				continue
+			}
+		case c == '<':
+			if end := strings.IndexByte(text[i:], '>'); end > 0 {
+				inner := text[i+1 : i+end]
+				if safe, ok := safeMarkdownURL(inner); ok && strings.Contains(inner, ":") && !strings.ContainsAny(inner, " \t\n") {
+					b.WriteString(`<a href="` + html.EscapeString(safe) + `" rel="noopener noreferrer nofollow">` + html.EscapeString(inner) + "</a>")
+					i += end + 1
+					continue
+				}
+			}
+		case c == 'h' && (i == 0 || !isWordByte(text[i-1])):
+			if m := mdInlineURLPattern.FindString(text[i:]); m != "" {
+				b.WriteString(`<a href="` + html.EscapeString(m) + `" rel="noopener noreferrer nofollow">` + html.EscapeString(m) + "</a>")
+				i += len(m)
+				continue
+			}
+		case c == ' ' && strings.HasPrefix(text[i:], "  \n"):
+			b.WriteString("<br>\n")
+			i += 3
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		b.WriteString(html.EscapeString(text[i : i+size]))
+		i += size
+	}
+	return b.String()
+}
+
+// renderEmphasis handles *em*, **strong**, _em_, __strong__ and ~~del~~
+// starting at i, returning the index after the closing delimiter.
+func renderEmphasis(b *strings.Builder, text string, i int) (int, bool) {
+	c := text[i]
+	run := countRun(text[i:], c)
+	if c == '~' && run != 2 {
+		return 0, false
+	}
+	if run > 2 {
+		run = 2
+	}
+	if c == '_' && i > 0 && isWordByte(text[i-1]) {
+		return 0, false
+	}
+	delim := strings.Repeat(string(c), run)
+	start := i + run
+	if start >= len(text) || unicode.IsSpace(rune(text[start])) {
+		return 0, false
+	}
+	for search := start; ; {
+		idx := strings.Index(text[search:], delim)
+		if idx < 0 {
+			return 0, false
+		}
+		closeAt := search + idx
+		after := closeAt + run
+		validClose := closeAt > start && !unicode.IsSpace(rune(text[closeAt-1])) &&
+			(after >= len(text) || text[after] != c) &&
+			(c != '_' || after >= len(text) || !isWordByte(text[after]))
+		if validClose {
+			inner := renderMarkdownInline(text[start:closeAt])
+			switch {
+			case c == '~':
+				b.WriteString("<del>" + inner + "</del>")
+			case run == 2:
+				b.WriteString("<strong>" + inner + "</strong>")
+			default:
+				b.WriteString("<em>" + inner + "</em>")
+			}
+			return after, true
+		}
+		search = closeAt + 1
+		for search < len(text) && text[search] == c {
+			search++
+		}
+	}
+}
+
+// parseMarkdownLink parses [label](target "title") at i.
+func parseMarkdownLink(text string, i int) (label, target string, next int, ok bool) {
+	depth := 0
+	closeLabel := -1
+	for j := i; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeLabel = j
+			}
+		}
+		if closeLabel >= 0 {
+			break
+		}
+	}
+	if closeLabel < 0 || closeLabel+1 >= len(text) || text[closeLabel+1] != '(' {
+		return "", "", 0, false
+	}
+	closeTarget := strings.IndexByte(text[closeLabel+2:], ')')
+	if closeTarget < 0 {
+		return "", "", 0, false
+	}
+	target = strings.TrimSpace(text[closeLabel+2 : closeLabel+2+closeTarget])
+	if sp := strings.IndexAny(target, " \t"); sp >= 0 {
+		target = target[:sp]
+	}
+	target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+	return text[i+1 : closeLabel], target, closeLabel + 3 + closeTarget, true
+}
+
+func writeMarkdownLink(b *strings.Builder, target, labelHTML string) {
+	safe, ok := safeMarkdownURL(target)
+	if !ok {
+		b.WriteString(labelHTML)
+		return
+	}
+	b.WriteString(`<a href="` + html.EscapeString(safe) + `" rel="noopener noreferrer nofollow">` + labelHTML + "</a>")
+}
+
+// safeMarkdownURL allows http, https and mailto links plus scheme-less
+// relative ones.
+func safeMarkdownURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.ContainsAny(raw, "\x00\n\r") {
+		return "", false
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https", "mailto":
+		return parsed.String(), true
+	case "":
+		if parsed.Host != "" || strings.HasPrefix(raw, "//") {
+			return "", false
+		}
+		return parsed.String(), true
+	default:
+		return "", false
+	}
+}
+
+func countRun(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+func isMarkdownPunct(c byte) bool {
+	return strings.IndexByte("\\`*_{}[]()#+-.!|~<>\"'", c) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
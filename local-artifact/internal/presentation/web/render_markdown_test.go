@@ -0,0 +1,98 @@
+package web
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownEscapesRawHTML(t *testing.T) {
+	out := string(renderMarkdown("# Title\n\n<script>alert(1)</script>\n\n<img src=x onerror=alert(1)>"))
+
+	if strings.Contains(out, "<script>") || strings.Contains(out, "<img") {
+		t.Fatalf("raw html leaked into output: %s", out)
+	}
+	if !strings.Contains(out, "&lt;script&gt;") {
+		t.Fatalf("expected escaped script tag, got %s", out)
+	}
+	if !strings.Contains(out, "<h1>Title</h1>") {
+		t.Fatalf("expected heading, got %s", out)
+	}
+}
+
+func TestRenderMarkdownDropsUnsafeLinks(t *testing.T) {
+	out := string(renderMarkdown("[bad](javascript:alert(1)) and [good](https://example.com/a?b=1)"))
+
+	if strings.Contains(out, `href="javascript`) {
+		t.Fatalf("unsafe href rendered: %s", out)
+	}
+	if !strings.Contains(out, `href="https://example.com/a?b=1"`) {
+		t.Fatalf("expected safe link, got %s", out)
+	}
+	if !strings.Contains(out, `rel="noopener noreferrer nofollow"`) {
+		t.Fatalf("expected rel attribute on link, got %s", out)
+	}
+}
+
+func TestRenderMarkdownBlocks(t *testing.T) {
+	src := strings.Join([]string{
+		"- [x] done",
+		"- [ ] todo",
+		"  - nested",
+		"",
+		"| a | b |",
+		"|---|---|",
+		"| 1 | `x` |",
+		"",
+		"```go",
+		"func main() {}",
+		"```",
+	}, "\n")
+	out := string(renderMarkdown(src))
+
+	for _, want := range []string{
+		`type="checkbox" disabled checked`,
+		"<ul>",
+		"<table>",
+		"<th>a</th>",
+		"<code>x</code>",
+		`<span class="tok-kw">func</span>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestHighlightCodeEscapesSource(t *testing.T) {
+	out := string(highlightCode(languageForFence("js"), `const s = "<b>"; // </script>`))
+
+	if strings.Contains(out, "<b>") || strings.Contains(out, "</script>") {
+		t.Fatalf("source not escaped: %s", out)
+	}
+	if !strings.Contains(out, `<span class="tok-kw">const</span>`) || !strings.Contains(out, `<span class="tok-com">`) {
+		t.Fatalf("expected keyword and comment spans: %s", out)
+	}
+}
+
+func TestDiffLinesSideBySide(t *testing.T) {
+	lines, ok := diffLines("a\nb\nc\n", "a\nB\nc\nd\n")
+	if !ok {
+		t.Fatalf("diffLines reported too large")
+	}
+	rows := sideBySide(lines)
+
+	want := []diffRow{
+		{Left: diffRowCell{Line: 1, Text: "a"}, Right: diffRowCell{Line: 1, Text: "a"}},
+		{Left: diffRowCell{Line: 2, Text: "b", Class: "del"}, Right: diffRowCell{Line: 2, Text: "B", Class: "ins"}},
+		{Left: diffRowCell{Line: 3, Text: "c"}, Right: diffRowCell{Line: 3, Text: "c"}},
+		{Right: diffRowCell{Line: 4, Text: "d", Class: "ins"}},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows=%+v want=%+v", rows, want)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Fatalf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+}
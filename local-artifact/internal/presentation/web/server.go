@@ -48,6 +48,9 @@ type Server struct {
 	closeByKey          map[string]func() error
 	resolver            ServiceResolver
 	apiMaxJSONBodyBytes int64
+
+	liveMu    sync.Mutex
+	liveFeeds map[string]*liveFeed
 }
 
 type ServiceResolver func(selector string) (*artifacts.Service, error)
@@ -71,10 +74,13 @@ func NewWithServiceResolver(baseStoreRoot string, resolver ServiceResolver) *Ser
 		closeByKey:          make(map[string]func() error),
 		resolver:            resolver,
 		apiMaxJSONBodyBytes: maxInsertJSONBodyBytes,
+		liveFeeds:           make(map[string]*liveFeed),
 	}
 }
 
 func (s *Server) Close() error {
+	s.CloseStreams()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -103,6 +109,7 @@ func (s *Server) Serve(ctx context.Context, addr string) error {
 		Addr:    addr,
 		Handler: s.Handler(),
 	}
+	httpServer.RegisterOnShutdown(s.CloseStreams)
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -132,6 +139,10 @@ func (s *Server) routes() http.Handler {
 	mux.HandleFunc("/", s.handleIndex)
 	mux.HandleFunc("/insert", s.handleInsert)
 	mux.HandleFunc("/delete", s.handleDelete)
+	mux.HandleFunc("/artifact", s.handleArtifactDetail)
+	mux.HandleFunc("/artifact/image", s.handleArtifactImage)
+	mux.HandleFunc("/artifact/edit", s.handleArtifactEdit)
+	mux.HandleFunc("/events", s.handleEvents)
 	mux.HandleFunc("/api/artifacts", s.handleAPIArtifacts)
 	mux.HandleFunc("/api/artifact-content", s.handleAPIContent)
 	mux.HandleFunc("/api/subspaces", s.handleAPISubspaces)
@@ -158,6 +169,7 @@ type pageData struct {
 	Message     string
 	Error       string
 	Items       []pageItem
+	Usage       *storageUsage
 	GeneratedAt string
 }
 
@@ -229,6 +241,7 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var items []pageItem
+	var usage *storageUsage
 	if subspace != "" {
 		svc, svcErr := s.serviceForSubspace(subspace)
 		if svcErr != nil {
@@ -269,6 +282,11 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 				CreatedAt: a.CreatedAt.Format(time.RFC3339),
 			})
 		}
+		// Usage is informational; a repository that cannot report it just
+		// hides the panel.
+		if u, usageErr := subspaceUsage(r.Context(), svc); usageErr == nil {
+			usage = u
+		}
 	}
 
 	message := strings.TrimSpace(r.URL.Query().Get("msg"))
@@ -281,6 +299,7 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		Sort:        sortMode,
 		Limit:       limit,
 		Items:       items,
+		Usage:       usage,
 		Message:     message,
 		Error:       errorMsg,
 		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
@@ -715,7 +734,11 @@ func (s *Server) handleAPISave(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		status := http.StatusBadRequest
+		if errors.Is(err, artifacts.ErrQuotaExceeded) {
+			status = http.StatusInsufficientStorage
+		}
+		writeJSON(w, status, map[string]any{"error": err.Error()})
 		return
 	}
 
@@ -5,7 +5,11 @@ import (
 	"html/template"
 )
 
-//go:embed templates/index.html
+//go:embed templates/index.html templates/artifact.html templates/edit.html
 var templateFiles embed.FS
 
 var indexTemplate = template.Must(template.ParseFS(templateFiles, "templates/index.html"))
+
+var artifactTemplate = template.Must(template.ParseFS(templateFiles, "templates/artifact.html"))
+
+var editTemplate = template.Must(template.ParseFS(templateFiles, "templates/edit.html"))
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+// quotaWarnPercent is the share of the storage quota at which the index page
+// highlights the usage bar.
+const quotaWarnPercent = 90
+
+type storageUsage struct {
+	Artifacts  int64
+	Versions   int64
+	Bytes      string
+	Limits     []string
+	QuotaBytes string
+	Percent    int
+	Warn       bool
+}
+
+func subspaceUsage(ctx context.Context, svc *artifacts.Service) (*storageUsage, error) {
+	usage, err := svc.Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	quota := svc.Quota()
+	out := &storageUsage{
+		Artifacts: usage.Artifacts,
+		Versions:  usage.Versions,
+		Bytes:     formatByteSize(usage.Bytes),
+	}
+	if quota.MaxTotalBytes > 0 {
+		out.QuotaBytes = formatByteSize(quota.MaxTotalBytes)
+		out.Percent = int(min(100, usage.Bytes*100/quota.MaxTotalBytes))
+		out.Warn = out.Percent >= quotaWarnPercent
+	}
+	if quota.MaxArtifactBytes > 0 {
+		out.Limits = append(out.Limits, formatByteSize(quota.MaxArtifactBytes)+" per artifact")
+	}
+	if quota.MaxVersionsPerName > 0 {
+		out.Limits = append(out.Limits, fmt.Sprintf("%d versions per name", quota.MaxVersionsPerName))
+	}
+	return out, nil
+}
+
+func formatByteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,40 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/CeraCharlesCC/CCSubAgents/local-artifact/internal/core/artifacts"
+)
+
+func TestIndexShowsStorageUsageAgainstQuota(t *testing.T) {
+	h := newWebHarness(t)
+	h.svc(globalSubspaceSelector).SetQuota(artifacts.Quota{MaxTotalBytes: 10, MaxVersionsPerName: 5})
+	h.mustSaveText(globalSubspaceSelector, "plan/a", "123456789")
+
+	rr := h.request(http.MethodGet, "/?subspace=global", nil, nil)
+	assertStatus(t, rr, http.StatusOK)
+	body := rr.Body.String()
+	for _, want := range []string{
+		`class="usage warn" id="storage-usage"`,
+		"1 artifacts, 1 versions",
+		"9 B of 10 B (90%)",
+		"5 versions per name",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in index, body=%s", want, body)
+		}
+	}
+}
+
+func TestAPISaveReportsQuotaExceeded(t *testing.T) {
+	h := newWebHarness(t)
+	h.svc(globalSubspaceSelector).SetQuota(artifacts.Quota{MaxArtifactBytes: 3})
+
+	rr := h.jsonRequest(http.MethodPost, "/api/artifacts?subspace=global", `{"name":"api/text","text":"hello"}`)
+	assertStatus(t, rr, http.StatusInsufficientStorage)
+	if !strings.Contains(rr.Body.String(), "quota exceeded") {
+		t.Fatalf("expected quota error, body=%s", rr.Body.String())
+	}
+}